@@ -39,7 +39,7 @@ func main() {
 	nbi := inventory.NewNetboxInventory(benchmarkCtx, inventoryLogger, config.Netbox, false)
 	mainLogger.Debug(benchmarkCtx, "Netbox inventory: ", nbi)
 
-	err = nbi.Init()
+	err = nbi.Init(benchmarkCtx)
 	if err != nil {
 		mainLogger.Error(benchmarkCtx, err)
 		return
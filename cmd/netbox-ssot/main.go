@@ -2,23 +2,56 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
-	"sync"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
-	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/api"
 	"github.com/bl4ko/netbox-ssot/internal/logger"
-	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
+	"github.com/bl4ko/netbox-ssot/internal/metrics"
 	"github.com/bl4ko/netbox-ssot/internal/parser"
-	"github.com/bl4ko/netbox-ssot/internal/source"
-	"github.com/bl4ko/netbox-ssot/internal/source/common"
+	"github.com/bl4ko/netbox-ssot/internal/runner"
+	"github.com/bl4ko/netbox-ssot/internal/scheduler"
 )
 
 var (
 	configPath = flag.String("config", "config.yaml", "Path to the configuration file")
 	dryRun     = flag.Bool("dry-run", false, "Preview changes without writing to Netbox")
+	daemon     = flag.Bool("daemon", false, "Run as a long-running process, syncing according to --schedule")
+	schedule   = flag.String(
+		"schedule",
+		"@every 20m",
+		"Schedule of runs in daemon mode: cron expression (e.g. \"*/20 * * * *\") or \"@every <duration>\"",
+	)
+	onlySource = flag.String(
+		"only-source", "", "Comma separated names of the sources to sync, all other sources are skipped",
+	)
+	skipSource = flag.String("skip-source", "", "Comma separated names of the sources to skip")
+	reportPath = flag.String(
+		"report",
+		"",
+		"Write report of all changes made during a run to this file (JSON, or CSV if file has .csv extension)",
+	)
+	approveDeletions = flag.String(
+		"approve-deletions",
+		"",
+		"Apply pending deletions from this file, written by a run that exceeded a deletion threshold, and exit",
+	)
+	dumpSourceData = flag.String(
+		"dump-source-data",
+		"",
+		"Dump data fetched by each source to <dir>/<source name>.json, which can be synced by a replay source",
+	)
+	runTimeout = flag.Duration(
+		"run-timeout",
+		0,
+		"Maximum duration of a single run (e.g. 30m), after which the run is canceled. 0 means no limit",
+	)
 )
 
 // Build variables provided with ldflags.
@@ -29,6 +62,15 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate":
+			os.Exit(validate(os.Args[2:]))
+		case "schema":
+			os.Exit(printSchema())
+		}
+	}
+
 	// Print build information
 	fmt.Printf("Running version %s built on %s (commit %s)\n\n", version, date, commit)
 
@@ -43,125 +85,169 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Create our main context
-	mainCtx := context.Background()
-	mainCtx = context.WithValue(mainCtx, constants.CtxSourceKey, "main")
-
 	// Initialize Logger
-	ssotLogger, err := logger.New(config.Logger.Dest, config.Logger.Level)
+	ssotLogger, err := logger.NewWithOptions(logger.Options{
+		Level:         config.Logger.Level,
+		Dest:          config.Logger.Dest,
+		Format:        config.Logger.Format,
+		SyslogNetwork: config.Logger.Syslog.Network,
+		SyslogAddress: config.Logger.Syslog.Address,
+		SyslogTag:     config.Logger.Syslog.Tag,
+		MaxSize:       config.Logger.MaxSize,
+		MaxBackups:    config.Logger.MaxBackups,
+	})
 	if err != nil {
 		fmt.Println("Logger:", err)
 		os.Exit(1)
 	}
+
+	ssotRunner := runner.New(ssotLogger, config, *configPath, *dryRun)
+	ssotRunner.ReportPath = *reportPath
+	ssotRunner.RunTimeout = *runTimeout
+	ssotRunner.DumpSourceDataDir = *dumpSourceData
+	if config.Metrics.Enabled() {
+		ssotRunner.Metrics = metrics.New()
+	}
+	mainCtx := ssotRunner.Ctx
 	ssotLogger.Debug(mainCtx, "Parsed Logger config: ", config.Logger)
 	ssotLogger.Debug(mainCtx, "Parsed Netbox config: ", config.Netbox)
 	ssotLogger.Debug(mainCtx, "Parsed Source config: ", config.Sources)
 
-	inventoryLogger, err := logger.New(config.Logger.Dest, config.Logger.Level)
-	if err != nil {
-		ssotLogger.Errorf(mainCtx, "inventoryLogger: %s", err)
-		os.Exit(1)
-	}
 	if *dryRun {
 		ssotLogger.Info(mainCtx, "DRY-RUN MODE ENABLED: No changes will be written to Netbox")
 	}
 
-	inventoryCtx := context.WithValue(context.Background(), constants.CtxSourceKey, "inventory")
-	netboxInventory := inventory.NewNetboxInventory(inventoryCtx, inventoryLogger, config.Netbox, *dryRun)
-	ssotLogger.Debug(mainCtx, "Netbox inventory: ", netboxInventory)
+	runOpts := runner.RunOptions{
+		Sources:     splitSourceNames(*onlySource),
+		SkipSources: splitSourceNames(*skipSource),
+	}
 
-	ssotLogger.Info(mainCtx, "Starting initializing netbox inventory")
-	err = netboxInventory.Init()
-	if err != nil {
-		ssotLogger.Error(mainCtx, err)
-		os.Exit(1)
+	if *approveDeletions != "" {
+		if *daemon {
+			ssotLogger.Error(mainCtx, "--approve-deletions can't be used in daemon mode")
+			os.Exit(1)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+		defer stop()
+		if err := ssotRunner.ApproveDeletions(ctx, *approveDeletions); err != nil {
+			ssotLogger.Error(mainCtx, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if config.API.Address != "" && !*daemon {
+		ssotLogger.Warning(mainCtx, "Control API is only available in daemon mode, ignoring api.address")
 	}
-	ssotLogger.Debug(mainCtx, "Netbox inventory initialized: ", netboxInventory)
-
-	// Variable to store if the run was successful. If it wasn't we don't remove orphans.
-	successfullRun := true
-	// Variable to store failed sourcesFalse
-	encounteredErrors := map[string]error{}
-
-	// Go through all sources and sync data
-	var wg sync.WaitGroup
-	for i := range config.Sources {
-		sourceConfig := &config.Sources[i]
-		ssotLogger.Info(mainCtx, "Processing source ", sourceConfig.Name, "...")
-		sourceCtx := context.WithValue(mainCtx, constants.CtxSourceKey, sourceConfig.Name)
-		source, err := source.NewSource(sourceCtx, sourceConfig, ssotLogger, netboxInventory)
+
+	if *daemon {
+		runSchedule, err := scheduler.Parse(*schedule)
 		if err != nil {
-			ssotLogger.Error(sourceCtx, err)
+			ssotLogger.Errorf(mainCtx, "schedule: %s", err)
 			os.Exit(1)
 		}
-		ssotLogger.Infof(sourceCtx, "Successfully created source %s", constants.CheckMark)
-		ssotLogger.Debugf(sourceCtx, "Source content: %s", source)
-		wg.Add(1)
-		// Run each source in parallel
-		go func(sourceCtx context.Context, source common.Source) {
-			defer wg.Done()
-			sourceName, ok := sourceCtx.Value(constants.CtxSourceKey).(string)
-			if !ok {
-				ssotLogger.Errorf(sourceCtx, "source ctx value is not set")
-				return
-			}
-			// Source initialization
-			ssotLogger.Info(sourceCtx, "Initializing source")
-			err = source.Init()
-			if err != nil {
-				ssotLogger.Error(sourceCtx, err)
-				successfullRun = false
-				encounteredErrors[sourceName] = err
-				return
-			}
-			ssotLogger.Infof(sourceCtx, "Successfully initialized source %s", constants.CheckMark)
-
-			// Source synchronization
-			ssotLogger.Info(sourceCtx, "Syncing source...")
-			err = source.Sync(netboxInventory)
-			if err != nil {
-				successfullRun = false
-				ssotLogger.Error(sourceCtx, err)
-				encounteredErrors[sourceName] = err
-				return
+
+		// SIGTERM and SIGINT stop the daemon, SIGHUP reloads the configuration
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+		defer stop()
+		hupSignals := make(chan os.Signal, 1)
+		signal.Notify(hupSignals, syscall.SIGHUP)
+		reload := make(chan struct{}, 1)
+		go func() {
+			for range hupSignals {
+				select {
+				case reload <- struct{}{}:
+				default:
+				}
 			}
-			ssotLogger.Infof(sourceCtx, "Source synced successfully %s", constants.CheckMark)
-		}(sourceCtx, source)
-	}
-	wg.Wait()
+		}()
+
+		serveMetrics(ctx, ssotRunner, stop)
+
+		if config.API.Address != "" {
+			apiServer := api.NewServer(ctx, ssotLogger, config.API, ssotRunner)
+			go func() {
+				if err := apiServer.ListenAndServe(ctx); err != nil {
+					ssotLogger.Error(mainCtx, err)
+					stop()
+				}
+			}()
+		}
 
-	// Orphan manager cleanup on successful run and if enabled
-	if successfullRun {
-		ssotLogger.Info(mainCtx, "Cleaning up orphaned objects...")
-		err = netboxInventory.DeleteOrphans(config.Netbox.RemoveOrphans)
-		if err != nil {
+		if err := ssotRunner.Daemon(ctx, runSchedule, runOpts, reload); err != nil {
 			ssotLogger.Error(mainCtx, err)
 			os.Exit(1)
 		}
-		ssotLogger.Infof(mainCtx, "%s Successfully removed orphans", constants.CheckMark)
-	} else {
-		ssotLogger.Info(mainCtx, "Skipping removing orphaned objects because run failed...")
+		return
 	}
 
-	duration := time.Since(startTime)
-	minutes := int(duration.Minutes())
-	seconds := int((duration - time.Duration(minutes)*time.Minute).Seconds())
-	if *dryRun {
-		ssotLogger.Info(mainCtx, "DRY-RUN COMPLETE: Review the log above for [DRY-RUN] entries to see what would change")
+	// SIGTERM and SIGINT cancel the run
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	serveMetrics(ctx, ssotRunner, stop)
+	runOpts.Trigger = runner.TriggerCLI
+	result := ssotRunner.Run(ctx, runOpts)
+	if !result.Successful() {
+		os.Exit(1)
 	}
+}
 
-	if successfullRun {
-		ssotLogger.Infof(
-			mainCtx,
-			"%s Syncing took %d min %d sec in total",
-			constants.Rocket,
-			minutes,
-			seconds,
-		)
-	} else {
-		for source, err := range encounteredErrors {
-			ssotLogger.Infof(mainCtx, "%s syncing of source %s failed with: %v", constants.WarningSign, source, err)
+// validate runs the validate subcommand, which validates the config without
+// running netbox-ssot and reports all errors in it. It returns the exit code.
+func validate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	validateConfigPath := flags.String("config", "config.yaml", "Path to the configuration file")
+	resolveSecrets := flags.Bool(
+		"resolve-secrets", false, "Resolve secret references, instead of only checking that they are valid",
+	)
+	flags.Parse(args) //nolint:errcheck
+	errs := parser.ValidateConfig(*validateConfigPath, *resolveSecrets)
+	if len(errs) == 0 {
+		fmt.Printf("%s is valid\n", *validateConfigPath)
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "%s is not valid:\n", *validateConfigPath)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "  - %s\n", strings.ReplaceAll(err.Error(), "\n", "\n    "))
+	}
+	return 1
+}
+
+// printSchema runs the schema subcommand, which prints JSON Schema
+// of the config file. It returns the exit code.
+func printSchema() int {
+	schema, err := json.MarshalIndent(parser.JSONSchema(), "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Schema:", err)
+		return 1
+	}
+	fmt.Println(string(schema))
+	return 0
+}
+
+// serveMetrics serves metrics of the runner in the background until ctx is
+// canceled, if metrics.address is configured. If the server fails, stop is called.
+func serveMetrics(ctx context.Context, ssotRunner *runner.Runner, stop context.CancelFunc) {
+	address := ssotRunner.Config.Metrics.Address
+	if address == "" {
+		return
+	}
+	go func() {
+		ssotRunner.Logger.Infof(ssotRunner.Ctx, "Serving metrics on %s/metrics", address)
+		if err := ssotRunner.Metrics.ListenAndServe(ctx, address); err != nil {
+			ssotRunner.Logger.Error(ssotRunner.Ctx, err)
+			stop()
+		}
+	}()
+}
+
+// splitSourceNames splits comma separated list of source names.
+func splitSourceNames(names string) []string {
+	var sourceNames []string
+	for name := range strings.SplitSeq(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			sourceNames = append(sourceNames, name)
 		}
-		os.Exit(1)
 	}
+	return sourceNames
 }
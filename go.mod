@@ -9,6 +9,7 @@ require (
 	github.com/hetznercloud/hcloud-go/v2 v2.43.0
 	github.com/luthermonson/go-proxmox v0.8.0
 	github.com/ovirt/go-ovirt v4.3.4+incompatible
+	github.com/prometheus/client_golang v1.23.2
 	github.com/scrapli/scrapligo v1.4.0
 	github.com/src-doo/go-devicetype-library v0.1.56
 	github.com/vmware/govmomi v0.55.1
@@ -28,9 +29,9 @@ require (
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/gorilla/websocket v1.5.3 // indirect
 	github.com/jinzhu/copier v0.4.0 // indirect
+	github.com/kylelemons/godebug v1.1.0 // indirect
 	github.com/magefile/mage v1.15.0 // indirect
 	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
-	github.com/prometheus/client_golang v1.23.2 // indirect
 	github.com/prometheus/client_model v0.6.2 // indirect
 	github.com/prometheus/common v0.66.1 // indirect
 	github.com/prometheus/procfs v0.16.1 // indirect
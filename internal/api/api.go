@@ -0,0 +1,207 @@
+// Package api implements the embedded HTTP control API of netbox-ssot.
+// It allows triggering, inspecting and canceling synchronization runs,
+// while netbox-ssot is running in daemon mode.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/logger"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+	"github.com/bl4ko/netbox-ssot/internal/runner"
+)
+
+// Time given to in-flight requests when the server is shutting down.
+const shutdownTimeout = 5 * time.Second
+
+// Maximum accepted size of the request body.
+const maxRequestBodySize = 1 << 20
+
+// Controller controls synchronization runs. It is implemented by runner.Runner.
+type Controller interface {
+	Start(ctx context.Context, opts runner.RunOptions) (runner.Result, error)
+	Cancel(runID int) error
+	Runs() []runner.Result
+	GetRun(runID int) (runner.Result, bool)
+	SourceStatuses() []runner.SourceStatus
+}
+
+// Server is the HTTP control API server.
+type Server struct {
+	Logger     *logger.Logger
+	Config     *parser.APIConfig
+	Controller Controller
+	// Ctx is used for logging and as a parent context of all triggered runs.
+	Ctx context.Context //nolint:containedctx
+}
+
+// NewServer creates a new API server.
+func NewServer(
+	ctx context.Context,
+	logger *logger.Logger,
+	config *parser.APIConfig,
+	controller Controller,
+) *Server {
+	return &Server{
+		Logger:     logger,
+		Config:     config,
+		Controller: controller,
+		Ctx:        context.WithValue(ctx, constants.CtxSourceKey, "api"),
+	}
+}
+
+// Handler returns the http.Handler serving all API endpoints.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.Handle("POST /api/v1/runs", s.authenticate(s.handleTriggerRun))
+	mux.Handle("GET /api/v1/runs", s.authenticate(s.handleListRuns))
+	mux.Handle("GET /api/v1/runs/{id}", s.authenticate(s.handleGetRun))
+	mux.Handle("POST /api/v1/runs/{id}/cancel", s.authenticate(s.handleCancelRun))
+	mux.Handle("GET /api/v1/sources", s.authenticate(s.handleListSources))
+	return mux
+}
+
+// ListenAndServe serves the API until ctx is canceled.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	server := &http.Server{
+		Addr:              s.Config.Address,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: shutdownTimeout,
+	}
+	errChan := make(chan error, 1)
+	go func() {
+		s.Logger.Infof(s.Ctx, "Control API listening on %s", s.Config.Address)
+		errChan <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-errChan:
+		return fmt.Errorf("control api: %s", err)
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return server.Shutdown(shutdownCtx) //nolint:contextcheck
+	}
+}
+
+// authenticate wraps handler so it requires the configured bearer token.
+func (s *Server) authenticate(handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Config.Token != "" {
+			expected := "Bearer " + s.Config.Token
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(expected)) != 1 {
+				writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+				return
+			}
+		}
+		handler(w, r)
+	})
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Body of the request for triggering a run.
+type triggerRunRequest struct {
+	// Sources are names of the sources to sync. If empty, all sources are synced.
+	Sources []string `json:"sources"`
+}
+
+func (s *Server) handleTriggerRun(w http.ResponseWriter, r *http.Request) {
+	var request triggerRunRequest
+	if r.ContentLength != 0 {
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %s", err))
+			return
+		}
+	}
+	run, err := s.Controller.Start(s.Ctx, runner.RunOptions{
+		Trigger: runner.TriggerAPI,
+		Sources: request.Sources,
+	})
+	switch {
+	case errors.Is(err, runner.ErrRunInProgress):
+		writeError(w, http.StatusConflict, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.Logger.Infof(s.Ctx, "Triggered run %d for sources %v", run.ID, run.Sources)
+	writeJSON(w, http.StatusAccepted, newRunResponse(run))
+}
+
+func (s *Server) handleListRuns(w http.ResponseWriter, _ *http.Request) {
+	runs := s.Controller.Runs()
+	response := make([]runResponse, 0, len(runs))
+	for _, run := range runs {
+		response = append(response, newRunResponse(run))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleGetRun(w http.ResponseWriter, r *http.Request) {
+	runID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid run id: %s", r.PathValue("id")))
+		return
+	}
+	run, ok := s.Controller.GetRun(runID)
+	if !ok {
+		writeError(w, http.StatusNotFound, runner.ErrRunNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, newRunResponse(run))
+}
+
+func (s *Server) handleCancelRun(w http.ResponseWriter, r *http.Request) {
+	runID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid run id: %s", r.PathValue("id")))
+		return
+	}
+	err = s.Controller.Cancel(runID)
+	switch {
+	case errors.Is(err, runner.ErrRunNotFound):
+		writeError(w, http.StatusNotFound, err)
+		return
+	case errors.Is(err, runner.ErrRunNotInProgress):
+		writeError(w, http.StatusConflict, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	run, _ := s.Controller.GetRun(runID)
+	writeJSON(w, http.StatusAccepted, newRunResponse(run))
+}
+
+func (s *Server) handleListSources(w http.ResponseWriter, _ *http.Request) {
+	statuses := s.Controller.SourceStatuses()
+	response := make([]sourceStatusResponse, 0, len(statuses))
+	for _, status := range statuses {
+		response = append(response, newSourceStatusResponse(status))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, err error) {
+	writeJSON(w, statusCode, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,298 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/logger"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+	"github.com/bl4ko/netbox-ssot/internal/runner"
+)
+
+var (
+	testStart = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	testEnd   = testStart.Add(90 * time.Second)
+)
+
+// fakeController is a Controller with a fixed history of runs.
+type fakeController struct {
+	runs       []runner.Result
+	inProgress bool
+	started    *runner.RunOptions
+}
+
+func (c *fakeController) Start(_ context.Context, opts runner.RunOptions) (runner.Result, error) {
+	if c.inProgress {
+		return runner.Result{}, runner.ErrRunInProgress
+	}
+	for _, name := range opts.Sources {
+		if name != "vmware" && name != "ovirt" {
+			return runner.Result{}, errors.New("source " + name + " is not configured")
+		}
+	}
+	c.started = &opts
+	sources := opts.Sources
+	if len(sources) == 0 {
+		sources = []string{"vmware", "ovirt"}
+	}
+	return runner.Result{ID: 3, Trigger: opts.Trigger, Sources: sources, StartTime: testStart}, nil
+}
+
+func (c *fakeController) Cancel(runID int) error {
+	run, ok := c.GetRun(runID)
+	switch {
+	case !ok:
+		return runner.ErrRunNotFound
+	case !run.EndTime.IsZero():
+		return runner.ErrRunNotInProgress
+	}
+	return nil
+}
+
+func (c *fakeController) Runs() []runner.Result {
+	return c.runs
+}
+
+func (c *fakeController) GetRun(runID int) (runner.Result, bool) {
+	for _, run := range c.runs {
+		if run.ID == runID {
+			return run, true
+		}
+	}
+	return runner.Result{}, false
+}
+
+func (c *fakeController) SourceStatuses() []runner.SourceStatus {
+	return []runner.SourceStatus{
+		{Name: "vmware", LastRunID: 1, LastRunTime: testEnd, LastSuccessTime: testEnd},
+		{Name: "ovirt", LastRunID: 1, LastRunTime: testEnd, LastError: errors.New("connection refused")},
+	}
+}
+
+func newTestServer(token string, inProgress bool) (*Server, *fakeController) {
+	controller := &fakeController{
+		inProgress: inProgress,
+		runs: []runner.Result{
+			{
+				ID:           2,
+				Trigger:      runner.TriggerSchedule,
+				Sources:      []string{"vmware"},
+				StartTime:    testStart,
+				SourceErrors: map[string]error{},
+			},
+			{
+				ID:           1,
+				Trigger:      runner.TriggerCLI,
+				Sources:      []string{"vmware", "ovirt"},
+				StartTime:    testStart,
+				EndTime:      testEnd,
+				SourceErrors: map[string]error{"ovirt": errors.New("connection refused")},
+			},
+		},
+	}
+	server := NewServer(
+		context.Background(),
+		&logger.Logger{Logger: log.New(io.Discard, "", 0)},
+		&parser.APIConfig{Address: ":0", Token: token},
+		controller,
+	)
+	return server, controller
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		inProgress bool
+		method     string
+		path       string
+		body       string
+		authHeader string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "Health check",
+			method:     http.MethodGet,
+			path:       "/healthz",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"status":"ok"}`,
+		},
+		{
+			name:       "Health check doesn't require token",
+			token:      "secret",
+			method:     http.MethodGet,
+			path:       "/healthz",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"status":"ok"}`,
+		},
+		{
+			name:       "Missing token",
+			token:      "secret",
+			method:     http.MethodGet,
+			path:       "/api/v1/runs",
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   `{"error":"missing or invalid token"}`,
+		},
+		{
+			name:       "Invalid token",
+			token:      "secret",
+			method:     http.MethodGet,
+			path:       "/api/v1/runs",
+			authHeader: "Bearer wrong",
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   `{"error":"missing or invalid token"}`,
+		},
+		{
+			name:       "Trigger run of all sources",
+			token:      "secret",
+			method:     http.MethodPost,
+			path:       "/api/v1/runs",
+			authHeader: "Bearer secret",
+			wantStatus: http.StatusAccepted,
+			wantBody:   `{"id":3,"trigger":"api","status":"running","start_time":"2024-01-01T10:00:00Z","duration_seconds":`,
+		},
+		{
+			name:       "Trigger run of selected sources",
+			method:     http.MethodPost,
+			path:       "/api/v1/runs",
+			body:       `{"sources":["ovirt"]}`,
+			wantStatus: http.StatusAccepted,
+			wantBody:   `"sources":[{"name":"ovirt"}]`,
+		},
+		{
+			name:       "Trigger run of unknown source",
+			method:     http.MethodPost,
+			path:       "/api/v1/runs",
+			body:       `{"sources":["proxmox"]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"source proxmox is not configured"}`,
+		},
+		{
+			name:       "Trigger run with invalid body",
+			method:     http.MethodPost,
+			path:       "/api/v1/runs",
+			body:       `{"source":"vmware"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"invalid request body: json: unknown field \"source\""}`,
+		},
+		{
+			name:       "Trigger run while another is in progress",
+			inProgress: true,
+			method:     http.MethodPost,
+			path:       "/api/v1/runs",
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"error":"another run is already in progress"}`,
+		},
+		{
+			name:       "List runs",
+			method:     http.MethodGet,
+			path:       "/api/v1/runs",
+			wantStatus: http.StatusOK,
+			wantBody: `{"id":1,"trigger":"cli","status":"failed","start_time":"2024-01-01T10:00:00Z",` +
+				`"end_time":"2024-01-01T10:01:30Z","duration_seconds":90,` +
+				`"sources":[{"name":"vmware"},{"name":"ovirt","error":"connection refused"}]}]`,
+		},
+		{
+			name:       "Get run",
+			method:     http.MethodGet,
+			path:       "/api/v1/runs/1",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":1,"trigger":"cli","status":"failed"`,
+		},
+		{
+			name:       "Get unknown run",
+			method:     http.MethodGet,
+			path:       "/api/v1/runs/10",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"error":"run not found"}`,
+		},
+		{
+			name:       "Get run with invalid id",
+			method:     http.MethodGet,
+			path:       "/api/v1/runs/abc",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"invalid run id: abc"}`,
+		},
+		{
+			name:       "Cancel run in progress",
+			method:     http.MethodPost,
+			path:       "/api/v1/runs/2/cancel",
+			wantStatus: http.StatusAccepted,
+			wantBody:   `{"id":2,"trigger":"schedule","status":"running"`,
+		},
+		{
+			name:       "Cancel finished run",
+			method:     http.MethodPost,
+			path:       "/api/v1/runs/1/cancel",
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"error":"run is not in progress"}`,
+		},
+		{
+			name:       "Cancel unknown run",
+			method:     http.MethodPost,
+			path:       "/api/v1/runs/10/cancel",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"error":"run not found"}`,
+		},
+		{
+			name:       "List sources",
+			method:     http.MethodGet,
+			path:       "/api/v1/sources",
+			wantStatus: http.StatusOK,
+			wantBody: `[{"name":"vmware","last_run_id":1,"last_run_time":"2024-01-01T10:01:30Z",` +
+				`"last_success_time":"2024-01-01T10:01:30Z"},` +
+				`{"name":"ovirt","last_run_id":1,"last_run_time":"2024-01-01T10:01:30Z","last_error":"connection refused"}]`,
+		},
+		{
+			name:       "Method not allowed",
+			method:     http.MethodDelete,
+			path:       "/api/v1/runs",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(tt.token, tt.inProgress)
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			rec := httptest.NewRecorder()
+
+			server.Handler().ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestTriggerRunOptions(t *testing.T) {
+	server, controller := newTestServer("", false)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/runs", strings.NewReader(`{"sources":["vmware","ovirt"]}`))
+	rec := httptest.NewRecorder()
+
+	server.Handler().ServeHTTP(rec, req)
+
+	if controller.started == nil {
+		t.Fatalf("run was not started")
+	}
+	if controller.started.Trigger != runner.TriggerAPI {
+		t.Errorf("trigger = %s, want %s", controller.started.Trigger, runner.TriggerAPI)
+	}
+	if strings.Join(controller.started.Sources, ",") != "vmware,ovirt" {
+		t.Errorf("sources = %v, want [vmware ovirt]", controller.started.Sources)
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/runner"
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// runSourceResponse is the outcome of a single source within a run.
+type runSourceResponse struct {
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+}
+
+// runResponse is the JSON representation of runner.Result.
+type runResponse struct {
+	ID        int                 `json:"id"`
+	Trigger   string              `json:"trigger"`
+	Status    runner.RunStatus    `json:"status"`
+	StartTime time.Time           `json:"start_time"`
+	EndTime   *time.Time          `json:"end_time,omitempty"`
+	Duration  float64             `json:"duration_seconds"`
+	Error     string              `json:"error,omitempty"`
+	Sources   []runSourceResponse `json:"sources"`
+	// Branch is the NetBox branch, in which changes of the run are staged.
+	Branch       string `json:"branch,omitempty"`
+	BranchMerged bool   `json:"branch_merged,omitempty"`
+}
+
+func newRunResponse(run runner.Result) runResponse {
+	response := runResponse{
+		ID:           run.ID,
+		Trigger:      run.Trigger,
+		Status:       run.Status(),
+		StartTime:    run.StartTime,
+		Duration:     run.Duration().Seconds(),
+		Sources:      make([]runSourceResponse, 0, len(run.Sources)),
+		Branch:       run.Branch,
+		BranchMerged: run.BranchMerged,
+	}
+	if !run.EndTime.IsZero() {
+		response.EndTime = &run.EndTime
+	}
+	if run.Err != nil {
+		response.Error = run.Err.Error()
+	}
+	for _, sourceName := range run.Sources {
+		sourceResponse := runSourceResponse{Name: sourceName}
+		if err := run.SourceErrors[sourceName]; err != nil {
+			sourceResponse.Error = err.Error()
+		}
+		response.Sources = append(response.Sources, sourceResponse)
+	}
+	return response
+}
+
+// sourceStatusResponse is the JSON representation of runner.SourceStatus.
+type sourceStatusResponse struct {
+	Name            string     `json:"name"`
+	LastRunID       int        `json:"last_run_id,omitempty"`
+	LastRunTime     *time.Time `json:"last_run_time,omitempty"`
+	LastSuccessTime *time.Time `json:"last_success_time,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+}
+
+func newSourceStatusResponse(status runner.SourceStatus) sourceStatusResponse {
+	response := sourceStatusResponse{
+		Name:      status.Name,
+		LastRunID: status.LastRunID,
+	}
+	if !status.LastRunTime.IsZero() {
+		response.LastRunTime = &status.LastRunTime
+	}
+	if !status.LastSuccessTime.IsZero() {
+		response.LastSuccessTime = &status.LastSuccessTime
+	}
+	if status.LastError != nil {
+		response.LastError = status.LastError.Error()
+	}
+	return response
+}
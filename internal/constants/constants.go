@@ -14,6 +14,8 @@ const (
 	F5           SourceType = "f5"
 	HetznerCloud SourceType = "hetznercloud"
 	OpenStack    SourceType = "openstack"
+	// Replay syncs source data dumped with --dump-source-data.
+	Replay SourceType = "replay"
 )
 
 const WildcardIP = "0.0.0.0"
@@ -30,6 +32,10 @@ const IgnoreDeviceTypeTagName = "netbox-ssot-ignore-device-type"
 const IgnoreDeviceTypeTagColor = ColorGrey
 const IgnoreDeviceTypeTagDescription = "Tag used by netbox-ssot to preserve manually set device types"
 
+const CreateOnlyTagName = "netbox-ssot-create-only"
+const CreateOnlyTagColor = ColorGrey
+const CreateOnlyTagDescription = "Tag used by netbox-ssot to preserve all manually set fields of an object"
+
 const DefaultVlanGroupName = "DefaultVlanGroup"
 
 const DefaultVlanGroupDescription = "Default netbox-ssot VlanGroup for all vlans that are not part of " +
@@ -95,6 +101,7 @@ var SourceTagColorMap = map[SourceType]string{
 	F5:           ColorRed,
 	HetznerCloud: "d50c2d",
 	OpenStack:    ColorRed,
+	Replay:       ColorGrey,
 }
 
 // Each source Mapping for source type tag. E.g. tag "paloalto" -> color orange.
@@ -109,11 +116,32 @@ var SourceTypeTagColorMap = map[SourceType]string{
 	F5:           ColorDarkRed,
 	HetznerCloud: ColorRed,
 	OpenStack:    ColorRed,
+	Replay:       ColorGrey,
 }
 
 const (
 	// API timeout in seconds.
 	DefaultAPITimeout = 15
+	// Number of retries of failed API requests.
+	DefaultAPIMaxRetries = 5
+	// Number of concurrent init steps during inventory initialization.
+	DefaultInitConcurrency = 4
+	// Number of pages of a single object type fetched concurrently.
+	DefaultPageConcurrency = 2
+	// Number of objects created or updated in a single bulk request.
+	DefaultBulkSize = 50
+	// File, where deletions are written when a deletion threshold is exceeded.
+	DefaultPendingDeletionsFile = "pending-deletions.json"
+	// Job name of metrics pushed to the Prometheus Pushgateway.
+	DefaultMetricsJob = "netbox-ssot"
+	// Size of the log file in megabytes, after which it is rotated.
+	DefaultLogMaxSize = 100
+	// Number of rotated log files that are kept.
+	DefaultLogMaxBackups = 3
+	// Prefix of names of branches, in which runs are staged.
+	DefaultBranchNamePrefix = "netbox-ssot"
+	// Time in seconds to wait for a branch to be provisioned or merged.
+	DefaultBranchTimeout = 300
 )
 
 // Magic numbers for dealing with bytes.
@@ -187,12 +215,34 @@ const (
 	CustomFieldDeviceUUIDLabel       = "uuid"
 	CustomFieldDeviceUUIDDescription = "Universally Unique Identifier for a device"
 
+	// Custom field for listing fields of an object, which are set only when netbox-ssot creates it.
+	CustomFieldCreateOnlyFieldsName        = "ssot_create_only_fields"
+	CustomFieldCreateOnlyFieldsLabel       = "Create-only fields"
+	CustomFieldCreateOnlyFieldsDescription = "Comma separated fields (e.g. tenant, description), " +
+		"which netbox-ssot sets only when it creates the object"
+
+	// Custom field for recording the source, which set each field with a field source priority.
+	CustomFieldFieldSourcesName        = "ssot_field_sources"
+	CustomFieldFieldSourcesLabel       = "Field sources"
+	CustomFieldFieldSourcesDescription = "Sources, which set fields of the object according to field source priorities"
+
 	// Custom field for ModelTypeIPAddress, so we can determine if an ip is part of an arp table or not.
 	CustomFieldArpEntryName        = "arp_entry"
 	CustomFieldArpEntryLabel       = "Arp Entry"
 	CustomFieldArpEntryDescription = "Was this IP collected from ARP table"
 )
 
+// SsotOwnedFields are fields, which netbox-ssot uses to manage objects,
+// so they are always updated, regardless of field ownership.
+var SsotOwnedFields = []string{
+	"tags",
+	"custom_fields." + CustomFieldSourceName,
+	"custom_fields." + CustomFieldSourceIDName,
+	"custom_fields." + CustomFieldOrphanLastSeenName,
+	"custom_fields." + CustomFieldCreateOnlyFieldsName,
+	"custom_fields." + CustomFieldFieldSourcesName,
+}
+
 // Device Role constants.
 const (
 	DeviceRoleFirewall            = "Firewall"
@@ -0,0 +1,304 @@
+// Package expr implements a small expression language, which matches objects
+// of sources by their attributes, e.g.
+//
+//	cluster == "Prod" && tags == "web" && !(ips in "10.1.0.0/16")
+//
+// Expressions compare attributes of the object with literals:
+//
+//   - attribute == "value" and attribute != "value" compare values,
+//   - attribute =~ "regex" and attribute !~ "regex" match values with a regex,
+//   - attribute in ["a", "b"] matches one of the values,
+//   - attribute in "10.0.0.0/8" matches IP addresses in the subnet.
+//
+// Attributes can have several values (e.g. tags), in which case the comparison is true, if
+// it is true for any value (and != is true, if no value equals). Comparisons are combined with
+// && (and), || (or), ! (not) and parentheses. Indexed attributes are accessed with a key, e.g.
+// attributes["Owner"].
+package expr
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Attributes are values of attributes of an object, by attribute names.
+// Values of indexed attributes are stored under IndexedName.
+type Attributes map[string][]string
+
+// Set sets values of the attribute.
+func (a Attributes) Set(name string, values ...string) {
+	a[name] = values
+}
+
+// IndexedName returns the name, under which values of the indexed attribute with the key are stored.
+func IndexedName(name string, key string) string {
+	return name + "[" + key + "]"
+}
+
+// Expr is a compiled expression.
+type Expr struct {
+	source string
+	root   node
+}
+
+// Compile compiles the expression. Names are attributes, which can be used in
+// the expression. Attributes, which are indexed by a key, map to true.
+func Compile(source string, names map[string]bool) (*Expr, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens, names: names}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", next, next.pos)
+	}
+	return &Expr{source: source, root: root}, nil
+}
+
+// Match returns true, if the object with the attributes matches the expression.
+func (e *Expr) Match(attributes Attributes) bool {
+	return e.root.eval(attributes)
+}
+
+func (e *Expr) String() string {
+	return e.source
+}
+
+// node is a node of the syntax tree of the expression.
+type node interface {
+	eval(attributes Attributes) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(attributes Attributes) bool {
+	return n.left.eval(attributes) && n.right.eval(attributes)
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(attributes Attributes) bool {
+	return n.left.eval(attributes) || n.right.eval(attributes)
+}
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(attributes Attributes) bool {
+	return !n.operand.eval(attributes)
+}
+
+// comparisonNode compares values of the attribute with a literal. Negated comparisons
+// (!= and !~) are true, if the comparison is false for all values.
+type comparisonNode struct {
+	attribute string
+	negated   bool
+	matches   func(value string) bool
+}
+
+func (n comparisonNode) eval(attributes Attributes) bool {
+	return slices.ContainsFunc(attributes[n.attribute], n.matches) != n.negated
+}
+
+type exprParser struct {
+	tokens []token
+	pos    int
+	names  map[string]bool
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) expect(kind tokenKind, text string) (token, error) {
+	t := p.next()
+	if t.kind != kind || text != "" && t.text != text {
+		expected := text
+		if expected == "" {
+			expected = kind.String()
+		}
+		return t, fmt.Errorf("expected %s at position %d, got %s", expected, t.pos, t)
+	}
+	return t, nil
+}
+
+func (p *exprParser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is(tokenOperator, "||") || p.peek().is(tokenIdent, "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is(tokenOperator, "&&") || p.peek().is(tokenIdent, "and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (node, error) {
+	if p.peek().is(tokenOperator, "!") || p.peek().is(tokenIdent, "not") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	if p.peek().is(tokenOperator, "(") {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenOperator, ")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (node, error) {
+	attribute, err := p.parseAttribute()
+	if err != nil {
+		return nil, err
+	}
+	operator := p.next()
+	switch {
+	case operator.is(tokenOperator, "==") || operator.is(tokenOperator, "!="):
+		literal, err := p.expect(tokenString, "")
+		if err != nil {
+			return nil, err
+		}
+		return comparisonNode{
+			attribute: attribute,
+			negated:   operator.text == "!=",
+			matches:   func(value string) bool { return value == literal.text },
+		}, nil
+	case operator.is(tokenOperator, "=~") || operator.is(tokenOperator, "!~"):
+		literal, err := p.expect(tokenString, "")
+		if err != nil {
+			return nil, err
+		}
+		regex, err := regexp.Compile(literal.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex at position %d: %s", literal.pos, err)
+		}
+		return comparisonNode{attribute: attribute, negated: operator.text == "!~", matches: regex.MatchString}, nil
+	case operator.is(tokenIdent, "in"):
+		return p.parseIn(attribute)
+	default:
+		return nil, fmt.Errorf(
+			"expected comparison operator (==, !=, =~, !~, in) at position %d, got %s", operator.pos, operator,
+		)
+	}
+}
+
+// parseIn parses the right side of the in operator: a list of values or a subnet.
+func (p *exprParser) parseIn(attribute string) (node, error) {
+	if p.peek().kind == tokenString {
+		literal := p.next()
+		subnet, err := netip.ParsePrefix(literal.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subnet at position %d: %s", literal.pos, err)
+		}
+		return comparisonNode{attribute: attribute, matches: func(value string) bool {
+			// Addresses can be with a mask (e.g. 10.0.0.1/24)
+			address, _, _ := strings.Cut(value, "/")
+			ip, err := netip.ParseAddr(address)
+			return err == nil && subnet.Contains(ip.Unmap())
+		}}, nil
+	}
+	if _, err := p.expect(tokenOperator, "["); err != nil {
+		return nil, err
+	}
+	var values []string
+	for {
+		literal, err := p.expect(tokenString, "")
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, literal.text)
+		if !p.peek().is(tokenOperator, ",") {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect(tokenOperator, "]"); err != nil {
+		return nil, err
+	}
+	return comparisonNode{attribute: attribute, matches: func(value string) bool {
+		return slices.Contains(values, value)
+	}}, nil
+}
+
+// parseAttribute parses name of an attribute, which is followed by a key, if the attribute is indexed.
+func (p *exprParser) parseAttribute() (string, error) {
+	name, err := p.expect(tokenIdent, "")
+	if err != nil {
+		return "", err
+	}
+	indexed, ok := p.names[name.text]
+	if !ok {
+		return "", fmt.Errorf("unknown attribute %s at position %d (%s)", name.text, name.pos, p.knownNames())
+	}
+	if !indexed {
+		return name.text, nil
+	}
+	if _, err := p.expect(tokenOperator, "["); err != nil {
+		return "", fmt.Errorf("attribute %s must be indexed with a key: %s", name.text, err)
+	}
+	key, err := p.expect(tokenString, "")
+	if err != nil {
+		return "", err
+	}
+	if _, err := p.expect(tokenOperator, "]"); err != nil {
+		return "", err
+	}
+	return IndexedName(name.text, key.text), nil
+}
+
+func (p *exprParser) knownNames() string {
+	names := make([]string, 0, len(p.names))
+	for name, indexed := range p.names {
+		if indexed {
+			name += `["key"]`
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,95 @@
+package expr
+
+import (
+	"testing"
+)
+
+var testNames = map[string]bool{
+	"name":       false,
+	"cluster":    false,
+	"tags":       false,
+	"ips":        false,
+	"attributes": true,
+}
+
+func testAttributes() Attributes {
+	attributes := Attributes{}
+	attributes.Set("name", "web01")
+	attributes.Set("cluster", "Prod")
+	attributes.Set("tags", "web", "backup")
+	attributes.Set("ips", "10.1.2.3/24", "fe80::1")
+	attributes.Set(IndexedName("attributes", "Owner"), "team-a")
+	return attributes
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`cluster == "Prod"`, true},
+		{`cluster != "Prod"`, false},
+		{`tags == "backup"`, true},
+		{`tags != "db"`, true},
+		{`tags != "web"`, false},
+		{`name =~ '^web\d+$'`, true},
+		{`name !~ "^web"`, false},
+		{`cluster in ["Dev", "Prod"]`, true},
+		{`cluster in ["Dev"]`, false},
+		{`ips in "10.1.0.0/16"`, true},
+		{`ips in "192.168.0.0/16"`, false},
+		{`ips in "fe80::/10"`, true},
+		{`attributes["Owner"] == "team-a"`, true},
+		{`attributes["Missing"] == ""`, false},
+		{`attributes["Missing"] != "team-a"`, true},
+		{`cluster == "Prod" && tags == "web"`, true},
+		{`cluster == "Prod" && tags == "db"`, false},
+		{`cluster == "Dev" || tags == "web"`, true},
+		{`!(cluster == "Dev")`, true},
+		{`not cluster == "Prod" or name == "web01" and tags == "db"`, false},
+		{`(cluster == "Dev" || cluster == "Prod") && !(tags in ["db"])`, true},
+		{`name == "web01"`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := Compile(tt.expr, testNames)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if got := expr.Match(testAttributes()); got != tt.want {
+				t.Errorf("Match() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{
+			`folder == "Prod"`,
+			`unknown attribute folder at position 1 (attributes["key"], cluster, ips, name, tags)`,
+		},
+		{`cluster = "Prod"`, `unexpected character '=' at position 9`},
+		{`cluster == Prod`, `expected string at position 12, got Prod`},
+		{`cluster == "Prod`, `invalid string at position 12: missing closing "`},
+		{`cluster "Prod"`, `expected comparison operator (==, !=, =~, !~, in) at position 9, got "Prod"`},
+		{`name =~ "web("`, "invalid regex at position 9: error parsing regexp: missing closing ): `web(`"},
+		{`ips in "10.0.0.0"`, `invalid subnet at position 8: netip.ParsePrefix("10.0.0.0"): no '/'`},
+		{`cluster in []`, `expected string at position 13, got ]`},
+		{`attributes == "x"`, `attribute attributes must be indexed with a key: expected [ at position 12, got ==`},
+		{`(cluster == "Prod"`, `expected ) at position 19, got end of expression`},
+		{`cluster == "Prod" tags == "web"`, `unexpected tags at position 19`},
+		{``, `expected attribute at position 1, got end of expression`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Compile(tt.expr, testNames)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Compile() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
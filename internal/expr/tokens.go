@@ -0,0 +1,120 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenOperator
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenIdent:
+		return "attribute"
+	case tokenString:
+		return "string"
+	case tokenOperator:
+		return "operator"
+	default:
+		return "end of expression"
+	}
+}
+
+type token struct {
+	kind tokenKind
+	// text of the token. Strings are unquoted.
+	text string
+	// pos is the position of the token in the expression, starting at 1.
+	pos int
+}
+
+func (t token) is(kind tokenKind, text string) bool {
+	return t.kind == kind && t.text == text
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return t.kind.String()
+	case tokenString:
+		return strconv.Quote(t.text)
+	default:
+		return t.text
+	}
+}
+
+// operators are all operators, two-character operators are listed before their prefixes.
+var operators = []string{"==", "!=", "=~", "!~", "&&", "||", "!", "(", ")", "[", "]", ","}
+
+// tokenize splits the expression into tokens, the last token is always tokenEOF.
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := rune(source[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			text, length, err := readString(source[i:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %s", i+1, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: i + 1})
+			i += length
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(source) && (source[i] == '_' || unicode.IsLetter(rune(source[i])) || unicode.IsDigit(rune(source[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[start:i], pos: start + 1})
+		default:
+			operator := ""
+			for _, op := range operators {
+				if strings.HasPrefix(source[i:], op) {
+					operator = op
+					break
+				}
+			}
+			if operator == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i+1)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: operator, pos: i + 1})
+			i += len(operator)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(source) + 1}), nil
+}
+
+// readString reads a quoted string at the start of s, and returns its value and length in s.
+// Double-quoted strings can contain escape sequences, single-quoted strings are read as they are,
+// which is handy for regexes.
+func readString(s string) (string, int, error) {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quote == '"' {
+				i++
+			}
+		case quote:
+			if quote == '\'' {
+				return s[1:i], i + 1, nil
+			}
+			value, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", 0, err
+			}
+			return value, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("missing closing %c", quote)
+}
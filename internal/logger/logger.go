@@ -2,12 +2,15 @@ package logger
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"log/syslog"
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
 )
@@ -20,28 +23,119 @@ const (
 	ERROR
 )
 
+// Formats of log lines.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Special destinations of the logger. All other destinations are file paths.
+const (
+	DestStdout = "stdout"
+	DestStderr = "stderr"
+	DestSyslog = "syslog"
+)
+
 const logCallDepth = 2
 
+var levelNames = map[int]string{
+	DEBUG:   "DEBUG",
+	INFO:    "INFO",
+	WARNING: "WARNING",
+	ERROR:   "ERROR",
+}
+
 type Logger struct {
 	*log.Logger
 	// Level of the logger (DEBUG, INFO, WARNING, ERROR).
 	level int
+	// format of the log lines, FormatText or FormatJSON. Empty format is FormatText.
+	format string
+	// syslogWriter is set when logging to syslog. Messages are then written
+	// with the syslog priority matching their level.
+	syslogWriter *syslog.Writer
+}
+
+// Options configure the logger created with NewWithOptions.
+type Options struct {
+	// Level of the logger (DEBUG, INFO, WARNING, ERROR).
+	Level int
+	// Dest is DestStdout (or empty), DestStderr, DestSyslog or path of the log file.
+	// Log files are appended to.
+	Dest string
+	// Format of the log lines, FormatText (or empty) or FormatJSON.
+	Format string
+	// SyslogNetwork and SyslogAddress of the syslog server (e.g. udp, syslog.example.com:514).
+	// If SyslogAddress is empty, the local syslog server is used.
+	SyslogNetwork string
+	SyslogAddress string
+	// SyslogTag is the tag of syslog messages. Defaults to netbox-ssot.
+	SyslogTag string
+	// MaxSize is the size of the log file in megabytes, after which it is rotated.
+	// Zero disables rotation.
+	MaxSize int
+	// MaxBackups is the number of rotated log files that are kept.
+	MaxBackups int
 }
 
 // New creates a new Logger instance, which writes to the specified destination (file) or stdout if dest is empty.
 // It also sets the log level.
 func New(dest string, logLevel int) (*Logger, error) {
+	return NewWithOptions(Options{Dest: dest, Level: logLevel})
+}
+
+// NewWithOptions creates a new Logger instance, configured with opts.
+func NewWithOptions(opts Options) (*Logger, error) {
+	logger := &Logger{level: opts.Level, format: opts.Format}
 	var output io.Writer
-	if dest == "" {
+	flags := log.LstdFlags
+	switch opts.Dest {
+	case "", DestStdout:
 		output = os.Stdout
-	} else {
-		file, err := os.Create(dest)
+	case DestStderr:
+		output = os.Stderr
+	case DestSyslog:
+		tag := opts.SyslogTag
+		if tag == "" {
+			tag = "netbox-ssot"
+		}
+		syslogWriter, err := syslog.Dial(opts.SyslogNetwork, opts.SyslogAddress, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+		if err != nil {
+			return nil, fmt.Errorf("connect to syslog: %s", err)
+		}
+		logger.syslogWriter = syslogWriter
+		output = syslogWriter
+		// Syslog adds its own timestamps
+		flags = 0
+	default:
+		file, err := newRotatingFile(opts.Dest, opts.MaxSize, opts.MaxBackups)
 		if err != nil {
 			return nil, err
 		}
 		output = file
 	}
-	return &Logger{log.New(output, "", log.LstdFlags), logLevel}, nil
+	if opts.Format == FormatJSON {
+		// Time is a field of the JSON object
+		flags = 0
+	}
+	logger.Logger = log.New(output, "", flags)
+	return logger, nil
+}
+
+// objectCtxKey is the context key of the object a log message refers to.
+type objectCtxKey struct{}
+
+// logObject is the Netbox object a log message refers to.
+type logObject struct {
+	objectType string
+	id         int
+}
+
+// WithObject returns a context, which marks log messages logged with it as
+// messages about the Netbox object of objectType (e.g. Device) with the given
+// ID. In JSON format they are logged as separate fields.
+func WithObject(ctx context.Context, objectType string, id int) context.Context {
+	return context.WithValue(ctx, objectCtxKey{}, logObject{objectType: objectType, id: id})
 }
 
 // Custom log output function. It is used to add additional runtime information to the log message.
@@ -71,126 +165,131 @@ func (l *Logger) Output(calldepth int, s string) error {
 	return nil
 }
 
+// jsonEntry is a single log line in JSON format.
+type jsonEntry struct {
+	Time       string `json:"time"`
+	Level      string `json:"level"`
+	Source     any    `json:"source,omitempty"`
+	Caller     string `json:"caller"`
+	Message    string `json:"msg"`
+	ObjectType string `json:"object_type,omitempty"`
+	NetboxID   int    `json:"netbox_id,omitempty"`
+}
+
+// outputJSON writes message as a JSON object with source, caller and object
+// of the message as separate fields.
+func (l *Logger) outputJSON(ctx context.Context, calldepth int, level int, message string) error {
+	entry := jsonEntry{
+		Time:    time.Now().Format(time.RFC3339Nano),
+		Level:   levelNames[level],
+		Source:  ctx.Value(constants.CtxSourceKey),
+		Caller:  "???",
+		Message: message,
+	}
+	if _, file, line, ok := runtime.Caller(calldepth); ok {
+		entry.Caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+	if object, ok := ctx.Value(objectCtxKey{}).(logObject); ok {
+		entry.ObjectType = object.objectType
+		entry.NetboxID = object.id
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return l.write(level, string(line))
+}
+
+// write writes the formatted line. Lines written to syslog
+// get the priority matching their level.
+func (l *Logger) write(level int, line string) error {
+	if l.syslogWriter == nil {
+		l.Println(line)
+		return nil
+	}
+	switch level {
+	case DEBUG:
+		return l.syslogWriter.Debug(line)
+	case WARNING:
+		return l.syslogWriter.Warning(line)
+	case ERROR:
+		return l.syslogWriter.Err(line)
+	default:
+		return l.syslogWriter.Info(line)
+	}
+}
+
+// logMessage logs message on the given level. Callers check the level
+// before formatting the message, because formatting can be expensive.
+func (l *Logger) logMessage(ctx context.Context, level int, message string) error {
+	// Caller of the exported logging function
+	calldepth := logCallDepth + 1
+	if l.format == FormatJSON {
+		return l.outputJSON(ctx, calldepth, level, message)
+	}
+	line := fmt.Sprintf("%-7s (%s): %s", levelNames[level], ctx.Value(constants.CtxSourceKey), message)
+	if l.syslogWriter != nil {
+		return l.write(level, line)
+	}
+	return l.Output(calldepth, line)
+}
+
 func (l *Logger) Debug(ctx context.Context, v ...interface{}) error {
-	if l.level <= DEBUG {
-		return l.Output(
-			logCallDepth,
-			fmt.Sprintf(
-				"%-7s (%s): %s",
-				"DEBUG",
-				ctx.Value(constants.CtxSourceKey),
-				fmt.Sprint(v...),
-			),
-		)
+	if l.level > DEBUG {
+		return nil
 	}
-	return nil
+	return l.logMessage(ctx, DEBUG, fmt.Sprint(v...))
 }
 
 // Debugf logs a formatted debug message.
 func (l *Logger) Debugf(ctx context.Context, format string, v ...interface{}) error {
-	if l.level <= DEBUG {
-		return l.Output(
-			logCallDepth,
-			fmt.Sprintf(
-				"%-7s (%s): %s",
-				"DEBUG",
-				ctx.Value(constants.CtxSourceKey),
-				fmt.Sprintf(format, v...),
-			),
-		)
+	if l.level > DEBUG {
+		return nil
 	}
-	return nil
+	return l.logMessage(ctx, DEBUG, fmt.Sprintf(format, v...))
 }
 
 func (l *Logger) Info(ctx context.Context, v ...interface{}) error {
-	if l.level <= INFO {
-		return l.Output(
-			logCallDepth,
-			fmt.Sprintf(
-				"%-7s (%s): %s",
-				"INFO",
-				ctx.Value(constants.CtxSourceKey),
-				fmt.Sprint(v...),
-			),
-		)
+	if l.level > INFO {
+		return nil
 	}
-	return nil
+	return l.logMessage(ctx, INFO, fmt.Sprint(v...))
 }
 
 // Infof logs a formatted info message.
 func (l *Logger) Infof(ctx context.Context, format string, v ...interface{}) error {
-	if l.level <= INFO {
-		return l.Output(
-			logCallDepth,
-			fmt.Sprintf(
-				"%-7s (%s): %s",
-				"INFO",
-				ctx.Value(constants.CtxSourceKey),
-				fmt.Sprintf(format, v...),
-			),
-		)
+	if l.level > INFO {
+		return nil
 	}
-	return nil
+	return l.logMessage(ctx, INFO, fmt.Sprintf(format, v...))
 }
 
 func (l *Logger) Warning(ctx context.Context, v ...interface{}) error {
-	if l.level <= WARNING {
-		return l.Output(
-			logCallDepth,
-			fmt.Sprintf(
-				"%-7s (%s): %s",
-				"WARNING",
-				ctx.Value(constants.CtxSourceKey),
-				fmt.Sprint(v...),
-			),
-		)
+	if l.level > WARNING {
+		return nil
 	}
-	return nil
+	return l.logMessage(ctx, WARNING, fmt.Sprint(v...))
 }
 
 // Warningf logs a formatted warning message.
 func (l *Logger) Warningf(ctx context.Context, format string, v ...interface{}) error {
-	if l.level <= WARNING {
-		return l.Output(
-			logCallDepth,
-			fmt.Sprintf(
-				"%-7s (%s): %s",
-				"WARNING",
-				ctx.Value(constants.CtxSourceKey),
-				fmt.Sprintf(format, v...),
-			),
-		)
+	if l.level > WARNING {
+		return nil
 	}
-	return nil
+	return l.logMessage(ctx, WARNING, fmt.Sprintf(format, v...))
 }
 
 func (l *Logger) Error(ctx context.Context, v ...interface{}) error {
-	if l.level <= ERROR {
-		return l.Output(
-			logCallDepth,
-			fmt.Sprintf(
-				"%-7s (%s): %s",
-				"ERROR",
-				ctx.Value(constants.CtxSourceKey),
-				fmt.Sprint(v...),
-			),
-		)
+	if l.level > ERROR {
+		return nil
 	}
-	return nil
+	return l.logMessage(ctx, ERROR, fmt.Sprint(v...))
 }
 
 // Errorf logs a formatted error message.
 func (l *Logger) Errorf(ctx context.Context, format string, v ...interface{}) error {
-	if l.level <= ERROR {
-		return l.Output(
-			logCallDepth,
-			fmt.Sprintf(
-				"%-7s (%s): %s",
-				"ERROR",
-				ctx.Value(constants.CtxSourceKey),
-				fmt.Sprintf(format, v...),
-			),
-		)
+	if l.level > ERROR {
+		return nil
 	}
-	return nil
+	return l.logMessage(ctx, ERROR, fmt.Sprintf(format, v...))
 }
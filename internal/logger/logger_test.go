@@ -3,8 +3,13 @@ package logger
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
 )
@@ -197,3 +202,90 @@ func TestOutputUnknownCaller(t *testing.T) {
 		t.Errorf("Expected \"???\" in output, but didn't get it")
 	}
 }
+
+func TestJSONFormat(t *testing.T) {
+	testCtx := context.WithValue(context.Background(), constants.CtxSourceKey, "vmware")
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want map[string]any
+	}{
+		{
+			name: "Message with source",
+			ctx:  testCtx,
+			want: map[string]any{"level": "WARNING", "source": "vmware", "msg": "Test 1"},
+		},
+		{
+			name: "Message with object",
+			ctx:  WithObject(testCtx, "Device", 42),
+			want: map[string]any{
+				"level":       "WARNING",
+				"source":      "vmware",
+				"msg":         "Test 1",
+				"object_type": "Device",
+				"netbox_id":   float64(42),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewWithOptions(Options{Level: DEBUG, Format: FormatJSON})
+			if err != nil {
+				t.Fatalf("Error creating logger: %v", err)
+			}
+			buf := new(bytes.Buffer)
+			l.SetOutput(buf)
+			l.Warningf(tt.ctx, "Test %d", 1)
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Output %q is not valid JSON: %s", buf.String(), err)
+			}
+			if caller, _ := got["caller"].(string); !strings.HasPrefix(caller, "logger_test.go:") {
+				t.Errorf("caller = %v, want logger_test.go", got["caller"])
+			}
+			if _, err := time.Parse(time.RFC3339Nano, got["time"].(string)); err != nil {
+				t.Errorf("time = %v is not in RFC3339 format", got["time"])
+			}
+			delete(got, "caller")
+			delete(got, "time")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("JSON log = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextFormatCaller(t *testing.T) {
+	testCtx := context.WithValue(context.Background(), constants.CtxSourceKey, "test")
+	l, err := New("", DEBUG)
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	l.SetOutput(buf)
+	l.Info(WithObject(testCtx, "Device", 42), "Test INFO")
+	output := buf.String()
+	if !strings.Contains(output, "logger_test.go:") || !strings.Contains(output, "INFO    (test): Test INFO") {
+		t.Errorf("Unexpected output %q", output)
+	}
+}
+
+func TestLoggerAppendsToFile(t *testing.T) {
+	testCtx := context.WithValue(context.Background(), constants.CtxSourceKey, "test")
+	path := filepath.Join(t.TempDir(), "netbox-ssot.log")
+	for _, message := range []string{"first run", "second run"} {
+		l, err := New(path, INFO)
+		if err != nil {
+			t.Fatalf("Error creating logger: %v", err)
+		}
+		l.Info(testCtx, message)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "first run") || !strings.Contains(string(content), "second run") {
+		t.Errorf("Log file doesn't contain logs of both runs: %q", content)
+	}
+}
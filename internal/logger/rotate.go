@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+const bytesInMegabyte = 1024 * 1024
+
+// rotatingFile is a log file, which is rotated when it reaches maxSize bytes.
+// Rotated files are renamed to <path>.1, <path>.2, ... where <path>.1 is the
+// newest. Only maxBackups rotated files are kept.
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+// newRotatingFile opens the log file at path for appending. File is rotated
+// after it reaches maxSizeMB megabytes, or never if maxSizeMB is 0.
+func newRotatingFile(path string, maxSizeMB int, maxBackups int) (*rotatingFile, error) {
+	rf := &rotatingFile{
+		path:       path,
+		maxSize:    int64(maxSizeMB) * bytesInMegabyte,
+		maxBackups: maxBackups,
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// open opens the log file for appending and reads its current size.
+func (rf *rotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644) //nolint:mnd,gosec
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rf.file = file
+	rf.size = info.Size()
+	return nil
+}
+
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.lock.Lock()
+	defer rf.lock.Unlock()
+	var rotateErr error
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		// If rotation fails, p is still written to the reopened log file
+		if rotateErr = rf.rotate(); rotateErr != nil {
+			rotateErr = fmt.Errorf("rotate log file: %s", rotateErr)
+		}
+	}
+	if rf.file == nil {
+		return 0, rotateErr
+	}
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, rotateErr
+}
+
+// rotate closes the current log file, shifts the rotated files and
+// opens a new empty log file. If the files can't be shifted, the current
+// log file is reopened for appending, so logging continues.
+func (rf *rotatingFile) rotate() error {
+	closeErr := rf.file.Close()
+	rf.file = nil
+	if closeErr == nil {
+		closeErr = rf.shiftBackups()
+	}
+	if err := rf.open(); err != nil {
+		return errors.Join(closeErr, err)
+	}
+	return closeErr
+}
+
+// shiftBackups renames the log file and its rotated files to the next backup path.
+func (rf *rotatingFile) shiftBackups() error {
+	if rf.maxBackups > 0 {
+		// The oldest backup is overwritten by the next one
+		for i := rf.maxBackups - 1; i > 0; i-- {
+			err := os.Rename(rf.backupPath(i), rf.backupPath(i+1))
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+		return os.Rename(rf.path, rf.backupPath(1))
+	}
+	return os.Remove(rf.path)
+}
+
+func (rf *rotatingFile) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", rf.path, i)
+}
@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"os"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxBackups  int
+		writes      []string
+		wantFiles   map[string]string
+		wantMissing []string
+	}{
+		{
+			name:       "Without rotation",
+			maxBackups: 2,
+			writes:     []string{"aaaa\n"},
+			wantFiles:  map[string]string{"": "aaaa\n"},
+		},
+		{
+			name:       "Rotation keeps backups",
+			maxBackups: 2,
+			writes:     []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n"},
+			wantFiles: map[string]string{
+				"":   "dddd\n",
+				".1": "cccc\n",
+				".2": "bbbb\n",
+			},
+			wantMissing: []string{".3"},
+		},
+		{
+			name:        "Rotation without backups",
+			maxBackups:  0,
+			writes:      []string{"aaaa\n", "bbbb\n"},
+			wantFiles:   map[string]string{"": "bbbb\n"},
+			wantMissing: []string{".1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := t.TempDir() + "/test.log"
+			rf, err := newRotatingFile(path, 0, tt.maxBackups)
+			if err != nil {
+				t.Fatalf("newRotatingFile() error = %s", err)
+			}
+			// Each write fills the whole file
+			rf.maxSize = 8
+			for _, write := range tt.writes {
+				if _, err := rf.Write([]byte(write)); err != nil {
+					t.Fatalf("Write() error = %s", err)
+				}
+			}
+			for suffix, want := range tt.wantFiles {
+				content, err := os.ReadFile(path + suffix)
+				if err != nil {
+					t.Fatalf("read %s: %s", path+suffix, err)
+				}
+				if string(content) != want {
+					t.Errorf("%s content = %q, want %q", path+suffix, content, want)
+				}
+			}
+			for _, suffix := range tt.wantMissing {
+				if _, err := os.Stat(path + suffix); err == nil {
+					t.Errorf("%s exists, but it should have been removed", path+suffix)
+				}
+			}
+		})
+	}
+}
+
+func TestRotatingFileAppends(t *testing.T) {
+	path := t.TempDir() + "/test.log"
+	if err := os.WriteFile(path, []byte("previous run\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rf, err := newRotatingFile(path, 1, 1)
+	if err != nil {
+		t.Fatalf("newRotatingFile() error = %s", err)
+	}
+	if rf.size != int64(len("previous run\n")) {
+		t.Errorf("size = %d, want size of the existing file", rf.size)
+	}
+	rf.Write([]byte("this run\n"))
+	content, _ := os.ReadFile(path)
+	if string(content) != "previous run\nthis run\n" {
+		t.Errorf("content = %q, want appended content", content)
+	}
+}
+
+func TestRotatingFileRotationFails(t *testing.T) {
+	path := t.TempDir() + "/test.log"
+	rf, err := newRotatingFile(path, 0, 1)
+	if err != nil {
+		t.Fatalf("newRotatingFile() error = %s", err)
+	}
+	rf.maxSize = 8
+	// Log file can't be renamed to a non empty directory
+	if err := os.MkdirAll(path+".1/dir", 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rf.Write([]byte("aaaa\n")); err != nil {
+		t.Fatalf("Write() error = %s", err)
+	}
+	if _, err := rf.Write([]byte("bbbb\n")); err == nil {
+		t.Errorf("Write() error = nil, want rotation error")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %s", path, err)
+	}
+	// Messages are still written to the current log file
+	if string(content) != "aaaa\nbbbb\n" {
+		t.Errorf("%s content = %q, want %q", path, content, "aaaa\nbbbb\n")
+	}
+}
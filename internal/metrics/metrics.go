@@ -0,0 +1,249 @@
+// Package metrics exposes Prometheus metrics of synchronization runs,
+// sources and Netbox API usage. Metrics are either served on an HTTP
+// listener, or pushed to a Prometheus Pushgateway after each run.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/report"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// Namespace of all netbox-ssot metrics.
+const namespace = "netbox_ssot"
+
+// Time given to in-flight scrapes when the server is shutting down.
+const shutdownTimeout = 5 * time.Second
+
+// Phases of a source run.
+const (
+	PhaseInit = "init"
+	PhaseSync = "sync"
+)
+
+// Metrics holds all netbox-ssot metrics. It is safe for concurrent use.
+// All methods of nil Metrics are no-ops, so metrics can be disabled by
+// passing nil.
+type Metrics struct {
+	// Registry contains all netbox-ssot metrics. Go runtime and process
+	// metrics are added only when metrics are served.
+	Registry *prometheus.Registry
+
+	runs             *prometheus.CounterVec
+	runDuration      prometheus.Gauge
+	runSuccess       prometheus.Gauge
+	runTimestamp     prometheus.Gauge
+	sourceDuration   *prometheus.GaugeVec
+	sourceSuccess    *prometheus.GaugeVec
+	sourceLastOK     *prometheus.GaugeVec
+	objectChanges    *prometheus.CounterVec
+	orphanCandidates *prometheus.GaugeVec
+	requests         *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+}
+
+// New creates Metrics with a new registry.
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "runs_total",
+			Help:      "Number of finished synchronization runs by status.",
+		}, []string{"status"}),
+		runDuration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_run_duration_seconds",
+			Help:      "Duration of the last synchronization run.",
+		}),
+		runSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_run_success",
+			Help:      "Whether the last synchronization run succeeded (1) or not (0).",
+		}),
+		runTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_run_timestamp_seconds",
+			Help:      "Unix time when the last synchronization run finished.",
+		}),
+		sourceDuration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "source_duration_seconds",
+			Help:      "Duration of the init and sync phase of the source in its last run.",
+		}, []string{"source", "phase"}),
+		sourceSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "source_success",
+			Help:      "Whether the last run of the source succeeded (1) or not (0).",
+		}, []string{"source"}),
+		sourceLastOK: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "source_last_success_timestamp_seconds",
+			Help:      "Unix time of the last successful sync of the source.",
+		}, []string{"source"}),
+		objectChanges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "object_changes_total",
+			Help:      "Number of changes of Netbox objects by action, object type and source.",
+		}, []string{"action", "object_type", "source"}),
+		orphanCandidates: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "orphan_candidates",
+			Help:      "Number of managed objects, that weren't found in their source in the last run.",
+		}, []string{"object_type", "source"}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "netbox_requests_total",
+			Help:      "Number of requests sent to the Netbox API by method and status code.",
+		}, []string{"method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "netbox_request_duration_seconds",
+			Help:      "Latency of requests sent to the Netbox API by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+	}
+	m.Registry.MustRegister(
+		m.runs,
+		m.runDuration,
+		m.runSuccess,
+		m.runTimestamp,
+		m.sourceDuration,
+		m.sourceSuccess,
+		m.sourceLastOK,
+		m.objectChanges,
+		m.orphanCandidates,
+		m.requests,
+		m.requestDuration,
+	)
+	return m
+}
+
+// ObserveRun records the outcome of a finished synchronization run.
+func (m *Metrics) ObserveRun(status string, duration time.Duration, successful bool, endTime time.Time) {
+	if m == nil {
+		return
+	}
+	m.runs.WithLabelValues(status).Inc()
+	m.runDuration.Set(duration.Seconds())
+	m.runSuccess.Set(boolToFloat(successful))
+	m.runTimestamp.Set(float64(endTime.Unix()))
+}
+
+// ObserveSourcePhase records duration of the phase (PhaseInit or PhaseSync) of the source.
+func (m *Metrics) ObserveSourcePhase(sourceName string, phase string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.sourceDuration.WithLabelValues(sourceName, phase).Set(duration.Seconds())
+}
+
+// ObserveSourceResult records the outcome of a source's run, which finished at endTime.
+func (m *Metrics) ObserveSourceResult(sourceName string, successful bool, endTime time.Time) {
+	if m == nil {
+		return
+	}
+	m.sourceSuccess.WithLabelValues(sourceName).Set(boolToFloat(successful))
+	if successful {
+		m.sourceLastOK.WithLabelValues(sourceName).Set(float64(endTime.Unix()))
+	}
+}
+
+// ObserveChanges counts changes of Netbox objects made during a run.
+func (m *Metrics) ObserveChanges(changes []report.Change) {
+	if m == nil {
+		return
+	}
+	for _, change := range changes {
+		m.objectChanges.WithLabelValues(string(change.Action), change.ObjectType, change.Source).Inc()
+	}
+}
+
+// SetOrphanCandidates sets number of orphan candidates of the given sources.
+// Candidates are indexed by source name and object type. Previous values
+// of the sources are removed, so object types without candidates disappear.
+func (m *Metrics) SetOrphanCandidates(sourceNames []string, candidates map[string]map[string]int) {
+	if m == nil {
+		return
+	}
+	for _, sourceName := range sourceNames {
+		m.orphanCandidates.DeletePartialMatch(prometheus.Labels{"source": sourceName})
+		for objectType, count := range candidates[sourceName] {
+			m.orphanCandidates.WithLabelValues(objectType, sourceName).Set(float64(count))
+		}
+	}
+}
+
+// ObserveRequest records a request sent to the Netbox API. Status code 0
+// means the request failed without a response (e.g. transport error).
+func (m *Metrics) ObserveRequest(method string, statusCode int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	status := "error"
+	if statusCode != 0 {
+		status = strconv.Itoa(statusCode)
+	}
+	m.requests.WithLabelValues(method, status).Inc()
+	m.requestDuration.WithLabelValues(method).Observe(duration.Seconds())
+}
+
+// Handler returns the http.Handler serving metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{})
+}
+
+// ListenAndServe serves metrics at /metrics on address until ctx is canceled.
+// Go runtime and process metrics are served as well.
+func (m *Metrics) ListenAndServe(ctx context.Context, address string) error {
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m.Handler())
+	server := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: shutdownTimeout,
+	}
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-errChan:
+		return fmt.Errorf("metrics server: %s", err)
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return server.Shutdown(shutdownCtx) //nolint:contextcheck
+	}
+}
+
+// Push pushes all metrics to the Pushgateway at url, replacing
+// metrics previously pushed with the same job.
+func (m *Metrics) Push(ctx context.Context, url string, job string) error {
+	if m == nil {
+		return nil
+	}
+	if err := push.New(url, job).Gatherer(m.Registry).PushContext(ctx); err != nil {
+		return fmt.Errorf("push metrics: %s", err)
+	}
+	return nil
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/report"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics_ObserveRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		statusCode int
+		wantStatus string
+	}{
+		{name: "Successful request", method: http.MethodGet, statusCode: http.StatusOK, wantStatus: "200"},
+		{name: "Failed request", method: http.MethodPatch, statusCode: http.StatusBadRequest, wantStatus: "400"},
+		{name: "Request without response", method: http.MethodPost, statusCode: 0, wantStatus: "error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			m.ObserveRequest(tt.method, tt.statusCode, time.Second)
+			if got := testutil.ToFloat64(m.requests.WithLabelValues(tt.method, tt.wantStatus)); got != 1 {
+				t.Errorf("netbox_requests_total{method=%q,status=%q} = %g, want 1", tt.method, tt.wantStatus, got)
+			}
+			if got := testutil.CollectAndCount(m.requestDuration); got != 1 {
+				t.Errorf("netbox_request_duration_seconds has %d series, want 1", got)
+			}
+		})
+	}
+}
+
+func TestMetrics_ObserveChanges(t *testing.T) {
+	m := New()
+	m.ObserveChanges([]report.Change{
+		{Action: report.ActionCreate, ObjectType: "Device", Source: "vmware"},
+		{Action: report.ActionCreate, ObjectType: "Device", Source: "vmware"},
+		{Action: report.ActionUpdate, ObjectType: "VM", Source: "ovirt"},
+	})
+	if got := testutil.ToFloat64(m.objectChanges.WithLabelValues("create", "Device", "vmware")); got != 2 {
+		t.Errorf("object_changes_total{action=create,object_type=Device} = %g, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.objectChanges.WithLabelValues("update", "VM", "ovirt")); got != 1 {
+		t.Errorf("object_changes_total{action=update,object_type=VM} = %g, want 1", got)
+	}
+}
+
+func TestMetrics_SetOrphanCandidates(t *testing.T) {
+	m := New()
+	m.SetOrphanCandidates([]string{"vmware", "ovirt"}, map[string]map[string]int{
+		"vmware": {"Device": 2, "VM": 5},
+		"ovirt":  {"VM": 1},
+	})
+	// Object types without candidates are removed, other sources are kept
+	m.SetOrphanCandidates([]string{"vmware"}, map[string]map[string]int{
+		"vmware": {"VM": 3},
+	})
+	if got := testutil.CollectAndCount(m.orphanCandidates); got != 2 {
+		t.Errorf("orphan_candidates has %d series, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.orphanCandidates.WithLabelValues("VM", "vmware")); got != 3 {
+		t.Errorf("orphan_candidates{object_type=VM,source=vmware} = %g, want 3", got)
+	}
+	if got := testutil.ToFloat64(m.orphanCandidates.WithLabelValues("VM", "ovirt")); got != 1 {
+		t.Errorf("orphan_candidates{object_type=VM,source=ovirt} = %g, want 1", got)
+	}
+}
+
+func TestMetrics_Nil(_ *testing.T) {
+	var m *Metrics
+	m.ObserveRun("succeeded", time.Second, true, time.Now())
+	m.ObserveSourcePhase("vmware", PhaseInit, time.Second)
+	m.ObserveSourceResult("vmware", true, time.Now())
+	m.ObserveChanges([]report.Change{{Action: report.ActionCreate}})
+	m.SetOrphanCandidates([]string{"vmware"}, nil)
+	m.ObserveRequest(http.MethodGet, http.StatusOK, time.Second)
+}
+
+func TestMetrics_Push(t *testing.T) {
+	var gotPath, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	m := New()
+	m.ObserveRun("succeeded", time.Minute, true, time.Now())
+	if err := m.Push(context.Background(), server.URL, "netbox-ssot"); err != nil {
+		t.Fatalf("Push() error = %s", err)
+	}
+	if gotPath != "/metrics/job/netbox-ssot" {
+		t.Errorf("Push() path = %s, want /metrics/job/netbox-ssot", gotPath)
+	}
+	if !strings.Contains(gotBody, "netbox_ssot_last_run_duration_seconds") {
+		t.Errorf("Push() didn't push netbox_ssot_last_run_duration_seconds")
+	}
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	m := New()
+	m.ObserveSourcePhase("vmware", PhaseSync, 2*time.Second)
+	m.ObserveSourceResult("vmware", false, time.Now())
+
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := recorder.Body.String()
+	for _, want := range []string{
+		`netbox_ssot_source_duration_seconds{phase="sync",source="vmware"} 2`,
+		`netbox_ssot_source_success{source="vmware"} 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Handler() response doesn't contain %s", want)
+		}
+	}
+	if strings.Contains(body, "netbox_ssot_source_last_success_timestamp_seconds{") {
+		t.Errorf("Handler() response contains last success of a source that never succeeded")
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/bl4ko/netbox-ssot/internal/constants"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/service"
-	"github.com/bl4ko/netbox-ssot/internal/utils"
 )
 
 // AddTag adds the newTag from source sourceName to the local inventory.
@@ -18,13 +17,13 @@ func (nbi *NetboxInventory) AddTag(ctx context.Context, newTag *objects.Tag) (*o
 	defer nbi.tagsLock.Unlock()
 	if _, ok := nbi.tagsIndexByName[newTag.Name]; ok {
 		oldTag := nbi.tagsIndexByName[newTag.Name]
-		diffMap, err := utils.JSONDiffMapExceptID(newTag, oldTag, false, nbi.SourcePriority)
+		diffMap, err := nbi.diffMap(ctx, newTag, oldTag)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldTag),
 				"Tag %s already exists in Netbox but is out of date. Patching it... ",
 				newTag.Name,
 			)
@@ -34,7 +33,11 @@ func (nbi *NetboxInventory) AddTag(ctx context.Context, newTag *objects.Tag) (*o
 			}
 			nbi.tagsIndexByName[newTag.Name] = patchedTag
 		} else {
-			nbi.Logger.Debugf(ctx, "Tag %s already exists in Netbox and is up to date...", newTag.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldTag),
+				"Tag %s already exists in Netbox and is up to date...",
+				newTag.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "Tag %s does not exist in Netbox. Creating it...", newTag.Name)
@@ -57,13 +60,13 @@ func (nbi *NetboxInventory) AddTenant(
 	defer nbi.tenantsLock.Unlock()
 	if _, ok := nbi.tenantsIndexByName[newTenant.Name]; ok {
 		oldTenant := nbi.tenantsIndexByName[newTenant.Name]
-		diffMap, err := utils.JSONDiffMapExceptID(newTenant, oldTenant, false, nbi.SourcePriority)
+		diffMap, err := nbi.diffMap(ctx, newTenant, oldTenant)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldTenant),
 				"Tenant %s already exists in Netbox but is out of date. Patching it...",
 				newTenant.Name,
 			)
@@ -78,7 +81,11 @@ func (nbi *NetboxInventory) AddTenant(
 			}
 			nbi.tenantsIndexByName[newTenant.Name] = patchedTenant
 		} else {
-			nbi.Logger.Debugf(ctx, "Tenant %s already exists in Netbox and is up to date...", newTenant.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldTenant),
+				"Tenant %s already exists in Netbox and is up to date...",
+				newTenant.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "Tenant %s does not exist in Netbox. Creating it...", newTenant.Name)
@@ -101,13 +108,13 @@ func (nbi *NetboxInventory) AddSite(
 	defer nbi.sitesLock.Unlock()
 	if _, ok := nbi.sitesIndexByName[newSite.Name]; ok {
 		oldSite := nbi.sitesIndexByName[newSite.Name]
-		diffMap, err := utils.JSONDiffMapExceptID(newSite, oldSite, false, nbi.SourcePriority)
+		diffMap, err := nbi.diffMap(ctx, newSite, oldSite)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldSite),
 				"Site %s already exists in Netbox but is out of date. Patching it... ",
 				newSite.Name,
 			)
@@ -117,7 +124,11 @@ func (nbi *NetboxInventory) AddSite(
 			}
 			nbi.sitesIndexByName[newSite.Name] = patchedSite
 		} else {
-			nbi.Logger.Debugf(ctx, "Site %s already exists in Netbox and is up to date...", newSite.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldSite),
+				"Site %s already exists in Netbox and is up to date...",
+				newSite.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "Site %s does not exist in Netbox. Creating it...", newSite.Name)
@@ -140,19 +151,27 @@ func (nbi *NetboxInventory) AddLocation(
 	defer nbi.locationsLock.Unlock()
 	if _, ok := nbi.locationsIndexByName[newLocation.Name]; ok {
 		oldLocation := nbi.locationsIndexByName[newLocation.Name]
-		diffMap, err := utils.JSONDiffMapExceptID(newLocation, oldLocation, false, nbi.SourcePriority)
+		diffMap, err := nbi.diffMap(ctx, newLocation, oldLocation)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
-			nbi.Logger.Debugf(ctx, "Location %s already exists in Netbox but is out of date. Patching it...", newLocation.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldLocation),
+				"Location %s already exists in Netbox but is out of date. Patching it...",
+				newLocation.Name,
+			)
 			patchedLocation, err := service.Patch[objects.Location](ctx, nbi.NetboxAPI, oldLocation.ID, diffMap)
 			if err != nil {
 				return nil, err
 			}
 			nbi.locationsIndexByName[newLocation.Name] = patchedLocation
 		} else {
-			nbi.Logger.Debugf(ctx, "Location %s already exists in Netbox and is up to date...", newLocation.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldLocation),
+				"Location %s already exists in Netbox and is up to date...",
+				newLocation.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "Location %s does not exist in Netbox. Creating it...", newLocation.Name)
@@ -175,18 +194,17 @@ func (nbi *NetboxInventory) AddSiteGroup(
 	defer nbi.sitesLock.Unlock()
 	if _, ok := nbi.siteGroupsIndexByName[newSiteGroup.Name]; ok {
 		oldSiteGroup := nbi.siteGroupsIndexByName[newSiteGroup.Name]
-		diffMap, err := utils.JSONDiffMapExceptID(
+		diffMap, err := nbi.diffMap(
+			ctx,
 			newSiteGroup,
 			oldSiteGroup,
-			false,
-			nbi.SourcePriority,
 		)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldSiteGroup),
 				"SiteGroup %s already exists in Netbox but is out of date. Patching it...",
 				newSiteGroup.Name,
 			)
@@ -201,7 +219,11 @@ func (nbi *NetboxInventory) AddSiteGroup(
 			}
 			nbi.siteGroupsIndexByName[newSiteGroup.Name] = patchedSiteGroup
 		} else {
-			nbi.Logger.Debugf(ctx, "SiteGroup %s already exists in Netbox and is up to date...", newSiteGroup.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldSiteGroup),
+				"SiteGroup %s already exists in Netbox and is up to date...",
+				newSiteGroup.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "SiteGroup %s does not exist in Netbox. Creating it...", newSiteGroup.Name)
@@ -225,18 +247,17 @@ func (nbi *NetboxInventory) AddContactRole(
 	defer nbi.contactRolesLock.Unlock()
 	if _, ok := nbi.contactRolesIndexByName[newContactRole.Name]; ok {
 		oldContactRole := nbi.contactRolesIndexByName[newContactRole.Name]
-		diffMap, err := utils.JSONDiffMapExceptID(
+		diffMap, err := nbi.diffMap(
+			ctx,
 			newContactRole,
 			oldContactRole,
-			false,
-			nbi.SourcePriority,
 		)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldContactRole),
 				"Contact role %s already exists in Netbox but is out of date. Patching it...",
 				newContactRole.Name,
 			)
@@ -251,7 +272,11 @@ func (nbi *NetboxInventory) AddContactRole(
 			}
 			nbi.contactRolesIndexByName[newContactRole.Name] = patchedContactRole
 		} else {
-			nbi.Logger.Debugf(ctx, "Contact role %s already exists in Netbox and is up to date...", newContactRole.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldContactRole),
+				"Contact role %s already exists in Netbox and is up to date...",
+				newContactRole.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "Contact role %s does not exist in Netbox. Creating it...", newContactRole.Name)
@@ -274,18 +299,17 @@ func (nbi *NetboxInventory) AddContactGroup(
 	defer nbi.contactGroupsLock.Unlock()
 	if _, ok := nbi.contactGroupsIndexByName[newContactGroup.Name]; ok {
 		oldContactGroup := nbi.contactGroupsIndexByName[newContactGroup.Name]
-		diffMap, err := utils.JSONDiffMapExceptID(
+		diffMap, err := nbi.diffMap(
+			ctx,
 			newContactGroup,
 			oldContactGroup,
-			false,
-			nbi.SourcePriority,
 		)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldContactGroup),
 				"Contact group %s already exists in Netbox but is out of date. Patching it...",
 				newContactGroup.Name,
 			)
@@ -300,7 +324,11 @@ func (nbi *NetboxInventory) AddContactGroup(
 			}
 			nbi.contactGroupsIndexByName[newContactGroup.Name] = patchedContactGroup
 		} else {
-			nbi.Logger.Debugf(ctx, "Contact group %s already exists in Netbox and is up to date...", newContactGroup.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldContactGroup),
+				"Contact group %s already exists in Netbox and is up to date...",
+				newContactGroup.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "Contact group %s does not exist in Netbox. Creating it...", newContactGroup.Name)
@@ -325,13 +353,13 @@ func (nbi *NetboxInventory) AddContact(
 	if _, ok := nbi.contactsIndexByName[newContact.Name]; ok {
 		oldContact := nbi.contactsIndexByName[newContact.Name]
 		nbi.OrphanManager.RemoveItem(oldContact)
-		diffMap, err := utils.JSONDiffMapExceptID(newContact, oldContact, false, nbi.SourcePriority)
+		diffMap, err := nbi.diffMap(ctx, newContact, oldContact)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldContact),
 				"Contact %s already exists in Netbox but is out of date. Patching it...",
 				newContact.Name,
 			)
@@ -346,7 +374,11 @@ func (nbi *NetboxInventory) AddContact(
 			}
 			nbi.contactsIndexByName[newContact.Name] = patchedContact
 		} else {
-			nbi.Logger.Debugf(ctx, "Contact %s already exists in Netbox and is up to date...", newContact.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldContact),
+				"Contact %s already exists in Netbox and is up to date...",
+				newContact.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "Contact %s does not exist in Netbox. Creating it...", newContact.Name)
@@ -384,16 +416,21 @@ func (nbi *NetboxInventory) AddContactAssignment(
 		)
 	}
 	newCA.Tags = append(newCA.Tags, nbi.SsotTag)
+	if _, ok := nbi.contactAssignmentsIndex[newCA.ModelType][newCA.ObjectID][newCA.Contact.ID][newCA.Role.ID]; !ok {
+		if err := nbi.indexUntaggedContactAssignment(ctx, newCA); err != nil {
+			return nil, err
+		}
+	}
 	if _, ok := nbi.contactAssignmentsIndex[newCA.ModelType][newCA.ObjectID][newCA.Contact.ID][newCA.Role.ID]; ok {
 		oldCA := nbi.contactAssignmentsIndex[newCA.ModelType][newCA.ObjectID][newCA.Contact.ID][newCA.Role.ID]
 		nbi.OrphanManager.RemoveItem(oldCA)
-		diffMap, err := utils.JSONDiffMapExceptID(newCA, oldCA, false, nbi.SourcePriority)
+		diffMap, err := nbi.diffMap(ctx, newCA, oldCA)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldCA),
 				"ContactAssignment %d already exists in Netbox but is out of date. Patching it...",
 				newCA.ID,
 			)
@@ -408,7 +445,11 @@ func (nbi *NetboxInventory) AddContactAssignment(
 			}
 			nbi.contactAssignmentsIndex[newCA.ModelType][newCA.ObjectID][newCA.Contact.ID][newCA.Role.ID] = patchedCA
 		} else {
-			nbi.Logger.Debugf(ctx, "ContactAssignment %d already exists in Netbox and is up to date...", newCA.ID)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldCA),
+				"ContactAssignment %d already exists in Netbox and is up to date...",
+				newCA.ID,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "ContactAssignment %s does not exist in Netbox. Creating it...", newCA)
@@ -434,13 +475,13 @@ func (nbi *NetboxInventory) AddCustomField(
 	defer nbi.customFieldsLock.Unlock()
 	if _, ok := nbi.customFieldsIndexByName[newCf.Name]; ok {
 		oldCustomField := nbi.customFieldsIndexByName[newCf.Name]
-		diffMap, err := utils.JSONDiffMapExceptID(newCf, oldCustomField, false, nbi.SourcePriority)
+		diffMap, err := nbi.diffMap(ctx, newCf, oldCustomField)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldCustomField),
 				"Custom field %s already exists in Netbox but is out of date. Patching it...",
 				newCf.Name,
 			)
@@ -455,7 +496,11 @@ func (nbi *NetboxInventory) AddCustomField(
 			}
 			nbi.customFieldsIndexByName[newCf.Name] = patchedCf
 		} else {
-			nbi.Logger.Debugf(ctx, "Custom field %s already exists in Netbox and is up to date...", newCf.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldCustomField),
+				"Custom field %s already exists in Netbox and is up to date...",
+				newCf.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "Custom field %s does not exist in Netbox. Creating it...", newCf.Name)
@@ -485,13 +530,13 @@ func (nbi *NetboxInventory) AddClusterGroup(
 	if _, ok := nbi.clusterGroupsIndexByName[newCg.Name]; ok {
 		oldCg := nbi.clusterGroupsIndexByName[newCg.Name]
 		nbi.OrphanManager.RemoveItem(oldCg)
-		diffMap, err := utils.JSONDiffMapExceptID(newCg, oldCg, false, nbi.SourcePriority)
+		diffMap, err := nbi.diffMap(ctx, newCg, oldCg)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldCg),
 				"Cluster group %s already exists in Netbox but is out of date. Patching it...",
 				newCg.Name,
 			)
@@ -506,7 +551,11 @@ func (nbi *NetboxInventory) AddClusterGroup(
 			}
 			nbi.clusterGroupsIndexByName[newCg.Name] = patchedCg
 		} else {
-			nbi.Logger.Debugf(ctx, "Cluster group %s already exists in Netbox and is up to date...", newCg.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldCg),
+				"Cluster group %s already exists in Netbox and is up to date...",
+				newCg.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "Cluster group %s does not exist in Netbox. Creating it...", newCg.Name)
@@ -537,18 +586,17 @@ func (nbi *NetboxInventory) AddClusterType(
 	if _, ok := nbi.clusterTypesIndexByName[newClusterType.Name]; ok {
 		oldClusterType := nbi.clusterTypesIndexByName[newClusterType.Name]
 		nbi.OrphanManager.RemoveItem(oldClusterType)
-		diffMap, err := utils.JSONDiffMapExceptID(
+		diffMap, err := nbi.diffMap(
+			ctx,
 			newClusterType,
 			oldClusterType,
-			false,
-			nbi.SourcePriority,
 		)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldClusterType),
 				"Cluster type %s already exists in Netbox but is out of date. Patching it...",
 				newClusterType.Name,
 			)
@@ -565,7 +613,7 @@ func (nbi *NetboxInventory) AddClusterType(
 			return patchedClusterType, nil
 		}
 		nbi.Logger.Debugf(
-			ctx,
+			objectLogCtx(ctx, oldClusterType),
 			"Cluster type %s already exists in Netbox and is up to date...",
 			newClusterType.Name,
 		)
@@ -604,13 +652,13 @@ func (nbi *NetboxInventory) AddCluster(
 		// Remove id from orphan manager, because it still exists in the sources
 		oldCluster := nbi.clustersIndexByName[newCluster.Name]
 		nbi.OrphanManager.RemoveItem(oldCluster)
-		diffMap, err := utils.JSONDiffMapExceptID(newCluster, oldCluster, false, nbi.SourcePriority)
+		diffMap, err := nbi.diffMap(ctx, newCluster, oldCluster)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldCluster),
 				"Cluster %s already exists in Netbox but is out of date. Patching it...",
 				newCluster.Name,
 			)
@@ -625,7 +673,11 @@ func (nbi *NetboxInventory) AddCluster(
 			}
 			nbi.clustersIndexByName[newCluster.Name] = patchedCluster
 		} else {
-			nbi.Logger.Debugf(ctx, "Cluster %s already exists in Netbox and is up to date...", newCluster.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldCluster),
+				"Cluster %s already exists in Netbox and is up to date...",
+				newCluster.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "Cluster %s does not exist in Netbox. Creating it...", newCluster.Name)
@@ -655,18 +707,17 @@ func (nbi *NetboxInventory) AddDeviceRole(
 		// Remove id from orphan manager, because it still exists in the sources
 		oldDeviceRole := nbi.deviceRolesIndexByName[newDeviceRole.Name]
 		nbi.OrphanManager.RemoveItem(oldDeviceRole)
-		diffMap, err := utils.JSONDiffMapExceptID(
+		diffMap, err := nbi.diffMap(
+			ctx,
 			newDeviceRole,
 			oldDeviceRole,
-			false,
-			nbi.SourcePriority,
 		)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldDeviceRole),
 				"Device role %s already exists in Netbox but is out of date. Patching it...",
 				newDeviceRole.Name,
 			)
@@ -681,7 +732,11 @@ func (nbi *NetboxInventory) AddDeviceRole(
 			}
 			nbi.deviceRolesIndexByName[newDeviceRole.Name] = patchedDeviceRole
 		} else {
-			nbi.Logger.Debugf(ctx, "Device role %s already exists in Netbox and is up to date...", newDeviceRole.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldDeviceRole),
+				"Device role %s already exists in Netbox and is up to date...",
+				newDeviceRole.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "Device role %s does not exist in Netbox. Creating it...", newDeviceRole.Name)
@@ -712,18 +767,17 @@ func (nbi *NetboxInventory) AddManufacturer(
 		// Remove id from orphan manager, because it still exists in the sources
 		oldManufacturer := nbi.manufacturersIndexByName[newManufacturer.Name]
 		nbi.OrphanManager.RemoveItem(oldManufacturer)
-		diffMap, err := utils.JSONDiffMapExceptID(
+		diffMap, err := nbi.diffMap(
+			ctx,
 			newManufacturer,
 			oldManufacturer,
-			false,
-			nbi.SourcePriority,
 		)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldManufacturer),
 				"Manufacturer %s already exists in Netbox but is out of date. Patching it...",
 				newManufacturer.Name,
 			)
@@ -738,7 +792,11 @@ func (nbi *NetboxInventory) AddManufacturer(
 			}
 			nbi.manufacturersIndexByName[newManufacturer.Name] = patchedManufacturer
 		} else {
-			nbi.Logger.Debugf(ctx, "Manufacturer %s already exists in Netbox and is up to date...", newManufacturer.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldManufacturer),
+				"Manufacturer %s already exists in Netbox and is up to date...",
+				newManufacturer.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "Manufacturer %s does not exist in Netbox. Creating it...", newManufacturer.Name)
@@ -767,18 +825,17 @@ func (nbi *NetboxInventory) AddDeviceType(
 	if _, ok := nbi.deviceTypesIndexByModel[newDeviceType.Model]; ok {
 		oldDeviceType := nbi.deviceTypesIndexByModel[newDeviceType.Model]
 		nbi.OrphanManager.RemoveItem(oldDeviceType)
-		diffMap, err := utils.JSONDiffMapExceptID(
+		diffMap, err := nbi.diffMap(
+			ctx,
 			newDeviceType,
 			oldDeviceType,
-			false,
-			nbi.SourcePriority,
 		)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldDeviceType),
 				"Device type %s already exists in Netbox but is out of date. Patching it...",
 				newDeviceType.Model,
 			)
@@ -793,7 +850,11 @@ func (nbi *NetboxInventory) AddDeviceType(
 			}
 			nbi.deviceTypesIndexByModel[newDeviceType.Model] = patchedDeviceType
 		} else {
-			nbi.Logger.Debugf(ctx, "Device type %s already exists in Netbox and is up to date...", newDeviceType.Model)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldDeviceType),
+				"Device type %s already exists in Netbox and is up to date...",
+				newDeviceType.Model,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "Device type %s does not exist in Netbox. Creating it...", newDeviceType.Model)
@@ -811,10 +872,12 @@ func (nbi *NetboxInventory) AddDeviceType(
 // returns the created or updated platform object and an error, if any.
 // If the platform already exists in Netbox, it checks if it is up to date and patches it if necessary.
 // If the platform does not exist, it creates a new one.
+// Transform rules are applied to the platform before.
 func (nbi *NetboxInventory) AddPlatform(
 	ctx context.Context,
 	newPlatform *objects.Platform,
 ) (*objects.Platform, error) {
+	nbi.applyTransforms(ctx, newPlatform)
 	newPlatform.AddTag(nbi.SsotTag)
 	newPlatform.SetCustomField(constants.CustomFieldOrphanLastSeenName, nil)
 	nbi.platformsLock.Lock()
@@ -823,18 +886,17 @@ func (nbi *NetboxInventory) AddPlatform(
 		// Remove id from orphan manager, because it still exists in the sources
 		oldPlatform := nbi.platformsIndexByName[newPlatform.Name]
 		nbi.OrphanManager.RemoveItem(oldPlatform)
-		diffMap, err := utils.JSONDiffMapExceptID(
+		diffMap, err := nbi.diffMap(
+			ctx,
 			newPlatform,
 			oldPlatform,
-			false,
-			nbi.SourcePriority,
 		)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldPlatform),
 				"Platform %s already exists in Netbox but is out of date. Patching it...",
 				newPlatform.Name,
 			)
@@ -849,7 +911,11 @@ func (nbi *NetboxInventory) AddPlatform(
 			}
 			nbi.platformsIndexByName[newPlatform.Name] = patchedPlatform
 		} else {
-			nbi.Logger.Debugf(ctx, "Platform %s already exists in Netbox and is up to date...", newPlatform.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldPlatform),
+				"Platform %s already exists in Netbox and is up to date...",
+				newPlatform.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "Platform %s does not exist in Netbox. Creating it...", newPlatform.Name)
@@ -867,10 +933,15 @@ func (nbi *NetboxInventory) AddPlatform(
 // returns the created or updated rack role object and an error, if any.
 // If the rack role already exists in Netbox, it checks if it is up to date and patches it if necessary.
 // If the rack role does not exist, it creates a new one.
+// Transform rules are applied to the device before. ErrDropped is returned,
+// if the device is dropped by a transform rule.
 func (nbi *NetboxInventory) AddDevice(
 	ctx context.Context,
 	newDevice *objects.Device,
 ) (*objects.Device, error) {
+	if nbi.applyTransforms(ctx, newDevice) {
+		return nil, ErrDropped
+	}
 	newDevice.AddTag(nbi.SsotTag)
 	addSourceNameCustomField(ctx, &newDevice.NetboxObject)
 	nbi.applyDeviceFieldLengthLimitations(newDevice)
@@ -880,6 +951,11 @@ func (nbi *NetboxInventory) AddDevice(
 	if newDevice.Site == nil {
 		return nil, fmt.Errorf("device %s is not assigned to a site, but it should be", newDevice)
 	}
+	if _, ok := nbi.devicesIndexByNameAndSiteID[newDevice.Name][newDevice.Site.ID]; !ok {
+		if err := nbi.indexUntaggedDevice(ctx, newDevice); err != nil {
+			return nil, err
+		}
+	}
 	if _, ok := nbi.devicesIndexByNameAndSiteID[newDevice.Name][newDevice.Site.ID]; ok {
 		oldDevice := nbi.devicesIndexByNameAndSiteID[newDevice.Name][newDevice.Site.ID]
 		nbi.OrphanManager.RemoveItem(oldDevice)
@@ -893,13 +969,13 @@ func (nbi *NetboxInventory) AddDevice(
 			newDevice.AddTag(nbi.IgnoreDeviceTypeTag)
 		}
 
-		diffMap, err := utils.JSONDiffMapExceptID(newDevice, oldDevice, false, nbi.SourcePriority)
+		diffMap, err := nbi.diffMap(ctx, newDevice, oldDevice)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldDevice),
 				"Device %s already exists in Netbox but is out of date. Patching it...",
 				newDevice.Name,
 			)
@@ -915,7 +991,11 @@ func (nbi *NetboxInventory) AddDevice(
 			nbi.devicesIndexByNameAndSiteID[newDevice.Name][newDevice.Site.ID] = patchedDevice
 			nbi.devicesIndexByID[patchedDevice.ID] = patchedDevice
 		} else {
-			nbi.Logger.Debugf(ctx, "Device %s already exists in Netbox and is up to date...", newDevice.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldDevice),
+				"Device %s already exists in Netbox and is up to date...",
+				newDevice.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "Device %s does not exist in Netbox. Creating it...", newDevice.Name)
@@ -952,16 +1032,21 @@ func (nbi *NetboxInventory) AddVirtualDeviceContext(
 			newVDC,
 		)
 	}
+	if _, ok := nbi.virtualDeviceContextsIndex[newVDC.Name][newVDC.Device.ID]; !ok {
+		if err := nbi.indexUntaggedVirtualDeviceContext(ctx, newVDC); err != nil {
+			return nil, err
+		}
+	}
 	if _, ok := nbi.virtualDeviceContextsIndex[newVDC.Name][newVDC.Device.ID]; ok {
 		oldVDC := nbi.virtualDeviceContextsIndex[newVDC.Name][newVDC.Device.ID]
 		nbi.OrphanManager.RemoveItem(oldVDC)
-		diffMap, err := utils.JSONDiffMapExceptID(newVDC, oldVDC, false, nbi.SourcePriority)
+		diffMap, err := nbi.diffMap(ctx, newVDC, oldVDC)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldVDC),
 				"VirtualDeviceContext %s already exists in Netbox but is out of date. Patching it...",
 				newVDC.Name,
 			)
@@ -976,7 +1061,11 @@ func (nbi *NetboxInventory) AddVirtualDeviceContext(
 			}
 			nbi.virtualDeviceContextsIndex[newVDC.Name][newVDC.Device.ID] = patchedVDC
 		} else {
-			nbi.Logger.Debugf(ctx, "VirtualDeviceContext %s already exists in Netbox and is up to date...", newVDC.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldVDC),
+				"VirtualDeviceContext %s already exists in Netbox and is up to date...",
+				newVDC.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "VirtualDeviceContext %s does not exist in Netbox. Creating it...", newVDC.Name)
@@ -1009,18 +1098,17 @@ func (nbi *NetboxInventory) AddVlanGroup(
 		// Remove id from orphan manager, because it still exists in the sources
 		oldVlanGroup := nbi.vlanGroupsIndexByName[newVlanGroup.Name]
 		nbi.OrphanManager.RemoveItem(oldVlanGroup)
-		diffMap, err := utils.JSONDiffMapExceptID(
+		diffMap, err := nbi.diffMap(
+			ctx,
 			newVlanGroup,
 			oldVlanGroup,
-			false,
-			nbi.SourcePriority,
 		)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldVlanGroup),
 				"VlanGroup %s already exists in Netbox but is out of date. Patching it...",
 				newVlanGroup.Name,
 			)
@@ -1035,7 +1123,11 @@ func (nbi *NetboxInventory) AddVlanGroup(
 			}
 			nbi.vlanGroupsIndexByName[newVlanGroup.Name] = patchedVlanGroup
 		} else {
-			nbi.Logger.Debugf(ctx, "VlanGroup %s already exists in Netbox and is up to date...", newVlanGroup.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldVlanGroup),
+				"VlanGroup %s already exists in Netbox and is up to date...",
+				newVlanGroup.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "VlanGroup %s does not exist in Netbox. Creating it...", newVlanGroup.Name)
@@ -1065,13 +1157,13 @@ func (nbi *NetboxInventory) AddVlan(
 		// Remove id from orphan manager, because it still exists in the sources
 		oldVlan := nbi.vlansIndexByVlanGroupIDAndVID[newVlan.Group.ID][newVlan.Vid]
 		nbi.OrphanManager.RemoveItem(oldVlan)
-		diffMap, err := utils.JSONDiffMapExceptID(newVlan, oldVlan, false, nbi.SourcePriority)
+		diffMap, err := nbi.diffMap(ctx, newVlan, oldVlan)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldVlan),
 				"Vlan %s already exists in Netbox but is out of date. Patching it...",
 				newVlan.Name,
 			)
@@ -1081,7 +1173,11 @@ func (nbi *NetboxInventory) AddVlan(
 			}
 			nbi.vlansIndexByVlanGroupIDAndVID[newVlan.Group.ID][newVlan.Vid] = patchedVlan
 		} else {
-			nbi.Logger.Debugf(ctx, "Vlan %s already exists in Netbox and is up to date...", newVlan.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldVlan),
+				"Vlan %s already exists in Netbox and is up to date...",
+				newVlan.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "Vlan %s does not exist in Netbox. Creating it...", newVlan.Name)
@@ -1114,21 +1210,25 @@ func (nbi *NetboxInventory) AddInterface(
 	}
 	nbi.interfacesLock.Lock()
 	defer nbi.interfacesLock.Unlock()
+	if _, ok := nbi.interfacesIndexByDeviceIDAndName[newInterface.Device.ID][newInterface.Name]; !ok {
+		if err := nbi.indexUntaggedInterface(ctx, newInterface); err != nil {
+			return nil, err
+		}
+	}
 	if _, ok := nbi.interfacesIndexByDeviceIDAndName[newInterface.Device.ID][newInterface.Name]; ok {
 		oldInterface := nbi.interfacesIndexByDeviceIDAndName[newInterface.Device.ID][newInterface.Name]
 		nbi.OrphanManager.RemoveItem(oldInterface)
-		diffMap, err := utils.JSONDiffMapExceptID(
+		diffMap, err := nbi.diffMap(
+			ctx,
 			newInterface,
 			oldInterface,
-			false,
-			nbi.SourcePriority,
 		)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldInterface),
 				"Interface %s/%s already exists in Netbox but is out of date. Patching it...",
 				newInterface.Device.Name,
 				newInterface.Name,
@@ -1146,7 +1246,7 @@ func (nbi *NetboxInventory) AddInterface(
 			nbi.interfacesIndexByID[patchedInterface.ID] = patchedInterface
 		} else {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldInterface),
 				"Interface %s/%s already exists in Netbox and is up to date...",
 				newInterface.Device.Name, newInterface.Name,
 			)
@@ -1176,7 +1276,12 @@ func (nbi *NetboxInventory) AddInterface(
 // returns the created or updated virtual machine object and an error, if any.
 // If the virtual machine already exists in Netbox, it checks if it is up to date and patches it if necessary.
 // If the virtual machine does not exist, it creates a new one.
+// Transform rules are applied to the virtual machine before. ErrDropped
+// is returned, if the virtual machine is dropped by a transform rule.
 func (nbi *NetboxInventory) AddVM(ctx context.Context, newVM *objects.VM) (*objects.VM, error) {
+	if nbi.applyTransforms(ctx, newVM) {
+		return nil, ErrDropped
+	}
 	newVM.AddTag(nbi.SsotTag)
 	addSourceNameCustomField(ctx, &newVM.NetboxObject)
 	newVM.SetCustomField(constants.CustomFieldOrphanLastSeenName, nil)
@@ -1189,15 +1294,20 @@ func (nbi *NetboxInventory) AddVM(ctx context.Context, newVM *objects.VM) (*obje
 	if len(newVM.Name) > constants.MaxVMNameLength {
 		newVM.Name = newVM.Name[:constants.MaxVMNameLength]
 	}
+	if _, ok := nbi.vmsIndexByNameAndClusterID[newVM.Name][newVMClusterID]; !ok {
+		if err := nbi.indexUntaggedVM(ctx, newVM, newVMClusterID); err != nil {
+			return nil, err
+		}
+	}
 	if oldVM, ok := nbi.vmsIndexByNameAndClusterID[newVM.Name][newVMClusterID]; ok {
 		nbi.OrphanManager.RemoveItem(oldVM)
-		diffMap, err := utils.JSONDiffMapExceptID(newVM, oldVM, false, nbi.SourcePriority)
+		diffMap, err := nbi.diffMap(ctx, newVM, oldVM)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldVM),
 				"VM %s already exists in Netbox but is out of date. Patching it...",
 				newVM,
 			)
@@ -1209,7 +1319,7 @@ func (nbi *NetboxInventory) AddVM(ctx context.Context, newVM *objects.VM) (*obje
 			nbi.vmsIndexByNameAndClusterID[newVM.Name][newVMClusterID] = patchedVM
 			nbi.vmsIndexByID[patchedVM.ID] = patchedVM
 		} else {
-			nbi.Logger.Debugf(ctx, "VM %s already exists in Netbox and is up to date...", newVM)
+			nbi.Logger.Debugf(objectLogCtx(ctx, oldVM), "VM %s already exists in Netbox and is up to date...", newVM)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "VM %s does not exist in Netbox. Creating it...", newVM)
@@ -1244,21 +1354,25 @@ func (nbi *NetboxInventory) AddVMInterface(
 	if len(newVMInterface.Name) > constants.MaxVMInterfaceNameLength {
 		newVMInterface.Name = newVMInterface.Name[:constants.MaxVMInterfaceNameLength]
 	}
+	if _, ok := nbi.vmInterfacesIndexByVMIdAndName[newVMInterface.VM.ID][newVMInterface.Name]; !ok {
+		if err := nbi.indexUntaggedVMInterface(ctx, newVMInterface); err != nil {
+			return nil, err
+		}
+	}
 	if _, ok := nbi.vmInterfacesIndexByVMIdAndName[newVMInterface.VM.ID][newVMInterface.Name]; ok {
 		oldVMIface := nbi.vmInterfacesIndexByVMIdAndName[newVMInterface.VM.ID][newVMInterface.Name]
 		nbi.OrphanManager.RemoveItem(oldVMIface)
-		diffMap, err := utils.JSONDiffMapExceptID(
+		diffMap, err := nbi.diffMap(
+			ctx,
 			newVMInterface,
 			oldVMIface,
-			false,
-			nbi.SourcePriority,
 		)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldVMIface),
 				"VM interface %s already exists in Netbox but is out of date. Patching it...",
 				newVMInterface.Name,
 			)
@@ -1274,7 +1388,11 @@ func (nbi *NetboxInventory) AddVMInterface(
 			nbi.vmInterfacesIndexByVMIdAndName[newVMInterface.VM.ID][newVMInterface.Name] = patchedVMInterface
 			nbi.vmInterfacesIndexByID[patchedVMInterface.ID] = patchedVMInterface
 		} else {
-			nbi.Logger.Debugf(ctx, "VM interface %s already exists in Netbox and is up to date...", newVMInterface.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldVMIface),
+				"VM interface %s already exists in Netbox and is up to date...",
+				newVMInterface.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "VM interface %s does not exist in Netbox. Creating it...", newVMInterface.Name)
@@ -1326,21 +1444,32 @@ func (nbi *NetboxInventory) AddIPAddress(
 		}
 	}
 
+	if _, ok := nbi.ipAddressesIndex[objType][objName][ifaceName][indexKey]; !ok {
+		foundKey, err := nbi.indexUntaggedIPAddress(
+			ctx, newIPAddress, nbi.ipAddressesIndex[objType][objName][ifaceName],
+		)
+		if err != nil {
+			return nil, err
+		}
+		if foundKey != "" {
+			indexKey = foundKey
+		}
+	}
+
 	if _, ok := nbi.ipAddressesIndex[objType][objName][ifaceName][indexKey]; ok {
 		oldIPAddress := nbi.ipAddressesIndex[objType][objName][ifaceName][indexKey]
 		nbi.OrphanManager.RemoveItem(oldIPAddress)
-		diffMap, err := utils.JSONDiffMapExceptID(
+		diffMap, err := nbi.diffMap(
+			ctx,
 			newIPAddress,
 			oldIPAddress,
-			false,
-			nbi.SourcePriority,
 		)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldIPAddress),
 				"IP address %s already exists in Netbox but is out of date. Patching it...",
 				newIPAddress.Address,
 			)
@@ -1357,7 +1486,7 @@ func (nbi *NetboxInventory) AddIPAddress(
 			return patchedIPAddress, nil
 		}
 		nbi.Logger.Debugf(
-			ctx,
+			objectLogCtx(ctx, oldIPAddress),
 			"IP address %s already exists in Netbox and is up to date...",
 			newIPAddress.Address,
 		)
@@ -1434,15 +1563,20 @@ func (nbi *NetboxInventory) AddMACAddress(
 
 	nbi.macAddressesLock.Lock()
 	defer nbi.macAddressesLock.Unlock()
+	if _, ok := nbi.macAddressesIndex[objType][objName][ifaceName][newMACAddress.MAC]; !ok {
+		err := nbi.indexUntaggedMACAddress(ctx, newMACAddress, nbi.macAddressesIndex[objType][objName][ifaceName])
+		if err != nil {
+			return nil, err
+		}
+	}
 	if _, ok := nbi.macAddressesIndex[objType][objName][ifaceName][newMACAddress.MAC]; ok {
 		oldMACAddress := nbi.macAddressesIndex[objType][objName][ifaceName][newMACAddress.MAC]
 		nbi.OrphanManager.RemoveItem(oldMACAddress)
 
-		diffMap, err := utils.JSONDiffMapExceptID(
+		diffMap, err := nbi.diffMap(
+			ctx,
 			newMACAddress,
 			oldMACAddress,
-			false,
-			nbi.SourcePriority,
 		)
 		if err != nil {
 			return nil, err
@@ -1450,7 +1584,7 @@ func (nbi *NetboxInventory) AddMACAddress(
 
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldMACAddress),
 				"MAC address %s already exists in Netbox but is out of date. Patching it...",
 				newMACAddress.MAC,
 			)
@@ -1467,7 +1601,7 @@ func (nbi *NetboxInventory) AddMACAddress(
 			return patchedMACAddress, nil
 		}
 		nbi.Logger.Debugf(
-			ctx,
+			objectLogCtx(ctx, oldMACAddress),
 			"MAC address %s already exists in Netbox and is up to date...",
 			newMACAddress.MAC,
 		)
@@ -1527,13 +1661,13 @@ func (nbi *NetboxInventory) AddPrefix(
 	if _, ok := nbi.prefixesIndexByPrefix[newPrefix.Prefix][vrfID]; ok {
 		oldPrefix := nbi.prefixesIndexByPrefix[newPrefix.Prefix][vrfID]
 		nbi.OrphanManager.RemoveItem(oldPrefix)
-		diffMap, err := utils.JSONDiffMapExceptID(newPrefix, oldPrefix, false, nbi.SourcePriority)
+		diffMap, err := nbi.diffMap(ctx, newPrefix, oldPrefix)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldPrefix),
 				"Prefix %s already exists in Netbox but is out of date. Patching it...",
 				newPrefix.Prefix,
 			)
@@ -1548,7 +1682,11 @@ func (nbi *NetboxInventory) AddPrefix(
 			}
 			nbi.prefixesIndexByPrefix[newPrefix.Prefix][vrfID] = patchedPrefix
 		} else {
-			nbi.Logger.Debugf(ctx, "Prefix %s already exists in Netbox and is up to date...", newPrefix.Prefix)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldPrefix),
+				"Prefix %s already exists in Netbox and is up to date...",
+				newPrefix.Prefix,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "Prefix %s does not exist in Netbox. Creating it...", newPrefix.Prefix)
@@ -1579,18 +1717,17 @@ func (nbi *NetboxInventory) AddWirelessLAN(
 		// Remove id from orphan manager, because it still exists in the sources
 		oldWirelessLan := nbi.wirelessLANsIndexBySSID[newWirelessLan.SSID]
 		nbi.OrphanManager.RemoveItem(oldWirelessLan)
-		diffMap, err := utils.JSONDiffMapExceptID(
+		diffMap, err := nbi.diffMap(
+			ctx,
 			newWirelessLan,
 			oldWirelessLan,
-			false,
-			nbi.SourcePriority,
 		)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldWirelessLan),
 				"WirelessLAN %s already exists in Netbox but is out of date. Patching it...",
 				newWirelessLan.SSID,
 			)
@@ -1605,7 +1742,11 @@ func (nbi *NetboxInventory) AddWirelessLAN(
 			}
 			nbi.wirelessLANsIndexBySSID[newWirelessLan.SSID] = patchedWirelessLan
 		} else {
-			nbi.Logger.Debugf(ctx, "WirelessLAN %s already exists in Netbox and is up to date...", newWirelessLan.SSID)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldWirelessLan),
+				"WirelessLAN %s already exists in Netbox and is up to date...",
+				newWirelessLan.SSID,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "WirelessLAN %s does not exist in Netbox. Creating it...", newWirelessLan.SSID)
@@ -1635,18 +1776,17 @@ func (nbi *NetboxInventory) AddWirelessLANGroup(
 		// Remove id from orphan manager, because it still exists in the sources
 		oldWirelessLANGroup := nbi.wirelessLANGroupsIndexByName[newWirelessLANGroup.Name]
 		nbi.OrphanManager.RemoveItem(oldWirelessLANGroup)
-		diffMap, err := utils.JSONDiffMapExceptID(
+		diffMap, err := nbi.diffMap(
+			ctx,
 			newWirelessLANGroup,
 			oldWirelessLANGroup,
-			false,
-			nbi.SourcePriority,
 		)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldWirelessLANGroup),
 				"WirelessLANGroup %s already exists in Netbox but is out of date. Patching it...",
 				newWirelessLANGroup.Name,
 			)
@@ -1661,7 +1801,11 @@ func (nbi *NetboxInventory) AddWirelessLANGroup(
 			}
 			nbi.wirelessLANGroupsIndexByName[newWirelessLANGroup.Name] = patchedWirelessLANGroup
 		} else {
-			nbi.Logger.Debugf(ctx, "WirelessLANGroup %s already exists in Netbox and is up to date...", newWirelessLANGroup.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldWirelessLANGroup),
+				"WirelessLANGroup %s already exists in Netbox and is up to date...",
+				newWirelessLANGroup.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "WirelessLANGroup %s does not exist in Netbox. Creating it...", newWirelessLANGroup.Name)
@@ -1697,21 +1841,25 @@ func (nbi *NetboxInventory) AddVirtualDisk(
 	}
 	nbi.virtualDisksLock.Lock()
 	defer nbi.virtualDisksLock.Unlock()
+	if _, ok := nbi.virtualDisksIndexByVMIDAndName[newVirtualDisk.VM.ID][newVirtualDisk.Name]; !ok {
+		if err := nbi.indexUntaggedVirtualDisk(ctx, newVirtualDisk); err != nil {
+			return nil, err
+		}
+	}
 	if _, ok := nbi.virtualDisksIndexByVMIDAndName[newVirtualDisk.VM.ID][newVirtualDisk.Name]; ok {
 		oldVirtualDisk := nbi.virtualDisksIndexByVMIDAndName[newVirtualDisk.VM.ID][newVirtualDisk.Name]
 		nbi.OrphanManager.RemoveItem(oldVirtualDisk)
-		diffMap, err := utils.JSONDiffMapExceptID(
+		diffMap, err := nbi.diffMap(
+			ctx,
 			newVirtualDisk,
 			oldVirtualDisk,
-			false,
-			nbi.SourcePriority,
 		)
 		if err != nil {
 			return nil, err
 		}
 		if len(diffMap) > 0 {
 			nbi.Logger.Debugf(
-				ctx,
+				objectLogCtx(ctx, oldVirtualDisk),
 				"VirtualDisk %s already exists in Netbox but is out of date. Patching it...",
 				newVirtualDisk.Name,
 			)
@@ -1726,7 +1874,11 @@ func (nbi *NetboxInventory) AddVirtualDisk(
 			}
 			nbi.virtualDisksIndexByVMIDAndName[newVirtualDisk.VM.ID][newVirtualDisk.Name] = patchedVirtualDisk
 		} else {
-			nbi.Logger.Debugf(ctx, "VirtualDisk %s already exists in Netbox and is up to date...", newVirtualDisk.Name)
+			nbi.Logger.Debugf(
+				objectLogCtx(ctx, oldVirtualDisk),
+				"VirtualDisk %s already exists in Netbox and is up to date...",
+				newVirtualDisk.Name,
+			)
 		}
 	} else {
 		nbi.Logger.Debugf(ctx, "VirtualDisk %s does not exist in Netbox. Creating it...", newVirtualDisk.Name)
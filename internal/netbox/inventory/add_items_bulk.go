@@ -0,0 +1,291 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/service"
+)
+
+// bulkRequests collects creates and patches of objects of type T,
+// which are sent to Netbox with bulk requests.
+type bulkRequests[T any] struct {
+	creates []*T
+	// createIndexes are positions of creates in the input of the bulk add.
+	createIndexes []int
+	patches       []service.PatchRequest
+	// patchIndexes are positions of patches in the input of the bulk add.
+	patchIndexes []int
+}
+
+func (br *bulkRequests[T]) addCreate(index int, obj *T) {
+	br.creates = append(br.creates, obj)
+	br.createIndexes = append(br.createIndexes, index)
+}
+
+func (br *bulkRequests[T]) addPatch(index int, id int, diffMap map[string]interface{}) {
+	br.patches = append(br.patches, service.PatchRequest{ID: id, Body: diffMap})
+	br.patchIndexes = append(br.patchIndexes, index)
+}
+
+// send sends all collected requests to Netbox and calls store for each
+// created or patched object with its position in the input. It returns
+// positions of objects which were not created or patched, because
+// a bulk request failed.
+func (br *bulkRequests[T]) send(
+	ctx context.Context,
+	nbi *NetboxInventory,
+	store func(index int, obj *T),
+) []int {
+	var failed []int
+	created, err := service.BulkCreate(ctx, nbi.NetboxAPI, br.creates)
+	for i, obj := range created {
+		store(br.createIndexes[i], obj)
+	}
+	if err != nil {
+		nbi.Logger.Warningf(ctx, "bulk create of %d objects failed, creating them one by one: %s", len(br.creates), err)
+		failed = append(failed, br.createIndexes[len(created):]...)
+	}
+	patched, err := service.BulkPatch[T](ctx, nbi.NetboxAPI, br.patches)
+	for i, obj := range patched {
+		store(br.patchIndexes[i], obj)
+	}
+	if err != nil {
+		nbi.Logger.Warningf(ctx, "bulk patch of %d objects failed, patching them one by one: %s", len(br.patches), err)
+		failed = append(failed, br.patchIndexes[len(patched):]...)
+	}
+	return failed
+}
+
+// AddVMInterfaces adds multiple VM interfaces to the Netbox inventory.
+// It works the same way as AddVMInterface, but new and out of date VM interfaces
+// are created and patched with bulk requests. VM interfaces that can't be added
+// with bulk requests are added one by one with AddVMInterface.
+// Returned VM interfaces are in the same order as newVMInterfaces.
+func (nbi *NetboxInventory) AddVMInterfaces(
+	ctx context.Context,
+	newVMInterfaces []*objects.VMInterface,
+) ([]*objects.VMInterface, error) {
+	results := make([]*objects.VMInterface, len(newVMInterfaces))
+	failed, err := nbi.bulkAddVMInterfaces(ctx, newVMInterfaces, results)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range failed {
+		results[i], err = nbi.AddVMInterface(ctx, newVMInterfaces[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// bulkAddVMInterfaces stores added VM interfaces into results, and returns
+// positions of VM interfaces that have to be added one by one.
+func (nbi *NetboxInventory) bulkAddVMInterfaces(
+	ctx context.Context,
+	newVMInterfaces []*objects.VMInterface,
+	results []*objects.VMInterface,
+) ([]int, error) {
+	nbi.vmInterfacesLock.Lock()
+	defer nbi.vmInterfacesLock.Unlock()
+
+	var requests bulkRequests[objects.VMInterface]
+	var failed []int
+	// Names of VM interfaces created in this bulk, indexed by VM ID
+	pendingCreates := make(map[int]map[string]bool)
+	for i, newVMInterface := range newVMInterfaces {
+		newVMInterface.AddTag(nbi.SsotTag)
+		addSourceNameCustomField(ctx, &newVMInterface.NetboxObject)
+		newVMInterface.SetCustomField(constants.CustomFieldOrphanLastSeenName, nil)
+		if len(newVMInterface.Name) > constants.MaxVMInterfaceNameLength {
+			newVMInterface.Name = newVMInterface.Name[:constants.MaxVMInterfaceNameLength]
+		}
+		if oldVMIface, ok := nbi.vmInterfacesIndexByVMIdAndName[newVMInterface.VM.ID][newVMInterface.Name]; ok {
+			nbi.OrphanManager.RemoveItem(oldVMIface)
+			diffMap, err := nbi.diffMap(
+				ctx,
+				newVMInterface,
+				oldVMIface,
+			)
+			if err != nil {
+				return nil, err
+			}
+			if len(diffMap) > 0 {
+				nbi.Logger.Debugf(
+					objectLogCtx(ctx, oldVMIface),
+					"VM interface %s already exists in Netbox but is out of date. Patching it...",
+					newVMInterface.Name,
+				)
+				requests.addPatch(i, oldVMIface.ID, diffMap)
+			} else {
+				nbi.Logger.Debugf(
+					objectLogCtx(ctx, oldVMIface),
+					"VM interface %s already exists in Netbox and is up to date...",
+					newVMInterface.Name,
+				)
+				results[i] = oldVMIface
+			}
+		} else if pendingCreates[newVMInterface.VM.ID][newVMInterface.Name] || nbi.tagFilter() != "" {
+			// Same VM interface is already created in this bulk, so it is
+			// added after the bulk, when it is already in the index.
+			// If only tagged objects are collected, it may exist untagged,
+			// so it is looked up by AddVMInterface
+			failed = append(failed, i)
+		} else {
+			nbi.Logger.Debugf(ctx, "VM interface %s does not exist in Netbox. Creating it...", newVMInterface.Name)
+			requests.addCreate(i, newVMInterface)
+			if pendingCreates[newVMInterface.VM.ID] == nil {
+				pendingCreates[newVMInterface.VM.ID] = make(map[string]bool)
+			}
+			pendingCreates[newVMInterface.VM.ID][newVMInterface.Name] = true
+		}
+	}
+
+	failed = append(requests.send(ctx, nbi, func(i int, vmInterface *objects.VMInterface) {
+		// Responses of patches contain only nested VM, so we key by the VM of the input
+		vmID := newVMInterfaces[i].VM.ID
+		name := newVMInterfaces[i].Name
+		if nbi.vmInterfacesIndexByVMIdAndName[vmID] == nil {
+			nbi.vmInterfacesIndexByVMIdAndName[vmID] = make(map[string]*objects.VMInterface)
+		}
+		nbi.vmInterfacesIndexByVMIdAndName[vmID][name] = vmInterface
+		nbi.vmInterfacesIndexByID[vmInterface.ID] = vmInterface
+		results[i] = vmInterface
+	}), failed...)
+	return failed, nil
+}
+
+// ipAddressIndexValues are the keys of an IP address in the ipAddressesIndex.
+type ipAddressIndexValues struct {
+	objType   constants.ContentType
+	objName   string
+	ifaceName string
+	key       string
+}
+
+// AddIPAddresses adds multiple IP addresses to the Netbox inventory.
+// It works the same way as AddIPAddress, but new and out of date IP addresses
+// are created and patched with bulk requests. IP addresses that can't be added
+// with bulk requests are added one by one with AddIPAddress.
+//
+// Returned IP addresses are in the same order as newIPAddresses. If an IP address
+// can't be added, its result is nil, and its error is included in the returned error.
+func (nbi *NetboxInventory) AddIPAddresses(
+	ctx context.Context,
+	newIPAddresses []*objects.IPAddress,
+) ([]*objects.IPAddress, error) {
+	results := make([]*objects.IPAddress, len(newIPAddresses))
+	indexValues := make([]*ipAddressIndexValues, len(newIPAddresses))
+	var errs []error
+	for i, newIPAddress := range newIPAddresses {
+		newIPAddress.AddTag(nbi.SsotTag)
+		addSourceNameCustomField(ctx, &newIPAddress.NetboxObject)
+		newIPAddress.SetCustomField(constants.CustomFieldOrphanLastSeenName, nil)
+		objType, objName, ifaceName, err := nbi.getIndexValuesForIPAddress(newIPAddress)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("get index values for ip address %+v: %s", newIPAddress, err))
+			continue
+		}
+		nbi.verifyIPAddressIndexExists(objType, objName, ifaceName)
+		indexValues[i] = &ipAddressIndexValues{
+			objType:   objType,
+			objName:   objName,
+			ifaceName: ifaceName,
+			key:       ipAddressIndexKey(newIPAddress),
+		}
+	}
+
+	failed, err := nbi.bulkAddIPAddresses(ctx, newIPAddresses, indexValues, results)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range failed {
+		results[i], err = nbi.AddIPAddress(ctx, newIPAddresses[i])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("add ip address %s: %s", newIPAddresses[i].Address, err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// bulkAddIPAddresses stores added IP addresses into results, and returns
+// positions of IP addresses that have to be added one by one.
+// IP addresses without indexValues are skipped.
+func (nbi *NetboxInventory) bulkAddIPAddresses(
+	ctx context.Context,
+	newIPAddresses []*objects.IPAddress,
+	indexValues []*ipAddressIndexValues,
+	results []*objects.IPAddress,
+) ([]int, error) {
+	nbi.ipAddressesLock.Lock()
+	defer nbi.ipAddressesLock.Unlock()
+
+	var requests bulkRequests[objects.IPAddress]
+	var failed []int
+	pendingCreates := make(map[ipAddressIndexValues]bool)
+	for i, newIPAddress := range newIPAddresses {
+		iv := indexValues[i]
+		if iv == nil {
+			continue
+		}
+		ifaceIndex := nbi.ipAddressesIndex[iv.objType][iv.objName][iv.ifaceName]
+		// When VRF is not specified by the source (nil), try to find the IP in any VRF.
+		// This preserves manually assigned VRFs in NetBox and avoids creating duplicates.
+		if _, ok := ifaceIndex[iv.key]; !ok && newIPAddress.VRF == nil {
+			if foundKey, foundIP := findIPAddressAcrossVRFs(ifaceIndex, newIPAddress.Address); foundIP != nil {
+				iv.key = foundKey
+				newIPAddress.VRF = foundIP.VRF
+			}
+		}
+
+		if oldIPAddress, ok := ifaceIndex[iv.key]; ok {
+			nbi.OrphanManager.RemoveItem(oldIPAddress)
+			diffMap, err := nbi.diffMap(
+				ctx,
+				newIPAddress,
+				oldIPAddress,
+			)
+			if err != nil {
+				return nil, err
+			}
+			if len(diffMap) > 0 {
+				nbi.Logger.Debugf(
+					objectLogCtx(ctx, oldIPAddress),
+					"IP address %s already exists in Netbox but is out of date. Patching it...",
+					newIPAddress.Address,
+				)
+				requests.addPatch(i, oldIPAddress.ID, diffMap)
+			} else {
+				nbi.Logger.Debugf(
+					objectLogCtx(ctx, oldIPAddress),
+					"IP address %s already exists in Netbox and is up to date...",
+					newIPAddress.Address,
+				)
+				results[i] = oldIPAddress
+			}
+		} else if pendingCreates[*iv] || nbi.tagFilter() != "" {
+			// Same IP address is already created in this bulk, so it is
+			// added after the bulk, when it is already in the index.
+			// If only tagged objects are collected, it may exist untagged,
+			// so it is looked up by AddIPAddress
+			failed = append(failed, i)
+		} else {
+			nbi.Logger.Debugf(ctx, "IP address %s does not exist in Netbox. Creating it...", newIPAddress.Address)
+			requests.addCreate(i, newIPAddress)
+			pendingCreates[*iv] = true
+		}
+	}
+
+	// Failed creates are retried with AddIPAddress, which also handles
+	// IP addresses that exist in Netbox, but are not in the index
+	failed = append(requests.send(ctx, nbi, func(i int, ipAddress *objects.IPAddress) {
+		iv := indexValues[i]
+		nbi.ipAddressesIndex[iv.objType][iv.objName][iv.ifaceName][iv.key] = ipAddress
+		results[i] = ipAddress
+	}), failed...)
+	return failed, nil
+}
@@ -0,0 +1,263 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/logger"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/service"
+)
+
+// bulkTestServer mimics Netbox endpoints, which accept both single objects
+// and JSON arrays. Created objects are stored, so patches return the whole
+// patched object. When rejectBulk is set, requests with JSON arrays fail.
+type bulkTestServer struct {
+	lock       sync.Mutex
+	rejectBulk bool
+	nextID     int
+	objects    map[int]map[string]interface{}
+	// requests counts requests by method
+	requests map[string]int
+	// list contains results of GET requests by path, which are also stored
+	// in objects, so they can be patched
+	list map[string][]map[string]interface{}
+	// queries contains queries of GET requests
+	queries []string
+}
+
+func (s *bulkTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requests[r.Method]++
+	if r.Method == http.MethodGet {
+		s.queries = append(s.queries, r.URL.RawQuery)
+		results := s.list[r.URL.Path]
+		for _, obj := range results {
+			s.objects[int(obj["id"].(float64))] = obj //nolint:forcetypeassert
+		}
+		resp, _ := json.Marshal(service.Response[map[string]interface{}]{Count: len(results), Results: results})
+		_, _ = w.Write(resp)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	var objs []map[string]interface{}
+	isBulk := json.Unmarshal(body, &objs) == nil
+	if !isBulk {
+		var obj map[string]interface{}
+		_ = json.Unmarshal(body, &obj)
+		objs = []map[string]interface{}{obj}
+	} else if s.rejectBulk {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, obj := range objs {
+		// Netbox returns nested objects instead of their IDs
+		if tagIDs, ok := obj["tags"].([]interface{}); ok {
+			for i, tagID := range tagIDs {
+				if id, ok := tagID.(float64); ok {
+					tagIDs[i] = map[string]interface{}{"id": id}
+				}
+			}
+		}
+		for _, field := range []string{"virtual_machine", "site"} {
+			if id, ok := obj[field].(float64); ok {
+				obj[field] = map[string]interface{}{"id": id}
+			}
+		}
+	}
+	status := http.StatusOK
+	for i, obj := range objs {
+		switch r.Method {
+		case http.MethodPost:
+			status = http.StatusCreated
+			s.nextID++
+			obj["id"] = s.nextID
+			s.objects[s.nextID] = obj
+		case http.MethodPatch:
+			id, ok := obj["id"].(float64)
+			if !ok {
+				// Path of a single patch is /api/<app>/<model>/<id>/
+				_, _ = fmt.Sscanf(path.Base(r.URL.Path), "%f", &id)
+			}
+			stored := s.objects[int(id)]
+			maps.Copy(stored, obj)
+			objs[i] = stored
+		}
+	}
+	var resp []byte
+	if isBulk {
+		resp, _ = json.Marshal(objs)
+	} else {
+		resp, _ = json.Marshal(objs[0])
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(resp)
+}
+
+func newBulkTestInventory(t *testing.T, rejectBulk bool) (*NetboxInventory, *bulkTestServer) {
+	t.Helper()
+	testServer := &bulkTestServer{
+		rejectBulk: rejectBulk,
+		nextID:     100,
+		objects:    map[int]map[string]interface{}{},
+		requests:   map[string]int{},
+		list:       map[string][]map[string]interface{}{},
+	}
+	server := httptest.NewServer(testServer)
+	t.Cleanup(server.Close)
+	testLogger := &logger.Logger{Logger: log.New(io.Discard, "", 0)}
+	nbi := &NetboxInventory{
+		Logger:  testLogger,
+		SsotTag: &objects.Tag{ID: 1, Name: constants.SsotTagName},
+		NetboxAPI: &service.NetboxClient{
+			HTTPClient: &http.Client{},
+			Logger:     testLogger,
+			BaseURL:    server.URL,
+			Timeout:    constants.DefaultAPITimeout,
+		},
+		OrphanManager:                  NewOrphanManager(testLogger),
+		vmInterfacesIndexByVMIdAndName: map[int]map[string]*objects.VMInterface{},
+		vmInterfacesIndexByID:          map[int]*objects.VMInterface{},
+		ipAddressesIndex:               map[constants.ContentType]map[string]map[string]map[string]*objects.IPAddress{},
+	}
+	return nbi, testServer
+}
+
+func TestNetboxInventory_AddVMInterfacesAndIPAddresses(t *testing.T) {
+	tests := []struct {
+		name         string
+		rejectBulk   bool
+		wantRequests map[string]int
+	}{
+		{
+			name:       "Bulk requests",
+			rejectBulk: false,
+			// VM interfaces: 1 create, 1 patch; IP addresses: 1 create, then
+			// the duplicate IP address is added on its own without a request
+			wantRequests: map[string]int{http.MethodPost: 2, http.MethodPatch: 1},
+		},
+		{
+			name:       "Fallback to single requests",
+			rejectBulk: true,
+			// Failed bulk requests (2 POST, 1 PATCH) are followed by
+			// 3 VM interface creates, 1 VM interface patch and 2 IP address creates
+			wantRequests: map[string]int{http.MethodPost: 7, http.MethodPatch: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), constants.CtxSourceKey, "test")
+			nbi, testServer := newBulkTestInventory(t, tt.rejectBulk)
+			vm := &objects.VM{NetboxObject: objects.NetboxObject{ID: 1}, Name: "vm1"}
+			existingIface := &objects.VMInterface{
+				NetboxObject: objects.NetboxObject{ID: 5, Description: "old"},
+				VM:           vm,
+				Name:         "eth0",
+			}
+			nbi.vmInterfacesIndexByVMIdAndName[vm.ID] = map[string]*objects.VMInterface{"eth0": existingIface}
+			nbi.vmInterfacesIndexByID[existingIface.ID] = existingIface
+			testServer.objects[existingIface.ID] = map[string]interface{}{
+				"id":              existingIface.ID,
+				"name":            existingIface.Name,
+				"description":     existingIface.Description,
+				"virtual_machine": map[string]interface{}{"id": vm.ID},
+			}
+
+			vmIfaces, err := nbi.AddVMInterfaces(ctx, []*objects.VMInterface{
+				{NetboxObject: objects.NetboxObject{Description: "new"}, VM: vm, Name: "eth0"},
+				{VM: vm, Name: "eth1"},
+				{VM: vm, Name: "eth2"},
+				{VM: vm, Name: "eth3"},
+			})
+			if err != nil {
+				t.Fatalf("AddVMInterfaces() error = %s", err)
+			}
+			if len(vmIfaces) != 4 || vmIfaces[0].ID != existingIface.ID {
+				t.Fatalf("AddVMInterfaces() = %v", vmIfaces)
+			}
+			for i, vmIface := range vmIfaces[1:] {
+				indexed := nbi.vmInterfacesIndexByVMIdAndName[vm.ID][vmIface.Name]
+				if vmIface.ID == 0 || indexed != vmIface || nbi.GetVMInterfaceByID(vmIface.ID) != vmIface {
+					t.Errorf("VM interface %d = %+v is not indexed", i+1, vmIface)
+				}
+			}
+
+			ipAddresses, err := nbi.AddIPAddresses(ctx, []*objects.IPAddress{
+				{
+					Address:            "10.0.0.1/24",
+					AssignedObjectType: constants.ContentTypeVirtualizationVMInterface,
+					AssignedObjectID:   vmIfaces[1].ID,
+				},
+				{
+					Address:            "10.0.0.2/24",
+					AssignedObjectType: constants.ContentTypeVirtualizationVMInterface,
+					AssignedObjectID:   vmIfaces[2].ID,
+				},
+				{
+					Address:            "10.0.0.2/24",
+					AssignedObjectType: constants.ContentTypeVirtualizationVMInterface,
+					AssignedObjectID:   vmIfaces[2].ID,
+				},
+			})
+			if err != nil {
+				t.Fatalf("AddIPAddresses() error = %s", err)
+			}
+			if len(ipAddresses) != 3 || ipAddresses[0].ID == 0 || ipAddresses[1].ID == 0 {
+				t.Fatalf("AddIPAddresses() = %v", ipAddresses)
+			}
+			if ipAddresses[2].ID != ipAddresses[1].ID {
+				t.Errorf("duplicate IP address was created twice: %d != %d", ipAddresses[2].ID, ipAddresses[1].ID)
+			}
+			vmIPAddresses := nbi.ipAddressesIndex[constants.ContentTypeVirtualizationVirtualMachine]
+			indexed := vmIPAddresses[vmIfaces[2].Name][vmIfaces[2].VM.Name]
+			if indexed["10.0.0.2/24"] != ipAddresses[1] {
+				t.Errorf("IP address %+v is not indexed", ipAddresses[1])
+			}
+
+			testServer.lock.Lock()
+			defer testServer.lock.Unlock()
+			for method, want := range tt.wantRequests {
+				if got := testServer.requests[method]; got != want {
+					t.Errorf("%s requests = %d, want %d", method, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNetboxInventory_AddVMInterfacesBulkSize(t *testing.T) {
+	ctx := context.WithValue(context.Background(), constants.CtxSourceKey, "test")
+	nbi, testServer := newBulkTestInventory(t, false)
+	nbi.NetboxAPI.BulkSize = 2
+	// Interfaces of multiple VMs are created together
+	vmIfaces := make([]*objects.VMInterface, 0)
+	for vmID := 1; vmID <= 3; vmID++ {
+		vm := &objects.VM{NetboxObject: objects.NetboxObject{ID: vmID}, Name: fmt.Sprintf("vm%d", vmID)}
+		vmIfaces = append(vmIfaces, &objects.VMInterface{VM: vm, Name: "eth0"}, &objects.VMInterface{VM: vm, Name: "eth1"})
+	}
+	added, err := nbi.AddVMInterfaces(ctx, vmIfaces)
+	if err != nil {
+		t.Fatalf("AddVMInterfaces() error = %s", err)
+	}
+	for i, vmIface := range added {
+		if vmIface.ID == 0 || nbi.vmInterfacesIndexByVMIdAndName[vmIfaces[i].VM.ID][vmIfaces[i].Name] != vmIface {
+			t.Errorf("VM interface %d = %+v is not indexed", i, vmIface)
+		}
+	}
+	testServer.lock.Lock()
+	defer testServer.lock.Unlock()
+	if got := testServer.requests[http.MethodPost]; got != 3 {
+		t.Errorf("POST requests = %d, want 3", got)
+	}
+}
@@ -1,27 +1,78 @@
 package inventory
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/service"
+	"github.com/bl4ko/netbox-ssot/internal/report"
 	"github.com/bl4ko/netbox-ssot/internal/utils"
 )
 
-func (nbi *NetboxInventory) DeleteOrphans(hard bool) error {
-	for i := 0; i < len(nbi.OrphanManager.OrphanObjectPriority); i++ {
-		deleteTypeStr := "soft"
-		if hard {
-			deleteTypeStr = "hard"
-		}
+// DeleteOrphans deletes (hard) or marks as orphaned (soft) all objects left
+// in the orphan manager, which are within the scope. Nil scope includes all objects.
+//
+// If thresholds are set, and objects that would be deleted or newly marked as
+// orphaned exceed them, no objects are deleted or marked as orphaned, and
+// *DeletionThresholdError containing the pending deletions is returned instead.
+func (nbi *NetboxInventory) DeleteOrphans(hard bool, scope *OrphanScope, thresholds *DeletionThresholds) error {
+	deleteTypeStr := "soft"
+	if hard {
+		deleteTypeStr = "hard"
+	}
+	orphansByPriority := make([]map[int]objects.OrphanItem, len(nbi.OrphanManager.OrphanObjectPriority))
+	for i := range orphansByPriority {
 		objectAPIPath := nbi.OrphanManager.OrphanObjectPriority[i]
-		id2orphanItem := nbi.OrphanManager.Items[objectAPIPath]
+		id2orphanItem := make(map[int]objects.OrphanItem, len(nbi.OrphanManager.Items[objectAPIPath]))
+		for id, orphanItem := range nbi.OrphanManager.Items[objectAPIPath] {
+			if !scope.Contains(orphanItem) {
+				nbi.OrphanManager.Logger.Debugf(
+					objectLogCtx(nbi.Ctx, orphanItem),
+					"Keeping %s owned by source %q, which is out of scope of orphan cleanup",
+					orphanItem,
+					ItemSource(orphanItem),
+				)
+				continue
+			}
+			id2orphanItem[id] = orphanItem
+		}
+		orphansByPriority[i] = id2orphanItem
+	}
+
+	if thresholds != nil {
+		// Objects marked as orphans in this run are counted as deletions, because
+		// they are deleted after RemoveOrphansAfterDays without another check
+		var deletions []objects.OrphanItem
+		markings := make(map[objects.OrphanItem]bool)
+		for _, id2orphanItem := range orphansByPriority {
+			for _, orphanItem := range id2orphanItem {
+				switch {
+				case hard || nbi.isExpiredOrphan(orphanItem):
+					deletions = append(deletions, orphanItem)
+				case !nbi.isMarkedOrphan(orphanItem):
+					deletions = append(deletions, orphanItem)
+					markings[orphanItem] = true
+				}
+			}
+		}
+		byAPIPath, bySource := nbi.OrphanManager.ManagedCounts()
+		if reasons := thresholds.Check(deletions, byAPIPath, bySource); len(reasons) > 0 {
+			pending := NewPendingDeletions(reasons, deletions)
+			for i, orphanItem := range deletions {
+				pending.Deletions[i].MarkOrphan = markings[orphanItem]
+			}
+			return &DeletionThresholdError{Pending: pending}
+		}
+	}
+
+	for i, id2orphanItem := range orphansByPriority {
 		if len(id2orphanItem) == 0 {
 			continue
 		}
-
+		objectAPIPath := nbi.OrphanManager.OrphanObjectPriority[i]
 		nbi.OrphanManager.Logger.Infof(
 			nbi.Ctx,
 			"Performing %s deletion of orphaned objects of type %s",
@@ -40,13 +91,13 @@ func (nbi *NetboxInventory) DeleteOrphans(hard bool) error {
 				// Perform hard deletion
 				err := nbi.hardDelete(orphanItem)
 				if err != nil {
-					nbi.OrphanManager.Logger.Errorf(nbi.Ctx, "hard delete object: %s", err)
+					nbi.OrphanManager.Logger.Errorf(objectLogCtx(nbi.Ctx, orphanItem), "hard delete object: %s", err)
 					continue
 				}
 			} else {
 				err := nbi.softDelete(orphanItem)
 				if err != nil {
-					nbi.OrphanManager.Logger.Errorf(nbi.Ctx, "soft delete object: %s", err)
+					nbi.OrphanManager.Logger.Errorf(objectLogCtx(nbi.Ctx, orphanItem), "soft delete object: %s", err)
 				}
 			}
 		}
@@ -68,10 +119,7 @@ func (nbi *NetboxInventory) softDelete(orphanItem objects.OrphanItem) error {
 	// Perform soft deletion
 	// Add tag to the object to mark it as orphaned
 	todayDate := time.Now().Format(constants.CustomFieldOrphanLastSeenFormat)
-	if !orphanItem.GetNetboxObject().HasTag(nbi.OrphanManager.Tag) ||
-		orphanItem.GetNetboxObject().
-			GetCustomField(constants.CustomFieldOrphanLastSeenName) ==
-			nil {
+	if !nbi.isMarkedOrphan(orphanItem) {
 		// This OrphanItem has been marked as orphan for the first time
 		orphanItem.GetNetboxObject().AddTag(nbi.OrphanManager.Tag)
 		orphanItem.GetNetboxObject().
@@ -81,77 +129,17 @@ func (nbi *NetboxInventory) softDelete(orphanItem objects.OrphanItem) error {
 			[]string{"tags", "custom_fields"},
 		)
 		// Update object on the API
-		var err error
-		switch orphanItem.(type) {
-		case *objects.VlanGroup:
-			_, err = service.Patch[objects.VlanGroup](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.Prefix:
-			_, err = service.Patch[objects.Prefix](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.Vlan:
-			_, err = service.Patch[objects.Vlan](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.IPAddress:
-			_, err = service.Patch[objects.IPAddress](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.VirtualDeviceContext:
-			_, err = service.Patch[objects.VirtualDeviceContext](
-				nbi.OrphanManager.Ctx,
-				nbi.NetboxAPI,
-				orphanItem.GetID(),
-				diffMap,
-			)
-		case *objects.Interface:
-			_, err = service.Patch[objects.Interface](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.VMInterface:
-			_, err = service.Patch[objects.VMInterface](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.VM:
-			_, err = service.Patch[objects.VM](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.Device:
-			_, err = service.Patch[objects.Device](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.Platform:
-			_, err = service.Patch[objects.Platform](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.DeviceType:
-			_, err = service.Patch[objects.DeviceType](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.Manufacturer:
-			_, err = service.Patch[objects.Manufacturer](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.DeviceRole:
-			_, err = service.Patch[objects.DeviceRole](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.ClusterType:
-			_, err = service.Patch[objects.ClusterType](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.Cluster:
-			_, err = service.Patch[objects.Cluster](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.ClusterGroup:
-			_, err = service.Patch[objects.ClusterGroup](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.ContactAssignment:
-			_, err = service.Patch[objects.ContactAssignment](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.Contact:
-			_, err = service.Patch[objects.Contact](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.WirelessLAN:
-			_, err = service.Patch[objects.WirelessLAN](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.WirelessLANGroup:
-			_, err = service.Patch[objects.WirelessLANGroup](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.MACAddress:
-			_, err = service.Patch[objects.MACAddress](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		case *objects.VirtualDisk:
-			_, err = service.Patch[objects.VirtualDisk](nbi.OrphanManager.Ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
-		default:
-			return fmt.Errorf("unsupported type for orphan item%T", orphanItem)
-		}
-		if err != nil {
+		softDeleteCtx := report.WithAction(nbi.OrphanManager.Ctx, report.ActionSoftDelete)
+		if err := nbi.patchOrphanItem(softDeleteCtx, orphanItem, diffMap); err != nil {
 			return fmt.Errorf("failed updating %s object with orphan tag: %s", orphanItem, err)
 		}
 	} else {
-		nbi.Logger.Debugf(nbi.Ctx, "%s is already marked as orphan", orphanItem)
-		lastSeenRaw, ok := orphanItem.GetNetboxObject().GetCustomField(constants.CustomFieldOrphanLastSeenName).(string)
-		if !ok {
-			return fmt.Errorf("failed to get last seen date as string for %s", orphanItem)
-		}
-		lastSeen, err := time.Parse(
-			constants.CustomFieldOrphanLastSeenFormat,
-			lastSeenRaw,
-		)
+		nbi.Logger.Debugf(objectLogCtx(nbi.Ctx, orphanItem), "%s is already marked as orphan", orphanItem)
+		expired, err := nbi.orphanExpired(orphanItem)
 		if err != nil {
-			return fmt.Errorf("failed parsing last seen date: %s", err)
+			return err
 		}
-		if int((time.Since(lastSeen).Hours())/24) > nbi.NetboxConfig.RemoveOrphansAfterDays { //nolint:mnd
+		if expired {
 			err := nbi.hardDelete(orphanItem)
 			if err != nil {
 				return fmt.Errorf("failed deleting %s object: %s", orphanItem, err)
@@ -160,3 +148,100 @@ func (nbi *NetboxInventory) softDelete(orphanItem objects.OrphanItem) error {
 	}
 	return nil
 }
+
+// patchOrphanItem patches orphanItem with diffMap.
+func (nbi *NetboxInventory) patchOrphanItem(
+	ctx context.Context,
+	orphanItem objects.OrphanItem,
+	diffMap map[string]interface{},
+) error {
+	var err error
+	switch orphanItem.(type) {
+	case *objects.VlanGroup:
+		_, err = service.Patch[objects.VlanGroup](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.Prefix:
+		_, err = service.Patch[objects.Prefix](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.Vlan:
+		_, err = service.Patch[objects.Vlan](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.IPAddress:
+		_, err = service.Patch[objects.IPAddress](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.VirtualDeviceContext:
+		_, err = service.Patch[objects.VirtualDeviceContext](
+			ctx,
+			nbi.NetboxAPI,
+			orphanItem.GetID(),
+			diffMap,
+		)
+	case *objects.Interface:
+		_, err = service.Patch[objects.Interface](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.VMInterface:
+		_, err = service.Patch[objects.VMInterface](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.VM:
+		_, err = service.Patch[objects.VM](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.Device:
+		_, err = service.Patch[objects.Device](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.Platform:
+		_, err = service.Patch[objects.Platform](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.DeviceType:
+		_, err = service.Patch[objects.DeviceType](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.Manufacturer:
+		_, err = service.Patch[objects.Manufacturer](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.DeviceRole:
+		_, err = service.Patch[objects.DeviceRole](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.ClusterType:
+		_, err = service.Patch[objects.ClusterType](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.Cluster:
+		_, err = service.Patch[objects.Cluster](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.ClusterGroup:
+		_, err = service.Patch[objects.ClusterGroup](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.ContactAssignment:
+		_, err = service.Patch[objects.ContactAssignment](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.Contact:
+		_, err = service.Patch[objects.Contact](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.WirelessLAN:
+		_, err = service.Patch[objects.WirelessLAN](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.WirelessLANGroup:
+		_, err = service.Patch[objects.WirelessLANGroup](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.MACAddress:
+		_, err = service.Patch[objects.MACAddress](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	case *objects.VirtualDisk:
+		_, err = service.Patch[objects.VirtualDisk](ctx, nbi.NetboxAPI, orphanItem.GetID(), diffMap)
+	default:
+		return fmt.Errorf("unsupported type for orphan item%T", orphanItem)
+	}
+	return err
+}
+
+// isMarkedOrphan returns true if orphanItem is already marked as orphan
+// with the orphan tag and its last seen date.
+func (nbi *NetboxInventory) isMarkedOrphan(orphanItem objects.OrphanItem) bool {
+	return orphanItem.GetNetboxObject().HasTag(nbi.OrphanManager.Tag) &&
+		orphanItem.GetNetboxObject().GetCustomField(constants.CustomFieldOrphanLastSeenName) != nil
+}
+
+// isExpiredOrphan returns true if orphanItem is already marked as orphan for
+// more than RemoveOrphansAfterDays, so soft deletion will hard delete it.
+func (nbi *NetboxInventory) isExpiredOrphan(orphanItem objects.OrphanItem) bool {
+	if !nbi.isMarkedOrphan(orphanItem) {
+		return false
+	}
+	expired, err := nbi.orphanExpired(orphanItem)
+	return err == nil && expired
+}
+
+// orphanExpired returns true if last seen date of orphanItem is more
+// than RemoveOrphansAfterDays ago.
+func (nbi *NetboxInventory) orphanExpired(orphanItem objects.OrphanItem) (bool, error) {
+	lastSeenRaw, ok := orphanItem.GetNetboxObject().GetCustomField(constants.CustomFieldOrphanLastSeenName).(string)
+	if !ok {
+		return false, fmt.Errorf("failed to get last seen date as string for %s", orphanItem)
+	}
+	lastSeen, err := time.Parse(
+		constants.CustomFieldOrphanLastSeenFormat,
+		lastSeenRaw,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed parsing last seen date: %s", err)
+	}
+	return int((time.Since(lastSeen).Hours())/24) > nbi.NetboxConfig.RemoveOrphansAfterDays, nil //nolint:mnd
+}
@@ -0,0 +1,280 @@
+package inventory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/service"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+)
+
+// DeletionThresholds limit number of objects deleted by DeleteOrphans in a single run.
+type DeletionThresholds struct {
+	// Default is the threshold for each object type.
+	Default parser.DeletionThreshold
+	// ObjectTypes override Default for single object types.
+	ObjectTypes map[constants.ContentType]parser.DeletionThreshold
+	// Sources are thresholds for objects owned by each source, indexed by source name.
+	Sources map[string]parser.DeletionThreshold
+}
+
+// NewDeletionThresholds creates DeletionThresholds from the configuration.
+func NewDeletionThresholds(config *parser.Config) *DeletionThresholds {
+	thresholds := &DeletionThresholds{
+		Default:     config.Netbox.DeletionThreshold,
+		ObjectTypes: config.Netbox.ObjectTypeDeletionThresholds,
+		Sources:     make(map[string]parser.DeletionThreshold, len(config.Sources)),
+	}
+	for _, sourceConfig := range config.Sources {
+		thresholds.Sources[sourceConfig.Name] = sourceConfig.DeletionThreshold
+	}
+	return thresholds
+}
+
+// Check returns reasons why deletions exceed the thresholds, or nil if they don't.
+// Percentages are calculated from number of managed objects of each objectAPIPath
+// and of each source.
+func (dt *DeletionThresholds) Check(
+	deletions []objects.OrphanItem,
+	managedByAPIPath map[constants.APIPath]int,
+	managedBySource map[string]int,
+) []string {
+	deletionsByAPIPath := map[constants.APIPath]int{}
+	objectTypes := map[constants.APIPath]constants.ContentType{}
+	deletionsBySource := map[string]int{}
+	for _, orphanItem := range deletions {
+		deletionsByAPIPath[orphanItem.GetAPIPath()]++
+		objectTypes[orphanItem.GetAPIPath()] = orphanItem.GetObjectType()
+		deletionsBySource[ItemSource(orphanItem)]++
+	}
+
+	var reasons []string
+	for objectAPIPath, count := range deletionsByAPIPath {
+		objectType := objectTypes[objectAPIPath]
+		threshold, ok := dt.ObjectTypes[objectType]
+		if !ok {
+			threshold = dt.Default
+		}
+		if reason := thresholdExceeded(threshold, count, managedByAPIPath[objectAPIPath]); reason != "" {
+			reasons = append(reasons, fmt.Sprintf("object type %s: %s", objectType, reason))
+		}
+	}
+	for sourceName, count := range deletionsBySource {
+		threshold, ok := dt.Sources[sourceName]
+		if !ok {
+			continue
+		}
+		if reason := thresholdExceeded(threshold, count, managedBySource[sourceName]); reason != "" {
+			reasons = append(reasons, fmt.Sprintf("source %s: %s", sourceName, reason))
+		}
+	}
+	slices.Sort(reasons)
+	return reasons
+}
+
+// thresholdExceeded returns the reason why deleting count out of total
+// managed objects exceeds the threshold, or empty string if it doesn't.
+func thresholdExceeded(threshold parser.DeletionThreshold, count int, total int) string {
+	if threshold.MaxObjects > 0 && count > threshold.MaxObjects {
+		return fmt.Sprintf("%d deletions exceed maxObjects %d", count, threshold.MaxObjects)
+	}
+	if threshold.MaxPercent > 0 && total > 0 {
+		percent := float64(count) * 100 / float64(total) //nolint:mnd
+		if percent > threshold.MaxPercent {
+			return fmt.Sprintf(
+				"%d deletions (%.1f%% of %d objects) exceed maxPercent %g",
+				count,
+				percent,
+				total,
+				threshold.MaxPercent,
+			)
+		}
+	}
+	return ""
+}
+
+// PendingDeletion is an object, which wasn't deleted because a deletion threshold was exceeded.
+type PendingDeletion struct {
+	ObjectType constants.ContentType `json:"object_type"`
+	APIPath    constants.APIPath     `json:"api_path"`
+	ID         int                   `json:"id"`
+	// Name is a human readable representation of the object.
+	Name string `json:"name"`
+	// Source is name of the source which owns the object.
+	Source string `json:"source"`
+	// MarkOrphan is set, if the object is only marked as orphan (soft deletion).
+	MarkOrphan bool `json:"mark_orphan,omitempty"`
+}
+
+// PendingDeletions are deletions which wait for approval. They are applied
+// with the --approve-deletions flag.
+type PendingDeletions struct {
+	CreatedAt time.Time `json:"created_at"`
+	// Reasons are the exceeded thresholds.
+	Reasons []string `json:"reasons"`
+	// Deletions are in the order in which they have to be applied.
+	Deletions []PendingDeletion `json:"deletions"`
+}
+
+// NewPendingDeletions creates PendingDeletions of orphanItems.
+func NewPendingDeletions(reasons []string, orphanItems []objects.OrphanItem) *PendingDeletions {
+	pending := &PendingDeletions{
+		CreatedAt: time.Now(),
+		Reasons:   reasons,
+		Deletions: make([]PendingDeletion, 0, len(orphanItems)),
+	}
+	for _, orphanItem := range orphanItems {
+		pending.Deletions = append(pending.Deletions, PendingDeletion{
+			ObjectType: orphanItem.GetObjectType(),
+			APIPath:    orphanItem.GetAPIPath(),
+			ID:         orphanItem.GetID(),
+			Name:       fmt.Sprint(orphanItem),
+			Source:     ItemSource(orphanItem),
+		})
+	}
+	return pending
+}
+
+// WriteFile writes pending deletions as JSON to path.
+func (pd *PendingDeletions) WriteFile(path string) error {
+	data, err := json.MarshalIndent(pd, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal pending deletions: %s", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil { //nolint:mnd
+		return fmt.Errorf("write pending deletions: %s", err)
+	}
+	return nil
+}
+
+// ReadPendingDeletions reads pending deletions written by WriteFile.
+func ReadPendingDeletions(path string) (*PendingDeletions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read pending deletions: %s", err)
+	}
+	var pending PendingDeletions
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, fmt.Errorf("parse pending deletions %s: %s", path, err)
+	}
+	return &pending, nil
+}
+
+// DeletionThresholdError is returned by DeleteOrphans, when deletions exceed thresholds.
+type DeletionThresholdError struct {
+	Pending *PendingDeletions
+}
+
+func (e *DeletionThresholdError) Error() string {
+	return fmt.Sprintf(
+		"deletion of %d orphaned objects stopped, because deletion thresholds were exceeded: %s",
+		len(e.Pending.Deletions),
+		strings.Join(e.Pending.Reasons, "; "),
+	)
+}
+
+// ApplyPendingDeletions hard deletes objects of pending deletions, or marks them as
+// orphans, if MarkOrphan is set. Only objects which are still managed by netbox-ssot
+// and are in the orphan manager are deleted, others are skipped. Objects updated after
+// the pending deletions were created (e.g. reported by their source again) are skipped
+// as well, and so are hard deletions of orphans, which wouldn't be hard deleted anymore.
+// It returns number of deleted or marked objects.
+func (nbi *NetboxInventory) ApplyPendingDeletions(pending *PendingDeletions) (int, error) {
+	lastUpdated, err := nbi.pendingDeletionsLastUpdated(pending)
+	if err != nil {
+		return 0, fmt.Errorf("get last updated times: %s", err)
+	}
+	deleted := 0
+	var errs []error
+	for _, deletion := range pending.Deletions {
+		orphanItem, ok := nbi.OrphanManager.GetItem(deletion.APIPath, deletion.ID)
+		if !ok {
+			nbi.Logger.Warningf(
+				nbi.Ctx,
+				"Skipping deletion of %s (ID: %d) at %s, because it is no longer managed by netbox-ssot",
+				deletion.Name,
+				deletion.ID,
+				deletion.APIPath,
+			)
+			continue
+		}
+		if updated, ok := lastUpdated[deletion.APIPath][deletion.ID]; ok && updated.After(pending.CreatedAt) {
+			nbi.Logger.Warningf(
+				nbi.Ctx,
+				"Skipping deletion of %s (ID: %d) at %s, because it was updated at %s, after the deletion was stopped",
+				deletion.Name,
+				deletion.ID,
+				deletion.APIPath,
+				updated.Format(time.RFC3339),
+			)
+			continue
+		}
+		if !deletion.MarkOrphan && !nbi.NetboxConfig.RemoveOrphans && !nbi.isExpiredOrphan(orphanItem) {
+			nbi.Logger.Warningf(
+				nbi.Ctx,
+				"Skipping deletion of %s (ID: %d) at %s, because it is no longer an expired orphan",
+				deletion.Name,
+				deletion.ID,
+				deletion.APIPath,
+			)
+			continue
+		}
+		applyDeletion := nbi.hardDelete
+		if deletion.MarkOrphan {
+			applyDeletion = nbi.softDelete
+		}
+		if err := applyDeletion(orphanItem); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		deleted++
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// pendingDeletionsLastUpdated returns last_updated timestamps of objects of pending
+// deletions, by their API paths and IDs. Objects, which no longer exist, are left out.
+func (nbi *NetboxInventory) pendingDeletionsLastUpdated(
+	pending *PendingDeletions,
+) (map[constants.APIPath]map[int]time.Time, error) {
+	idsByAPIPath := map[constants.APIPath][]int{}
+	for _, deletion := range pending.Deletions {
+		idsByAPIPath[deletion.APIPath] = append(idsByAPIPath[deletion.APIPath], deletion.ID)
+	}
+	lastUpdated := make(map[constants.APIPath]map[int]time.Time, len(idsByAPIPath))
+	for apiPath, ids := range idsByAPIPath {
+		lastUpdated[apiPath] = make(map[int]time.Time, len(ids))
+		for chunk := range slices.Chunk(ids, snapshotIDsPerRequest) {
+			idFilter := make([]string, 0, len(chunk))
+			for _, id := range chunk {
+				idFilter = append(idFilter, fmt.Sprintf("&id=%d", id))
+			}
+			results, err := service.GetAllRawAt(
+				nbi.Ctx,
+				nbi.NetboxAPI,
+				apiPath,
+				"&fields=id,last_updated"+strings.Join(idFilter, ""),
+			)
+			if err != nil {
+				return nil, err
+			}
+			for _, object := range results {
+				var fields snapshotObject
+				if err := json.Unmarshal(object, &fields); err != nil {
+					return nil, fmt.Errorf("parse object at %s: %s", apiPath, err)
+				}
+				if fields.LastUpdated != nil {
+					lastUpdated[apiPath][fields.ID] = *fields.LastUpdated
+				}
+			}
+		}
+	}
+	return lastUpdated, nil
+}
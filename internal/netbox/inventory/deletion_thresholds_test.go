@@ -0,0 +1,263 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/logger"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/service"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+)
+
+func managedVM(id int, sourceName string) *objects.VM {
+	vm := &objects.VM{NetboxObject: objects.NetboxObject{ID: id}, Name: "vm"}
+	vm.Tags = []*objects.Tag{{Name: constants.SsotTagName}}
+	vm.SetCustomField(constants.CustomFieldSourceName, sourceName)
+	return vm
+}
+
+func TestDeletionThresholds_Check(t *testing.T) {
+	deletions := []objects.OrphanItem{
+		managedVM(1, "vmware"),
+		managedVM(2, "vmware"),
+		managedVM(3, "ovirt"),
+		deviceOwnedBy("vmware"),
+	}
+	managedByAPIPath := map[constants.APIPath]int{
+		constants.VirtualMachinesAPIPath: 10,
+		constants.DevicesAPIPath:         100,
+	}
+	managedBySource := map[string]int{"vmware": 5, "ovirt": 100}
+	tests := []struct {
+		name       string
+		thresholds DeletionThresholds
+		want       []string
+	}{
+		{
+			name:       "No thresholds",
+			thresholds: DeletionThresholds{},
+		},
+		{
+			name:       "Thresholds are not exceeded",
+			thresholds: DeletionThresholds{Default: parser.DeletionThreshold{MaxObjects: 3, MaxPercent: 30}},
+		},
+		{
+			name:       "Default max objects exceeded",
+			thresholds: DeletionThresholds{Default: parser.DeletionThreshold{MaxObjects: 2}},
+			want:       []string{"object type virtualization.virtualmachine: 3 deletions exceed maxObjects 2"},
+		},
+		{
+			name: "Object type threshold overrides default",
+			thresholds: DeletionThresholds{
+				Default: parser.DeletionThreshold{MaxObjects: 2},
+				ObjectTypes: map[constants.ContentType]parser.DeletionThreshold{
+					constants.ContentTypeVirtualizationVirtualMachine: {MaxPercent: 50},
+				},
+			},
+		},
+		{
+			name: "Source max percent exceeded",
+			thresholds: DeletionThresholds{
+				Sources: map[string]parser.DeletionThreshold{
+					"vmware": {MaxPercent: 50},
+					"ovirt":  {MaxPercent: 50},
+				},
+			},
+			want: []string{"source vmware: 3 deletions (60.0% of 5 objects) exceed maxPercent 50"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.thresholds.Check(deletions, managedByAPIPath, managedBySource)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// deleteTestServer accepts deletions of single objects and records their paths.
+// It lists objects in lastUpdated with their last_updated timestamps by their IDs.
+type deleteTestServer struct {
+	lock        sync.Mutex
+	deleted     []string
+	lastUpdated map[int]time.Time
+}
+
+func (s *deleteTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if r.Method == http.MethodGet {
+		results := []map[string]any{}
+		for _, rawID := range r.URL.Query()["id"] {
+			id, _ := strconv.Atoi(rawID)
+			if lastUpdated, ok := s.lastUpdated[id]; ok {
+				results = append(results, map[string]any{"id": id, "last_updated": lastUpdated})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"count": len(results), "results": results})
+		return
+	}
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s.deleted = append(s.deleted, r.URL.Path)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newDeleteTestInventory(t *testing.T, vms ...*objects.VM) (*NetboxInventory, *deleteTestServer) {
+	t.Helper()
+	testServer := &deleteTestServer{}
+	server := httptest.NewServer(testServer)
+	t.Cleanup(server.Close)
+	testLogger := &logger.Logger{Logger: log.New(io.Discard, "", 0)}
+	nbi := &NetboxInventory{
+		Ctx:          context.Background(),
+		Logger:       testLogger,
+		NetboxConfig: &parser.NetboxConfig{},
+		NetboxAPI: &service.NetboxClient{
+			HTTPClient: &http.Client{},
+			Logger:     testLogger,
+			BaseURL:    server.URL,
+			Timeout:    constants.DefaultAPITimeout,
+		},
+		OrphanManager: NewOrphanManager(testLogger),
+	}
+	nbi.OrphanManager.Ctx = context.Background()
+	nbi.OrphanManager.Tag = &objects.Tag{ID: 2, Name: constants.OrphanTagName}
+	for _, vm := range vms {
+		nbi.OrphanManager.AddItem(vm)
+	}
+	return nbi, testServer
+}
+
+func TestNetboxInventory_DeleteOrphans_Thresholds(t *testing.T) {
+	tests := []struct {
+		name        string
+		soft        bool
+		thresholds  *DeletionThresholds
+		wantDeleted int
+		wantPending int
+	}{
+		{
+			name:        "Without thresholds",
+			thresholds:  nil,
+			wantDeleted: 3,
+		},
+		{
+			name:        "Threshold not exceeded",
+			thresholds:  &DeletionThresholds{Default: parser.DeletionThreshold{MaxObjects: 3}},
+			wantDeleted: 3,
+		},
+		{
+			name:        "Threshold exceeded",
+			thresholds:  &DeletionThresholds{Default: parser.DeletionThreshold{MaxPercent: 50}},
+			wantPending: 3,
+		},
+		{
+			name:        "Orphan markings exceed threshold",
+			soft:        true,
+			thresholds:  &DeletionThresholds{Default: parser.DeletionThreshold{MaxObjects: 2}},
+			wantPending: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nbi, testServer := newDeleteTestInventory(
+				t,
+				managedVM(1, "vmware"),
+				managedVM(2, "vmware"),
+				managedVM(3, "vmware"),
+				managedVM(4, "vmware"),
+			)
+			// VM 4 was synced, so it is not an orphan
+			nbi.OrphanManager.RemoveItem(managedVM(4, "vmware"))
+
+			err := nbi.DeleteOrphans(!tt.soft, nil, tt.thresholds)
+			var thresholdErr *DeletionThresholdError
+			if tt.wantPending > 0 {
+				if !errors.As(err, &thresholdErr) {
+					t.Fatalf("DeleteOrphans() error = %v, want DeletionThresholdError", err)
+				}
+				if len(thresholdErr.Pending.Deletions) != tt.wantPending {
+					t.Errorf("DeleteOrphans() pending deletions = %v", thresholdErr.Pending.Deletions)
+				}
+				for _, deletion := range thresholdErr.Pending.Deletions {
+					if deletion.MarkOrphan != tt.soft {
+						t.Errorf("DeleteOrphans() pending deletion %+v, want MarkOrphan %t", deletion, tt.soft)
+					}
+				}
+			} else if err != nil {
+				t.Fatalf("DeleteOrphans() error = %s", err)
+			}
+			if len(testServer.deleted) != tt.wantDeleted {
+				t.Errorf("DeleteOrphans() deleted %v, want %d objects", testServer.deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestNetboxInventory_ApplyPendingDeletions(t *testing.T) {
+	pending := NewPendingDeletions(
+		[]string{"object type virtualization.virtualmachine: 3 deletions exceed maxObjects 1"},
+		[]objects.OrphanItem{managedVM(1, "vmware"), managedVM(2, "vmware"), managedVM(3, "vmware")},
+	)
+	path := filepath.Join(t.TempDir(), "pending.json")
+	if err := pending.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile() error = %s", err)
+	}
+	readPending, err := ReadPendingDeletions(path)
+	if err != nil {
+		t.Fatalf("ReadPendingDeletions() error = %s", err)
+	}
+	if !reflect.DeepEqual(readPending.Deletions, pending.Deletions) {
+		t.Errorf("ReadPendingDeletions() = %+v, want %+v", readPending.Deletions, pending.Deletions)
+	}
+
+	// VM 2 is no longer managed by netbox-ssot, and VM 3 was reported by its source
+	// again after the pending deletions were written, so they are skipped
+	nbi, testServer := newDeleteTestInventory(t, managedVM(1, "vmware"), managedVM(3, "vmware"))
+	nbi.NetboxConfig.RemoveOrphans = true
+	testServer.lastUpdated = map[int]time.Time{
+		1: pending.CreatedAt.Add(-time.Hour),
+		3: pending.CreatedAt.Add(time.Minute),
+	}
+	deleted, err := nbi.ApplyPendingDeletions(readPending)
+	if err != nil {
+		t.Fatalf("ApplyPendingDeletions() error = %s", err)
+	}
+	wantDeleted := []string{string(constants.VirtualMachinesAPIPath) + "1/"}
+	if deleted != 1 || !reflect.DeepEqual(testServer.deleted, wantDeleted) {
+		t.Errorf("ApplyPendingDeletions() deleted %d: %v, want %v", deleted, testServer.deleted, wantDeleted)
+	}
+}
+
+func TestNetboxInventory_ApplyPendingDeletions_NotExpiredOrphan(t *testing.T) {
+	pending := NewPendingDeletions(
+		[]string{"object type virtualization.virtualmachine: 1 deletions (100.0% of 1 objects) exceed maxPercent 50"},
+		[]objects.OrphanItem{managedVM(1, "vmware")},
+	)
+	// Orphans are soft deleted, and VM 1 isn't marked as orphan anymore
+	nbi, testServer := newDeleteTestInventory(t, managedVM(1, "vmware"))
+	deleted, err := nbi.ApplyPendingDeletions(pending)
+	if err != nil {
+		t.Fatalf("ApplyPendingDeletions() error = %s", err)
+	}
+	if deleted != 0 || len(testServer.deleted) != 0 {
+		t.Errorf("ApplyPendingDeletions() deleted %d: %v, want none", deleted, testServer.deleted)
+	}
+}
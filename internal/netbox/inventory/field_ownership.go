@@ -0,0 +1,174 @@
+package inventory
+
+import (
+	"context"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/utils"
+)
+
+// Prefix of custom fields in field ownership rules, e.g. custom_fields.uuid.
+const customFieldsPrefix = "custom_fields."
+
+// fieldOwnership decides, which fields of an existing object are owned by netbox-ssot.
+// Fields, which aren't owned, are set only when netbox-ssot creates the object.
+type fieldOwnership struct {
+	// createOnlyAll is true when no field is owned, except constants.SsotOwnedFields.
+	createOnlyAll bool
+	// owned are the only owned fields. If empty, all fields except createOnly are owned.
+	owned []string
+	// createOnly are fields, which aren't owned.
+	createOnly []string
+}
+
+// owns returns true if field (as named in the Netbox API) is owned by netbox-ssot.
+func (o *fieldOwnership) owns(field string) bool {
+	if slices.Contains(constants.SsotOwnedFields, field) {
+		return true
+	}
+	if o.createOnlyAll {
+		return false
+	}
+	if len(o.owned) > 0 && !matchesField(o.owned, field) {
+		return false
+	}
+	return !matchesField(o.createOnly, field)
+}
+
+// matchesField returns true if field is in fields. Custom fields are
+// matched either by their name (custom_fields.<name>), or by custom_fields.
+func matchesField(fields []string, field string) bool {
+	return slices.Contains(fields, field) ||
+		(strings.HasPrefix(field, customFieldsPrefix) && slices.Contains(fields, "custom_fields"))
+}
+
+// fieldOwnershipOf returns ownership of fields of the existing object. Ownership
+// configured for the object type is narrowed by the create-only tag, which makes
+// all fields create-only, and by fields listed in the create-only custom field.
+func (nbi *NetboxInventory) fieldOwnershipOf(existingObj objects.OrphanItem) *fieldOwnership {
+	ownership := &fieldOwnership{}
+	if nbi.NetboxConfig != nil {
+		if rule, ok := nbi.NetboxConfig.FieldOwnership[existingObj.GetObjectType()]; ok {
+			ownership.owned = rule.Owned
+			ownership.createOnly = slices.Clone(rule.CreateOnly)
+		}
+	}
+	netboxObject := existingObj.GetNetboxObject()
+	if netboxObject.HasTagByName(constants.CreateOnlyTagName) {
+		ownership.createOnlyAll = true
+	}
+	if fields, ok := netboxObject.GetCustomField(constants.CustomFieldCreateOnlyFieldsName).(string); ok {
+		for _, field := range strings.Split(fields, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				ownership.createOnly = append(ownership.createOnly, field)
+			}
+		}
+	}
+	return ownership
+}
+
+// diffMap returns fields of newObj, which are different from existingObj,
+// and have to be patched. Fields, which netbox-ssot doesn't own, are preserved.
+// Priority of newObj is decided for each field with a field source priority,
+// and the chosen sources are recorded in the existing object.
+func (nbi *NetboxInventory) diffMap(
+	ctx context.Context,
+	newObj, existingObj interface{},
+) (map[string]interface{}, error) {
+	existingItem, ok := existingObj.(objects.OrphanItem)
+	if !ok {
+		return utils.JSONDiffMapExceptID(newObj, existingObj, false, nbi.SourcePriority)
+	}
+	ownership := nbi.fieldOwnershipOf(existingItem)
+	preserved := preserveFields(newObj, existingObj, ownership)
+	if len(preserved) > 0 {
+		nbi.Logger.Debugf(
+			ctx,
+			"Preserving fields %v of %T with ID %d, because they are set only on create",
+			preserved,
+			existingObj,
+			existingItem.GetID(),
+		)
+	}
+	priority := nbi.fieldPriorityOf(newObj, existingItem)
+	diff, err := utils.JSONDiffMapWithFieldPriority(newObj, existingObj, false, priority.hasPriority)
+	if err != nil {
+		return nil, err
+	}
+	fieldSources := nbi.recordFieldSources(ctx, diff, existingItem, priority, ownership)
+	nbi.reportFieldSources(existingItem, priority, fieldSources)
+	return diff, nil
+}
+
+// preserveFields sets all fields of newObj, which aren't owned, to their
+// values in existingObj, so they aren't patched. Both objects must be pointers
+// to structs of the same type. It returns names of the preserved fields,
+// whose values were different.
+func preserveFields(newObj, existingObj interface{}, ownership *fieldOwnership) []string {
+	newValue := reflect.ValueOf(newObj)
+	existingValue := reflect.ValueOf(existingObj)
+	if newValue.Kind() != reflect.Pointer || newValue.IsNil() || existingValue.Type() != newValue.Type() ||
+		existingValue.IsNil() {
+		return nil
+	}
+	return preserveStructFields(newValue.Elem(), existingValue.Elem(), ownership)
+}
+
+func preserveStructFields(newStruct, existingStruct reflect.Value, ownership *fieldOwnership) []string {
+	var preserved []string
+	for i := range newStruct.NumField() {
+		field := newStruct.Type().Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			// Fields of embedded NetboxObject
+			preserved = append(preserved, preserveStructFields(newStruct.Field(i), existingStruct.Field(i), ownership)...)
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || name == "id" || !newStruct.Field(i).CanSet() {
+			continue
+		}
+		if name == "custom_fields" {
+			preserved = append(preserved, preserveCustomFields(newStruct.Field(i), existingStruct.Field(i), ownership)...)
+			continue
+		}
+		if ownership.owns(name) {
+			continue
+		}
+		if !reflect.DeepEqual(newStruct.Field(i).Interface(), existingStruct.Field(i).Interface()) {
+			preserved = append(preserved, name)
+		}
+		newStruct.Field(i).Set(existingStruct.Field(i))
+	}
+	return preserved
+}
+
+// preserveCustomFields sets custom fields of the new object, which aren't owned,
+// to their values in the existing object. Custom fields are map[string]interface{}.
+func preserveCustomFields(newFields, existingFields reflect.Value, ownership *fieldOwnership) []string {
+	newMap, ok := newFields.Interface().(map[string]interface{})
+	if !ok || newMap == nil {
+		return nil
+	}
+	existingMap, _ := existingFields.Interface().(map[string]interface{})
+	var preserved []string
+	for _, name := range slices.Sorted(maps.Keys(newMap)) {
+		if ownership.owns(customFieldsPrefix + name) {
+			continue
+		}
+		existingValue, exists := existingMap[name]
+		if !reflect.DeepEqual(newMap[name], existingValue) {
+			preserved = append(preserved, customFieldsPrefix+name)
+		}
+		if exists {
+			newMap[name] = existingValue
+		} else {
+			delete(newMap, name)
+		}
+	}
+	return preserved
+}
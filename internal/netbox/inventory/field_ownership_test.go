@@ -0,0 +1,176 @@
+package inventory
+
+import (
+	"context"
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+)
+
+func TestFieldOwnership_owns(t *testing.T) {
+	tests := []struct {
+		name      string
+		ownership fieldOwnership
+		field     string
+		want      bool
+	}{
+		{name: "All fields are owned by default", field: "tenant", want: true},
+		{
+			name:      "Create-only field",
+			ownership: fieldOwnership{createOnly: []string{"tenant"}},
+			field:     "tenant",
+			want:      false,
+		},
+		{
+			name:      "Field, which is not create-only",
+			ownership: fieldOwnership{createOnly: []string{"tenant"}},
+			field:     "serial",
+			want:      true,
+		},
+		{
+			name:      "Field, which is not owned",
+			ownership: fieldOwnership{owned: []string{"serial"}},
+			field:     "tenant",
+			want:      false,
+		},
+		{
+			name:      "Custom field matched by custom_fields",
+			ownership: fieldOwnership{createOnly: []string{"custom_fields"}},
+			field:     "custom_fields.host_cpu_cores",
+			want:      false,
+		},
+		{
+			name:      "Custom field matched by name",
+			ownership: fieldOwnership{owned: []string{"custom_fields.uuid"}},
+			field:     "custom_fields.uuid",
+			want:      true,
+		},
+		{
+			name:      "Tags are always owned",
+			ownership: fieldOwnership{createOnlyAll: true},
+			field:     "tags",
+			want:      true,
+		},
+		{
+			name:      "Source custom field is always owned",
+			ownership: fieldOwnership{owned: []string{"serial"}},
+			field:     "custom_fields." + constants.CustomFieldSourceName,
+			want:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ownership.owns(tt.field); got != tt.want {
+				t.Errorf("owns(%s) = %t, want %t", tt.field, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetboxInventory_diffMap_FieldOwnership(t *testing.T) {
+	existingDevice := func(tags []*objects.Tag, createOnlyFields string) *objects.Device {
+		customFields := map[string]interface{}{
+			constants.CustomFieldSourceName:         "vmware",
+			constants.CustomFieldHostCPUCoresName:   "4",
+			constants.CustomFieldOrphanLastSeenName: nil,
+		}
+		if createOnlyFields != "" {
+			customFields[constants.CustomFieldCreateOnlyFieldsName] = createOnlyFields
+		}
+		return &objects.Device{
+			NetboxObject: objects.NetboxObject{
+				ID:           1,
+				Tags:         tags,
+				Description:  "Edited by NOC",
+				CustomFields: customFields,
+			},
+			Name:         "server1",
+			Tenant:       &objects.Tenant{NetboxObject: objects.NetboxObject{ID: 1}, Name: "NOC"},
+			SerialNumber: "OLD",
+		}
+	}
+	newDevice := func() *objects.Device {
+		return &objects.Device{
+			NetboxObject: objects.NetboxObject{
+				Tags:        []*objects.Tag{MockInventory.SsotTag},
+				Description: "Synced from vmware",
+				CustomFields: map[string]interface{}{
+					constants.CustomFieldSourceName:         "vmware",
+					constants.CustomFieldHostCPUCoresName:   "8",
+					constants.CustomFieldOrphanLastSeenName: nil,
+				},
+			},
+			Name:         "server1",
+			Tenant:       &objects.Tenant{NetboxObject: objects.NetboxObject{ID: 2}, Name: "Default"},
+			SerialNumber: "NEW",
+		}
+	}
+	createOnlyTag := &objects.Tag{ID: 3, Name: constants.CreateOnlyTagName}
+
+	tests := []struct {
+		name           string
+		fieldOwnership map[constants.ContentType]parser.FieldOwnership
+		existing       *objects.Device
+		wantFields     []string
+	}{
+		{
+			name:       "All fields are owned",
+			existing:   existingDevice(nil, ""),
+			wantFields: []string{"custom_fields", "description", "serial", "tags", "tenant"},
+		},
+		{
+			name: "Create-only fields of the object type",
+			fieldOwnership: map[constants.ContentType]parser.FieldOwnership{
+				constants.ContentTypeDcimDevice: {CreateOnly: []string{"tenant", "description"}},
+			},
+			existing:   existingDevice(nil, ""),
+			wantFields: []string{"custom_fields", "serial", "tags"},
+		},
+		{
+			name: "Owned fields of the object type",
+			fieldOwnership: map[constants.ContentType]parser.FieldOwnership{
+				constants.ContentTypeDcimDevice: {Owned: []string{"serial"}},
+			},
+			existing:   existingDevice(nil, ""),
+			wantFields: []string{"serial", "tags"},
+		},
+		{
+			name: "Rules of other object types don't apply",
+			fieldOwnership: map[constants.ContentType]parser.FieldOwnership{
+				constants.ContentTypeVirtualizationVirtualMachine: {CreateOnly: []string{"tenant"}},
+			},
+			existing:   existingDevice(nil, ""),
+			wantFields: []string{"custom_fields", "description", "serial", "tags", "tenant"},
+		},
+		{
+			name:       "Create-only tag",
+			existing:   existingDevice([]*objects.Tag{createOnlyTag}, ""),
+			wantFields: []string{"tags"},
+		},
+		{
+			name:       "Create-only custom field",
+			existing:   existingDevice(nil, "tenant, custom_fields.host_cpu_cores"),
+			wantFields: []string{"description", "serial", "tags"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nbi := &NetboxInventory{
+				Logger:       mockLogger,
+				NetboxConfig: &parser.NetboxConfig{FieldOwnership: tt.fieldOwnership},
+			}
+			diffMap, err := nbi.diffMap(context.Background(), newDevice(), tt.existing)
+			if err != nil {
+				t.Fatalf("diffMap() error = %s", err)
+			}
+			if got := slices.Sorted(maps.Keys(diffMap)); !reflect.DeepEqual(got, tt.wantFields) {
+				t.Errorf("diffMap() fields = %v, want %v", got, tt.wantFields)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/utils"
+)
+
+// fieldPriority decides for each field of an existing object, whether the new
+// object has priority over it. Fields with a field source priority are decided
+// by ranks of their sources, other fields by priority of the whole object.
+type fieldPriority struct {
+	// rules are priorities of sources for fields of the object type.
+	rules map[string][]string
+	// objectPriority is priority of the new object over the whole existing object.
+	objectPriority bool
+	// arpEntry is true if only one of the objects is an arp entry.
+	// Arp entries always lose, so such fields are decided by objectPriority.
+	arpEntry bool
+	// newSource is the source of the new object.
+	newSource string
+	// existingSource is the source of the existing object.
+	existingSource string
+	// sources are recorded sources, which set fields of the existing object.
+	sources map[string]string
+	// chosen are sources chosen for fields with rules.
+	chosen map[string]string
+}
+
+// hasPriority implements utils.FieldPriority.
+func (p *fieldPriority) hasPriority(field string) (bool, bool) {
+	priorities, ok := p.rules[field]
+	if !ok || p.newSource == "" {
+		return p.objectPriority, false
+	}
+	existingSource := p.existingSource
+	if source, ok := p.sources[field]; ok {
+		existingSource = source
+	}
+	newRank := sourceRank(priorities, p.newSource)
+	existingRank := sourceRank(priorities, existingSource)
+	var hasPriority bool
+	switch {
+	case p.arpEntry:
+		hasPriority = p.objectPriority
+	case existingSource == "":
+		hasPriority = true
+	case newRank == len(priorities) && existingRank == len(priorities):
+		// Neither of the sources is listed
+		hasPriority = p.objectPriority
+	default:
+		hasPriority = newRank <= existingRank
+	}
+	if hasPriority {
+		p.chosen[field] = p.newSource
+	} else if existingSource != "" {
+		p.chosen[field] = existingSource
+	}
+	return hasPriority, true
+}
+
+// sourceRank returns index of source in priorities. Sources,
+// which aren't listed, are ranked after all listed sources.
+func sourceRank(priorities []string, source string) int {
+	if rank := slices.Index(priorities, source); rank >= 0 {
+		return rank
+	}
+	return len(priorities)
+}
+
+// fieldPriorityOf returns priority of newObj over fields of the existing object.
+func (nbi *NetboxInventory) fieldPriorityOf(newObj interface{}, existingItem objects.OrphanItem) *fieldPriority {
+	priority := &fieldPriority{
+		objectPriority: utils.HasPriorityOver(newObj, existingItem, nbi.SourcePriority),
+		chosen:         map[string]string{},
+	}
+	newItem, ok := newObj.(objects.OrphanItem)
+	if !ok || nbi.NetboxConfig == nil {
+		return priority
+	}
+	priority.rules = nbi.NetboxConfig.FieldSourcePriority[existingItem.GetObjectType()]
+	newObject := newItem.GetNetboxObject()
+	existingObject := existingItem.GetNetboxObject()
+	priority.newSource, _ = newObject.GetCustomField(constants.CustomFieldSourceName).(string)
+	priority.existingSource, _ = existingObject.GetCustomField(constants.CustomFieldSourceName).(string)
+	newArpEntry, _ := newObject.GetCustomField(constants.CustomFieldArpEntryName).(bool)
+	existingArpEntry, _ := existingObject.GetCustomField(constants.CustomFieldArpEntryName).(bool)
+	priority.arpEntry = newArpEntry != existingArpEntry
+	priority.sources = parseFieldSources(existingObject.GetCustomField(constants.CustomFieldFieldSourcesName))
+	return priority
+}
+
+// parseFieldSources parses value of the field sources custom field,
+// which is a JSON object of fields and their sources.
+func parseFieldSources(value interface{}) map[string]string {
+	sources := map[string]string{}
+	if value, ok := value.(string); ok && value != "" {
+		if err := json.Unmarshal([]byte(value), &sources); err != nil {
+			return map[string]string{}
+		}
+	}
+	return sources
+}
+
+// recordFieldSources adds sources chosen for owned fields to the field sources
+// custom field in diff, if they are different from the recorded sources.
+// It returns sources of the fields after the diff is patched.
+func (nbi *NetboxInventory) recordFieldSources(
+	ctx context.Context,
+	diff map[string]interface{},
+	existingItem objects.OrphanItem,
+	priority *fieldPriority,
+	ownership *fieldOwnership,
+) map[string]string {
+	sources := maps.Clone(priority.sources)
+	for field, source := range priority.chosen {
+		if ownership.owns(field) {
+			sources[field] = source
+		}
+	}
+	if maps.Equal(sources, priority.sources) {
+		return sources
+	}
+	// Keys of maps are sorted when marshalled
+	value, err := json.Marshal(sources)
+	if err != nil {
+		nbi.Logger.Warningf(
+			ctx, "Failed to record field sources of %T with ID %d: %s", existingItem, existingItem.GetID(), err,
+		)
+		return priority.sources
+	}
+	nbi.Logger.Debugf(ctx, "Fields of %T with ID %d are set by sources %v", existingItem, existingItem.GetID(), sources)
+	customFields, ok := diff["custom_fields"].(map[string]interface{})
+	if !ok {
+		// Netbox merges patched custom fields with the existing ones
+		customFields = map[string]interface{}{}
+		diff["custom_fields"] = customFields
+	}
+	customFields[constants.CustomFieldFieldSourcesName] = string(value)
+	return sources
+}
+
+// reportedObject identifies an object with field source priority.
+type reportedObject struct {
+	objectType constants.ContentType
+	id         int
+}
+
+// objectReports are sources, which reported an object in the current run,
+// together with the sources recorded for its fields.
+type objectReports struct {
+	item         objects.OrphanItem
+	fieldSources map[string]string
+	sources      map[string]bool
+}
+
+// reportFieldSources remembers, that the new source of priority reported the existing
+// object in the current run, and fieldSources recorded for its fields.
+func (nbi *NetboxInventory) reportFieldSources(
+	existingItem objects.OrphanItem,
+	priority *fieldPriority,
+	fieldSources map[string]string,
+) {
+	if len(priority.rules) == 0 || priority.newSource == "" {
+		return
+	}
+	nbi.fieldSourceReportsLock.Lock()
+	defer nbi.fieldSourceReportsLock.Unlock()
+	if nbi.fieldSourceReports == nil {
+		nbi.fieldSourceReports = map[reportedObject]*objectReports{}
+	}
+	key := reportedObject{objectType: existingItem.GetObjectType(), id: existingItem.GetID()}
+	reports, ok := nbi.fieldSourceReports[key]
+	if !ok {
+		reports = &objectReports{sources: map[string]bool{}}
+		nbi.fieldSourceReports[key] = reports
+	}
+	reports.item = existingItem
+	reports.fieldSources = fieldSources
+	reports.sources[priority.newSource] = true
+}
+
+// ExpireFieldSources removes recorded sources of fields of objects reported in the
+// current run, if the recorded source is one of syncedSources, but it didn't report
+// the object. Fields are then decided by ranks of the sources, which still report
+// the object, from the next run on. syncedSources must include only sources, which
+// synced successfully, so objects of the failed sources aren't affected.
+func (nbi *NetboxInventory) ExpireFieldSources(syncedSources []string) {
+	nbi.fieldSourceReportsLock.Lock()
+	defer nbi.fieldSourceReportsLock.Unlock()
+	for _, reports := range nbi.fieldSourceReports {
+		sources := maps.Clone(reports.fieldSources)
+		maps.DeleteFunc(sources, func(_ string, source string) bool {
+			return slices.Contains(syncedSources, source) && !reports.sources[source]
+		})
+		if len(sources) == len(reports.fieldSources) {
+			continue
+		}
+		ctx := objectLogCtx(nbi.Ctx, reports.item)
+		value, err := json.Marshal(sources)
+		if err != nil {
+			nbi.Logger.Warningf(ctx, "Failed to expire field sources of %s: %s", reports.item, err)
+			continue
+		}
+		diffMap := map[string]interface{}{
+			"custom_fields": map[string]interface{}{constants.CustomFieldFieldSourcesName: string(value)},
+		}
+		if err := nbi.patchOrphanItem(ctx, reports.item, diffMap); err != nil {
+			nbi.Logger.Warningf(ctx, "Failed to expire field sources of %s: %s", reports.item, err)
+			continue
+		}
+		nbi.Logger.Debugf(ctx, "Field sources of %s expired, fields are set by sources %v", reports.item, sources)
+	}
+	nbi.fieldSourceReports = nil
+}
@@ -0,0 +1,203 @@
+package inventory
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+	"github.com/bl4ko/netbox-ssot/internal/utils"
+)
+
+func TestNetboxInventory_diffMap_FieldSourcePriority(t *testing.T) {
+	device := func(source string, serial string, platformID int, fieldSources string) *objects.Device {
+		customFields := map[string]interface{}{constants.CustomFieldSourceName: source}
+		if fieldSources != "" {
+			customFields[constants.CustomFieldFieldSourcesName] = fieldSources
+		}
+		return &objects.Device{
+			NetboxObject: objects.NetboxObject{CustomFields: customFields},
+			Name:         "server1",
+			SerialNumber: serial,
+			Platform:     &objects.Platform{NetboxObject: objects.NetboxObject{ID: platformID}},
+		}
+	}
+	fieldSourcePriority := map[constants.ContentType]map[string][]string{
+		constants.ContentTypeDcimDevice: {
+			"serial":   {"dnac", "vcenter"},
+			"platform": {"vcenter", "dnac"},
+		},
+	}
+
+	tests := []struct {
+		name                string
+		fieldSourcePriority map[constants.ContentType]map[string][]string
+		sourcePriority      map[string]int
+		newObj              *objects.Device
+		existingObj         *objects.Device
+		want                map[string]interface{}
+	}{
+		{
+			// Referenced objects are updated regardless of source priority
+			name:           "Whole object is decided by source priority without field source priority",
+			sourcePriority: map[string]int{"dnac": 0, "vcenter": 1},
+			newObj:         device("vcenter", "VC123", 1, ""),
+			existingObj:    device("dnac", "DNAC123", 2, ""),
+			want:           map[string]interface{}{"platform": 1},
+		},
+		{
+			name:                "Higher priority source of a field wins",
+			fieldSourcePriority: fieldSourcePriority,
+			sourcePriority:      map[string]int{"dnac": 0, "vcenter": 1},
+			newObj:              device("vcenter", "VC123", 1, ""),
+			existingObj:         device("dnac", "DNAC123", 2, ""),
+			want: map[string]interface{}{
+				"platform": 1,
+				"custom_fields": map[string]interface{}{
+					constants.CustomFieldFieldSourcesName: `{"platform":"vcenter","serial":"dnac"}`,
+				},
+			},
+		},
+		{
+			name:                "Recorded sources of fields are used",
+			fieldSourcePriority: fieldSourcePriority,
+			sourcePriority:      map[string]int{"dnac": 0, "vcenter": 1},
+			newObj:              device("dnac", "DNAC123", 2, ""),
+			existingObj:         device("dnac", "VC123", 1, `{"platform":"vcenter","serial":"vcenter"}`),
+			want: map[string]interface{}{
+				"serial": "DNAC123",
+				"custom_fields": map[string]interface{}{
+					constants.CustomFieldFieldSourcesName: `{"platform":"vcenter","serial":"dnac"}`,
+				},
+			},
+		},
+		{
+			name:                "Unchanged sources aren't recorded again",
+			fieldSourcePriority: fieldSourcePriority,
+			sourcePriority:      map[string]int{"dnac": 0, "vcenter": 1},
+			newObj:              device("vcenter", "VC123", 1, ""),
+			existingObj:         device("dnac", "DNAC123", 1, `{"platform":"vcenter","serial":"dnac"}`),
+			want:                map[string]interface{}{},
+		},
+		{
+			name: "Unlisted sources are ranked last",
+			fieldSourcePriority: map[constants.ContentType]map[string][]string{
+				constants.ContentTypeDcimDevice: {"serial": {"dnac"}},
+			},
+			sourcePriority: map[string]int{"vcenter": 0, "dnac": 1},
+			newObj:         device("vcenter", "VC123", 1, ""),
+			existingObj:    device("dnac", "DNAC123", 2, ""),
+			want: map[string]interface{}{
+				"platform": 1,
+				"custom_fields": map[string]interface{}{
+					constants.CustomFieldSourceName:       "vcenter",
+					constants.CustomFieldFieldSourcesName: `{"serial":"dnac"}`,
+				},
+			},
+		},
+		{
+			name:                "Arp entries always lose",
+			fieldSourcePriority: fieldSourcePriority,
+			newObj: func() *objects.Device {
+				newDevice := device("dnac", "DNAC123", 2, "")
+				newDevice.CustomFields[constants.CustomFieldArpEntryName] = true
+				return newDevice
+			}(),
+			existingObj: device("vcenter", "VC123", 1, ""),
+			want: map[string]interface{}{
+				"custom_fields": map[string]interface{}{
+					constants.CustomFieldArpEntryName:     true,
+					constants.CustomFieldFieldSourcesName: `{"platform":"vcenter","serial":"vcenter"}`,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nbi := &NetboxInventory{
+				Logger:         mockLogger,
+				NetboxConfig:   &parser.NetboxConfig{FieldSourcePriority: tt.fieldSourcePriority},
+				SourcePriority: tt.sourcePriority,
+			}
+			got, err := nbi.diffMap(context.Background(), tt.newObj, tt.existingObj)
+			if err != nil {
+				t.Fatalf("diffMap() error = %s", err)
+			}
+			if platform, ok := got["platform"].(utils.IDObject); ok {
+				got["platform"] = platform.ID
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetboxInventory_ExpireFieldSources(t *testing.T) {
+	fieldSourcePriority := map[constants.ContentType]map[string][]string{
+		constants.ContentTypeDcimDevice: {
+			"serial":   {"vcenter", "dnac"},
+			"platform": {"vcenter", "dnac"},
+		},
+	}
+	device := func(id int, source string, serial string, fieldSources string) *objects.Device {
+		return &objects.Device{
+			NetboxObject: objects.NetboxObject{
+				ID: id,
+				CustomFields: map[string]interface{}{
+					constants.CustomFieldSourceName:       source,
+					constants.CustomFieldFieldSourcesName: fieldSources,
+				},
+			},
+			Name:         "server1",
+			SerialNumber: serial,
+		}
+	}
+	tests := []struct {
+		name          string
+		syncedSources []string
+		want          map[string]interface{}
+	}{
+		{
+			name:          "Sources, which don't report the object anymore, expire",
+			syncedSources: []string{"vcenter", "dnac"},
+			want: map[string]interface{}{
+				"custom_fields": map[string]interface{}{
+					constants.CustomFieldFieldSourcesName: `{"platform":"dnac"}`,
+				},
+			},
+		},
+		{
+			name:          "Sources, which didn't sync, don't expire",
+			syncedSources: []string{"dnac"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nbi, testServer := newBulkTestInventory(t, false)
+			nbi.Ctx = context.Background()
+			nbi.NetboxConfig = &parser.NetboxConfig{FieldSourcePriority: fieldSourcePriority}
+			testServer.objects[1] = map[string]interface{}{}
+
+			// vcenter set the serial in a previous run, but only dnac reports the device now
+			existingDevice := device(1, "vcenter", "VC123", `{"platform":"dnac","serial":"vcenter"}`)
+			if _, err := nbi.diffMap(context.Background(), device(0, "dnac", "DNAC123", ""), existingDevice); err != nil {
+				t.Fatalf("diffMap() error = %s", err)
+			}
+			nbi.ExpireFieldSources(tt.syncedSources)
+
+			want := map[int]map[string]interface{}{1: {}}
+			if tt.want != nil {
+				want[1] = tt.want
+			}
+			if !reflect.DeepEqual(testServer.objects, want) {
+				t.Errorf("patched objects = %v, want %v", testServer.objects, want)
+			}
+			if nbi.fieldSourceReports != nil {
+				t.Errorf("reports of the run weren't reset")
+			}
+		})
+	}
+}
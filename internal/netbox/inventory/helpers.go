@@ -3,9 +3,12 @@ package inventory
 import (
 	"context"
 	"fmt"
+	"reflect"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/logger"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/report"
 	"github.com/bl4ko/netbox-ssot/internal/utils"
 )
 
@@ -207,3 +210,8 @@ func findPrefixAcrossVRFs(vrfMap map[int]*objects.Prefix) (int, *objects.Prefix)
 	}
 	return 0, nil
 }
+
+// objectLogCtx returns ctx, which adds type and ID of the existing object to log messages.
+func objectLogCtx(ctx context.Context, obj interface{ GetID() int }) context.Context {
+	return logger.WithObject(ctx, report.TypeName(reflect.TypeOf(obj)), obj.GetID())
+}
@@ -6,18 +6,13 @@ import (
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
-	"github.com/bl4ko/netbox-ssot/internal/netbox/service"
 	"github.com/bl4ko/netbox-ssot/internal/utils"
 )
 
 // Collect all tags from Netbox API and store them in the NetBoxInventory.
 func (nbi *NetboxInventory) initTags(ctx context.Context) error {
 	extraArgs := fmt.Sprintf("&fields=%s", utils.ExtractJSONTagsFromStructIntoString(objects.Tag{}))
-	nbTags, err := service.GetAll[objects.Tag](
-		ctx,
-		nbi.NetboxAPI,
-		extraArgs,
-	)
+	nbTags, err := getAll[objects.Tag](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -74,6 +69,20 @@ func (nbi *NetboxInventory) initTags(ctx context.Context) error {
 	}
 
 	nbi.IgnoreDeviceTypeTag = ignoreDeviceTypeTag
+
+	// Create default tag for preserving all manually set fields of objects
+	_, err = nbi.AddTag(
+		ctx,
+		&objects.Tag{
+			Name:        constants.CreateOnlyTagName,
+			Slug:        constants.CreateOnlyTagName,
+			Description: constants.CreateOnlyTagDescription,
+			Color:       constants.CreateOnlyTagColor,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error creating default create only tag: %s", err)
+	}
 	return nil
 }
 
@@ -83,7 +92,7 @@ func (nbi *NetboxInventory) initTenants(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.Tenant{}),
 	)
-	nbTenants, err := service.GetAll[objects.Tenant](ctx, nbi.NetboxAPI, extraArgs)
+	nbTenants, err := getAll[objects.Tenant](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -103,7 +112,7 @@ func (nbi *NetboxInventory) initContacts(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.Contact{}),
 	)
-	nbContacts, err := service.GetAll[objects.Contact](ctx, nbi.NetboxAPI, extraArgs)
+	nbContacts, err := getAll[objects.Contact](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -124,7 +133,7 @@ func (nbi *NetboxInventory) initContactRoles(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.ContactRole{}),
 	)
-	nbContactRoles, err := service.GetAll[objects.ContactRole](ctx, nbi.NetboxAPI, extraArgs)
+	nbContactRoles, err := getAll[objects.ContactRole](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -144,10 +153,11 @@ func (nbi *NetboxInventory) initContactRoles(ctx context.Context) error {
 
 func (nbi *NetboxInventory) initContactAssignments(ctx context.Context) error {
 	extraArgs := fmt.Sprintf(
-		"&fields=%s",
+		"&fields=%s%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.ContactAssignment{}),
+		nbi.tagFilter(),
 	)
-	nbCAs, err := service.GetAll[objects.ContactAssignment](ctx, nbi.NetboxAPI, extraArgs)
+	nbCAs, err := getAll[objects.ContactAssignment](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -200,7 +210,7 @@ func (nbi *NetboxInventory) initContactGroups(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.ContactGroup{}),
 	)
-	nbContactGroups, err := service.GetAll[objects.ContactGroup](ctx, nbi.NetboxAPI, extraArgs)
+	nbContactGroups, err := getAll[objects.ContactGroup](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -224,7 +234,7 @@ func (nbi *NetboxInventory) initSites(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.Site{}),
 	)
-	nbSites, err := service.GetAll[objects.Site](ctx, nbi.NetboxAPI, extraArgs)
+	nbSites, err := getAll[objects.Site](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -244,7 +254,7 @@ func (nbi *NetboxInventory) initLocations(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.Location{}),
 	)
-	nbLocations, err := service.GetAll[objects.Location](ctx, nbi.NetboxAPI, extraArgs)
+	nbLocations, err := getAll[objects.Location](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -263,7 +273,7 @@ func (nbi *NetboxInventory) initSiteGroups(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.SiteGroup{}),
 	)
-	nbSiteGroups, err := service.GetAll[objects.SiteGroup](ctx, nbi.NetboxAPI, extraArgs)
+	nbSiteGroups, err := getAll[objects.SiteGroup](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -307,7 +317,7 @@ func (nbi *NetboxInventory) initManufacturers(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.Manufacturer{}),
 	)
-	nbManufacturers, err := service.GetAll[objects.Manufacturer](ctx, nbi.NetboxAPI, extraArgs)
+	nbManufacturers, err := getAll[objects.Manufacturer](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -333,7 +343,7 @@ func (nbi *NetboxInventory) initPlatforms(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.Platform{}),
 	)
-	nbPlatforms, err := service.GetAll[objects.Platform](ctx, nbi.NetboxAPI, extraArgs)
+	nbPlatforms, err := getAll[objects.Platform](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -357,10 +367,11 @@ func (nbi *NetboxInventory) initPlatforms(ctx context.Context) error {
 // Collect all devices from Netbox API and store them in the NetBoxInventory.
 func (nbi *NetboxInventory) initDevices(ctx context.Context) error {
 	extraArgs := fmt.Sprintf(
-		"&fields=%s",
+		"&fields=%s%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.Device{}),
+		nbi.tagFilter(),
 	)
-	nbDevices, err := service.GetAll[objects.Device](ctx, nbi.NetboxAPI, extraArgs)
+	nbDevices, err := getAll[objects.Device](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -390,14 +401,11 @@ func (nbi *NetboxInventory) initDevices(ctx context.Context) error {
 // Collect all devices from Netbox API and store them in the NetBoxInventory.
 func (nbi *NetboxInventory) initVirtualDeviceContexts(ctx context.Context) error {
 	extraArgs := fmt.Sprintf(
-		"&fields=%s",
+		"&fields=%s%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.VirtualDeviceContext{}),
+		nbi.tagFilter(),
 	)
-	nbVirtualDeviceContexts, err := service.GetAll[objects.VirtualDeviceContext](
-		ctx,
-		nbi.NetboxAPI,
-		extraArgs,
-	)
+	nbVirtualDeviceContexts, err := getAll[objects.VirtualDeviceContext](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -431,7 +439,7 @@ func (nbi *NetboxInventory) initDeviceRoles(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.DeviceRole{}),
 	)
-	nbDeviceRoles, err := service.GetAll[objects.DeviceRole](ctx, nbi.NetboxAPI, extraArgs)
+	nbDeviceRoles, err := getAll[objects.DeviceRole](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -457,7 +465,7 @@ func (nbi *NetboxInventory) initCustomFields(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.CustomField{}),
 	)
-	customFields, err := service.GetAll[objects.CustomField](ctx, nbi.NetboxAPI, extraArgs)
+	customFields, err := getAll[objects.CustomField](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -621,6 +629,98 @@ func (nbi *NetboxInventory) initSsotCustomFields(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("add source_id custom field %s", err)
 	}
+	// Custom field for listing fields of the object, which are set only on create.
+	_, err = nbi.AddCustomField(ctx, &objects.CustomField{
+		Name:                  constants.CustomFieldCreateOnlyFieldsName,
+		Label:                 constants.CustomFieldCreateOnlyFieldsLabel,
+		Type:                  objects.CustomFieldTypeText,
+		FilterLogic:           objects.FilterLogicLoose,
+		CustomFieldUIVisible:  &objects.CustomFieldUIVisibleAlways,
+		CustomFieldUIEditable: &objects.CustomFieldUIEditableYes,
+		DisplayWeight:         objects.DisplayWeightDefault,
+		Description:           constants.CustomFieldCreateOnlyFieldsDescription,
+		SearchWeight:          objects.SearchWeightDefault,
+		ObjectTypes: []constants.ContentType{
+			constants.ContentTypeDcimDevice,
+			constants.ContentTypeDcimDeviceRole,
+			constants.ContentTypeDcimDeviceType,
+			constants.ContentTypeDcimInterface,
+			constants.ContentTypeDcimLocation,
+			constants.ContentTypeDcimManufacturer,
+			constants.ContentTypeDcimPlatform,
+			constants.ContentTypeDcimRegion,
+			constants.ContentTypeDcimSite,
+			constants.ContentTypeDcimVirtualDeviceContext,
+			constants.ContentTypeIpamIPAddress,
+			constants.ContentTypeIpamVlanGroup,
+			constants.ContentTypeIpamVlan,
+			constants.ContentTypeIpamPrefix,
+			constants.ContentTypeIpamVRF,
+			constants.ContentTypeTenancyTenantGroup,
+			constants.ContentTypeTenancyTenant,
+			constants.ContentTypeTenancyContact,
+			constants.ContentTypeTenancyContactAssignment,
+			constants.ContentTypeTenancyContactGroup,
+			constants.ContentTypeTenancyContactRole,
+			constants.ContentTypeVirtualizationCluster,
+			constants.ContentTypeVirtualizationClusterGroup,
+			constants.ContentTypeVirtualizationClusterType,
+			constants.ContentTypeVirtualizationVirtualMachine,
+			constants.ContentTypeVirtualizationVMInterface,
+			constants.ContentTypeWirelessLAN,
+			constants.ContentTypeWirelessLANGroup,
+			constants.ContentTypeVirtualizationVirtualDisk,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("add create only fields custom field: %s", err)
+	}
+	// Custom field for recording sources, which set fields with a field source priority.
+	_, err = nbi.AddCustomField(ctx, &objects.CustomField{
+		Name:                  constants.CustomFieldFieldSourcesName,
+		Label:                 constants.CustomFieldFieldSourcesLabel,
+		Type:                  objects.CustomFieldTypeLongText,
+		FilterLogic:           objects.FilterLogicLoose,
+		CustomFieldUIVisible:  &objects.CustomFieldUIVisibleIfSet,
+		CustomFieldUIEditable: &objects.CustomFieldUIEditableNo,
+		DisplayWeight:         objects.DisplayWeightDefault,
+		Description:           constants.CustomFieldFieldSourcesDescription,
+		SearchWeight:          objects.SearchWeightDefault,
+		ObjectTypes: []constants.ContentType{
+			constants.ContentTypeDcimDevice,
+			constants.ContentTypeDcimDeviceRole,
+			constants.ContentTypeDcimDeviceType,
+			constants.ContentTypeDcimInterface,
+			constants.ContentTypeDcimLocation,
+			constants.ContentTypeDcimManufacturer,
+			constants.ContentTypeDcimPlatform,
+			constants.ContentTypeDcimRegion,
+			constants.ContentTypeDcimSite,
+			constants.ContentTypeDcimVirtualDeviceContext,
+			constants.ContentTypeIpamIPAddress,
+			constants.ContentTypeIpamVlanGroup,
+			constants.ContentTypeIpamVlan,
+			constants.ContentTypeIpamPrefix,
+			constants.ContentTypeIpamVRF,
+			constants.ContentTypeTenancyTenantGroup,
+			constants.ContentTypeTenancyTenant,
+			constants.ContentTypeTenancyContact,
+			constants.ContentTypeTenancyContactAssignment,
+			constants.ContentTypeTenancyContactGroup,
+			constants.ContentTypeTenancyContactRole,
+			constants.ContentTypeVirtualizationCluster,
+			constants.ContentTypeVirtualizationClusterGroup,
+			constants.ContentTypeVirtualizationClusterType,
+			constants.ContentTypeVirtualizationVirtualMachine,
+			constants.ContentTypeVirtualizationVMInterface,
+			constants.ContentTypeWirelessLAN,
+			constants.ContentTypeWirelessLANGroup,
+			constants.ContentTypeVirtualizationVirtualDisk,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("add field sources custom field: %s", err)
+	}
 	// Custom field for storing number of CPU cores for device (server).
 	_, err = nbi.AddCustomField(ctx, &objects.CustomField{
 		Name:                  constants.CustomFieldHostCPUCoresName,
@@ -744,7 +844,7 @@ func (nbi *NetboxInventory) initClusterGroups(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.ClusterGroup{}),
 	)
-	nbClusterGroups, err := service.GetAll[objects.ClusterGroup](ctx, nbi.NetboxAPI, extraArgs)
+	nbClusterGroups, err := getAll[objects.ClusterGroup](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -770,7 +870,7 @@ func (nbi *NetboxInventory) initClusterTypes(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.ClusterType{}),
 	)
-	nbClusterTypes, err := service.GetAll[objects.ClusterType](ctx, nbi.NetboxAPI, extraArgs)
+	nbClusterTypes, err := getAll[objects.ClusterType](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -797,7 +897,7 @@ func (nbi *NetboxInventory) initClusters(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.Cluster{}),
 	)
-	nbClusters, err := service.GetAll[objects.Cluster](ctx, nbi.NetboxAPI, extraArgs)
+	nbClusters, err := getAll[objects.Cluster](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -820,7 +920,7 @@ func (nbi *NetboxInventory) initDeviceTypes(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.DeviceType{}),
 	)
-	nbDeviceTypes, err := service.GetAll[objects.DeviceType](ctx, nbi.NetboxAPI, extraArgs)
+	nbDeviceTypes, err := getAll[objects.DeviceType](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -844,10 +944,11 @@ func (nbi *NetboxInventory) initDeviceTypes(ctx context.Context) error {
 // Collects all interfaces from Netbox API and stores them to local inventory.
 func (nbi *NetboxInventory) initInterfaces(ctx context.Context) error {
 	extraArgs := fmt.Sprintf(
-		"&fields=%s",
+		"&fields=%s%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.Interface{}),
+		nbi.tagFilter(),
 	)
-	nbInterfaces, err := service.GetAll[objects.Interface](ctx, nbi.NetboxAPI, extraArgs)
+	nbInterfaces, err := getAll[objects.Interface](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -883,7 +984,7 @@ func (nbi *NetboxInventory) initVlanGroups(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.VlanGroup{}),
 	)
-	nbVlanGroups, err := service.GetAll[objects.VlanGroup](ctx, nbi.NetboxAPI, extraArgs)
+	nbVlanGroups, err := getAll[objects.VlanGroup](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -907,7 +1008,7 @@ func (nbi *NetboxInventory) initVlans(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.Vlan{}),
 	)
-	nbVlans, err := service.GetAll[objects.Vlan](ctx, nbi.NetboxAPI, extraArgs)
+	nbVlans, err := getAll[objects.Vlan](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -945,10 +1046,11 @@ func (nbi *NetboxInventory) initVlans(ctx context.Context) error {
 // Collects all vms from Netbox API and stores them to local inventory.
 func (nbi *NetboxInventory) initVMs(ctx context.Context) error {
 	extraArgs := fmt.Sprintf(
-		"&fields=%s",
+		"&fields=%s%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.VM{}),
+		nbi.tagFilter(),
 	)
-	nbVMs, err := service.GetAll[objects.VM](ctx, nbi.NetboxAPI, extraArgs)
+	nbVMs, err := getAll[objects.VM](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -982,10 +1084,11 @@ func (nbi *NetboxInventory) initVMs(ctx context.Context) error {
 // Collects all VMInterfaces from Netbox API and stores them to local inventory.
 func (nbi *NetboxInventory) initVMInterfaces(ctx context.Context) error {
 	extraArgs := fmt.Sprintf(
-		"&fields=%s",
+		"&fields=%s%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.VMInterface{}),
+		nbi.tagFilter(),
 	)
-	nbVMInterfaces, err := service.GetAll[objects.VMInterface](ctx, nbi.NetboxAPI, extraArgs)
+	nbVMInterfaces, err := getAll[objects.VMInterface](ctx, nbi, extraArgs)
 	if err != nil {
 		return fmt.Errorf("Init vm interfaces: %s", err)
 	}
@@ -1014,10 +1117,11 @@ func (nbi *NetboxInventory) initVMInterfaces(ctx context.Context) error {
 // Collects all IP addresses from Netbox API and stores them to local inventory.
 func (nbi *NetboxInventory) initIPAddresses(ctx context.Context) error {
 	extraArgs := fmt.Sprintf(
-		"&fields=%s",
+		"&fields=%s%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.IPAddress{}),
+		nbi.tagFilter(),
 	)
-	ipAddresses, err := service.GetAll[objects.IPAddress](ctx, nbi.NetboxAPI, extraArgs)
+	ipAddresses, err := getAll[objects.IPAddress](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -1051,10 +1155,11 @@ func (nbi *NetboxInventory) initIPAddresses(ctx context.Context) error {
 
 func (nbi *NetboxInventory) initMACAddresses(ctx context.Context) error {
 	extraArgs := fmt.Sprintf(
-		"&fields=%s",
+		"&fields=%s%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.MACAddress{}),
+		nbi.tagFilter(),
 	)
-	nbMACAddresses, err := service.GetAll[objects.MACAddress](ctx, nbi.NetboxAPI, extraArgs)
+	nbMACAddresses, err := getAll[objects.MACAddress](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -1093,7 +1198,7 @@ func (nbi *NetboxInventory) initPrefixes(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.Prefix{}),
 	)
-	prefixes, err := service.GetAll[objects.Prefix](ctx, nbi.NetboxAPI, extraArgs)
+	prefixes, err := getAll[objects.Prefix](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -1127,7 +1232,7 @@ func (nbi *NetboxInventory) initWirelessLANs(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.WirelessLAN{}),
 	)
-	nbWirelessLans, err := service.GetAll[objects.WirelessLAN](ctx, nbi.NetboxAPI, extraArgs)
+	nbWirelessLans, err := getAll[objects.WirelessLAN](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -1154,11 +1259,7 @@ func (nbi *NetboxInventory) initWirelessLANGroups(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.WirelessLANGroup{}),
 	)
-	nbWirelessLanGroups, err := service.GetAll[objects.WirelessLANGroup](
-		ctx,
-		nbi.NetboxAPI,
-		extraArgs,
-	)
+	nbWirelessLanGroups, err := getAll[objects.WirelessLANGroup](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -1183,10 +1284,11 @@ func (nbi *NetboxInventory) initWirelessLANGroups(ctx context.Context) error {
 // and stores them to local inventory.
 func (nbi *NetboxInventory) initVirtualDisks(ctx context.Context) error {
 	extraArgs := fmt.Sprintf(
-		"&fields=%s",
+		"&fields=%s%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.VirtualDisk{}),
+		nbi.tagFilter(),
 	)
-	nbVirtualDisks, err := service.GetAll[objects.VirtualDisk](ctx, nbi.NetboxAPI, extraArgs)
+	nbVirtualDisks, err := getAll[objects.VirtualDisk](ctx, nbi, extraArgs)
 	if err != nil {
 		return err
 	}
@@ -1214,7 +1316,7 @@ func (nbi *NetboxInventory) initVRFs(ctx context.Context) error {
 		"&fields=%s",
 		utils.ExtractJSONTagsFromStructIntoString(objects.VRF{}),
 	)
-	nbVRFs, err := service.GetAll[objects.VRF](ctx, nbi.NetboxAPI, extraArgs)
+	nbVRFs, err := getAll[objects.VRF](ctx, nbi, extraArgs)
 	if err != nil {
 		return fmt.Errorf("get all vrfs: %s", err)
 	}
@@ -0,0 +1,151 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/logger"
+	"github.com/bl4ko/netbox-ssot/internal/utils"
+)
+
+// initStep is a single step of the inventory initialization.
+type initStep struct {
+	// name of the step, used for logging and in dependsOn of other steps.
+	name string
+	init func(context.Context) error
+	// dependsOn are names of the steps, which have to finish before this step starts.
+	dependsOn []string
+}
+
+// newInitStep creates an initStep for initFunc, which depends on dependencies.
+// Names of the steps are names of the init functions without the init prefix.
+func newInitStep(
+	initFunc func(context.Context) error,
+	dependencies ...func(context.Context) error,
+) initStep {
+	step := initStep{
+		name: utils.ExtractFunctionNameWithTrimPrefix(initFunc, "init"),
+		init: initFunc,
+	}
+	for _, dependency := range dependencies {
+		step.dependsOn = append(step.dependsOn, utils.ExtractFunctionNameWithTrimPrefix(dependency, "init"))
+	}
+	return step
+}
+
+// initStepResult is the result of a finished initStep.
+type initStepResult struct {
+	step     initStep
+	err      error
+	duration time.Duration
+}
+
+// runInitSteps runs steps with up to concurrency steps at the same time.
+// A step is started once all of its dependencies have finished successfully.
+// After the first failed step no new steps are started, and the error
+// of the failed step is returned once the running steps finish.
+func runInitSteps(
+	ctx context.Context,
+	logger *logger.Logger,
+	steps []initStep,
+	concurrency int,
+) error {
+	if err := validateInitSteps(steps); err != nil {
+		return err
+	}
+	concurrency = max(1, concurrency)
+	finished := make(map[string]bool, len(steps))
+	started := make(map[string]bool, len(steps))
+	results := make(chan initStepResult)
+	running := 0
+	var firstErr error
+	for {
+		if firstErr == nil {
+			for _, step := range steps {
+				if running >= concurrency {
+					break
+				}
+				if started[step.name] || !allFinished(step.dependsOn, finished) {
+					continue
+				}
+				started[step.name] = true
+				running++
+				go func(step initStep) {
+					startTime := time.Now()
+					err := step.init(ctx)
+					results <- initStepResult{step: step, err: err, duration: time.Since(startTime)}
+				}(step)
+			}
+		}
+		if running == 0 {
+			return firstErr
+		}
+
+		result := <-results
+		running--
+		if result.err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: init%s", result.err, result.step.name)
+			}
+			continue
+		}
+		finished[result.step.name] = true
+		logger.Infof(
+			ctx,
+			"Successfully initialized %s in %f seconds",
+			result.step.name,
+			result.duration.Seconds(),
+		)
+	}
+}
+
+// validateInitSteps checks that all dependencies of the steps exist,
+// and that there are no dependency cycles.
+func validateInitSteps(steps []initStep) error {
+	name2step := make(map[string]initStep, len(steps))
+	for _, step := range steps {
+		if _, ok := name2step[step.name]; ok {
+			return fmt.Errorf("duplicate init step %s", step.name)
+		}
+		name2step[step.name] = step
+	}
+	// Steps are resolved in the same way as in runInitSteps, if some
+	// steps can't be resolved, they depend on each other.
+	resolved := make(map[string]bool, len(steps))
+	for len(resolved) < len(steps) {
+		progress := false
+		for _, step := range steps {
+			for _, dependency := range step.dependsOn {
+				if _, ok := name2step[dependency]; !ok {
+					return fmt.Errorf("init step %s depends on unknown step %s", step.name, dependency)
+				}
+			}
+			if !resolved[step.name] && allFinished(step.dependsOn, resolved) {
+				resolved[step.name] = true
+				progress = true
+			}
+		}
+		if !progress {
+			var unresolved []string
+			for _, step := range steps {
+				if !resolved[step.name] {
+					unresolved = append(unresolved, step.name)
+				}
+			}
+			return errors.New("dependency cycle between init steps: " + strings.Join(unresolved, ", "))
+		}
+	}
+	return nil
+}
+
+func allFinished(names []string, finished map[string]bool) bool {
+	for _, name := range names {
+		if !finished[name] {
+			return false
+		}
+	}
+	return true
+}
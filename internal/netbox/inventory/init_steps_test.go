@@ -0,0 +1,248 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+)
+
+// initStepRecorder records order of started and finished init steps,
+// and the maximum number of steps running at the same time.
+type initStepRecorder struct {
+	lock       sync.Mutex
+	events     []string
+	running    int
+	maxRunning int
+}
+
+func (r *initStepRecorder) step(name string, err error, dependsOn ...string) initStep {
+	return initStep{
+		name:      name,
+		dependsOn: dependsOn,
+		init: func(context.Context) error {
+			r.lock.Lock()
+			r.events = append(r.events, "start "+name)
+			r.running++
+			r.maxRunning = max(r.maxRunning, r.running)
+			r.lock.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			r.lock.Lock()
+			defer r.lock.Unlock()
+			r.running--
+			r.events = append(r.events, "finish "+name)
+			return err
+		},
+	}
+}
+
+func (r *initStepRecorder) index(event string) int {
+	return slices.Index(r.events, event)
+}
+
+func TestRunInitSteps(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		failStep    string
+		// wantOrder contains pairs of events, where the first one happens before the second one
+		wantOrder      [][2]string
+		wantNotStarted []string
+		wantErr        string
+	}{
+		{
+			name:        "Dependencies finish before dependent steps",
+			concurrency: 10,
+			wantOrder: [][2]string{
+				{"finish Tags", "start DefaultSite"},
+				{"finish Sites", "start DefaultSite"},
+				{"finish Interfaces", "start IPAddresses"},
+			},
+		},
+		{
+			name:        "Concurrency limits running steps",
+			concurrency: 1,
+		},
+		{
+			name:           "Failed step stops initialization",
+			concurrency:    10,
+			failStep:       "Tags",
+			wantNotStarted: []string{"DefaultSite"},
+			wantErr:        "init step failed: initTags",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &initStepRecorder{}
+			stepErr := func(name string) error {
+				if name == tt.failStep {
+					return errors.New("init step failed")
+				}
+				return nil
+			}
+			steps := []initStep{
+				recorder.step("DefaultSite", stepErr("DefaultSite"), "Tags", "Sites"),
+				recorder.step("IPAddresses", stepErr("IPAddresses"), "Interfaces"),
+				recorder.step("Tags", stepErr("Tags")),
+				recorder.step("Sites", stepErr("Sites")),
+				recorder.step("Interfaces", stepErr("Interfaces")),
+			}
+			err := runInitSteps(context.Background(), mockLogger, steps, tt.concurrency)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("runInitSteps() error = %v, want %s", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("runInitSteps() error = %s", err)
+			}
+			for _, order := range tt.wantOrder {
+				if recorder.index(order[0]) == -1 || recorder.index(order[0]) > recorder.index(order[1]) {
+					t.Errorf("%q didn't happen before %q: %v", order[0], order[1], recorder.events)
+				}
+			}
+			for _, name := range tt.wantNotStarted {
+				if recorder.index("start "+name) != -1 {
+					t.Errorf("step %s was started: %v", name, recorder.events)
+				}
+			}
+			if recorder.maxRunning > tt.concurrency {
+				t.Errorf("%d steps were running at the same time, want at most %d", recorder.maxRunning, tt.concurrency)
+			}
+		})
+	}
+}
+
+func TestValidateInitSteps(t *testing.T) {
+	noop := func(context.Context) error { return nil }
+	tests := []struct {
+		name    string
+		steps   []initStep
+		wantErr string
+	}{
+		{
+			name: "Valid steps",
+			steps: []initStep{
+				{name: "Sites", init: noop},
+				{name: "DefaultSite", init: noop, dependsOn: []string{"Sites"}},
+			},
+		},
+		{
+			name: "Unknown dependency",
+			steps: []initStep{
+				{name: "DefaultSite", init: noop, dependsOn: []string{"Sites"}},
+			},
+			wantErr: "init step DefaultSite depends on unknown step Sites",
+		},
+		{
+			name: "Dependency cycle",
+			steps: []initStep{
+				{name: "Tags", init: noop},
+				{name: "A", init: noop, dependsOn: []string{"B"}},
+				{name: "B", init: noop, dependsOn: []string{"A", "Tags"}},
+			},
+			wantErr: "dependency cycle between init steps: A, B",
+		},
+		{
+			name: "Duplicate step",
+			steps: []initStep{
+				{name: "Tags", init: noop},
+				{name: "Tags", init: noop},
+			},
+			wantErr: "duplicate init step Tags",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateInitSteps(tt.steps)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("validateInitSteps() error = %s", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("validateInitSteps() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNetboxInventory_InitSteps(t *testing.T) {
+	steps := (&NetboxInventory{}).initSteps()
+	if err := validateInitSteps(steps); err != nil {
+		t.Fatalf("initSteps() are not valid: %s", err)
+	}
+	// 34 init functions
+	if len(steps) != 34 {
+		t.Errorf("initSteps() returned %d steps, want 34", len(steps))
+	}
+	defaultSite := steps[slices.IndexFunc(steps, func(step initStep) bool { return step.name == "DefaultSite" })]
+	if strings.Join(defaultSite.dependsOn, ",") != "Sites,Tags,SsotCustomFields" {
+		t.Errorf("DefaultSite depends on %v", defaultSite.dependsOn)
+	}
+}
+
+func TestNetboxInventory_TagFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *parser.NetboxConfig
+		want   string
+	}{
+		{
+			name:   "No filter",
+			config: &parser.NetboxConfig{},
+			want:   "",
+		},
+		{
+			name:   "Only tagged objects",
+			config: &parser.NetboxConfig{InitOnlyTagged: true},
+			want:   "&tag=netbox-ssot",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nbi := &NetboxInventory{
+				NetboxConfig: tt.config,
+				SsotTag:      &objects.Tag{Name: "netbox-ssot", Slug: "netbox-ssot"},
+			}
+			if got := nbi.tagFilter(); got != tt.want {
+				t.Errorf("tagFilter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetboxInventory_RefreshObjectTypes(t *testing.T) {
+	ctx := context.Background()
+	server := newSnapshotServer()
+	server.setObject(constants.DevicesAPIPath, map[string]any{
+		"id":   1,
+		"name": "device1",
+		"site": map[string]any{"id": 1, "name": "site1"},
+	}, time.Now())
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	nbi := newSnapshotTestInventory(t, httpServer.URL, parser.SnapshotConfig{})
+	nbi.SsotTag = &objects.Tag{Name: "netbox-ssot", Slug: "netbox-ssot"}
+
+	if err := nbi.RefreshObjectTypes(ctx, []constants.APIPath{constants.DevicesAPIPath}); err != nil {
+		t.Fatalf("RefreshObjectTypes() error = %v", err)
+	}
+	if device := nbi.devicesIndexByNameAndSiteID["device1"][1]; device == nil || device.ID != 1 {
+		t.Errorf("RefreshObjectTypes() didn't collect device1: %v", nbi.devicesIndexByNameAndSiteID)
+	}
+	for _, query := range strings.Split(server.queryLog(), "\n") {
+		if !strings.HasPrefix(query, string(constants.DevicesAPIPath)) {
+			t.Errorf("RefreshObjectTypes() queried %q, want only devices", query)
+		}
+	}
+
+	if err := nbi.RefreshObjectTypes(ctx, []constants.APIPath{constants.SitesAPIPath}); err == nil {
+		t.Error("RefreshObjectTypes() of sites didn't fail")
+	}
+}
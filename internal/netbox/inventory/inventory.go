@@ -3,16 +3,19 @@ package inventory
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
 	"github.com/bl4ko/netbox-ssot/internal/logger"
+	"github.com/bl4ko/netbox-ssot/internal/metrics"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/service"
 	"github.com/bl4ko/netbox-ssot/internal/parser"
+	"github.com/bl4ko/netbox-ssot/internal/report"
 	"github.com/bl4ko/netbox-ssot/internal/utils"
 )
 
@@ -26,6 +29,10 @@ type NetboxInventory struct {
 	DryRun bool
 	// NetboxAPI is the Netbox API object, for communicating with the Netbox API
 	NetboxAPI *service.NetboxClient
+	// Recorder records all changes made to Netbox. If nil, changes are not recorded.
+	Recorder *report.Recorder
+	// Metrics records usage of the Netbox API. If nil, it is not measured.
+	Metrics *metrics.Metrics
 	// SourcePriority: if object is found on multiple sources, which source has
 	// the priority for the object attributes.
 	SourcePriority map[string]int
@@ -41,6 +48,12 @@ type NetboxInventory struct {
 	// to functions for logging.
 	Ctx context.Context //nolint:containedctx
 
+	// snapshot of collected objects, which is used to collect only changed
+	// objects on the next run. Nil if the snapshot is disabled.
+	snapshot *inventorySnapshot
+	// transforms are rules, which transform objects from sources. They are set by SetTransforms.
+	transforms []transform
+
 	// tagsIndexByName is a map of all tags in the Netbox's inventory,
 	// indexed by their name
 	tagsIndexByName map[string]*objects.Tag
@@ -215,6 +228,11 @@ type NetboxInventory struct {
 	// indexed by their vm's id and vm's name
 	virtualDisksIndexByVMIDAndName map[int]map[string]*objects.VirtualDisk
 	virtualDisksLock               sync.Mutex
+
+	// fieldSourceReports are objects with field source priority reported
+	// by sources in the current run, see ExpireFieldSources.
+	fieldSourceReports     map[reportedObject]*objectReports
+	fieldSourceReportsLock sync.Mutex
 }
 
 // Func string representation.
@@ -253,7 +271,10 @@ func NewNetboxInventory(
 }
 
 // Init function that initializes the NetBoxInventory object with objects from Netbox.
-func (nbi *NetboxInventory) Init() error {
+// Ctx is used for all requests made by the inventory until the next Init or Refresh,
+// so canceling it stops them.
+func (nbi *NetboxInventory) Init(ctx context.Context) error {
+	nbi.Ctx = ctx
 	baseURL := fmt.Sprintf(
 		"%s://%s:%d",
 		nbi.NetboxConfig.HTTPScheme,
@@ -275,27 +296,127 @@ func (nbi *NetboxInventory) Init() error {
 	if err != nil {
 		return fmt.Errorf("create new netbox client: %s", err)
 	}
+	nbi.wireClient()
+	nbi.NetboxAPI.MaxRetries = nbi.NetboxConfig.MaxRetries
+	nbi.NetboxAPI.RateLimiter = service.NewRateLimiter(nbi.NetboxConfig.RequestsPerSecond)
+	nbi.NetboxAPI.PageConcurrency = nbi.NetboxConfig.PageConcurrency
+	nbi.NetboxAPI.BulkSize = nbi.NetboxConfig.BulkSize
 
 	err = nbi.checkVersion()
 	if err != nil {
 		return err
 	}
 
-	// WARNING: Order matters
-	initFunctions := []func(context.Context) error{
-		nbi.initCustomFields,
-		nbi.initSsotCustomFields,
-		nbi.initTags,
+	return nbi.collect()
+}
+
+// Refresh reloads all objects of an already initialized inventory from Netbox.
+// Unlike Init, it reuses the existing Netbox API client, so it can be used by
+// long-running processes to prepare the inventory for the next run.
+func (nbi *NetboxInventory) Refresh(ctx context.Context) error {
+	if nbi.NetboxAPI == nil {
+		return nbi.Init(ctx)
+	}
+	nbi.Ctx = ctx
+	nbi.wireClient()
+	nbi.OrphanManager.Reset()
+	return nbi.collect()
+}
+
+// RefreshObjectTypes reloads only objects of the given types (by their API paths)
+// from Netbox. Objects of other types are kept as they were collected by the last
+// Init or Refresh, so runs, which change only a few object types, can use it instead
+// of Refresh. Only types owned by sources (e.g. devices and their interfaces) can be
+// refreshed on their own.
+func (nbi *NetboxInventory) RefreshObjectTypes(ctx context.Context, objectTypes []constants.APIPath) error {
+	if nbi.NetboxAPI == nil {
+		return nbi.Init(ctx)
+	}
+	initFuncs := map[constants.APIPath]func(context.Context) error{
+		constants.DevicesAPIPath:         nbi.initDevices,
+		constants.InterfacesAPIPath:      nbi.initInterfaces,
+		constants.VirtualMachinesAPIPath: nbi.initVMs,
+		constants.VMInterfacesAPIPath:    nbi.initVMInterfaces,
+		constants.VirtualDisksAPIPath:    nbi.initVirtualDisks,
+		constants.IPAddressesAPIPath:     nbi.initIPAddresses,
+		constants.MACAddressesAPIPath:    nbi.initMACAddresses,
+	}
+	stepNames := make(map[string]bool, len(objectTypes))
+	for _, objectType := range objectTypes {
+		initFunc, ok := initFuncs[objectType]
+		if !ok {
+			return fmt.Errorf("objects at %s can't be refreshed on their own", objectType)
+		}
+		stepNames[utils.ExtractFunctionNameWithTrimPrefix(initFunc, "init")] = true
+	}
+	var steps []initStep
+	for _, step := range nbi.initSteps() {
+		if !stepNames[step.name] {
+			continue
+		}
+		// Steps of the other object types finished by the last Init or Refresh
+		step.dependsOn = slices.DeleteFunc(step.dependsOn, func(name string) bool { return !stepNames[name] })
+		steps = append(steps, step)
+	}
+	nbi.Ctx = ctx
+	nbi.wireClient()
+	nbi.prepareSnapshot(nbi.Ctx)
+	if err := runInitSteps(nbi.Ctx, nbi.Logger, steps, nbi.NetboxConfig.InitConcurrency); err != nil {
+		return err
+	}
+	nbi.saveSnapshot(nbi.Ctx)
+	return nil
+}
+
+// wireClient passes the recorder and metrics of the inventory to its netbox client.
+// It is called on every run, because they can be set after the inventory is initialized
+// (e.g. when notifications are added by reloading the config).
+func (nbi *NetboxInventory) wireClient() {
+	nbi.NetboxAPI.Recorder = nbi.Recorder
+	nbi.NetboxAPI.Metrics = nbi.Metrics
+}
+
+// collect runs all init functions, which collect objects from Netbox
+// and store them in the local inventory. Init functions run concurrently,
+// each one after all of its dependencies have finished.
+func (nbi *NetboxInventory) collect() error {
+	nbi.fieldSourceReportsLock.Lock()
+	nbi.fieldSourceReports = nil
+	nbi.fieldSourceReportsLock.Unlock()
+	nbi.prepareSnapshot(nbi.Ctx)
+	if err := runInitSteps(nbi.Ctx, nbi.Logger, nbi.initSteps(), nbi.NetboxConfig.InitConcurrency); err != nil {
+		return err
+	}
+	nbi.saveSnapshot(nbi.Ctx)
+	return nil
+}
+
+// initSteps returns all init functions with their dependencies.
+func (nbi *NetboxInventory) initSteps() []initStep {
+	steps := []initStep{
+		newInitStep(nbi.initCustomFields),
+		newInitStep(nbi.initSsotCustomFields, nbi.initCustomFields),
+		newInitStep(nbi.initTags),
+		// Objects created during init are tagged with the ssot tag and have
+		// ssot custom fields set, so they depend on initTags and initSsotCustomFields
+		newInitStep(nbi.initAdminContactRole, nbi.initContactRoles, nbi.initTags, nbi.initSsotCustomFields),
+		newInitStep(nbi.initDefaultSite, nbi.initSites, nbi.initTags, nbi.initSsotCustomFields),
+		newInitStep(nbi.initVlans, nbi.initVlanGroups, nbi.initTags, nbi.initSsotCustomFields),
+		// IP and MAC addresses are indexed by their assigned interfaces
+		newInitStep(nbi.initIPAddresses, nbi.initInterfaces, nbi.initVMInterfaces),
+		newInitStep(nbi.initMACAddresses, nbi.initInterfaces, nbi.initVMInterfaces),
+	}
+	// The remaining init functions only collect objects of a single type.
+	// Tag filter requires the ssot tag, which is created in initTags.
+	for _, initFunc := range []func(context.Context) error{
 		nbi.initContactGroups,
 		nbi.initContactRoles,
-		nbi.initAdminContactRole,
 		nbi.initContacts,
 		nbi.initContactAssignments,
 		nbi.initTenants,
 		nbi.initSites,
 		nbi.initLocations,
 		nbi.initSiteGroups,
-		nbi.initDefaultSite,
 		nbi.initManufacturers,
 		nbi.initPlatforms,
 		nbi.initVMs,
@@ -303,12 +424,9 @@ func (nbi *NetboxInventory) Init() error {
 		nbi.initVMInterfaces,
 		nbi.initDevices,
 		nbi.initInterfaces,
-		nbi.initIPAddresses,
-		nbi.initMACAddresses,
 		nbi.initVlanGroups,
 		nbi.initPrefixes,
 		nbi.initVRFs,
-		nbi.initVlans,
 		nbi.initDeviceRoles,
 		nbi.initDeviceTypes,
 		nbi.initClusterGroups,
@@ -317,22 +435,24 @@ func (nbi *NetboxInventory) Init() error {
 		nbi.initVirtualDeviceContexts,
 		nbi.initWirelessLANs,
 		nbi.initWirelessLANGroups,
+	} {
+		steps = append(steps, newInitStep(initFunc, nbi.initTags))
 	}
-	for _, initFunc := range initFunctions {
-		startTime := time.Now()
-		if err := initFunc(nbi.Ctx); err != nil {
-			return fmt.Errorf("%s: %s", err, utils.ExtractFunctionName(initFunc))
-		}
-		duration := time.Since(startTime)
-		nbi.Logger.Infof(
-			nbi.Ctx,
-			"Successfully initialized %s in %f seconds",
-			utils.ExtractFunctionNameWithTrimPrefix(initFunc, "init"),
-			duration.Seconds(),
-		)
-	}
+	return steps
+}
 
-	return nil
+// tagFilter returns query params for init functions, which restrict collected
+// objects to the ones tagged with the ssot tag, if InitOnlyTagged is set.
+// It is used only for object types owned by netbox-ssot (e.g. devices, interfaces
+// and IP addresses). Their existing untagged objects are looked up in Netbox by
+// their natural keys (e.g. name and site of a device), when they are not found
+// in the index, so they aren't created again (see untagged.go). Other object
+// types (e.g. tenants, sites and platforms) are always collected in full.
+func (nbi *NetboxInventory) tagFilter() string {
+	if nbi.NetboxConfig == nil || !nbi.NetboxConfig.InitOnlyTagged {
+		return ""
+	}
+	return fmt.Sprintf("&tag=%s", url.QueryEscape(nbi.SsotTag.Slug))
 }
 
 func (nbi *NetboxInventory) checkVersion() error {
@@ -2,10 +2,14 @@ package inventory
 
 import (
 	"context"
+	"reflect"
+	"slices"
+	"sync"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
 	"github.com/bl4ko/netbox-ssot/internal/logger"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/report"
 )
 
 type OrphanManager struct {
@@ -37,6 +41,14 @@ type OrphanManager struct {
 	Logger *logger.Logger
 	// Context for orphan manager
 	Ctx context.Context
+
+	// managed stores sources of all objects managed by netbox-ssot, which were
+	// added to the orphan manager, indexed by objectAPIPath and ID. Unlike Items,
+	// objects are not removed from it, so it is used to count managed objects.
+	managed map[constants.APIPath]map[int]string
+
+	// lock protects Items and managed, which are modified by concurrent init functions and sources
+	lock sync.Mutex
 }
 
 func NewOrphanManager(logger *logger.Logger) *OrphanManager {
@@ -70,6 +82,7 @@ func NewOrphanManager(logger *logger.Logger) *OrphanManager {
 
 	return &OrphanManager{
 		Items:                map[constants.APIPath]map[int]objects.OrphanItem{},
+		managed:              map[constants.APIPath]map[int]string{},
 		OrphanObjectPriority: orphanObjectPriority,
 		Logger:               logger,
 		Ctx:                  orphanCtx,
@@ -80,13 +93,121 @@ func (orphanManager *OrphanManager) AddItem(orphanItem objects.OrphanItem) {
 	// Manage only objects created with netbox-ssot tag
 	netboxObject := orphanItem.GetNetboxObject()
 	if netboxObject.HasTagByName(constants.SsotTagName) {
+		orphanManager.lock.Lock()
+		defer orphanManager.lock.Unlock()
 		if orphanManager.Items[orphanItem.GetAPIPath()] == nil {
 			orphanManager.Items[orphanItem.GetAPIPath()] = map[int]objects.OrphanItem{}
 		}
 		orphanManager.Items[orphanItem.GetAPIPath()][netboxObject.ID] = orphanItem
+		if orphanManager.managed[orphanItem.GetAPIPath()] == nil {
+			orphanManager.managed[orphanItem.GetAPIPath()] = map[int]string{}
+		}
+		orphanManager.managed[orphanItem.GetAPIPath()][netboxObject.ID] = ItemSource(orphanItem)
 	}
 }
 
 func (orphanManager *OrphanManager) RemoveItem(obj objects.OrphanItem) {
+	orphanManager.lock.Lock()
+	defer orphanManager.lock.Unlock()
 	delete(orphanManager.Items[obj.GetAPIPath()], obj.GetID())
 }
+
+// CandidatesBySource returns number of orphan candidates owned by each source.
+// Candidates without the source custom field are counted under empty string.
+func (orphanManager *OrphanManager) CandidatesBySource() map[string]int {
+	orphanManager.lock.Lock()
+	defer orphanManager.lock.Unlock()
+	candidates := map[string]int{}
+	for _, id2orphanItem := range orphanManager.Items {
+		for _, orphanItem := range id2orphanItem {
+			candidates[ItemSource(orphanItem)]++
+		}
+	}
+	return candidates
+}
+
+// CandidatesBySourceAndType returns number of orphan candidates owned by each
+// source, indexed by source name and by object type name (e.g. Device).
+func (orphanManager *OrphanManager) CandidatesBySourceAndType() map[string]map[string]int {
+	orphanManager.lock.Lock()
+	defer orphanManager.lock.Unlock()
+	candidates := map[string]map[string]int{}
+	for _, id2orphanItem := range orphanManager.Items {
+		for _, orphanItem := range id2orphanItem {
+			sourceName := ItemSource(orphanItem)
+			if candidates[sourceName] == nil {
+				candidates[sourceName] = map[string]int{}
+			}
+			candidates[sourceName][report.TypeName(reflect.TypeOf(orphanItem))]++
+		}
+	}
+	return candidates
+}
+
+// Reset removes all items from the orphan manager.
+// It is used before the inventory is refreshed for a new run.
+func (orphanManager *OrphanManager) Reset() {
+	orphanManager.lock.Lock()
+	defer orphanManager.lock.Unlock()
+	orphanManager.Items = map[constants.APIPath]map[int]objects.OrphanItem{}
+	orphanManager.managed = map[constants.APIPath]map[int]string{}
+}
+
+// ManagedCounts returns number of objects managed by netbox-ssot, which were
+// added to the orphan manager since the last Reset. Objects are counted
+// by objectAPIPath and by the source that owns them.
+func (orphanManager *OrphanManager) ManagedCounts() (map[constants.APIPath]int, map[string]int) {
+	orphanManager.lock.Lock()
+	defer orphanManager.lock.Unlock()
+	byAPIPath := map[constants.APIPath]int{}
+	bySource := map[string]int{}
+	for objectAPIPath, id2source := range orphanManager.managed {
+		byAPIPath[objectAPIPath] = len(id2source)
+		for _, sourceName := range id2source {
+			bySource[sourceName]++
+		}
+	}
+	return byAPIPath, bySource
+}
+
+// GetItem returns the orphan item with the given objectAPIPath and id,
+// if it is still in the orphan manager.
+func (orphanManager *OrphanManager) GetItem(objectAPIPath constants.APIPath, id int) (objects.OrphanItem, bool) {
+	orphanManager.lock.Lock()
+	defer orphanManager.lock.Unlock()
+	orphanItem, ok := orphanManager.Items[objectAPIPath][id]
+	return orphanItem, ok
+}
+
+// OrphanScope limits orphan cleanup to objects owned by some of the sources.
+// Owner of an object is stored in its source custom field.
+type OrphanScope struct {
+	// Sources are names of the sources whose objects can be removed.
+	Sources []string
+	// ConfiguredSources are names of all configured sources. Objects which
+	// don't belong to any of them (e.g. objects created by netbox-ssot itself,
+	// or by a source that was removed from the configuration) are unowned.
+	ConfiguredSources []string
+	// IncludeUnowned allows removing unowned objects.
+	IncludeUnowned bool
+}
+
+// Contains returns true if orphanItem can be removed within this scope.
+// Nil scope contains all objects.
+func (scope *OrphanScope) Contains(orphanItem objects.OrphanItem) bool {
+	if scope == nil {
+		return true
+	}
+	sourceName := ItemSource(orphanItem)
+	if slices.Contains(scope.Sources, sourceName) {
+		return true
+	}
+	return scope.IncludeUnowned && !slices.Contains(scope.ConfiguredSources, sourceName)
+}
+
+// ItemSource returns name of the source that owns the orphanItem,
+// or empty string if the source custom field is not set.
+func ItemSource(orphanItem objects.OrphanItem) string {
+	sourceName, _ := orphanItem.GetNetboxObject().GetCustomField(constants.CustomFieldSourceName).(string)
+	return sourceName
+}
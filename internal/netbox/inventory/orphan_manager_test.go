@@ -0,0 +1,136 @@
+package inventory
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+)
+
+func deviceOwnedBy(sourceName string) *objects.Device {
+	device := &objects.Device{NetboxObject: objects.NetboxObject{ID: 1}}
+	if sourceName != "" {
+		device.SetCustomField(constants.CustomFieldSourceName, sourceName)
+	}
+	return device
+}
+
+func TestOrphanScope_Contains(t *testing.T) {
+	configured := []string{"vmware", "ovirt", "fmc"}
+	tests := []struct {
+		name  string
+		scope *OrphanScope
+		item  objects.OrphanItem
+		want  bool
+	}{
+		{
+			name:  "Nil scope contains everything",
+			scope: nil,
+			item:  deviceOwnedBy("vmware"),
+			want:  true,
+		},
+		{
+			name:  "Object of selected source",
+			scope: &OrphanScope{Sources: []string{"vmware"}, ConfiguredSources: configured},
+			item:  deviceOwnedBy("vmware"),
+			want:  true,
+		},
+		{
+			name:  "Object of not selected source",
+			scope: &OrphanScope{Sources: []string{"vmware"}, ConfiguredSources: configured, IncludeUnowned: true},
+			item:  deviceOwnedBy("fmc"),
+			want:  false,
+		},
+		{
+			name:  "Object without source in partial scope",
+			scope: &OrphanScope{Sources: []string{"vmware"}, ConfiguredSources: configured},
+			item:  deviceOwnedBy(""),
+			want:  false,
+		},
+		{
+			name:  "Object without source in full scope",
+			scope: &OrphanScope{Sources: configured, ConfiguredSources: configured, IncludeUnowned: true},
+			item:  deviceOwnedBy(""),
+			want:  true,
+		},
+		{
+			name:  "Object of removed source in full scope",
+			scope: &OrphanScope{Sources: configured, ConfiguredSources: configured, IncludeUnowned: true},
+			item:  deviceOwnedBy("old-vmware"),
+			want:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scope.Contains(tt.item); got != tt.want {
+				t.Errorf("OrphanScope.Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrphanManager_CandidatesBySource(t *testing.T) {
+	orphanManager := NewOrphanManager(nil)
+	ssotTag := []*objects.Tag{{Name: constants.SsotTagName}}
+	for id, sourceName := range []string{"vmware", "vmware", "fmc", ""} {
+		device := deviceOwnedBy(sourceName)
+		device.ID = id + 1
+		device.Tags = ssotTag
+		orphanManager.AddItem(device)
+	}
+	// Objects without netbox-ssot tag are not orphan candidates
+	orphanManager.AddItem(&objects.Device{NetboxObject: objects.NetboxObject{ID: 10}})
+
+	got := orphanManager.CandidatesBySource()
+	want := map[string]int{"vmware": 2, "fmc": 1, "": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CandidatesBySource() = %v, want %v", got, want)
+	}
+}
+
+func TestOrphanManager_CandidatesBySourceAndType(t *testing.T) {
+	orphanManager := NewOrphanManager(nil)
+	device := deviceOwnedBy("vmware")
+	device.Tags = []*objects.Tag{{Name: constants.SsotTagName}}
+	orphanManager.AddItem(device)
+	orphanManager.AddItem(managedVM(1, "vmware"))
+	orphanManager.AddItem(managedVM(2, "vmware"))
+	orphanManager.AddItem(managedVM(3, "ovirt"))
+
+	got := orphanManager.CandidatesBySourceAndType()
+	want := map[string]map[string]int{
+		"vmware": {"Device": 1, "VM": 2},
+		"ovirt":  {"VM": 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CandidatesBySourceAndType() = %v, want %v", got, want)
+	}
+}
+
+func TestOrphanManager_ManagedCounts(t *testing.T) {
+	orphanManager := NewOrphanManager(nil)
+	ssotTag := []*objects.Tag{{Name: constants.SsotTagName}}
+	for id, sourceName := range []string{"vmware", "vmware", "fmc"} {
+		device := deviceOwnedBy(sourceName)
+		device.ID = id + 1
+		device.Tags = ssotTag
+		orphanManager.AddItem(device)
+		// Removed items are still counted as managed
+		orphanManager.RemoveItem(device)
+	}
+	orphanManager.AddItem(&objects.Device{NetboxObject: objects.NetboxObject{ID: 10}})
+
+	byAPIPath, bySource := orphanManager.ManagedCounts()
+	if want := map[constants.APIPath]int{constants.DevicesAPIPath: 3}; !reflect.DeepEqual(byAPIPath, want) {
+		t.Errorf("ManagedCounts() by api path = %v, want %v", byAPIPath, want)
+	}
+	if want := map[string]int{"vmware": 2, "fmc": 1}; !reflect.DeepEqual(bySource, want) {
+		t.Errorf("ManagedCounts() by source = %v, want %v", bySource, want)
+	}
+
+	orphanManager.Reset()
+	if byAPIPath, _ := orphanManager.ManagedCounts(); len(byAPIPath) != 0 {
+		t.Errorf("ManagedCounts() after Reset() = %v", byAPIPath)
+	}
+}
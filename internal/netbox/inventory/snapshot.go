@@ -0,0 +1,492 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/mapper"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/service"
+)
+
+// snapshotVersion is version of the format of snapshot files. Snapshots
+// of other versions are ignored, so all objects are collected again.
+const snapshotVersion = 2
+
+// snapshotOverlap is subtracted from the newest last_updated timestamp, when changed
+// objects are collected. Objects saved in transactions, which committed after the
+// previous run collected the objects, can have older timestamps than the newest one.
+const snapshotOverlap = time.Minute
+
+// snapshotReferenceDepth is depth of nested objects, whose changes are checked, e.g.
+// 2 includes the manufacturer of the device type nested in a device.
+const snapshotReferenceDepth = 2
+
+// snapshotIDsPerRequest is the maximum number of objects requested by their IDs at once.
+const snapshotIDsPerRequest = 100
+
+// inventorySnapshot is a local copy of objects collected from Netbox. With a snapshot,
+// init functions collect only objects changed since the previous run, and find deleted
+// objects by listing IDs of all objects, so startup cost scales with churn instead of
+// total number of objects in Netbox.
+type inventorySnapshot struct {
+	// Version of the snapshot format, see snapshotVersion.
+	Version int `json:"version"`
+	// NetboxURL is the base URL of Netbox, from which the objects were collected.
+	NetboxURL string `json:"netboxURL"`
+	// Created is the time, when all objects were collected.
+	Created time.Time `json:"created"`
+	// ObjectTypes are snapshots of object types by their API paths.
+	ObjectTypes map[constants.APIPath]*objectTypeSnapshot `json:"objectTypes"`
+
+	lock sync.Mutex
+}
+
+// objectTypeSnapshot are collected objects of a single object type.
+type objectTypeSnapshot struct {
+	// Params are query params, with which the objects were collected. If init
+	// function uses different params (e.g. fields), all objects are collected again.
+	Params string `json:"params"`
+	// LastUpdated is the newest last_updated timestamp of the objects.
+	LastUpdated time.Time `json:"lastUpdated"`
+	// Objects are the collected objects by their IDs, in the format of the Netbox API.
+	Objects map[int]json.RawMessage `json:"objects"`
+	// References are last_updated timestamps of object types referenced by the objects,
+	// since which changes of the referenced objects weren't checked yet. Objects nest
+	// referenced objects (e.g. name of the site of a device), so objects referencing
+	// changed objects are collected again, even if they didn't change themselves.
+	References map[constants.APIPath]time.Time `json:"references"`
+}
+
+// snapshotObject are fields of objects, which are needed to update the snapshot.
+type snapshotObject struct {
+	ID          int        `json:"id"`
+	LastUpdated *time.Time `json:"last_updated"`
+}
+
+// newInventorySnapshot returns an empty snapshot of Netbox at netboxURL.
+func newInventorySnapshot(netboxURL string) *inventorySnapshot {
+	return &inventorySnapshot{
+		Version:     snapshotVersion,
+		NetboxURL:   netboxURL,
+		Created:     time.Now(),
+		ObjectTypes: make(map[constants.APIPath]*objectTypeSnapshot),
+	}
+}
+
+// readSnapshot reads the snapshot file at path. If the file doesn't exist,
+// or can't be used for Netbox at netboxURL, an empty snapshot is returned.
+func readSnapshot(path string, netboxURL string) (*inventorySnapshot, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return newInventorySnapshot(netboxURL), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshot inventorySnapshot
+	if err := json.Unmarshal(content, &snapshot); err != nil {
+		return nil, fmt.Errorf("parse %s: %s", path, err)
+	}
+	if snapshot.Version != snapshotVersion || snapshot.NetboxURL != netboxURL || snapshot.ObjectTypes == nil {
+		return newInventorySnapshot(netboxURL), nil
+	}
+	return &snapshot, nil
+}
+
+// write writes the snapshot to path. The file is replaced atomically,
+// so the previous snapshot is kept if writing fails.
+func (s *inventorySnapshot) write(path string) error {
+	s.lock.Lock()
+	content, err := json.Marshal(s)
+	s.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.Write(content); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), path)
+}
+
+// objectType returns snapshot of objects at path, or nil if they are not in the snapshot.
+func (s *inventorySnapshot) objectType(path constants.APIPath) *objectTypeSnapshot {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.ObjectTypes[path]
+}
+
+// setObjectType sets snapshot of objects at path.
+func (s *inventorySnapshot) setObjectType(path constants.APIPath, objectType *objectTypeSnapshot) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.ObjectTypes[path] = objectType
+}
+
+// update adds or replaces objects in the snapshot.
+func (ots *objectTypeSnapshot) update(objects []json.RawMessage) error {
+	for _, object := range objects {
+		var fields snapshotObject
+		if err := json.Unmarshal(object, &fields); err != nil {
+			return err
+		}
+		ots.Objects[fields.ID] = object
+		if fields.LastUpdated != nil && fields.LastUpdated.After(ots.LastUpdated) {
+			ots.LastUpdated = *fields.LastUpdated
+		}
+	}
+	return nil
+}
+
+// prepareSnapshot loads the snapshot before the inventory is collected, if it is
+// enabled. Snapshot of an earlier collection is reused, unless it is older than MaxAge.
+func (nbi *NetboxInventory) prepareSnapshot(ctx context.Context) {
+	if nbi.NetboxConfig == nil || nbi.NetboxConfig.Snapshot.Path == "" {
+		nbi.snapshot = nil
+		return
+	}
+	config := nbi.NetboxConfig.Snapshot
+	if nbi.snapshot == nil {
+		snapshot, err := readSnapshot(config.Path, nbi.NetboxAPI.BaseURL)
+		if err != nil {
+			nbi.Logger.Warningf(ctx, "Failed to read inventory snapshot, collecting all objects: %s", err)
+			snapshot = newInventorySnapshot(nbi.NetboxAPI.BaseURL)
+		}
+		nbi.snapshot = snapshot
+	}
+	maxAge := time.Duration(config.MaxAge) * time.Second
+	if maxAge > 0 && time.Since(nbi.snapshot.Created) > maxAge {
+		nbi.Logger.Infof(ctx, "Inventory snapshot is older than %s, collecting all objects", maxAge)
+		nbi.snapshot = newInventorySnapshot(nbi.NetboxAPI.BaseURL)
+	}
+}
+
+// saveSnapshot writes the snapshot after the inventory was collected.
+// Failure to write the snapshot is logged, so the next run collects all objects.
+func (nbi *NetboxInventory) saveSnapshot(ctx context.Context) {
+	if nbi.snapshot == nil {
+		return
+	}
+	if err := nbi.snapshot.write(nbi.NetboxConfig.Snapshot.Path); err != nil {
+		nbi.Logger.Warningf(ctx, "Failed to write inventory snapshot: %s", err)
+		return
+	}
+	nbi.Logger.Debugf(ctx, "Inventory snapshot written to %s", nbi.NetboxConfig.Snapshot.Path)
+}
+
+// getAll collects all objects of type T from Netbox with extraArgs. If the snapshot
+// is enabled, only objects changed since the previous collection are fetched, and
+// merged with the objects in the snapshot.
+func getAll[T any](ctx context.Context, nbi *NetboxInventory, extraArgs string) ([]T, error) {
+	if nbi.snapshot == nil {
+		return service.GetAll[T](ctx, nbi.NetboxAPI, extraArgs)
+	}
+	var dummy T
+	path := mapper.Type2Path[reflect.TypeOf(dummy)]
+	objectType, err := refreshObjectType[T](ctx, nbi, path, extraArgs)
+	if err != nil {
+		return nil, err
+	}
+	nbi.snapshot.setObjectType(path, objectType)
+
+	// Objects are ordered by their IDs, so indexes are built in the same order on each run
+	ids := slices.Sorted(maps.Keys(objectType.Objects))
+	objects := make([]T, 0, len(ids))
+	for _, id := range ids {
+		var object T
+		if err := json.Unmarshal(objectType.Objects[id], &object); err != nil {
+			return nil, fmt.Errorf("unmarshal %T with ID %d from snapshot: %s", dummy, id, err)
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// refreshObjectType returns an updated copy of the snapshot of objects of type T at path.
+func refreshObjectType[T any](
+	ctx context.Context,
+	nbi *NetboxInventory,
+	path constants.APIPath,
+	extraArgs string,
+) (*objectTypeSnapshot, error) {
+	var dummy T
+	lastUpdatedArgs, err := snapshotQueryArgs(extraArgs, "last_updated")
+	if err != nil {
+		return nil, err
+	}
+	previous := nbi.snapshot.objectType(path)
+	objectType := &objectTypeSnapshot{Params: extraArgs, Objects: make(map[int]json.RawMessage)}
+	var changedReferences map[constants.APIPath]map[int]bool
+	collectAll := previous == nil || previous.Params != extraArgs
+	if !collectAll {
+		changedReferences, objectType.References, err = nbi.changedReferences(ctx, reflect.TypeOf(dummy), previous)
+		if err != nil {
+			return nil, err
+		}
+		collectAll = changedReferences == nil
+	}
+	if collectAll {
+		objects, err := service.GetAllRaw[T](ctx, nbi.NetboxAPI, lastUpdatedArgs)
+		if err != nil {
+			return nil, err
+		}
+		if err := objectType.update(objects); err != nil {
+			return nil, err
+		}
+		objectType.References = nbi.snapshot.referenceBaselines(reflect.TypeOf(dummy), objectType.LastUpdated)
+		nbi.Logger.Debugf(ctx, "Collected all %d %T for the inventory snapshot", len(objects), dummy)
+		return objectType, nil
+	}
+
+	since := previous.LastUpdated.Add(-snapshotOverlap)
+	changed, err := service.GetAllRaw[T](
+		ctx,
+		nbi.NetboxAPI,
+		fmt.Sprintf("%s&last_updated__gte=%s", lastUpdatedArgs, url.QueryEscape(since.Format(time.RFC3339Nano))),
+	)
+	if err != nil {
+		return nil, err
+	}
+	idArgs, err := snapshotQueryArgs(extraArgs, "")
+	if err != nil {
+		return nil, err
+	}
+	existing, err := service.GetAllRaw[T](ctx, nbi.NetboxAPI, idArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only objects, which still exist, are kept. IDs are listed after changed objects
+	// are collected, so objects deleted in the meantime are removed too.
+	existingIDs := make(map[int]bool, len(existing))
+	for _, object := range existing {
+		var fields snapshotObject
+		if err := json.Unmarshal(object, &fields); err != nil {
+			return nil, err
+		}
+		existingIDs[fields.ID] = true
+	}
+	objectType.LastUpdated = previous.LastUpdated
+	for id, object := range previous.Objects {
+		if existingIDs[id] {
+			objectType.Objects[id] = object
+		}
+	}
+	deleted := len(previous.Objects) - len(objectType.Objects)
+
+	// Nested objects of the objects, which reference changed objects, are outdated
+	var staleIDs []int
+	for id, object := range objectType.Objects {
+		if referencesChanged(reflect.TypeOf(dummy), object, snapshotReferenceDepth, changedReferences) {
+			staleIDs = append(staleIDs, id)
+		}
+	}
+	slices.Sort(staleIDs)
+	for ids := range slices.Chunk(staleIDs, snapshotIDsPerRequest) {
+		idFilter := make([]string, 0, len(ids))
+		for _, id := range ids {
+			idFilter = append(idFilter, fmt.Sprintf("&id=%d", id))
+		}
+		stale, err := service.GetAllRaw[T](ctx, nbi.NetboxAPI, lastUpdatedArgs+strings.Join(idFilter, ""))
+		if err != nil {
+			return nil, err
+		}
+		changed = append(changed, stale...)
+	}
+
+	changed = slices.DeleteFunc(changed, func(object json.RawMessage) bool {
+		var fields snapshotObject
+		return json.Unmarshal(object, &fields) != nil || !existingIDs[fields.ID]
+	})
+	if err := objectType.update(changed); err != nil {
+		return nil, err
+	}
+	nbi.Logger.Debugf(
+		ctx,
+		"Collected %d changed %T since %s (%d with changed nested objects), %d were deleted",
+		len(changed),
+		dummy,
+		since.Format(time.RFC3339),
+		len(staleIDs),
+		deleted,
+	)
+	return objectType, nil
+}
+
+// changedReferences returns IDs of objects of types referenced by objects of type t,
+// which changed since they were checked for the previous snapshot, together with the
+// timestamps, since which they have to be checked on the next refresh. If changes of
+// some referenced type can't be found, nil is returned, so all objects are collected.
+func (nbi *NetboxInventory) changedReferences(
+	ctx context.Context,
+	t reflect.Type,
+	previous *objectTypeSnapshot,
+) (map[constants.APIPath]map[int]bool, map[constants.APIPath]time.Time, error) {
+	paths := make(map[constants.APIPath]bool)
+	referencedTypes(t, snapshotReferenceDepth, paths)
+	changed := make(map[constants.APIPath]map[int]bool, len(paths))
+	references := make(map[constants.APIPath]time.Time, len(paths))
+	for path := range paths {
+		since, ok := previous.References[path]
+		if !ok || since.IsZero() {
+			return nil, nil, nil
+		}
+		objects, err := service.GetAllRawAt(
+			ctx,
+			nbi.NetboxAPI,
+			path,
+			"&fields=id,last_updated&last_updated__gte="+
+				url.QueryEscape(since.Add(-snapshotOverlap).Format(time.RFC3339Nano)),
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		changed[path] = make(map[int]bool, len(objects))
+		references[path] = since
+		for _, object := range objects {
+			var fields snapshotObject
+			if err := json.Unmarshal(object, &fields); err != nil {
+				return nil, nil, err
+			}
+			changed[path][fields.ID] = true
+			if fields.LastUpdated != nil && fields.LastUpdated.After(references[path]) {
+				references[path] = *fields.LastUpdated
+			}
+		}
+	}
+	return changed, references, nil
+}
+
+// referenceBaselines returns timestamps, since which changes of object types referenced
+// by objects of type t are checked, after all objects were collected. Referenced objects
+// changed after the collection are newer than lastUpdated of the collected objects, and
+// than lastUpdated of the referenced types in the snapshot.
+func (s *inventorySnapshot) referenceBaselines(
+	t reflect.Type,
+	lastUpdated time.Time,
+) map[constants.APIPath]time.Time {
+	paths := make(map[constants.APIPath]bool)
+	referencedTypes(t, snapshotReferenceDepth, paths)
+	references := make(map[constants.APIPath]time.Time, len(paths))
+	for path := range paths {
+		references[path] = lastUpdated
+		if objectType := s.objectType(path); objectType != nil && objectType.LastUpdated.After(lastUpdated) {
+			references[path] = objectType.LastUpdated
+		}
+	}
+	return references
+}
+
+// referencedTypes adds API paths of object types referenced by struct t to paths,
+// including types referenced by the nested objects up to depth.
+func referencedTypes(t reflect.Type, depth int, paths map[constants.APIPath]bool) {
+	referenceFields(t, func(_ string, refType reflect.Type, path constants.APIPath) {
+		paths[path] = true
+		if depth > 1 {
+			referencedTypes(refType, depth-1, paths)
+		}
+	})
+}
+
+// referenceFields calls f for each field of struct t, which is a nested object (or a list
+// of them) of a type with an API path. Fields of the embedded structs are included.
+func referenceFields(t reflect.Type, f func(jsonTag string, refType reflect.Type, path constants.APIPath)) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer || fieldType.Kind() == reflect.Slice {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() != reflect.Struct {
+			continue
+		}
+		if field.Anonymous {
+			referenceFields(fieldType, f)
+			continue
+		}
+		path, ok := mapper.Type2Path[fieldType]
+		jsonTag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !ok || jsonTag == "" || jsonTag == "-" {
+			continue
+		}
+		f(jsonTag, fieldType, path)
+	}
+}
+
+// referencesChanged returns true if object (of struct t in the format of the Netbox API)
+// references any of the changed objects, directly or in nested objects up to depth.
+func referencesChanged(
+	t reflect.Type,
+	object json.RawMessage,
+	depth int,
+	changed map[constants.APIPath]map[int]bool,
+) bool {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(object, &fields) != nil {
+		return false
+	}
+	found := false
+	referenceFields(t, func(jsonTag string, refType reflect.Type, path constants.APIPath) {
+		value, ok := fields[jsonTag]
+		if found || !ok {
+			return
+		}
+		nestedObjects := []json.RawMessage{value}
+		var list []json.RawMessage
+		if json.Unmarshal(value, &list) == nil {
+			nestedObjects = list
+		}
+		for _, nestedObject := range nestedObjects {
+			var nested snapshotObject
+			if json.Unmarshal(nestedObject, &nested) != nil {
+				continue
+			}
+			if changed[path][nested.ID] ||
+				depth > 1 && referencesChanged(refType, nestedObject, depth-1, changed) {
+				found = true
+				return
+			}
+		}
+	})
+	return found
+}
+
+// snapshotQueryArgs returns extraArgs of an init function, with field added to
+// the requested fields. If field is empty, only IDs of objects are requested.
+func snapshotQueryArgs(extraArgs string, field string) (string, error) {
+	query, err := url.ParseQuery(strings.TrimPrefix(extraArgs, "&"))
+	if err != nil {
+		return "", fmt.Errorf("parse query params %s: %s", extraArgs, err)
+	}
+	switch {
+	case field == "":
+		query.Set("fields", "id")
+	case query.Has("fields"):
+		query.Set("fields", query.Get("fields")+","+field)
+	}
+	if len(query) == 0 {
+		return "", nil
+	}
+	return "&" + query.Encode(), nil
+}
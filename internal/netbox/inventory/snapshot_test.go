@@ -0,0 +1,340 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/service"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+)
+
+// snapshotServer serves objects by their API paths, filtered by last_updated__gte and id,
+// with only requested fields as in Netbox.
+type snapshotServer struct {
+	lock    sync.Mutex
+	objects map[string]map[int]map[string]any
+	queries []string
+}
+
+func newSnapshotServer() *snapshotServer {
+	return &snapshotServer{objects: map[string]map[int]map[string]any{}}
+}
+
+func (s *snapshotServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	query := r.URL.Query()
+	s.queries = append(s.queries, r.URL.Path+"?"+query.Encode())
+	var since time.Time
+	if value := query.Get("last_updated__gte"); value != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339Nano, value); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	results := []map[string]any{}
+	for id, object := range s.objects[r.URL.Path] {
+		lastUpdated, _ := time.Parse(time.RFC3339Nano, object["last_updated"].(string))
+		if lastUpdated.Before(since) {
+			continue
+		}
+		if ids := query["id"]; len(ids) > 0 && !slices.Contains(ids, strconv.Itoa(id)) {
+			continue
+		}
+		if fields := query.Get("fields"); fields != "" {
+			projected := map[string]any{}
+			for _, field := range strings.Split(fields, ",") {
+				if value, ok := object[field]; ok {
+					projected[field] = value
+				}
+			}
+			object = projected
+		}
+		results = append(results, object)
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"count": len(results), "results": results})
+}
+
+func (s *snapshotServer) setObject(path constants.APIPath, object map[string]any, lastUpdated time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.objects[string(path)] == nil {
+		s.objects[string(path)] = map[int]map[string]any{}
+	}
+	object["last_updated"] = lastUpdated.Format(time.RFC3339Nano)
+	s.objects[string(path)][object["id"].(int)] = object
+}
+
+func (s *snapshotServer) setSite(id int, name string, lastUpdated time.Time) {
+	s.setObject(constants.SitesAPIPath, map[string]any{"id": id, "name": name}, lastUpdated)
+}
+
+func (s *snapshotServer) resetQueries() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.queries = nil
+}
+
+func (s *snapshotServer) queryLog() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return strings.Join(s.queries, "\n")
+}
+
+func newSnapshotTestInventory(t *testing.T, serverURL string, config parser.SnapshotConfig) *NetboxInventory {
+	t.Helper()
+	nbi := NewNetboxInventory(context.Background(), mockLogger, &parser.NetboxConfig{Snapshot: config}, false)
+	nbi.NetboxAPI = &service.NetboxClient{
+		HTTPClient: &http.Client{},
+		Logger:     mockLogger,
+		BaseURL:    serverURL,
+		Timeout:    constants.DefaultAPITimeout,
+	}
+	return nbi
+}
+
+func siteNames(sites []objects.Site) []string {
+	names := make([]string, 0, len(sites))
+	for _, site := range sites {
+		names = append(names, site.Name)
+	}
+	return names
+}
+
+func TestGetAllWithSnapshot(t *testing.T) {
+	ctx := context.Background()
+	server := newSnapshotServer()
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	server.setSite(1, "site1", start)
+	server.setSite(2, "site2", start.Add(time.Hour))
+	server.setSite(3, "site3", start.Add(2*time.Hour))
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	snapshotPath := filepath.Join(t.TempDir(), "snapshot.json")
+	config := parser.SnapshotConfig{Path: snapshotPath}
+
+	// The first run collects all objects
+	nbi := newSnapshotTestInventory(t, httpServer.URL, config)
+	nbi.prepareSnapshot(ctx)
+	sites, err := getAll[objects.Site](ctx, nbi, "&fields=id,name")
+	if err != nil {
+		t.Fatalf("getAll() error = %v", err)
+	}
+	if got, want := siteNames(sites), []string{"site1", "site2", "site3"}; !slices.Equal(got, want) {
+		t.Errorf("getAll() = %v, want %v", got, want)
+	}
+	if query := server.queryLog(); !strings.Contains(query, "fields=id%2Cname%2Clast_updated") ||
+		strings.Contains(query, "last_updated__gte") {
+		t.Errorf("first run queried %q, want all objects with last_updated field", query)
+	}
+	nbi.saveSnapshot(ctx)
+
+	// The next run collects only changed objects and removes deleted ones
+	server.setSite(2, "site2-renamed", start.Add(3*time.Hour))
+	server.setSite(4, "site4", start.Add(4*time.Hour))
+	server.lock.Lock()
+	delete(server.objects[string(constants.SitesAPIPath)], 3)
+	server.queries = nil
+	server.lock.Unlock()
+	nbi = newSnapshotTestInventory(t, httpServer.URL, config)
+	nbi.prepareSnapshot(ctx)
+	sites, err = getAll[objects.Site](ctx, nbi, "&fields=id,name")
+	if err != nil {
+		t.Fatalf("getAll() error = %v", err)
+	}
+	if got, want := siteNames(sites), []string{"site1", "site2-renamed", "site4"}; !slices.Equal(got, want) {
+		t.Errorf("getAll() = %v, want %v", got, want)
+	}
+	since := start.Add(2*time.Hour - snapshotOverlap).Format(time.RFC3339Nano)
+	wantQuery := "last_updated__gte=" + strings.ReplaceAll(since, ":", "%3A")
+	if query := server.queryLog(); !strings.Contains(query, wantQuery) {
+		t.Errorf("next run queried %q, want objects updated since %s", query, since)
+	}
+	if got := nbi.snapshot.objectType(constants.SitesAPIPath).LastUpdated; !got.Equal(start.Add(4 * time.Hour)) {
+		t.Errorf("snapshot last updated = %s, want %s", got, start.Add(4*time.Hour))
+	}
+
+	// Changed params collect all objects again
+	server.resetQueries()
+	if _, err := getAll[objects.Site](ctx, nbi, "&fields=id,name,slug"); err != nil {
+		t.Fatalf("getAll() error = %v", err)
+	}
+	if query := server.queryLog(); strings.Contains(query, "last_updated__gte") {
+		t.Errorf("run with changed params queried %q, want all objects", query)
+	}
+}
+
+func TestGetAllWithSnapshotNestedObjects(t *testing.T) {
+	ctx := context.Background()
+	server := newSnapshotServer()
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	server.setSite(1, "site1", start)
+	server.setSite(2, "site2", start)
+	setLocation := func(id int, siteID int, siteName string, lastUpdated time.Time) {
+		server.setObject(constants.LocationsAPIPath, map[string]any{
+			"id":   id,
+			"name": "location" + strconv.Itoa(id),
+			"site": map[string]any{"id": siteID, "name": siteName},
+		}, lastUpdated)
+	}
+	setLocation(1, 1, "site1", start)
+	setLocation(2, 2, "site2", start.Add(time.Hour))
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	config := parser.SnapshotConfig{Path: filepath.Join(t.TempDir(), "snapshot.json")}
+
+	nbi := newSnapshotTestInventory(t, httpServer.URL, config)
+	nbi.prepareSnapshot(ctx)
+	if _, err := getAll[objects.Site](ctx, nbi, ""); err != nil {
+		t.Fatalf("getAll() error = %v", err)
+	}
+	if _, err := getAll[objects.Location](ctx, nbi, ""); err != nil {
+		t.Fatalf("getAll() error = %v", err)
+	}
+	nbi.saveSnapshot(ctx)
+
+	// Renaming the site changes the nested site of the location, without changing the location
+	server.setSite(1, "site1-renamed", start.Add(2*time.Hour))
+	server.lock.Lock()
+	server.objects[string(constants.LocationsAPIPath)][1]["site"] = map[string]any{"id": 1, "name": "site1-renamed"}
+	server.lock.Unlock()
+	server.resetQueries()
+
+	nbi = newSnapshotTestInventory(t, httpServer.URL, config)
+	nbi.prepareSnapshot(ctx)
+	locations, err := getAll[objects.Location](ctx, nbi, "")
+	if err != nil {
+		t.Fatalf("getAll() error = %v", err)
+	}
+	locationSites := make([]string, 0, len(locations))
+	for _, location := range locations {
+		locationSites = append(locationSites, location.Site.Name)
+	}
+	if want := []string{"site1-renamed", "site2"}; !slices.Equal(locationSites, want) {
+		t.Errorf("getAll() sites of locations = %v, want %v", locationSites, want)
+	}
+	if query := server.queryLog(); !strings.Contains(query, "id=1") || strings.Contains(query, "id=2") {
+		t.Errorf("next run queried %q, want only the location with changed site", query)
+	}
+}
+
+func TestPrepareSnapshot(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	netboxURL := "https://netbox.example.com:443"
+	writeSnapshot := func(t *testing.T, snapshot *inventorySnapshot) string {
+		t.Helper()
+		path := filepath.Join(dir, t.Name()+".json")
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if snapshot != nil {
+			if err := snapshot.write(path); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return path
+	}
+	withSites := func(snapshot *inventorySnapshot) *inventorySnapshot {
+		snapshot.ObjectTypes[constants.SitesAPIPath] = &objectTypeSnapshot{Objects: map[int]json.RawMessage{}}
+		return snapshot
+	}
+	oldSnapshot := withSites(newInventorySnapshot(netboxURL))
+	oldSnapshot.Created = time.Now().Add(-2 * time.Hour)
+
+	tests := []struct {
+		name       string
+		snapshot   *inventorySnapshot
+		maxAge     int
+		wantReused bool
+	}{
+		{
+			name:       "Snapshot is reused",
+			snapshot:   withSites(newInventorySnapshot(netboxURL)),
+			wantReused: true,
+		},
+		{
+			name:     "Missing snapshot",
+			snapshot: nil,
+		},
+		{
+			name:     "Snapshot of other Netbox",
+			snapshot: withSites(newInventorySnapshot("https://other.example.com:443")),
+		},
+		{
+			name:     "Snapshot older than max age",
+			snapshot: oldSnapshot,
+			maxAge:   3600,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeSnapshot(t, tt.snapshot)
+			nbi := newSnapshotTestInventory(t, netboxURL, parser.SnapshotConfig{Path: path, MaxAge: tt.maxAge})
+			nbi.prepareSnapshot(ctx)
+			if nbi.snapshot == nil {
+				t.Fatal("prepareSnapshot() didn't prepare a snapshot")
+			}
+			if reused := nbi.snapshot.objectType(constants.SitesAPIPath) != nil; reused != tt.wantReused {
+				t.Errorf("snapshot reused = %t, want %t", reused, tt.wantReused)
+			}
+		})
+	}
+
+	nbi := newSnapshotTestInventory(t, netboxURL, parser.SnapshotConfig{})
+	nbi.prepareSnapshot(ctx)
+	if nbi.snapshot != nil {
+		t.Error("prepareSnapshot() prepared a snapshot, when it is disabled")
+	}
+}
+
+func TestSnapshotQueryArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		extraArgs string
+		field     string
+		want      string
+	}{
+		{
+			name:      "Add field",
+			extraArgs: "&fields=id,name&tag=netbox-ssot",
+			field:     "last_updated",
+			want:      "&fields=id%2Cname%2Clast_updated&tag=netbox-ssot",
+		},
+		{
+			name:      "Only IDs",
+			extraArgs: "&fields=id,name&tag=netbox-ssot",
+			want:      "&fields=id&tag=netbox-ssot",
+		},
+		{
+			name:      "All fields are requested",
+			extraArgs: "",
+			field:     "last_updated",
+			want:      "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := snapshotQueryArgs(tt.extraArgs, tt.field)
+			if err != nil {
+				t.Fatalf("snapshotQueryArgs() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("snapshotQueryArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
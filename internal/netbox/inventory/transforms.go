@@ -0,0 +1,130 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+	"github.com/bl4ko/netbox-ssot/internal/utils"
+)
+
+// ErrDropped is returned by AddDevice and AddVM, when the object is dropped
+// by a transform rule. Sources skip dropped objects.
+var ErrDropped = errors.New("object is dropped by a transform rule")
+
+// transform is a transform rule with compiled match regex.
+type transform struct {
+	parser.TransformRule
+	match *regexp.Regexp
+}
+
+// apply applies all actions of the rule, except drop, to the value.
+func (t *transform) apply(value string) string {
+	if t.Replace != nil {
+		value = t.match.ReplaceAllString(value, *t.Replace)
+	}
+	if mapped, ok := t.Map[value]; ok {
+		value = mapped
+	}
+	value = strings.TrimSuffix(value, t.TrimSuffix)
+	if value != "" && !strings.HasSuffix(value, t.AppendSuffix) {
+		value += t.AppendSuffix
+	}
+	switch t.Case {
+	case parser.CaseLower:
+		value = strings.ToLower(value)
+	case parser.CaseUpper:
+		value = strings.ToUpper(value)
+	}
+	return value
+}
+
+// SetTransforms sets transform rules, which are applied to objects
+// from sources by AddDevice, AddVM and AddPlatform.
+func (nbi *NetboxInventory) SetTransforms(rules []parser.TransformRule) error {
+	transforms := make([]transform, 0, len(rules))
+	for i, rule := range rules {
+		match, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return fmt.Errorf("transforms[%d].match: %s", i, err)
+		}
+		transforms = append(transforms, transform{TransformRule: rule, match: match})
+	}
+	nbi.transforms = transforms
+	return nil
+}
+
+// applyTransforms applies transform rules to the object from the source in ctx, and returns
+// true if the object is dropped. Objects, which are already in Netbox (e.g. copies of objects
+// returned by AddVM), were transformed when they were added, so they are left unchanged.
+// Slug of the object is updated, when its name is transformed.
+func (nbi *NetboxInventory) applyTransforms(ctx context.Context, obj objects.OrphanItem) bool {
+	if len(nbi.transforms) == 0 || obj.GetID() != 0 {
+		return false
+	}
+	source, _ := ctx.Value(constants.CtxSourceKey).(string)
+	for i := range nbi.transforms {
+		rule := &nbi.transforms[i]
+		if rule.ObjectType != obj.GetObjectType() || len(rule.Sources) > 0 && !slices.Contains(rule.Sources, source) {
+			continue
+		}
+		field := stringField(obj, rule.Field)
+		if !field.IsValid() {
+			continue
+		}
+		value := field.String()
+		if rule.Match != "" && !rule.match.MatchString(value) {
+			continue
+		}
+		if rule.Drop {
+			nbi.Logger.Debugf(ctx, "Dropping %s with %s %q by a transform rule", obj.GetObjectType(), rule.Field, value)
+			return true
+		}
+		transformed := rule.apply(value)
+		if transformed == value {
+			continue
+		}
+		nbi.Logger.Debugf(
+			ctx, "Transformed %s of %s from %q to %q", rule.Field, obj.GetObjectType(), value, transformed,
+		)
+		field.SetString(transformed)
+		if slug := stringField(obj, "slug"); rule.Field == "name" && slug.IsValid() {
+			slug.SetString(utils.Slugify(transformed))
+		}
+	}
+	return false
+}
+
+// stringField returns the string field of the object, which is named name in the
+// Netbox API. Invalid value is returned, if the object has no such field.
+func stringField(obj interface{}, name string) reflect.Value {
+	value := reflect.ValueOf(obj)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	return structStringField(value.Elem(), name)
+}
+
+func structStringField(structValue reflect.Value, name string) reflect.Value {
+	for i := range structValue.NumField() {
+		field := structValue.Type().Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			// Fields of embedded NetboxObject
+			if found := structStringField(structValue.Field(i), name); found.IsValid() {
+				return found
+			}
+			continue
+		}
+		if strings.Split(field.Tag.Get("json"), ",")[0] == name && field.Type.Kind() == reflect.String {
+			return structValue.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
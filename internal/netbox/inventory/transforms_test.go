@@ -0,0 +1,161 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"reflect"
+	"testing"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/logger"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+)
+
+func TestNetboxInventory_applyTransforms(t *testing.T) {
+	replace := "${1}-$2"
+	tests := []struct {
+		name        string
+		rules       []parser.TransformRule
+		source      string
+		obj         objects.OrphanItem
+		want        objects.OrphanItem
+		wantDropped bool
+	}{
+		{
+			name: "Actions are applied in order",
+			rules: []parser.TransformRule{{
+				ObjectType:   constants.ContentTypeDcimDevice,
+				Field:        "name",
+				Match:        `^(esx)(\d+)`,
+				Replace:      &replace,
+				TrimSuffix:   ".example.com",
+				AppendSuffix: ".lan",
+				Case:         parser.CaseUpper,
+			}},
+			source: "vcenter",
+			obj:    &objects.Device{Name: "esx01.example.com"},
+			want:   &objects.Device{Name: "ESX-01.LAN"},
+		},
+		{
+			name: "Map replaces the whole value",
+			rules: []parser.TransformRule{{
+				ObjectType: constants.ContentTypeDcimPlatform,
+				Field:      "name",
+				Map:        map[string]string{"Microsoft Windows Server 2019": "Windows Server 2019"},
+			}},
+			source: "vcenter",
+			obj:    &objects.Platform{Name: "Microsoft Windows Server 2019", Slug: "microsoft-windows-server-2019"},
+			want:   &objects.Platform{Name: "Windows Server 2019", Slug: "windows-server-2019"},
+		},
+		{
+			name: "Field of embedded NetboxObject is transformed",
+			rules: []parser.TransformRule{{
+				ObjectType: constants.ContentTypeVirtualizationVirtualMachine,
+				Field:      "description",
+				Case:       parser.CaseLower,
+			}},
+			source: "vcenter",
+			obj:    &objects.VM{NetboxObject: objects.NetboxObject{Description: "Web Server"}, Name: "web1"},
+			want:   &objects.VM{NetboxObject: objects.NetboxObject{Description: "web server"}, Name: "web1"},
+		},
+		{
+			name: "Matching VM is dropped",
+			rules: []parser.TransformRule{{
+				ObjectType: constants.ContentTypeVirtualizationVirtualMachine,
+				Field:      "name",
+				Match:      `^vCLS-`,
+				Drop:       true,
+			}},
+			source:      "vcenter",
+			obj:         &objects.VM{Name: "vCLS-1234"},
+			want:        &objects.VM{Name: "vCLS-1234"},
+			wantDropped: true,
+		},
+		{
+			name: "Objects of other sources and types aren't transformed",
+			rules: []parser.TransformRule{
+				{
+					ObjectType: constants.ContentTypeDcimDevice,
+					Sources:    []string{"ovirt"},
+					Field:      "name",
+					Case:       parser.CaseUpper,
+				},
+				{
+					ObjectType: constants.ContentTypeVirtualizationVirtualMachine,
+					Field:      "name",
+					Case:       parser.CaseUpper,
+				},
+			},
+			source: "vcenter",
+			obj:    &objects.Device{Name: "esx01"},
+			want:   &objects.Device{Name: "esx01"},
+		},
+		{
+			name: "Objects, which aren't matched, aren't transformed",
+			rules: []parser.TransformRule{{
+				ObjectType:   constants.ContentTypeDcimDevice,
+				Field:        "name",
+				Match:        `^esx`,
+				AppendSuffix: ".lan",
+			}},
+			source: "vcenter",
+			obj:    &objects.Device{Name: "kvm01"},
+			want:   &objects.Device{Name: "kvm01"},
+		},
+		{
+			name: "Objects, which are already in Netbox, aren't transformed again",
+			rules: []parser.TransformRule{{
+				ObjectType: constants.ContentTypeDcimDevice,
+				Field:      "name",
+				Drop:       true,
+			}},
+			source: "vcenter",
+			obj:    &objects.Device{NetboxObject: objects.NetboxObject{ID: 1}, Name: "esx01"},
+			want:   &objects.Device{NetboxObject: objects.NetboxObject{ID: 1}, Name: "esx01"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nbi := &NetboxInventory{Logger: &logger.Logger{Logger: log.New(io.Discard, "", 0)}}
+			if err := nbi.SetTransforms(tt.rules); err != nil {
+				t.Fatalf("SetTransforms() error = %v", err)
+			}
+			ctx := context.WithValue(context.Background(), constants.CtxSourceKey, tt.source)
+			if dropped := nbi.applyTransforms(ctx, tt.obj); dropped != tt.wantDropped {
+				t.Errorf("applyTransforms() = %t, want %t", dropped, tt.wantDropped)
+			}
+			if !reflect.DeepEqual(tt.obj, tt.want) {
+				t.Errorf("applyTransforms() obj = %+v, want %+v", tt.obj, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetboxInventory_AddVM_Dropped(t *testing.T) {
+	nbi := &NetboxInventory{Logger: &logger.Logger{Logger: log.New(io.Discard, "", 0)}}
+	err := nbi.SetTransforms([]parser.TransformRule{{
+		ObjectType: constants.ContentTypeVirtualizationVirtualMachine,
+		Field:      "name",
+		Match:      `^vCLS-`,
+		Drop:       true,
+	}})
+	if err != nil {
+		t.Fatalf("SetTransforms() error = %v", err)
+	}
+	ctx := context.WithValue(context.Background(), constants.CtxSourceKey, "vcenter")
+	vm, err := nbi.AddVM(ctx, &objects.VM{Name: "vCLS-1234"})
+	if vm != nil || !errors.Is(err, ErrDropped) {
+		t.Errorf("AddVM() = %v, %v, want nil, %v", vm, err, ErrDropped)
+	}
+}
+
+func TestNetboxInventory_SetTransforms_InvalidMatch(t *testing.T) {
+	nbi := &NetboxInventory{}
+	err := nbi.SetTransforms([]parser.TransformRule{{Match: "("}})
+	if err == nil {
+		t.Errorf("SetTransforms() error = nil, want error")
+	}
+}
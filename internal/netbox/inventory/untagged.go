@@ -0,0 +1,245 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/service"
+)
+
+// If InitOnlyTagged is set, objects of types owned by netbox-ssot which aren't
+// tagged with the ssot tag are not collected at startup. Creating them again
+// would either fail on unique constraints of Netbox (e.g. devices and
+// interfaces), or duplicate them (e.g. IP addresses). So before an object of
+// such type is created, it is looked up in Netbox by the same natural key
+// that is used by the index. If it is found, it is added to the index, and
+// it is then patched (and tagged) in the same way as collected objects.
+//
+// All functions in this file expect the lock of the index to be held.
+
+// findUntagged returns objects of type T matching params, which are not
+// tagged with the ssot tag. If InitOnlyTagged is not set, all objects are
+// already in the index, so nil is returned without a request.
+func findUntagged[T any](ctx context.Context, nbi *NetboxInventory, params string) ([]T, error) {
+	if nbi.tagFilter() == "" {
+		return nil, nil
+	}
+	untagged, err := service.GetAll[T](
+		ctx,
+		nbi.NetboxAPI,
+		fmt.Sprintf("%s&tag__n=%s", params, url.QueryEscape(nbi.SsotTag.Slug)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("look up untagged objects: %s", err)
+	}
+	return untagged, nil
+}
+
+// indexUntaggedDevice adds an existing untagged device with the name and site
+// of newDevice to the device indexes.
+func (nbi *NetboxInventory) indexUntaggedDevice(ctx context.Context, newDevice *objects.Device) error {
+	devices, err := findUntagged[objects.Device](ctx, nbi, fmt.Sprintf(
+		"&name=%s&site_id=%d", url.QueryEscape(newDevice.Name), newDevice.Site.ID,
+	))
+	if err != nil || len(devices) == 0 {
+		return err
+	}
+	device := &devices[0]
+	nbi.devicesIndexByID[device.ID] = device
+	if nbi.devicesIndexByNameAndSiteID[newDevice.Name] == nil {
+		nbi.devicesIndexByNameAndSiteID[newDevice.Name] = make(map[int]*objects.Device)
+	}
+	nbi.devicesIndexByNameAndSiteID[newDevice.Name][newDevice.Site.ID] = device
+	return nil
+}
+
+// indexUntaggedVirtualDeviceContext adds an existing untagged virtual device
+// context with the name and device of newVDC to the index.
+func (nbi *NetboxInventory) indexUntaggedVirtualDeviceContext(
+	ctx context.Context,
+	newVDC *objects.VirtualDeviceContext,
+) error {
+	vdcs, err := findUntagged[objects.VirtualDeviceContext](ctx, nbi, fmt.Sprintf(
+		"&name=%s&device_id=%d", url.QueryEscape(newVDC.Name), newVDC.Device.ID,
+	))
+	if err != nil || len(vdcs) == 0 {
+		return err
+	}
+	if nbi.virtualDeviceContextsIndex[newVDC.Name] == nil {
+		nbi.virtualDeviceContextsIndex[newVDC.Name] = make(map[int]*objects.VirtualDeviceContext)
+	}
+	nbi.virtualDeviceContextsIndex[newVDC.Name][newVDC.Device.ID] = &vdcs[0]
+	return nil
+}
+
+// indexUntaggedInterface adds an existing untagged interface with the device
+// and name of newInterface to the interface indexes.
+func (nbi *NetboxInventory) indexUntaggedInterface(ctx context.Context, newInterface *objects.Interface) error {
+	interfaces, err := findUntagged[objects.Interface](ctx, nbi, fmt.Sprintf(
+		"&device_id=%d&name=%s", newInterface.Device.ID, url.QueryEscape(newInterface.Name),
+	))
+	if err != nil || len(interfaces) == 0 {
+		return err
+	}
+	iface := &interfaces[0]
+	nbi.interfacesIndexByID[iface.ID] = iface
+	if nbi.interfacesIndexByDeviceIDAndName[newInterface.Device.ID] == nil {
+		nbi.interfacesIndexByDeviceIDAndName[newInterface.Device.ID] = make(map[string]*objects.Interface)
+	}
+	nbi.interfacesIndexByDeviceIDAndName[newInterface.Device.ID][newInterface.Name] = iface
+	return nil
+}
+
+// indexUntaggedVM adds an existing untagged VM with the name and cluster
+// of newVM to the VM indexes. VMs without a cluster are indexed by cluster ID -1.
+func (nbi *NetboxInventory) indexUntaggedVM(ctx context.Context, newVM *objects.VM, clusterID int) error {
+	params := fmt.Sprintf("&name=%s", url.QueryEscape(newVM.Name))
+	if newVM.Cluster != nil {
+		params += fmt.Sprintf("&cluster_id=%d", clusterID)
+	}
+	vms, err := findUntagged[objects.VM](ctx, nbi, params)
+	if err != nil {
+		return err
+	}
+	for i := range vms {
+		vm := &vms[i]
+		// VMs without a cluster can't be filtered by cluster
+		if newVM.Cluster == nil && vm.Cluster != nil {
+			continue
+		}
+		nbi.vmsIndexByID[vm.ID] = vm
+		if nbi.vmsIndexByNameAndClusterID[newVM.Name] == nil {
+			nbi.vmsIndexByNameAndClusterID[newVM.Name] = make(map[int]*objects.VM)
+		}
+		nbi.vmsIndexByNameAndClusterID[newVM.Name][clusterID] = vm
+		return nil
+	}
+	return nil
+}
+
+// indexUntaggedVMInterface adds an existing untagged VM interface with the VM
+// and name of newVMInterface to the VM interface indexes.
+func (nbi *NetboxInventory) indexUntaggedVMInterface(
+	ctx context.Context,
+	newVMInterface *objects.VMInterface,
+) error {
+	vmInterfaces, err := findUntagged[objects.VMInterface](ctx, nbi, fmt.Sprintf(
+		"&virtual_machine_id=%d&name=%s", newVMInterface.VM.ID, url.QueryEscape(newVMInterface.Name),
+	))
+	if err != nil || len(vmInterfaces) == 0 {
+		return err
+	}
+	vmIface := &vmInterfaces[0]
+	nbi.vmInterfacesIndexByID[vmIface.ID] = vmIface
+	if nbi.vmInterfacesIndexByVMIdAndName[newVMInterface.VM.ID] == nil {
+		nbi.vmInterfacesIndexByVMIdAndName[newVMInterface.VM.ID] = make(map[string]*objects.VMInterface)
+	}
+	nbi.vmInterfacesIndexByVMIdAndName[newVMInterface.VM.ID][newVMInterface.Name] = vmIface
+	return nil
+}
+
+// indexUntaggedVirtualDisk adds an existing untagged virtual disk with the VM
+// and name of newVirtualDisk to the index.
+func (nbi *NetboxInventory) indexUntaggedVirtualDisk(
+	ctx context.Context,
+	newVirtualDisk *objects.VirtualDisk,
+) error {
+	virtualDisks, err := findUntagged[objects.VirtualDisk](ctx, nbi, fmt.Sprintf(
+		"&virtual_machine_id=%d&name=%s", newVirtualDisk.VM.ID, url.QueryEscape(newVirtualDisk.Name),
+	))
+	if err != nil || len(virtualDisks) == 0 {
+		return err
+	}
+	if nbi.virtualDisksIndexByVMIDAndName[newVirtualDisk.VM.ID] == nil {
+		nbi.virtualDisksIndexByVMIDAndName[newVirtualDisk.VM.ID] = make(map[string]*objects.VirtualDisk)
+	}
+	nbi.virtualDisksIndexByVMIDAndName[newVirtualDisk.VM.ID][newVirtualDisk.Name] = &virtualDisks[0]
+	return nil
+}
+
+// indexUntaggedContactAssignment adds an existing untagged contact assignment
+// with the object, contact and role of newCA to the index.
+func (nbi *NetboxInventory) indexUntaggedContactAssignment(
+	ctx context.Context,
+	newCA *objects.ContactAssignment,
+) error {
+	cas, err := findUntagged[objects.ContactAssignment](ctx, nbi, fmt.Sprintf(
+		"&object_type=%s&object_id=%d&contact_id=%d&role_id=%d",
+		url.QueryEscape(string(newCA.ModelType)), newCA.ObjectID, newCA.Contact.ID, newCA.Role.ID,
+	))
+	if err != nil || len(cas) == 0 {
+		return err
+	}
+	nbi.contactAssignmentsIndex[newCA.ModelType][newCA.ObjectID][newCA.Contact.ID][newCA.Role.ID] = &cas[0]
+	return nil
+}
+
+// indexUntaggedIPAddress adds an existing untagged IP address with the address
+// and assigned object of newIPAddress to the IP address index, and returns
+// its index key. If newIPAddress has no VRF, IP addresses of all VRFs match,
+// and the VRF of the found IP address is preserved, as with findIPAddressAcrossVRFs.
+func (nbi *NetboxInventory) indexUntaggedIPAddress(
+	ctx context.Context,
+	newIPAddress *objects.IPAddress,
+	ipIndex map[string]*objects.IPAddress,
+) (string, error) {
+	params := fmt.Sprintf("&address=%s", url.QueryEscape(newIPAddress.Address))
+	if newIPAddress.AssignedObjectType != "" {
+		params += fmt.Sprintf(
+			"&assigned_object_type=%s&assigned_object_id=%d",
+			url.QueryEscape(string(newIPAddress.AssignedObjectType)), newIPAddress.AssignedObjectID,
+		)
+	}
+	if newIPAddress.VRF != nil {
+		params += fmt.Sprintf("&vrf_id=%d", newIPAddress.VRF.ID)
+	}
+	ipAddresses, err := findUntagged[objects.IPAddress](ctx, nbi, params)
+	if err != nil {
+		return "", err
+	}
+	for i := range ipAddresses {
+		ipAddress := &ipAddresses[i]
+		// Unassigned IP addresses can't be filtered by assigned object
+		if ipAddress.AssignedObjectType != newIPAddress.AssignedObjectType {
+			continue
+		}
+		if newIPAddress.VRF == nil {
+			newIPAddress.VRF = ipAddress.VRF
+		}
+		indexKey := ipAddressIndexKey(ipAddress)
+		ipIndex[indexKey] = ipAddress
+		return indexKey, nil
+	}
+	return "", nil
+}
+
+// indexUntaggedMACAddress adds an existing untagged MAC address with the address
+// and assigned object of newMACAddress to the MAC address index.
+func (nbi *NetboxInventory) indexUntaggedMACAddress(
+	ctx context.Context,
+	newMACAddress *objects.MACAddress,
+	macIndex map[string]*objects.MACAddress,
+) error {
+	params := fmt.Sprintf("&mac_address=%s", url.QueryEscape(newMACAddress.MAC))
+	if newMACAddress.AssignedObjectType != "" {
+		params += fmt.Sprintf(
+			"&assigned_object_type=%s&assigned_object_id=%d",
+			url.QueryEscape(string(newMACAddress.AssignedObjectType)), newMACAddress.AssignedObjectID,
+		)
+	}
+	macAddresses, err := findUntagged[objects.MACAddress](ctx, nbi, params)
+	if err != nil {
+		return err
+	}
+	for i := range macAddresses {
+		// Unassigned MAC addresses can't be filtered by assigned object
+		if macAddresses[i].AssignedObjectType != newMACAddress.AssignedObjectType {
+			continue
+		}
+		macIndex[newMACAddress.MAC] = &macAddresses[i]
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package inventory
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+)
+
+func TestNetboxInventory_AddDeviceUntagged(t *testing.T) {
+	untaggedDevice := map[string]interface{}{
+		"id":   float64(7),
+		"name": "device1",
+		"site": map[string]interface{}{"id": float64(1)},
+	}
+	tests := []struct {
+		name           string
+		initOnlyTagged bool
+		untagged       []map[string]interface{}
+		wantID         int
+		wantRequests   map[string]int
+	}{
+		{
+			name:           "Untagged device is patched",
+			initOnlyTagged: true,
+			untagged:       []map[string]interface{}{untaggedDevice},
+			wantID:         7,
+			wantRequests:   map[string]int{http.MethodGet: 1, http.MethodPatch: 1, http.MethodPost: 0},
+		},
+		{
+			name:           "Missing device is created",
+			initOnlyTagged: true,
+			wantID:         101,
+			wantRequests:   map[string]int{http.MethodGet: 1, http.MethodPatch: 0, http.MethodPost: 1},
+		},
+		{
+			name:           "No lookup without initOnlyTagged",
+			initOnlyTagged: false,
+			untagged:       []map[string]interface{}{untaggedDevice},
+			wantID:         101,
+			wantRequests:   map[string]int{http.MethodGet: 0, http.MethodPatch: 0, http.MethodPost: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), constants.CtxSourceKey, "test")
+			nbi, testServer := newBulkTestInventory(t, false)
+			nbi.NetboxConfig = &parser.NetboxConfig{InitOnlyTagged: tt.initOnlyTagged}
+			nbi.SsotTag.Slug = "netbox-ssot"
+			nbi.devicesIndexByNameAndSiteID = map[string]map[int]*objects.Device{}
+			nbi.devicesIndexByID = map[int]*objects.Device{}
+			testServer.list[string(constants.DevicesAPIPath)] = tt.untagged
+
+			site := &objects.Site{NetboxObject: objects.NetboxObject{ID: 1}}
+			device, err := nbi.AddDevice(ctx, &objects.Device{Name: "device1", Site: site})
+			if err != nil {
+				t.Fatalf("AddDevice() error = %s", err)
+			}
+			if device.ID != tt.wantID {
+				t.Errorf("AddDevice() ID = %d, want %d", device.ID, tt.wantID)
+			}
+			if nbi.devicesIndexByNameAndSiteID["device1"][1] != device {
+				t.Errorf("device %+v is not indexed", device)
+			}
+
+			testServer.lock.Lock()
+			defer testServer.lock.Unlock()
+			for method, want := range tt.wantRequests {
+				if got := testServer.requests[method]; got != want {
+					t.Errorf("%s requests = %d, want %d", method, got, want)
+				}
+			}
+			for _, query := range testServer.queries {
+				if !strings.Contains(query, "name=device1&site_id=1&tag__n=netbox-ssot") {
+					t.Errorf("unexpected query %s", query)
+				}
+			}
+		})
+	}
+}
+
+func TestNetboxInventory_AddIPAddressesUntagged(t *testing.T) {
+	ctx := context.WithValue(context.Background(), constants.CtxSourceKey, "test")
+	nbi, testServer := newBulkTestInventory(t, false)
+	nbi.NetboxConfig = &parser.NetboxConfig{InitOnlyTagged: true}
+	vm := &objects.VM{NetboxObject: objects.NetboxObject{ID: 1}, Name: "vm1"}
+	vmIface := &objects.VMInterface{NetboxObject: objects.NetboxObject{ID: 5}, VM: vm, Name: "eth0"}
+	nbi.vmInterfacesIndexByVMIdAndName[vm.ID] = map[string]*objects.VMInterface{"eth0": vmIface}
+	nbi.vmInterfacesIndexByID[vmIface.ID] = vmIface
+	// Untagged IP address with a manually assigned VRF
+	testServer.list[string(constants.IPAddressesAPIPath)] = []map[string]interface{}{
+		{
+			"id":                   float64(9),
+			"address":              "10.0.0.1/24",
+			"vrf":                  map[string]interface{}{"id": float64(3)},
+			"assigned_object_type": string(constants.ContentTypeVirtualizationVMInterface),
+			"assigned_object_id":   float64(vmIface.ID),
+		},
+	}
+
+	ipAddresses, err := nbi.AddIPAddresses(ctx, []*objects.IPAddress{
+		{
+			Address:            "10.0.0.1/24",
+			AssignedObjectType: constants.ContentTypeVirtualizationVMInterface,
+			AssignedObjectID:   vmIface.ID,
+		},
+	})
+	if err != nil {
+		t.Fatalf("AddIPAddresses() error = %s", err)
+	}
+	if len(ipAddresses) != 1 || ipAddresses[0].ID != 9 {
+		t.Fatalf("AddIPAddresses() = %v, want untagged IP address 9", ipAddresses)
+	}
+	indexed := nbi.ipAddressesIndex[constants.ContentTypeVirtualizationVirtualMachine]["eth0"]["vm1"]
+	if indexed["vrf3/10.0.0.1/24"] != ipAddresses[0] {
+		t.Errorf("IP address %+v is not indexed with its VRF: %v", ipAddresses[0], indexed)
+	}
+
+	testServer.lock.Lock()
+	defer testServer.lock.Unlock()
+	if got := testServer.requests[http.MethodPost]; got != 0 {
+		t.Errorf("POST requests = %d, want 0", got)
+	}
+	if got := testServer.requests[http.MethodPatch]; got != 1 {
+		t.Errorf("PATCH requests = %d, want 1", got)
+	}
+}
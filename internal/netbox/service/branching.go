@@ -0,0 +1,161 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Path of branches in the API of the NetBox branching plugin.
+const branchesPath = "/api/plugins/branching/branches/"
+
+// BranchHeader is the header, which selects the branch a request is performed in.
+// Its value is the schema ID of the branch.
+const BranchHeader = "X-NetBox-Branch"
+
+// Statuses of a branch.
+const (
+	BranchStatusReady  = "ready"
+	BranchStatusMerged = "merged"
+	BranchStatusFailed = "failed"
+)
+
+// Interval between requests, which check status of a branch.
+var branchPollInterval = 2 * time.Second
+
+// Branch is a branch of the NetBox branching plugin.
+type Branch struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// SchemaID identifies the branch in the BranchHeader.
+	SchemaID string `json:"schema_id"`
+	Status   struct {
+		Value string `json:"value"`
+	} `json:"status"`
+}
+
+func (b Branch) String() string {
+	return fmt.Sprintf("Branch{ID: %d, Name: %s, SchemaID: %s, Status: %s}", b.ID, b.Name, b.SchemaID, b.Status.Value)
+}
+
+// isBranchingPath returns true for paths of the branching plugin API,
+// which are always requested in main, regardless of api.Branch.
+func isBranchingPath(path string) bool {
+	return strings.HasPrefix(path, branchesPath)
+}
+
+// CreateBranch creates a new branch and waits up to timeout for it to be provisioned.
+func CreateBranch(
+	ctx context.Context,
+	netboxClient *NetboxClient,
+	name string,
+	description string,
+	timeout time.Duration,
+) (*Branch, error) {
+	netboxClient.Logger.Debugf(ctx, "Creating branch %s", name)
+	requestBody, err := json.Marshal(map[string]string{"name": name, "description": description})
+	if err != nil {
+		return nil, err
+	}
+	response, err := netboxClient.doRequest(ctx, http.MethodPost, branchesPath, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("create branch %s: %s", name, err)
+	}
+	if response.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("create branch %s: unexpected status code: %d: %s", name, response.StatusCode, response.Body)
+	}
+	var branch Branch
+	if err := json.Unmarshal(response.Body, &branch); err != nil {
+		return nil, fmt.Errorf("create branch %s: %s", name, err)
+	}
+	return waitForBranchStatus(ctx, netboxClient, &branch, BranchStatusReady, timeout)
+}
+
+// GetBranch returns the branch with the given id.
+func GetBranch(ctx context.Context, netboxClient *NetboxClient, id int) (*Branch, error) {
+	response, err := netboxClient.doRequest(ctx, http.MethodGet, fmt.Sprintf("%s%d/", branchesPath, id), nil)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d: %s", response.StatusCode, response.Body)
+	}
+	var branch Branch
+	if err := json.Unmarshal(response.Body, &branch); err != nil {
+		return nil, err
+	}
+	return &branch, nil
+}
+
+// MergeBranch merges the branch into main and waits up to timeout for the merge to finish.
+func MergeBranch(ctx context.Context, netboxClient *NetboxClient, branch *Branch, timeout time.Duration) error {
+	netboxClient.Logger.Debugf(ctx, "Merging branch %s", branch.Name)
+	requestBody, err := json.Marshal(map[string]bool{"commit": true})
+	if err != nil {
+		return err
+	}
+	response, err := netboxClient.doRequest(
+		ctx,
+		http.MethodPost,
+		fmt.Sprintf("%s%d/merge/", branchesPath, branch.ID),
+		bytes.NewBuffer(requestBody),
+	)
+	if err != nil {
+		return fmt.Errorf("merge branch %s: %s", branch.Name, err)
+	}
+	// Merge is performed by a background job, which is returned in the response
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
+	default:
+		return fmt.Errorf(
+			"merge branch %s: unexpected status code: %d: %s", branch.Name, response.StatusCode, response.Body,
+		)
+	}
+	_, err = waitForBranchStatus(ctx, netboxClient, branch, BranchStatusMerged, timeout)
+	return err
+}
+
+// waitForBranchStatus polls the branch until it has status want, and returns it.
+// Status is checked right away and then every branchPollInterval. It fails if
+// the branch fails, or doesn't reach the status within timeout.
+func waitForBranchStatus(
+	ctx context.Context,
+	netboxClient *NetboxClient,
+	branch *Branch,
+	want string,
+	timeout time.Duration,
+) (*Branch, error) {
+	deadline := time.Now().Add(timeout)
+	for attempt := 0; ; attempt++ {
+		switch branch.Status.Value {
+		case want:
+			return branch, nil
+		case BranchStatusFailed:
+			return nil, fmt.Errorf("branch %s failed", branch.Name)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf(
+				"branch %s is still %s after %s, expected %s", branch.Name, branch.Status.Value, timeout, want,
+			)
+		}
+		if attempt > 0 {
+			timer := time.NewTimer(branchPollInterval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, fmt.Errorf("waiting for branch %s: %w", branch.Name, ctx.Err())
+			case <-timer.C:
+			}
+		}
+		current, err := GetBranch(ctx, netboxClient, branch.ID)
+		if err != nil {
+			return nil, fmt.Errorf("get branch %s: %s", branch.Name, err)
+		}
+		netboxClient.Logger.Debugf(ctx, "Branch %s is %s", current.Name, current.Status.Value)
+		branch = current
+	}
+}
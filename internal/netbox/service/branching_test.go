@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+)
+
+func setBranchPollInterval(t *testing.T, interval time.Duration) {
+	t.Helper()
+	previous := branchPollInterval
+	branchPollInterval = interval
+	t.Cleanup(func() { branchPollInterval = previous })
+}
+
+func TestBranching(t *testing.T) {
+	setBranchPollInterval(t, time.Millisecond)
+	branching := &MockBranching{}
+	server := httptest.NewServer(branching)
+	defer server.Close()
+	client := newBulkTestClient(server.URL)
+	ctx := context.Background()
+
+	branch, err := CreateBranch(ctx, client, "netbox-ssot-run-1", "Run 1", time.Second)
+	if err != nil {
+		t.Fatalf("CreateBranch() error = %s", err)
+	}
+	if branch.Status.Value != BranchStatusReady || branch.SchemaID != "schema1" {
+		t.Fatalf("CreateBranch() = %s, want ready branch with schema ID schema1", branch)
+	}
+
+	if _, err := Create(ctx, client, &objects.Tag{Name: "main"}); err != nil {
+		t.Fatal(err)
+	}
+	client.Branch = branch.SchemaID
+	if _, err := Create(ctx, client, &objects.Site{Name: "branched"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Patch[objects.Site](ctx, client, 1, map[string]any{"name": "patched"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.DeleteObject(ctx, &objects.Site{NetboxObject: objects.NetboxObject{ID: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := MergeBranch(ctx, client, branch, time.Second); err != nil {
+		t.Fatalf("MergeBranch() error = %s", err)
+	}
+
+	wantHeaders := map[string]string{
+		"POST /api/extras/tags/":    "",
+		"POST /api/dcim/sites/":     "schema1",
+		"PATCH /api/dcim/sites/1/":  "schema1",
+		"DELETE /api/dcim/sites/1/": "schema1",
+	}
+	for request, want := range wantHeaders {
+		if got, ok := branching.BranchHeaders[request]; !ok || got != want {
+			t.Errorf("%s has branch header %q, want %q", request, got, want)
+		}
+	}
+	if branches := branching.Branches(); len(branches) != 1 || branches[0].Status.Value != BranchStatusMerged {
+		t.Errorf("Branches() = %v, want a single merged branch", branches)
+	}
+}
+
+func TestBranching_Errors(t *testing.T) {
+	setBranchPollInterval(t, time.Millisecond)
+	notInstalled := httptest.NewServer(http.NotFoundHandler())
+	defer notInstalled.Close()
+	stuck := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		_, _ = w.Write([]byte(`{"id": 1, "name": "stuck", "status": {"value": "provisioning"}}`))
+	}))
+	defer stuck.Close()
+	failingMerge := &MockBranching{FailMerge: true}
+	failingMergeServer := httptest.NewServer(failingMerge)
+	defer failingMergeServer.Close()
+
+	tests := []struct {
+		name    string
+		run     func(ctx context.Context) error
+		wantErr string
+	}{
+		{
+			name: "Plugin is not installed",
+			run: func(ctx context.Context) error {
+				_, err := CreateBranch(ctx, newBulkTestClient(notInstalled.URL), "run", "", time.Second)
+				return err
+			},
+			wantErr: "create branch run: unexpected status code: 404",
+		},
+		{
+			name: "Branch is not provisioned in time",
+			run: func(ctx context.Context) error {
+				_, err := CreateBranch(ctx, newBulkTestClient(stuck.URL), "stuck", "", 10*time.Millisecond)
+				return err
+			},
+			wantErr: "branch stuck is still provisioning after 10ms, expected ready",
+		},
+		{
+			name: "Merge fails",
+			run: func(ctx context.Context) error {
+				client := newBulkTestClient(failingMergeServer.URL)
+				branch, err := CreateBranch(ctx, client, "failing", "", time.Second)
+				if err != nil {
+					return err
+				}
+				return MergeBranch(ctx, client, branch, time.Second)
+			},
+			wantErr: "branch failing failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run(context.Background())
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
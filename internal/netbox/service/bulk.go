@@ -0,0 +1,201 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/bl4ko/netbox-ssot/internal/netbox/mapper"
+	"github.com/bl4ko/netbox-ssot/internal/report"
+	"github.com/bl4ko/netbox-ssot/internal/utils"
+)
+
+// Default maximum number of objects sent in a single bulk request.
+const bulkPageSize = 50
+
+// bulkSize returns the maximum number of objects sent in a single bulk create or patch.
+func (netboxClient *NetboxClient) bulkSize() int {
+	if netboxClient.BulkSize < 1 {
+		return bulkPageSize
+	}
+	return netboxClient.BulkSize
+}
+
+// PatchRequest is a patch of a single object in a bulk update.
+type PatchRequest struct {
+	// ID of the object to patch.
+	ID int
+	// Body contains the changed fields of the object.
+	Body map[string]interface{}
+}
+
+// BulkCreate creates all objects of type T, with requests to the list endpoint
+// of type T containing up to BulkSize objects of the client. Created objects are returned
+// in the same order as the given objects.
+//
+// Each request is atomic in Netbox. If a request fails, objects created by
+// the previous requests are returned together with the error.
+func BulkCreate[T any](ctx context.Context, netboxClient *NetboxClient, objs []*T) ([]*T, error) {
+	var dummy T // dummy variable for printf
+	objectPath := mapper.Type2Path[reflect.TypeOf(dummy)]
+	if objectPath == "" {
+		return nil, fmt.Errorf("path not found for type %T", dummy)
+	}
+	if len(objs) == 0 {
+		return nil, nil
+	}
+
+	objectMaps := make([]map[string]interface{}, 0, len(objs))
+	for _, obj := range objs {
+		objectMaps = append(objectMaps, utils.StructToNetboxJSONMap(obj))
+	}
+
+	if netboxClient.DryRun {
+		netboxClient.Logger.Infof(ctx, "[DRY-RUN] Would bulk create %d %T at %s", len(objs), dummy, objectPath)
+		for i, obj := range objs {
+			setFakeID(obj, netboxClient.generateFakeID())
+			netboxClient.Recorder.Record(
+				ctx, report.ActionCreate, reflect.TypeOf(dummy), objectPath, getID(obj), objectMaps[i],
+			)
+		}
+		return objs, nil
+	}
+
+	created := make([]*T, 0, len(objs))
+	bulkSize := netboxClient.bulkSize()
+	for i := 0; i < len(objs); i += bulkSize {
+		end := min(i+bulkSize, len(objs))
+		netboxClient.Logger.Debugf(
+			ctx,
+			"Bulk creating %d %T with path %s (offset=%d)",
+			end-i,
+			dummy,
+			objectPath,
+			i,
+		)
+		requestBody, err := json.Marshal(objectMaps[i:end])
+		if err != nil {
+			return created, err
+		}
+		response, err := netboxClient.doRequest(ctx, http.MethodPost, string(objectPath), bytes.NewBuffer(requestBody))
+		if err != nil {
+			return created, err
+		}
+		if response.StatusCode != http.StatusCreated {
+			return created, fmt.Errorf("unexpected status code: %d: %s", response.StatusCode, response.Body)
+		}
+
+		var objectsResponse []*T
+		if err := json.Unmarshal(response.Body, &objectsResponse); err != nil {
+			return created, err
+		}
+		if len(objectsResponse) != end-i {
+			return created, fmt.Errorf("bulk create returned %d objects, expected %d", len(objectsResponse), end-i)
+		}
+		for j, obj := range objectsResponse {
+			netboxClient.Recorder.Record(
+				ctx, report.ActionCreate, reflect.TypeOf(dummy), objectPath, getID(obj), objectMaps[i+j],
+			)
+		}
+		created = append(created, objectsResponse...)
+	}
+
+	netboxClient.Logger.Debugf(ctx, "Successfully bulk created %d %T", len(created), dummy)
+	return created, nil
+}
+
+// BulkPatch patches all objects of type T, with requests to the list endpoint
+// of type T containing up to BulkSize patches of the client. Patched objects are returned
+// in the same order as the given patches.
+//
+// Each request is atomic in Netbox. If a request fails, objects patched by
+// the previous requests are returned together with the error.
+func BulkPatch[T any](ctx context.Context, netboxClient *NetboxClient, patches []PatchRequest) ([]*T, error) {
+	var dummy T // dummy variable for printf
+	objectPath := mapper.Type2Path[reflect.TypeOf(dummy)]
+	if objectPath == "" {
+		return nil, fmt.Errorf("path not found for type %T", dummy)
+	}
+	if len(patches) == 0 {
+		return nil, nil
+	}
+
+	if netboxClient.DryRun {
+		netboxClient.Logger.Infof(ctx, "[DRY-RUN] Would bulk update %d %T at %s", len(patches), dummy, objectPath)
+		patched := make([]*T, 0, len(patches))
+		for _, patch := range patches {
+			netboxClient.Logger.Infof(
+				objectLogCtx(ctx, reflect.TypeOf(dummy), patch.ID),
+				"[DRY-RUN] Would update %T (ID: %d) with: %v",
+				dummy,
+				patch.ID,
+				patch.Body,
+			)
+			netboxClient.Recorder.Record(ctx, report.ActionUpdate, reflect.TypeOf(dummy), objectPath, patch.ID, patch.Body)
+			var result T
+			setFakeID(&result, patch.ID)
+			patched = append(patched, &result)
+		}
+		return patched, nil
+	}
+
+	patched := make([]*T, 0, len(patches))
+	bulkSize := netboxClient.bulkSize()
+	for i := 0; i < len(patches); i += bulkSize {
+		end := min(i+bulkSize, len(patches))
+		netboxClient.Logger.Debugf(
+			ctx,
+			"Bulk patching %d %T with path %s (offset=%d)",
+			end-i,
+			dummy,
+			objectPath,
+			i,
+		)
+		// Netbox API supports only JSON request body in the following format:
+		// [ {"id": 1, "field": "value"}, {"id": 2, "field": "value"} ]
+		body := make([]map[string]interface{}, 0, end-i)
+		for _, patch := range patches[i:end] {
+			patchBody := make(map[string]interface{}, len(patch.Body)+1)
+			for field, value := range patch.Body {
+				patchBody[field] = value
+			}
+			patchBody["id"] = patch.ID
+			body = append(body, patchBody)
+		}
+		requestBody, err := json.Marshal(body)
+		if err != nil {
+			return patched, err
+		}
+		response, err := netboxClient.doRequest(ctx, http.MethodPatch, string(objectPath), bytes.NewBuffer(requestBody))
+		if err != nil {
+			return patched, err
+		}
+		if response.StatusCode != http.StatusOK {
+			return patched, fmt.Errorf("unexpected status code: %d: %s", response.StatusCode, response.Body)
+		}
+
+		var objectsResponse []*T
+		if err := json.Unmarshal(response.Body, &objectsResponse); err != nil {
+			return patched, err
+		}
+		// Map responses back by ID, so the order of the response doesn't matter
+		id2object := make(map[int]*T, len(objectsResponse))
+		for _, obj := range objectsResponse {
+			id2object[getID(obj)] = obj
+		}
+		for _, patch := range patches[i:end] {
+			obj, ok := id2object[patch.ID]
+			if !ok {
+				return patched, fmt.Errorf("bulk patch response is missing %T with ID %d", dummy, patch.ID)
+			}
+			netboxClient.Recorder.Record(ctx, report.ActionUpdate, reflect.TypeOf(dummy), objectPath, patch.ID, patch.Body)
+			patched = append(patched, obj)
+		}
+	}
+
+	netboxClient.Logger.Debugf(ctx, "Successfully bulk patched %d %T", len(patched), dummy)
+	return patched, nil
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/logger"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/report"
+)
+
+// newBulkTestServer creates a server which mimics Netbox list endpoints.
+// POST assigns IDs starting from 1 to the objects in the request, PATCH
+// echoes the patches in reversed order. Requests with more than failAfter
+// objects in total fail with 400. It returns the server and sizes of
+// received requests.
+func newBulkTestServer(t *testing.T, failAfter int) (*httptest.Server, *[]int) {
+	t.Helper()
+	requestSizes := []int{}
+	received := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var objs []map[string]interface{}
+		if err := json.Unmarshal(body, &objs); err != nil {
+			t.Errorf("request body is not a JSON array: %s", body)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requestSizes = append(requestSizes, len(objs))
+		if received+len(objs) > failAfter {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			for i, obj := range objs {
+				obj["id"] = received + i + 1
+			}
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodPatch:
+			slices.Reverse(objs)
+			w.WriteHeader(http.StatusOK)
+		}
+		received += len(objs)
+		resp, _ := json.Marshal(objs)
+		_, _ = w.Write(resp)
+	}))
+	return server, &requestSizes
+}
+
+func newBulkTestClient(baseURL string) *NetboxClient {
+	return &NetboxClient{
+		HTTPClient: &http.Client{},
+		Logger:     &logger.Logger{Logger: log.New(io.Discard, "", 0)},
+		BaseURL:    baseURL,
+		Timeout:    constants.DefaultAPITimeout,
+		Recorder:   report.NewRecorder(),
+		nextFakeID: dryRunFakeIDStart,
+	}
+}
+
+func TestBulkCreate(t *testing.T) {
+	tests := []struct {
+		name             string
+		count            int
+		bulkSize         int
+		failAfter        int
+		wantRequestSizes []int
+		wantCreated      int
+		wantErr          bool
+	}{
+		{
+			name:             "Create in multiple requests",
+			count:            bulkPageSize + 5,
+			failAfter:        1000,
+			wantRequestSizes: []int{bulkPageSize, 5},
+			wantCreated:      bulkPageSize + 5,
+		},
+		{
+			name:             "Second request fails",
+			count:            bulkPageSize + 5,
+			failAfter:        bulkPageSize,
+			wantRequestSizes: []int{bulkPageSize, 5},
+			wantCreated:      bulkPageSize,
+			wantErr:          true,
+		},
+		{
+			name:             "Configured bulk size",
+			count:            25,
+			bulkSize:         10,
+			failAfter:        1000,
+			wantRequestSizes: []int{10, 10, 5},
+			wantCreated:      25,
+		},
+		{
+			name:             "No objects",
+			count:            0,
+			failAfter:        1000,
+			wantRequestSizes: []int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requestSizes := newBulkTestServer(t, tt.failAfter)
+			defer server.Close()
+			client := newBulkTestClient(server.URL)
+			client.BulkSize = tt.bulkSize
+
+			tags := make([]*objects.Tag, 0, tt.count)
+			for range tt.count {
+				tags = append(tags, &objects.Tag{Name: "tag", Slug: "tag"})
+			}
+			created, err := BulkCreate(context.Background(), client, tags)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BulkCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(*requestSizes, tt.wantRequestSizes) {
+				t.Errorf("BulkCreate() request sizes = %v, want %v", *requestSizes, tt.wantRequestSizes)
+			}
+			if len(created) != tt.wantCreated {
+				t.Fatalf("BulkCreate() created %d objects, want %d", len(created), tt.wantCreated)
+			}
+			for i, tag := range created {
+				if tag.ID != i+1 || tag.Name != "tag" {
+					t.Errorf("BulkCreate() object %d = %+v", i, tag)
+				}
+			}
+			if changes := client.Recorder.Changes(); len(changes) != tt.wantCreated {
+				t.Errorf("BulkCreate() recorded %d changes, want %d", len(changes), tt.wantCreated)
+			}
+		})
+	}
+}
+
+func TestBulkPatch(t *testing.T) {
+	server, requestSizes := newBulkTestServer(t, 1000)
+	defer server.Close()
+	client := newBulkTestClient(server.URL)
+
+	patches := []PatchRequest{
+		{ID: 3, Body: map[string]interface{}{"name": "tag3"}},
+		{ID: 7, Body: map[string]interface{}{"name": "tag7"}},
+	}
+	patched, err := BulkPatch[objects.Tag](context.Background(), client, patches)
+	if err != nil {
+		t.Fatalf("BulkPatch() error = %s", err)
+	}
+	if !slices.Equal(*requestSizes, []int{2}) {
+		t.Errorf("BulkPatch() request sizes = %v, want [2]", *requestSizes)
+	}
+	// Responses are mapped back to patches by ID
+	for i, tag := range patched {
+		if tag.ID != patches[i].ID || tag.Name != patches[i].Body["name"] {
+			t.Errorf("BulkPatch() object %d = %+v, want ID %d", i, tag, patches[i].ID)
+		}
+	}
+	if len(patches[0].Body) != 1 {
+		t.Errorf("BulkPatch() modified patch body: %v", patches[0].Body)
+	}
+}
+
+func TestBulk_DryRun(t *testing.T) {
+	client := newBulkTestClient("http://localhost:1")
+	client.DryRun = true
+
+	tags := []*objects.Tag{{Name: "tag1"}, {Name: "tag2"}}
+	created, err := BulkCreate(context.Background(), client, tags)
+	if err != nil {
+		t.Fatalf("BulkCreate() error = %s", err)
+	}
+	if len(created) != 2 || created[0].ID == 0 || created[0].ID == created[1].ID {
+		t.Errorf("BulkCreate() in dry-run didn't set unique fake IDs: %+v", created)
+	}
+
+	patched, err := BulkPatch[objects.Tag](
+		context.Background(),
+		client,
+		[]PatchRequest{{ID: 5, Body: map[string]interface{}{"name": "tag5"}}},
+	)
+	if err != nil {
+		t.Fatalf("BulkPatch() error = %s", err)
+	}
+	if len(patched) != 1 || patched[0].ID != 5 {
+		t.Errorf("BulkPatch() in dry-run = %+v", patched)
+	}
+	if changes := client.Recorder.Changes(); len(changes) != 3 {
+		t.Errorf("dry-run recorded %d changes, want 3", len(changes))
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -9,6 +10,8 @@ import (
 	"time"
 
 	"github.com/bl4ko/netbox-ssot/internal/logger"
+	"github.com/bl4ko/netbox-ssot/internal/metrics"
+	"github.com/bl4ko/netbox-ssot/internal/report"
 	"github.com/bl4ko/netbox-ssot/internal/utils"
 )
 
@@ -20,8 +23,25 @@ type NetboxClient struct {
 	BaseURL    string
 	APIToken   string
 	Timeout    int // in seconds
-	MaxRetires int
-	DryRun     bool
+	// MaxRetries is the number of times a failed request is retried.
+	// Requests are retried on transport errors and 429 or 5xx responses.
+	MaxRetries int
+	// RateLimiter limits number of requests per second. If nil, requests are not limited.
+	RateLimiter *RateLimiter
+	// PageConcurrency is the maximum number of pages fetched concurrently by GetAll.
+	// Values lower than 1 are treated as 1.
+	PageConcurrency int
+	// BulkSize is the maximum number of objects sent in a single bulk create or patch.
+	// Values lower than 1 are treated as bulkPageSize.
+	BulkSize int
+	DryRun   bool
+	// Recorder records all changes made to Netbox. If nil, changes are not recorded.
+	Recorder *report.Recorder
+	// Metrics records count and latency of all requests. If nil, requests are not measured.
+	Metrics *metrics.Metrics
+	// Branch is the schema ID of the branch of the NetBox branching plugin, in which
+	// all requests are performed. If empty, requests are performed in main.
+	Branch string
 
 	nextFakeID     int64
 	nextFakeIDLock sync.Mutex
@@ -68,39 +88,110 @@ func (api *NetboxClient) generateFakeID() int {
 	return int(id)
 }
 
+// doRequest performs request to the Netbox API. Requests that fail with a
+// transport error, or with 429 or 5xx status code are retried up to MaxRetries
+// times with exponential backoff. If response contains Retry-After header,
+// it is used instead of the backoff. Waiting between retries is interrupted
+// when ctx is done.
 func (api *NetboxClient) doRequest(
+	ctx context.Context,
 	method string,
 	path string,
 	body io.Reader,
 ) (*APIResponse, error) {
+	// Body is read upfront, so it can be resent on retries
+	var requestBody []byte
+	if body != nil {
+		var err error
+		requestBody, err = io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		response, retryAfter, err := api.doRequestOnce(ctx, method, path, requestBody)
+		if !shouldRetry(method, response, err) || attempt >= api.MaxRetries {
+			if err != nil && attempt > 0 {
+				return nil, fmt.Errorf("request failed after %d attempts: %w", attempt+1, err)
+			}
+			return response, err
+		}
+
+		wait := exponentialBackoff(attempt)
+		if retryAfter >= 0 {
+			wait = retryAfter
+		}
+		if err != nil {
+			api.Logger.Warningf(ctx, "%s %s attempt %d failed: %s. Retrying in %s", method, path, attempt, err, wait)
+		} else {
+			api.Logger.Warningf(
+				ctx,
+				"%s %s attempt %d failed with status code %d. Retrying in %s",
+				method, path, attempt, response.StatusCode, wait,
+			)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%s %s canceled while waiting for retry: %w", method, path, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// doRequestOnce performs a single attempt of the request. Besides the response
+// it returns wait duration requested by Retry-After header, or -1 if not set.
+// Request is canceled after api.Timeout seconds, or when ctx is done. Time spent
+// waiting for the rate limiter doesn't count against the timeout.
+func (api *NetboxClient) doRequestOnce(
+	ctx context.Context,
+	method string,
+	path string,
+	body []byte,
+) (*APIResponse, time.Duration, error) {
+	if err := api.RateLimiter.Wait(ctx); err != nil {
+		return nil, -1, err
+	}
 	ctx, cancelCtx := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Second*time.Duration(api.Timeout),
 	)
 	defer cancelCtx()
 
-	req, err := http.NewRequestWithContext(ctx, method, api.BaseURL+path, body)
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, api.BaseURL+path, bodyReader)
 	if err != nil {
-		return nil, err
+		return nil, -1, err
 	}
 
 	// We add necessary headers to the request
 	req.Header.Add("Authorization", "Token "+api.APIToken)
 	req.Header.Add("Content-Type", "application/json")
+	if api.Branch != "" && !isBranchingPath(path) {
+		req.Header.Add(BranchHeader, api.Branch)
+	}
 
+	start := time.Now()
 	resp, err := api.HTTPClient.Do(req)
 	if err != nil {
-		return nil, err
+		api.Metrics.ObserveRequest(method, 0, time.Since(start))
+		return nil, -1, err
 	}
 	defer resp.Body.Close()
 
 	responseBody, err := io.ReadAll(resp.Body)
+	api.Metrics.ObserveRequest(method, resp.StatusCode, time.Since(start))
 	if err != nil {
-		return nil, err
+		return nil, -1, err
 	}
 
 	return &APIResponse{
 		StatusCode: resp.StatusCode,
 		Body:       responseBody,
-	}, nil
+	}, parseRetryAfter(resp.Header.Get("Retry-After")), nil
 }
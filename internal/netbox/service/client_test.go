@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"crypto/tls"
 	"io"
 	"log"
@@ -175,7 +176,7 @@ func TestNetboxAPI_doRequest(t *testing.T) {
 	MockNetboxClient.BaseURL = mockServer.URL
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := tt.netboxClient.doRequest(tt.args.method, tt.args.path, tt.args.body)
+			got, err := tt.netboxClient.doRequest(context.Background(), tt.args.method, tt.args.path, tt.args.body)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NetboxAPI.doRequest() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -7,10 +7,13 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"sync"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/logger"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/mapper"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/report"
 	"github.com/bl4ko/netbox-ssot/internal/utils"
 )
 
@@ -18,6 +21,9 @@ type VersionResponse struct {
 	NetboxVersion string `json:"netbox-version"`
 }
 
+// Number of objects requested in a single page by GetAll.
+const getAllPageSize = 250
+
 // Standard response format from Netbox's API.
 type Response[T any] struct {
 	Count    int     `json:"count"`
@@ -30,7 +36,7 @@ type Response[T any] struct {
 func GetVersion(ctx context.Context, netboxClient *NetboxClient) (string, error) {
 	var versionResponse VersionResponse
 	netboxClient.Logger.Debugf(ctx, "Getting netbox's version")
-	response, err := netboxClient.doRequest(http.MethodGet, "/api/status", nil)
+	response, err := netboxClient.doRequest(ctx, http.MethodGet, "/api/status", nil)
 	if err != nil {
 		return "", err
 	}
@@ -46,7 +52,9 @@ func GetVersion(ctx context.Context, netboxClient *NetboxClient) (string, error)
 }
 
 // GetAll queries all objects of type T from Netbox's API.
-// It is querying objects via pagination of limit=200.
+// It is querying objects via pagination of limit=getAllPageSize. The first page
+// is fetched to get the number of objects, the remaining pages are then fetched
+// with up to netboxClient.PageConcurrency concurrent requests.
 //
 // extraParams in a string format of: &extraParam1=...&extraParam2=...
 func GetAll[T any](
@@ -54,56 +62,161 @@ func GetAll[T any](
 	netboxClient *NetboxClient,
 	extraParams string,
 ) ([]T, error) {
-	var allResults []T
+	var dummy T // Dummy variable for extracting type of generic
+	netboxClient.Logger.Debugf(ctx, "Getting all %T from Netbox", dummy)
+	allResults, err := getAll[T, T](ctx, netboxClient, extraParams)
+	if err != nil {
+		return nil, err
+	}
+	netboxClient.Logger.Debugf(ctx, "Successfully received all %T: %v", dummy, allResults)
+	return allResults, nil
+}
+
+// GetAllRaw queries all objects of type T from Netbox's API in the same way as GetAll,
+// but returns them as raw JSON, so they can include fields, which T doesn't have.
+func GetAllRaw[T any](
+	ctx context.Context,
+	netboxClient *NetboxClient,
+	extraParams string,
+) ([]json.RawMessage, error) {
+	var dummy T // Dummy variable for extracting type of generic
+	netboxClient.Logger.Debugf(ctx, "Getting all raw %T from Netbox", dummy)
+	allResults, err := getAll[T, json.RawMessage](ctx, netboxClient, extraParams)
+	if err != nil {
+		return nil, err
+	}
+	netboxClient.Logger.Debugf(ctx, "Successfully received %d raw %T", len(allResults), dummy)
+	return allResults, nil
+}
+
+// GetAllRawAt is like GetAllRaw, but queries objects at path,
+// so it can be used when the type of the objects isn't known.
+func GetAllRawAt(
+	ctx context.Context,
+	netboxClient *NetboxClient,
+	path constants.APIPath,
+	extraParams string,
+) ([]json.RawMessage, error) {
+	netboxClient.Logger.Debugf(ctx, "Getting all raw objects at %s from Netbox", path)
+	allResults, err := getAllAt[json.RawMessage](ctx, netboxClient, path, extraParams)
+	if err != nil {
+		return nil, err
+	}
+	netboxClient.Logger.Debugf(ctx, "Successfully received %d raw objects at %s", len(allResults), path)
+	return allResults, nil
+}
+
+// getAll queries all objects of type T from Netbox's API, and returns them as R.
+func getAll[T any, R any](
+	ctx context.Context,
+	netboxClient *NetboxClient,
+	extraParams string,
+) ([]R, error) {
 	var dummy T // Dummy variable for extracting type of generic
 	path := mapper.Type2Path[reflect.TypeOf(dummy)]
 	if path == "" {
 		return nil, fmt.Errorf("path not found for type %T", dummy)
 	}
-	limit := 250
-	offset := 0
+	return getAllAt[R](ctx, netboxClient, path, extraParams)
+}
 
-	netboxClient.Logger.Debugf(ctx, "Getting all %T from Netbox", dummy)
+// getAllAt queries all objects at path from Netbox's API, and returns them as R.
+func getAllAt[R any](
+	ctx context.Context,
+	netboxClient *NetboxClient,
+	path constants.APIPath,
+	extraParams string,
+) ([]R, error) {
+	firstPage, err := getPage[R](ctx, netboxClient, path, 0, extraParams)
+	if err != nil {
+		return nil, err
+	}
+	if firstPage.Next == nil {
+		return firstPage.Results, nil
+	}
 
-	for {
-		netboxClient.Logger.Debugf(
-			ctx,
-			"Getting %T with limit=%d and offset=%d",
-			dummy,
-			limit,
-			offset,
-		)
-		queryPath := fmt.Sprintf("%s?limit=%d&offset=%d%s", path, limit, offset, extraParams)
-		response, err := netboxClient.doRequest(http.MethodGet, queryPath, nil)
+	numPages := (firstPage.Count + getAllPageSize - 1) / getAllPageSize
+	pages := make([]*Response[R], numPages)
+	pages[0] = firstPage
+
+	// Use a guard channel as semaphore to limit the number of concurrent requests
+	guard := make(chan struct{}, max(1, netboxClient.PageConcurrency))
+	errChan := make(chan error, numPages)
+	var wg sync.WaitGroup
+	for page := 1; page < numPages; page++ {
+		guard <- struct{}{}
+		wg.Add(1)
+		go func(page int) {
+			defer wg.Done()
+			defer func() { <-guard }()
+			response, err := getPage[R](ctx, netboxClient, path, page*getAllPageSize, extraParams)
+			if err != nil {
+				errChan <- err
+				return
+			}
+			pages[page] = response
+		}(page)
+	}
+	wg.Wait()
+	close(errChan)
+	for err := range errChan {
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		if response.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf(
-				"unexpected status code %d: %s",
-				response.StatusCode,
-				response.Body,
-			)
-		}
-
-		var responseObj Response[T]
-		err = json.Unmarshal(response.Body, &responseObj)
+	var allResults []R
+	for _, page := range pages {
+		allResults = append(allResults, page.Results...)
+	}
+	// Objects could be created while the pages were fetched,
+	// so the rest of them are fetched sequentially
+	for offset := numPages * getAllPageSize; pages[numPages-1].Next != nil; offset += getAllPageSize {
+		page, err := getPage[R](ctx, netboxClient, path, offset, extraParams)
 		if err != nil {
 			return nil, err
 		}
+		allResults = append(allResults, page.Results...)
+		pages[numPages-1] = page
+	}
+	return allResults, nil
+}
 
-		allResults = append(allResults, responseObj.Results...)
-
-		if responseObj.Next == nil {
-			break
-		}
-		offset += limit
+// getPage queries a single page of objects of type T from Netbox, starting at offset.
+func getPage[T any](
+	ctx context.Context,
+	netboxClient *NetboxClient,
+	path constants.APIPath,
+	offset int,
+	extraParams string,
+) (*Response[T], error) {
+	netboxClient.Logger.Debugf(
+		ctx,
+		"Getting %s with limit=%d and offset=%d",
+		path,
+		getAllPageSize,
+		offset,
+	)
+	queryPath := fmt.Sprintf("%s?limit=%d&offset=%d%s", path, getAllPageSize, offset, extraParams)
+	response, err := netboxClient.doRequest(ctx, http.MethodGet, queryPath, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	netboxClient.Logger.Debugf(ctx, "Successfully received all %T: %v", dummy, allResults)
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(
+			"unexpected status code %d: %s",
+			response.StatusCode,
+			response.Body,
+		)
+	}
 
-	return allResults, nil
+	var responseObj Response[T]
+	err = json.Unmarshal(response.Body, &responseObj)
+	if err != nil {
+		return nil, err
+	}
+	return &responseObj, nil
 }
 
 // Patch func patches the object of type T, with the given api path and body.
@@ -119,8 +232,10 @@ func Patch[T any](
 	if objectPath == "" {
 		return nil, fmt.Errorf("path not found for type %T", dummy)
 	}
+	logCtx := objectLogCtx(ctx, reflect.TypeOf(dummy), objectID)
 	if netboxClient.DryRun {
-		netboxClient.Logger.Infof(ctx, "[DRY-RUN] Would update %T (ID: %d) with: %v", dummy, objectID, body)
+		netboxClient.Logger.Infof(logCtx, "[DRY-RUN] Would update %T (ID: %d) with: %v", dummy, objectID, body)
+		netboxClient.Recorder.Record(ctx, report.ActionUpdate, reflect.TypeOf(dummy), objectPath, objectID, body)
 		var result T
 		setFakeID(&result, objectID)
 		return &result, nil
@@ -128,7 +243,7 @@ func Patch[T any](
 
 	path := fmt.Sprintf("%s%d/", objectPath, objectID)
 	netboxClient.Logger.Debugf(
-		ctx,
+		logCtx,
 		"Patching %T with path %s with data: %v",
 		dummy,
 		path,
@@ -141,7 +256,7 @@ func Patch[T any](
 	}
 
 	requestBodyBuffer := bytes.NewBuffer(requestBody)
-	response, err := netboxClient.doRequest(http.MethodPatch, path, requestBodyBuffer)
+	response, err := netboxClient.doRequest(ctx, http.MethodPatch, path, requestBodyBuffer)
 	if err != nil {
 		return nil, err
 	}
@@ -156,7 +271,8 @@ func Patch[T any](
 		return nil, err
 	}
 
-	netboxClient.Logger.Debugf(ctx, "Successfully patched %T: %v", dummy, objectResponse)
+	netboxClient.Recorder.Record(ctx, report.ActionUpdate, reflect.TypeOf(dummy), objectPath, objectID, body)
+	netboxClient.Logger.Debugf(logCtx, "Successfully patched %T: %v", dummy, objectResponse)
 	return &objectResponse, nil
 }
 
@@ -168,9 +284,16 @@ func Create[T any](ctx context.Context, netboxClient *NetboxClient, object *T) (
 		return nil, fmt.Errorf("path not found for type %T", dummy)
 	}
 
+	objectMap := utils.StructToNetboxJSONMap(object)
 	if netboxClient.DryRun {
-		netboxClient.Logger.Infof(ctx, "[DRY-RUN] Would create %T at %s", dummy, objectPath)
 		setFakeID(object, netboxClient.generateFakeID())
+		netboxClient.Logger.Infof(
+			objectLogCtx(ctx, reflect.TypeOf(dummy), getID(object)),
+			"[DRY-RUN] Would create %T at %s",
+			dummy,
+			objectPath,
+		)
+		netboxClient.Recorder.Record(ctx, report.ActionCreate, reflect.TypeOf(dummy), objectPath, getID(object), objectMap)
 		return object, nil
 	}
 
@@ -181,13 +304,13 @@ func Create[T any](ctx context.Context, netboxClient *NetboxClient, object *T) (
 		objectPath,
 		object,
 	)
-	requestBody, err := utils.NetboxJSONMarshal(object)
+	requestBody, err := json.Marshal(objectMap)
 	if err != nil {
 		return nil, err
 	}
 
 	requestBodyBuffer := bytes.NewBuffer(requestBody)
-	response, err := netboxClient.doRequest(http.MethodPost, string(objectPath), requestBodyBuffer)
+	response, err := netboxClient.doRequest(ctx, http.MethodPost, string(objectPath), requestBodyBuffer)
 	if err != nil {
 		return nil, err
 	}
@@ -202,10 +325,23 @@ func Create[T any](ctx context.Context, netboxClient *NetboxClient, object *T) (
 		return nil, err
 	}
 
-	netboxClient.Logger.Debugf(ctx, "Successfully created %T: %v", dummy, objectResponse)
+	netboxClient.Recorder.Record(
+		ctx, report.ActionCreate, reflect.TypeOf(dummy), objectPath, getID(&objectResponse), objectMap,
+	)
+	netboxClient.Logger.Debugf(
+		objectLogCtx(ctx, reflect.TypeOf(dummy), getID(&objectResponse)),
+		"Successfully created %T: %v",
+		dummy,
+		objectResponse,
+	)
 	return &objectResponse, nil
 }
 
+// objectLogCtx returns ctx, which adds type and ID of the object to log messages.
+func objectLogCtx(ctx context.Context, objectType reflect.Type, id int) context.Context {
+	return logger.WithObject(ctx, report.TypeName(objectType), id)
+}
+
 // setFakeID assigns a fake ID to a Netbox object using reflection.
 // It handles both objects embedding NetboxObject and objects with a direct ID field.
 func setFakeID(object any, id int) {
@@ -229,6 +365,27 @@ func setFakeID(object any, id int) {
 	}
 }
 
+// getID returns ID of a Netbox object using reflection,
+// or 0 if the object doesn't have an ID field.
+func getID(object any) int {
+	v := reflect.ValueOf(object)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return 0
+	}
+	if nb := v.FieldByName("NetboxObject"); nb.IsValid() {
+		if idField := nb.FieldByName("ID"); idField.IsValid() && idField.CanInt() {
+			return int(idField.Int())
+		}
+	}
+	if idField := v.FieldByName("ID"); idField.IsValid() && idField.CanInt() {
+		return int(idField.Int())
+	}
+	return 0
+}
+
 // Function that deletes object on path objectPath.
 // It deletes objects in pages of 50 so we don't stress
 // the API too much.
@@ -239,26 +396,27 @@ func (api *NetboxClient) BulkDeleteObjects(
 ) error {
 	if api.DryRun {
 		api.Logger.Infof(ctx, "[DRY-RUN] Would bulk delete %d objects at %s", len(idSet), objectPath)
+		for id := range idSet {
+			api.Recorder.Record(ctx, report.ActionDelete, mapper.Path2Type[objectPath], objectPath, id, nil)
+		}
 		return nil
 	}
 
-	const pageSize = 50
-
 	// Convert the map to a slice for easier slicing.
 	ids := make([]int, 0, len(idSet))
 	for id := range idSet {
 		ids = append(ids, id)
 	}
 
-	for i := 0; i < len(ids); i += pageSize {
+	for i := 0; i < len(ids); i += bulkPageSize {
 		api.Logger.Debugf(
 			ctx,
 			"Deleting %s with pagesize=%d and offset=%d",
 			objectPath,
-			pageSize,
+			bulkPageSize,
 			i,
 		)
-		end := i + pageSize
+		end := i + bulkPageSize
 		if end > len(ids) {
 			end = len(ids)
 		}
@@ -276,7 +434,7 @@ func (api *NetboxClient) BulkDeleteObjects(
 		}
 
 		requestBodyBuffer := bytes.NewBuffer(requestBody)
-		response, err := api.doRequest(http.MethodDelete, string(objectPath), requestBodyBuffer)
+		response, err := api.doRequest(ctx, http.MethodDelete, string(objectPath), requestBodyBuffer)
 		if err != nil {
 			return err
 		}
@@ -284,6 +442,9 @@ func (api *NetboxClient) BulkDeleteObjects(
 		if response.StatusCode != http.StatusNoContent {
 			return fmt.Errorf("unexpected status code: %d: %s", response.StatusCode, response.Body)
 		}
+		for _, id := range ids[i:end] {
+			api.Recorder.Record(ctx, report.ActionDelete, mapper.Path2Type[objectPath], objectPath, id, nil)
+		}
 	}
 	api.Logger.Debugf(ctx, "Successfully deleted all objects of path %s", objectPath)
 
@@ -294,16 +455,18 @@ func (api *NetboxClient) BulkDeleteObjects(
 // It deletes a single object at a time. It is alternative to bulk delete
 // because if one delete fails other still go.
 func (api *NetboxClient) DeleteObject(ctx context.Context, idItem objects.IDItem) error {
+	logCtx := objectLogCtx(ctx, reflect.TypeOf(idItem), idItem.GetID())
 	if api.DryRun {
-		api.Logger.Infof(ctx, "[DRY-RUN] Would delete %T (ID: %d) at %s", idItem, idItem.GetID(), idItem.GetAPIPath())
+		api.Logger.Infof(logCtx, "[DRY-RUN] Would delete %T (ID: %d) at %s", idItem, idItem.GetID(), idItem.GetAPIPath())
+		api.Recorder.Record(ctx, report.ActionDelete, reflect.TypeOf(idItem), idItem.GetAPIPath(), idItem.GetID(), nil)
 		return nil
 	}
 
 	id := idItem.GetID()
 	objectPath := idItem.GetAPIPath()
-	api.Logger.Debugf(ctx, "Deleting object with id %d on route %s", id, objectPath)
+	api.Logger.Debugf(logCtx, "Deleting object with id %d on route %s", id, objectPath)
 
-	response, err := api.doRequest(http.MethodDelete, fmt.Sprintf("%s%d/", objectPath, id), nil)
+	response, err := api.doRequest(ctx, http.MethodDelete, fmt.Sprintf("%s%d/", objectPath, id), nil)
 	if err != nil {
 		return err
 	}
@@ -311,5 +474,6 @@ func (api *NetboxClient) DeleteObject(ctx context.Context, idItem objects.IDItem
 	if response.StatusCode != http.StatusNoContent {
 		return fmt.Errorf("unexpected status code: %d: %s", response.StatusCode, response.Body)
 	}
+	api.Recorder.Record(ctx, report.ActionDelete, reflect.TypeOf(idItem), objectPath, id, nil)
 	return nil
 }
@@ -1,13 +1,20 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/logger"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/report"
 )
 
 func TestGetAll(t *testing.T) {
@@ -71,6 +78,34 @@ func TestGetAll(t *testing.T) {
 	}
 }
 
+func TestGetAllRaw(t *testing.T) {
+	mockServer := CreateMockServer()
+	defer mockServer.Close()
+	MockNetboxClient.BaseURL = mockServer.URL
+	ctx := context.WithValue(context.Background(), constants.CtxSourceKey, "test")
+
+	response, err := GetAllRaw[objects.Tag](ctx, MockNetboxClient, "")
+	if err != nil {
+		t.Fatalf("GetAllRaw() error = %v", err)
+	}
+	want, err := GetAll[objects.Tag](ctx, MockNetboxClient, "")
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if len(response) != len(want) {
+		t.Fatalf("GetAllRaw() returned %d objects, want %d", len(response), len(want))
+	}
+	for i, rawTag := range response {
+		var tag objects.Tag
+		if err := json.Unmarshal(rawTag, &tag); err != nil {
+			t.Fatalf("unmarshal raw tag: %v", err)
+		}
+		if !reflect.DeepEqual(tag, want[i]) {
+			t.Errorf("GetAllRaw()[%d] = %v, want %v", i, tag, want[i])
+		}
+	}
+}
+
 func TestPatch(t *testing.T) {
 	type args struct {
 		ctx      context.Context
@@ -244,6 +279,33 @@ func TestDeleteObject_DryRun(t *testing.T) {
 	}
 }
 
+func TestDryRun_LogsObjectFields(t *testing.T) {
+	ctx := context.WithValue(context.Background(), constants.CtxSourceKey, "test")
+	jsonLogger, err := logger.NewWithOptions(logger.Options{Level: logger.INFO, Format: logger.FormatJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logBuffer := new(bytes.Buffer)
+	jsonLogger.SetOutput(logBuffer)
+	dryRunClient := &NetboxClient{
+		HTTPClient: &http.Client{Transport: &FailingHTTPClient{}},
+		Logger:     jsonLogger,
+		DryRun:     true,
+		Timeout:    constants.DefaultAPITimeout,
+	}
+
+	if _, err := Patch[objects.Tag](ctx, dryRunClient, 42, map[string]interface{}{"name": "updated"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal(logBuffer.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid JSON log %q: %s", logBuffer.String(), err)
+	}
+	if entry["object_type"] != "Tag" || entry["netbox_id"] != float64(42) || entry["source"] != "test" {
+		t.Errorf("log entry %v doesn't contain object fields", entry)
+	}
+}
+
 func TestBulkDeleteObjects_DryRun(t *testing.T) {
 	ctx := context.WithValue(context.Background(), constants.CtxSourceKey, "test")
 	dryRunClient := &NetboxClient{
@@ -259,6 +321,69 @@ func TestBulkDeleteObjects_DryRun(t *testing.T) {
 	}
 }
 
+func TestRecorder_DryRun(t *testing.T) {
+	ctx := context.WithValue(context.Background(), constants.CtxSourceKey, "test")
+	recorder := report.NewRecorder()
+	dryRunClient := &NetboxClient{
+		HTTPClient: &http.Client{Transport: &FailingHTTPClient{}},
+		Logger:     MockNetboxClient.Logger,
+		DryRun:     true,
+		nextFakeID: dryRunFakeIDStart,
+		Timeout:    constants.DefaultAPITimeout,
+		Recorder:   recorder,
+	}
+
+	if _, err := Create(ctx, dryRunClient, &objects.Tag{Name: "new", Slug: "new"}); err != nil {
+		t.Fatalf("Create() error = %s", err)
+	}
+	if _, err := Patch[objects.Tag](ctx, dryRunClient, 42, map[string]interface{}{"name": "updated"}); err != nil {
+		t.Fatalf("Patch() error = %s", err)
+	}
+	softDeleteCtx := report.WithAction(ctx, report.ActionSoftDelete)
+	_, err := Patch[objects.Device](softDeleteCtx, dryRunClient, 7, map[string]interface{}{"tags": []int{1}})
+	if err != nil {
+		t.Fatalf("Patch() error = %s", err)
+	}
+	if err := dryRunClient.DeleteObject(ctx, &objects.Tag{ID: 1}); err != nil {
+		t.Fatalf("DeleteObject() error = %s", err)
+	}
+	if err := dryRunClient.BulkDeleteObjects(ctx, constants.TagsAPIPath, map[int]bool{2: true}); err != nil {
+		t.Fatalf("BulkDeleteObjects() error = %s", err)
+	}
+
+	type recorded struct {
+		action     report.Action
+		objectType string
+		id         int
+		diffKey    string
+	}
+	want := []recorded{
+		{report.ActionCreate, "Tag", dryRunFakeIDStart, "name"},
+		{report.ActionUpdate, "Tag", 42, "name"},
+		{report.ActionSoftDelete, "Device", 7, "tags"},
+		{report.ActionDelete, "Tag", 1, ""},
+		{report.ActionDelete, "Tag", 2, ""},
+	}
+	changes := recorder.Changes()
+	if len(changes) != len(want) {
+		t.Fatalf("recorded %d changes, want %d", len(changes), len(want))
+	}
+	for i, change := range changes {
+		got := recorded{change.Action, change.ObjectType, change.ID, ""}
+		for key := range change.Diff {
+			if key == want[i].diffKey {
+				got.diffKey = key
+			}
+		}
+		if got != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, got, want[i])
+		}
+		if change.Source != "test" {
+			t.Errorf("change %d source = %s, want test", i, change.Source)
+		}
+	}
+}
+
 func TestGetAll_Error(t *testing.T) {
 	ctx := context.WithValue(context.Background(), constants.CtxSourceKey, "test")
 	_, err := GetAll[objects.Tag](ctx, FailingMockNetboxClient, "")
@@ -405,3 +530,86 @@ func TestBulkDeleteObjects(t *testing.T) {
 		})
 	}
 }
+
+func TestGetAll_Pages(t *testing.T) {
+	tests := []struct {
+		name            string
+		count           int
+		pageConcurrency int
+		// created is number of objects created after the first page was fetched
+		created      int
+		wantRequests int
+	}{
+		{
+			name:            "Single page",
+			count:           10,
+			pageConcurrency: 4,
+			wantRequests:    1,
+		},
+		{
+			name:            "Pages fetched concurrently",
+			count:           getAllPageSize*5 + 1,
+			pageConcurrency: 4,
+			wantRequests:    6,
+		},
+		{
+			name:            "Pages fetched sequentially",
+			count:           getAllPageSize * 3,
+			pageConcurrency: 0,
+			wantRequests:    3,
+		},
+		{
+			name:            "Objects created during fetching",
+			count:           getAllPageSize * 2,
+			pageConcurrency: 4,
+			created:         getAllPageSize + 1,
+			wantRequests:    4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lock sync.Mutex
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				lock.Lock()
+				requests++
+				count := tt.count
+				if requests > 1 {
+					count += tt.created
+				}
+				lock.Unlock()
+				offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+				limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+				response := Response[objects.Tag]{Count: count, Results: []objects.Tag{}}
+				for id := offset; id < min(offset+limit, count); id++ {
+					response.Results = append(response.Results, objects.Tag{ID: id})
+				}
+				if offset+limit < count {
+					next := "next"
+					response.Next = &next
+				}
+				body, _ := json.Marshal(response)
+				_, _ = w.Write(body)
+			}))
+			defer server.Close()
+			client := newBulkTestClient(server.URL)
+			client.PageConcurrency = tt.pageConcurrency
+
+			tags, err := GetAll[objects.Tag](context.Background(), client, "")
+			if err != nil {
+				t.Fatalf("GetAll() error = %s", err)
+			}
+			if len(tags) != tt.count+tt.created {
+				t.Fatalf("GetAll() returned %d objects, want %d", len(tags), tt.count+tt.created)
+			}
+			for i, tag := range tags {
+				if tag.ID != i {
+					t.Fatalf("GetAll() object %d has ID %d", i, tag.ID)
+				}
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("GetAll() made %d requests, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	initialBackoff = 500 * time.Millisecond
+	backoffFactor  = 2.0
+	maxBackoff     = 16 * time.Second
+	// maxRetryAfter caps the wait requested by Retry-After header.
+	maxRetryAfter = 5 * time.Minute
+)
+
+// shouldRetry returns true if the request failed with a transport error,
+// or with 429 or 5xx status code. POST requests aren't idempotent, because netbox
+// could have created the objects before the response was lost, so they are
+// retried only if the connection was refused or on 429.
+func shouldRetry(method string, response *APIResponse, err error) bool {
+	if err != nil {
+		var urlErr *url.Error
+		if !errors.As(err, &urlErr) {
+			return false
+		}
+		return method != http.MethodPost || errors.Is(err, syscall.ECONNREFUSED)
+	}
+	if response.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return method != http.MethodPost && response.StatusCode >= http.StatusInternalServerError
+}
+
+// exponentialBackoff calculates the backoff duration based on the number of attempts.
+// Jitter of up to half of the backoff is subtracted, so concurrent requests
+// that failed at the same time are not retried at the same time.
+func exponentialBackoff(attempt int) time.Duration {
+	backoff := float64(initialBackoff) * math.Pow(backoffFactor, float64(attempt))
+	if backoff > float64(maxBackoff) {
+		backoff = float64(maxBackoff)
+	}
+	jitter := rand.Float64() * backoff / 2 //nolint:gosec,mnd
+	return time.Duration(backoff - jitter)
+}
+
+// parseRetryAfter parses value of the Retry-After header, which is either
+// number of seconds or a HTTP date. It returns -1 if the value is empty or invalid.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return -1
+	}
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		wait = time.Until(date)
+	} else {
+		return -1
+	}
+	return min(max(wait, 0), maxRetryAfter)
+}
+
+// RateLimiter is a token bucket, which limits the number of requests per second.
+// Nil RateLimiter doesn't limit requests.
+type RateLimiter struct {
+	lock sync.Mutex
+	// rate is number of tokens added to the bucket per second.
+	rate float64
+	// burst is the maximum number of tokens in the bucket.
+	burst float64
+	// tokens currently in the bucket. It is negative when tokens
+	// are reserved by waiting requests.
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a RateLimiter which allows requestsPerSecond requests
+// per second, with bursts of up to requestsPerSecond requests (at least one).
+// It returns nil if requestsPerSecond is not positive.
+func NewRateLimiter(requestsPerSecond float64) *RateLimiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	burst := math.Max(1, math.Ceil(requestsPerSecond))
+	return &RateLimiter{
+		rate:   requestsPerSecond,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a request is allowed, or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	wait := l.reserve()
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancelReservation()
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token from the bucket and returns how long
+// the caller has to wait before the token is available.
+func (l *RateLimiter) reserve() time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancelReservation returns a reserved token to the bucket.
+func (l *RateLimiter) cancelReservation() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.tokens = math.Min(l.burst, l.tokens+1)
+}
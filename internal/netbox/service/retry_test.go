@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/logger"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt int
+		max     time.Duration
+	}{
+		{
+			name:    "First attempt",
+			attempt: 0,
+			max:     initialBackoff,
+		},
+		{
+			name:    "Second attempt",
+			attempt: 1,
+			max:     time.Duration(float64(initialBackoff) * backoffFactor),
+		},
+		{
+			name:    "Capped at max backoff",
+			attempt: 10,
+			max:     maxBackoff,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 20 {
+				got := exponentialBackoff(tt.attempt)
+				if got > tt.max || got < tt.max/2 {
+					t.Fatalf("exponentialBackoff(%d) = %v, want between %v and %v", tt.attempt, got, tt.max/2, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{
+			name:  "Empty",
+			value: "",
+			want:  -1,
+		},
+		{
+			name:  "Seconds",
+			value: "3",
+			want:  3 * time.Second,
+		},
+		{
+			name:  "Capped seconds",
+			value: "3600",
+			want:  maxRetryAfter,
+		},
+		{
+			name:  "Date in the past",
+			value: "Wed, 21 Oct 2015 07:28:00 GMT",
+			want:  0,
+		},
+		{
+			name:  "Invalid value",
+			value: "soon",
+			want:  -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDoRequestRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		maxRetries   int
+		failures     int
+		failStatus   int
+		wantStatus   int
+		wantRequests int
+	}{
+		{
+			name:         "Retry bad gateway until success",
+			method:       http.MethodPatch,
+			maxRetries:   3,
+			failures:     2,
+			failStatus:   http.StatusBadGateway,
+			wantStatus:   http.StatusOK,
+			wantRequests: 3,
+		},
+		{
+			name:         "Retry too many requests",
+			method:       http.MethodPatch,
+			maxRetries:   1,
+			failures:     1,
+			failStatus:   http.StatusTooManyRequests,
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+		},
+		{
+			name:         "Give up after max retries",
+			method:       http.MethodPatch,
+			maxRetries:   1,
+			failures:     5,
+			failStatus:   http.StatusServiceUnavailable,
+			wantStatus:   http.StatusServiceUnavailable,
+			wantRequests: 2,
+		},
+		{
+			name:         "Don't retry client errors",
+			method:       http.MethodPatch,
+			maxRetries:   3,
+			failures:     5,
+			failStatus:   http.StatusBadRequest,
+			wantStatus:   http.StatusBadRequest,
+			wantRequests: 1,
+		},
+		{
+			name:         "Don't retry POST on server errors",
+			method:       http.MethodPost,
+			maxRetries:   3,
+			failures:     5,
+			failStatus:   http.StatusBadGateway,
+			wantStatus:   http.StatusBadGateway,
+			wantRequests: 1,
+		},
+		{
+			name:         "Retry POST on too many requests",
+			method:       http.MethodPost,
+			maxRetries:   1,
+			failures:     1,
+			failStatus:   http.StatusTooManyRequests,
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != `{"name":"test"}` {
+					t.Errorf("request %d body = %s", requests, body)
+				}
+				if requests <= tt.failures {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(tt.failStatus)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+			client := &NetboxClient{
+				HTTPClient: &http.Client{},
+				Logger:     &logger.Logger{Logger: log.New(io.Discard, "", 0)},
+				BaseURL:    server.URL,
+				Timeout:    constants.DefaultAPITimeout,
+				MaxRetries: tt.maxRetries,
+			}
+
+			response, err := client.doRequest(
+				context.Background(),
+				tt.method,
+				"/api/extras/tags/",
+				strings.NewReader(`{"name":"test"}`),
+			)
+			if err != nil {
+				t.Fatalf("doRequest() error = %s", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Errorf("doRequest() status = %d, want %d", response.StatusCode, tt.wantStatus)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("doRequest() made %d requests, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestDoRequestCanceled(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	client := &NetboxClient{
+		HTTPClient: &http.Client{},
+		Logger:     &logger.Logger{Logger: log.New(io.Discard, "", 0)},
+		BaseURL:    server.URL,
+		Timeout:    constants.DefaultAPITimeout,
+		MaxRetries: 3,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	startTime := time.Now()
+	_, err := client.doRequest(ctx, http.MethodGet, "/api/status", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("doRequest() error = %v, want %s", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(startTime); elapsed > 5*time.Second {
+		t.Errorf("doRequest() returned after %s, it didn't stop waiting for retry", elapsed)
+	}
+	if requests != 1 {
+		t.Errorf("doRequest() made %d requests, want 1", requests)
+	}
+
+	// Requests with already canceled context are not sent
+	_, err = client.doRequest(ctx, http.MethodGet, "/api/status", nil)
+	if !errors.Is(err, context.DeadlineExceeded) || requests != 1 {
+		t.Errorf("doRequest() with canceled context error = %v, made %d requests", err, requests)
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	refused := &url.Error{Op: "Post", URL: "http://netbox", Err: syscall.ECONNREFUSED}
+	timeout := &url.Error{Op: "Post", URL: "http://netbox", Err: context.DeadlineExceeded}
+	tests := []struct {
+		name     string
+		method   string
+		response *APIResponse
+		err      error
+		want     bool
+	}{
+		{"GET transport error", http.MethodGet, nil, timeout, true},
+		{"POST timeout", http.MethodPost, nil, timeout, false},
+		{"POST connection refused", http.MethodPost, nil, refused, true},
+		{"Other error", http.MethodGet, nil, errors.New("invalid request"), false},
+		{"PATCH server error", http.MethodPatch, &APIResponse{StatusCode: http.StatusBadGateway}, nil, true},
+		{"POST server error", http.MethodPost, &APIResponse{StatusCode: http.StatusBadGateway}, nil, false},
+		{"POST too many requests", http.MethodPost, &APIResponse{StatusCode: http.StatusTooManyRequests}, nil, true},
+		{"DELETE client error", http.MethodDelete, &APIResponse{StatusCode: http.StatusNotFound}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldRetry(tt.method, tt.response, tt.err); got != tt.want {
+				t.Errorf("shouldRetry() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDoRequestRateLimiterWaitIsNotTimed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client := &NetboxClient{
+		HTTPClient: &http.Client{},
+		Logger:     &logger.Logger{Logger: log.New(io.Discard, "", 0)},
+		BaseURL:    server.URL,
+		Timeout:    1,
+		// Second request waits 1.25s for the limiter, which is longer than the timeout
+		RateLimiter: NewRateLimiter(0.8), //nolint:mnd
+	}
+	for i := range 2 {
+		if _, err := client.doRequest(context.Background(), http.MethodGet, "/api/status", nil); err != nil {
+			t.Fatalf("request %d error = %s", i, err)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	if NewRateLimiter(0) != nil {
+		t.Errorf("NewRateLimiter(0) is not nil")
+	}
+	var unlimited *RateLimiter
+	if err := unlimited.Wait(context.Background()); err != nil {
+		t.Errorf("nil RateLimiter.Wait() error = %s", err)
+	}
+
+	const requestsPerSecond = 20
+	limiter := NewRateLimiter(requestsPerSecond)
+	start := time.Now()
+	// First requestsPerSecond requests are allowed immediately as a burst,
+	// the following ones are limited to requestsPerSecond.
+	for range requestsPerSecond + 4 {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() error = %s", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("%d requests took %s, want at least 150ms", requestsPerSecond+4, elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(ctx); err == nil {
+		t.Errorf("Wait() with canceled context returned no error")
+	}
+}
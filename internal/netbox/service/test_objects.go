@@ -7,6 +7,8 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
 	"github.com/bl4ko/netbox-ssot/internal/logger"
@@ -1037,3 +1039,87 @@ type FaultyReader struct{}
 func (m *FaultyReader) Read(_ []byte) (n int, err error) {
 	return 0, fmt.Errorf("mock read error")
 }
+
+// MockBranching is a stand-in for the API of the NetBox branching plugin.
+// Branches are provisioned and merged in the background, so they change their
+// status on the first status check after the request. All other requests are
+// answered with an object with ID 1, and their branch headers are recorded.
+type MockBranching struct {
+	lock     sync.Mutex
+	branches []*Branch
+	// FailMerge fails merges of all branches.
+	FailMerge bool
+	// BranchHeaders are values of the branch header of requests
+	// outside of the branching API, indexed by "METHOD path".
+	BranchHeaders map[string]string
+}
+
+// Branches returns copies of all created branches.
+func (m *MockBranching) Branches() []Branch {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	branches := make([]Branch, 0, len(m.branches))
+	for _, branch := range m.branches {
+		branches = append(branches, *branch)
+	}
+	return branches
+}
+
+// ServeHTTP implements http.Handler.
+func (m *MockBranching) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if !isBranchingPath(r.URL.Path) {
+		if m.BranchHeaders == nil {
+			m.BranchHeaders = map[string]string{}
+		}
+		m.BranchHeaders[r.Method+" "+r.URL.Path] = r.Header.Get(BranchHeader)
+		switch r.Method {
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		_, _ = io.WriteString(w, `{"id": 1}`)
+		return
+	}
+
+	var id int
+	var action string
+	_, _ = fmt.Sscanf(strings.TrimPrefix(r.URL.Path, branchesPath), "%d/%s", &id, &action)
+	switch {
+	case r.Method == http.MethodPost && id == 0:
+		var branch Branch
+		if err := json.NewDecoder(r.Body).Decode(&branch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		branch.ID = len(m.branches) + 1
+		branch.SchemaID = fmt.Sprintf("schema%d", branch.ID)
+		branch.Status.Value = "provisioning"
+		m.branches = append(m.branches, &branch)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(branch)
+	case id < 1 || id > len(m.branches):
+		w.WriteHeader(http.StatusNotFound)
+	case r.Method == http.MethodPost && action == "merge/":
+		m.branches[id-1].Status.Value = "merging"
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = io.WriteString(w, `{"id": 1, "status": {"value": "pending"}}`)
+	case r.Method == http.MethodGet:
+		branch := m.branches[id-1]
+		switch branch.Status.Value {
+		case "provisioning":
+			branch.Status.Value = BranchStatusReady
+		case "merging":
+			branch.Status.Value = BranchStatusMerged
+			if m.FailMerge {
+				branch.Status.Value = BranchStatusFailed
+			}
+		}
+		_ = json.NewEncoder(w).Encode(branch)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
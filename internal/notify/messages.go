@@ -0,0 +1,100 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+)
+
+// fact is a single line of the summary, shown as "Title: value".
+type fact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// title returns the headline of the summary, e.g. "✓ netbox-ssot run 3 succeeded".
+func title(summary *Summary) string {
+	symbol := constants.CheckMark
+	if !summary.Successful {
+		symbol = constants.WarningSign
+	}
+	headline := fmt.Sprintf("%s netbox-ssot run %d %s", symbol, summary.RunID, summary.Status)
+	if summary.DryRun {
+		headline += " (dry run)"
+	}
+	return headline
+}
+
+// facts returns details of the summary, which are shown by Slack and Teams messages.
+func facts(summary *Summary) []fact {
+	changesTitle := "Changes"
+	if summary.DryRun {
+		changesTitle = "Changes (not applied)"
+	}
+	sources := make([]string, 0, len(summary.Sources))
+	for _, source := range summary.Sources {
+		sources = append(sources, fmt.Sprintf("%s: %s", source.Name, source.Status))
+	}
+	return []fact{
+		{Title: "Trigger", Value: summary.Trigger},
+		{Title: "Duration", Value: summary.Duration},
+		{Title: "Sources", Value: strings.Join(sources, ", ")},
+		{Title: changesTitle, Value: fmt.Sprintf(
+			"%d created, %d updated, %d orphaned, %d deleted",
+			summary.Changes.Created, summary.Changes.Updated, summary.Changes.Orphaned, summary.Changes.Deleted,
+		)},
+	}
+}
+
+// slackMessage returns payload of a Slack incoming webhook.
+func slackMessage(summary *Summary) map[string]any {
+	var text strings.Builder
+	fmt.Fprintf(&text, "*%s*", title(summary))
+	for _, f := range facts(summary) {
+		fmt.Fprintf(&text, "\n*%s:* %s", f.Title, f.Value)
+	}
+	if len(summary.Errors) > 0 {
+		fmt.Fprintf(&text, "\n*Errors:*\n```%s```", strings.Join(summary.Errors, "\n"))
+	}
+	return map[string]any{"text": text.String()}
+}
+
+// teamsMessage returns payload of a Microsoft Teams workflow webhook, which posts an adaptive card.
+func teamsMessage(summary *Summary) map[string]any {
+	color := "Good"
+	if !summary.Successful {
+		color = "Attention"
+	}
+	body := []any{
+		map[string]any{
+			"type":   "TextBlock",
+			"text":   title(summary),
+			"size":   "Medium",
+			"weight": "Bolder",
+			"color":  color,
+			"wrap":   true,
+		},
+		map[string]any{"type": "FactSet", "facts": facts(summary)},
+	}
+	for _, err := range summary.Errors {
+		body = append(body, map[string]any{
+			"type":  "TextBlock",
+			"text":  err,
+			"color": "Attention",
+			"wrap":  true,
+		})
+	}
+	return map[string]any{
+		"type": "message",
+		"attachments": []any{map[string]any{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	}
+}
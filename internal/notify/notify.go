@@ -0,0 +1,199 @@
+// Package notify sends summaries of finished runs to generic webhooks,
+// Slack and Microsoft Teams, so failed runs are noticed without reading logs.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+	"github.com/bl4ko/netbox-ssot/internal/report"
+)
+
+// Statuses of sources in the summary.
+const (
+	SourceStatusSucceeded = "succeeded"
+	SourceStatusFailed    = "failed"
+)
+
+// Summary is the summary of a finished run, which is sent in notifications.
+// It is also the data of templates of webhook notifications.
+type Summary struct {
+	RunID   int    `json:"run_id"`
+	Trigger string `json:"trigger"`
+	// Status of the run: succeeded, failed or canceled.
+	Status     string `json:"status"`
+	Successful bool   `json:"successful"`
+	// DryRun is true, if changes were only logged and not applied to Netbox.
+	DryRun          bool            `json:"dry_run"`
+	StartTime       time.Time       `json:"start_time"`
+	EndTime         time.Time       `json:"end_time"`
+	Duration        string          `json:"duration"`
+	DurationSeconds float64         `json:"duration_seconds"`
+	Sources         []SourceSummary `json:"sources"`
+	Changes         Changes         `json:"changes"`
+	// Errors are all errors of the run, errors of sources are prefixed with the source name.
+	Errors []string `json:"errors"`
+}
+
+// SourceSummary is the outcome of a single source of the run.
+type SourceSummary struct {
+	Name string `json:"name"`
+	// Status of the source: succeeded or failed.
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Changes are counts of objects changed by the run. In dry-run mode
+// they are counts of objects, which would be changed.
+type Changes struct {
+	Created  int `json:"created"`
+	Updated  int `json:"updated"`
+	Orphaned int `json:"orphaned"`
+	Deleted  int `json:"deleted"`
+}
+
+// Total returns number of all changed objects.
+func (c Changes) Total() int {
+	return c.Created + c.Updated + c.Orphaned + c.Deleted
+}
+
+// CountChanges counts changes recorded during the run by their actions.
+func CountChanges(changes []report.Change) Changes {
+	summary := report.NewReport(changes).Summary
+	return Changes{
+		Created:  summary[report.ActionCreate],
+		Updated:  summary[report.ActionUpdate],
+		Orphaned: summary[report.ActionSoftDelete],
+		Deleted:  summary[report.ActionDelete],
+	}
+}
+
+// Notifier sends summaries of finished runs to all configured notifications.
+// Nil notifier doesn't send anything.
+type Notifier struct {
+	notifications []parser.NotificationConfig
+	// templates of webhook notifications by their names.
+	templates map[string]*template.Template
+	// HTTPClient used to send notifications. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+// New creates a Notifier for the notifications. It returns nil, if no notification is configured.
+func New(notifications []parser.NotificationConfig) (*Notifier, error) {
+	if len(notifications) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+	templates := make(map[string]*template.Template)
+	for _, notification := range notifications {
+		if notification.Template == "" {
+			continue
+		}
+		tmpl, err := parser.ParseNotificationTemplate(notification.Name, notification.Template)
+		if err != nil {
+			return nil, fmt.Errorf("notification %s: %s", notification.Name, err)
+		}
+		templates[notification.Name] = tmpl
+	}
+	return &Notifier{notifications: notifications, templates: templates}, nil
+}
+
+// Notify sends the summary to all notifications, which are configured to be sent
+// after the run. Errors of all notifications, which failed, are returned.
+func (n *Notifier) Notify(ctx context.Context, summary *Summary) error {
+	if n == nil {
+		return nil
+	}
+	var errs []error
+	for _, notification := range n.notifications {
+		if !shouldNotify(notification.When, summary) {
+			continue
+		}
+		if err := n.send(ctx, notification, summary); err != nil {
+			errs = append(errs, fmt.Errorf("notification %s: %s", notification.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// shouldNotify returns true, if a notification with the when option is sent after the run.
+func shouldNotify(when parser.NotifyWhen, summary *Summary) bool {
+	switch when {
+	case parser.NotifyFailure:
+		return !summary.Successful
+	case parser.NotifyChanges:
+		return !summary.Successful || summary.Changes.Total() > 0
+	default:
+		return true
+	}
+}
+
+// send posts the summary in the format of the notification type.
+func (n *Notifier) send(ctx context.Context, notification parser.NotificationConfig, summary *Summary) error {
+	var body []byte
+	var err error
+	switch notification.Type {
+	case parser.NotificationSlack:
+		body, err = json.Marshal(slackMessage(summary))
+	case parser.NotificationTeams:
+		body, err = json.Marshal(teamsMessage(summary))
+	default:
+		body, err = n.webhookBody(notification.Name, summary)
+	}
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, notification.URL, bytes.NewReader(body))
+	if err != nil {
+		// Error contains the url, which can contain a secret
+		return errors.New("invalid url")
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range notification.Headers {
+		request.Header.Set(name, value)
+	}
+	httpClient := n.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("send: %s", err)
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("send: unexpected status code %d", response.StatusCode)
+	}
+	return nil
+}
+
+// webhookBody returns the summary as JSON, or the body rendered from the
+// template of the notification, which must be valid JSON.
+func (n *Notifier) webhookBody(name string, summary *Summary) ([]byte, error) {
+	tmpl, ok := n.templates[name]
+	if !ok {
+		return json.Marshal(summary)
+	}
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, summary); err != nil {
+		return nil, fmt.Errorf("render template: %s", err)
+	}
+	if !json.Valid(body.Bytes()) {
+		return nil, fmt.Errorf("render template: %q is not valid JSON", body.String())
+	}
+	return body.Bytes(), nil
+}
@@ -0,0 +1,180 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+	"github.com/bl4ko/netbox-ssot/internal/report"
+)
+
+func testSummary(successful bool, changes Changes) *Summary {
+	summary := &Summary{
+		RunID:      7,
+		Trigger:    "schedule",
+		Status:     "succeeded",
+		Successful: successful,
+		Duration:   "1m5s",
+		Sources:    []SourceSummary{{Name: "vcenter", Status: SourceStatusSucceeded}},
+		Changes:    changes,
+		Errors:     []string{},
+	}
+	if !successful {
+		summary.Status = "failed"
+		summary.Sources[0] = SourceSummary{Name: "vcenter", Status: SourceStatusFailed, Error: `login "admin" failed`}
+		summary.Errors = []string{`vcenter: login "admin" failed`}
+	}
+	return summary
+}
+
+func TestCountChanges(t *testing.T) {
+	changes := []report.Change{
+		{Action: report.ActionCreate},
+		{Action: report.ActionCreate},
+		{Action: report.ActionUpdate},
+		{Action: report.ActionSoftDelete},
+		{Action: report.ActionDelete},
+	}
+	want := Changes{Created: 2, Updated: 1, Orphaned: 1, Deleted: 1}
+	if got := CountChanges(changes); got != want {
+		t.Errorf("CountChanges() = %+v, want %+v", got, want)
+	}
+}
+
+func TestShouldNotify(t *testing.T) {
+	tests := []struct {
+		name    string
+		when    parser.NotifyWhen
+		summary *Summary
+		want    bool
+	}{
+		{"always after success", parser.NotifyAlways, testSummary(true, Changes{}), true},
+		{"failure after success", parser.NotifyFailure, testSummary(true, Changes{Created: 1}), false},
+		{"failure after failure", parser.NotifyFailure, testSummary(false, Changes{}), true},
+		{"changes without changes", parser.NotifyChanges, testSummary(true, Changes{}), false},
+		{"changes with changes", parser.NotifyChanges, testSummary(true, Changes{Orphaned: 1}), true},
+		{"changes after failure", parser.NotifyChanges, testSummary(false, Changes{}), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldNotify(tt.when, tt.summary); got != tt.want {
+				t.Errorf("shouldNotify() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotify(t *testing.T) {
+	tests := []struct {
+		name         string
+		notification parser.NotificationConfig
+		summary      *Summary
+		want         []string
+	}{
+		{
+			name:         "Webhook posts the summary",
+			notification: parser.NotificationConfig{Type: parser.NotificationWebhook},
+			summary:      testSummary(false, Changes{Created: 3}),
+			want:         []string{`"run_id":7`, `"status":"failed"`, `"created":3`, `"errors":["vcenter: login`},
+		},
+		{
+			name: "Webhook posts the rendered template",
+			notification: parser.NotificationConfig{
+				Type:     parser.NotificationWebhook,
+				Template: `{"text": "run {{ .RunID }} {{ .Status }}", "errors": {{ json .Errors }}}`,
+			},
+			summary: testSummary(false, Changes{}),
+			want:    []string{`{"text": "run 7 failed", "errors": ["vcenter: login \"admin\" failed"]}`},
+		},
+		{
+			name:         "Slack message",
+			notification: parser.NotificationConfig{Type: parser.NotificationSlack},
+			summary:      testSummary(true, Changes{Created: 1, Updated: 2}),
+			want:         []string{`"text":"*✓ netbox-ssot run 7 succeeded*`, `1 created, 2 updated, 0 orphaned`},
+		},
+		{
+			name:         "Teams adaptive card",
+			notification: parser.NotificationConfig{Type: parser.NotificationTeams},
+			summary:      testSummary(false, Changes{}),
+			want:         []string{`"type":"AdaptiveCard"`, `"color":"Attention"`, `"value":"vcenter: failed"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bodies := make(chan string, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if r.Header.Get("Authorization") != "Bearer token" {
+					t.Errorf("Authorization header = %s, want Bearer token", r.Header.Get("Authorization"))
+				}
+				bodies <- string(body)
+			}))
+			defer server.Close()
+			notification := tt.notification
+			notification.Name = "test"
+			notification.URL = server.URL
+			notification.When = parser.NotifyAlways
+			notification.Headers = map[string]string{"Authorization": "Bearer token"}
+			notifier, err := New([]parser.NotificationConfig{notification})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			if err := notifier.Notify(context.Background(), tt.summary); err != nil {
+				t.Fatalf("Notify() error = %v", err)
+			}
+			body := <-bodies
+			if !json.Valid([]byte(body)) {
+				t.Errorf("body %s is not valid JSON", body)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("body %s doesn't contain %s", body, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNotifyErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+	notifier, err := New([]parser.NotificationConfig{
+		{Name: "rejected", Type: parser.NotificationSlack, URL: server.URL, When: parser.NotifyAlways},
+		{Name: "skipped", Type: parser.NotificationSlack, URL: server.URL, When: parser.NotifyFailure},
+		{
+			Name:     "invalid",
+			Type:     parser.NotificationWebhook,
+			URL:      server.URL,
+			When:     parser.NotifyAlways,
+			Template: `{"text": {{ .Status }}}`,
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	err = notifier.Notify(context.Background(), testSummary(true, Changes{}))
+	want := "notification rejected: send: unexpected status code 400\n" +
+		`notification invalid: render template: "{\"text\": succeeded}" is not valid JSON`
+	if err == nil || err.Error() != want {
+		t.Errorf("Notify() error = %v, want %s", err, want)
+	}
+}
+
+func TestNotifierNil(t *testing.T) {
+	notifier, err := New(nil)
+	if notifier != nil || err != nil {
+		t.Fatalf("New(nil) = %v, %v, want nil notifier", notifier, err)
+	}
+	if err := notifier.Notify(context.Background(), testSummary(true, Changes{})); err != nil {
+		t.Errorf("Notify() of nil notifier error = %v", err)
+	}
+}
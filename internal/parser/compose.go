@@ -0,0 +1,365 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Keys of the config, which are used to compose the config
+// and aren't decoded directly into Config.
+const (
+	// include is a path or a list of paths of config files, directories
+	// with config files (e.g. conf.d) or glob patterns, which are merged into the config.
+	includeKey = "include"
+	// sourceDefaults are options inherited by all sources, which don't set them.
+	sourceDefaultsKey = "sourceDefaults"
+	sourcesKey        = "source"
+)
+
+// envVarPattern matches ${VAR} and ${VAR:-default} in config values.
+// $${VAR} is an escaped literal ${VAR}.
+var envVarPattern = regexp.MustCompile(`\$(\$?)\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// configDocument is the config composed of the config file and all files it includes.
+type configDocument struct {
+	// root node of the composed config
+	root *yaml.Node
+	// sourceFiles are paths of files, in which sources defined outside
+	// of the main config file are defined. They are used in errors.
+	sourceFiles map[*yaml.Node]string
+}
+
+// loadConfigDocument reads the config file, interpolates environment
+// variables and merges all included files into a single document.
+func loadConfigDocument(filename string) (*configDocument, error) {
+	document := &configDocument{sourceFiles: make(map[*yaml.Node]string)}
+	root, err := document.load(filename, nil)
+	if err != nil {
+		return nil, err
+	}
+	document.root = root
+	return document, nil
+}
+
+// load reads the config file filename and merges all files it includes into it.
+// includedBy are the files, which (transitively) include filename.
+func (d *configDocument) load(filename string, includedBy []string) (*yaml.Node, error) {
+	// Errors of the main config file aren't prefixed with its name
+	fileErr := func(err error) error {
+		if len(includedBy) == 0 {
+			return err
+		}
+		return fmt.Errorf("%s: %s", filename, err)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var document yaml.Node
+	if err := yaml.NewDecoder(file).Decode(&document); err != nil {
+		if errors.Is(err, io.EOF) && len(includedBy) > 0 {
+			// Empty included files are allowed
+			return &yaml.Node{Kind: yaml.MappingNode}, nil
+		}
+		return nil, fileErr(err)
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		if len(includedBy) > 0 {
+			return nil, fileErr(errors.New("included config must be a mapping"))
+		}
+		// Decoding of the config reports the error
+		return root, nil
+	}
+	if err := interpolate(root); err != nil {
+		return nil, fileErr(err)
+	}
+
+	include := removeKey(root, includeKey)
+	if include == nil || isNull(include) {
+		return root, nil
+	}
+	var patterns []string
+	if err := include.Decode(&patterns); err != nil {
+		var pattern string
+		if err := include.Decode(&pattern); err != nil {
+			return nil, fileErr(errors.New("include: must be a path or a list of paths"))
+		}
+		patterns = []string{pattern}
+	}
+	absFilename, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, fileErr(err)
+	}
+	includedBy = append(slices.Clip(includedBy), absFilename)
+	for _, pattern := range patterns {
+		paths, err := includePaths(filepath.Dir(filename), pattern)
+		if err != nil {
+			return nil, fileErr(fmt.Errorf("include: %s", err))
+		}
+		for _, path := range paths {
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				return nil, fileErr(err)
+			}
+			if slices.Contains(includedBy, absPath) {
+				return nil, fileErr(fmt.Errorf("include: %s is included recursively", path))
+			}
+			included, err := d.load(path, includedBy)
+			if err != nil {
+				return nil, err
+			}
+			if err := d.merge(root, included, "", path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return root, nil
+}
+
+// includePaths returns paths of config files pattern refers to. Pattern is a
+// path of a file, a directory, whose .yaml and .yml files are included,
+// or a glob pattern. Relative patterns are relative to dir.
+func includePaths(dir string, pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+	info, err := os.Stat(pattern)
+	if err == nil && !info.IsDir() {
+		return []string{pattern}, nil
+	}
+	if err == nil {
+		entries, err := os.ReadDir(pattern)
+		if err != nil {
+			return nil, err
+		}
+		var paths []string
+		for _, entry := range entries {
+			ext := filepath.Ext(entry.Name())
+			if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				paths = append(paths, filepath.Join(pattern, entry.Name()))
+			}
+		}
+		return paths, nil
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		return nil, err
+	}
+	return filepath.Glob(pattern)
+}
+
+// merge merges mapping src from file into mapping dst. Sources of both
+// mappings are concatenated, nested mappings are merged and all other
+// options can be set only once. Key of the mappings in the config is path.
+func (d *configDocument) merge(dst *yaml.Node, src *yaml.Node, path string, file string) error {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		keyPath := key.Value
+		if path != "" {
+			keyPath = path + "." + key.Value
+		}
+		if keyPath == sourcesKey && value.Kind == yaml.SequenceNode {
+			for _, source := range value.Content {
+				if _, ok := d.sourceFiles[source]; !ok {
+					d.sourceFiles[source] = file
+				}
+			}
+		}
+
+		valueIndex := mappingValueIndex(dst, key.Value)
+		switch {
+		case isNull(value):
+		case valueIndex < 0:
+			dst.Content = append(dst.Content, key, value)
+		case isNull(dst.Content[valueIndex]):
+			dst.Content[valueIndex] = value
+		case keyPath == sourcesKey && dst.Content[valueIndex].Kind == yaml.SequenceNode &&
+			value.Kind == yaml.SequenceNode:
+			existing := dst.Content[valueIndex]
+			existing.Content = append(existing.Content, value.Content...)
+		case dst.Content[valueIndex].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			if err := d.merge(dst.Content[valueIndex], value, keyPath, file); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: line %d: %s is already set", file, key.Line, keyPath)
+		}
+	}
+	return nil
+}
+
+// decode decodes the document into config. Each section of the config
+// and each source are decoded separately, so all errors are reported.
+func (d *configDocument) decode(config *Config) []error {
+	root := d.root
+	if root.Kind != yaml.MappingNode {
+		if err := root.Decode(config); err != nil {
+			return []error{err}
+		}
+		return nil
+	}
+
+	var errs []error
+	sourceDefaults := removeKey(root, sourceDefaultsKey)
+	if sourceDefaults != nil && !isNull(sourceDefaults) {
+		if err := validateSourceDefaults(sourceDefaults); err != nil {
+			errs = append(errs, err)
+			sourceDefaults = nil
+		}
+	}
+	sources := removeKey(root, sourcesKey)
+
+	keyLines := make(map[string]int)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key := root.Content[i]
+		if line, ok := keyLines[key.Value]; ok {
+			errs = append(errs, fmt.Errorf("line %d: %s is already set at line %d", key.Line, key.Value, line))
+			continue
+		}
+		keyLines[key.Value] = key.Line
+		section := &yaml.Node{Kind: yaml.MappingNode, Content: root.Content[i : i+2]}
+		if err := section.Decode(config); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if sources == nil || isNull(sources) {
+		return errs
+	}
+	if sources.Kind != yaml.SequenceNode {
+		return append(errs, fmt.Errorf("line %d: source: must be a list of sources", sources.Line))
+	}
+	for _, sourceNode := range sources.Content {
+		if sourceDefaults != nil && sourceNode.Kind == yaml.MappingNode {
+			inheritDefaults(sourceNode, sourceDefaults)
+		}
+		var source SourceConfig
+		if err := sourceNode.Decode(&source); err != nil {
+			// All invalid relations of the source are reported separately
+			sourceErrs := []error{err}
+			if joinedErr, ok := err.(interface{ Unwrap() []error }); ok {
+				sourceErrs = joinedErr.Unwrap()
+			}
+			for _, err := range sourceErrs {
+				if file, ok := d.sourceFiles[sourceNode]; ok {
+					err = fmt.Errorf("%s: %s", file, err)
+				}
+				errs = append(errs, err)
+			}
+			continue
+		}
+		config.Sources = append(config.Sources, source)
+	}
+	return errs
+}
+
+// validateSourceDefaults validates the sourceDefaults block.
+func validateSourceDefaults(sourceDefaults *yaml.Node) error {
+	if sourceDefaults.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: sourceDefaults: must be a mapping of source options", sourceDefaults.Line)
+	}
+	for i := 0; i+1 < len(sourceDefaults.Content); i += 2 {
+		if key := sourceDefaults.Content[i].Value; key == "name" {
+			return fmt.Errorf("line %d: sourceDefaults.%s: cannot be set", sourceDefaults.Content[i].Line, key)
+		}
+	}
+	return nil
+}
+
+// inheritDefaults adds options from sourceDefaults, which aren't set in source.
+// Options set in the source replace the defaults, lists (e.g. relations) aren't merged.
+func inheritDefaults(source *yaml.Node, sourceDefaults *yaml.Node) {
+	for i := 0; i+1 < len(sourceDefaults.Content); i += 2 {
+		key := sourceDefaults.Content[i]
+		if mappingValue(source, key.Value) == nil {
+			source.Content = append(source.Content, key, sourceDefaults.Content[i+1])
+		}
+	}
+}
+
+// interpolate replaces ${VAR} and ${VAR:-default} in all values of the
+// config with environment variables.
+func interpolate(node *yaml.Node) error {
+	var errs []error
+	var walk func(node *yaml.Node, isKey bool)
+	walk = func(node *yaml.Node, isKey bool) {
+		switch node.Kind {
+		case yaml.ScalarNode:
+			if isKey || !strings.Contains(node.Value, "${") {
+				return
+			}
+			node.Value = envVarPattern.ReplaceAllStringFunc(node.Value, func(match string) string {
+				groups := envVarPattern.FindStringSubmatch(match)
+				if groups[1] != "" {
+					return match[1:]
+				}
+				value, ok := os.LookupEnv(groups[2])
+				if !ok {
+					if groups[3] == "" {
+						errs = append(errs, fmt.Errorf("line %d: environment variable %s is not set", node.Line, groups[2]))
+					}
+					return groups[4]
+				}
+				return value
+			})
+			if node.Style&(yaml.TaggedStyle|yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) == 0 {
+				// Type of plain values is resolved from the interpolated value (e.g. port: ${PORT})
+				node.Tag = ""
+			}
+		case yaml.MappingNode:
+			for i, child := range node.Content {
+				walk(child, i%2 == 0)
+			}
+		case yaml.DocumentNode, yaml.SequenceNode:
+			for _, child := range node.Content {
+				walk(child, false)
+			}
+		}
+	}
+	walk(node, false)
+	return errors.Join(errs...)
+}
+
+// mappingValueIndex returns index of the value of key in mapping's content,
+// or -1 if the mapping doesn't contain key.
+func mappingValueIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// mappingValue returns value of key in mapping, or nil if the mapping doesn't contain key.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if i := mappingValueIndex(mapping, key); i >= 0 {
+		return mapping.Content[i]
+	}
+	return nil
+}
+
+// removeKey removes key from mapping and returns its value, or nil if the mapping doesn't contain key.
+func removeKey(mapping *yaml.Node, key string) *yaml.Node {
+	i := mappingValueIndex(mapping, key)
+	if i < 0 {
+		return nil
+	}
+	value := mapping.Content[i]
+	mapping.Content = slices.Delete(mapping.Content, i-1, i+1)
+	return value
+}
+
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null"
+}
@@ -0,0 +1,153 @@
+package parser
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("NETBOX_SSOT_TEST_HOST", "netbox.example.com")
+	t.Setenv("NETBOX_SSOT_TEST_EMPTY", "")
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr string
+	}{
+		{name: "Variable", value: "https://${NETBOX_SSOT_TEST_HOST}/api", want: "https://netbox.example.com/api"},
+		{name: "Empty variable", value: "${NETBOX_SSOT_TEST_EMPTY}", want: ""},
+		{name: "Default value", value: "${NETBOX_SSOT_TEST_UNSET:-fallback}", want: "fallback"},
+		{name: "Escaped variable", value: "pa$${NETBOX_SSOT_TEST_HOST}", want: "pa${NETBOX_SSOT_TEST_HOST}"},
+		{name: "Dollar sign without braces", value: "pa$$w0rd", want: "pa$$w0rd"},
+		{
+			name:    "Unset variable",
+			value:   "${NETBOX_SSOT_TEST_UNSET}",
+			wantErr: "line 1: environment variable NETBOX_SSOT_TEST_UNSET is not set",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+				{Kind: yaml.ScalarNode, Value: "key", Line: 1},
+				{Kind: yaml.ScalarNode, Value: tt.value, Line: 1},
+			}}
+			err := interpolate(node)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("interpolate() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("interpolate() error = %s", err)
+			}
+			if got := node.Content[1].Value; got != tt.want {
+				t.Errorf("interpolate() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConfigInclude(t *testing.T) {
+	t.Setenv("NETBOX_SSOT_TEST_TOKEN", "netbox-token")
+	t.Setenv("NETBOX_SSOT_TEST_PORT", "8443")
+	config, err := ParseConfig(filepath.Join("../../testdata/parser/include", "config.yaml"))
+	if err != nil {
+		t.Fatalf("ParseConfig() error = %s", err)
+	}
+
+	if config.Netbox.APIToken != "netbox-token" || config.Netbox.Port != 8443 || config.Netbox.Timeout != 30 {
+		t.Errorf("Netbox = %+v, want interpolated token and port and timeout from team-b.yml", config.Netbox)
+	}
+	if config.Logger.Level != 1 {
+		t.Errorf("Logger.Level = %d, want default value of the variable", config.Logger.Level)
+	}
+
+	var names []string
+	for _, source := range config.Sources {
+		names = append(names, source.Name)
+	}
+	if want := []string{"vcenter", "team-a-ovirt", "team-b-proxmox"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("Sources = %v, want %v", names, want)
+	}
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"vcenter.password", config.Sources[0].Password, "pa${NOT_A_VAR}"},
+		{"vcenter.hostSiteRelations", config.Sources[0].HostSiteRelations, map[string]string{".*": "Berlin"}},
+		{"vcenter.validateCert", config.Sources[0].ValidateCert, true},
+		{"team-a-ovirt.hostSiteRelations", config.Sources[1].HostSiteRelations, map[string]string{".*": "Default"}},
+		{"team-a-ovirt.ignoredSubnets", config.Sources[1].IgnoredSubnets, []string{"172.16.0.0/12"}},
+		{"team-a-ovirt.tag", config.Sources[1].Tag, "shared"},
+		{"team-b-proxmox.validateCert", config.Sources[2].ValidateCert, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConfigIncludeErrors(t *testing.T) {
+	tests := []struct {
+		filename string
+		wantErr  string
+	}{
+		{
+			filename: "include_cycle/config.yaml",
+			wantErr:  "include: ../../testdata/parser/include_cycle/config.yaml is included recursively",
+		},
+		{
+			filename: "include_conflict/config.yaml",
+			wantErr:  "../../testdata/parser/include_conflict/other.yaml: line 2: netbox.hostname is already set",
+		},
+		{
+			filename: "include_conflict/broken.yaml",
+			wantErr: "../../testdata/parser/include_conflict/broken-source.yaml: " +
+				"broken.hostSiteRelations: invalid regex: (wrong, in relation: (wrong = Berlin",
+		},
+		{
+			filename: "include/config.yaml",
+			wantErr:  "line 9: environment variable NETBOX_SSOT_TEST_TOKEN is not set",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			_, err := ParseConfig(filepath.Join("../../testdata/parser", tt.filename))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseConfig() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	errs := ValidateConfig(filepath.Join("../../testdata/parser", "invalid_config64.yaml"), true)
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		"vcenter.hostSiteRelations: invalid regex: (wrong, in relation: (wrong = Berlin",
+		"vcenter.clusterSiteRelations: invalid regex: cluster[, in relation: cluster[ = Berlin",
+		"logger.level: must be between 0 and 3",
+		"netbox.apiToken: cannot be empty",
+		"netbox.timeout: cannot be negative",
+		"ovirt.username: cannot be empty",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateConfig() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if errs := ValidateConfig(filepath.Join("../../testdata/parser", "valid_config1.yaml"), true); len(errs) > 0 {
+		t.Errorf("ValidateConfig() of valid config = %v", errs)
+	}
+}
@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"text/template"
+
+	"github.com/bl4ko/netbox-ssot/internal/secrets"
+)
+
+// NotificationType is the format of notifications sent to a target.
+type NotificationType string
+
+const (
+	// NotificationWebhook posts the summary of the run as JSON, or as the JSON body
+	// rendered from the template of the notification.
+	NotificationWebhook NotificationType = "webhook"
+	// NotificationSlack posts a message to a Slack incoming webhook.
+	NotificationSlack NotificationType = "slack"
+	// NotificationTeams posts an adaptive card to a Microsoft Teams workflow webhook.
+	NotificationTeams NotificationType = "teams"
+)
+
+// NotifyWhen defines runs, after which a notification is sent.
+type NotifyWhen string
+
+const (
+	// NotifyAlways sends notifications after all runs.
+	NotifyAlways NotifyWhen = "always"
+	// NotifyFailure sends notifications only after failed or canceled runs.
+	NotifyFailure NotifyWhen = "failure"
+	// NotifyChanges sends notifications after failed runs and runs, which changed any objects.
+	NotifyChanges NotifyWhen = "changes"
+)
+
+// Configuration of a notification, which is sent with the summary of each finished run.
+type NotificationConfig struct {
+	// Name of the notification, used in logs and errors.
+	Name string `yaml:"name"`
+	// Type of the notification: webhook, slack or teams.
+	Type NotificationType `yaml:"type"`
+	// URL, where the notification is posted (e.g. URL of the Slack incoming webhook).
+	URL string `yaml:"url"`
+	// When the notification is sent: always (default), failure or changes.
+	When NotifyWhen `yaml:"when"`
+	// Headers added to the requests (e.g. Authorization).
+	Headers map[string]string `yaml:"headers"`
+	// Template of the JSON body of webhook notifications in go text/template format.
+	// If empty, the summary of the run is posted as JSON.
+	Template string `yaml:"template"`
+}
+
+func (n NotificationConfig) String() string {
+	headers := make(map[string]string, len(n.Headers))
+	for name, value := range n.Headers {
+		headers[name] = redact(value)
+	}
+	return fmt.Sprintf(
+		"NotificationConfig{Name: %s, Type: %s, URL: %s, When: %s, Headers: %v, Template: %q}",
+		n.Name, n.Type, redact(n.URL), n.When, headers, n.Template,
+	)
+}
+
+// ParseNotificationTemplate parses template of a webhook notification. Besides the
+// builtin functions, templates can use json, which encodes a value as JSON
+// (e.g. {"text": {{ json .Errors }}}), so strings are properly escaped.
+func ParseNotificationTemplate(name string, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+		"json": func(value any) (string, error) {
+			content, err := json.Marshal(value)
+			return string(content), err
+		},
+	}).Parse(text)
+}
+
+// Function that validates notifications. Default value of when is set here.
+func validateNotificationsConfig(config *Config) []error {
+	var errs []error
+	names := make(map[string]bool, len(config.Notifications))
+	for i := range config.Notifications {
+		notification := &config.Notifications[i]
+		if notification.Name == "" {
+			errs = append(errs, fmt.Errorf("notifications[%d].name: cannot be empty", i))
+			continue
+		}
+		if names[notification.Name] {
+			errs = append(errs, fmt.Errorf("notifications.%s: name is not unique", notification.Name))
+		}
+		names[notification.Name] = true
+		errs = append(errs, validateNotification(notification)...)
+	}
+	return errs
+}
+
+func validateNotification(notification *NotificationConfig) []error {
+	var errs []error
+	field := "notifications." + notification.Name
+	switch notification.Type {
+	case NotificationWebhook, NotificationSlack, NotificationTeams:
+	case "":
+		errs = append(errs, fmt.Errorf("%s.type: cannot be empty", field))
+	default:
+		errs = append(errs, fmt.Errorf(
+			"%s.type: %s is not a valid notification type (webhook, slack, teams)", field, notification.Type,
+		))
+	}
+
+	if notification.URL == "" {
+		errs = append(errs, fmt.Errorf("%s.url: cannot be empty", field))
+	} else if !secrets.IsReference(notification.URL) {
+		// References are left unresolved, when the config is only validated
+		if err := validateNotificationURL(field, notification.URL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	switch notification.When {
+	case "":
+		notification.When = NotifyAlways
+	case NotifyAlways, NotifyFailure, NotifyChanges:
+	default:
+		errs = append(errs, fmt.Errorf(
+			"%s.when: %s is not valid (always, failure, changes)", field, notification.When,
+		))
+	}
+
+	if notification.Template != "" {
+		if notification.Type != NotificationWebhook {
+			errs = append(errs, fmt.Errorf("%s.template: can only be set for webhook notifications", field))
+		} else if _, err := ParseNotificationTemplate(notification.Name, notification.Template); err != nil {
+			errs = append(errs, fmt.Errorf("%s.template: %s", field, err))
+		}
+	}
+	return errs
+}
+
+// validateNotificationURL validates http(s) URL of the notification configured in field.
+func validateNotificationURL(field string, notificationURL string) error {
+	parsedURL, err := url.Parse(notificationURL)
+	if err != nil {
+		// Error of url.Parse contains the url, which can contain a secret
+		return fmt.Errorf("%s.url: is not a valid URL", field)
+	}
+	if parsedURL.Scheme != string(HTTP) && parsedURL.Scheme != string(HTTPS) || parsedURL.Host == "" {
+		return fmt.Errorf("%s.url: is not a valid http(s) URL", field)
+	}
+	return nil
+}
@@ -1,25 +1,59 @@
 package parser
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"net"
+	"net/url"
 	"os"
 	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/logger"
+	"github.com/bl4ko/netbox-ssot/internal/secrets"
 	"github.com/bl4ko/netbox-ssot/internal/utils"
-	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
 	Logger  *LoggerConfig  `yaml:"logger"`
 	Netbox  *NetboxConfig  `yaml:"netbox"`
 	Sources []SourceConfig `yaml:"source"`
+	API     *APIConfig     `yaml:"api"`
+	Metrics *MetricsConfig `yaml:"metrics"`
+	Secrets *SecretsConfig `yaml:"secrets"`
+	// Notifications sent with the summary of each finished run.
+	Notifications []NotificationConfig `yaml:"notifications"`
+	// Transforms rewrite fields of objects from all sources, before they are synced.
+	Transforms []TransformRule `yaml:"transforms"`
 }
 
 type LoggerConfig struct {
 	Level int    `yaml:"level"`
 	Dest  string `yaml:"dest"`
+	// Format of the log lines: text or json.
+	Format string `yaml:"format"`
+	// Syslog server, which is used when dest is syslog.
+	Syslog SyslogConfig `yaml:"syslog"`
+	// Size of the log file in megabytes, after which it is rotated. 0 disables rotation.
+	MaxSize int `yaml:"maxSize"`
+	// Number of rotated log files that are kept.
+	MaxBackups int `yaml:"maxBackups"`
+}
+
+// Configuration of the syslog server used by the logger.
+type SyslogConfig struct {
+	// Network of the syslog server (udp, tcp or unix). Empty network means local syslog.
+	Network string `yaml:"network"`
+	// Address of the syslog server (e.g. syslog.example.com:514).
+	Address string `yaml:"address"`
+	// Tag of the syslog messages.
+	Tag string `yaml:"tag"`
 }
 
 func (l *LoggerConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -28,6 +62,21 @@ func (l *LoggerConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return fmt.Errorf("logger: %s", err)
 	}
 
+	// Options with simple types are decoded directly, keeping the defaults
+	options := struct {
+		Format     string       `yaml:"format"`
+		Syslog     SyslogConfig `yaml:"syslog"`
+		MaxSize    int          `yaml:"maxSize"`
+		MaxBackups int          `yaml:"maxBackups"`
+	}{Format: l.Format, Syslog: l.Syslog, MaxSize: l.MaxSize, MaxBackups: l.MaxBackups}
+	if err := unmarshal(&options); err != nil {
+		return fmt.Errorf("logger: %s", err)
+	}
+	l.Format = options.Format
+	l.Syslog = options.Syslog
+	l.MaxSize = options.MaxSize
+	l.MaxBackups = options.MaxBackups
+
 	switch item := rawMarshal["dest"].(type) {
 	case string:
 		l.Dest = item
@@ -63,9 +112,77 @@ func (l *LoggerConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 
 func (l LoggerConfig) String() string {
 	if l.Dest == "" {
-		return fmt.Sprintf("LoggerConfig{Level: %d, Dest: stdout}", l.Level)
+		return fmt.Sprintf("LoggerConfig{Level: %d, Dest: stdout, Format: %s}", l.Level, l.Format)
 	}
-	return fmt.Sprintf("LoggerConfig{Level: %d, Dest: %s}", l.Level, l.Dest)
+	return fmt.Sprintf("LoggerConfig{Level: %d, Dest: %s, Format: %s}", l.Level, l.Dest, l.Format)
+}
+
+// Configuration of the embedded HTTP control API,
+// which is available when running in daemon mode.
+type APIConfig struct {
+	// Address on which the API listens (e.g. ":8080"). Empty address disables the API.
+	Address string `yaml:"address"`
+	// Token that clients must provide in the Authorization header ("Bearer <token>").
+	// If empty, the API doesn't require authentication.
+	Token string `yaml:"token"`
+}
+
+func (a APIConfig) String() string {
+	return fmt.Sprintf("APIConfig{Address: %s, Token: %s}", a.Address, redact(a.Token))
+}
+
+// redact hides secret values, when they are printed.
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return secrets.Redacted
+}
+
+// Configuration of secret providers. Secrets in the config (netbox.apiToken,
+// api.token, username, password and apiToken of sources, and url and headers
+// of notifications) can be references to secrets instead of plaintext values:
+// env:VAR, file:/path or vault:path#key.
+type SecretsConfig struct {
+	// Vault server, which resolves vault:path#key references.
+	Vault VaultConfig `yaml:"vault"`
+}
+
+// Configuration of the HashiCorp Vault KV secrets engine.
+type VaultConfig struct {
+	// Address of the Vault server (e.g. https://vault.example.com:8200). Empty address disables Vault.
+	Address string `yaml:"address"`
+	// Token used to authenticate to Vault. Can be an env: or file: reference.
+	Token string `yaml:"token"`
+	// Mount path of the KV secrets engine.
+	Mount string `yaml:"mount"`
+	// Version of the KV secrets engine (1 or 2).
+	KVVersion    int    `yaml:"kvVersion"`
+	ValidateCert bool   `yaml:"validateCert"`
+	CAFile       string `yaml:"caFile"`
+}
+
+func (v VaultConfig) String() string {
+	return fmt.Sprintf(
+		"VaultConfig{Address: %s, Token: %s, Mount: %s, KVVersion: %d, ValidateCert: %t, CAFile: %s}",
+		v.Address, redact(v.Token), v.Mount, v.KVVersion, v.ValidateCert, v.CAFile,
+	)
+}
+
+// Configuration of Prometheus metrics.
+type MetricsConfig struct {
+	// Address on which metrics are served at /metrics (e.g. ":9090"). Empty address disables the listener.
+	Address string `yaml:"address"`
+	// URL of the Prometheus Pushgateway, where metrics are pushed after each run.
+	// Empty URL disables pushing.
+	PushgatewayURL string `yaml:"pushgatewayURL"`
+	// Job name used when pushing metrics to the Pushgateway.
+	Job string `yaml:"job"`
+}
+
+// Enabled returns true if metrics are either served or pushed.
+func (m *MetricsConfig) Enabled() bool {
+	return m.Address != "" || m.PushgatewayURL != ""
 }
 
 type HTTPScheme string
@@ -91,14 +208,92 @@ type NetboxConfig struct {
 	RemoveOrphansAfterDays int        `yaml:"removeOrphansAfterDays"`
 	SourcePriority         []string   `yaml:"sourcePriority"`
 	CAFile                 string     `yaml:"caFile"`
+	// Number of retries of failed requests to the Netbox API.
+	MaxRetries int `yaml:"maxRetries"`
+	// Maximum number of requests per second to the Netbox API. 0 means unlimited.
+	RequestsPerSecond float64 `yaml:"requestsPerSecond"`
+	// Maximum number of init steps run concurrently when the inventory is initialized.
+	InitConcurrency int `yaml:"initConcurrency"`
+	// Maximum number of pages fetched concurrently for a single object type.
+	PageConcurrency int `yaml:"pageConcurrency"`
+	// Maximum number of objects created or updated in a single bulk request.
+	BulkSize int `yaml:"bulkSize"`
+	// InitOnlyTagged restricts initialization of the inventory
+	// to objects tagged with the netbox-ssot tag.
+	InitOnlyTagged bool `yaml:"initOnlyTagged"`
+	// DeletionThreshold limits deletions of orphaned objects of each object type in a single run.
+	DeletionThreshold DeletionThreshold `yaml:"deletionThreshold"`
+	// ObjectTypeDeletionThresholds override DeletionThreshold for object types,
+	// which are identified by their content type (e.g. virtualization.virtualmachine).
+	ObjectTypeDeletionThresholds map[constants.ContentType]DeletionThreshold `yaml:"objectTypeDeletionThresholds"`
+	// PendingDeletionsFile is path of the file, where deletions are written when
+	// a deletion threshold is exceeded. They can be applied with --approve-deletions.
+	PendingDeletionsFile string `yaml:"pendingDeletionsFile"`
+	// Branching stages changes of each run in a branch of the NetBox branching plugin.
+	Branching BranchingConfig `yaml:"branching"`
+	// FieldOwnership defines fields of object types, identified by their
+	// content type (e.g. dcim.device), which netbox-ssot updates.
+	FieldOwnership map[constants.ContentType]FieldOwnership `yaml:"fieldOwnership"`
+	// FieldSourcePriority overrides SourcePriority for single fields of object types,
+	// e.g. serial of dcim.device. Sources are ordered from the highest priority.
+	FieldSourcePriority map[constants.ContentType]map[string][]string `yaml:"fieldSourcePriority"`
+	// Snapshot enables incremental refresh of the inventory from a local snapshot of collected objects.
+	Snapshot SnapshotConfig `yaml:"snapshot"`
+}
+
+// Configuration of the local snapshot of the inventory. With a snapshot, only objects
+// changed since the previous run are collected from Netbox, and deleted objects are
+// found by listing IDs of all objects.
+type SnapshotConfig struct {
+	// Path of the snapshot file. If empty, all objects are collected on each run.
+	Path string `yaml:"path"`
+	// MaxAge in seconds, after which all objects are collected again,
+	// e.g. to refresh nested objects. 0 means no limit.
+	MaxAge int `yaml:"maxAge"`
+}
+
+// FieldOwnership defines fields of an object type, which are owned by netbox-ssot.
+// Owned fields are updated on each run. Other fields are set only when netbox-ssot
+// creates the object, so their manual changes are preserved. Fields are named as
+// in the Netbox API, custom fields as custom_fields.<name>.
+type FieldOwnership struct {
+	// Owned are the only fields updated by netbox-ssot. If empty, all fields except CreateOnly are owned.
+	Owned []string `yaml:"owned"`
+	// CreateOnly are fields, which are set only on create.
+	CreateOnly []string `yaml:"createOnly"`
+}
+
+// Configuration of runs staged in branches of the NetBox branching plugin.
+type BranchingConfig struct {
+	// Enabled creates a new branch for each run. The branch is merged only if all
+	// sources synced successfully and orphan cleanup passed its checks,
+	// otherwise it is left for review.
+	Enabled bool `yaml:"enabled"`
+	// NamePrefix is prefix of names of the created branches.
+	NamePrefix string `yaml:"namePrefix"`
+	// Timeout in seconds to wait for a branch to be provisioned or merged.
+	Timeout int `yaml:"timeout"`
+}
+
+// DeletionThreshold limits number of orphaned objects that can be deleted in a single run.
+// Zero values mean no limit.
+type DeletionThreshold struct {
+	// MaxObjects is the maximum number of deleted objects.
+	MaxObjects int `yaml:"maxObjects"`
+	// MaxPercent is the maximum percentage of objects managed by netbox-ssot that can be deleted.
+	MaxPercent float64 `yaml:"maxPercent"`
 }
 
 func (n NetboxConfig) String() string {
 	return fmt.Sprintf(
 		"NetboxConfig{ApiToken: %s, Hostname: %s, Port: %d, "+
 			"HTTPScheme: %s, ValidateCert: %t, Timeout: %d, "+
-			"Tag: %s, TagColor: %s, RemoveOrphans: %t, RemoveOrphansAfterDays: %d}",
-		n.APIToken,
+			"Tag: %s, TagColor: %s, RemoveOrphans: %t, RemoveOrphansAfterDays: %d, "+
+			"MaxRetries: %d, RequestsPerSecond: %g, InitConcurrency: %d, PageConcurrency: %d, BulkSize: %d, "+
+			"InitOnlyTagged: %t, "+
+			"DeletionThreshold: %+v, ObjectTypeDeletionThresholds: %v, PendingDeletionsFile: %s, Branching: %+v, "+
+			"FieldOwnership: %v, FieldSourcePriority: %v, Snapshot: %+v}",
+		redact(n.APIToken),
 		n.Hostname,
 		n.Port,
 		n.HTTPScheme,
@@ -108,6 +303,19 @@ func (n NetboxConfig) String() string {
 		n.TagColor,
 		n.RemoveOrphans,
 		n.RemoveOrphansAfterDays,
+		n.MaxRetries,
+		n.RequestsPerSecond,
+		n.InitConcurrency,
+		n.PageConcurrency,
+		n.BulkSize,
+		n.InitOnlyTagged,
+		n.DeletionThreshold,
+		n.ObjectTypeDeletionThresholds,
+		n.PendingDeletionsFile,
+		n.Branching,
+		n.FieldOwnership,
+		n.FieldSourcePriority,
+		n.Snapshot,
 	)
 }
 
@@ -136,6 +344,8 @@ type SourceConfig struct {
 	IgnoreTags          bool                 `yaml:"ignoreTags"`
 	AssignDomainName    string               `yaml:"assignDomainName"`
 	ContinueOnError     bool                 `yaml:"continueOnError"`
+	SyncTimeout         int                  `yaml:"syncTimeout"`
+	DeletionThreshold   DeletionThreshold    `yaml:"deletionThreshold"`
 	VlanPrefix          string               `yaml:"vlanPrefix"`
 	DefaultIPv4MaskBits int                  `yaml:"defaultIPv4MaskBits"`
 	DefaultIPv6MaskBits int                  `yaml:"defaultIPv6MaskBits"`
@@ -152,6 +362,12 @@ type SourceConfig struct {
 	ClusterName         string               `yaml:"clusterName"`
 	ClusterType         string               `yaml:"clusterType"`
 	ClusterGroupName    string               `yaml:"clusterGroupName"`
+	// DataFile is path of the file with source data dumped with --dump-source-data,
+	// which is synced by the replay source.
+	DataFile string `yaml:"dataFile"`
+	// Watch enables watching the source for changes while running as a daemon.
+	// Changed objects are synced between the scheduled runs.
+	Watch bool `yaml:"watch"`
 
 	// Relations
 	DatacenterClusterGroupRelations map[string]string `yaml:"datacenterClusterGroupRelations"`
@@ -169,6 +385,8 @@ type SourceConfig struct {
 	IPVrfRelations                  map[string]string `yaml:"ipVrfRelations"`
 	WlanTenantRelations             map[string]string `yaml:"wlanTenantRelations"`
 	CustomFieldMappings             map[string]string `yaml:"customFieldMappings"`
+	// RelationRules set relations of hosts and VMs, which match expressions of their attributes.
+	RelationRules []RelationRule `yaml:"relationRules"`
 }
 
 // UnmarshalYAML is a custom unmarshal function for SourceConfig.
@@ -199,6 +417,8 @@ func (sc *SourceConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		IgnoreVMDisks                   bool                 `yaml:"ignoreVMDisks"`
 		IgnoreTags                      bool                 `yaml:"ignoreTags"`
 		ContinueOnError                 bool                 `yaml:"continueOnError"`
+		SyncTimeout                     int                  `yaml:"syncTimeout"`
+		DeletionThreshold               DeletionThreshold    `yaml:"deletionThreshold"`
 		DefaultIPv4MaskBits             int                  `yaml:"defaultIPv4MaskBits"`
 		DefaultIPv6MaskBits             int                  `yaml:"defaultIPv6MaskBits"`
 		TargetInterface                 string               `yaml:"targetInterface"`
@@ -229,6 +449,9 @@ func (sc *SourceConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		ClusterName                     string               `yaml:"clusterName"`
 		ClusterType                     string               `yaml:"clusterType"`
 		ClusterGroupName                string               `yaml:"clusterGroupName"`
+		DataFile                        string               `yaml:"dataFile"`
+		Watch                           bool                 `yaml:"watch"`
+		RelationRules                   []RelationRule       `yaml:"relationRules"`
 	}
 	rawMarshal := realSourceConfig{}
 	if err := unmarshal(&rawMarshal); err != nil {
@@ -258,6 +481,8 @@ func (sc *SourceConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	sc.IgnoreVMDisks = rawMarshal.IgnoreVMDisks
 	sc.IgnoreTags = rawMarshal.IgnoreTags
 	sc.ContinueOnError = rawMarshal.ContinueOnError
+	sc.SyncTimeout = rawMarshal.SyncTimeout
+	sc.DeletionThreshold = rawMarshal.DeletionThreshold
 	sc.DefaultIPv4MaskBits = rawMarshal.DefaultIPv4MaskBits
 	sc.DefaultIPv6MaskBits = rawMarshal.DefaultIPv6MaskBits
 	sc.TargetInterface = rawMarshal.TargetInterface
@@ -273,125 +498,55 @@ func (sc *SourceConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	sc.ClusterName = rawMarshal.ClusterName
 	sc.ClusterType = rawMarshal.ClusterType
 	sc.ClusterGroupName = rawMarshal.ClusterGroupName
+	sc.DataFile = rawMarshal.DataFile
+	sc.Watch = rawMarshal.Watch
+	sc.RelationRules = rawMarshal.RelationRules
 
-	if len(rawMarshal.DatacenterClusterGroupRelations) > 0 {
-		err := utils.ValidateRegexRelations(rawMarshal.DatacenterClusterGroupRelations)
-		if err != nil {
-			return fmt.Errorf("%s.datacenterClusterGroupRelations: %s", rawMarshal.Name, err)
-		}
-		sc.DatacenterClusterGroupRelations = utils.ConvertStringsToRegexPairs(
-			rawMarshal.DatacenterClusterGroupRelations,
-		)
+	relations := []struct {
+		name     string
+		rawValue []string
+		value    *map[string]string
+	}{
+		{"datacenterClusterGroupRelations", rawMarshal.DatacenterClusterGroupRelations, &sc.DatacenterClusterGroupRelations},
+		{"hostSiteRelations", rawMarshal.HostSiteRelations, &sc.HostSiteRelations},
+		{"hostRoleRelations", rawMarshal.HostRoleRelations, &sc.HostRoleRelations},
+		{"clusterSiteRelations", rawMarshal.ClusterSiteRelations, &sc.ClusterSiteRelations},
+		{"clusterTenantRelations", rawMarshal.ClusterTenantRelations, &sc.ClusterTenantRelations},
+		{"hostTenantRelations", rawMarshal.HostTenantRelations, &sc.HostTenantRelations},
+		{"vmTenantRelations", rawMarshal.VMTenantRelations, &sc.VMTenantRelations},
+		{"vmRoleRelations", rawMarshal.VMRoleRelations, &sc.VMRoleRelations},
+		{"vlanGroupRelations", rawMarshal.VlanGroupRelations, &sc.VlanGroupRelations},
+		{"vlanTenantRelations", rawMarshal.VlanTenantRelations, &sc.VlanTenantRelations},
+		{"vlanSiteRelations", rawMarshal.VlanSiteRelations, &sc.VlanSiteRelations},
+		{"ipVrfRelations", rawMarshal.IPVrfRelations, &sc.IPVrfRelations},
+		{"vlanGroupSiteRelations", rawMarshal.VlanGroupSiteRelations, &sc.VlanGroupSiteRelations},
+		{"wlanTenantRelations", rawMarshal.WlanTenantRelations, &sc.WlanTenantRelations},
+		{"customFieldMappings", rawMarshal.CustomFieldMappings, &sc.CustomFieldMappings},
 	}
-	if len(rawMarshal.HostSiteRelations) > 0 {
-		err := utils.ValidateRegexRelations(rawMarshal.HostSiteRelations)
-		if err != nil {
-			return fmt.Errorf("%s.hostSiteRelations: %s", rawMarshal.Name, err)
+	// Every relation is compiled, so all invalid relations are reported at once
+	var errs []error
+	for _, relation := range relations {
+		if len(relation.rawValue) == 0 {
+			continue
 		}
-		sc.HostSiteRelations = utils.ConvertStringsToRegexPairs(rawMarshal.HostSiteRelations)
-	}
-	if len(rawMarshal.HostRoleRelations) > 0 {
-		err := utils.ValidateRegexRelations(rawMarshal.HostRoleRelations)
-		if err != nil {
-			return fmt.Errorf("%s.hostRoleRelations: %s", rawMarshal.Name, err)
-		}
-		sc.HostRoleRelations = utils.ConvertStringsToRegexPairs(rawMarshal.HostRoleRelations)
-	}
-	if len(rawMarshal.ClusterSiteRelations) > 0 {
-		err := utils.ValidateRegexRelations(rawMarshal.ClusterSiteRelations)
-		if err != nil {
-			return fmt.Errorf("%s.clusterSiteRelations: %s", rawMarshal.Name, err)
-		}
-		sc.ClusterSiteRelations = utils.ConvertStringsToRegexPairs(rawMarshal.ClusterSiteRelations)
-	}
-	if len(rawMarshal.ClusterTenantRelations) > 0 {
-		err := utils.ValidateRegexRelations(rawMarshal.ClusterTenantRelations)
-		if err != nil {
-			return fmt.Errorf("%s.clusterTenantRelations: %s", rawMarshal.Name, err)
-		}
-		sc.ClusterTenantRelations = utils.ConvertStringsToRegexPairs(
-			rawMarshal.ClusterTenantRelations,
-		)
-	}
-	if len(rawMarshal.HostTenantRelations) > 0 {
-		err := utils.ValidateRegexRelations(rawMarshal.HostTenantRelations)
-		if err != nil {
-			return fmt.Errorf("%s.hostTenantRelations: %s", rawMarshal.Name, err)
-		}
-		sc.HostTenantRelations = utils.ConvertStringsToRegexPairs(rawMarshal.HostTenantRelations)
-	}
-	if len(rawMarshal.VMTenantRelations) > 0 {
-		err := utils.ValidateRegexRelations(rawMarshal.VMTenantRelations)
-		if err != nil {
-			return fmt.Errorf("%s.vmTenantRelations: %s", rawMarshal.Name, err)
-		}
-		sc.VMTenantRelations = utils.ConvertStringsToRegexPairs(rawMarshal.VMTenantRelations)
-	}
-	if len(rawMarshal.VMRoleRelations) > 0 {
-		err := utils.ValidateRegexRelations(rawMarshal.VMRoleRelations)
-		if err != nil {
-			return fmt.Errorf("%s.vmRoleRelations: %s", rawMarshal.Name, err)
-		}
-		sc.VMRoleRelations = utils.ConvertStringsToRegexPairs(rawMarshal.VMRoleRelations)
-	}
-	if len(rawMarshal.VlanGroupRelations) > 0 {
-		err := utils.ValidateRegexRelations(rawMarshal.VlanGroupRelations)
-		if err != nil {
-			return fmt.Errorf("%s.vlanGroupRelations: %v", rawMarshal.Name, err)
-		}
-		sc.VlanGroupRelations = utils.ConvertStringsToRegexPairs(rawMarshal.VlanGroupRelations)
-	}
-	if len(rawMarshal.VlanTenantRelations) > 0 {
-		err := utils.ValidateRegexRelations((rawMarshal.VlanTenantRelations))
-		if err != nil {
-			return fmt.Errorf("%s.vlanTenantRelations: %v", rawMarshal.Name, err)
-		}
-		sc.VlanTenantRelations = utils.ConvertStringsToRegexPairs(rawMarshal.VlanTenantRelations)
-	}
-	if len(rawMarshal.VlanSiteRelations) > 0 {
-		err := utils.ValidateRegexRelations((rawMarshal.VlanSiteRelations))
-		if err != nil {
-			return fmt.Errorf("%s.vlanSiteRelations: %v", rawMarshal.Name, err)
-		}
-		sc.VlanSiteRelations = utils.ConvertStringsToRegexPairs(rawMarshal.VlanSiteRelations)
-	}
-	if len(rawMarshal.IPVrfRelations) > 0 {
-		err := utils.ValidateRegexRelations(rawMarshal.IPVrfRelations)
-		if err != nil {
-			return fmt.Errorf("%s.ipVrfRelations: %v", rawMarshal.Name, err)
-		}
-		sc.IPVrfRelations = utils.ConvertStringsToRegexPairs(rawMarshal.IPVrfRelations)
-	}
-	if len(rawMarshal.VlanGroupSiteRelations) > 0 {
-		err := utils.ValidateRegexRelations((rawMarshal.VlanGroupSiteRelations))
-		if err != nil {
-			return fmt.Errorf("%s.vlanGroupSiteRelations: %v", rawMarshal.Name, err)
+		valid := true
+		for _, regexRelation := range relation.rawValue {
+			if err := utils.ValidateRegexRelations([]string{regexRelation}); err != nil {
+				errs = append(errs, fmt.Errorf("%s.%s: %s", rawMarshal.Name, relation.name, err))
+				valid = false
+			}
 		}
-		sc.VlanGroupSiteRelations = utils.ConvertStringsToRegexPairs(
-			rawMarshal.VlanGroupSiteRelations,
-		)
-	}
-	if len(rawMarshal.WlanTenantRelations) > 0 {
-		err := utils.ValidateRegexRelations((rawMarshal.WlanTenantRelations))
-		if err != nil {
-			return fmt.Errorf("%s.wlanTenantRelations: %v", rawMarshal.Name, err)
+		if valid {
+			*relation.value = utils.ConvertStringsToRegexPairs(relation.rawValue)
 		}
-		sc.WlanTenantRelations = utils.ConvertStringsToRegexPairs(rawMarshal.WlanTenantRelations)
 	}
-	if len(rawMarshal.CustomFieldMappings) > 0 {
-		err := utils.ValidateRegexRelations((rawMarshal.CustomFieldMappings))
-		if err != nil {
-			return fmt.Errorf("%s.customFieldMappings: %v", rawMarshal.Name, err)
-		}
-		sc.CustomFieldMappings = utils.ConvertStringsToRegexPairs(rawMarshal.CustomFieldMappings)
-	}
-	return nil
+	return errors.Join(errs...)
 }
 
 func (sc SourceConfig) String() string {
 	return fmt.Sprintf(
 		"SourceConfig{Name: %s, Type: %s, HTTPScheme: %s, Hostname: %s, Port: %d, "+
-			"Username: %s, Password: %s, PermittedSubnets: %v, ValidateCert: %t, "+
+			"Username: %s, Password: %s, APIToken: %s, PermittedSubnets: %v, ValidateCert: %t, "+
 			"Tag: %s, TagColor: %s, AssignDomainName: %s, VlanPrefix: %s, "+
 			"clusterGroupName: %s, DatacenterClusterGroupRelations: %s, "+
 			"HostSiteRelations: %v, ClusterSiteRelations: %v, ClusterTenantRelations: %v, "+
@@ -403,7 +558,8 @@ func (sc SourceConfig) String() string {
 		sc.Hostname,
 		sc.Port,
 		sc.Username,
-		sc.Password,
+		redact(sc.Password),
+		redact(sc.APIToken),
 		sc.IgnoredSubnets,
 		sc.ValidateCert,
 		sc.Tag,
@@ -424,87 +580,368 @@ func (sc SourceConfig) String() string {
 }
 
 // Validates the user's config for limits and required fields.
-func validateConfig(config *Config) error {
-	err := validateLoggerConfig(config)
-	if err != nil {
-		return err
+// All errors found in the config are returned.
+func validateConfig(config *Config) []error {
+	var errs []error
+	errs = append(errs, validateLoggerConfig(config)...)
+	errs = append(errs, validateNetboxConfig(config)...)
+	errs = append(errs, validateSourceConfig(config)...)
+	errs = append(errs, validateAPIConfig(config)...)
+	errs = append(errs, validateMetricsConfig(config)...)
+	errs = append(errs, validateNotificationsConfig(config)...)
+	errs = append(errs, validateTransforms(config)...)
+	return errs
+}
+
+// Function that validates APIConfig.
+func validateAPIConfig(config *Config) []error {
+	if config.API.Address == "" {
+		return nil
+	}
+	if err := validateListenAddress("api.address", config.API.Address); err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
+// Function that validates MetricsConfig.
+func validateMetricsConfig(config *Config) []error {
+	var errs []error
+	if config.Metrics.Address != "" {
+		if err := validateListenAddress("metrics.address", config.Metrics.Address); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if config.Metrics.PushgatewayURL != "" {
+		pushgatewayURL, err := url.Parse(config.Metrics.PushgatewayURL)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("metrics.pushgatewayURL: %s", err))
+		} else if pushgatewayURL.Scheme != string(HTTP) && pushgatewayURL.Scheme != string(HTTPS) ||
+			pushgatewayURL.Host == "" {
+			errs = append(errs, fmt.Errorf(
+				"metrics.pushgatewayURL: %s is not a valid http(s) URL", config.Metrics.PushgatewayURL,
+			))
+		}
+	}
+	if config.Metrics.Job == "" {
+		errs = append(errs, errors.New("metrics.job: cannot be empty"))
 	}
+	return errs
+}
 
-	err = validateNetboxConfig(config)
+// Function that validates SecretsConfig.
+func validateSecretsConfig(config *Config) error {
+	vault := config.Secrets.Vault
+	if vault.Address == "" {
+		return nil
+	}
+	vaultURL, err := url.Parse(vault.Address)
 	if err != nil {
-		return err
+		return fmt.Errorf("secrets.vault.address: %s", err)
+	}
+	if vaultURL.Scheme != string(HTTP) && vaultURL.Scheme != string(HTTPS) || vaultURL.Host == "" {
+		return fmt.Errorf("secrets.vault.address: %s is not a valid http(s) URL", vault.Address)
+	}
+	if vault.Token == "" {
+		return errors.New("secrets.vault.token: cannot be empty")
+	}
+	if vault.Mount == "" {
+		return errors.New("secrets.vault.mount: cannot be empty")
 	}
+	if vault.KVVersion != 1 && vault.KVVersion != 2 {
+		return fmt.Errorf("secrets.vault.kvVersion: must be 1 or 2. Is %d", vault.KVVersion)
+	}
+	return nil
+}
 
-	err = validateSourceConfig(config)
+// secretField is a config field, which can contain a secret reference.
+type secretField struct {
+	// name of the field used in errors (e.g. netbox.apiToken)
+	name  string
+	value *string
+}
+
+// resolveSecrets replaces secret references in the config with secrets
+// they reference. Plaintext values are left unchanged. Errors of all
+// references, which can't be resolved, are returned. If resolve is false,
+// references are only checked and left in the config, so no secret is read.
+func resolveSecrets(config *Config, resolve bool) []error {
+	err := validateSecretsConfig(config)
 	if err != nil {
-		return err
+		return []error{err}
+	}
+	ctx := context.Background()
+	resolver := secrets.NewResolver()
+	resolveValue := func(value string) (string, error) {
+		if !resolve {
+			return resolver.Check(value)
+		}
+		return resolver.Resolve(ctx, value)
 	}
 
+	vault := config.Secrets.Vault
+	if vault.Address != "" {
+		// Vault token can't be stored in vault itself
+		token, err := resolveValue(vault.Token)
+		if err != nil {
+			return []error{fmt.Errorf("secrets.vault.token: %s", err)}
+		}
+		httpClient, err := utils.NewHTTPClient(vault.ValidateCert, vault.CAFile)
+		if err != nil {
+			return []error{fmt.Errorf("secrets.vault: %s", err)}
+		}
+		httpClient.Timeout = constants.DefaultAPITimeout * time.Second
+		resolver.Register(secrets.SchemeVault, &secrets.VaultProvider{
+			Address:    vault.Address,
+			Token:      token,
+			Mount:      vault.Mount,
+			KVVersion:  vault.KVVersion,
+			HTTPClient: httpClient,
+		})
+	}
+
+	fields := []secretField{
+		{name: "netbox.apiToken", value: &config.Netbox.APIToken},
+		{name: "api.token", value: &config.API.Token},
+	}
+	for i := range config.Sources {
+		source := &config.Sources[i]
+		fields = append(fields,
+			secretField{name: source.Name + ".username", value: &source.Username},
+			secretField{name: source.Name + ".password", value: &source.Password},
+			secretField{name: source.Name + ".apiToken", value: &source.APIToken},
+		)
+	}
+	for i := range config.Notifications {
+		notification := &config.Notifications[i]
+		fields = append(fields,
+			secretField{name: "notifications." + notification.Name + ".url", value: &notification.URL},
+		)
+	}
+	var errs []error
+	for _, field := range fields {
+		secret, err := resolveValue(*field.value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", field.name, err))
+			continue
+		}
+		*field.value = secret
+	}
+	// Values of headers aren't addressable, so they are resolved separately
+	for _, notification := range config.Notifications {
+		for name, value := range notification.Headers {
+			secret, err := resolveValue(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("notifications.%s.headers.%s: %s", notification.Name, name, err))
+				continue
+			}
+			notification.Headers[name] = secret
+		}
+	}
+	return errs
+}
+
+// validateListenAddress validates address in host:port format configured in field.
+func validateListenAddress(field string, address string) error {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%s: %s", field, err)
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		return fmt.Errorf("%s: invalid port %s", field, port)
+	}
 	return nil
 }
 
-func validateLoggerConfig(config *Config) error {
+func validateLoggerConfig(config *Config) []error {
+	var errs []error
 	if config.Logger.Level < 0 || config.Logger.Level > 3 {
-		return errors.New("logger.level: must be between 0 and 3")
+		errs = append(errs, errors.New("logger.level: must be between 0 and 3"))
+	}
+	if config.Logger.Format != logger.FormatText && config.Logger.Format != logger.FormatJSON {
+		errs = append(errs, fmt.Errorf("logger.format: %s is not a valid format (text, json)", config.Logger.Format))
+	}
+	if config.Logger.MaxSize < 0 {
+		errs = append(errs, errors.New("logger.maxSize: cannot be negative"))
+	}
+	if config.Logger.MaxBackups < 0 {
+		errs = append(errs, errors.New("logger.maxBackups: cannot be negative"))
+	}
+	switch config.Logger.Syslog.Network {
+	case "", "udp", "tcp", "unix":
+	default:
+		errs = append(errs, fmt.Errorf(
+			"logger.syslog.network: %s is not a valid network (udp, tcp, unix)", config.Logger.Syslog.Network,
+		))
+	}
+	return errs
+}
+
+// validateDeletionThreshold validates threshold configured in field.
+func validateDeletionThreshold(field string, threshold DeletionThreshold) error {
+	if threshold.MaxObjects < 0 {
+		return fmt.Errorf("%s.maxObjects: cannot be negative", field)
+	}
+	if threshold.MaxPercent < 0 || threshold.MaxPercent > 100 {
+		return fmt.Errorf("%s.maxPercent: must be between 0 and 100", field)
 	}
 	return nil
 }
 
+// validateFieldOwnership validates field ownership of all object types.
+func validateFieldOwnership(fieldOwnership map[constants.ContentType]FieldOwnership) []error {
+	var errs []error
+	for _, objectType := range slices.Sorted(maps.Keys(fieldOwnership)) {
+		ownership := fieldOwnership[objectType]
+		field := fmt.Sprintf("netbox.fieldOwnership.%s", objectType)
+		if len(ownership.Owned) > 0 && len(ownership.CreateOnly) > 0 {
+			errs = append(errs, fmt.Errorf("%s: owned and createOnly cannot be both set", field))
+			continue
+		}
+		for _, name := range slices.Concat(ownership.Owned, ownership.CreateOnly) {
+			if slices.Contains(constants.SsotOwnedFields, name) {
+				errs = append(errs, fmt.Errorf("%s: %s is always owned by netbox-ssot", field, name))
+			}
+		}
+	}
+	return errs
+}
+
+// validateFieldSourcePriority validates priorities of sources for fields of all object types.
+func validateFieldSourcePriority(config *Config) []error {
+	var errs []error
+	for _, objectType := range slices.Sorted(maps.Keys(config.Netbox.FieldSourcePriority)) {
+		fieldPriorities := config.Netbox.FieldSourcePriority[objectType]
+		for _, name := range slices.Sorted(maps.Keys(fieldPriorities)) {
+			field := fmt.Sprintf("netbox.fieldSourcePriority.%s.%s", objectType, name)
+			if strings.HasPrefix(name, "custom_fields.") {
+				errs = append(errs, fmt.Errorf("%s: priority of a single custom field is not supported, use custom_fields", field))
+				continue
+			}
+			if slices.Contains(constants.SsotOwnedFields, name) {
+				errs = append(errs, fmt.Errorf("%s: %s is always owned by netbox-ssot", field, name))
+				continue
+			}
+			if len(fieldPriorities[name]) == 0 {
+				errs = append(errs, fmt.Errorf("%s: cannot be empty", field))
+			}
+			for i, sourceName := range fieldPriorities[name] {
+				if !slices.ContainsFunc(config.Sources, func(source SourceConfig) bool { return source.Name == sourceName }) {
+					errs = append(errs, fmt.Errorf("%s: %s doesn't exist in the sources array", field, sourceName))
+				} else if slices.Contains(fieldPriorities[name][:i], sourceName) {
+					errs = append(errs, fmt.Errorf("%s: %s is listed more than once", field, sourceName))
+				}
+			}
+		}
+	}
+	return errs
+}
+
 // Function that validates NetboxConfig.
-func validateNetboxConfig(config *Config) error {
+func validateNetboxConfig(config *Config) []error {
+	var errs []error
 	// Validate Netbox config
 	if config.Netbox.APIToken == "" {
-		return errors.New("netbox.apiToken: cannot be empty")
+		errs = append(errs, errors.New("netbox.apiToken: cannot be empty"))
 	}
 	if config.Netbox.HTTPScheme != HTTP && config.Netbox.HTTPScheme != HTTPS {
-		return errors.New(
-			"netbox.httpScheme: must be either http or https. Is " + string(
+		errs = append(errs, errors.New(
+			"netbox.httpScheme: must be either http or https. Is "+string(
 				config.Netbox.HTTPScheme,
 			),
-		)
+		))
 	}
 	if config.Netbox.Hostname == "" {
-		return errors.New("netbox.hostname: cannot be empty")
+		errs = append(errs, errors.New("netbox.hostname: cannot be empty"))
 	}
 	if config.Netbox.Port < 0 || config.Netbox.Port > 65535 {
-		return errors.New(
-			"netbox.port: must be between 0 and 65535. Is " + fmt.Sprintf("%d", config.Netbox.Port),
-		)
+		errs = append(errs, errors.New(
+			"netbox.port: must be between 0 and 65535. Is "+fmt.Sprintf("%d", config.Netbox.Port),
+		))
 	}
 	if config.Netbox.Timeout < 0 {
-		return errors.New("netbox.timeout: cannot be negative")
+		errs = append(errs, errors.New("netbox.timeout: cannot be negative"))
+	}
+	if config.Netbox.MaxRetries < 0 {
+		errs = append(errs, errors.New("netbox.maxRetries: cannot be negative"))
+	}
+	if config.Netbox.RequestsPerSecond < 0 {
+		errs = append(errs, errors.New("netbox.requestsPerSecond: cannot be negative"))
+	}
+	if config.Netbox.InitConcurrency < 1 {
+		errs = append(errs, errors.New("netbox.initConcurrency: must be at least 1"))
+	}
+	if config.Netbox.PageConcurrency < 1 {
+		errs = append(errs, errors.New("netbox.pageConcurrency: must be at least 1"))
+	}
+	if config.Netbox.BulkSize < 1 {
+		errs = append(errs, errors.New("netbox.bulkSize: must be at least 1"))
+	}
+	if err := validateDeletionThreshold("netbox.deletionThreshold", config.Netbox.DeletionThreshold); err != nil {
+		errs = append(errs, err)
+	}
+	for objectType, threshold := range config.Netbox.ObjectTypeDeletionThresholds {
+		field := fmt.Sprintf("netbox.objectTypeDeletionThresholds.%s", objectType)
+		if err := validateDeletionThreshold(field, threshold); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if config.Netbox.PendingDeletionsFile == "" {
+		config.Netbox.PendingDeletionsFile = constants.DefaultPendingDeletionsFile
+	}
+	errs = append(errs, validateFieldOwnership(config.Netbox.FieldOwnership)...)
+	errs = append(errs, validateFieldSourcePriority(config)...)
+	if config.Netbox.Snapshot.MaxAge < 0 {
+		errs = append(errs, errors.New("netbox.snapshot.maxAge: cannot be negative"))
+	}
+	if config.Netbox.Branching.Timeout < 0 {
+		errs = append(errs, errors.New("netbox.branching.timeout: cannot be negative"))
+	}
+	if config.Netbox.Branching.Enabled {
+		if config.Netbox.Branching.NamePrefix == "" {
+			config.Netbox.Branching.NamePrefix = constants.DefaultBranchNamePrefix
+		}
+		if config.Netbox.Branching.Timeout == 0 {
+			config.Netbox.Branching.Timeout = constants.DefaultBranchTimeout
+		}
 	}
 	if config.Netbox.Tag == "" {
 		config.Netbox.Tag = constants.SsotTagName
 	}
 	if !config.Netbox.RemoveOrphans {
 		if config.Netbox.RemoveOrphansAfterDays < 0 {
-			return fmt.Errorf("netbox.RemoveOrphansAfterDays: must be positive integer")
+			errs = append(errs, fmt.Errorf("netbox.RemoveOrphansAfterDays: must be positive integer"))
 		}
 		if config.Netbox.RemoveOrphansAfterDays == 0 {
 			config.Netbox.RemoveOrphansAfterDays = constants.CustomFieldOrphanLastSeenDefaultValue
 		}
 	} else if config.Netbox.RemoveOrphansAfterDays != 0 {
-		return fmt.Errorf("netbox.removeOrphansAfterDays has no effect when netbox.removeOrphans is set to true")
+		errs = append(errs, fmt.Errorf(
+			"netbox.removeOrphansAfterDays has no effect when netbox.removeOrphans is set to true",
+		))
 	}
 	if config.Netbox.TagColor == "" {
 		config.Netbox.TagColor = constants.SsotTagColor
 	} else {
 		// Ensure that TagColor is a string of 6 hexadecimal characters
 		if len(config.Netbox.TagColor) != len("ffffff") {
-			return errors.New("netbox.tagColor: must be a string of 6 hexadecimal characters")
-		}
-		for _, c := range config.Netbox.TagColor {
-			if c < '0' || c > '9' && c < 'a' || c > 'f' {
-				return errors.New("netbox.tagColor: must be a string of 6 lowercase hexadecimal characters")
+			errs = append(errs, errors.New("netbox.tagColor: must be a string of 6 hexadecimal characters"))
+		} else {
+			for _, c := range config.Netbox.TagColor {
+				if c < '0' || c > '9' && c < 'a' || c > 'f' {
+					errs = append(errs, errors.New(
+						"netbox.tagColor: must be a string of 6 lowercase hexadecimal characters",
+					))
+					break
+				}
 			}
 		}
 	}
 	if len(config.Netbox.SourcePriority) > 0 {
 		if len(config.Netbox.SourcePriority) != len(config.Sources) {
-			return fmt.Errorf(
+			errs = append(errs, fmt.Errorf(
 				"netbox.sourcePriority: len(config.Netbox.SourcePriority) != len(config.Sources)",
-			)
+			))
 		}
 		for _, sourceName := range config.Netbox.SourcePriority {
 			contains := false
@@ -515,87 +952,90 @@ func validateNetboxConfig(config *Config) error {
 				}
 			}
 			if !contains {
-				return fmt.Errorf(
+				errs = append(errs, fmt.Errorf(
 					"netbox.sourcePriority: %s doesn't exist in the sources array",
 					sourceName,
-				)
+				))
 			}
 		}
 	}
 	if config.Netbox.CAFile != "" {
 		_, err := os.ReadFile(config.Netbox.CAFile)
 		if err != nil {
-			return fmt.Errorf("netbox.caFile: %s", err)
+			errs = append(errs, fmt.Errorf("netbox.caFile: %s", err))
 		}
 	}
-	return nil
+	return errs
 }
 
 //nolint:gocyclo
-func validateSourceConfig(config *Config) error {
+func validateSourceConfig(config *Config) []error {
+	var errs []error
 	// Validate Sources
 	for i := range config.Sources {
 		externalSource := &config.Sources[i]
 		externalSourceStr := externalSource.Name
 		if externalSource.Name == "" {
-			return fmt.Errorf("source name: cannot be empty")
-		}
-		switch externalSource.Type {
-		case constants.Ovirt:
-		case constants.Vmware:
-		case constants.Dnac:
-		case constants.Proxmox:
-		case constants.PaloAlto:
-		case constants.Fortigate:
-		case constants.FMC:
-		case constants.IOSXE:
-		case constants.F5:
-		case constants.HetznerCloud:
-		case constants.OpenStack:
-			if externalSource.ProjectName == "" && externalSource.TenantName == "" &&
-				externalSource.ProjectID == "" && externalSource.TenantID == "" {
-				return fmt.Errorf(
-					"%s: at least one of projectName, tenantName, projectID, or tenantID is required for OpenStack",
-					externalSourceStr,
-				)
+			errs = append(errs, fmt.Errorf("source name: cannot be empty"))
+			continue
+		}
+		definition, ok := getSourceTypeDefinition(externalSource.Type)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s.type is not valid", externalSourceStr))
+		}
+		for _, option := range definition.Required {
+			if isSourceOptionSet(externalSource, option) {
+				continue
 			}
-			if externalSource.DomainName == "" && externalSource.DomainID == "" {
-				return fmt.Errorf("%s: at least one of domainName or domainID is required for OpenStack", externalSourceStr)
+			if option == "apiToken" {
+				// Only some source types authenticate with a token instead of credentials
+				errs = append(errs, fmt.Errorf("%s.apiToken is required for %s", externalSourceStr, externalSource.Type))
+			} else {
+				errs = append(errs, fmt.Errorf("%s.%s: cannot be empty", externalSourceStr, option))
+			}
+		}
+		for _, options := range definition.RequiredOneOf {
+			if !slices.ContainsFunc(options, func(option string) bool {
+				return isSourceOptionSet(externalSource, option)
+			}) {
+				errs = append(errs, fmt.Errorf(
+					"%s: at least one of %s is required for %s",
+					externalSourceStr,
+					joinOptions(options),
+					externalSource.Type,
+				))
 			}
-		default:
-			return fmt.Errorf("%s.type is not valid", externalSourceStr)
 		}
 		if externalSource.HTTPScheme == "" {
 			externalSource.HTTPScheme = "https"
 		} else if externalSource.HTTPScheme != HTTP && externalSource.HTTPScheme != HTTPS {
-			return fmt.Errorf(
+			errs = append(errs, fmt.Errorf(
 				"%s.httpScheme: must be either http or https. Is %s",
 				externalSourceStr,
 				string(externalSource.HTTPScheme),
-			)
-		}
-		if externalSource.Hostname == "" && externalSource.Type != constants.HetznerCloud {
-			return fmt.Errorf("%s.hostname: cannot be empty", externalSourceStr)
+			))
 		}
 		if externalSource.Port == 0 {
 			externalSource.Port = 443
 		} else if externalSource.Port < 0 || externalSource.Port > 65535 {
-			return fmt.Errorf("%s.port: must be between 0 and 65535. Is %d", externalSourceStr, externalSource.Port)
+			errs = append(errs, fmt.Errorf(
+				"%s.port: must be between 0 and 65535. Is %d", externalSourceStr, externalSource.Port,
+			))
 		}
-		tokenOnlySources := externalSource.Type == constants.Fortigate ||
-			externalSource.Type == constants.HetznerCloud
-		if externalSource.APIToken == "" && tokenOnlySources {
-			return fmt.Errorf(
-				"%s.apiToken is required for %s",
-				externalSourceStr,
-				externalSource.Type,
-			)
+		if externalSource.SyncTimeout < 0 {
+			errs = append(errs, fmt.Errorf("%s.syncTimeout: cannot be negative", externalSourceStr))
 		}
-		if externalSource.Username == "" && !tokenOnlySources {
-			return fmt.Errorf("%s.username: cannot be empty", externalSourceStr)
+		if externalSource.Watch && ok && !definition.Watch {
+			errs = append(errs, fmt.Errorf(
+				"%s.watch: is not supported for source type %s", externalSourceStr, externalSource.Type,
+			))
 		}
-		if externalSource.Password == "" && !tokenOnlySources {
-			return fmt.Errorf("%s.password: cannot be empty", externalSourceStr)
+		errs = append(errs, validateRelationRules(externalSource, !ok || definition.RelationRules)...)
+		if err := validateDeletionThreshold(
+			externalSourceStr+".deletionThreshold",
+			externalSource.DeletionThreshold,
+		); err != nil {
+			errs = append(errs, err)
 		}
 		if externalSource.Tag == "" {
 			externalSource.Tag = fmt.Sprintf("Source: %s", externalSource.Name)
@@ -605,28 +1045,28 @@ func validateSourceConfig(config *Config) error {
 		}
 		if externalSource.CAFile != "" {
 			if _, err := os.ReadFile(externalSource.CAFile); err != nil {
-				return fmt.Errorf("%s.caFile: %s", externalSourceStr, err)
+				errs = append(errs, fmt.Errorf("%s.caFile: %s", externalSourceStr, err))
 			}
 		}
 		if len(externalSource.IgnoredSubnets) > 0 {
 			for _, ignoredSubnet := range externalSource.IgnoredSubnets {
 				if !utils.VerifySubnet(ignoredSubnet) {
-					return fmt.Errorf(
+					errs = append(errs, fmt.Errorf(
 						"%s.ignoredSubnets: wrong format: %s",
 						externalSourceStr,
 						ignoredSubnet,
-					)
+					))
 				}
 			}
 		}
 		if len(externalSource.PermittedSubnets) > 0 {
 			for _, permittedSubnet := range externalSource.PermittedSubnets {
 				if !utils.VerifySubnet(permittedSubnet) {
-					return fmt.Errorf(
+					errs = append(errs, fmt.Errorf(
 						"%s.permittedSubnets: wrong format: %s",
 						externalSourceStr,
 						permittedSubnet,
-					)
+					))
 				}
 			}
 		}
@@ -634,46 +1074,77 @@ func validateSourceConfig(config *Config) error {
 		// Try to compile interfaceFilter
 		_, err := regexp.Compile(externalSource.InterfaceFilter)
 		if err != nil {
-			return fmt.Errorf("%s.interfaceFilter: wrong format: %s", externalSourceStr, err)
+			errs = append(errs, fmt.Errorf("%s.interfaceFilter: wrong format: %s", externalSourceStr, err))
 		}
 	}
-	return nil
+	return errs
 }
 
 func ParseConfig(configFilename string) (*Config, error) {
-	// First we read the config file
-	file, err := os.Open(configFilename)
+	config, errs := parseConfig(configFilename, true)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return config, nil
+}
+
+// ValidateConfig parses and validates the config file like ParseConfig, but
+// instead of stopping at the first error it returns all errors in the config.
+// Secret references are only resolved if resolveSecrets is true, otherwise
+// it is only checked that they are valid references.
+func ValidateConfig(configFilename string, resolveSecrets bool) []error {
+	_, errs := parseConfig(configFilename, resolveSecrets)
+	return errs
+}
+
+// parseConfig parses the config file and returns all errors found in it.
+// Secret references are resolved only if resolve is true.
+func parseConfig(configFilename string, resolve bool) (*Config, []error) {
+	// First we read the config file together with all files it includes
+	document, err := loadConfigDocument(configFilename)
 	if err != nil {
-		return nil, err
+		return nil, []error{err}
 	}
-	defer file.Close()
 
 	// Define Config with default values
 	config := &Config{
 		Logger: &LoggerConfig{
-			Level: 1,
-			Dest:  "",
+			Level:      1,
+			Dest:       "",
+			Format:     logger.FormatText,
+			MaxSize:    constants.DefaultLogMaxSize,
+			MaxBackups: constants.DefaultLogMaxBackups,
 		},
 		Netbox: &NetboxConfig{
-			HTTPScheme:    "https",
-			Port:          constants.HTTPSDefaultPort,
-			Timeout:       constants.DefaultAPITimeout,
-			RemoveOrphans: true,
+			HTTPScheme:      "https",
+			Port:            constants.HTTPSDefaultPort,
+			Timeout:         constants.DefaultAPITimeout,
+			RemoveOrphans:   true,
+			MaxRetries:      constants.DefaultAPIMaxRetries,
+			InitConcurrency: constants.DefaultInitConcurrency,
+			PageConcurrency: constants.DefaultPageConcurrency,
+			BulkSize:        constants.DefaultBulkSize,
 		},
 		Sources: []SourceConfig{},
+		API:     &APIConfig{},
+		Metrics: &MetricsConfig{Job: constants.DefaultMetricsJob},
+		Secrets: &SecretsConfig{
+			Vault: VaultConfig{
+				Mount:        secrets.DefaultVaultMount,
+				KVVersion:    secrets.DefaultVaultKVVersion,
+				ValidateCert: true,
+			},
+		},
 	}
 
-	// Parse the config file into a Config struct
-	err = yaml.NewDecoder(file).Decode(config)
-	if err != nil {
-		return nil, err
-	}
+	// Parse the config into a Config struct
+	errs := document.decode(config)
+
+	// Replace secret references with secrets
+	errs = append(errs, resolveSecrets(config, resolve)...)
 
 	// Validate the config for limits and required fields
-	err = validateConfig(config)
-	if err != nil {
-		return nil, err
-	}
+	errs = append(errs, validateConfig(config)...)
 
-	return config, nil
+	return config, errs
 }
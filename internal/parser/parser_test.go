@@ -2,6 +2,9 @@ package parser
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
@@ -14,8 +17,11 @@ func TestValidonfig(t *testing.T) {
 	filename := filepath.Join("../../testdata/parser", "valid_config1.yaml")
 	want := &Config{
 		Logger: &LoggerConfig{
-			Level: 2,
-			Dest:  "test",
+			Level:      2,
+			Dest:       "test",
+			Format:     "text",                         // Default
+			MaxSize:    constants.DefaultLogMaxSize,    // Default
+			MaxBackups: constants.DefaultLogMaxBackups, // Default
 		},
 		Netbox: &NetboxConfig{
 			APIToken:               "netbox-token",
@@ -28,6 +34,11 @@ func TestValidonfig(t *testing.T) {
 			TagColor:               constants.SsotTagColor, // Default
 			RemoveOrphans:          false,                  // Default
 			RemoveOrphansAfterDays: 5,
+			MaxRetries:             constants.DefaultAPIMaxRetries,        // Default
+			InitConcurrency:        constants.DefaultInitConcurrency,      // Default
+			PageConcurrency:        constants.DefaultPageConcurrency,      // Default
+			BulkSize:               constants.DefaultBulkSize,             // Default
+			PendingDeletionsFile:   constants.DefaultPendingDeletionsFile, // Default
 		},
 		Sources: []SourceConfig{
 			{
@@ -114,6 +125,11 @@ func TestValidonfig(t *testing.T) {
 				},
 			},
 		},
+		API:     &APIConfig{},                                     // Default
+		Metrics: &MetricsConfig{Job: constants.DefaultMetricsJob}, // Default
+		Secrets: &SecretsConfig{ // Default
+			Vault: VaultConfig{Mount: "secret", KVVersion: 2, ValidateCert: true},
+		},
 	}
 	got, err := ParseConfig(filename)
 	if err != nil {
@@ -156,6 +172,24 @@ func TestParseValidConfigs(t *testing.T) {
 		{
 			filename: "valid_config8.yaml",
 		},
+		{
+			filename: "valid_config9.yaml",
+		},
+		{
+			filename: "valid_config10.yaml",
+		},
+		{
+			filename: "valid_config11.yaml",
+		},
+		{
+			filename: "valid_config12.yaml",
+		},
+		{
+			filename: "valid_config13.yaml",
+		},
+		{
+			filename: "valid_config14.yaml",
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.filename, func(t *testing.T) {
@@ -200,6 +234,179 @@ func TestIgnoreFlags(t *testing.T) {
 	}
 }
 
+func TestLoggerOptions(t *testing.T) {
+	filename := filepath.Join("../../testdata/parser", "valid_config10.yaml")
+	config, err := ParseConfig(filename)
+	if err != nil {
+		t.Fatalf("ParseConfig() error = %v", err)
+	}
+	want := &LoggerConfig{
+		Level:      0,
+		Dest:       "/var/log/netbox-ssot.log",
+		Format:     "json",
+		Syslog:     SyslogConfig{Tag: "ssot"},
+		MaxSize:    10,
+		MaxBackups: 5,
+	}
+	if !reflect.DeepEqual(config.Logger, want) {
+		t.Errorf("Logger = %+v, want %+v", config.Logger, want)
+	}
+}
+
+func TestParseConfigSecretReferences(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" || r.URL.Path != "/v1/secret/data/netbox-ssot/vcenter" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"data": {"username": "vault-admin", "password": "vault-pass"}}}`))
+	}))
+	defer vault.Close()
+	t.Setenv("NETBOX_SSOT_TEST_VAULT_TOKEN", "vault-token")
+	t.Setenv("NETBOX_SSOT_TEST_API_TOKEN", "env-api-token")
+
+	config := fmt.Sprintf(`
+netbox:
+  apiToken: file:../../testdata/parser/secrets/netbox-token
+  hostname: netbox.example.com
+api:
+  address: ":8080"
+  token: env:NETBOX_SSOT_TEST_API_TOKEN
+secrets:
+  vault:
+    address: %s
+    token: env:NETBOX_SSOT_TEST_VAULT_TOKEN
+    validateCert: false
+source:
+  - name: vcenter
+    type: vmware
+    hostname: vcenter.example.com
+    username: vault:netbox-ssot/vcenter#username
+    password: vault:netbox-ssot/vcenter#password
+notifications:
+  - name: alerts
+    type: webhook
+    url: https://alerts.example.com/hooks/netbox-ssot
+    headers:
+      Authorization: env:NETBOX_SSOT_TEST_API_TOKEN
+`, vault.URL)
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(filename, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ParseConfig(filename)
+	if err != nil {
+		t.Fatalf("ParseConfig() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"netbox.apiToken", got.Netbox.APIToken, "file-netbox-token"},
+		{"api.token", got.API.Token, "env-api-token"},
+		{"vcenter.username", got.Sources[0].Username, "vault-admin"},
+		{"vcenter.password", got.Sources[0].Password, "vault-pass"},
+		{"notifications.alerts.headers.Authorization", got.Notifications[0].Headers["Authorization"], "env-api-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("%s = %s, want %s", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateConfigSecretReferences(t *testing.T) {
+	vaultRequests := 0
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		vaultRequests++
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer vault.Close()
+
+	config := fmt.Sprintf(`
+netbox:
+  apiToken: env:NETBOX_SSOT_TEST_MISSING
+  hostname: netbox.example.com
+secrets:
+  vault:
+    address: %s
+    token: file:/nonexistent/vault-token
+source:
+  - name: vcenter
+    type: vmware
+    hostname: vcenter.example.com
+    username: vault:netbox-ssot/vcenter#username
+    password: "file:"
+notifications:
+  - name: alerts
+    type: slack
+    url: env:NETBOX_SSOT_TEST_MISSING_URL
+`, vault.URL)
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(filename, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		resolveSecrets bool
+		want           []string
+	}{
+		{
+			name: "References are only checked",
+			want: []string{"vcenter.password: file reference is empty"},
+		},
+		{
+			name:           "References are resolved",
+			resolveSecrets: true,
+			want: []string{
+				"secrets.vault.token: resolve file reference: read secret file: " +
+					"open /nonexistent/vault-token: no such file or directory",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, err := range ValidateConfig(filename, tt.resolveSecrets) {
+				got = append(got, err.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateConfig() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+	if vaultRequests > 0 {
+		t.Errorf("vault received %d requests, want 0", vaultRequests)
+	}
+}
+
+func TestStringRedactsSecrets(t *testing.T) {
+	tests := []struct {
+		name   string
+		config fmt.Stringer
+	}{
+		{"NetboxConfig", NetboxConfig{APIToken: "secret-value"}},
+		{"SourceConfig password", SourceConfig{Password: "secret-value"}},
+		{"SourceConfig apiToken", SourceConfig{APIToken: "secret-value"}},
+		{"APIConfig", APIConfig{Token: "secret-value"}},
+		{"VaultConfig", VaultConfig{Token: "secret-value"}},
+		{"NotificationConfig url", NotificationConfig{URL: "https://hooks.example.com/secret-value"}},
+		{"NotificationConfig headers", NotificationConfig{Headers: map[string]string{"Authorization": "secret-value"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.String(); strings.Contains(got, "secret-value") {
+				t.Errorf("String() = %s, contains secret", got)
+			}
+		})
+	}
+}
+
 func TestIgnoreFlagsDefaultFalse(t *testing.T) {
 	// valid_config2 has no ignore flags — verify they default to false
 	filename := filepath.Join("../../testdata/parser", "valid_config2.yaml")
@@ -390,6 +597,172 @@ func TestParseConfigInvalidConfigs(t *testing.T) {
 			filename:    "invalid_config48.yaml",
 			expectedErr: "wrong.vlanGroupSiteRelations: invalid regex: (wrong(), in relation: (wrong() = wwrong",
 		},
+		{
+			filename:    "invalid_config49.yaml",
+			expectedErr: "api.address: address localhost: missing port in address",
+		},
+		{
+			filename:    "invalid_config50.yaml",
+			expectedErr: "netbox.maxRetries: cannot be negative",
+		},
+		{
+			filename:    "invalid_config51.yaml",
+			expectedErr: "netbox.requestsPerSecond: cannot be negative",
+		},
+		{
+			filename:    "invalid_config52.yaml",
+			expectedErr: "netbox.initConcurrency: must be at least 1",
+		},
+		{
+			filename:    "invalid_config53.yaml",
+			expectedErr: "testvmware.syncTimeout: cannot be negative",
+		},
+		{
+			filename: "invalid_config54.yaml",
+			expectedErr: "netbox.objectTypeDeletionThresholds.virtualization.virtualmachine.maxPercent: " +
+				"must be between 0 and 100",
+		},
+		{
+			filename:    "invalid_config55.yaml",
+			expectedErr: "testvmware.deletionThreshold.maxObjects: cannot be negative",
+		},
+		{
+			filename:    "invalid_config56.yaml",
+			expectedErr: "metrics.pushgatewayURL: pushgateway:9091 is not a valid http(s) URL",
+		},
+		{
+			filename:    "invalid_config57.yaml",
+			expectedErr: "metrics.address: address localhost: missing port in address",
+		},
+		{
+			filename:    "invalid_config58.yaml",
+			expectedErr: "logger.format: yaml is not a valid format (text, json)",
+		},
+		{
+			filename:    "invalid_config59.yaml",
+			expectedErr: "logger.syslog.network: http is not a valid network (udp, tcp, unix)",
+		},
+		{
+			filename:    "invalid_config60.yaml",
+			expectedErr: "secrets.vault.address: vault.example.com:8200 is not a valid http(s) URL",
+		},
+		{
+			filename:    "invalid_config61.yaml",
+			expectedErr: "secrets.vault.kvVersion: must be 1 or 2. Is 3",
+		},
+		{
+			filename: "invalid_config62.yaml",
+			expectedErr: "testvmware.password: resolve env reference: " +
+				"environment variable NETBOX_SSOT_UNSET_PASSWORD is not set",
+		},
+		{
+			filename:    "invalid_config63.yaml",
+			expectedErr: "testvmware.password: vault references are not configured",
+		},
+		{
+			filename:    "invalid_config65.yaml",
+			expectedErr: "netbox.branching.timeout: cannot be negative",
+		},
+		{
+			filename:    "invalid_config66.yaml",
+			expectedErr: "netbox.fieldOwnership.dcim.device: owned and createOnly cannot be both set",
+		},
+		{
+			filename:    "invalid_config67.yaml",
+			expectedErr: "netbox.fieldSourcePriority.dcim.device.serial: testdnac doesn't exist in the sources array",
+		},
+		{
+			filename:    "invalid_config68.yaml",
+			expectedErr: "testreplay.dataFile: cannot be empty",
+		},
+		{
+			filename:    "invalid_config69.yaml",
+			expectedErr: "netbox.snapshot.maxAge: cannot be negative",
+		},
+		{
+			filename:    "invalid_config70.yaml",
+			expectedErr: "testovirt.watch: is not supported for source type ovirt",
+		},
+		{
+			filename:    "invalid_config71.yaml",
+			expectedErr: "notifications.slack.url: is not a valid http(s) URL",
+		},
+		{
+			filename:    "invalid_config72.yaml",
+			expectedErr: "notifications.teams.template: can only be set for webhook notifications",
+		},
+		{
+			filename: "invalid_config73.yaml",
+			expectedErr: "notifications.webhook.template: template: webhook:1: " +
+				"function \"summary\" not defined",
+		},
+		{
+			filename:    "invalid_config74.yaml",
+			expectedErr: "notifications.webhook.when: sometimes is not valid (always, failure, changes)",
+		},
+		{
+			filename: "invalid_config75.yaml",
+			expectedErr: "transforms[0].objectType: dcim.site is not valid " +
+				"([dcim.device dcim.platform virtualization.virtualmachine])",
+		},
+		{
+			filename:    "invalid_config76.yaml",
+			expectedErr: "transforms[0].drop: only devices and VMs can be dropped",
+		},
+		{
+			filename: "invalid_config77.yaml",
+			expectedErr: "transforms[0].field: serial can't be transformed for " +
+				"virtualization.virtualmachine ([name description comments])",
+		},
+		{
+			filename:    "invalid_config78.yaml",
+			expectedErr: "transforms[0].sources: source vcenter is not configured",
+		},
+		{filename: "invalid_config79.yaml", expectedErr: "transforms[0].replace: match must be set"},
+		{filename: "invalid_config80.yaml", expectedErr: "transforms[0].case: title is not valid (lower, upper)"},
+		{
+			filename:    "invalid_config81.yaml",
+			expectedErr: "transforms[0].match: invalid regex: error parsing regexp: missing closing ): `esx(`",
+		},
+		{
+			filename:    "invalid_config82.yaml",
+			expectedErr: "transforms[0].drop: can't be combined with other actions",
+		},
+		{
+			filename:    "invalid_config83.yaml",
+			expectedErr: "testf5.relationRules: is not supported for source type f5",
+		},
+		{
+			filename: "invalid_config84.yaml",
+			expectedErr: "testvmware.relationRules[0].match: unknown attribute owner at position 22 " +
+				"(attributes[\"key\"], cluster, datacenter, folder, host, ips, name, networks, tags, vid)",
+		},
+		{
+			filename:    "invalid_config85.yaml",
+			expectedErr: "testvmware.relationRules[0].location: objectType must be dcim.device",
+		},
+		{
+			filename: "invalid_config86.yaml",
+			expectedErr: "testvmware.relationRules[0]: " +
+				"at least one of site, tenant, role, location, vlanGroup or tags must be set",
+		},
+		{
+			filename: "invalid_config87.yaml",
+			expectedErr: "testvmware.relationRules[0].objectType: dcim.site is not valid " +
+				"(dcim.device, virtualization.virtualmachine, virtualization.cluster, ipam.vlan)",
+		},
+		{
+			filename:    "invalid_config88.yaml",
+			expectedErr: "netbox.pageConcurrency: must be at least 1",
+		},
+		{
+			filename:    "invalid_config89.yaml",
+			expectedErr: "testovirt.relationRules[0].role: objectType must be dcim.device or virtualization.virtualmachine",
+		},
+		{
+			filename:    "invalid_config90.yaml",
+			expectedErr: "testovirt.relationRules[0].vlanGroup: objectType must be ipam.vlan",
+		},
 		{
 			filename:    "invalid_config1111.yaml",
 			expectedErr: "open ../../testdata/parser/invalid_config1111.yaml: no such file or directory",
@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/expr"
+)
+
+// Attributes of objects, which can be used in expressions of relation rules.
+const (
+	RelationAttributeName       = "name"
+	RelationAttributeCluster    = "cluster"
+	RelationAttributeDatacenter = "datacenter"
+	RelationAttributeHost       = "host"
+	RelationAttributeFolder     = "folder"
+	RelationAttributeTags       = "tags"
+	RelationAttributeNetworks   = "networks"
+	RelationAttributeIPs        = "ips"
+	RelationAttributeVID        = "vid"
+	// RelationAttributeAttributes are custom attributes of the object in the source, indexed by their names.
+	RelationAttributeAttributes = "attributes"
+)
+
+// relationAttributes are names of attributes of relation rules. Indexed attributes map to true.
+var relationAttributes = map[string]bool{
+	RelationAttributeName:       false,
+	RelationAttributeCluster:    false,
+	RelationAttributeDatacenter: false,
+	RelationAttributeHost:       false,
+	RelationAttributeFolder:     false,
+	RelationAttributeTags:       false,
+	RelationAttributeNetworks:   false,
+	RelationAttributeIPs:        false,
+	RelationAttributeVID:        false,
+	RelationAttributeAttributes: true,
+}
+
+// RelationRule sets relations of hosts, VMs, clusters and VLANs of a source, whose attributes
+// match the expression of the rule. Relation rules take precedence over *Relations of the source.
+type RelationRule struct {
+	// Match is an expression of attributes of the object (see package expr),
+	// e.g. cluster == "Prod" && tags == "web".
+	Match string `yaml:"match"`
+	// ObjectType of matched objects: dcim.device (hosts), virtualization.virtualmachine,
+	// virtualization.cluster or ipam.vlan. If empty, both hosts and VMs are matched.
+	ObjectType constants.ContentType `yaml:"objectType"`
+	// Site, Tenant, Role, Location and VlanGroup are names of relations set to matching
+	// objects. Role can only be set for hosts and VMs, Location only for hosts, and
+	// VlanGroup only for VLANs.
+	Site      string `yaml:"site"`
+	Tenant    string `yaml:"tenant"`
+	Role      string `yaml:"role"`
+	Location  string `yaml:"location"`
+	VlanGroup string `yaml:"vlanGroup"`
+	// Tags added to matching objects.
+	Tags []string `yaml:"tags"`
+
+	// Expr is the compiled Match. It is set when the config is validated.
+	Expr *expr.Expr `yaml:"-"`
+}
+
+// Function that validates and compiles relation rules of the source.
+func validateRelationRules(source *SourceConfig, supported bool) []error {
+	if len(source.RelationRules) == 0 {
+		return nil
+	}
+	if !supported {
+		return []error{fmt.Errorf("%s.relationRules: is not supported for source type %s", source.Name, source.Type)}
+	}
+	var errs []error
+	for i := range source.RelationRules {
+		rule := &source.RelationRules[i]
+		field := fmt.Sprintf("%s.relationRules[%d]", source.Name, i)
+		compiled, err := expr.Compile(rule.Match, relationAttributes)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s.match: %s", field, err))
+		}
+		rule.Expr = compiled
+		switch rule.ObjectType {
+		case "", constants.ContentTypeDcimDevice, constants.ContentTypeVirtualizationVirtualMachine:
+		case constants.ContentTypeVirtualizationCluster, constants.ContentTypeIpamVlan:
+			if rule.Role != "" {
+				errs = append(errs, fmt.Errorf(
+					"%s.role: objectType must be %s or %s",
+					field, constants.ContentTypeDcimDevice, constants.ContentTypeVirtualizationVirtualMachine,
+				))
+			}
+		default:
+			errs = append(errs, fmt.Errorf(
+				"%s.objectType: %s is not valid (%s, %s, %s, %s)",
+				field,
+				rule.ObjectType,
+				constants.ContentTypeDcimDevice,
+				constants.ContentTypeVirtualizationVirtualMachine,
+				constants.ContentTypeVirtualizationCluster,
+				constants.ContentTypeIpamVlan,
+			))
+		}
+		if rule.Location != "" && rule.ObjectType != constants.ContentTypeDcimDevice {
+			errs = append(errs, fmt.Errorf("%s.location: objectType must be %s", field, constants.ContentTypeDcimDevice))
+		}
+		if rule.VlanGroup != "" && rule.ObjectType != constants.ContentTypeIpamVlan {
+			errs = append(errs, fmt.Errorf("%s.vlanGroup: objectType must be %s", field, constants.ContentTypeIpamVlan))
+		}
+		if rule.Site == "" && rule.Tenant == "" && rule.Role == "" && rule.Location == "" && rule.VlanGroup == "" &&
+			len(rule.Tags) == 0 {
+			errs = append(errs, fmt.Errorf(
+				"%s: at least one of site, tenant, role, location, vlanGroup or tags must be set", field,
+			))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,186 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/logger"
+)
+
+// Schema is a JSON Schema.
+type Schema = map[string]any
+
+// Keys of reusable definitions in the JSON Schema.
+const (
+	schemaSourceOptionsDef = "sourceOptions"
+	schemaRelationsDef     = "relations"
+)
+
+var (
+	nonNegativeInteger = Schema{"type": "integer", "minimum": 0}
+	portSchema         = Schema{"type": "integer", "minimum": 0, "maximum": 65535}
+	httpSchemeSchema   = Schema{"type": "string", "enum": []any{string(HTTP), string(HTTPS)}}
+	thresholdSchema    = Schema{
+		"type": "object",
+		"properties": Schema{
+			"maxObjects": nonNegativeInteger,
+			"maxPercent": Schema{"type": "number", "minimum": 0, "maximum": 100},
+		},
+		"additionalProperties": false,
+	}
+)
+
+// schemaOverrides are schemas of options (identified by their path in the config),
+// which are more specific than the schema generated from their go type.
+var schemaOverrides = map[string]Schema{
+	"include": {
+		"description": "Config files, directories with config files or glob patterns, which are merged into the config.",
+		"oneOf": []any{
+			Schema{"type": "string"},
+			Schema{"type": "array", "items": Schema{"type": "string"}},
+		},
+	},
+	"logger.level": {
+		"oneOf": []any{
+			Schema{"type": "integer", "minimum": 0, "maximum": 3},
+			Schema{"type": "string", "enum": []any{
+				"debug", "DEBUG", "Debug",
+				"info", "INFO", "Info",
+				"warn", "WARN", "Warn", "warning", "WARNING", "Warning",
+				"error", "ERROR", "Error",
+			}},
+		},
+	},
+	"logger.format":            {"type": "string", "enum": []any{logger.FormatText, logger.FormatJSON}},
+	"logger.syslog.network":    {"type": "string", "enum": []any{"", "udp", "tcp", "unix"}},
+	"logger.maxSize":           nonNegativeInteger,
+	"logger.maxBackups":        nonNegativeInteger,
+	"netbox.httpScheme":        httpSchemeSchema,
+	"netbox.port":              portSchema,
+	"netbox.timeout":           nonNegativeInteger,
+	"netbox.maxRetries":        nonNegativeInteger,
+	"netbox.requestsPerSecond": {"type": "number", "minimum": 0},
+	"netbox.initConcurrency":   {"type": "integer", "minimum": 1},
+	"netbox.pageConcurrency":   {"type": "integer", "minimum": 1},
+	"netbox.bulkSize":          {"type": "integer", "minimum": 1},
+	"netbox.tagColor":          {"type": "string", "pattern": "^[0-9a-f]{6}$"},
+	"netbox.deletionThreshold": thresholdSchema,
+	"netbox.branching.timeout": nonNegativeInteger,
+	"secrets.vault.kvVersion":  {"type": "integer", "enum": []any{1, 2}},
+	"source.httpScheme":        httpSchemeSchema,
+	"source.port":              portSchema,
+	"source.syncTimeout":       nonNegativeInteger,
+	"source.deletionThreshold": thresholdSchema,
+	"notifications.type": {"type": "string", "enum": []any{
+		string(NotificationWebhook), string(NotificationSlack), string(NotificationTeams),
+	}},
+	"notifications.when": {"type": "string", "enum": []any{
+		string(NotifyAlways), string(NotifyFailure), string(NotifyChanges),
+	}},
+	"transforms.objectType": {"type": "string", "enum": []any{
+		string(constants.ContentTypeDcimDevice),
+		string(constants.ContentTypeVirtualizationVirtualMachine),
+		string(constants.ContentTypeDcimPlatform),
+	}},
+	"transforms.case": {"type": "string", "enum": []any{CaseLower, CaseUpper}},
+	"source.relationRules.objectType": {"type": "string", "enum": []any{
+		string(constants.ContentTypeDcimDevice),
+		string(constants.ContentTypeVirtualizationVirtualMachine),
+		string(constants.ContentTypeVirtualizationCluster),
+		string(constants.ContentTypeIpamVlan),
+	}},
+	"netbox.objectTypeDeletionThresholds": {
+		"type":                 "object",
+		"additionalProperties": thresholdSchema,
+	},
+}
+
+// JSONSchema returns JSON Schema of the config file. Source types are
+// generated from the same definitions, which are used to validate sources.
+func JSONSchema() Schema {
+	schema := schemaOf(reflect.TypeOf(Config{}), "")
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "netbox-ssot configuration"
+
+	properties := schema["properties"].(Schema)
+	properties["include"] = schemaOverrides["include"]
+	properties[sourceDefaultsKey] = Schema{
+		"description": "Options inherited by all sources, which don't set them.",
+		"$ref":        "#/$defs/" + schemaSourceOptionsDef,
+	}
+	properties[sourcesKey] = Schema{"type": "array", "items": sourceSchema()}
+	schema["$defs"] = Schema{
+		schemaSourceOptionsDef: schemaOf(reflect.TypeOf(SourceConfig{}), sourcesKey),
+		schemaRelationsDef: Schema{
+			"type":  "array",
+			"items": Schema{"type": "string", "pattern": "^[^=]+=[^=]+$"},
+		},
+	}
+
+	netbox := properties["netbox"].(Schema)
+	netbox["required"] = []any{"apiToken", "hostname"}
+	return schema
+}
+
+// sourceSchema returns schema of a source. Only the name of the source is required,
+// as all other options (including the ones required by its type) can be inherited
+// from sourceDefaults, possibly set in another file. Required options are checked
+// on the merged config by netbox-ssot validate.
+func sourceSchema() Schema {
+	var sourceTypes []any
+	for _, definition := range sourceTypeDefinitions {
+		sourceTypes = append(sourceTypes, string(definition.Type))
+	}
+	return Schema{
+		"$ref":     "#/$defs/" + schemaSourceOptionsDef,
+		"required": []any{"name"},
+		"properties": Schema{
+			"type": Schema{"enum": sourceTypes},
+		},
+	}
+}
+
+// schemaOf generates schema of the go type t of option at path in the config.
+func schemaOf(t reflect.Type, path string) Schema {
+	if override, ok := schemaOverrides[path]; ok {
+		return override
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaOf(t.Elem(), path)
+	case reflect.Struct:
+		properties := Schema{}
+		for i := range t.NumField() {
+			field := t.Field(i)
+			key := yamlKey(field)
+			if key == "" {
+				continue
+			}
+			fieldPath := key
+			if path != "" {
+				fieldPath = path + "." + key
+			}
+			properties[key] = schemaOf(field.Type, fieldPath)
+		}
+		return Schema{"type": "object", "properties": properties, "additionalProperties": false}
+	case reflect.Slice:
+		return Schema{"type": "array", "items": schemaOf(t.Elem(), path)}
+	case reflect.Map:
+		if strings.HasPrefix(path, sourcesKey+".") && t.Key().Kind() == reflect.String &&
+			t.Elem().Kind() == reflect.String {
+			// Relations of sources are lists of "regex = value" strings
+			return Schema{"$ref": "#/$defs/" + schemaRelationsDef}
+		}
+		return Schema{"type": "object", "additionalProperties": schemaOf(t.Elem(), path)}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	default:
+		return Schema{"type": "string"}
+	}
+}
@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSourceTypeDefinitions(t *testing.T) {
+	for sourceType := range constants.SourceTagColorMap {
+		if _, ok := getSourceTypeDefinition(sourceType); !ok {
+			t.Errorf("source type %s has no definition", sourceType)
+		}
+	}
+	for _, definition := range sourceTypeDefinitions {
+		options := definition.Required
+		for _, oneOf := range definition.RequiredOneOf {
+			options = append(options, oneOf...)
+		}
+		for _, option := range options {
+			if !hasSourceOption(option) {
+				t.Errorf("%s requires unknown option %s", definition.Type, option)
+			}
+		}
+	}
+}
+
+func hasSourceOption(option string) bool {
+	sourceType := reflect.TypeOf(SourceConfig{})
+	for i := range sourceType.NumField() {
+		if yamlKey(sourceType.Field(i)) == option {
+			return true
+		}
+	}
+	return false
+}
+
+func TestJSONSchema_SourceRequirements(t *testing.T) {
+	// Round trip through JSON, as the schema is used by other tools
+	content, err := json.Marshal(JSONSchema())
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %s", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(content, &schema); err != nil {
+		t.Fatalf("json.Unmarshal() error = %s", err)
+	}
+	source := schema["properties"].(map[string]any)["source"].(map[string]any)["items"].(map[string]any)
+
+	// Other options can be inherited from sourceDefaults
+	if got, _ := json.Marshal(source["required"]); string(got) != `["name"]` {
+		t.Errorf("required options of sources = %s, want [\"name\"]", got)
+	}
+	if _, ok := source["allOf"]; ok {
+		t.Errorf("sources have requirements of source types: %v", source["allOf"])
+	}
+	sourceTypes := source["properties"].(map[string]any)["type"].(map[string]any)["enum"].([]any)
+	if len(sourceTypes) != len(sourceTypeDefinitions) {
+		t.Errorf("schema has %d source types, want %d", len(sourceTypes), len(sourceTypeDefinitions))
+	}
+}
+
+// unknownKeys returns paths of keys in value, which aren't allowed by schema.
+func unknownKeys(root Schema, schema Schema, value any, path string) []string {
+	var unknown []string
+	if ref, ok := schema["$ref"].(string); ok {
+		definition := root["$defs"].(Schema)[strings.TrimPrefix(ref, "#/$defs/")].(Schema)
+		unknown = unknownKeys(root, definition, value, path)
+		if _, ok := schema["properties"]; !ok {
+			return unknown
+		}
+	}
+	switch value := value.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(Schema)
+		for key, item := range value {
+			keyPath := fmt.Sprintf("%s.%s", path, key)
+			if propertySchema, ok := properties[key].(Schema); ok {
+				unknown = append(unknown, unknownKeys(root, propertySchema, item, keyPath)...)
+			} else if schema["additionalProperties"] == false {
+				unknown = append(unknown, keyPath)
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(Schema); ok {
+			for i, item := range value {
+				unknown = append(unknown, unknownKeys(root, items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return unknown
+}
+
+func TestJSONSchema_ValidConfigs(t *testing.T) {
+	schema := JSONSchema()
+	filenames, err := filepath.Glob("../../testdata/parser/valid_config*.yaml")
+	if err != nil || len(filenames) == 0 {
+		t.Fatalf("no valid configs found: %v", err)
+	}
+	filenames = append(filenames, "../../testdata/parser/include/config.yaml")
+	for _, filename := range filenames {
+		t.Run(filepath.Base(filename), func(t *testing.T) {
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var config map[string]any
+			if err := yaml.Unmarshal(content, &config); err != nil {
+				t.Fatal(err)
+			}
+			if unknown := unknownKeys(schema, schema, config, ""); len(unknown) > 0 {
+				t.Errorf("options %v are not in the schema", unknown)
+			}
+		})
+	}
+}
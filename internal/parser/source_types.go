@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+)
+
+// sourceTypeDefinition defines options required by a source type.
+// Definitions are used both to validate sources and to generate
+// the JSON Schema of the config.
+type sourceTypeDefinition struct {
+	Type constants.SourceType
+	// Required are options, which must be set.
+	Required []string
+	// RequiredOneOf are groups of options, of which at least one must be set.
+	RequiredOneOf [][]string
+	// Watch is true, if the source type can be watched for changes (see SourceConfig.Watch).
+	Watch bool
+	// RelationRules is true, if the source type supports relation rules (see SourceConfig.RelationRules).
+	RelationRules bool
+}
+
+// Options required by sources, which authenticate with username and password.
+var credentialOptions = []string{"hostname", "username", "password"}
+
+// sourceTypeDefinitions are definitions of all supported source types.
+var sourceTypeDefinitions = []sourceTypeDefinition{
+	{Type: constants.Ovirt, Required: credentialOptions, RelationRules: true},
+	{Type: constants.Vmware, Required: credentialOptions, Watch: true, RelationRules: true},
+	{Type: constants.Dnac, Required: credentialOptions, RelationRules: true},
+	{Type: constants.Proxmox, Required: credentialOptions, RelationRules: true},
+	{Type: constants.PaloAlto, Required: credentialOptions, RelationRules: true},
+	{Type: constants.Fortigate, Required: []string{"hostname", "apiToken"}, RelationRules: true},
+	{Type: constants.FMC, Required: credentialOptions, RelationRules: true},
+	{Type: constants.IOSXE, Required: credentialOptions, RelationRules: true},
+	{Type: constants.F5, Required: credentialOptions},
+	{Type: constants.HetznerCloud, Required: []string{"apiToken"}},
+	{
+		Type:     constants.OpenStack,
+		Required: credentialOptions,
+		RequiredOneOf: [][]string{
+			{"projectName", "tenantName", "projectID", "tenantID"},
+			{"domainName", "domainID"},
+		},
+	},
+	// Replayed source type is known only when the data file is read
+	{Type: constants.Replay, Required: []string{"dataFile"}, RelationRules: true},
+}
+
+// getSourceTypeDefinition returns definition of the source type,
+// or false if the source type is not supported.
+func getSourceTypeDefinition(sourceType constants.SourceType) (sourceTypeDefinition, bool) {
+	for _, definition := range sourceTypeDefinitions {
+		if definition.Type == sourceType {
+			return definition, true
+		}
+	}
+	return sourceTypeDefinition{}, false
+}
+
+// isSourceOptionSet returns true if option (yaml key) of the source is set to a non zero value.
+func isSourceOptionSet(source *SourceConfig, option string) bool {
+	value := reflect.ValueOf(source).Elem()
+	for i := range value.NumField() {
+		if yamlKey(value.Type().Field(i)) == option {
+			return !value.Field(i).IsZero()
+		}
+	}
+	return false
+}
+
+// yamlKey returns key of the struct field in yaml, or empty string if the field isn't decoded from yaml.
+func yamlKey(field reflect.StructField) string {
+	key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if key == "-" || !field.IsExported() {
+		return ""
+	}
+	return key
+}
+
+// joinOptions joins options into a list in the form "a, b or c".
+func joinOptions(options []string) string {
+	if len(options) < 2 { //nolint:mnd
+		return strings.Join(options, "")
+	}
+	return strings.Join(options[:len(options)-1], ", ") + " or " + options[len(options)-1]
+}
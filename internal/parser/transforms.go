@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+)
+
+// Cases, to which transform rules convert fields.
+const (
+	CaseLower = "lower"
+	CaseUpper = "upper"
+)
+
+// TransformFields are fields (as named in the Netbox API), which can be transformed, by object type.
+var TransformFields = map[constants.ContentType][]string{
+	constants.ContentTypeDcimDevice:                   {"name", "serial", "asset_tag", "description"},
+	constants.ContentTypeVirtualizationVirtualMachine: {"name", "description", "comments"},
+	constants.ContentTypeDcimPlatform:                 {"name", "description"},
+}
+
+// Transform rule, which rewrites a field of objects from sources, before they are
+// added to the inventory. Actions of the rule are applied in the order: drop,
+// replace, map, trimSuffix, appendSuffix and case.
+type TransformRule struct {
+	// ObjectType of transformed objects (dcim.device, virtualization.virtualmachine or dcim.platform).
+	ObjectType constants.ContentType `yaml:"objectType"`
+	// Sources, whose objects are transformed. If empty, objects of all sources are transformed.
+	Sources []string `yaml:"sources"`
+	// Field, which is transformed (default name).
+	Field string `yaml:"field"`
+	// Match is a regex, which must match the field, so the rule is applied.
+	// If empty, the rule is applied to all objects.
+	Match string `yaml:"match"`
+	// Drop drops matching objects, so they are not synced. Only devices and VMs can be dropped.
+	Drop bool `yaml:"drop"`
+	// Replace replaces all matches of Match in the field. It can reference groups of Match (e.g. $1).
+	Replace *string `yaml:"replace"`
+	// Map replaces values of the field, which equal its keys.
+	Map map[string]string `yaml:"map"`
+	// TrimSuffix is removed from the end of the field (e.g. a domain).
+	TrimSuffix string `yaml:"trimSuffix"`
+	// AppendSuffix is appended to the field, if it doesn't end with it yet.
+	AppendSuffix string `yaml:"appendSuffix"`
+	// Case to which the field is converted: lower or upper.
+	Case string `yaml:"case"`
+}
+
+// Function that validates transform rules. Default field is set here.
+func validateTransforms(config *Config) []error {
+	var errs []error
+	sourceNames := make(map[string]bool, len(config.Sources))
+	for _, source := range config.Sources {
+		sourceNames[source.Name] = true
+	}
+	for i := range config.Transforms {
+		rule := &config.Transforms[i]
+		field := fmt.Sprintf("transforms[%d]", i)
+		fields, ok := TransformFields[rule.ObjectType]
+		if !ok {
+			errs = append(errs, fmt.Errorf(
+				"%s.objectType: %s is not valid (%v)", field, rule.ObjectType, slices.Sorted(maps.Keys(TransformFields)),
+			))
+			continue
+		}
+		if rule.Field == "" {
+			rule.Field = "name"
+		}
+		if !slices.Contains(fields, rule.Field) {
+			errs = append(errs, fmt.Errorf(
+				"%s.field: %s can't be transformed for %s (%v)", field, rule.Field, rule.ObjectType, fields,
+			))
+		}
+		for _, sourceName := range rule.Sources {
+			if !sourceNames[sourceName] {
+				errs = append(errs, fmt.Errorf("%s.sources: source %s is not configured", field, sourceName))
+			}
+		}
+		if _, err := regexp.Compile(rule.Match); err != nil {
+			errs = append(errs, fmt.Errorf("%s.match: invalid regex: %s", field, err))
+		}
+		errs = append(errs, validateTransformActions(field, rule)...)
+	}
+	return errs
+}
+
+func validateTransformActions(field string, rule *TransformRule) []error {
+	var errs []error
+	hasAction := rule.Replace != nil || len(rule.Map) > 0 || rule.TrimSuffix != "" ||
+		rule.AppendSuffix != "" || rule.Case != ""
+	switch {
+	case rule.Drop && hasAction:
+		errs = append(errs, fmt.Errorf("%s.drop: can't be combined with other actions", field))
+	case rule.Drop && rule.ObjectType == constants.ContentTypeDcimPlatform:
+		errs = append(errs, fmt.Errorf("%s.drop: only devices and VMs can be dropped", field))
+	case !rule.Drop && !hasAction:
+		errs = append(errs, fmt.Errorf(
+			"%s: at least one action (drop, replace, map, trimSuffix, appendSuffix, case) must be set", field,
+		))
+	}
+	if rule.Replace != nil && rule.Match == "" {
+		errs = append(errs, fmt.Errorf("%s.replace: match must be set", field))
+	}
+	if rule.Case != "" && rule.Case != CaseLower && rule.Case != CaseUpper {
+		errs = append(errs, fmt.Errorf("%s.case: %s is not valid (lower, upper)", field, rule.Case))
+	}
+	return errs
+}
@@ -0,0 +1,232 @@
+// Package report records changes made to Netbox during a run, and writes
+// them as a machine-readable (JSON or CSV) report.
+package report
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+)
+
+// Action is a type of change made to an object.
+type Action string
+
+const (
+	ActionCreate     Action = "create"
+	ActionUpdate     Action = "update"
+	ActionDelete     Action = "delete"
+	ActionSoftDelete Action = "soft_delete"
+)
+
+// actionCtxKey is the context key used to override action of a change.
+type actionCtxKey struct{}
+
+// WithAction returns a context which marks all updates made with it
+// as the given action. It is used for soft deletions, which are
+// performed as updates of the object.
+func WithAction(ctx context.Context, action Action) context.Context {
+	return context.WithValue(ctx, actionCtxKey{}, action)
+}
+
+// actionFromContext returns the action stored in ctx or defaultAction.
+func actionFromContext(ctx context.Context, defaultAction Action) Action {
+	if action, ok := ctx.Value(actionCtxKey{}).(Action); ok {
+		return action
+	}
+	return defaultAction
+}
+
+// Change is a single change of a Netbox object.
+type Change struct {
+	Time   time.Time `json:"time"`
+	Action Action    `json:"action"`
+	// ObjectType is name of the object's type, e.g. Device.
+	ObjectType string            `json:"object_type"`
+	APIPath    constants.APIPath `json:"api_path"`
+	// ID of the object in Netbox. In dry-run mode created objects have fake IDs.
+	ID int `json:"id"`
+	// Source is name of the source which made the change.
+	Source string `json:"source"`
+	// Diff contains changed fields. For created objects it contains all
+	// fields of the object, for deleted objects it is empty.
+	Diff map[string]interface{} `json:"diff,omitempty"`
+}
+
+// Recorder records changes made to Netbox. It is safe for concurrent use.
+// Nil recorder doesn't record anything.
+type Recorder struct {
+	lock    sync.Mutex
+	changes []Change
+}
+
+// NewRecorder creates a new empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Record records a change of the object. Action of updates can be
+// overridden with WithAction. Source of the change is taken from ctx.
+func (r *Recorder) Record(
+	ctx context.Context,
+	action Action,
+	objectType reflect.Type,
+	apiPath constants.APIPath,
+	id int,
+	diff map[string]interface{},
+) {
+	if r == nil {
+		return
+	}
+	if action == ActionUpdate {
+		action = actionFromContext(ctx, action)
+	}
+	source, _ := ctx.Value(constants.CtxSourceKey).(string)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.changes = append(r.changes, Change{
+		Time:       time.Now(),
+		Action:     action,
+		ObjectType: TypeName(objectType),
+		APIPath:    apiPath,
+		ID:         id,
+		Source:     source,
+		Diff:       diff,
+	})
+}
+
+// Changes returns a copy of all recorded changes.
+func (r *Recorder) Changes() []Change {
+	if r == nil {
+		return nil
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return slices.Clone(r.changes)
+}
+
+// Reset removes all recorded changes.
+func (r *Recorder) Reset() {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.changes = nil
+}
+
+// TypeName returns name of the type without package and pointer, e.g. Device.
+func TypeName(objectType reflect.Type) string {
+	if objectType == nil {
+		return ""
+	}
+	for objectType.Kind() == reflect.Pointer {
+		objectType = objectType.Elem()
+	}
+	return objectType.Name()
+}
+
+// Report is a report of all changes made during a single run.
+type Report struct {
+	RunID     int       `json:"run_id"`
+	Trigger   string    `json:"trigger"`
+	DryRun    bool      `json:"dry_run"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Status    string    `json:"status"`
+	Sources   []string  `json:"sources"`
+	// Summary is number of changes for each action.
+	Summary map[Action]int `json:"summary"`
+	Changes []Change       `json:"changes"`
+}
+
+// NewReport creates a report with the given changes, and computes their summary.
+func NewReport(changes []Change) *Report {
+	report := &Report{
+		Summary: map[Action]int{
+			ActionCreate:     0,
+			ActionUpdate:     0,
+			ActionDelete:     0,
+			ActionSoftDelete: 0,
+		},
+		Changes: changes,
+	}
+	if report.Changes == nil {
+		report.Changes = []Change{}
+	}
+	for _, change := range changes {
+		report.Summary[change.Action]++
+	}
+	return report
+}
+
+// WriteJSON writes the report in JSON format.
+func (report *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// WriteCSV writes changes of the report in CSV format, one change per line.
+// Diff is written as a JSON object.
+func (report *Report) WriteCSV(w io.Writer) error {
+	csvWriter := csv.NewWriter(w)
+	err := csvWriter.Write([]string{"time", "action", "object_type", "api_path", "id", "source", "diff"})
+	if err != nil {
+		return err
+	}
+	for _, change := range report.Changes {
+		diff := ""
+		if len(change.Diff) > 0 {
+			diffJSON, err := json.Marshal(change.Diff)
+			if err != nil {
+				return fmt.Errorf("marshal diff: %s", err)
+			}
+			diff = string(diffJSON)
+		}
+		err := csvWriter.Write([]string{
+			change.Time.Format(time.RFC3339),
+			string(change.Action),
+			change.ObjectType,
+			string(change.APIPath),
+			strconv.Itoa(change.ID),
+			change.Source,
+			diff,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// WriteFile writes the report to the file at path. If path has .csv
+// extension the report is written in CSV format, otherwise in JSON.
+func (report *Report) WriteFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create report file: %s", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		err = report.WriteCSV(file)
+	} else {
+		err = report.WriteJSON(file)
+	}
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("write report: %s", err)
+	}
+	return file.Close()
+}
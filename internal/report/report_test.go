@@ -0,0 +1,159 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+)
+
+var testTime = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+func testChanges() []Change {
+	return []Change{
+		{
+			Time:       testTime,
+			Action:     ActionCreate,
+			ObjectType: "Device",
+			APIPath:    constants.DevicesAPIPath,
+			ID:         1,
+			Source:     "vmware",
+			Diff:       map[string]interface{}{"name": "server01"},
+		},
+		{
+			Time:       testTime,
+			Action:     ActionDelete,
+			ObjectType: "Tag",
+			APIPath:    constants.TagsAPIPath,
+			ID:         2,
+			Source:     "orphanManager",
+		},
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	ctx := context.WithValue(context.Background(), constants.CtxSourceKey, "vmware")
+	recorder := NewRecorder()
+	recorder.Record(ctx, ActionCreate, reflect.TypeOf(&objects.Device{}), constants.DevicesAPIPath, 1, nil)
+	recorder.Record(
+		WithAction(ctx, ActionSoftDelete),
+		ActionUpdate,
+		reflect.TypeOf(objects.VM{}),
+		constants.VirtualMachinesAPIPath,
+		2,
+		nil,
+	)
+	// Only updates can be overridden with WithAction
+	recorder.Record(WithAction(ctx, ActionSoftDelete), ActionDelete, nil, constants.TagsAPIPath, 3, nil)
+
+	changes := recorder.Changes()
+	want := []struct {
+		action     Action
+		objectType string
+	}{
+		{ActionCreate, "Device"},
+		{ActionSoftDelete, "VM"},
+		{ActionDelete, ""},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("Changes() returned %d changes, want %d", len(changes), len(want))
+	}
+	for i, change := range changes {
+		if change.Action != want[i].action || change.ObjectType != want[i].objectType {
+			t.Errorf("change %d = %s %s, want %s %s", i, change.Action, change.ObjectType, want[i].action, want[i].objectType)
+		}
+		if change.Source != "vmware" {
+			t.Errorf("change %d source = %s, want vmware", i, change.Source)
+		}
+	}
+
+	recorder.Reset()
+	if len(recorder.Changes()) != 0 {
+		t.Errorf("Changes() after Reset() is not empty")
+	}
+
+	var nilRecorder *Recorder
+	nilRecorder.Record(ctx, ActionCreate, nil, constants.TagsAPIPath, 1, nil)
+	if nilRecorder.Changes() != nil {
+		t.Errorf("nil recorder recorded a change")
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	got := NewReport(testChanges()).Summary
+	want := map[Action]int{ActionCreate: 1, ActionUpdate: 0, ActionDelete: 1, ActionSoftDelete: 0}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewReport() summary = %v, want %v", got, want)
+	}
+	if changes := NewReport(nil).Changes; changes == nil {
+		t.Errorf("NewReport(nil) changes are nil, want empty slice")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewReport(testChanges()).WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() error = %s", err)
+	}
+	want := `time,action,object_type,api_path,id,source,diff
+2024-01-01T10:00:00Z,create,Device,/api/dcim/devices/,1,vmware,"{""name"":""server01""}"
+2024-01-01T10:00:00Z,delete,Tag,/api/extras/tags/,2,orphanManager,
+`
+	if buf.String() != want {
+		t.Errorf("WriteCSV() = %s, want %s", buf.String(), want)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		wantCSV  bool
+	}{
+		{
+			name:     "JSON report",
+			fileName: "report.json",
+		},
+		{
+			name:     "CSV report",
+			fileName: "report.CSV",
+			wantCSV:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.fileName)
+			changesReport := NewReport(testChanges())
+			changesReport.RunID = 3
+			changesReport.DryRun = true
+			if err := changesReport.WriteFile(path); err != nil {
+				t.Fatalf("WriteFile() error = %s", err)
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantCSV {
+				if !strings.HasPrefix(string(content), "time,action,") {
+					t.Errorf("WriteFile() content is not CSV: %s", content)
+				}
+				return
+			}
+			var got Report
+			if err := json.Unmarshal(content, &got); err != nil {
+				t.Fatalf("WriteFile() content is not JSON: %s", err)
+			}
+			if got.RunID != 3 || !got.DryRun || len(got.Changes) != 2 || got.Summary[ActionCreate] != 1 {
+				t.Errorf("WriteFile() wrote %+v", got)
+			}
+		})
+	}
+}
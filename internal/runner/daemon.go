@@ -0,0 +1,115 @@
+package runner
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+	"github.com/bl4ko/netbox-ssot/internal/scheduler"
+)
+
+// Daemon runs synchronization repeatedly according to the schedule, until ctx
+// is canceled. The first run starts immediately. Each scheduled run uses
+// source selection from opts. Each value received on the reload channel
+// re-reads the configuration file, which is used from the next run on.
+// Sources with the watch option are watched from their first run on, and
+// their changes are synced between the scheduled runs with SyncChanges.
+// Changes collected within watchDebounce are synced together.
+//
+// When ctx is canceled during a run, the run is finished before Daemon returns.
+func (r *Runner) Daemon(
+	ctx context.Context,
+	schedule scheduler.Schedule,
+	opts RunOptions,
+	reload <-chan struct{},
+) error {
+	if _, err := r.selectSources(opts.Sources, opts.SkipSources); err != nil {
+		return err
+	}
+	opts.Trigger = TriggerSchedule
+	r.Logger.Infof(r.Ctx, "Starting netbox-ssot daemon with schedule %s", schedule)
+	changes := r.startWatching(ctx)
+	defer r.stopWatching()
+	// syncChanges fires after watchDebounce since the first of the collected changes
+	var syncChanges <-chan time.Time
+	nextRun := time.Now()
+	for {
+		timer := time.NewTimer(time.Until(nextRun))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			r.Logger.Info(r.Ctx, "Received shutdown signal, stopping daemon...")
+			return nil
+		case <-reload:
+			timer.Stop()
+			r.Reload()
+			// Watched sources are created again by the next run, with the reloaded configuration
+			r.stopWatching()
+			changes = r.startWatching(ctx)
+			syncChanges = nil
+			continue
+		case <-changes:
+			timer.Stop()
+			if syncChanges == nil {
+				syncChanges = time.After(watchDebounce)
+			}
+			continue
+		case <-syncChanges:
+			timer.Stop()
+			syncChanges = nil
+			if result := r.SyncChanges(ctx); result != nil && !result.Successful() {
+				r.Logger.Warningf(r.Ctx, "%s Syncing of changes finished with errors", constants.WarningSign)
+			}
+			continue
+		case <-timer.C:
+		}
+
+		result := r.Run(ctx, opts)
+		if !result.Successful() {
+			r.Logger.Warningf(r.Ctx, "%s Run finished with errors", constants.WarningSign)
+		}
+
+		nextRun = schedule.Next(time.Now())
+		if nextRun.IsZero() {
+			r.Logger.Warning(r.Ctx, "Schedule has no further activations, stopping daemon...")
+			return nil
+		}
+		r.Logger.Infof(r.Ctx, "Next run scheduled at %s", nextRun.Format(time.RFC3339))
+	}
+}
+
+// Reload re-reads the configuration file. On success the new configuration is
+// used from the next run on. If the netbox configuration has changed, the
+// inventory is recreated from scratch on the next run. On failure the current
+// configuration is kept.
+func (r *Runner) Reload() {
+	r.Logger.Infof(r.Ctx, "Reloading configuration from %s", r.ConfigPath)
+	config, err := parser.ParseConfig(r.ConfigPath)
+	if err != nil {
+		r.Logger.Errorf(r.Ctx, "reload config: %s. Keeping the current configuration", err)
+		return
+	}
+
+	r.runLock.Lock()
+	defer r.runLock.Unlock()
+	if !reflect.DeepEqual(config.Netbox, r.Config.Netbox) {
+		r.Logger.Info(r.Ctx, "Netbox configuration has changed, inventory will be recreated on the next run")
+		r.Inventory = nil
+	}
+	if !reflect.DeepEqual(config.Logger, r.Config.Logger) {
+		r.Logger.Warning(r.Ctx, "Logger configuration changes are applied only after restart")
+	}
+	if !reflect.DeepEqual(config.API, r.Config.API) {
+		r.Logger.Warning(r.Ctx, "API configuration changes are applied only after restart")
+	}
+	if oldMetrics := r.Config.Metrics; oldMetrics != nil &&
+		(config.Metrics.Enabled() != oldMetrics.Enabled() || config.Metrics.Address != oldMetrics.Address) {
+		r.Logger.Warning(r.Ctx, "Enabling or disabling metrics and metrics.address changes are applied only after restart")
+	}
+	r.stateLock.Lock()
+	r.Config = config
+	r.stateLock.Unlock()
+	r.Logger.Infof(r.Ctx, "%s Successfully reloaded configuration", constants.CheckMark)
+}
@@ -0,0 +1,938 @@
+// Package runner orchestrates synchronization runs. A run initializes the
+// netbox inventory, syncs all configured sources in parallel and removes
+// orphaned objects. The same runner can be used for a single run, or
+// repeatedly by the long-running daemon.
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/logger"
+	"github.com/bl4ko/netbox-ssot/internal/metrics"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/service"
+	"github.com/bl4ko/netbox-ssot/internal/notify"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+	"github.com/bl4ko/netbox-ssot/internal/report"
+	"github.com/bl4ko/netbox-ssot/internal/source/common"
+)
+
+// Number of finished runs kept in the runner's history.
+const maxHistory = 50
+
+var (
+	// ErrRunInProgress is returned when a run is requested while another one is in progress.
+	ErrRunInProgress = errors.New("another run is already in progress")
+	// ErrRunNotFound is returned when a run with the given ID doesn't exist.
+	ErrRunNotFound = errors.New("run not found")
+	// ErrRunNotInProgress is returned when canceling a run that has already finished.
+	ErrRunNotInProgress = errors.New("run is not in progress")
+)
+
+// Triggers of a run.
+const (
+	TriggerCLI      = "cli"
+	TriggerSchedule = "schedule"
+	TriggerAPI      = "api"
+	// Runs triggered by watched sources sync only the collected changes.
+	TriggerWatch = "watch"
+)
+
+// RunStatus is the status of a run.
+type RunStatus string
+
+const (
+	RunStatusRunning   RunStatus = "running"
+	RunStatusSucceeded RunStatus = "succeeded"
+	RunStatusFailed    RunStatus = "failed"
+	RunStatusCanceled  RunStatus = "canceled"
+)
+
+// RunOptions control a single synchronization run.
+type RunOptions struct {
+	// Trigger describes what started the run (e.g. cli, schedule, api).
+	Trigger string
+	// Sources are names of the sources to sync. If empty, all sources are synced.
+	Sources []string
+	// SkipSources are names of the sources that are excluded from the run.
+	SkipSources []string
+}
+
+// Result holds the outcome of a single synchronization run.
+type Result struct {
+	// ID of the run, unique for the lifetime of the runner.
+	ID      int
+	Trigger string
+	// Sources are names of all sources included in this run.
+	Sources   []string
+	StartTime time.Time
+	// EndTime is zero while the run is in progress.
+	EndTime  time.Time
+	Canceled bool
+	// Err is set when the run failed outside of any source
+	// (e.g. netbox inventory couldn't be initialized).
+	Err error
+	// SourceErrors maps names of the failed sources to the encountered errors.
+	SourceErrors map[string]error
+	// Branch is the name of the NetBox branch, in which changes of the run
+	// are staged. It is empty if branching is disabled.
+	Branch string
+	// BranchMerged is true if the branch was merged into main.
+	BranchMerged bool
+	// objectTypes are API paths of object types refreshed by runs of
+	// watched sources. Other runs refresh all object types.
+	objectTypes []constants.APIPath
+}
+
+// Successful returns true if the run and all of its sources finished without errors.
+func (r *Result) Successful() bool {
+	return r.Err == nil && len(r.SourceErrors) == 0 && !r.Canceled
+}
+
+// Duration returns the duration of the run.
+func (r *Result) Duration() time.Duration {
+	if r.EndTime.IsZero() {
+		return time.Since(r.StartTime)
+	}
+	return r.EndTime.Sub(r.StartTime)
+}
+
+// Status returns the current status of the run.
+func (r *Result) Status() RunStatus {
+	switch {
+	case r.EndTime.IsZero():
+		return RunStatusRunning
+	case r.Canceled:
+		return RunStatusCanceled
+	case r.Successful():
+		return RunStatusSucceeded
+	default:
+		return RunStatusFailed
+	}
+}
+
+// clone returns a copy of the result, which is safe to read
+// while the original is being updated.
+func (r *Result) clone() Result {
+	c := *r
+	c.Sources = slices.Clone(r.Sources)
+	c.SourceErrors = maps.Clone(r.SourceErrors)
+	return c
+}
+
+// SourceStatus is the outcome of the last run of a source.
+type SourceStatus struct {
+	Name string
+	// LastRunID is the ID of the last run that included this source.
+	LastRunID int
+	// LastRunTime is the time when the last run that included this source finished.
+	LastRunTime time.Time
+	// LastError is the error of the last run that included this source, nil on success.
+	LastError error
+	// LastSuccessTime is the time of the last successful sync of this source.
+	LastSuccessTime time.Time
+}
+
+// Runner performs synchronization runs of all configured sources.
+type Runner struct {
+	// Logger used for the runner and all of the sources.
+	Logger *logger.Logger
+	// Config is the parsed configuration of netbox-ssot.
+	Config *parser.Config
+	// ConfigPath is path to the configuration file. It is used
+	// to reload the configuration while running as a daemon.
+	ConfigPath string
+	// DryRun when true prevents all writes to Netbox API.
+	DryRun bool
+	// ReportPath is path of the file, where report of changes made during
+	// the run is written. If empty, no report is written.
+	ReportPath string
+	// DumpSourceDataDir is the directory, where data fetched by each source is
+	// dumped after Init, so it can be synced later by the replay source.
+	// If empty, source data is not dumped.
+	DumpSourceDataDir string
+	// RunTimeout is the maximum duration of a single run. When it is exceeded,
+	// the run is canceled in the same way as with Cancel. Zero means no limit.
+	RunTimeout time.Duration
+	// Metrics records metrics of runs, sources and Netbox API usage.
+	// If nil, metrics are not recorded.
+	Metrics *metrics.Metrics
+	// Inventory is the netbox inventory. It is created on the first run,
+	// and refreshed on each of the following runs.
+	Inventory *inventory.NetboxInventory
+	// Default context for the runner, we use it to pass sourcename
+	// to the logger.
+	Ctx context.Context //nolint:containedctx
+
+	// runLock ensures that only one run is in progress at a time.
+	runLock sync.Mutex
+
+	// stateLock guards all of the fields below.
+	stateLock sync.Mutex
+	nextRunID int
+	// history of runs, the newest run is the last.
+	history []*Result
+	// current is the run in progress, and cancelCurrent function to cancel it.
+	current       *Result
+	cancelCurrent context.CancelFunc
+	// sourceStatuses stores outcome of the last run of each source, indexed by source name.
+	sourceStatuses map[string]*SourceStatus
+
+	// recorder records changes of the current run, when ReportPath, Metrics
+	// or notifications are set. It is guarded by runLock.
+	recorder *report.Recorder
+	// watchers watch sources for changes while the daemon is running,
+	// nil otherwise. It is guarded by runLock.
+	watchers *watchers
+	// inventoryStale is set, when the inventory holds objects, which aren't in main
+	// (e.g. created in a branch, which wasn't merged), so the next run must refresh
+	// the whole inventory. It is guarded by runLock.
+	inventoryStale bool
+}
+
+// New creates a new Runner for the given configuration.
+func New(
+	logger *logger.Logger,
+	config *parser.Config,
+	configPath string,
+	dryRun bool,
+) *Runner {
+	return &Runner{
+		Logger:         logger,
+		Config:         config,
+		ConfigPath:     configPath,
+		DryRun:         dryRun,
+		Ctx:            context.WithValue(context.Background(), constants.CtxSourceKey, "main"),
+		nextRunID:      1,
+		sourceStatuses: map[string]*SourceStatus{},
+	}
+}
+
+// Run performs a single synchronization run and blocks until it is finished.
+// If another run is in progress, it waits for it to finish first.
+// The netbox inventory is initialized on the first run and only refreshed
+// on the following runs. If ctx is canceled while sources are syncing,
+// orphan cleanup is skipped.
+func (r *Runner) Run(ctx context.Context, opts RunOptions) *Result {
+	r.runLock.Lock()
+	defer r.runLock.Unlock()
+	result, err := r.newRun(opts)
+	if err != nil {
+		return r.rejectedRun(opts, err)
+	}
+	r.execute(ctx, result)
+	return result
+}
+
+// Start starts a new synchronization run in the background and returns its
+// initial state. It returns ErrRunInProgress if another run is in progress.
+func (r *Runner) Start(ctx context.Context, opts RunOptions) (Result, error) {
+	if !r.runLock.TryLock() {
+		return Result{}, ErrRunInProgress
+	}
+	result, err := r.newRun(opts)
+	if err != nil {
+		r.runLock.Unlock()
+		return Result{}, err
+	}
+	r.stateLock.Lock()
+	started := result.clone()
+	r.stateLock.Unlock()
+	go func() {
+		defer r.runLock.Unlock()
+		r.execute(ctx, result)
+	}()
+	return started, nil
+}
+
+// Cancel cancels the run with the given ID, if it is still in progress.
+func (r *Runner) Cancel(runID int) error {
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+	if r.current == nil || r.current.ID != runID {
+		for _, run := range r.history {
+			if run.ID == runID {
+				return ErrRunNotInProgress
+			}
+		}
+		return ErrRunNotFound
+	}
+	r.Logger.Infof(r.Ctx, "Canceling run %d", runID)
+	r.current.Canceled = true
+	// Run may not have started executing yet, in which case
+	// execute cancels it as soon as it starts
+	if r.cancelCurrent != nil {
+		r.cancelCurrent()
+	}
+	return nil
+}
+
+// Runs returns copies of all runs in the history, the newest first.
+func (r *Runner) Runs() []Result {
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+	runs := make([]Result, 0, len(r.history))
+	for i := len(r.history) - 1; i >= 0; i-- {
+		runs = append(runs, r.history[i].clone())
+	}
+	return runs
+}
+
+// GetRun returns a copy of the run with the given ID.
+func (r *Runner) GetRun(runID int) (Result, bool) {
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+	for _, run := range r.history {
+		if run.ID == runID {
+			return run.clone(), true
+		}
+	}
+	return Result{}, false
+}
+
+// SourceStatuses returns outcome of the last run for each of the configured sources,
+// in the order they are defined in the configuration.
+func (r *Runner) SourceStatuses() []SourceStatus {
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+	statuses := make([]SourceStatus, 0, len(r.Config.Sources))
+	for _, sourceConfig := range r.Config.Sources {
+		if status, ok := r.sourceStatuses[sourceConfig.Name]; ok {
+			statuses = append(statuses, *status)
+		} else {
+			statuses = append(statuses, SourceStatus{Name: sourceConfig.Name})
+		}
+	}
+	return statuses
+}
+
+// newRun validates run options and registers a new run in the history
+// as the current run. Caller must hold runLock.
+func (r *Runner) newRun(opts RunOptions) (*Result, error) {
+	sourceNames, err := r.selectSources(opts.Sources, opts.SkipSources)
+	if err != nil {
+		return nil, err
+	}
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+	result := &Result{
+		ID:           r.nextRunID,
+		Trigger:      opts.Trigger,
+		Sources:      sourceNames,
+		StartTime:    time.Now(),
+		SourceErrors: map[string]error{},
+	}
+	r.nextRunID++
+	r.current = result
+	r.history = append(r.history, result)
+	if len(r.history) > maxHistory {
+		r.history = r.history[len(r.history)-maxHistory:]
+	}
+	return result, nil
+}
+
+// rejectedRun returns a finished failed result for a run that couldn't be started.
+func (r *Runner) rejectedRun(opts RunOptions, err error) *Result {
+	r.Logger.Error(r.Ctx, err)
+	now := time.Now()
+	return &Result{
+		Trigger:      opts.Trigger,
+		StartTime:    now,
+		EndTime:      now,
+		Err:          err,
+		SourceErrors: map[string]error{},
+	}
+}
+
+// selectSources returns names of the sources that should be synced, in the
+// order they are configured. If names is empty, all configured sources are
+// selected. Sources in skip are excluded from the selection.
+func (r *Runner) selectSources(names []string, skip []string) ([]string, error) {
+	configured := r.configuredSources()
+	for _, name := range slices.Concat(names, skip) {
+		if !slices.Contains(configured, name) {
+			return nil, fmt.Errorf("source %s is not configured", name)
+		}
+	}
+	selected := make([]string, 0, len(configured))
+	for _, name := range configured {
+		if (len(names) == 0 || slices.Contains(names, name)) && !slices.Contains(skip, name) {
+			selected = append(selected, name)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("no sources selected")
+	}
+	return selected, nil
+}
+
+// configuredSources returns names of all configured sources.
+func (r *Runner) configuredSources() []string {
+	configured := make([]string, 0, len(r.Config.Sources))
+	for _, sourceConfig := range r.Config.Sources {
+		configured = append(configured, sourceConfig.Name)
+	}
+	return configured
+}
+
+// partial returns true if the run doesn't include all configured sources.
+func (r *Runner) partial(result *Result) bool {
+	return len(result.Sources) != len(r.Config.Sources)
+}
+
+// orphanScope returns scope of the orphan cleanup for the run. Only objects
+// owned by the sources of the run that synced successfully can be removed,
+// objects of the failed sources are protected. Objects that don't belong to any
+// configured source can be removed only if all sources were synced successfully.
+func (r *Runner) orphanScope(result *Result) *inventory.OrphanScope {
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+	scope := &inventory.OrphanScope{
+		Sources:           make([]string, 0, len(result.Sources)),
+		ConfiguredSources: r.configuredSources(),
+		IncludeUnowned:    !r.partial(result) && len(result.SourceErrors) == 0,
+	}
+	for _, sourceName := range result.Sources {
+		if _, failed := result.SourceErrors[sourceName]; !failed {
+			scope.Sources = append(scope.Sources, sourceName)
+		}
+	}
+	return scope
+}
+
+// execute performs the registered run. Caller must hold runLock.
+func (r *Runner) execute(ctx context.Context, result *Result) {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if r.RunTimeout > 0 {
+		var cancelTimeout context.CancelFunc
+		runCtx, cancelTimeout = context.WithTimeout(runCtx, r.RunTimeout)
+		defer cancelTimeout()
+	}
+	r.stateLock.Lock()
+	r.cancelCurrent = cancel
+	if result.Canceled {
+		cancel()
+	}
+	r.stateLock.Unlock()
+	defer r.finish(result)
+
+	r.Logger.Infof(r.Ctx, "Starting run %d (trigger: %s, sources: %v)", result.ID, result.Trigger, result.Sources)
+	if r.ReportPath != "" || r.Metrics != nil || len(r.Config.Notifications) > 0 {
+		if r.recorder == nil {
+			r.recorder = report.NewRecorder()
+			if r.Inventory != nil {
+				// Notifications can be added by reloading the config
+				r.Inventory.Recorder = r.recorder
+			}
+		}
+		r.recorder.Reset()
+	}
+	prepareInventory := r.prepareInventory
+	if result.Trigger == TriggerWatch {
+		prepareInventory = func(ctx context.Context) error { return r.refreshObjectTypes(ctx, result) }
+	}
+	if err := prepareInventory(runCtx); err != nil {
+		r.setRunError(result, err)
+		r.Logger.Error(r.Ctx, err)
+		return
+	}
+	// Transforms are set on each run, because they can be changed by reloading the config
+	if err := r.Inventory.SetTransforms(r.Config.Transforms); err != nil {
+		r.setRunError(result, err)
+		r.Logger.Error(r.Ctx, err)
+		return
+	}
+	branch, err := r.createBranch(runCtx, result)
+	if err != nil {
+		r.setRunError(result, err)
+		r.Logger.Error(r.Ctx, err)
+		return
+	}
+	if branch != nil {
+		defer r.closeBranch(runCtx, result, branch)
+	}
+
+	if result.Trigger == TriggerWatch {
+		r.syncChanges(runCtx, result)
+		return
+	}
+	r.syncSources(runCtx, result)
+
+	// Orphan manager cleanup of the sources that synced successfully
+	scope := r.orphanScope(result)
+	switch {
+	case runCtx.Err() != nil:
+		r.Logger.Info(r.Ctx, "Skipping removing orphaned objects because run was canceled...")
+	case len(scope.Sources) == 0:
+		r.Logger.Info(r.Ctx, "Skipping removing orphaned objects because all sources failed...")
+	default:
+		// Sources of the run, which synced successfully, don't report objects, which weren't added by them
+		r.Inventory.ExpireFieldSources(scope.Sources)
+		r.logProtectedOrphans(result, scope)
+		r.Metrics.SetOrphanCandidates(scope.Sources, r.Inventory.OrphanManager.CandidatesBySourceAndType())
+		if len(scope.Sources) != len(r.Config.Sources) {
+			r.Logger.Infof(r.Ctx, "Cleaning up orphaned objects of sources %v...", scope.Sources)
+		} else {
+			r.Logger.Info(r.Ctx, "Cleaning up orphaned objects...")
+		}
+		err := r.Inventory.DeleteOrphans(
+			r.Config.Netbox.RemoveOrphans,
+			scope,
+			inventory.NewDeletionThresholds(r.Config),
+		)
+		var thresholdErr *inventory.DeletionThresholdError
+		if errors.As(err, &thresholdErr) {
+			r.savePendingDeletions(thresholdErr.Pending)
+		}
+		if err != nil {
+			r.setRunError(result, err)
+			r.Logger.Error(r.Ctx, err)
+			return
+		}
+		r.Logger.Infof(r.Ctx, "%s Successfully removed orphans", constants.CheckMark)
+	}
+}
+
+// createBranch creates a branch of the NetBox branching plugin, in which all
+// changes of the run are staged, if branching is enabled. Otherwise it returns nil.
+func (r *Runner) createBranch(ctx context.Context, result *Result) (*service.Branch, error) {
+	branching := r.Config.Netbox.Branching
+	// Changes of watched sources are small and frequent, so they aren't staged
+	if !branching.Enabled || result.Trigger == TriggerWatch {
+		return nil, nil
+	}
+	name := fmt.Sprintf("%s-%s-run-%d", branching.NamePrefix, result.StartTime.Format("20060102-150405"), result.ID)
+	if r.DryRun {
+		r.Logger.Infof(r.Ctx, "[DRY-RUN] Would stage changes in branch %s", name)
+		return nil, nil
+	}
+	r.Logger.Infof(r.Ctx, "Creating branch %s", name)
+	description := fmt.Sprintf(
+		"Run %d of netbox-ssot (trigger: %s, sources: %s)",
+		result.ID,
+		result.Trigger,
+		strings.Join(result.Sources, ", "),
+	)
+	timeout := time.Duration(branching.Timeout) * time.Second
+	branch, err := service.CreateBranch(ctx, r.Inventory.NetboxAPI, name, description, timeout)
+	if err != nil {
+		return nil, err
+	}
+	r.Inventory.NetboxAPI.Branch = branch.SchemaID
+	r.stateLock.Lock()
+	result.Branch = branch.Name
+	r.stateLock.Unlock()
+	r.Logger.Infof(r.Ctx, "%s Changes are staged in branch %s", constants.CheckMark, branch.Name)
+	return branch, nil
+}
+
+// closeBranch stops staging changes in the branch, and merges it into main
+// if the run was successful. Otherwise the branch is left for review.
+func (r *Runner) closeBranch(ctx context.Context, result *Result, branch *service.Branch) {
+	r.Inventory.NetboxAPI.Branch = ""
+	r.stateLock.Lock()
+	successful := result.Successful()
+	r.stateLock.Unlock()
+	// Objects created in the branch stay in the inventory until it is merged
+	r.inventoryStale = true
+	if !successful {
+		r.Logger.Warningf(
+			r.Ctx,
+			"%s Branch %s is not merged, because the run failed. Review it in NetBox",
+			constants.WarningSign,
+			branch.Name,
+		)
+		return
+	}
+	r.Logger.Infof(r.Ctx, "Merging branch %s", branch.Name)
+	timeout := time.Duration(r.Config.Netbox.Branching.Timeout) * time.Second
+	if err := service.MergeBranch(ctx, r.Inventory.NetboxAPI, branch, timeout); err != nil {
+		r.setRunError(result, err)
+		r.Logger.Error(r.Ctx, err)
+		return
+	}
+	r.inventoryStale = false
+	r.stateLock.Lock()
+	result.BranchMerged = true
+	r.stateLock.Unlock()
+	r.Logger.Infof(r.Ctx, "%s Branch %s merged", constants.CheckMark, branch.Name)
+}
+
+// savePendingDeletions writes deletions, which were stopped because
+// a deletion threshold was exceeded, to the pending deletions file.
+func (r *Runner) savePendingDeletions(pending *inventory.PendingDeletions) {
+	if r.DryRun {
+		r.Logger.Infof(r.Ctx, "[DRY-RUN] Would write %d pending deletions", len(pending.Deletions))
+		return
+	}
+	path := r.Config.Netbox.PendingDeletionsFile
+	if err := pending.WriteFile(path); err != nil {
+		r.Logger.Error(r.Ctx, err)
+		return
+	}
+	r.Logger.Warningf(
+		r.Ctx,
+		"%s %d pending deletions written to %s. Review them and apply them with --approve-deletions %s",
+		constants.WarningSign,
+		len(pending.Deletions),
+		path,
+		path,
+	)
+}
+
+// ApproveDeletions applies pending deletions from the file at path, which was
+// written by a run that exceeded a deletion threshold. Only objects that are
+// still managed by netbox-ssot, and weren't updated since the file was written,
+// are deleted. It waits for the run in progress to finish.
+func (r *Runner) ApproveDeletions(ctx context.Context, path string) error {
+	r.runLock.Lock()
+	defer r.runLock.Unlock()
+	pending, err := inventory.ReadPendingDeletions(path)
+	if err != nil {
+		return err
+	}
+	r.Logger.Infof(
+		r.Ctx,
+		"Applying %d pending deletions created at %s (%s)",
+		len(pending.Deletions),
+		pending.CreatedAt.Format(time.RFC3339),
+		strings.Join(pending.Reasons, "; "),
+	)
+	if err := r.prepareInventory(ctx); err != nil {
+		return err
+	}
+	deleted, err := r.Inventory.ApplyPendingDeletions(pending)
+	if err != nil {
+		return fmt.Errorf("apply pending deletions: %s", err)
+	}
+	r.Logger.Infof(r.Ctx, "%s Deleted %d of %d pending deletions", constants.CheckMark, deleted, len(pending.Deletions))
+	return nil
+}
+
+// logProtectedOrphans logs number of orphan candidates that are kept,
+// because their source failed during the run.
+func (r *Runner) logProtectedOrphans(result *Result, scope *inventory.OrphanScope) {
+	candidates := r.Inventory.OrphanManager.CandidatesBySource()
+	for _, sourceName := range result.Sources {
+		if slices.Contains(scope.Sources, sourceName) || candidates[sourceName] == 0 {
+			continue
+		}
+		r.Logger.Warningf(
+			r.Ctx,
+			"%s Keeping %d orphan candidates of source %s, because the source failed",
+			constants.WarningSign,
+			candidates[sourceName],
+			sourceName,
+		)
+	}
+}
+
+// finish marks the run as finished, and updates statuses of its sources.
+func (r *Runner) finish(result *Result) {
+	r.stateLock.Lock()
+	result.EndTime = time.Now()
+	if result.Err == nil && result.Canceled && len(result.SourceErrors) == 0 {
+		result.Err = context.Canceled
+	}
+	// Changes of dry runs are only planned, and changes of branches,
+	// which weren't merged, aren't in main, so they aren't counted
+	changesMade := !r.DryRun && (result.Branch == "" || result.BranchMerged)
+	r.stateLock.Unlock()
+
+	if r.ReportPath != "" {
+		r.writeReport(result)
+	}
+	if changesMade {
+		r.Metrics.ObserveChanges(r.recorder.Changes())
+	}
+
+	r.stateLock.Lock()
+	for _, sourceName := range result.Sources {
+		status, ok := r.sourceStatuses[sourceName]
+		if !ok {
+			status = &SourceStatus{Name: sourceName}
+			r.sourceStatuses[sourceName] = status
+		}
+		status.LastRunID = result.ID
+		status.LastRunTime = result.EndTime
+		status.LastError = result.SourceErrors[sourceName]
+		if status.LastError == nil && result.Err != nil {
+			status.LastError = result.Err
+		}
+		if status.LastError == nil {
+			status.LastSuccessTime = result.EndTime
+		}
+	}
+	r.current = nil
+	r.cancelCurrent = nil
+	finished := result.clone()
+	r.stateLock.Unlock()
+
+	r.observeMetrics(&finished)
+	r.logSummary(&finished)
+	r.notify(&finished)
+}
+
+// notify sends the summary of the finished run to all configured notifications.
+// Failed notifications are only logged, because the run is already finished.
+func (r *Runner) notify(result *Result) {
+	if len(r.Config.Notifications) == 0 {
+		return
+	}
+	notifications := r.Config.Notifications
+	if result.Trigger == TriggerWatch {
+		// Runs of watched sources are frequent, so they notify only about failures and changes
+		notifications = slices.DeleteFunc(slices.Clone(notifications), func(notification parser.NotificationConfig) bool {
+			return notification.When == parser.NotifyAlways
+		})
+	}
+	notifier, err := notify.New(notifications)
+	if err != nil {
+		r.Logger.Error(r.Ctx, err)
+		return
+	}
+	summary := &notify.Summary{
+		RunID:           result.ID,
+		Trigger:         result.Trigger,
+		Status:          string(result.Status()),
+		Successful:      result.Successful(),
+		DryRun:          r.DryRun,
+		StartTime:       result.StartTime,
+		EndTime:         result.EndTime,
+		Duration:        result.Duration().Round(time.Second).String(),
+		DurationSeconds: result.Duration().Seconds(),
+		Changes:         notify.CountChanges(r.recorder.Changes()),
+		Errors:          []string{},
+	}
+	// Errors of the run (e.g. exceeded deletion thresholds) aren't errors of its sources
+	if result.Err != nil {
+		summary.Errors = append(summary.Errors, result.Err.Error())
+	}
+	for _, sourceName := range result.Sources {
+		source := notify.SourceSummary{Name: sourceName, Status: notify.SourceStatusSucceeded}
+		if err, failed := result.SourceErrors[sourceName]; failed {
+			source.Status = notify.SourceStatusFailed
+			source.Error = err.Error()
+			summary.Errors = append(summary.Errors, fmt.Sprintf("%s: %s", sourceName, err))
+		}
+		summary.Sources = append(summary.Sources, source)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Ctx, time.Duration(constants.DefaultAPITimeout)*time.Second)
+	defer cancel()
+	if err := notifier.Notify(ctx, summary); err != nil {
+		r.Logger.Error(r.Ctx, err)
+		return
+	}
+	r.Logger.Debugf(r.Ctx, "Notifications of run %d sent", result.ID)
+}
+
+// observeMetrics records outcome of the finished run and of its sources,
+// and pushes all metrics to the Pushgateway, if it is configured.
+func (r *Runner) observeMetrics(result *Result) {
+	if r.Metrics == nil {
+		return
+	}
+	for _, sourceName := range result.Sources {
+		_, failed := result.SourceErrors[sourceName]
+		r.Metrics.ObserveSourceResult(sourceName, !failed && result.Err == nil, result.EndTime)
+	}
+	r.Metrics.ObserveRun(string(result.Status()), result.Duration(), result.Successful(), result.EndTime)
+
+	if r.Config.Metrics == nil || r.Config.Metrics.PushgatewayURL == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Ctx, time.Duration(constants.DefaultAPITimeout)*time.Second)
+	defer cancel()
+	if err := r.Metrics.Push(ctx, r.Config.Metrics.PushgatewayURL, r.Config.Metrics.Job); err != nil {
+		r.Logger.Error(r.Ctx, err)
+		return
+	}
+	r.Logger.Debugf(r.Ctx, "Metrics pushed to %s", r.Config.Metrics.PushgatewayURL)
+}
+
+func (r *Runner) setRunError(result *Result, err error) {
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+	result.Err = err
+}
+
+// prepareInventory initializes the netbox inventory on the first run,
+// and refreshes it on all the following ones. The inventory uses ctx
+// for all of its requests during the run.
+func (r *Runner) prepareInventory(ctx context.Context) error {
+	inventoryCtx := context.WithValue(ctx, constants.CtxSourceKey, "inventory")
+	if r.Inventory == nil {
+		r.Inventory = inventory.NewNetboxInventory(inventoryCtx, r.Logger, r.Config.Netbox, r.DryRun)
+		r.Inventory.Recorder = r.recorder
+		r.Inventory.Metrics = r.Metrics
+		r.Logger.Debug(r.Ctx, "Netbox inventory: ", r.Inventory)
+		r.Logger.Info(r.Ctx, "Starting initializing netbox inventory")
+		if err := r.Inventory.Init(inventoryCtx); err != nil {
+			// Next run should start from scratch
+			r.Inventory = nil
+			return fmt.Errorf("initialize netbox inventory: %s", err)
+		}
+		r.Logger.Debug(r.Ctx, "Netbox inventory initialized: ", r.Inventory)
+		return nil
+	}
+	r.Logger.Info(r.Ctx, "Refreshing netbox inventory")
+	if err := r.Inventory.Refresh(inventoryCtx); err != nil {
+		return fmt.Errorf("refresh netbox inventory: %s", err)
+	}
+	r.inventoryStale = false
+	return nil
+}
+
+// syncSources creates, initializes and syncs all sources of the run in parallel.
+// Each source runs with its own context derived from ctx, which is limited
+// by the syncTimeout of the source. Errors of each source are stored in result.SourceErrors.
+func (r *Runner) syncSources(ctx context.Context, result *Result) {
+	var wg sync.WaitGroup
+	setSourceError := func(sourceName string, err error) {
+		r.stateLock.Lock()
+		defer r.stateLock.Unlock()
+		result.SourceErrors[sourceName] = err
+	}
+
+	for i := range r.Config.Sources {
+		sourceConfig := &r.Config.Sources[i]
+		if !slices.Contains(result.Sources, sourceConfig.Name) {
+			continue
+		}
+		r.Logger.Info(r.Ctx, "Processing source ", sourceConfig.Name, "...")
+		sourceCtx := context.WithValue(ctx, constants.CtxSourceKey, sourceConfig.Name)
+		var cancelSource context.CancelFunc
+		if sourceConfig.SyncTimeout > 0 {
+			sourceCtx, cancelSource = context.WithTimeout(sourceCtx, time.Duration(sourceConfig.SyncTimeout)*time.Second)
+		} else {
+			sourceCtx, cancelSource = context.WithCancel(sourceCtx)
+		}
+		src, watched, err := r.newSource(sourceCtx, sourceConfig)
+		if err != nil {
+			cancelSource()
+			r.Logger.Error(sourceCtx, err)
+			setSourceError(sourceConfig.Name, err)
+			continue
+		}
+		r.Logger.Infof(sourceCtx, "Successfully created source %s", constants.CheckMark)
+		r.Logger.Debugf(sourceCtx, "Source content: %s", src)
+		wg.Add(1)
+		// Run each source in parallel
+		go func(sourceCtx context.Context, sourceName string, src common.Source) {
+			defer wg.Done()
+			defer cancelSource()
+			// Source initialization
+			r.Logger.Info(sourceCtx, "Initializing source")
+			initStart := time.Now()
+			err := src.Init(sourceCtx)
+			r.Metrics.ObserveSourcePhase(sourceName, metrics.PhaseInit, time.Since(initStart))
+			if watched != nil {
+				watched.initialized = err == nil
+			}
+			if err != nil {
+				r.Logger.Error(sourceCtx, err)
+				setSourceError(sourceName, err)
+				return
+			}
+			r.Logger.Infof(sourceCtx, "Successfully initialized source %s", constants.CheckMark)
+			if r.DumpSourceDataDir != "" {
+				r.dumpSourceData(sourceCtx, sourceConfig, src)
+			}
+			if sourceCtx.Err() != nil {
+				r.Logger.Infof(sourceCtx, "Skipping sync: %s", sourceCtx.Err())
+				setSourceError(sourceName, sourceCtx.Err())
+				return
+			}
+
+			// Source synchronization
+			r.Logger.Info(sourceCtx, "Syncing source...")
+			syncStart := time.Now()
+			err = src.Sync(sourceCtx, r.Inventory)
+			r.Metrics.ObserveSourcePhase(sourceName, metrics.PhaseSync, time.Since(syncStart))
+			if err != nil {
+				r.Logger.Error(sourceCtx, err)
+				setSourceError(sourceName, err)
+				return
+			}
+			r.Logger.Infof(sourceCtx, "Source synced successfully %s", constants.CheckMark)
+		}(sourceCtx, sourceConfig.Name, src)
+	}
+	wg.Wait()
+}
+
+// dumpSourceData writes data of the initialized source into DumpSourceDataDir.
+// Failure to dump the data is logged, but doesn't fail the source.
+func (r *Runner) dumpSourceData(ctx context.Context, sourceConfig *parser.SourceConfig, src common.Source) {
+	dumper, ok := src.(common.Dumper)
+	if !ok {
+		r.Logger.Warningf(ctx, "Source type %s doesn't support dumping source data", sourceConfig.Type)
+		return
+	}
+	path, err := common.WriteSourceData(r.DumpSourceDataDir, sourceConfig.Name, sourceConfig.Type, dumper)
+	if err != nil {
+		r.Logger.Errorf(ctx, "Failed to dump source data: %s", err)
+		return
+	}
+	r.Logger.Infof(ctx, "Dumped source data to %s", path)
+}
+
+// writeReport writes report of all changes made during the run to ReportPath.
+// Failure to write the report fails the run.
+func (r *Runner) writeReport(result *Result) {
+	r.stateLock.Lock()
+	changesReport := report.NewReport(r.recorder.Changes())
+	changesReport.RunID = result.ID
+	changesReport.Trigger = result.Trigger
+	changesReport.DryRun = r.DryRun
+	changesReport.StartTime = result.StartTime
+	changesReport.EndTime = result.EndTime
+	changesReport.Status = string(result.Status())
+	changesReport.Sources = slices.Clone(result.Sources)
+	r.stateLock.Unlock()
+
+	if err := changesReport.WriteFile(r.ReportPath); err != nil {
+		r.Logger.Error(r.Ctx, err)
+		r.setRunError(result, err)
+		return
+	}
+	r.Logger.Infof(
+		r.Ctx,
+		"%s Report of %d changes written to %s",
+		constants.CheckMark,
+		len(changesReport.Changes),
+		r.ReportPath,
+	)
+}
+
+// logSummary logs duration of the run and errors of all failed sources.
+func (r *Runner) logSummary(result *Result) {
+	duration := result.Duration()
+	minutes := int(duration.Minutes())
+	seconds := int((duration - time.Duration(minutes)*time.Minute).Seconds())
+	if r.DryRun {
+		r.Logger.Info(r.Ctx, "DRY-RUN COMPLETE: Review the log above for [DRY-RUN] entries to see what would change")
+	}
+	if result.Successful() {
+		r.Logger.Infof(
+			r.Ctx,
+			"%s Syncing took %d min %d sec in total",
+			constants.Rocket,
+			minutes,
+			seconds,
+		)
+		return
+	}
+	for sourceName, err := range result.SourceErrors {
+		r.Logger.Infof(r.Ctx, "%s syncing of source %s failed with: %v", constants.WarningSign, sourceName, err)
+	}
+}
@@ -0,0 +1,622 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/logger"
+	"github.com/bl4ko/netbox-ssot/internal/metrics"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/service"
+	"github.com/bl4ko/netbox-ssot/internal/notify"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+	"github.com/bl4ko/netbox-ssot/internal/report"
+)
+
+func testRunner(t *testing.T, configFile string) *Runner {
+	t.Helper()
+	configPath := filepath.Join("../../testdata/parser", configFile)
+	config, err := parser.ParseConfig(configPath)
+	if err != nil {
+		t.Fatalf("parse config: %s", err)
+	}
+	return New(&logger.Logger{Logger: log.Default()}, config, configPath, false)
+}
+
+func TestReload(t *testing.T) {
+	tests := []struct {
+		name          string
+		configPath    string
+		changeNetbox  bool
+		wantReloaded  bool
+		wantInventory bool
+	}{
+		{
+			name:          "Unchanged netbox config keeps inventory",
+			configPath:    "valid_config1.yaml",
+			wantReloaded:  true,
+			wantInventory: true,
+		},
+		{
+			name:          "Changed netbox config resets inventory",
+			configPath:    "valid_config1.yaml",
+			changeNetbox:  true,
+			wantReloaded:  true,
+			wantInventory: false,
+		},
+		{
+			name:          "Invalid config keeps the current one",
+			configPath:    "invalid_config1.yaml",
+			wantReloaded:  false,
+			wantInventory: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := testRunner(t, "valid_config1.yaml")
+			oldConfig := r.Config
+			if tt.changeNetbox {
+				r.Config.Netbox.Hostname = "changed.example.com"
+			}
+			r.Inventory = &inventory.NetboxInventory{}
+			r.ConfigPath = filepath.Join("../../testdata/parser", tt.configPath)
+
+			r.Reload()
+
+			if reloaded := r.Config != oldConfig; reloaded != tt.wantReloaded {
+				t.Errorf("Reload() reloaded = %t, want %t", reloaded, tt.wantReloaded)
+			}
+			if hasInventory := r.Inventory != nil; hasInventory != tt.wantInventory {
+				t.Errorf("Reload() has inventory = %t, want %t", hasInventory, tt.wantInventory)
+			}
+		})
+	}
+}
+
+func TestRunFailsWithoutNetbox(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(serverURL.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := testRunner(t, "valid_config1.yaml")
+	r.Config.Netbox.HTTPScheme = parser.HTTP
+	r.Config.Netbox.Hostname = serverURL.Hostname()
+	r.Config.Netbox.Port = port
+	r.Config.Netbox.MaxRetries = 0
+
+	result := r.Run(context.Background(), RunOptions{Trigger: TriggerCLI})
+	if result.Successful() {
+		t.Fatalf("Run() succeeded, but netbox is not available")
+	}
+	if result.Err == nil {
+		t.Errorf("Run() error is not set")
+	}
+	if r.Inventory != nil {
+		t.Errorf("Run() kept inventory which failed to initialize")
+	}
+	if result.EndTime.Before(result.StartTime) {
+		t.Errorf("Run() end time %s is before start time %s", result.EndTime, result.StartTime)
+	}
+}
+
+func TestStart(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(serverURL.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := testRunner(t, "valid_config1.yaml")
+	r.Config.Netbox.HTTPScheme = parser.HTTP
+	r.Config.Netbox.Hostname = serverURL.Hostname()
+	r.Config.Netbox.Port = port
+	r.Config.Netbox.MaxRetries = 0
+
+	if _, err := r.Start(context.Background(), RunOptions{Sources: []string{"unknown"}}); err == nil {
+		t.Errorf("Start() with unknown source succeeded")
+	}
+	started, err := r.Start(context.Background(), RunOptions{Trigger: TriggerAPI})
+	if err != nil {
+		t.Fatalf("Start() error = %s", err)
+	}
+	if started.Status() != RunStatusRunning {
+		t.Errorf("Start() status = %s, want %s", started.Status(), RunStatusRunning)
+	}
+	if _, err := r.Start(context.Background(), RunOptions{Trigger: TriggerAPI}); err != ErrRunInProgress {
+		t.Errorf("second Start() error = %v, want %s", err, ErrRunInProgress)
+	}
+	if err := r.Cancel(started.ID + 1); err != ErrRunNotFound {
+		t.Errorf("Cancel() of unknown run error = %v, want %s", err, ErrRunNotFound)
+	}
+	if err := r.Cancel(started.ID); err != nil {
+		t.Errorf("Cancel() error = %s", err)
+	}
+	close(release)
+
+	// Run blocks until the started run is finished
+	r.Run(context.Background(), RunOptions{Trigger: TriggerCLI})
+	run, ok := r.GetRun(started.ID)
+	if !ok {
+		t.Fatalf("GetRun(%d) not found", started.ID)
+	}
+	if run.Status() != RunStatusCanceled {
+		t.Errorf("run status = %s, want %s", run.Status(), RunStatusCanceled)
+	}
+	if err := r.Cancel(started.ID); err != ErrRunNotInProgress {
+		t.Errorf("Cancel() of finished run error = %v, want %s", err, ErrRunNotInProgress)
+	}
+	if runs := r.Runs(); len(runs) != 2 || runs[0].ID != started.ID+1 {
+		t.Errorf("Runs() = %v, want 2 runs with the newest first", runs)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	// Netbox that never responds
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(serverURL.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := testRunner(t, "valid_config1.yaml")
+	r.Config.Netbox.HTTPScheme = parser.HTTP
+	r.Config.Netbox.Hostname = serverURL.Hostname()
+	r.Config.Netbox.Port = port
+	r.RunTimeout = 100 * time.Millisecond
+
+	startTime := time.Now()
+	result := r.Run(context.Background(), RunOptions{Trigger: TriggerCLI})
+	if elapsed := time.Since(startTime); elapsed > 5*time.Second {
+		t.Errorf("Run() returned after %s, want it to stop after run timeout", elapsed)
+	}
+	if result.Err == nil || !strings.Contains(result.Err.Error(), context.DeadlineExceeded.Error()) {
+		t.Errorf("Run() error = %v, want %s", result.Err, context.DeadlineExceeded)
+	}
+}
+
+func TestRunMetrics(t *testing.T) {
+	netboxServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer netboxServer.Close()
+	pushed := make(chan string, 1)
+	pushgatewayServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushed <- r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer pushgatewayServer.Close()
+	serverURL, err := url.Parse(netboxServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(serverURL.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := testRunner(t, "valid_config1.yaml")
+	r.Config.Netbox.HTTPScheme = parser.HTTP
+	r.Config.Netbox.Hostname = serverURL.Hostname()
+	r.Config.Netbox.Port = port
+	r.Config.Netbox.MaxRetries = 0
+	r.Config.Metrics.PushgatewayURL = pushgatewayServer.URL
+	r.Metrics = metrics.New()
+
+	r.Run(context.Background(), RunOptions{Trigger: TriggerCLI})
+
+	select {
+	case path := <-pushed:
+		if want := "/metrics/job/" + r.Config.Metrics.Job; path != want {
+			t.Errorf("metrics pushed to %s, want %s", path, want)
+		}
+	default:
+		t.Errorf("metrics weren't pushed after the run")
+	}
+	recorder := httptest.NewRecorder()
+	r.Metrics.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`netbox_ssot_runs_total{status="failed"} 1`,
+		`netbox_ssot_last_run_success 0`,
+		`netbox_ssot_netbox_requests_total{method="GET",status="500"} 1`,
+		`netbox_ssot_source_success{source="paloalto"} 0`,
+	} {
+		if !strings.Contains(recorder.Body.String(), want) {
+			t.Errorf("metrics don't contain %s", want)
+		}
+	}
+}
+
+func TestRunNotifications(t *testing.T) {
+	netboxServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer netboxServer.Close()
+	summaries := make(chan notify.Summary, 1)
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var summary notify.Summary
+		if err := json.NewDecoder(r.Body).Decode(&summary); err != nil {
+			t.Errorf("decode notification: %s", err)
+		}
+		summaries <- summary
+	}))
+	defer webhookServer.Close()
+	serverURL, err := url.Parse(netboxServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(serverURL.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := testRunner(t, "valid_config1.yaml")
+	r.Config.Netbox.HTTPScheme = parser.HTTP
+	r.Config.Netbox.Hostname = serverURL.Hostname()
+	r.Config.Netbox.Port = port
+	r.Config.Netbox.MaxRetries = 0
+	r.Config.Notifications = []parser.NotificationConfig{{
+		Name: "alerts",
+		Type: parser.NotificationWebhook,
+		URL:  webhookServer.URL,
+		When: parser.NotifyFailure,
+	}}
+
+	result := r.Run(context.Background(), RunOptions{Trigger: TriggerCLI})
+
+	select {
+	case summary := <-summaries:
+		if summary.RunID != result.ID || summary.Status != string(RunStatusFailed) || summary.Successful {
+			t.Errorf("notified run %d with status %s, want failed run %d", summary.RunID, summary.Status, result.ID)
+		}
+		if len(summary.Errors) != 1 || !strings.Contains(summary.Errors[0], "netbox inventory") {
+			t.Errorf("notification errors = %v, want only the error of the run", summary.Errors)
+		}
+		// Sources didn't fail, the inventory couldn't be initialized
+		for _, source := range summary.Sources {
+			if source.Status == notify.SourceStatusFailed {
+				t.Errorf("source %s has status %s, want only the run to fail", source.Name, source.Status)
+			}
+		}
+	default:
+		t.Error("notification wasn't sent after the failed run")
+	}
+}
+
+func TestNotifyWatchRuns(t *testing.T) {
+	summaries := make(chan notify.Summary, 1)
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		var summary notify.Summary
+		if err := json.NewDecoder(r.Body).Decode(&summary); err != nil {
+			t.Errorf("decode notification: %s", err)
+		}
+		summaries <- summary
+	}))
+	defer webhookServer.Close()
+	r := testRunner(t, "valid_config1.yaml")
+	r.Config.Notifications = []parser.NotificationConfig{{
+		Name: "runs",
+		Type: parser.NotificationWebhook,
+		URL:  webhookServer.URL,
+		When: parser.NotifyAlways,
+	}}
+
+	tests := []struct {
+		trigger    string
+		wantNotify bool
+	}{
+		{trigger: TriggerSchedule, wantNotify: true},
+		{trigger: TriggerWatch, wantNotify: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.trigger, func(t *testing.T) {
+			now := time.Now()
+			r.notify(&Result{Trigger: tt.trigger, StartTime: now, EndTime: now})
+			select {
+			case <-summaries:
+				if !tt.wantNotify {
+					t.Errorf("notification with when always was sent after %s run", tt.trigger)
+				}
+			default:
+				if tt.wantNotify {
+					t.Errorf("notification with when always wasn't sent after %s run", tt.trigger)
+				}
+			}
+		})
+	}
+}
+
+func TestFinishObservesChanges(t *testing.T) {
+	tests := []struct {
+		name        string
+		dryRun      bool
+		branch      string
+		merged      bool
+		wantCounted bool
+	}{
+		{name: "Changes of a run are counted", wantCounted: true},
+		{name: "Planned changes of a dry run aren't counted", dryRun: true},
+		{name: "Changes of a merged branch are counted", branch: "ssot-run-1", merged: true, wantCounted: true},
+		{name: "Changes of a branch, which isn't merged, aren't counted", branch: "ssot-run-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := testRunner(t, "valid_config1.yaml")
+			r.DryRun = tt.dryRun
+			r.Metrics = metrics.New()
+			r.recorder = report.NewRecorder()
+			r.recorder.Record(
+				context.Background(), report.ActionCreate, reflect.TypeOf(objects.Device{}), constants.DevicesAPIPath, 1, nil,
+			)
+
+			r.finish(&Result{ID: 1, StartTime: time.Now(), Branch: tt.branch, BranchMerged: tt.merged})
+
+			recorder := httptest.NewRecorder()
+			r.Metrics.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			counted := strings.Contains(recorder.Body.String(), "netbox_ssot_object_changes_total{")
+			if counted != tt.wantCounted {
+				t.Errorf("changes counted = %t, want %t", counted, tt.wantCounted)
+			}
+		})
+	}
+}
+
+func TestRunRecorderOfExistingInventory(t *testing.T) {
+	netboxServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer netboxServer.Close()
+
+	r := testRunner(t, "valid_config1.yaml")
+	r.Config.Netbox.MaxRetries = 0
+	r.Inventory = inventory.NewNetboxInventory(context.Background(), r.Logger, r.Config.Netbox, false)
+	r.Inventory.NetboxAPI = &service.NetboxClient{
+		HTTPClient: netboxServer.Client(),
+		Logger:     r.Logger,
+		BaseURL:    netboxServer.URL,
+		Timeout:    1,
+	}
+	// Notifications are added by reloading the config after the inventory is initialized
+	r.Config.Notifications = []parser.NotificationConfig{{
+		Name: "alerts",
+		Type: parser.NotificationWebhook,
+		URL:  netboxServer.URL,
+		When: parser.NotifyFailure,
+	}}
+
+	r.Run(context.Background(), RunOptions{Trigger: TriggerCLI})
+
+	if r.recorder == nil {
+		t.Fatal("recorder wasn't created for notifications")
+	}
+	if r.Inventory.NetboxAPI.Recorder != r.recorder {
+		t.Error("recorder wasn't passed to the netbox client of the existing inventory")
+	}
+}
+
+func TestSelectSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []string
+		skip    []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "All sources",
+			want: []string{"testolvm", "paloalto", "prodolvm"},
+		},
+		{
+			name:    "Only sources in configured order",
+			sources: []string{"prodolvm", "testolvm"},
+			want:    []string{"testolvm", "prodolvm"},
+		},
+		{
+			name: "Skip source",
+			skip: []string{"paloalto"},
+			want: []string{"testolvm", "prodolvm"},
+		},
+		{
+			name:    "Only and skip source",
+			sources: []string{"prodolvm", "testolvm"},
+			skip:    []string{"testolvm"},
+			want:    []string{"prodolvm"},
+		},
+		{
+			name:    "Unknown source",
+			sources: []string{"fmc"},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown skipped source",
+			skip:    []string{"fmc"},
+			wantErr: true,
+		},
+		{
+			name:    "All sources skipped",
+			sources: []string{"paloalto"},
+			skip:    []string{"paloalto"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := testRunner(t, "valid_config1.yaml")
+			got, err := r.selectSources(tt.sources, tt.skip)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectSources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("selectSources() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrphanScope(t *testing.T) {
+	allSources := []string{"testolvm", "paloalto", "prodolvm"}
+	tests := []struct {
+		name               string
+		sources            []string
+		sourceErrors       map[string]error
+		wantSources        []string
+		wantIncludeUnowned bool
+	}{
+		{
+			name:               "Successful full run",
+			sources:            allSources,
+			wantSources:        allSources,
+			wantIncludeUnowned: true,
+		},
+		{
+			name:        "Successful partial run",
+			sources:     []string{"paloalto"},
+			wantSources: []string{"paloalto"},
+		},
+		{
+			name:         "Full run with failed source",
+			sources:      allSources,
+			sourceErrors: map[string]error{"paloalto": errors.New("connection refused")},
+			wantSources:  []string{"testolvm", "prodolvm"},
+		},
+		{
+			name:         "All sources failed",
+			sources:      []string{"paloalto"},
+			sourceErrors: map[string]error{"paloalto": errors.New("connection refused")},
+			wantSources:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := testRunner(t, "valid_config1.yaml")
+			scope := r.orphanScope(&Result{Sources: tt.sources, SourceErrors: tt.sourceErrors})
+			if !slices.Equal(scope.Sources, tt.wantSources) {
+				t.Errorf("orphanScope() sources = %v, want %v", scope.Sources, tt.wantSources)
+			}
+			if scope.IncludeUnowned != tt.wantIncludeUnowned {
+				t.Errorf("orphanScope() include unowned = %t, want %t", scope.IncludeUnowned, tt.wantIncludeUnowned)
+			}
+			if !slices.Equal(scope.ConfiguredSources, allSources) {
+				t.Errorf("orphanScope() configured sources = %v, want %v", scope.ConfiguredSources, allSources)
+			}
+		})
+	}
+}
+
+func TestBranch(t *testing.T) {
+	tests := []struct {
+		name         string
+		dryRun       bool
+		sourceErrors map[string]error
+		wantBranches int
+		wantMerged   bool
+	}{
+		{
+			name:         "Successful run is merged",
+			wantBranches: 1,
+			wantMerged:   true,
+		},
+		{
+			name:         "Failed run is left for review",
+			sourceErrors: map[string]error{"paloalto": errors.New("connection refused")},
+			wantBranches: 1,
+		},
+		{
+			name:   "Dry run doesn't create a branch",
+			dryRun: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			branching := &service.MockBranching{}
+			server := httptest.NewServer(branching)
+			defer server.Close()
+			r := testRunner(t, "valid_config1.yaml")
+			r.DryRun = tt.dryRun
+			r.Config.Netbox.Branching = parser.BranchingConfig{Enabled: true, NamePrefix: "ssot", Timeout: 1}
+			r.Inventory = &inventory.NetboxInventory{NetboxAPI: &service.NetboxClient{
+				HTTPClient: server.Client(),
+				Logger:     r.Logger,
+				BaseURL:    server.URL,
+				Timeout:    1,
+			}}
+			result := &Result{ID: 7, StartTime: time.Now(), Sources: []string{"paloalto"}, SourceErrors: map[string]error{}}
+
+			branch, err := r.createBranch(context.Background(), result)
+			if err != nil {
+				t.Fatalf("createBranch() error = %s", err)
+			}
+			if branch != nil {
+				if r.Inventory.NetboxAPI.Branch != branch.SchemaID {
+					t.Errorf("NetboxAPI.Branch = %s, want %s", r.Inventory.NetboxAPI.Branch, branch.SchemaID)
+				}
+				result.SourceErrors = tt.sourceErrors
+				r.closeBranch(context.Background(), result, branch)
+			}
+
+			branches := branching.Branches()
+			if len(branches) != tt.wantBranches {
+				t.Fatalf("created %d branches, want %d", len(branches), tt.wantBranches)
+			}
+			if r.Inventory.NetboxAPI.Branch != "" {
+				t.Errorf("NetboxAPI.Branch = %s after the run, want main", r.Inventory.NetboxAPI.Branch)
+			}
+			if result.BranchMerged != tt.wantMerged {
+				t.Errorf("BranchMerged = %t, want %t", result.BranchMerged, tt.wantMerged)
+			}
+			if len(branches) > 0 {
+				if !strings.HasPrefix(branches[0].Name, "ssot-") || !strings.HasSuffix(branches[0].Name, "-run-7") {
+					t.Errorf("branch name = %s, want ssot-<time>-run-7", branches[0].Name)
+				}
+				if result.Branch != branches[0].Name {
+					t.Errorf("Result.Branch = %s, want %s", result.Branch, branches[0].Name)
+				}
+				wantStatus := service.BranchStatusReady
+				if tt.wantMerged {
+					wantStatus = service.BranchStatusMerged
+				}
+				if branches[0].Status.Value != wantStatus {
+					t.Errorf("branch status = %s, want %s", branches[0].Status.Value, wantStatus)
+				}
+			}
+		})
+	}
+}
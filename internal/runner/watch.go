@@ -0,0 +1,240 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/metrics"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+	"github.com/bl4ko/netbox-ssot/internal/source"
+	"github.com/bl4ko/netbox-ssot/internal/source/common"
+)
+
+// watchRetryInterval is the time after which watching of a source is started
+// again, when it fails (e.g. the connection to the source is lost).
+const watchRetryInterval = 30 * time.Second
+
+// watchDebounce is the time, during which changes of watched sources are collected
+// after the first change, so a burst of changes is synced by a single run.
+const watchDebounce = 10 * time.Second
+
+// watchedSource is a source with the watch option, which is reused by all runs
+// of the daemon, so the changes are synced into its data from the last Init.
+type watchedSource struct {
+	source  common.Source
+	watcher common.Watcher
+	// initialized is true, if the last Init of the source succeeded.
+	initialized bool
+}
+
+// watchers watch sources with the watch option for changes, while the daemon is running.
+type watchers struct {
+	// ctx of all watchers, which is canceled by stopWatching.
+	ctx    context.Context //nolint:containedctx
+	cancel context.CancelFunc
+	// changes receives a value each time new changes are collected.
+	changes chan struct{}
+	// sources are the watched sources by their names.
+	sources map[string]*watchedSource
+	wg      sync.WaitGroup
+	// objectTypes are API paths of object types changed by the collected changes.
+	objectTypes     map[constants.APIPath]bool
+	objectTypesLock sync.Mutex
+}
+
+// addObjectTypes adds object types changed by the collected changes.
+func (w *watchers) addObjectTypes(objectTypes ...constants.APIPath) {
+	w.objectTypesLock.Lock()
+	defer w.objectTypesLock.Unlock()
+	for _, objectType := range objectTypes {
+		w.objectTypes[objectType] = true
+	}
+}
+
+// takeObjectTypes returns object types changed by the collected changes, and removes them.
+func (w *watchers) takeObjectTypes() []constants.APIPath {
+	w.objectTypesLock.Lock()
+	defer w.objectTypesLock.Unlock()
+	objectTypes := slices.Sorted(maps.Keys(w.objectTypes))
+	w.objectTypes = map[constants.APIPath]bool{}
+	return objectTypes
+}
+
+// startWatching enables watching of sources with the watch option. Each source is
+// watched from the first run, which creates it, until ctx is canceled or stopWatching
+// is called. A value is sent on the returned channel each time new changes are collected.
+func (r *Runner) startWatching(ctx context.Context) <-chan struct{} {
+	r.runLock.Lock()
+	defer r.runLock.Unlock()
+	watchCtx, cancel := context.WithCancel(ctx)
+	r.watchers = &watchers{
+		ctx:         watchCtx,
+		cancel:      cancel,
+		changes:     make(chan struct{}, 1),
+		sources:     map[string]*watchedSource{},
+		objectTypes: map[constants.APIPath]bool{},
+	}
+	return r.watchers.changes
+}
+
+// stopWatching stops watching of all sources, and waits for the watchers to finish.
+// It waits for the run in progress to finish first.
+func (r *Runner) stopWatching() {
+	r.runLock.Lock()
+	defer r.runLock.Unlock()
+	if r.watchers == nil {
+		return
+	}
+	r.watchers.cancel()
+	r.watchers.wg.Wait()
+	r.watchers = nil
+}
+
+// newSource creates the source of a run. While watching is enabled, sources with
+// the watch option are created only once, and watched for changes from then on.
+// Watched source is returned as well, or nil if the source isn't watched.
+// Caller must hold runLock.
+func (r *Runner) newSource(
+	ctx context.Context,
+	sourceConfig *parser.SourceConfig,
+) (common.Source, *watchedSource, error) {
+	if r.watchers == nil || !sourceConfig.Watch {
+		src, err := source.NewSource(ctx, sourceConfig, r.Logger, r.Inventory)
+		return src, nil, err
+	}
+	if watched, ok := r.watchers.sources[sourceConfig.Name]; ok {
+		return watched.source, watched, nil
+	}
+	src, err := source.NewSource(ctx, sourceConfig, r.Logger, r.Inventory)
+	if err != nil {
+		return nil, nil, err
+	}
+	watcher, ok := src.(common.Watcher)
+	if !ok {
+		r.Logger.Warningf(ctx, "Source type %s can't be watched for changes", sourceConfig.Type)
+		return src, nil, nil
+	}
+	return src, r.watchSource(sourceConfig.Name, src, watcher), nil
+}
+
+// watchSource starts watching the source for changes. Caller must hold runLock.
+func (r *Runner) watchSource(sourceName string, src common.Source, watcher common.Watcher) *watchedSource {
+	watched := &watchedSource{source: src, watcher: watcher}
+	r.watchers.sources[sourceName] = watched
+	watchCtx := context.WithValue(r.watchers.ctx, constants.CtxSourceKey, sourceName)
+	watchers := r.watchers
+	watchers.wg.Add(1)
+	go func() {
+		defer watchers.wg.Done()
+		r.watch(watchCtx, watcher, func(objectTypes ...constants.APIPath) {
+			watchers.addObjectTypes(objectTypes...)
+			select {
+			case watchers.changes <- struct{}{}:
+			default:
+			}
+		})
+	}()
+	return watched
+}
+
+// watch watches the source for changes until ctx is canceled. If watching
+// fails, it is started again after watchRetryInterval.
+func (r *Runner) watch(ctx context.Context, watcher common.Watcher, changed func(...constants.APIPath)) {
+	for {
+		err := watcher.Watch(ctx, changed)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			r.Logger.Errorf(ctx, "Watching for changes failed: %s", err)
+		}
+		r.Logger.Infof(ctx, "Watching for changes is restarted in %s", watchRetryInterval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
+
+// SyncChanges performs a run, which syncs only changes collected by the watched
+// sources. Orphans are not removed, because all other objects are not synced.
+// Only object types changed by the changes are refreshed in the inventory, and
+// changes aren't staged in a branch. Sources, which weren't initialized by a run
+// yet, are skipped, because the changes are synced by their Init. It returns nil
+// if no source was synced.
+func (r *Runner) SyncChanges(ctx context.Context) *Result {
+	r.runLock.Lock()
+	defer r.runLock.Unlock()
+	if r.watchers == nil {
+		return nil
+	}
+	var sourceNames []string
+	for _, sourceName := range r.configuredSources() {
+		if watched, ok := r.watchers.sources[sourceName]; ok && watched.initialized {
+			sourceNames = append(sourceNames, sourceName)
+		}
+	}
+	if len(sourceNames) == 0 {
+		return nil
+	}
+	opts := RunOptions{Trigger: TriggerWatch, Sources: sourceNames}
+	result, err := r.newRun(opts)
+	if err != nil {
+		return r.rejectedRun(opts, err)
+	}
+	result.objectTypes = r.watchers.takeObjectTypes()
+	r.execute(ctx, result)
+	if !result.Successful() {
+		// Failed changes are synced again with the next changes
+		r.watchers.addObjectTypes(result.objectTypes...)
+	}
+	return result
+}
+
+// refreshObjectTypes refreshes only object types of the inventory changed
+// by the collected changes, which are synced by the run. The whole inventory
+// is refreshed, if it is stale.
+func (r *Runner) refreshObjectTypes(ctx context.Context, result *Result) error {
+	if r.Inventory == nil || r.inventoryStale {
+		return r.prepareInventory(ctx)
+	}
+	inventoryCtx := context.WithValue(ctx, constants.CtxSourceKey, "inventory")
+	r.Logger.Infof(r.Ctx, "Refreshing %v of netbox inventory", result.objectTypes)
+	if err := r.Inventory.RefreshObjectTypes(inventoryCtx, result.objectTypes); err != nil {
+		return fmt.Errorf("refresh netbox inventory: %s", err)
+	}
+	return nil
+}
+
+// syncChanges syncs changes of all watched sources of the run in parallel.
+// Errors of each source are stored in result.SourceErrors.
+func (r *Runner) syncChanges(ctx context.Context, result *Result) {
+	var wg sync.WaitGroup
+	for _, sourceName := range result.Sources {
+		watched := r.watchers.sources[sourceName]
+		sourceCtx := context.WithValue(ctx, constants.CtxSourceKey, sourceName)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Logger.Info(sourceCtx, "Syncing changes of source...")
+			syncStart := time.Now()
+			err := watched.watcher.SyncChanges(sourceCtx, r.Inventory)
+			r.Metrics.ObserveSourcePhase(sourceName, metrics.PhaseSync, time.Since(syncStart))
+			if err != nil {
+				r.Logger.Error(sourceCtx, err)
+				r.stateLock.Lock()
+				result.SourceErrors[sourceName] = err
+				r.stateLock.Unlock()
+				return
+			}
+			r.Logger.Infof(sourceCtx, "Changes of source synced successfully %s", constants.CheckMark)
+		}()
+	}
+	wg.Wait()
+}
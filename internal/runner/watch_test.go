@@ -0,0 +1,146 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/service"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+)
+
+// testWatcher is a source, which collects a single change after watching is started.
+type testWatcher struct {
+	lock       sync.Mutex
+	syncedWith *inventory.NetboxInventory
+	syncErr    error
+}
+
+func (tw *testWatcher) Init(_ context.Context) error {
+	return nil
+}
+
+func (tw *testWatcher) Sync(_ context.Context, _ *inventory.NetboxInventory) error {
+	return nil
+}
+
+func (tw *testWatcher) Watch(ctx context.Context, changed func(...constants.APIPath)) error {
+	changed(constants.DevicesAPIPath)
+	<-ctx.Done()
+	return nil
+}
+
+func (tw *testWatcher) SyncChanges(_ context.Context, nbi *inventory.NetboxInventory) error {
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+	tw.syncedWith = nbi
+	return tw.syncErr
+}
+
+func TestWatch(t *testing.T) {
+	ctx := context.Background()
+	r := testRunner(t, "valid_config1.yaml")
+	r.Inventory = &inventory.NetboxInventory{}
+	if result := r.SyncChanges(ctx); result != nil {
+		t.Errorf("SyncChanges() without watching = %+v, want nil", result)
+	}
+
+	changes := r.startWatching(ctx)
+	watcher := &testWatcher{}
+	failingWatcher := &testWatcher{syncErr: errors.New("sync failed")}
+	r.runLock.Lock()
+	watched := r.watchSource("testolvm", watcher, watcher)
+	failingWatched := r.watchSource("prodolvm", failingWatcher, failingWatcher)
+	r.runLock.Unlock()
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no changes were received from watched sources")
+	}
+	if result := r.SyncChanges(ctx); result != nil {
+		t.Errorf("SyncChanges() of uninitialized sources = %+v, want nil", result)
+	}
+	if got := r.watchers.takeObjectTypes(); !slices.Equal(got, []constants.APIPath{constants.DevicesAPIPath}) {
+		t.Errorf("changed object types = %v, want devices", got)
+	}
+
+	watched.initialized = true
+	failingWatched.initialized = true
+	result := &Result{Trigger: TriggerWatch, Sources: []string{"testolvm", "prodolvm"}, SourceErrors: map[string]error{}}
+	r.syncChanges(ctx, result)
+	if watcher.syncedWith != r.Inventory || failingWatcher.syncedWith != r.Inventory {
+		t.Error("syncChanges() didn't sync changes of all watched sources into the inventory")
+	}
+	if _, failed := result.SourceErrors["testolvm"]; failed {
+		t.Errorf("syncChanges() failed source testolvm: %v", result.SourceErrors)
+	}
+	if _, failed := result.SourceErrors["prodolvm"]; !failed {
+		t.Errorf("syncChanges() didn't fail source prodolvm: %v", result.SourceErrors)
+	}
+
+	// Changes of watched sources aren't staged in branches
+	r.Config.Netbox.Branching.Enabled = true
+	if branch, err := r.createBranch(ctx, result); branch != nil || err != nil {
+		t.Errorf("createBranch() of watch run = %v, %v, want no branch", branch, err)
+	}
+
+	r.stopWatching()
+	if r.watchers != nil {
+		t.Error("stopWatching() kept the watchers")
+	}
+}
+
+func TestWatchRunAfterFailedBranchRun(t *testing.T) {
+	ctx := context.Background()
+	branching := &service.MockBranching{}
+	var lock sync.Mutex
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.HasPrefix(req.URL.Path, "/api/plugins/branching/") {
+			branching.ServeHTTP(w, req)
+			return
+		}
+		lock.Lock()
+		requested = append(requested, req.URL.Path)
+		lock.Unlock()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	r := testRunner(t, "valid_config1.yaml")
+	r.Config.Netbox.MaxRetries = 0
+	r.Config.Netbox.Branching = parser.BranchingConfig{Enabled: true, NamePrefix: "ssot", Timeout: 1}
+	r.Inventory = inventory.NewNetboxInventory(ctx, r.Logger, r.Config.Netbox, false)
+	r.Inventory.NetboxAPI = &service.NetboxClient{
+		HTTPClient: server.Client(),
+		Logger:     r.Logger,
+		BaseURL:    server.URL,
+		Timeout:    1,
+	}
+
+	result := &Result{ID: 1, StartTime: time.Now(), Sources: []string{"paloalto"}, SourceErrors: map[string]error{}}
+	branch, err := r.createBranch(ctx, result)
+	if err != nil {
+		t.Fatalf("createBranch() error = %s", err)
+	}
+	result.SourceErrors["paloalto"] = errors.New("connection refused")
+	r.closeBranch(ctx, result, branch)
+
+	// Objects of the branch, which wasn't merged, are removed by refreshing the whole inventory
+	watchResult := &Result{Trigger: TriggerWatch, objectTypes: []constants.APIPath{constants.DevicesAPIPath}}
+	if err := r.refreshObjectTypes(ctx, watchResult); err == nil {
+		t.Fatal("refreshObjectTypes() error = nil, want error of the failing netbox")
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if !slices.ContainsFunc(requested, func(path string) bool { return path != string(constants.DevicesAPIPath) }) {
+		t.Errorf("refreshObjectTypes() requested only %v, want the whole inventory", requested)
+	}
+}
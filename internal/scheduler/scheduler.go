@@ -0,0 +1,243 @@
+// Package scheduler provides schedules used by netbox-ssot when it runs as a
+// long-running daemon. A schedule is either a fixed interval or a standard
+// five field cron expression.
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule describes when the next synchronization run should start.
+type Schedule interface {
+	// Next returns the first activation time that is strictly after t.
+	Next(t time.Time) time.Time
+}
+
+// intervalSchedule activates in fixed intervals, relative to the previous run.
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+// Every returns a schedule that activates every interval.
+func Every(interval time.Duration) (Schedule, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive, got %s", interval)
+	}
+	return intervalSchedule{interval: interval}, nil
+}
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+func (s intervalSchedule) String() string {
+	return fmt.Sprintf("@every %s", s.interval)
+}
+
+// Bounds of each of the cron fields.
+type fieldBounds struct {
+	name     string
+	min, max int
+}
+
+var (
+	minuteBounds = fieldBounds{"minute", 0, 59}       //nolint:mnd
+	hourBounds   = fieldBounds{"hour", 0, 23}         //nolint:mnd
+	domBounds    = fieldBounds{"day of month", 1, 31} //nolint:mnd
+	monthBounds  = fieldBounds{"month", 1, 12}        //nolint:mnd
+	dowBounds    = fieldBounds{"day of week", 0, 7}   //nolint:mnd
+)
+
+// Predefined cron expressions.
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronFieldCount is the number of fields in a standard cron expression.
+const cronFieldCount = 5
+
+// maxSearchYears limits how far in the future we search for the next activation,
+// so impossible expressions (e.g. 30th of February) can't loop forever.
+const maxSearchYears = 5
+
+// cronSchedule is a schedule defined by a standard cron expression.
+// Each field is stored as a bitset of allowed values.
+type cronSchedule struct {
+	expr        string
+	minutes     uint64
+	hours       uint64
+	daysOfMonth uint64
+	months      uint64
+	daysOfWeek  uint64
+	// Whether day of month or day of week were restricted (not starting with *).
+	// Cron matches either of them, when both are restricted.
+	domRestricted bool
+	dowRestricted bool
+}
+
+// Parse parses a schedule specification. Supported formats are:
+//   - standard five field cron expression (e.g. "*/20 * * * *"),
+//   - predefined macros (@hourly, @daily, @weekly, @monthly, @yearly),
+//   - fixed intervals in format "@every <duration>" (e.g. "@every 20m").
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if rest, ok := strings.CutPrefix(spec, "@every"); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("parse interval %q: %s", spec, err)
+		}
+		return Every(interval)
+	}
+	return ParseCron(spec)
+}
+
+// ParseCron parses a standard five field cron expression
+// (minute, hour, day of month, month, day of week).
+func ParseCron(expr string) (Schedule, error) {
+	original := expr
+	if macro, ok := cronMacros[strings.TrimSpace(expr)]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != cronFieldCount {
+		return nil, fmt.Errorf("cron expression %q must have %d fields, got %d", original, cronFieldCount, len(fields))
+	}
+	schedule := &cronSchedule{expr: original}
+	var err error
+	if schedule.minutes, err = parseField(fields[0], minuteBounds); err != nil {
+		return nil, err
+	}
+	if schedule.hours, err = parseField(fields[1], hourBounds); err != nil {
+		return nil, err
+	}
+	if schedule.daysOfMonth, err = parseField(fields[2], domBounds); err != nil {
+		return nil, err
+	}
+	if schedule.months, err = parseField(fields[3], monthBounds); err != nil {
+		return nil, err
+	}
+	if schedule.daysOfWeek, err = parseField(fields[4], dowBounds); err != nil {
+		return nil, err
+	}
+	// Both 0 and 7 represent sunday
+	if schedule.daysOfWeek&(1<<7) != 0 {
+		schedule.daysOfWeek |= 1
+	}
+	// Fields starting with "*" (e.g. "*/2") are unrestricted, like in standard cron
+	schedule.domRestricted = !strings.HasPrefix(fields[2], "*")
+	schedule.dowRestricted = !strings.HasPrefix(fields[4], "*")
+	return schedule, nil
+}
+
+// parseField parses a single cron field into a bitset of allowed values.
+// Each field is a comma separated list of "*", "n", "n-m", each optionally
+// followed by a step "/s".
+func parseField(field string, bounds fieldBounds) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("%s: invalid step %q", bounds.name, stepPart)
+			}
+		}
+		start, end := bounds.min, bounds.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			lowStr, highStr, _ := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = parseValue(lowStr, bounds); err != nil {
+				return 0, err
+			}
+			if end, err = parseValue(highStr, bounds); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("%s: invalid range %q", bounds.name, rangePart)
+			}
+		default:
+			value, err := parseValue(rangePart, bounds)
+			if err != nil {
+				return 0, err
+			}
+			start = value
+			if !hasStep {
+				end = value
+			}
+		}
+		for i := start; i <= end; i += step {
+			set |= 1 << uint(i)
+		}
+	}
+	return set, nil
+}
+
+func parseValue(value string, bounds fieldBounds) (int, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid value %q", bounds.name, value)
+	}
+	if number < bounds.min || number > bounds.max {
+		return 0, fmt.Errorf("%s: value %d out of range [%d-%d]", bounds.name, number, bounds.min, bounds.max)
+	}
+	return number, nil
+}
+
+func has(set uint64, value int) bool {
+	return set&(1<<uint(value)) != 0
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := has(s.daysOfMonth, t.Day())
+	dowMatch := has(s.daysOfWeek, int(t.Weekday()))
+	if s.domRestricted && s.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// Next returns the next activation of the cron schedule after t.
+// Returns zero time if there is no activation in the next few years.
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	// Cron has a resolution of a minute, so we start at the beginning of the next minute
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if !has(s.months, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !has(s.hours, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !has(s.minutes, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *cronSchedule) String() string {
+	return s.expr
+}
@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	base := time.Date(2024, time.March, 15, 10, 7, 30, 0, time.UTC) // Friday
+	tests := []struct {
+		name    string
+		spec    string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name: "Every 20 minutes",
+			spec: "*/20 * * * *",
+			want: time.Date(2024, time.March, 15, 10, 20, 0, 0, time.UTC),
+		},
+		{
+			name: "Fixed interval",
+			spec: "@every 15m",
+			want: base.Add(15 * time.Minute),
+		},
+		{
+			name: "Hourly macro",
+			spec: "@hourly",
+			want: time.Date(2024, time.March, 15, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Daily at 2:30",
+			spec: "30 2 * * *",
+			want: time.Date(2024, time.March, 16, 2, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "Weekdays only list and range",
+			spec: "0 8,17 * * 1-5",
+			want: time.Date(2024, time.March, 15, 17, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Sunday as 7",
+			spec: "0 0 * * 7",
+			want: time.Date(2024, time.March, 17, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Day of month or day of week",
+			spec: "0 0 1 * 1",
+			want: time.Date(2024, time.March, 18, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Day of month step and day of week",
+			spec: "0 0 */2 * 1",
+			want: time.Date(2024, time.March, 25, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Next month",
+			spec: "0 0 1 4 *",
+			want: time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "Wrong number of fields",
+			spec:    "* * * *",
+			wantErr: true,
+		},
+		{
+			name:    "Value out of range",
+			spec:    "61 * * * *",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid step",
+			spec:    "*/0 * * * *",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid range",
+			spec:    "0 10-5 * * *",
+			wantErr: true,
+		},
+		{
+			name:    "Negative interval",
+			spec:    "@every -5m",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid interval",
+			spec:    "@every soon",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := schedule.Next(base); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronNextImpossibleDate(t *testing.T) {
+	schedule, err := ParseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("ParseCron() error = %v", err)
+	}
+	if got := schedule.Next(time.Now()); !got.IsZero() {
+		t.Errorf("Next() = %v, want zero time", got)
+	}
+}
@@ -0,0 +1,138 @@
+// Package secrets resolves references to secrets, which can be used in the
+// config instead of plaintext credentials. A reference consists of a scheme
+// and a provider specific path, e.g. env:NETBOX_TOKEN, file:/run/secrets/token
+// or vault:netbox-ssot/vcenter#password. Plaintext values, which start with
+// one of the schemes, are escaped with the plain scheme, e.g. plain:env:value.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Schemes of secret references.
+const (
+	SchemeEnv   = "env"
+	SchemeFile  = "file"
+	SchemeVault = "vault"
+	// SchemePlain escapes plaintext values, which would otherwise be references.
+	SchemePlain = "plain"
+)
+
+// Redacted replaces secrets, when they are printed.
+const Redacted = "********"
+
+// Provider resolves references of a single scheme.
+type Provider interface {
+	// Resolve returns the secret ref (reference without the scheme) points to.
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// EnvProvider resolves references to environment variables (env:VAR).
+type EnvProvider struct{}
+
+func (EnvProvider) Resolve(_ context.Context, ref string) (string, error) {
+	value, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", ref)
+	}
+	return value, nil
+}
+
+// FileProvider resolves references to files (file:/path). Trailing newline
+// of the file is removed, so secrets can be written with echo.
+type FileProvider struct{}
+
+func (FileProvider) Resolve(_ context.Context, ref string) (string, error) {
+	content, err := os.ReadFile(ref)
+	if err != nil {
+		return "", fmt.Errorf("read secret file: %s", err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// Resolver resolves values, which may be secret references, using providers
+// registered for their schemes.
+type Resolver struct {
+	providers map[string]Provider
+}
+
+// NewResolver returns a Resolver with env and file providers registered.
+func NewResolver() *Resolver {
+	return &Resolver{
+		providers: map[string]Provider{
+			SchemeEnv:  EnvProvider{},
+			SchemeFile: FileProvider{},
+		},
+	}
+}
+
+// Register registers provider for references with the given scheme.
+func (r *Resolver) Register(scheme string, provider Provider) {
+	r.providers[scheme] = provider
+}
+
+// IsReference returns true if value is a reference with one of the
+// known schemes. All other values are plaintext.
+func IsReference(value string) bool {
+	scheme, _, found := strings.Cut(value, ":")
+	if !found {
+		return false
+	}
+	switch scheme {
+	case SchemeEnv, SchemeFile, SchemeVault:
+		return true
+	}
+	return false
+}
+
+// Resolve returns the secret value references. Plaintext values are returned unchanged
+// and values escaped with the plain scheme are returned without it.
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	if plaintext, found := strings.CutPrefix(value, SchemePlain+":"); found {
+		return plaintext, nil
+	}
+	if !IsReference(value) {
+		return value, nil
+	}
+	provider, scheme, ref, err := r.provider(value)
+	if err != nil {
+		return "", err
+	}
+	secret, err := provider.Resolve(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s reference: %s", scheme, err)
+	}
+	return secret, nil
+}
+
+// Check checks that value is a plaintext value or a non-empty reference with
+// a configured scheme, without resolving it. Plaintext values are returned like
+// by Resolve, references are returned unchanged.
+func (r *Resolver) Check(value string) (string, error) {
+	if plaintext, found := strings.CutPrefix(value, SchemePlain+":"); found {
+		return plaintext, nil
+	}
+	if !IsReference(value) {
+		return value, nil
+	}
+	if _, _, _, err := r.provider(value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// provider returns provider, scheme and ref of the reference.
+func (r *Resolver) provider(reference string) (Provider, string, string, error) {
+	scheme, ref, _ := strings.Cut(reference, ":")
+	provider, ok := r.providers[scheme]
+	if !ok {
+		return nil, scheme, ref, fmt.Errorf("%s references are not configured", scheme)
+	}
+	if ref == "" {
+		return nil, scheme, ref, fmt.Errorf("%s reference is empty", scheme)
+	}
+	return provider, scheme, ref, nil
+}
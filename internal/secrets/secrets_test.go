@@ -0,0 +1,230 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsReference(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{value: "env:NETBOX_TOKEN", want: true},
+		{value: "file:/run/secrets/token", want: true},
+		{value: "vault:netbox-ssot/vcenter#password", want: true},
+		{value: "plaintext", want: false},
+		{value: "pass:word", want: false},
+		{value: "plain:env:NETBOX_TOKEN", want: false},
+		{value: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := IsReference(tt.value); got != tt.want {
+				t.Errorf("IsReference(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	t.Setenv("NETBOX_SSOT_TEST_SECRET", "env-secret")
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("file-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr string
+	}{
+		{name: "Plaintext value", value: "plaintext", want: "plaintext"},
+		{name: "Environment variable", value: "env:NETBOX_SSOT_TEST_SECRET", want: "env-secret"},
+		{name: "File", value: "file:" + secretFile, want: "file-secret"},
+		{name: "Escaped plaintext", value: "plain:env:NETBOX_SSOT_TEST_SECRET", want: "env:NETBOX_SSOT_TEST_SECRET"},
+		{name: "Escaped plain prefix", value: "plain:plain:value", want: "plain:value"},
+		{
+			name:    "Missing environment variable",
+			value:   "env:NETBOX_SSOT_TEST_MISSING",
+			wantErr: "resolve env reference: environment variable NETBOX_SSOT_TEST_MISSING is not set",
+		},
+		{name: "Empty reference", value: "env:", wantErr: "env reference is empty"},
+		{name: "Unconfigured vault", value: "vault:ssot#token", wantErr: "vault references are not configured"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewResolver().Resolve(context.Background(), tt.value)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Resolve() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolver_Check(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr string
+	}{
+		{name: "Plaintext value", value: "plaintext", want: "plaintext"},
+		{name: "Escaped plaintext", value: "plain:env:VALUE", want: "env:VALUE"},
+		{name: "Missing environment variable", value: "env:NETBOX_SSOT_TEST_MISSING", want: "env:NETBOX_SSOT_TEST_MISSING"},
+		{name: "Missing file", value: "file:/nonexistent/secret", want: "file:/nonexistent/secret"},
+		{name: "Empty reference", value: "file:", wantErr: "file reference is empty"},
+		{name: "Unconfigured vault", value: "vault:ssot#token", wantErr: "vault references are not configured"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewResolver().Check(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Check() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Check() error = %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("Check() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// newVaultServer returns a stand-in for Vault, which responds with body
+// to authenticated requests of path and counts all requests.
+func newVaultServer(t *testing.T, path string, body string, requests *int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != path {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVaultProvider_Resolve(t *testing.T) {
+	tests := []struct {
+		name      string
+		kvVersion int
+		mount     string
+		path      string
+		body      string
+		token     string
+		ref       string
+		want      string
+		wantErr   string
+	}{
+		{
+			name: "KV version 2",
+			path: "/v1/secret/data/netbox-ssot/vcenter",
+			body: `{"data": {"data": {"password": "vcenter-pass"}, "metadata": {"version": 3}}}`,
+			ref:  "netbox-ssot/vcenter#password",
+			want: "vcenter-pass",
+		},
+		{
+			name:      "KV version 1 with custom mount",
+			kvVersion: 1,
+			mount:     "kv",
+			path:      "/v1/kv/netbox-ssot/vcenter",
+			body:      `{"data": {"password": "vcenter-pass"}}`,
+			ref:       "netbox-ssot/vcenter#password",
+			want:      "vcenter-pass",
+		},
+		{
+			name:    "Missing key",
+			path:    "/v1/secret/data/netbox-ssot/vcenter",
+			body:    `{"data": {"data": {"password": "vcenter-pass"}}}`,
+			ref:     "netbox-ssot/vcenter#username",
+			wantErr: "secret netbox-ssot/vcenter has no key username",
+		},
+		{
+			name:    "Reference without key",
+			ref:     "netbox-ssot/vcenter",
+			wantErr: "netbox-ssot/vcenter is not in path#key format",
+		},
+		{
+			name:    "Wrong token",
+			path:    "/v1/secret/data/netbox-ssot/vcenter",
+			token:   "wrong",
+			ref:     "netbox-ssot/vcenter#password",
+			wantErr: "read secret netbox-ssot/vcenter: unexpected status code 403",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			server := newVaultServer(t, tt.path, tt.body, &requests)
+			token := tt.token
+			if token == "" {
+				token = "vault-token"
+			}
+			provider := &VaultProvider{
+				Address:    server.URL,
+				Token:      token,
+				Mount:      tt.mount,
+				KVVersion:  tt.kvVersion,
+				HTTPClient: server.Client(),
+			}
+			got, err := provider.Resolve(context.Background(), tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Resolve() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVaultProvider_CachesSecrets(t *testing.T) {
+	var requests int
+	server := newVaultServer(
+		t,
+		"/v1/secret/data/netbox-ssot/vcenter",
+		`{"data": {"data": {"username": "admin", "password": "vcenter-pass"}}}`,
+		&requests,
+	)
+	resolver := NewResolver()
+	resolver.Register(SchemeVault, &VaultProvider{Address: server.URL, Token: "vault-token"})
+	for _, ref := range []string{"vault:netbox-ssot/vcenter#username", "vault:netbox-ssot/vcenter#password"} {
+		if _, err := resolver.Resolve(context.Background(), ref); err != nil {
+			t.Fatalf("Resolve(%s) error = %s", ref, err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Vault received %d requests, want 1", requests)
+	}
+}
@@ -0,0 +1,127 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Defaults of the VaultProvider.
+const (
+	DefaultVaultMount     = "secret"
+	DefaultVaultKVVersion = 2
+)
+
+// VaultProvider resolves references to keys of secrets stored in a
+// HashiCorp Vault KV secrets engine. References are in path#key format,
+// e.g. vault:netbox-ssot/vcenter#password.
+type VaultProvider struct {
+	// Address of the Vault server (e.g. https://vault.example.com:8200).
+	Address string
+	// Token used to authenticate to Vault.
+	Token string
+	// Mount path of the KV secrets engine. Defaults to DefaultVaultMount.
+	Mount string
+	// KVVersion is the version of the KV secrets engine (1 or 2). Defaults to DefaultVaultKVVersion.
+	KVVersion int
+	// HTTPClient used for requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	lock sync.Mutex
+	// cache of already read secrets, because multiple keys are often read from the same secret.
+	cache map[string]map[string]any
+}
+
+func (v *VaultProvider) Resolve(ctx context.Context, ref string) (string, error) {
+	path, key, found := strings.Cut(ref, "#")
+	if !found || path == "" || key == "" {
+		return "", fmt.Errorf("%s is not in path#key format", ref)
+	}
+	data, err := v.readSecret(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %s", path, key)
+	}
+	secret, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("key %s of secret %s is not a string", key, path)
+	}
+	return secret, nil
+}
+
+// readSecret returns key value pairs of the secret at path.
+func (v *VaultProvider) readSecret(ctx context.Context, path string) (map[string]any, error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if data, ok := v.cache[path]; ok {
+		return data, nil
+	}
+
+	mount := v.Mount
+	if mount == "" {
+		mount = DefaultVaultMount
+	}
+	kvVersion := v.KVVersion
+	if kvVersion == 0 {
+		kvVersion = DefaultVaultKVVersion
+	}
+	requestURL, err := url.JoinPath(v.Address, "v1", mount, path)
+	if kvVersion == DefaultVaultKVVersion {
+		requestURL, err = url.JoinPath(v.Address, "v1", mount, "data", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("vault url: %s", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("X-Vault-Token", v.Token)
+	httpClient := v.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("read secret %s: %s", path, err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read secret %s: %s", path, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("read secret %s: unexpected status code %d", path, response.StatusCode)
+	}
+
+	var secret struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, fmt.Errorf("read secret %s: %s", path, err)
+	}
+	data := secret.Data
+	if kvVersion == DefaultVaultKVVersion {
+		// KV version 2 wraps the secret together with its metadata
+		versioned, _ := data["data"].(map[string]any)
+		data = versioned
+	}
+	if data == nil {
+		return nil, fmt.Errorf("read secret %s: response has no data", path)
+	}
+
+	if v.cache == nil {
+		v.cache = make(map[string]map[string]any)
+	}
+	v.cache[path] = data
+	return data, nil
+}
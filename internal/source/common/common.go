@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/x509"
 
+	"github.com/bl4ko/netbox-ssot/internal/constants"
 	"github.com/bl4ko/netbox-ssot/internal/logger"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
@@ -11,11 +12,25 @@ import (
 )
 
 // Source is an interface for all sources (e.g. oVirt, VMware, etc.).
+// Both Init and Sync stop early and return an error when ctx is done.
+// Ctx is expected to carry the name of the source under constants.CtxSourceKey.
 type Source interface {
 	// Init initializes the source
-	Init() error
+	Init(ctx context.Context) error
 	// Sync syncs the source to Netbox inventory
-	Sync(*inventory.NetboxInventory) error
+	Sync(ctx context.Context, nbi *inventory.NetboxInventory) error
+}
+
+// Watcher is implemented by sources, which can be watched for changes,
+// so only the changed objects are synced between the scheduled runs.
+type Watcher interface {
+	// Watch collects changes of the source, until ctx is canceled. Each time new
+	// changes are collected, changed is called with API paths of the object types,
+	// which are updated in Netbox when the changes are synced.
+	Watch(ctx context.Context, changed func(objectTypes ...constants.APIPath)) error
+	// SyncChanges syncs objects changed since the previous Init or SyncChanges.
+	// Source must be initialized with Init before.
+	SyncChanges(ctx context.Context, nbi *inventory.NetboxInventory) error
 }
 
 // Config is a common configuration that all sources share.
@@ -25,8 +40,9 @@ type Config struct {
 	CustomCertPool *x509.CertPool
 	SourceNameTag  *objects.Tag
 	SourceTypeTag  *objects.Tag
-	Ctx            context.Context //nolint:containedctx
-	CAFile         string          // path to the ca file
+	// Ctx of the current Init or Sync call, used by helper functions of the source.
+	Ctx    context.Context //nolint:containedctx
+	CAFile string          // path to the ca file
 }
 
 func (c Config) GetSourceTags() []*objects.Tag {
@@ -0,0 +1,145 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/utils"
+)
+
+// MapCustomFields maps attributes of an object of the source to custom fields of the object,
+// with customFieldMappings of the source in format attribute = customFieldName. It returns
+// values of mapped custom fields by their names, to which other custom fields can be added.
+//
+// Custom fields, which don't exist in netbox yet, are created with the type of the value
+// (text, integer, decimal or boolean). Existing custom fields are extended to objectType,
+// and values are converted to their type. Values, which can't be converted, are skipped.
+func MapCustomFields(
+	ctx context.Context,
+	nbi *inventory.NetboxInventory,
+	mappings map[string]string,
+	objectType constants.ContentType,
+	attributes map[string]interface{},
+) (map[string]interface{}, error) {
+	customFields := map[string]interface{}{}
+	for attribute, customFieldName := range mappings {
+		value, ok := attributes[attribute]
+		if !ok || value == nil {
+			continue
+		}
+		customFieldName = utils.Alphanumeric(customFieldName)
+		customFieldType, customFieldValue := customFieldTypeOf(value)
+		existingType, err := addCustomFieldForObjectType(ctx, nbi, customFieldName, customFieldType, objectType)
+		if err != nil {
+			return nil, fmt.Errorf("custom field %s: %s", customFieldName, err)
+		}
+		if existingType.Value != customFieldType.Value {
+			convertedValue, ok := convertCustomFieldValue(customFieldValue, existingType)
+			if !ok {
+				nbi.Logger.Warningf(
+					ctx,
+					"skipping value %v of attribute %s, which can't be converted to type %s of custom field %s",
+					value,
+					attribute,
+					existingType.Value,
+					customFieldName,
+				)
+				continue
+			}
+			customFieldValue = convertedValue
+		}
+		customFields[customFieldName] = customFieldValue
+	}
+	return customFields, nil
+}
+
+// customFieldTypeOf returns type of the custom field for the value, and the value converted
+// to that type. Lists are joined to text, and values of other types are formatted as text.
+func customFieldTypeOf(value interface{}) (objects.CustomFieldType, interface{}) {
+	switch value := value.(type) {
+	case string:
+		return objects.CustomFieldTypeText, value
+	case []string:
+		return objects.CustomFieldTypeText, strings.Join(value, ", ")
+	case bool:
+		return objects.CustomFieldTypeBoolean, value
+	case int:
+		return objects.CustomFieldTypeInteger, value
+	case int64:
+		return objects.CustomFieldTypeInteger, value
+	case float64:
+		return objects.CustomFieldTypeDecimal, value
+	case json.Number:
+		if integer, err := value.Int64(); err == nil {
+			return objects.CustomFieldTypeInteger, integer
+		}
+		if decimal, err := value.Float64(); err == nil {
+			return objects.CustomFieldTypeDecimal, decimal
+		}
+		return objects.CustomFieldTypeText, value.String()
+	default:
+		return objects.CustomFieldTypeText, fmt.Sprint(value)
+	}
+}
+
+// convertCustomFieldValue converts the value returned by customFieldTypeOf to customFieldType.
+// It returns false, if the value can't be converted.
+func convertCustomFieldValue(value interface{}, customFieldType objects.CustomFieldType) (interface{}, bool) {
+	text := fmt.Sprint(value)
+	if decimal, ok := value.(float64); ok {
+		text = strconv.FormatFloat(decimal, 'f', -1, 64)
+	}
+	switch customFieldType.Value {
+	case objects.CustomFieldTypeText.Value, objects.CustomFieldTypeLongText.Value:
+		return text, true
+	case objects.CustomFieldTypeInteger.Value:
+		if decimal, ok := value.(float64); ok && decimal == math.Trunc(decimal) {
+			return int64(decimal), true
+		}
+		integer, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+		return integer, err == nil
+	case objects.CustomFieldTypeDecimal.Value:
+		decimal, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		return decimal, err == nil
+	case objects.CustomFieldTypeBoolean.Value:
+		boolean, err := strconv.ParseBool(strings.TrimSpace(text))
+		return boolean, err == nil
+	default:
+		return nil, false
+	}
+}
+
+// addCustomFieldForObjectType ensures, that the custom field exists for objects of objectType.
+// Type of an existing custom field is kept, and it returns the type of the custom field.
+func addCustomFieldForObjectType(
+	ctx context.Context,
+	nbi *inventory.NetboxInventory,
+	name string,
+	customFieldType objects.CustomFieldType,
+	objectType constants.ContentType,
+) (objects.CustomFieldType, error) {
+	objectTypes := []constants.ContentType{objectType}
+	if existingCustomField, ok := nbi.GetCustomField(name); ok {
+		if slices.Contains(existingCustomField.ObjectTypes, objectType) {
+			return existingCustomField.Type, nil
+		}
+		customFieldType = existingCustomField.Type
+		objectTypes = append(slices.Clone(existingCustomField.ObjectTypes), objectType)
+	}
+	_, err := nbi.AddCustomField(ctx, &objects.CustomField{
+		Name:                  name,
+		Type:                  customFieldType,
+		CustomFieldUIVisible:  &objects.CustomFieldUIVisibleIfSet,
+		CustomFieldUIEditable: &objects.CustomFieldUIEditableYes,
+		ObjectTypes:           objectTypes,
+	})
+	return customFieldType, err
+}
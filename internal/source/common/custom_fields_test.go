@@ -0,0 +1,128 @@
+package common
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+)
+
+func TestMapCustomFields(t *testing.T) {
+	setupMockServer(t)
+	mappings := map[string]string{
+		"metadata.Owner": "Existing CF1",
+		"tags":           "existing_cf2",
+		"missing":        "missing_field",
+	}
+	attributes := map[string]interface{}{
+		"metadata.Owner": "team-a",
+		"tags":           []string{"web", "prod"},
+		"unmapped":       "value",
+	}
+	got, err := MapCustomFields(
+		testCtx(), inventory.MockInventory, mappings, constants.ContentTypeVirtualizationVirtualMachine, attributes,
+	)
+	if err != nil {
+		t.Fatalf("MapCustomFields() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"existing_cf1": "team-a",
+		"existing_cf2": "web, prod",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MapCustomFields() = %v, want %v", got, want)
+	}
+}
+
+func TestMapCustomFields_ExistingType(t *testing.T) {
+	setupMockServer(t)
+	mappings := map[string]string{"enabled": "existing_cf1"}
+	attributes := map[string]interface{}{"enabled": true}
+	got, err := MapCustomFields(
+		testCtx(), inventory.MockInventory, mappings, constants.ContentTypeDcimDevice, attributes,
+	)
+	if err != nil {
+		t.Fatalf("MapCustomFields() error = %v", err)
+	}
+	want := map[string]interface{}{"existing_cf1": "true"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MapCustomFields() = %v, want %v", got, want)
+	}
+}
+
+func TestMapCustomFields_NoMappings(t *testing.T) {
+	got, err := MapCustomFields(
+		testCtx(), nil, nil, constants.ContentTypeDcimDevice, map[string]interface{}{"name": "host1"},
+	)
+	if err != nil {
+		t.Fatalf("MapCustomFields() error = %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("MapCustomFields() = %v, want empty map", got)
+	}
+}
+
+func TestCustomFieldTypeOf(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     interface{}
+		wantType  objects.CustomFieldType
+		wantValue interface{}
+	}{
+		{"String", "value", objects.CustomFieldTypeText, "value"},
+		{"List", []string{"a", "b"}, objects.CustomFieldTypeText, "a, b"},
+		{"Boolean", true, objects.CustomFieldTypeBoolean, true},
+		{"Integer", 42, objects.CustomFieldTypeInteger, 42},
+		{"Integer64", int64(42), objects.CustomFieldTypeInteger, int64(42)},
+		{"Decimal", 1.5, objects.CustomFieldTypeDecimal, 1.5},
+		{"WholeNumber", json.Number("3600"), objects.CustomFieldTypeInteger, int64(3600)},
+		{"DecimalNumber", json.Number("1.5"), objects.CustomFieldTypeDecimal, 1.5},
+		{"Other", uint8(7), objects.CustomFieldTypeText, "7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotType, gotValue := customFieldTypeOf(tt.value)
+			if gotType != tt.wantType {
+				t.Errorf("customFieldTypeOf() type = %v, want %v", gotType, tt.wantType)
+			}
+			if !reflect.DeepEqual(gotValue, tt.wantValue) {
+				t.Errorf("customFieldTypeOf() value = %v, want %v", gotValue, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestConvertCustomFieldValue(t *testing.T) {
+	tests := []struct {
+		name            string
+		value           interface{}
+		customFieldType objects.CustomFieldType
+		wantValue       interface{}
+		wantOk          bool
+	}{
+		{"BooleanToText", true, objects.CustomFieldTypeText, "true", true},
+		{"IntegerToText", int64(42), objects.CustomFieldTypeLongText, "42", true},
+		{"DecimalToText", 1.5, objects.CustomFieldTypeText, "1.5", true},
+		{"TextToInteger", "42", objects.CustomFieldTypeInteger, int64(42), true},
+		{"WholeDecimalToInteger", 3600.0, objects.CustomFieldTypeInteger, int64(3600), true},
+		{"DecimalToInteger", 1.5, objects.CustomFieldTypeInteger, int64(0), false},
+		{"IntegerToDecimal", 42, objects.CustomFieldTypeDecimal, 42.0, true},
+		{"TextToBoolean", "false", objects.CustomFieldTypeBoolean, false, true},
+		{"TextToBooleanInvalid", "team-a", objects.CustomFieldTypeBoolean, false, false},
+		{"TextToDate", "2024-01-01", objects.CustomFieldTypeDate, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotValue, gotOk := convertCustomFieldValue(tt.value, tt.customFieldType)
+			if gotOk != tt.wantOk {
+				t.Fatalf("convertCustomFieldValue() ok = %v, want %v", gotOk, tt.wantOk)
+			}
+			if gotOk && !reflect.DeepEqual(gotValue, tt.wantValue) {
+				t.Errorf("convertCustomFieldValue() value = %v, want %v", gotValue, tt.wantValue)
+			}
+		})
+	}
+}
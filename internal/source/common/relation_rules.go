@@ -0,0 +1,219 @@
+package common
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/expr"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+	"github.com/bl4ko/netbox-ssot/internal/utils"
+)
+
+// RelationMatch are relations of an object set by relation rules, which it matches.
+// Relations, which aren't set by any matching rule, are nil.
+type RelationMatch struct {
+	Site      *objects.Site
+	Tenant    *objects.Tenant
+	Role      *objects.DeviceRole
+	Location  *objects.Location
+	VlanGroup *objects.VlanGroup
+	// Tags of all matching rules.
+	Tags []*objects.Tag
+}
+
+// MatchRelationRules matches the object of objectType with the attributes to relation rules.
+// Site, tenant, role, location and vlan group are set by the first matching rule, which sets
+// them, and tags are added by all matching rules. Location is added to the matched site, or
+// to the site of the object, if no rule sets the site. Rules without objectType match only
+// hosts and VMs.
+func MatchRelationRules(
+	ctx context.Context,
+	nbi *inventory.NetboxInventory,
+	rules []parser.RelationRule,
+	objectType constants.ContentType,
+	attributes expr.Attributes,
+	site *objects.Site,
+) (*RelationMatch, error) {
+	var siteName, tenantName, roleName, locationName, vlanGroupName string
+	var tagNames []string
+	for _, rule := range rules {
+		if !ruleMatchesObjectType(rule, objectType) || rule.Expr == nil || !rule.Expr.Match(attributes) {
+			continue
+		}
+		siteName = firstNonEmpty(siteName, rule.Site)
+		tenantName = firstNonEmpty(tenantName, rule.Tenant)
+		roleName = firstNonEmpty(roleName, rule.Role)
+		locationName = firstNonEmpty(locationName, rule.Location)
+		vlanGroupName = firstNonEmpty(vlanGroupName, rule.VlanGroup)
+		tagNames = append(tagNames, rule.Tags...)
+	}
+
+	match := &RelationMatch{}
+	var err error
+	if siteName != "" {
+		match.Site, err = nbi.AddSite(ctx, &objects.Site{Name: siteName, Slug: utils.Slugify(siteName)})
+		if err != nil {
+			return nil, fmt.Errorf("add site: %s", err)
+		}
+		site = match.Site
+	}
+	if tenantName != "" {
+		match.Tenant, err = nbi.AddTenant(ctx, &objects.Tenant{Name: tenantName, Slug: utils.Slugify(tenantName)})
+		if err != nil {
+			return nil, fmt.Errorf("add tenant: %s", err)
+		}
+	}
+	if roleName != "" {
+		match.Role, err = nbi.AddDeviceRole(ctx, &objects.DeviceRole{Name: roleName, Slug: utils.Slugify(roleName)})
+		if err != nil {
+			return nil, fmt.Errorf("add role: %s", err)
+		}
+	}
+	if locationName != "" && site != nil {
+		match.Location, err = nbi.AddLocation(ctx, &objects.Location{
+			Name:   locationName,
+			Slug:   utils.Slugify(locationName),
+			Site:   site,
+			Status: &objects.SiteStatusActive,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("add location: %s", err)
+		}
+	}
+	if vlanGroupName != "" {
+		match.VlanGroup, err = nbi.AddVlanGroup(ctx, &objects.VlanGroup{
+			Name:      vlanGroupName,
+			Slug:      utils.Slugify(vlanGroupName),
+			VidRanges: []objects.VidRange{{constants.DefaultVID, constants.MaxVID}},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("add vlan group: %s", err)
+		}
+	}
+	for _, tagName := range tagNames {
+		tag, err := nbi.AddTag(ctx, &objects.Tag{
+			Name:        tagName,
+			Slug:        utils.Slugify(tagName),
+			Color:       constants.ColorGrey,
+			Description: "Tag set by relation rules",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("add tag: %s", err)
+		}
+		match.Tags = append(match.Tags, tag)
+	}
+	return match, nil
+}
+
+// ApplyRelationRules matches the object (*objects.Device, *objects.VM, *objects.Cluster
+// or *objects.Vlan) with the attributes to relation rules, and sets relations of the object
+// set by the matching rules. Relations, which aren't set by any matching rule, are kept.
+func ApplyRelationRules(
+	ctx context.Context,
+	nbi *inventory.NetboxInventory,
+	rules []parser.RelationRule,
+	object objects.IDItem,
+	attributes expr.Attributes,
+) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	var site *objects.Site
+	switch object := object.(type) {
+	case *objects.Device:
+		site = object.Site
+	case *objects.VM:
+		site = object.Site
+	}
+	match, err := MatchRelationRules(ctx, nbi, rules, object.GetObjectType(), attributes, site)
+	if err != nil {
+		return err
+	}
+	switch object := object.(type) {
+	case *objects.Device:
+		if match.Site != nil && object.Location != nil && object.Location.Site != nil &&
+			object.Location.Site.ID != match.Site.ID {
+			// Location of the source is in another site
+			object.Location = nil
+		}
+		object.Site = cmp.Or(match.Site, object.Site)
+		object.Tenant = cmp.Or(match.Tenant, object.Tenant)
+		object.DeviceRole = cmp.Or(match.Role, object.DeviceRole)
+		object.Location = cmp.Or(match.Location, object.Location)
+		object.Tags = append(slices.Clip(object.Tags), match.Tags...)
+	case *objects.VM:
+		object.Site = cmp.Or(match.Site, object.Site)
+		object.Tenant = cmp.Or(match.Tenant, object.Tenant)
+		object.Role = cmp.Or(match.Role, object.Role)
+		object.Tags = append(slices.Clip(object.Tags), match.Tags...)
+	case *objects.Cluster:
+		if match.Site != nil {
+			object.ScopeType = constants.ContentTypeDcimSite
+			object.ScopeID = match.Site.ID
+		}
+		object.Tenant = cmp.Or(match.Tenant, object.Tenant)
+		object.Tags = append(slices.Clip(object.Tags), match.Tags...)
+	case *objects.Vlan:
+		object.Site = cmp.Or(match.Site, object.Site)
+		object.Tenant = cmp.Or(match.Tenant, object.Tenant)
+		object.Group = cmp.Or(match.VlanGroup, object.Group)
+		object.Tags = append(slices.Clip(object.Tags), match.Tags...)
+	default:
+		return fmt.Errorf("relation rules can't be applied to %T", object)
+	}
+	return nil
+}
+
+// MatchVlanGroupRules returns the group of the vlan with vlanName and vid set by relation
+// rules, or group, if no matching rule sets it. Vlans are created with relations set by
+// the rules, so they have to be looked up in this group.
+func MatchVlanGroupRules(
+	ctx context.Context,
+	nbi *inventory.NetboxInventory,
+	rules []parser.RelationRule,
+	vlanName string,
+	vid int,
+	group *objects.VlanGroup,
+) (*objects.VlanGroup, error) {
+	if len(rules) == 0 {
+		return group, nil
+	}
+	attributes := VlanAttributes(&objects.Vlan{Name: vlanName, Vid: vid})
+	match, err := MatchRelationRules(ctx, nbi, rules, constants.ContentTypeIpamVlan, attributes, nil)
+	if err != nil {
+		return nil, err
+	}
+	return cmp.Or(match.VlanGroup, group), nil
+}
+
+// VlanAttributes returns attributes of the vlan, which are matched by relation rules.
+// Vlans are matched only by their names and VIDs, so the same rules match the vlan,
+// when it is created and when it is looked up (e.g. for interfaces).
+func VlanAttributes(vlan *objects.Vlan) expr.Attributes {
+	attributes := expr.Attributes{}
+	attributes.Set(parser.RelationAttributeName, vlan.Name)
+	attributes.Set(parser.RelationAttributeVID, strconv.Itoa(vlan.Vid))
+	return attributes
+}
+
+// ruleMatchesObjectType returns true if the rule matches objects of objectType.
+func ruleMatchesObjectType(rule parser.RelationRule, objectType constants.ContentType) bool {
+	if rule.ObjectType == "" {
+		return objectType == constants.ContentTypeDcimDevice ||
+			objectType == constants.ContentTypeVirtualizationVirtualMachine
+	}
+	return rule.ObjectType == objectType
+}
+
+func firstNonEmpty(current string, value string) string {
+	if current != "" {
+		return current
+	}
+	return value
+}
@@ -0,0 +1,137 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/expr"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+)
+
+func relationRule(t *testing.T, rule parser.RelationRule) parser.RelationRule {
+	t.Helper()
+	compiled, err := expr.Compile(rule.Match, map[string]bool{"name": false, "cluster": false, "tags": false})
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	rule.Expr = compiled
+	return rule
+}
+
+func TestMatchRelationRules(t *testing.T) {
+	setupMockServer(t)
+	rules := []parser.RelationRule{
+		relationRule(t, parser.RelationRule{
+			Match:      `cluster == "Prod"`,
+			ObjectType: constants.ContentTypeVirtualizationVirtualMachine,
+			Tenant:     "existing_tenant2",
+		}),
+		relationRule(t, parser.RelationRule{
+			Match:  `cluster == "Prod" && tags == "web"`,
+			Tenant: "existing_tenant1",
+			Role:   "Web servers",
+			Tags:   []string{"Web"},
+		}),
+		relationRule(t, parser.RelationRule{
+			Match:  `cluster == "Prod"`,
+			Tenant: "existing_tenant2",
+			Site:   "existing_site1",
+		}),
+		relationRule(t, parser.RelationRule{Match: `cluster == "Dev"`, Site: "existing_site2"}),
+	}
+	attributes := expr.Attributes{}
+	attributes.Set("cluster", "Prod")
+	attributes.Set("tags", "web", "backup")
+
+	match, err := MatchRelationRules(
+		testCtx(), inventory.MockInventory, rules, constants.ContentTypeDcimDevice, attributes, nil,
+	)
+	if err != nil {
+		t.Fatalf("MatchRelationRules() error = %v", err)
+	}
+	if match.Site == nil || match.Site.Name != "existing_site1" {
+		t.Errorf("site = %v, want existing_site1", match.Site)
+	}
+	if match.Tenant == nil || match.Tenant.Name != "existing_tenant1" {
+		t.Errorf("tenant = %v, want existing_tenant1", match.Tenant)
+	}
+	if match.Role == nil || match.Role.Name != "Web servers" {
+		t.Errorf("role = %v, want Web servers", match.Role)
+	}
+	if match.Location != nil {
+		t.Errorf("location = %v, want nil", match.Location)
+	}
+	if len(match.Tags) != 1 || match.Tags[0].Name != "Web" {
+		t.Errorf("tags = %v, want [Web]", match.Tags)
+	}
+}
+
+func TestMatchRelationRules_NoRules(t *testing.T) {
+	match, err := MatchRelationRules(testCtx(), nil, nil, constants.ContentTypeDcimDevice, expr.Attributes{}, nil)
+	if err != nil {
+		t.Fatalf("MatchRelationRules() error = %v", err)
+	}
+	if !reflect.DeepEqual(match, &RelationMatch{}) {
+		t.Errorf("MatchRelationRules() = %+v, want empty match", match)
+	}
+}
+
+func TestMatchRelationRules_Vlans(t *testing.T) {
+	setupMockServer(t)
+	rules := []parser.RelationRule{
+		relationRule(t, parser.RelationRule{Match: `cluster == "Prod"`, Site: "existing_site1"}),
+		relationRule(t, parser.RelationRule{
+			Match:      `cluster == "Prod"`,
+			ObjectType: constants.ContentTypeIpamVlan,
+			Tenant:     "existing_tenant1",
+			VlanGroup:  "existing_vlan_group1",
+		}),
+	}
+	attributes := expr.Attributes{}
+	attributes.Set("cluster", "Prod")
+
+	match, err := MatchRelationRules(
+		testCtx(), inventory.MockInventory, rules, constants.ContentTypeIpamVlan, attributes, nil,
+	)
+	if err != nil {
+		t.Fatalf("MatchRelationRules() error = %v", err)
+	}
+	if match.Site != nil {
+		t.Errorf("site = %v, want nil, because rules without objectType match only hosts and VMs", match.Site)
+	}
+	if match.Tenant == nil || match.Tenant.Name != "existing_tenant1" {
+		t.Errorf("tenant = %v, want existing_tenant1", match.Tenant)
+	}
+	if match.VlanGroup == nil {
+		t.Error("vlan group = nil, want existing_vlan_group1")
+	}
+}
+
+func TestApplyRelationRules(t *testing.T) {
+	setupMockServer(t)
+	rules := []parser.RelationRule{
+		relationRule(t, parser.RelationRule{
+			Match:      `name == "Prod"`,
+			ObjectType: constants.ContentTypeVirtualizationCluster,
+			Site:       "existing_site1",
+			Tenant:     "existing_tenant1",
+		}),
+	}
+	attributes := expr.Attributes{}
+	attributes.Set("name", "Prod")
+	cluster := &objects.Cluster{Name: "Prod"}
+
+	err := ApplyRelationRules(testCtx(), inventory.MockInventory, rules, cluster, attributes)
+	if err != nil {
+		t.Fatalf("ApplyRelationRules() error = %v", err)
+	}
+	if cluster.ScopeType != constants.ContentTypeDcimSite || cluster.ScopeID == 0 {
+		t.Errorf("scope = %s %d, want site existing_site1", cluster.ScopeType, cluster.ScopeID)
+	}
+	if cluster.Tenant == nil || cluster.Tenant.Name != "existing_tenant1" {
+		t.Errorf("tenant = %v, want existing_tenant1", cluster.Tenant)
+	}
+}
@@ -0,0 +1,127 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+)
+
+// SourceDataVersion is version of the format of source data files. It has
+// to be increased whenever dumped data of a source changes incompatibly.
+const SourceDataVersion = 1
+
+// SourceData is content of a source data file, which is written with
+// --dump-source-data, and synced by the replay source.
+type SourceData struct {
+	// Version of the format of the file, see SourceDataVersion.
+	Version int `json:"version"`
+	// Name of the dumped source.
+	Name string `json:"name"`
+	// Type of the dumped source.
+	Type constants.SourceType `json:"type"`
+	// DumpedAt is the time, when the data was dumped.
+	DumpedAt time.Time `json:"dumpedAt"`
+	// Data are fields of the source initialized in Init, by their names.
+	Data map[string]json.RawMessage `json:"data"`
+}
+
+// Dumper is implemented by sources, whose data fetched in Init can be dumped,
+// and later loaded instead of calling Init, so Sync runs without the source.
+type Dumper interface {
+	// DumpData returns data fetched in Init, by names of fields of the source.
+	DumpData() (map[string]json.RawMessage, error)
+	// LoadData loads data returned by DumpData.
+	LoadData(data map[string]json.RawMessage) error
+}
+
+// WriteSourceData dumps data of the source into file <name>.json in dir,
+// and returns path of the file.
+func WriteSourceData(dir string, name string, sourceType constants.SourceType, source Dumper) (string, error) {
+	data, err := source.DumpData()
+	if err != nil {
+		return "", fmt.Errorf("dump data: %s", err)
+	}
+	content, err := json.MarshalIndent(SourceData{
+		Version:  SourceDataVersion,
+		Name:     name,
+		Type:     sourceType,
+		DumpedAt: time.Now(),
+		Data:     data,
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil { //nolint:mnd
+		return "", err
+	}
+	path := filepath.Join(dir, name+".json")
+	if err := os.WriteFile(path, content, 0o600); err != nil { //nolint:mnd
+		return "", err
+	}
+	return path, nil
+}
+
+// ReadSourceData reads the source data file at path.
+func ReadSourceData(path string) (*SourceData, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sourceData SourceData
+	if err := json.Unmarshal(content, &sourceData); err != nil {
+		return nil, fmt.Errorf("parse %s: %s", path, err)
+	}
+	if sourceData.Version != SourceDataVersion {
+		return nil, fmt.Errorf(
+			"%s has version %d, but only version %d is supported", path, sourceData.Version, SourceDataVersion,
+		)
+	}
+	return &sourceData, nil
+}
+
+// MarshalFields marshals the given fields of the struct source points to with marshal.
+func MarshalFields(
+	source any,
+	fields []string,
+	marshal func(any) ([]byte, error),
+) (map[string]json.RawMessage, error) {
+	sourceValue := reflect.ValueOf(source).Elem()
+	data := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		fieldValue := sourceValue.FieldByName(field)
+		if !fieldValue.IsValid() {
+			return nil, fmt.Errorf("%T has no field %s", source, field)
+		}
+		content, err := marshal(fieldValue.Interface())
+		if err != nil {
+			return nil, fmt.Errorf("marshal %s: %s", field, err)
+		}
+		data[field] = content
+	}
+	return data, nil
+}
+
+// UnmarshalFields unmarshals data into fields of the struct source points to with unmarshal.
+// Fields, which aren't in data, are left unchanged.
+func UnmarshalFields(
+	source any,
+	data map[string]json.RawMessage,
+	unmarshal func([]byte, any) error,
+) error {
+	sourceValue := reflect.ValueOf(source).Elem()
+	for field, content := range data {
+		fieldValue := sourceValue.FieldByName(field)
+		if !fieldValue.IsValid() || !fieldValue.CanSet() {
+			return fmt.Errorf("%T has no field %s", source, field)
+		}
+		if err := unmarshal(content, fieldValue.Addr().Interface()); err != nil {
+			return fmt.Errorf("unmarshal %s: %s", field, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package common
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+)
+
+type testDumper struct {
+	Names   []string
+	Name2ID map[string]int
+}
+
+func (td *testDumper) DumpData() (map[string]json.RawMessage, error) {
+	return MarshalFields(td, []string{"Names", "Name2ID"}, json.Marshal)
+}
+
+func (td *testDumper) LoadData(data map[string]json.RawMessage) error {
+	return UnmarshalFields(td, data, json.Unmarshal)
+}
+
+func TestWriteReadSourceData(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dump")
+	source := &testDumper{Names: []string{"a", "b"}, Name2ID: map[string]int{"a": 1}}
+	path, err := WriteSourceData(dir, "test", constants.Vmware, source)
+	if err != nil {
+		t.Fatalf("WriteSourceData() error = %v", err)
+	}
+	if want := filepath.Join(dir, "test.json"); path != want {
+		t.Errorf("WriteSourceData() path = %s, want %s", path, want)
+	}
+	sourceData, err := ReadSourceData(path)
+	if err != nil {
+		t.Fatalf("ReadSourceData() error = %v", err)
+	}
+	if sourceData.Name != "test" || sourceData.Type != constants.Vmware || sourceData.Version != SourceDataVersion {
+		t.Errorf("ReadSourceData() = %+v, want test source of type %s", sourceData, constants.Vmware)
+	}
+	loaded := &testDumper{}
+	if err := loaded.LoadData(sourceData.Data); err != nil {
+		t.Fatalf("LoadData() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, source) {
+		t.Errorf("loaded source = %+v, want %+v", loaded, source)
+	}
+}
+
+func TestReadSourceData(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			content: `{"version": 1, "name": "test", "type": "vmware", "data": {}}`,
+		},
+		{
+			name:    "unsupported version",
+			content: `{"version": 2, "name": "test", "type": "vmware", "data": {}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			content: `{"version": 1`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := ReadSourceData(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadSourceData() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := ReadSourceData(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("ReadSourceData() of missing file returned nil error")
+	}
+}
+
+func TestMarshalUnmarshalFields(t *testing.T) {
+	source := &testDumper{}
+	if _, err := MarshalFields(source, []string{"Unknown"}, json.Marshal); err == nil {
+		t.Error("MarshalFields() of unknown field returned nil error")
+	}
+	tests := []struct {
+		name string
+		data map[string]json.RawMessage
+	}{
+		{name: "unknown field", data: map[string]json.RawMessage{"Unknown": json.RawMessage(`1`)}},
+		{name: "invalid content", data: map[string]json.RawMessage{"Names": json.RawMessage(`1`)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := UnmarshalFields(source, tt.data, json.Unmarshal); err == nil {
+				t.Error("UnmarshalFields() returned nil error")
+			}
+		})
+	}
+}
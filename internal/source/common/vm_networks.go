@@ -0,0 +1,188 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/utils"
+)
+
+// VMNetwork is an interface of a VM together with its MAC and IP addresses.
+type VMNetwork struct {
+	Interface  *objects.VMInterface
+	MACAddress string
+	// IPv4Addresses and IPv6Addresses of the interface. They are assigned
+	// to the interface, when it is added to netbox.
+	IPv4Addresses []*objects.IPAddress
+	IPv6Addresses []*objects.IPAddress
+}
+
+// PrimaryIPSelector selects primary IPv4 and IPv6 address of a VM
+// from its IP addresses, which were added to netbox.
+type PrimaryIPSelector func(ipv4Addresses, ipv6Addresses []*objects.IPAddress) (*objects.IPAddress, *objects.IPAddress)
+
+// VMNetworks collects networks of all VMs of a sync step, so their interfaces and
+// IP addresses are added to netbox with bulk requests across all VMs, instead of
+// a few requests per VM. VMs can be added concurrently.
+type VMNetworks struct {
+	lock sync.Mutex
+	vms  []*vmNetworks
+}
+
+type vmNetworks struct {
+	vm               *objects.VM
+	networks         []*VMNetwork
+	selectPrimaryIPs PrimaryIPSelector
+}
+
+// Add adds networks of the vm. Primary IPs of the vm are chosen with
+// selectPrimaryIPs. If it is nil, first IP addresses are primary.
+func (n *VMNetworks) Add(vm *objects.VM, networks []*VMNetwork, selectPrimaryIPs PrimaryIPSelector) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.vms = append(n.vms, &vmNetworks{vm: vm, networks: networks, selectPrimaryIPs: selectPrimaryIPs})
+}
+
+// Sync adds all collected interfaces with their MAC addresses, IP addresses and
+// prefixes of the IP addresses to netbox, and sets primary IPs of the VMs.
+// Interfaces and IP addresses are created and patched with bulk requests.
+func (n *VMNetworks) Sync(ctx context.Context, nbi *inventory.NetboxInventory) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	networks := make([]*VMNetwork, 0)
+	vmInterfaces := make([]*objects.VMInterface, 0)
+	for _, vm := range n.vms {
+		for _, network := range vm.networks {
+			networks = append(networks, network)
+			vmInterfaces = append(vmInterfaces, network.Interface)
+		}
+	}
+	nbVMInterfaces, err := nbi.AddVMInterfaces(ctx, vmInterfaces)
+	if err != nil {
+		return fmt.Errorf("add vm interfaces: %s", err)
+	}
+
+	// Primary MAC addresses are set with another bulk patch of the interfaces
+	macInterfaces := make([]*objects.VMInterface, 0)
+	macInterfaceIndexes := make([]int, 0)
+	for i, network := range networks {
+		if network.MACAddress == "" {
+			continue
+		}
+		nbMACAddress, err := CreateMACAddressForObjectType(ctx, nbi, network.MACAddress, nbVMInterfaces[i])
+		if err != nil {
+			return fmt.Errorf("create mac address for %+v: %s", network.Interface, err)
+		}
+		vmInterfaceCopy := *nbVMInterfaces[i]
+		vmInterfaceCopy.PrimaryMACAddress = nbMACAddress
+		macInterfaces = append(macInterfaces, &vmInterfaceCopy)
+		macInterfaceIndexes = append(macInterfaceIndexes, i)
+	}
+	nbMACInterfaces, err := nbi.AddVMInterfaces(ctx, macInterfaces)
+	if err != nil {
+		return fmt.Errorf("set primary mac addresses of vm interfaces: %s", err)
+	}
+	for i, nbVMInterface := range nbMACInterfaces {
+		nbVMInterfaces[macInterfaceIndexes[i]] = nbVMInterface
+	}
+
+	// VRFs matched by the source, AddIPAddresses can change VRF of the IP address
+	// to the one set in netbox
+	ipAddresses := make([]*objects.IPAddress, 0)
+	ipVRFs := make([]*objects.VRF, 0)
+	ipMaxMaskBits := make([]int, 0)
+	for i, network := range networks {
+		for _, ipAddress := range network.IPv4Addresses {
+			ipAddress.AssignedObjectID = nbVMInterfaces[i].ID
+			ipAddresses = append(ipAddresses, ipAddress)
+			ipVRFs = append(ipVRFs, ipAddress.VRF)
+			ipMaxMaskBits = append(ipMaxMaskBits, constants.MaxIPv4MaskBits)
+		}
+		for _, ipAddress := range network.IPv6Addresses {
+			ipAddress.AssignedObjectID = nbVMInterfaces[i].ID
+			ipAddresses = append(ipAddresses, ipAddress)
+			ipVRFs = append(ipVRFs, ipAddress.VRF)
+			ipMaxMaskBits = append(ipMaxMaskBits, constants.MaxIPv6MaskBits)
+		}
+	}
+	nbIPAddresses, err := nbi.AddIPAddresses(ctx, ipAddresses)
+	if err != nil {
+		nbi.Logger.Warningf(ctx, "add ip addresses: %s", err)
+	}
+	if len(nbIPAddresses) != len(ipAddresses) {
+		nbIPAddresses = make([]*objects.IPAddress, len(ipAddresses))
+	}
+	for i, nbIPAddress := range nbIPAddresses {
+		if nbIPAddress == nil {
+			continue
+		}
+		prefix, mask, err := utils.GetPrefixAndMaskFromIPAddress(ipAddresses[i].Address)
+		if err != nil {
+			nbi.Logger.Warningf(ctx, "extract prefix from ip address: %s", err)
+		} else if mask != ipMaxMaskBits[i] {
+			prefixStruct := &objects.Prefix{
+				Prefix: prefix,
+				VRF:    ipVRFs[i],
+			}
+			if _, err = nbi.AddPrefix(ctx, prefixStruct); err != nil {
+				nbi.Logger.Errorf(ctx, "add prefix %+v: %s", prefixStruct, err)
+			}
+		}
+	}
+
+	// IP addresses are mapped back to VMs in the order in which they were collected
+	nbIPAddressIndex := 0
+	for _, vm := range n.vms {
+		vmIPv4Addresses := make([]*objects.IPAddress, 0)
+		vmIPv6Addresses := make([]*objects.IPAddress, 0)
+		for _, network := range vm.networks {
+			for range network.IPv4Addresses {
+				if nbIPAddress := nbIPAddresses[nbIPAddressIndex]; nbIPAddress != nil {
+					vmIPv4Addresses = append(vmIPv4Addresses, nbIPAddress)
+				}
+				nbIPAddressIndex++
+			}
+			for range network.IPv6Addresses {
+				if nbIPAddress := nbIPAddresses[nbIPAddressIndex]; nbIPAddress != nil {
+					vmIPv6Addresses = append(vmIPv6Addresses, nbIPAddress)
+				}
+				nbIPAddressIndex++
+			}
+		}
+		setVMPrimaryIPs(ctx, nbi, vm, vmIPv4Addresses, vmIPv6Addresses)
+	}
+	n.vms = nil
+	return nil
+}
+
+// setVMPrimaryIPs sets primary IPs of the vm, selected from its added IP addresses.
+func setVMPrimaryIPs(
+	ctx context.Context,
+	nbi *inventory.NetboxInventory,
+	vm *vmNetworks,
+	ipv4Addresses []*objects.IPAddress,
+	ipv6Addresses []*objects.IPAddress,
+) {
+	if len(ipv4Addresses) == 0 && len(ipv6Addresses) == 0 {
+		return
+	}
+	var primaryIPv4, primaryIPv6 *objects.IPAddress
+	if vm.selectPrimaryIPs != nil {
+		primaryIPv4, primaryIPv6 = vm.selectPrimaryIPs(ipv4Addresses, ipv6Addresses)
+	} else {
+		if len(ipv4Addresses) > 0 {
+			primaryIPv4 = ipv4Addresses[0]
+		}
+		if len(ipv6Addresses) > 0 {
+			primaryIPv6 = ipv6Addresses[0]
+		}
+	}
+	if err := SetPrimaryIPAddressForObject(ctx, nbi, vm.vm, primaryIPv4, primaryIPv6); err != nil {
+		nbi.Logger.Warningf(ctx, "set vm's primary ip addresses: %s", err)
+	}
+}
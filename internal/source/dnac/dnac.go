@@ -1,6 +1,7 @@
 package dnac
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"sync"
@@ -45,7 +46,8 @@ type DnacSource struct {
 	InterfaceID2nbInterface sync.Map // InterfaceID -> nbInterface
 }
 
-func (ds *DnacSource) Init() error {
+func (ds *DnacSource) Init(ctx context.Context) error {
+	ds.Ctx = ctx
 	dnacURL := fmt.Sprintf(
 		"%s://%s:%d",
 		ds.SourceConfig.HTTPScheme,
@@ -74,6 +76,9 @@ func (ds *DnacSource) Init() error {
 
 	for _, initFunc := range initFunctions {
 		startTime := time.Now()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := initFunc(Client); err != nil {
 			return fmt.Errorf("dnac initialization failure: %v", err)
 		}
@@ -88,7 +93,8 @@ func (ds *DnacSource) Init() error {
 	return nil
 }
 
-func (ds *DnacSource) Sync(nbi *inventory.NetboxInventory) error {
+func (ds *DnacSource) Sync(ctx context.Context, nbi *inventory.NetboxInventory) error {
+	ds.Ctx = ctx
 	syncFunctions := []func(*inventory.NetboxInventory) error{
 		ds.syncSites,
 		ds.syncVlans,
@@ -102,6 +108,9 @@ func (ds *DnacSource) Sync(nbi *inventory.NetboxInventory) error {
 	for _, syncFunc := range syncFunctions {
 		startTime := time.Now()
 		funcName := utils.ExtractFunctionNameWithTrimPrefix(syncFunc, "sync")
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("sync %s: %w", funcName, err)
+		}
 		err := syncFunc(nbi)
 		if err != nil {
 			if ds.SourceConfig.ContinueOnError {
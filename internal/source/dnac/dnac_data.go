@@ -0,0 +1,33 @@
+package dnac
+
+import (
+	"encoding/json"
+
+	"github.com/bl4ko/netbox-ssot/internal/source/common"
+)
+
+// dataFields are fields of DnacSource initialized in Init, which are dumped with --dump-source-data.
+var dataFields = []string{
+	"Sites",
+	"Devices",
+	"Interfaces",
+	"Vlans",
+	"WirelessLANInterfaceName2VlanID",
+	"SSID2WirelessProfileDetails",
+	"SSID2WlanGroupName",
+	"SSID2SecurityDetails",
+	"Site2Parent",
+	"Site2Devices",
+	"Device2Site",
+	"DeviceID2InterfaceIDs",
+}
+
+// DumpData implements common.Dumper.
+func (ds *DnacSource) DumpData() (map[string]json.RawMessage, error) {
+	return common.MarshalFields(ds, dataFields, json.Marshal)
+}
+
+// LoadData implements common.Dumper.
+func (ds *DnacSource) LoadData(data map[string]json.RawMessage) error {
+	return common.UnmarshalFields(ds, data, json.Unmarshal)
+}
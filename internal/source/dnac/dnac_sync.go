@@ -1,14 +1,19 @@
 package dnac
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/expr"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
 	"github.com/bl4ko/netbox-ssot/internal/source/common"
 	"github.com/bl4ko/netbox-ssot/internal/utils"
 	dnac "github.com/cisco-en-programmability/dnacenter-go-sdk/v8/sdk"
@@ -82,7 +87,7 @@ func (ds *DnacSource) syncVlans(nbi *inventory.NetboxInventory) error {
 		if err != nil {
 			return fmt.Errorf("vlanTenant: %s", err)
 		}
-		newVlan, err := nbi.AddVlan(ds.Ctx, &objects.Vlan{
+		vlanStruct := &objects.Vlan{
 			NetboxObject: objects.NetboxObject{
 				Tags:        ds.GetSourceTags(),
 				Description: vlan.VLANType,
@@ -95,7 +100,18 @@ func (ds *DnacSource) syncVlans(nbi *inventory.NetboxInventory) error {
 			Vid:    vid,
 			Site:   vlanSite,
 			Tenant: vlanTenant,
-		})
+		}
+		err = common.ApplyRelationRules(
+			ds.Ctx,
+			nbi,
+			ds.SourceConfig.RelationRules,
+			vlanStruct,
+			common.VlanAttributes(vlanStruct),
+		)
+		if err != nil {
+			return fmt.Errorf("match vlan to relation rules: %s", err)
+		}
+		newVlan, err := nbi.AddVlan(ds.Ctx, vlanStruct)
 		if err != nil {
 			return fmt.Errorf("adding vlan: %s", err)
 		}
@@ -283,15 +299,29 @@ func (ds *DnacSource) syncDevice(
 		deviceSerialNumber = device.SerialNumber
 	}
 
-	nbDevice, err := nbi.AddDevice(ds.Ctx, &objects.Device{
+	attributes, err := deviceAttributes(device)
+	if err != nil {
+		return fmt.Errorf("device attributes: %s", err)
+	}
+	deviceCustomFields, err := common.MapCustomFields(
+		ds.Ctx,
+		nbi,
+		ds.SourceConfig.CustomFieldMappings,
+		constants.ContentTypeDcimDevice,
+		attributes,
+	)
+	if err != nil {
+		return fmt.Errorf("map device custom fields: %s", err)
+	}
+	deviceCustomFields[constants.CustomFieldSourceName] = ds.SourceConfig.Name
+	deviceCustomFields[constants.CustomFieldSourceIDName] = deviceID
+	deviceCustomFields[constants.CustomFieldDeviceUUIDName] = device.InstanceUUID
+
+	deviceStruct := &objects.Device{
 		NetboxObject: objects.NetboxObject{
-			Tags:        ds.GetSourceTags(),
-			Description: description,
-			CustomFields: map[string]interface{}{
-				constants.CustomFieldSourceName:     ds.SourceConfig.Name,
-				constants.CustomFieldSourceIDName:   deviceID,
-				constants.CustomFieldDeviceUUIDName: device.InstanceUUID,
-			},
+			Tags:         ds.GetSourceTags(),
+			Description:  description,
+			CustomFields: deviceCustomFields,
 		},
 		Name:         device.Hostname,
 		Status:       deviceStatus,
@@ -302,8 +332,20 @@ func (ds *DnacSource) syncDevice(
 		Comments:     comments,
 		Site:         deviceSite,
 		DeviceType:   deviceType,
-	})
-
+	}
+	hostAttributes := expr.Attributes{}
+	hostAttributes.Set(parser.RelationAttributeName, device.Hostname)
+	err = common.ApplyRelationRules(ds.Ctx, nbi, ds.SourceConfig.RelationRules, deviceStruct, hostAttributes)
+	if err != nil {
+		return fmt.Errorf("match host to relation rules: %s", err)
+	}
+	nbDevice, err := nbi.AddDevice(ds.Ctx, deviceStruct)
+	if errors.Is(err, inventory.ErrDropped) {
+		// Nil device marks interfaces of the dropped device to be skipped
+		ds.DeviceID2nbDevice.Store(device.ID, (*objects.Device)(nil))
+		ds.DeviceID2isMissingPrimaryIP.Delete(device.ID)
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("adding dnac device: %s", err)
 	}
@@ -312,6 +354,23 @@ func (ds *DnacSource) syncDevice(
 	return nil
 }
 
+// deviceAttributes returns fields of the device by their names in the DNAC API
+// (e.g. softwareVersion), which can be mapped to custom fields.
+func deviceAttributes(device dnac.ResponseDevicesGetDeviceListResponse) (map[string]interface{}, error) {
+	deviceJSON, err := json.Marshal(device)
+	if err != nil {
+		return nil, err
+	}
+	attributes := map[string]interface{}{}
+	// Numbers are decoded as json.Number, so whole numbers are mapped to integer custom fields
+	decoder := json.NewDecoder(bytes.NewReader(deviceJSON))
+	decoder.UseNumber()
+	if err := decoder.Decode(&attributes); err != nil {
+		return nil, err
+	}
+	return attributes, nil
+}
+
 func (ds *DnacSource) syncDeviceInterfaces(nbi *inventory.NetboxInventory) error {
 	const maxGoroutines = 50
 	guard := make(chan struct{}, maxGoroutines)
@@ -358,6 +417,10 @@ func (ds *DnacSource) syncDeviceInterface(
 		ds.Logger.Errorf(ds.Ctx, "%s This interface will be skipped", err)
 		return nil
 	}
+	if ifaceDevice == nil {
+		// Device is dropped by a transform rule
+		return nil
+	}
 
 	ifaceDuplex := ds.getInterfaceDuplex(iface.Duplex)
 	ifaceStatus, err := ds.getInterfaceStatus(iface.Status)
@@ -682,6 +745,17 @@ func (ds *DnacSource) syncWirelessLANs(nbi *inventory.NetboxInventory) error {
 		}
 
 		wlanVID := ds.WirelessLANInterfaceName2VlanID[wlanWirelessProfile.InterfaceName]
+		vlanGroup, err = common.MatchVlanGroupRules(
+			ds.Ctx,
+			nbi,
+			ds.SourceConfig.RelationRules,
+			ds.Vlans[wlanVID].InterfaceName,
+			wlanVID,
+			vlanGroup,
+		)
+		if err != nil {
+			return fmt.Errorf("match vlan to relation rules: %s", err)
+		}
 		vlan, _ := nbi.GetVlan(vlanGroup.ID, wlanVID)
 		wlanStruct := &objects.WirelessLAN{
 			NetboxObject: objects.NetboxObject{
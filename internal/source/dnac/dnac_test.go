@@ -2,8 +2,10 @@ package dnac
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"os"
+	"reflect"
 	"sync"
 	"testing"
 
@@ -622,3 +624,26 @@ func TestDnacSourceDeviceDescriptionTruncation(t *testing.T) {
 		})
 	}
 }
+
+func TestDeviceAttributes(t *testing.T) {
+	uptime := 3600.0
+	managed := true
+	got, err := deviceAttributes(dnac.ResponseDevicesGetDeviceListResponse{
+		Hostname:           "switch-01",
+		SoftwareVersion:    "17.9.4",
+		UptimeSeconds:      &uptime,
+		ManagedAtleastOnce: &managed,
+	})
+	if err != nil {
+		t.Fatalf("deviceAttributes() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"hostname":           "switch-01",
+		"softwareVersion":    "17.9.4",
+		"uptimeSeconds":      json.Number("3600"),
+		"managedAtleastOnce": true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("deviceAttributes() = %v, want %v", got, want)
+	}
+}
@@ -58,7 +58,8 @@ func (c *Client) MakeRequest(
 	return c.HTTPClient.Do(req)
 }
 
-func (fs *F5Source) Init() error {
+func (fs *F5Source) Init(ctx context.Context) error {
+	fs.Ctx = ctx
 	httpClient, err := utils.NewHTTPClient(fs.SourceConfig.ValidateCert, fs.CAFile)
 	if err != nil {
 		return fmt.Errorf("create new http client: %s", err)
@@ -75,14 +76,14 @@ func (fs *F5Source) Init() error {
 		),
 		httpClient,
 	)
-	ctx := context.Background()
-	defer ctx.Done()
-
 	initFunctions := []func(context.Context, *Client) error{
 		fs.initVirtualServers,
 	}
 	for _, initFunc := range initFunctions {
 		startTime := time.Now()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := initFunc(ctx, c); err != nil {
 			return fmt.Errorf("f5 initialization failure: %v", err)
 		}
@@ -97,7 +98,8 @@ func (fs *F5Source) Init() error {
 	return nil
 }
 
-func (fs *F5Source) Sync(nbi *inventory.NetboxInventory) error {
+func (fs *F5Source) Sync(ctx context.Context, nbi *inventory.NetboxInventory) error {
+	fs.Ctx = ctx
 	syncFunctions := []func(*inventory.NetboxInventory) error{
 		fs.syncVirtualServers,
 	}
@@ -106,6 +108,9 @@ func (fs *F5Source) Sync(nbi *inventory.NetboxInventory) error {
 	for _, syncFunc := range syncFunctions {
 		startTime := time.Now()
 		funcName := utils.ExtractFunctionNameWithTrimPrefix(syncFunc, "sync")
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("sync %s: %w", funcName, err)
+		}
 		err := syncFunc(nbi)
 		if err != nil {
 			if fs.SourceConfig.ContinueOnError {
@@ -0,0 +1,22 @@
+package f5
+
+import (
+	"encoding/json"
+
+	"github.com/bl4ko/netbox-ssot/internal/source/common"
+)
+
+// dataFields are fields of F5Source initialized in Init, which are dumped with --dump-source-data.
+var dataFields = []string{
+	"VirtualServers",
+}
+
+// DumpData implements common.Dumper.
+func (fs *F5Source) DumpData() (map[string]json.RawMessage, error) {
+	return common.MarshalFields(fs, dataFields, json.Marshal)
+}
+
+// LoadData implements common.Dumper.
+func (fs *F5Source) LoadData(data map[string]json.RawMessage) error {
+	return common.UnmarshalFields(fs, data, json.Unmarshal)
+}
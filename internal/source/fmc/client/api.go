@@ -51,7 +51,7 @@ func (fmcc FMCClient) Authenticate() (string, string, error) {
 
 // Helper function to Authenticate. Performs single attempt to authenticate to fmc api.
 func (fmcc FMCClient) authenticateOnce() (string, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), fmcc.DefaultTimeout)
+	ctx, cancel := context.WithTimeout(fmcc.Ctx, fmcc.DefaultTimeout)
 	defer cancel()
 	req, err := http.NewRequestWithContext(
 		ctx,
@@ -240,7 +240,7 @@ func (fmcc *FMCClient) GetDomains() ([]Domain, error) {
 	offset := 0
 	limit := 25
 	domains := []Domain{}
-	ctx := context.Background()
+	ctx := fmcc.Ctx
 
 	for {
 		var marshaledResponse APIResponse[Domain]
@@ -268,7 +268,7 @@ func (fmcc *FMCClient) GetDevices(domainUUID string) ([]Device, error) {
 	offset := 0
 	limit := 25
 	devices := []Device{}
-	ctx := context.Background()
+	ctx := fmcc.Ctx
 
 	for {
 		devicesURL := fmt.Sprintf(
@@ -304,7 +304,7 @@ func (fmcc *FMCClient) GetDevicePhysicalInterfaces(
 	offset := 0
 	limit := 25
 	pIfaces := []PhysicalInterface{}
-	ctx := context.Background()
+	ctx := fmcc.Ctx
 
 	for {
 		pInterfacesURL := fmt.Sprintf(
@@ -344,7 +344,7 @@ func (fmcc *FMCClient) GetDeviceVLANInterfaces(
 	offset := 0
 	limit := 25
 	vlanIfaces := []VlanInterface{}
-	ctx := context.Background()
+	ctx := fmcc.Ctx
 
 	for {
 		vInterfacesURL := fmt.Sprintf(
@@ -394,7 +394,7 @@ func (fmcc *FMCClient) GetDeviceEtherChannelInterfaces(
 	offset := 0
 	limit := 25
 	etherChannelIfaces := []EtherChannelInterface{}
-	ctx := context.Background()
+	ctx := fmcc.Ctx
 
 	for {
 		vInterfacesURL := fmt.Sprintf(
@@ -444,7 +444,7 @@ func (fmcc *FMCClient) GetDeviceSubInterfaces(
 	offset := 0
 	limit := 25
 	subIfaces := []SubInterface{}
-	ctx := context.Background()
+	ctx := fmcc.Ctx
 
 	for {
 		subInterfacesURL := fmt.Sprintf(
@@ -482,7 +482,7 @@ func (fmcc *FMCClient) GetPhysicalInterfaceInfo(
 	interfaceID string,
 ) (*PhysicalInterfaceInfo, error) {
 	var pInterfaceInfo PhysicalInterfaceInfo
-	ctx := context.Background()
+	ctx := fmcc.Ctx
 
 	devicesURL := fmt.Sprintf(
 		"fmc_config/v1/domain/%s/devices/devicerecords/%s/physicalinterfaces/%s",
@@ -504,7 +504,7 @@ func (fmcc *FMCClient) GetVLANInterfaceInfo(
 	interfaceID string,
 ) (*VLANInterfaceInfo, error) {
 	var vlanInterfaceInfo VLANInterfaceInfo
-	ctx := context.Background()
+	ctx := fmcc.Ctx
 
 	devicesURL := fmt.Sprintf(
 		"fmc_config/v1/domain/%s/devices/devicerecords/%s/vlaninterfaces/%s",
@@ -530,7 +530,7 @@ func (fmcc *FMCClient) GetEtherChannelInterfaceInfo(
 	interfaceID string,
 ) (*EtherChannelInterfaceInfo, error) {
 	var etherChannelInterfaceInfo EtherChannelInterfaceInfo
-	ctx := context.Background()
+	ctx := fmcc.Ctx
 
 	devicesURL := fmt.Sprintf(
 		"fmc_config/v1/domain/%s/devices/devicerecords/%s/etherchannelinterfaces/%s",
@@ -555,7 +555,7 @@ func (fmcc *FMCClient) GetSubInterfaceInfo(
 	interfaceID string,
 ) (*SubInterfaceInfo, error) {
 	var subInterfaceInfo SubInterfaceInfo
-	ctx := context.Background()
+	ctx := fmcc.Ctx
 
 	devicesURL := fmt.Sprintf(
 		"fmc_config/v1/domain/%s/devices/devicerecords/%s/subinterfaces/%s",
@@ -572,7 +572,7 @@ func (fmcc *FMCClient) GetSubInterfaceInfo(
 
 func (fmcc *FMCClient) GetDeviceInfo(domainUUID string, deviceID string) (*DeviceInfo, error) {
 	var deviceInfo DeviceInfo
-	ctx := context.Background()
+	ctx := fmcc.Ctx
 
 	devicesURL := fmt.Sprintf(
 		"fmc_config/v1/domain/%s/devices/devicerecords/%s",
@@ -1,6 +1,7 @@
 package fmc
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -38,7 +39,8 @@ type FMCSource struct {
 	Name2NBInterface map[string]*objects.Interface
 }
 
-func (fmcs *FMCSource) Init() error {
+func (fmcs *FMCSource) Init(ctx context.Context) error {
+	fmcs.Ctx = ctx
 	httpClient, err := utils.NewHTTPClient(fmcs.SourceConfig.ValidateCert, fmcs.CAFile)
 	if err != nil {
 		return fmt.Errorf("create new http client: %s", err)
@@ -65,6 +67,9 @@ func (fmcs *FMCSource) Init() error {
 	}
 	for _, initFunc := range initFunctions {
 		startTime := time.Now()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := initFunc(c); err != nil {
 			return fmt.Errorf("fmc initialization failure: %v", err)
 		}
@@ -79,7 +84,8 @@ func (fmcs *FMCSource) Init() error {
 	return nil
 }
 
-func (fmcs *FMCSource) Sync(nbi *inventory.NetboxInventory) error {
+func (fmcs *FMCSource) Sync(ctx context.Context, nbi *inventory.NetboxInventory) error {
+	fmcs.Ctx = ctx
 	syncFunctions := []func(*inventory.NetboxInventory) error{
 		fmcs.syncDevices,
 	}
@@ -88,6 +94,9 @@ func (fmcs *FMCSource) Sync(nbi *inventory.NetboxInventory) error {
 	for _, syncFunc := range syncFunctions {
 		startTime := time.Now()
 		funcName := utils.ExtractFunctionNameWithTrimPrefix(syncFunc, "sync")
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("sync %s: %w", funcName, err)
+		}
 		err := syncFunc(nbi)
 		if err != nil {
 			if fmcs.SourceConfig.ContinueOnError {
@@ -0,0 +1,29 @@
+package fmc
+
+import (
+	"encoding/json"
+
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/source/common"
+)
+
+// dataFields are fields of FMCSource initialized in Init, which are dumped with --dump-source-data.
+var dataFields = []string{
+	"Domains",
+	"Devices",
+	"DevicePhysicalIfaces",
+	"DeviceVlanIfaces",
+	"DeviceEtherChannelIfaces",
+	"DeviceSubIfaces",
+}
+
+// DumpData implements common.Dumper.
+func (fmcs *FMCSource) DumpData() (map[string]json.RawMessage, error) {
+	return common.MarshalFields(fmcs, dataFields, json.Marshal)
+}
+
+// LoadData implements common.Dumper.
+func (fmcs *FMCSource) LoadData(data map[string]json.RawMessage) error {
+	fmcs.Name2NBInterface = make(map[string]*objects.Interface)
+	return common.UnmarshalFields(fmcs, data, json.Unmarshal)
+}
@@ -1,11 +1,14 @@
 package fmc
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/expr"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
 	"github.com/bl4ko/netbox-ssot/internal/source/common"
 	"github.com/bl4ko/netbox-ssot/internal/source/fmc/client"
 	"github.com/bl4ko/netbox-ssot/internal/utils"
@@ -91,7 +94,7 @@ func (fmcs *FMCSource) syncDevices(nbi *inventory.NetboxInventory) error {
 		if err != nil {
 			return fmt.Errorf("add platform: %s", err)
 		}
-		NBDevice, err := nbi.AddDevice(fmcs.Ctx, &objects.Device{
+		deviceStruct := &objects.Device{
 			NetboxObject: objects.NetboxObject{
 				Description: device.Description,
 				Tags:        fmcs.GetSourceTags(),
@@ -113,7 +116,17 @@ func (fmcs *FMCSource) syncDevices(nbi *inventory.NetboxInventory) error {
 			Tenant:       deviceTenant,
 			Platform:     devicePlatform,
 			SerialNumber: deviceSerialNumber,
-		})
+		}
+		hostAttributes := expr.Attributes{}
+		hostAttributes.Set(parser.RelationAttributeName, deviceName)
+		err = common.ApplyRelationRules(fmcs.Ctx, nbi, fmcs.SourceConfig.RelationRules, deviceStruct, hostAttributes)
+		if err != nil {
+			return fmt.Errorf("match host to relation rules: %s", err)
+		}
+		NBDevice, err := nbi.AddDevice(fmcs.Ctx, deviceStruct)
+		if errors.Is(err, inventory.ErrDropped) {
+			continue
+		}
 		if err != nil {
 			return fmt.Errorf("add device: %s", err)
 		}
@@ -210,7 +223,7 @@ func (fmcs *FMCSource) syncVlanInterfaces(
 				if err != nil {
 					return fmt.Errorf("match vlan to tenant: %s", err)
 				}
-				vlan, err := nbi.AddVlan(fmcs.Ctx, &objects.Vlan{
+				vlanStruct := &objects.Vlan{
 					NetboxObject: objects.NetboxObject{
 						Tags:        fmcs.GetSourceTags(),
 						Description: vlanIface.Description,
@@ -221,7 +234,18 @@ func (fmcs *FMCSource) syncVlanInterfaces(
 					Vid:    vlanIface.VID,
 					Tenant: vlanTenant,
 					Group:  vlanGroup,
-				})
+				}
+				err = common.ApplyRelationRules(
+					fmcs.Ctx,
+					nbi,
+					fmcs.SourceConfig.RelationRules,
+					vlanStruct,
+					common.VlanAttributes(vlanStruct),
+				)
+				if err != nil {
+					return fmt.Errorf("match vlan to relation rules: %s", err)
+				}
+				vlan, err := nbi.AddVlan(fmcs.Ctx, vlanStruct)
 				if err != nil {
 					return fmt.Errorf("add vlan: %s", err)
 				}
@@ -477,7 +501,7 @@ func (fmcs *FMCSource) syncSubInterfaces(
 				if err != nil {
 					return fmt.Errorf("match subiface vlan to tenant: %s", err)
 				}
-				vlan, err := nbi.AddVlan(fmcs.Ctx, &objects.Vlan{
+				vlanStruct := &objects.Vlan{
 					NetboxObject: objects.NetboxObject{
 						Tags:        fmcs.GetSourceTags(),
 						Description: subIface.Description,
@@ -488,7 +512,18 @@ func (fmcs *FMCSource) syncSubInterfaces(
 					Vid:    subIface.VlanID,
 					Tenant: vlanTenant,
 					Group:  vlanGroup,
-				})
+				}
+				err = common.ApplyRelationRules(
+					fmcs.Ctx,
+					nbi,
+					fmcs.SourceConfig.RelationRules,
+					vlanStruct,
+					common.VlanAttributes(vlanStruct),
+				)
+				if err != nil {
+					return fmt.Errorf("match vlan to relation rules: %s", err)
+				}
+				vlan, err := nbi.AddVlan(fmcs.Ctx, vlanStruct)
 				if err != nil {
 					return fmt.Errorf("add subiface vlan: %s", err)
 				}
@@ -2,6 +2,7 @@ package fortigate
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -26,7 +27,9 @@ type FortigateSource struct {
 
 type FortiSystemInfo struct {
 	Hostname string
+	Alias    string
 	Version  string
+	Build    int
 	Serial   string
 }
 
@@ -58,7 +61,8 @@ func (c FortiClient) MakeRequest(
 	return c.HTTPClient.Do(req)
 }
 
-func (fs *FortigateSource) Init() error {
+func (fs *FortigateSource) Init(ctx context.Context) error {
+	fs.Ctx = ctx
 	httpClient, err := utils.NewHTTPClient(fs.SourceConfig.ValidateCert, fs.CAFile)
 	if err != nil {
 		return fmt.Errorf("create new http client: %s", err)
@@ -73,15 +77,15 @@ func (fs *FortigateSource) Init() error {
 		),
 		httpClient,
 	)
-	ctx := context.Background()
-	defer ctx.Done()
-
 	initFunctions := []func(context.Context, *FortiClient) error{
 		fs.initSystemInfo,
 		fs.initInterfaces,
 	}
 	for _, initFunc := range initFunctions {
 		startTime := time.Now()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := initFunc(ctx, c); err != nil {
 			return fmt.Errorf("fortigate initialization failure: %v", err)
 		}
@@ -96,7 +100,8 @@ func (fs *FortigateSource) Init() error {
 	return nil
 }
 
-func (fs *FortigateSource) Sync(nbi *inventory.NetboxInventory) error {
+func (fs *FortigateSource) Sync(ctx context.Context, nbi *inventory.NetboxInventory) error {
+	fs.Ctx = ctx
 	syncFunctions := []func(*inventory.NetboxInventory) error{
 		fs.syncDevice,
 		fs.syncInterfaces,
@@ -106,7 +111,15 @@ func (fs *FortigateSource) Sync(nbi *inventory.NetboxInventory) error {
 	for _, syncFunc := range syncFunctions {
 		startTime := time.Now()
 		funcName := utils.ExtractFunctionNameWithTrimPrefix(syncFunc, "sync")
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("sync %s: %w", funcName, err)
+		}
 		err := syncFunc(nbi)
+		if errors.Is(err, inventory.ErrDropped) {
+			// Other objects of the source belong to the dropped device
+			fs.Logger.Infof(fs.Ctx, "Device is dropped by a transform rule, skipping the rest of the sync")
+			return nil
+		}
 		if err != nil {
 			if fs.SourceConfig.ContinueOnError {
 				fs.Logger.Errorf(
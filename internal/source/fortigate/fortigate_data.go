@@ -0,0 +1,23 @@
+package fortigate
+
+import (
+	"encoding/json"
+
+	"github.com/bl4ko/netbox-ssot/internal/source/common"
+)
+
+// dataFields are fields of FortigateSource initialized in Init, which are dumped with --dump-source-data.
+var dataFields = []string{
+	"SystemInfo",
+	"Ifaces",
+}
+
+// DumpData implements common.Dumper.
+func (fs *FortigateSource) DumpData() (map[string]json.RawMessage, error) {
+	return common.MarshalFields(fs, dataFields, json.Marshal)
+}
+
+// LoadData implements common.Dumper.
+func (fs *FortigateSource) LoadData(data map[string]json.RawMessage) error {
+	return common.UnmarshalFields(fs, data, json.Unmarshal)
+}
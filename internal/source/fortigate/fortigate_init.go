@@ -12,11 +12,13 @@ type APIResponse[T any] struct {
 	HTTPStatus int    `json:"http_status"`
 	Serial     string `json:"serial"`
 	Version    string `json:"version"`
+	Build      int    `json:"build"`
 	Results    T      `json:"results"`
 }
 
 type DeviceResponse struct {
 	Hostname string `json:"hostname"`
+	Alias    string `json:"alias"`
 }
 
 type InterfaceResponse struct {
@@ -66,7 +68,9 @@ func (fs *FortigateSource) initSystemInfo(ctx context.Context, c *FortiClient) e
 
 	fs.SystemInfo = FortiSystemInfo{
 		Hostname: deviceResponse.Results.Hostname,
+		Alias:    deviceResponse.Results.Alias,
 		Version:  deviceResponse.Version,
+		Build:    deviceResponse.Build,
 		Serial:   deviceResponse.Serial,
 	}
 
@@ -5,8 +5,10 @@ import (
 	"strings"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/expr"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
 	"github.com/bl4ko/netbox-ssot/internal/source/common"
 	"github.com/bl4ko/netbox-ssot/internal/utils"
 )
@@ -89,9 +91,26 @@ func (fs *FortigateSource) syncDevice(nbi *inventory.NetboxInventory) error {
 	if err != nil {
 		return fmt.Errorf("add platform: %s", err)
 	}
-	NBDevice, err := nbi.AddDevice(fs.Ctx, &objects.Device{
+	deviceCustomFields, err := common.MapCustomFields(
+		fs.Ctx,
+		nbi,
+		fs.SourceConfig.CustomFieldMappings,
+		constants.ContentTypeDcimDevice,
+		map[string]interface{}{
+			"hostname": fs.SystemInfo.Hostname,
+			"alias":    fs.SystemInfo.Alias,
+			"version":  fs.SystemInfo.Version,
+			"build":    fs.SystemInfo.Build,
+			"serial":   fs.SystemInfo.Serial,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("map device custom fields: %s", err)
+	}
+	deviceStruct := &objects.Device{
 		NetboxObject: objects.NetboxObject{
-			Tags: fs.GetSourceTags(),
+			Tags:         fs.GetSourceTags(),
+			CustomFields: deviceCustomFields,
 		},
 		Name:         deviceName,
 		Site:         deviceSite,
@@ -101,9 +120,16 @@ func (fs *FortigateSource) syncDevice(nbi *inventory.NetboxInventory) error {
 		Tenant:       deviceTenant,
 		Platform:     devicePlatform,
 		SerialNumber: deviceSerialNumber,
-	})
+	}
+	hostAttributes := expr.Attributes{}
+	hostAttributes.Set(parser.RelationAttributeName, deviceName)
+	err = common.ApplyRelationRules(fs.Ctx, nbi, fs.SourceConfig.RelationRules, deviceStruct, hostAttributes)
 	if err != nil {
-		return fmt.Errorf("add device: %s", err)
+		return fmt.Errorf("match host to relation rules: %s", err)
+	}
+	NBDevice, err := nbi.AddDevice(fs.Ctx, deviceStruct)
+	if err != nil {
+		return fmt.Errorf("add device: %w", err)
 	}
 
 	fs.NBFirewall = NBDevice
@@ -228,7 +254,7 @@ func (fs *FortigateSource) syncInterfaces(nbi *inventory.NetboxInventory) error
 			if err != nil {
 				return fmt.Errorf("match vlan to tenant: %s", err)
 			}
-			NBVlan, err := nbi.AddVlan(fs.Ctx, &objects.Vlan{
+			vlanStruct := &objects.Vlan{
 				NetboxObject: objects.NetboxObject{
 					Tags: fs.GetSourceTags(),
 				},
@@ -238,7 +264,18 @@ func (fs *FortigateSource) syncInterfaces(nbi *inventory.NetboxInventory) error
 				Site:   vlanSite,
 				Tenant: vlanTenant,
 				Group:  vlanGroup,
-			})
+			}
+			err = common.ApplyRelationRules(
+				fs.Ctx,
+				nbi,
+				fs.SourceConfig.RelationRules,
+				vlanStruct,
+				common.VlanAttributes(vlanStruct),
+			)
+			if err != nil {
+				return fmt.Errorf("match vlan to relation rules: %s", err)
+			}
+			NBVlan, err := nbi.AddVlan(fs.Ctx, vlanStruct)
 			if err != nil {
 				return fmt.Errorf("add vlan: %s", err)
 			}
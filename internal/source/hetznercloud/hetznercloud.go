@@ -29,7 +29,8 @@ type Source struct {
 	NetboxLocations map[string]*objects.Location // Datacenter Name -> Netbox Location
 }
 
-func (hcs *Source) Init() error {
+func (hcs *Source) Init(ctx context.Context) error {
+	hcs.Ctx = ctx
 	opts := make([]hcloud.ClientOption, 0, 2) //nolint:mnd
 	opts = append(opts, hcloud.WithToken(hcs.SourceConfig.APIToken))
 
@@ -41,9 +42,6 @@ func (hcs *Source) Init() error {
 
 	client := hcloud.NewClient(opts...)
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	initFuncs := []func(context.Context, *hcloud.Client) error{
 		hcs.initLocations,
 		hcs.initDatacenters,
@@ -55,6 +53,9 @@ func (hcs *Source) Init() error {
 
 	for _, initFunc := range initFuncs {
 		startTime := time.Now()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := initFunc(ctx, client); err != nil {
 			return fmt.Errorf("hetznercloud initialization failure: %v", err)
 		}
@@ -70,7 +71,8 @@ func (hcs *Source) Init() error {
 	return nil
 }
 
-func (hcs *Source) Sync(nbi *inventory.NetboxInventory) error {
+func (hcs *Source) Sync(ctx context.Context, nbi *inventory.NetboxInventory) error {
+	hcs.Ctx = ctx
 	syncFunctions := []func(*inventory.NetboxInventory) error{
 		hcs.syncLocationsAndDatacenters,
 		hcs.syncServers,
@@ -81,6 +83,9 @@ func (hcs *Source) Sync(nbi *inventory.NetboxInventory) error {
 	for _, syncFunc := range syncFunctions {
 		startTime := time.Now()
 		funcName := utils.ExtractFunctionNameWithTrimPrefix(syncFunc, "sync")
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("sync %s: %w", funcName, err)
+		}
 		err := syncFunc(nbi)
 		if err != nil {
 			if hcs.SourceConfig.ContinueOnError {
@@ -0,0 +1,63 @@
+package hetznercloud
+
+import (
+	"encoding/json"
+
+	"github.com/bl4ko/netbox-ssot/internal/source/common"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/schema"
+)
+
+// dataFields are fields of Source initialized in Init, which are dumped with --dump-source-data.
+var dataFields = []string{"Locations", "Datacenters", "Servers", "Networks", "FloatingIPs", "PrimaryIPs"}
+
+// sourceData are dataFields in the format of the Hetzner Cloud API,
+// because hcloud structs can't be marshalled directly.
+type sourceData struct {
+	Locations   []schema.Location
+	Datacenters []schema.Datacenter
+	Servers     []schema.Server
+	Networks    []schema.Network
+	FloatingIPs []schema.FloatingIP
+	PrimaryIPs  []schema.PrimaryIP
+}
+
+// DumpData implements common.Dumper.
+func (hcs *Source) DumpData() (map[string]json.RawMessage, error) {
+	data := &sourceData{
+		Locations:   convert(hcs.Locations, hcloud.SchemaFromLocation),
+		Datacenters: convert(hcs.Datacenters, hcloud.SchemaFromDatacenter),
+		Servers:     convert(hcs.Servers, hcloud.SchemaFromServer),
+		Networks:    convert(hcs.Networks, hcloud.SchemaFromNetwork),
+		FloatingIPs: convert(hcs.FloatingIPs, hcloud.SchemaFromFloatingIP),
+		PrimaryIPs:  convert(hcs.PrimaryIPs, hcloud.SchemaFromPrimaryIP),
+	}
+	return common.MarshalFields(data, dataFields, json.Marshal)
+}
+
+// LoadData implements common.Dumper.
+func (hcs *Source) LoadData(dump map[string]json.RawMessage) error {
+	data := &sourceData{}
+	if err := common.UnmarshalFields(data, dump, json.Unmarshal); err != nil {
+		return err
+	}
+	hcs.Locations = convert(data.Locations, hcloud.LocationFromSchema)
+	hcs.Datacenters = convert(data.Datacenters, hcloud.DatacenterFromSchema)
+	hcs.Servers = convert(data.Servers, hcloud.ServerFromSchema)
+	hcs.Networks = convert(data.Networks, hcloud.NetworkFromSchema)
+	hcs.FloatingIPs = convert(data.FloatingIPs, hcloud.FloatingIPFromSchema)
+	hcs.PrimaryIPs = convert(data.PrimaryIPs, hcloud.PrimaryIPFromSchema)
+	return nil
+}
+
+// convert converts each of the items with convertItem.
+func convert[T any, S any](items []T, convertItem func(T) S) []S {
+	if items == nil {
+		return nil
+	}
+	converted := make([]S, 0, len(items))
+	for _, item := range items {
+		converted = append(converted, convertItem(item))
+	}
+	return converted
+}
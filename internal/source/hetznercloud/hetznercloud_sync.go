@@ -1,6 +1,7 @@
 package hetznercloud
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -169,17 +170,32 @@ func (hcs *Source) syncServer(
 		status = &objects.VMStatusOffline
 	}
 
+	// Labels of the server are mapped to custom fields
+	vmAttributes := make(map[string]interface{}, len(server.Labels))
+	for key, value := range server.Labels {
+		vmAttributes["labels."+key] = value
+	}
+	vmCustomFields, err := common.MapCustomFields(
+		hcs.Ctx,
+		nbi,
+		hcs.SourceConfig.CustomFieldMappings,
+		constants.ContentTypeVirtualizationVirtualMachine,
+		vmAttributes,
+	)
+	if err != nil {
+		return fmt.Errorf("map custom fields of server %q: %w", server.Name, err)
+	}
+	vmCustomFields[constants.CustomFieldSourceName] = hcs.SourceConfig.Name
+	vmCustomFields[constants.CustomFieldSourceIDName] = fmt.Sprintf("%d", server.ID)
+	vmCustomFields[constants.CustomFieldServerCPUTypeName] = string(server.ServerType.CPUType)
+	vmCustomFields[constants.CustomFieldServerCategoryName] = server.ServerType.Category
+	vmCustomFields[constants.CustomFieldServerDeprecatedName] = server.ServerType.IsDeprecated()
+
 	vm := &objects.VM{
 		NetboxObject: objects.NetboxObject{
-			Tags:        hcs.GetSourceTags(),
-			Description: server.ServerType.Name,
-			CustomFields: map[string]interface{}{
-				constants.CustomFieldSourceName:           hcs.SourceConfig.Name,
-				constants.CustomFieldSourceIDName:         fmt.Sprintf("%d", server.ID),
-				constants.CustomFieldServerCPUTypeName:    string(server.ServerType.CPUType),
-				constants.CustomFieldServerCategoryName:   server.ServerType.Category,
-				constants.CustomFieldServerDeprecatedName: server.ServerType.IsDeprecated(),
-			},
+			Tags:         hcs.GetSourceTags(),
+			Description:  server.ServerType.Name,
+			CustomFields: vmCustomFields,
 		},
 		Name:     server.Name,
 		Cluster:  cluster,
@@ -193,6 +209,9 @@ func (hcs *Source) syncServer(
 	}
 
 	netboxVM, err := nbi.AddVM(hcs.Ctx, vm)
+	if errors.Is(err, inventory.ErrDropped) {
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("syncing server %s: %s", server.Name, err)
 	}
@@ -1,6 +1,8 @@
 package iosxe
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -27,7 +29,8 @@ type IOSXESource struct {
 	NBInterfaces map[string]*objects.Interface // interfaceName -> netboxInterface
 }
 
-func (is *IOSXESource) Init() error {
+func (is *IOSXESource) Init(ctx context.Context) error {
+	is.Ctx = ctx
 	d, err := netconf.NewDriver(
 		is.SourceConfig.Hostname,
 		options.WithAuthUsername(is.SourceConfig.Username),
@@ -56,6 +59,9 @@ func (is *IOSXESource) Init() error {
 
 	for _, initFunc := range initFunctions {
 		startTime := time.Now()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := initFunc(d); err != nil {
 			return fmt.Errorf("iosxe initialization failure: %v", err)
 		}
@@ -70,7 +76,8 @@ func (is *IOSXESource) Init() error {
 	return nil
 }
 
-func (is *IOSXESource) Sync(nbi *inventory.NetboxInventory) error {
+func (is *IOSXESource) Sync(ctx context.Context, nbi *inventory.NetboxInventory) error {
+	is.Ctx = ctx
 	syncFunctions := []func(*inventory.NetboxInventory) error{
 		is.syncDevice,
 		is.syncInterfaces,
@@ -81,7 +88,15 @@ func (is *IOSXESource) Sync(nbi *inventory.NetboxInventory) error {
 	for _, syncFunc := range syncFunctions {
 		startTime := time.Now()
 		funcName := utils.ExtractFunctionNameWithTrimPrefix(syncFunc, "sync")
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("sync %s: %w", funcName, err)
+		}
 		err := syncFunc(nbi)
+		if errors.Is(err, inventory.ErrDropped) {
+			// Other objects of the source belong to the dropped device
+			is.Logger.Infof(is.Ctx, "Device is dropped by a transform rule, skipping the rest of the sync")
+			return nil
+		}
 		if err != nil {
 			if is.SourceConfig.ContinueOnError {
 				is.Logger.Errorf(
@@ -0,0 +1,25 @@
+package iosxe
+
+import (
+	"encoding/json"
+
+	"github.com/bl4ko/netbox-ssot/internal/source/common"
+)
+
+// dataFields are fields of IOSXESource initialized in Init, which are dumped with --dump-source-data.
+var dataFields = []string{
+	"HardwareInfo",
+	"SystemInfo",
+	"Interfaces",
+	"ArpEntries",
+}
+
+// DumpData implements common.Dumper.
+func (is *IOSXESource) DumpData() (map[string]json.RawMessage, error) {
+	return common.MarshalFields(is, dataFields, json.Marshal)
+}
+
+// LoadData implements common.Dumper.
+func (is *IOSXESource) LoadData(data map[string]json.RawMessage) error {
+	return common.UnmarshalFields(is, data, json.Unmarshal)
+}
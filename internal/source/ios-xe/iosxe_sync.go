@@ -5,8 +5,10 @@ import (
 	"time"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/expr"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
 	"github.com/bl4ko/netbox-ssot/internal/source/common"
 	"github.com/bl4ko/netbox-ssot/internal/utils"
 	devices "github.com/src-doo/go-devicetype-library/pkg"
@@ -111,7 +113,7 @@ func (is *IOSXESource) syncDevice(nbi *inventory.NetboxInventory) error {
 	if err != nil {
 		return fmt.Errorf("add platform: %s", err)
 	}
-	NBDevice, err := nbi.AddDevice(is.Ctx, &objects.Device{
+	deviceStruct := &objects.Device{
 		NetboxObject: objects.NetboxObject{
 			Tags:        is.GetSourceTags(),
 			Description: description,
@@ -124,9 +126,16 @@ func (is *IOSXESource) syncDevice(nbi *inventory.NetboxInventory) error {
 		DeviceType:   deviceType,
 		Tenant:       deviceTenant,
 		Platform:     devicePlatform,
-	})
+	}
+	hostAttributes := expr.Attributes{}
+	hostAttributes.Set(parser.RelationAttributeName, deviceName)
+	err = common.ApplyRelationRules(is.Ctx, nbi, is.SourceConfig.RelationRules, deviceStruct, hostAttributes)
+	if err != nil {
+		return fmt.Errorf("match host to relation rules: %s", err)
+	}
+	NBDevice, err := nbi.AddDevice(is.Ctx, deviceStruct)
 	if err != nil {
-		return fmt.Errorf("add device: %s", err)
+		return fmt.Errorf("add device: %w", err)
 	}
 	is.NBDevice = NBDevice
 
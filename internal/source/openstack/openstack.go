@@ -105,7 +105,8 @@ func resolveDomainConfig(cfg *parser.SourceConfig) domainConfig {
 	}
 }
 
-func (oss *Source) Init() error {
+func (oss *Source) Init(ctx context.Context) error {
+	oss.Ctx = ctx
 	projectName := oss.SourceConfig.ProjectName
 	if projectName == "" {
 		projectName = oss.SourceConfig.TenantName
@@ -235,6 +236,9 @@ func (oss *Source) Init() error {
 
 	for _, initFunc := range initFuncs {
 		startTime := time.Now()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := initFunc(oss.Ctx); err != nil {
 			return fmt.Errorf("openstack initialization failure: %v", err)
 		}
@@ -250,13 +254,17 @@ func (oss *Source) Init() error {
 	return nil
 }
 
-func (oss *Source) Sync(nbi *inventory.NetboxInventory) error {
+func (oss *Source) Sync(ctx context.Context, nbi *inventory.NetboxInventory) error {
+	oss.Ctx = ctx
 	syncFunctions := []func(*inventory.NetboxInventory) error{
 		oss.syncServers,
 	}
 	for _, syncFunc := range syncFunctions {
 		startTime := time.Now()
 		funcName := utils.ExtractFunctionNameWithTrimPrefix(syncFunc, "sync")
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("sync %s: %w", funcName, err)
+		}
 		err := syncFunc(nbi)
 		if err != nil {
 			if oss.SourceConfig.ContinueOnError {
@@ -0,0 +1,20 @@
+package openstack
+
+import (
+	"encoding/json"
+
+	"github.com/bl4ko/netbox-ssot/internal/source/common"
+)
+
+// dataFields are fields of Source initialized in Init, which are dumped with --dump-source-data.
+var dataFields = []string{"Servers", "Flavors", "Networks", "Volumes", "Images"}
+
+// DumpData implements common.Dumper.
+func (oss *Source) DumpData() (map[string]json.RawMessage, error) {
+	return common.MarshalFields(oss, dataFields, json.Marshal)
+}
+
+// LoadData implements common.Dumper.
+func (oss *Source) LoadData(data map[string]json.RawMessage) error {
+	return common.UnmarshalFields(oss, data, json.Unmarshal)
+}
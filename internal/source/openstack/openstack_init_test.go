@@ -4,6 +4,10 @@ import (
 	"testing"
 
 	"github.com/bl4ko/netbox-ssot/internal/parser"
+	"github.com/gophercloud/gophercloud/v2/openstack/blockstorage/v3/volumes"
+	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/flavors"
+	"github.com/gophercloud/gophercloud/v2/openstack/image/v2/images"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/networks"
 )
 
 func TestResolveDomainConfig(t *testing.T) {
@@ -309,3 +313,45 @@ func TestResolveDomainConfig_DefaultFallback(t *testing.T) {
 		})
 	}
 }
+
+func TestSourceDumpLoadData(t *testing.T) {
+	oss := &Source{
+		Servers: []Server{
+			{
+				ID:     "server-1",
+				Name:   "vm1",
+				Status: "ACTIVE",
+				Flavor: map[string]any{"id": "flavor-1"},
+				Image:  map[string]any{"id": "image-1"},
+			},
+		},
+		Flavors:  []flavors.Flavor{{ID: "flavor-1", Name: "m1.small", VCPUs: 1, RAM: 2048, Disk: 20}},
+		Networks: []networks.Network{{ID: "network-1", Name: "private"}},
+		Volumes:  []volumes.Volume{{ID: "volume-1", Name: "data", Size: 10}},
+		Images:   []images.Image{{ID: "image-1", Name: "ubuntu-24.04"}},
+	}
+	data, err := oss.DumpData()
+	if err != nil {
+		t.Fatalf("DumpData() error = %v", err)
+	}
+	loaded := &Source{}
+	if err := loaded.LoadData(data); err != nil {
+		t.Fatalf("LoadData() error = %v", err)
+	}
+	if len(loaded.Servers) != 1 || loaded.Servers[0].Name != "vm1" ||
+		loaded.Servers[0].Flavor.(map[string]any)["id"] != "flavor-1" {
+		t.Errorf("loaded Servers = %+v, want %+v", loaded.Servers, oss.Servers)
+	}
+	if len(loaded.Flavors) != 1 || loaded.Flavors[0].RAM != 2048 || loaded.Flavors[0].Disk != 20 {
+		t.Errorf("loaded Flavors = %+v, want %+v", loaded.Flavors, oss.Flavors)
+	}
+	if len(loaded.Networks) != 1 || loaded.Networks[0].Name != "private" {
+		t.Errorf("loaded Networks = %+v, want %+v", loaded.Networks, oss.Networks)
+	}
+	if len(loaded.Volumes) != 1 || loaded.Volumes[0].Name != "data" || loaded.Volumes[0].Size != 10 {
+		t.Errorf("loaded Volumes = %+v, want %+v", loaded.Volumes, oss.Volumes)
+	}
+	if len(loaded.Images) != 1 || loaded.Images[0].Name != "ubuntu-24.04" {
+		t.Errorf("loaded Images = %+v, want %+v", loaded.Images, oss.Images)
+	}
+}
@@ -1,6 +1,7 @@
 package openstack
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 
@@ -112,14 +113,31 @@ func (oss *Source) syncServers(nbi *inventory.NetboxInventory) error {
 			vmStatus = &objects.VMStatusOffline
 		}
 
+		// Server metadata is mapped to custom fields
+		vmAttributes := map[string]interface{}{}
+		if sMeta, ok := server.Metadata.(map[string]interface{}); ok {
+			for key, value := range sMeta {
+				vmAttributes["metadata."+key] = value
+			}
+		}
+		vmCustomFields, err := common.MapCustomFields(
+			oss.Ctx,
+			nbi,
+			oss.SourceConfig.CustomFieldMappings,
+			constants.ContentTypeVirtualizationVirtualMachine,
+			vmAttributes,
+		)
+		if err != nil {
+			return fmt.Errorf("error mapping custom fields of vm %s: %s", server.Name, err)
+		}
+		vmCustomFields[constants.CustomFieldSourceName] = oss.SourceConfig.Name
+		vmCustomFields[constants.CustomFieldSourceIDName] = server.ID
+
 		vm := &objects.VM{
 			NetboxObject: objects.NetboxObject{
-				Tags:        oss.GetSourceTags(),
-				Description: flavorName,
-				CustomFields: map[string]interface{}{
-					constants.CustomFieldSourceName:   oss.SourceConfig.Name,
-					constants.CustomFieldSourceIDName: server.ID,
-				},
+				Tags:         oss.GetSourceTags(),
+				Description:  flavorName,
+				CustomFields: vmCustomFields,
 			},
 			Name:     server.Name,
 			Cluster:  cluster,
@@ -132,6 +150,9 @@ func (oss *Source) syncServers(nbi *inventory.NetboxInventory) error {
 		}
 
 		nbVM, err := nbi.AddVM(oss.Ctx, vm)
+		if errors.Is(err, inventory.ErrDropped) {
+			continue
+		}
 		if err != nil {
 			return fmt.Errorf("error adding vm %s: %s", server.Name, err)
 		}
@@ -1,12 +1,14 @@
 package ovirt
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
 	"github.com/bl4ko/netbox-ssot/internal/source/common"
 	"github.com/bl4ko/netbox-ssot/internal/utils"
 	ovirtsdk4 "github.com/ovirt/go-ovirt"
@@ -23,6 +25,9 @@ type OVirtSource struct {
 	Hosts       map[string]*ovirtsdk4.Host
 	Vms         map[string]*ovirtsdk4.Vm
 	Networks    map[string]*NetworkData // key: datacenter ID
+
+	// NetboxHosts is a map of host IDs to their devices in Netbox. Created in sync function.
+	NetboxHosts map[string]*objects.Device
 }
 
 type NetworkData struct {
@@ -32,7 +37,8 @@ type NetworkData struct {
 }
 
 // Function that initializes state from ovirt api to local storage.
-func (o *OVirtSource) Init() error {
+func (o *OVirtSource) Init(ctx context.Context) error {
+	o.Ctx = ctx
 	// Build the connection
 	o.Logger.Debug(o.Ctx, "Initializing oVirt source ", o.SourceConfig.Name)
 	connBuilder := ovirtsdk4.NewConnectionBuilder().
@@ -73,6 +79,9 @@ func (o *OVirtSource) Init() error {
 
 	for _, initFunc := range initFunctions {
 		startTime := time.Now()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := initFunc(conn); err != nil {
 			return fmt.Errorf(
 				"failed to initialize oVirt %s: %v",
@@ -92,7 +101,8 @@ func (o *OVirtSource) Init() error {
 }
 
 // Function that syncs all data from oVirt to Netbox.
-func (o *OVirtSource) Sync(nbi *inventory.NetboxInventory) error {
+func (o *OVirtSource) Sync(ctx context.Context, nbi *inventory.NetboxInventory) error {
+	o.Ctx = ctx
 	syncFunctions := []func(*inventory.NetboxInventory) error{
 		o.syncNetworks,
 		o.syncDatacenters,
@@ -104,6 +114,9 @@ func (o *OVirtSource) Sync(nbi *inventory.NetboxInventory) error {
 	for _, syncFunc := range syncFunctions {
 		startTime := time.Now()
 		funcName := utils.ExtractFunctionNameWithTrimPrefix(syncFunc, "sync")
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("sync %s: %w", funcName, err)
+		}
 		err := syncFunc(nbi)
 		if err != nil {
 			if o.SourceConfig.ContinueOnError {
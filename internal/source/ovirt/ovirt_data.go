@@ -0,0 +1,138 @@
+package ovirt
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"maps"
+	"slices"
+
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// DumpData implements common.Dumper. oVirt objects can't be marshalled
+// to JSON, so they are dumped in the XML format of the oVirt API.
+func (o *OVirtSource) DumpData() (map[string]json.RawMessage, error) {
+	var networks []*ovirtsdk4.Network
+	for _, dcID := range slices.Sorted(maps.Keys(o.Networks)) {
+		networkData := o.Networks[dcID]
+		for _, networkID := range slices.Sorted(maps.Keys(networkData.OVirtNetworks)) {
+			networks = append(networks, networkData.OVirtNetworks[networkID])
+		}
+	}
+	data := make(map[string]json.RawMessage)
+	var err error
+	if data["Disks"], err = dumpXML(sortedValues(o.Disks), ovirtsdk4.XMLDiskWriteMany); err != nil {
+		return nil, fmt.Errorf("dump disks: %s", err)
+	}
+	if data["DataCenters"], err = dumpXML(
+		sortedValues(o.DataCenters), ovirtsdk4.XMLDataCenterWriteMany,
+	); err != nil {
+		return nil, fmt.Errorf("dump data centers: %s", err)
+	}
+	if data["Clusters"], err = dumpXML(sortedValues(o.Clusters), ovirtsdk4.XMLClusterWriteMany); err != nil {
+		return nil, fmt.Errorf("dump clusters: %s", err)
+	}
+	if data["Networks"], err = dumpXML(networks, ovirtsdk4.XMLNetworkWriteMany); err != nil {
+		return nil, fmt.Errorf("dump networks: %s", err)
+	}
+	if data["Hosts"], err = dumpXML(sortedValues(o.Hosts), ovirtsdk4.XMLHostWriteMany); err != nil {
+		return nil, fmt.Errorf("dump hosts: %s", err)
+	}
+	if data["Vms"], err = dumpXML(sortedValues(o.Vms), ovirtsdk4.XMLVmWriteMany); err != nil {
+		return nil, fmt.Errorf("dump vms: %s", err)
+	}
+	return data, nil
+}
+
+// LoadData implements common.Dumper.
+func (o *OVirtSource) LoadData(data map[string]json.RawMessage) error {
+	disks, err := loadXML(data["Disks"], ovirtsdk4.XMLDiskReadMany)
+	if err != nil {
+		return fmt.Errorf("load disks: %s", err)
+	}
+	o.Disks = byID(disks)
+	dataCenters, err := loadXML(data["DataCenters"], ovirtsdk4.XMLDataCenterReadMany)
+	if err != nil {
+		return fmt.Errorf("load data centers: %s", err)
+	}
+	o.DataCenters = byID(dataCenters)
+	clusters, err := loadXML(data["Clusters"], ovirtsdk4.XMLClusterReadMany)
+	if err != nil {
+		return fmt.Errorf("load clusters: %s", err)
+	}
+	o.Clusters = byID(clusters)
+	// Networks are grouped by data centers, so they are loaded after them
+	networks, err := loadXML(data["Networks"], ovirtsdk4.XMLNetworkReadMany)
+	if err != nil {
+		return fmt.Errorf("load networks: %s", err)
+	}
+	o.setNetworks(networks)
+	hosts, err := loadXML(data["Hosts"], ovirtsdk4.XMLHostReadMany)
+	if err != nil {
+		return fmt.Errorf("load hosts: %s", err)
+	}
+	o.Hosts = byID(hosts)
+	vms, err := loadXML(data["Vms"], ovirtsdk4.XMLVmReadMany)
+	if err != nil {
+		return fmt.Errorf("load vms: %s", err)
+	}
+	o.Vms = byID(vms)
+	return nil
+}
+
+// dumpXML writes items with write, and marshals the XML as a JSON string.
+func dumpXML[T any, S any, PS interface {
+	*S
+	SetSlice([]T)
+}](items []T, write func(*ovirtsdk4.XMLWriter, PS, string, string) error) (json.RawMessage, error) {
+	slice := PS(new(S))
+	slice.SetSlice(items)
+	var buf bytes.Buffer
+	writer := ovirtsdk4.NewXMLWriter(&buf)
+	if err := write(writer, slice, "", ""); err != nil {
+		return nil, err
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(buf.String())
+}
+
+// loadXML reads items with read from data dumped with dumpXML.
+func loadXML[T any, S any, PS interface {
+	*S
+	Slice() []T
+}](data json.RawMessage, read func(*ovirtsdk4.XMLReader, *xml.StartElement) (PS, error)) ([]T, error) {
+	if data == nil {
+		return nil, nil
+	}
+	var content string
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, err
+	}
+	slice, err := read(ovirtsdk4.NewXMLReader([]byte(content)), nil)
+	if err != nil || slice == nil {
+		return nil, err
+	}
+	return slice.Slice(), nil
+}
+
+// sortedValues returns values of items sorted by their keys.
+func sortedValues[T any](items map[string]T) []T {
+	values := make([]T, 0, len(items))
+	for _, key := range slices.Sorted(maps.Keys(items)) {
+		values = append(values, items[key])
+	}
+	return values
+}
+
+// byID returns items by their IDs, as they are stored in Init.
+func byID[T interface{ MustId() string }](items []T) map[string]T {
+	itemsByID := make(map[string]T, len(items))
+	for _, item := range items {
+		itemsByID[item.MustId()] = item
+	}
+	return itemsByID
+}
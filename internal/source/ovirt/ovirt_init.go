@@ -17,6 +17,18 @@ func (o *OVirtSource) initNetworks(conn *ovirtsdk4.Connection) error {
 	if err != nil {
 		return fmt.Errorf("init oVirt networks: %v", err)
 	}
+	if networks, ok := networksResponse.Networks(); ok {
+		o.setNetworks(networks.Slice())
+		o.Logger.Debug(o.Ctx, "Successfully initialized oVirt networks: ", o.Networks)
+	} else {
+		o.setNetworks(nil)
+		o.Logger.Warning(o.Ctx, "Error initializing oVirt networks")
+	}
+	return nil
+}
+
+// setNetworks stores networks to local object, grouped by datacenter ID.
+func (o *OVirtSource) setNetworks(networks []*ovirtsdk4.Network) {
 	o.Networks = make(map[string]*NetworkData, len(o.DataCenters))
 	for dcID := range o.DataCenters {
 		o.Networks[dcID] = &NetworkData{
@@ -25,44 +37,38 @@ func (o *OVirtSource) initNetworks(conn *ovirtsdk4.Connection) error {
 			VnicProfile2Network: make(map[string]string),
 		}
 	}
-	if networks, ok := networksResponse.Networks(); ok {
-		for _, network := range networks.Slice() {
-			networkID, ok := network.Id()
-			if !ok {
-				continue
-			}
-			dc, ok := network.DataCenter()
-			if !ok {
-				continue
-			}
-			dcID, ok := dc.Id()
-			if !ok {
-				continue
-			}
-			networkData, ok := o.Networks[dcID]
-			if !ok {
-				o.Logger.Warningf(o.Ctx, "network %s references unknown datacenter %s, skipping", networkID, dcID)
-				continue
-			}
-			networkData.OVirtNetworks[networkID] = network
-			if vlan, exists := network.Vlan(); exists {
-				if vlanID, exists := vlan.Id(); exists {
-					networkData.Vid2Name[int(vlanID)] = network.MustName()
-				}
+	for _, network := range networks {
+		networkID, ok := network.Id()
+		if !ok {
+			continue
+		}
+		dc, ok := network.DataCenter()
+		if !ok {
+			continue
+		}
+		dcID, ok := dc.Id()
+		if !ok {
+			continue
+		}
+		networkData, ok := o.Networks[dcID]
+		if !ok {
+			o.Logger.Warningf(o.Ctx, "network %s references unknown datacenter %s, skipping", networkID, dcID)
+			continue
+		}
+		networkData.OVirtNetworks[networkID] = network
+		if vlan, exists := network.Vlan(); exists {
+			if vlanID, exists := vlan.Id(); exists {
+				networkData.Vid2Name[int(vlanID)] = network.MustName()
 			}
-			if vnicProfiles, ok := network.VnicProfiles(); ok {
-				for _, vnicProfile := range vnicProfiles.Slice() {
-					if vnicProfileID, ok := vnicProfile.Id(); ok {
-						networkData.VnicProfile2Network[vnicProfileID] = networkID
-					}
+		}
+		if vnicProfiles, ok := network.VnicProfiles(); ok {
+			for _, vnicProfile := range vnicProfiles.Slice() {
+				if vnicProfileID, ok := vnicProfile.Id(); ok {
+					networkData.VnicProfile2Network[vnicProfileID] = networkID
 				}
 			}
 		}
-		o.Logger.Debug(o.Ctx, "Successfully initialized oVirt networks: ", o.Networks)
-	} else {
-		o.Logger.Warning(o.Ctx, "Error initializing oVirt networks")
 	}
-	return nil
 }
 
 func (o *OVirtSource) initDisks(conn *ovirtsdk4.Connection) error {
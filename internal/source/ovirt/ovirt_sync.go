@@ -1,13 +1,16 @@
 package ovirt
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/expr"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
 	"github.com/bl4ko/netbox-ssot/internal/source/common"
 	"github.com/bl4ko/netbox-ssot/internal/utils"
 	ovirtsdk4 "github.com/ovirt/go-ovirt"
@@ -82,6 +85,16 @@ func (o *OVirtSource) syncNetworks(nbi *inventory.NetboxInventory) error {
 						Tenant:   vlanTenant,
 						Comments: network.MustComment(),
 					}
+					err = common.ApplyRelationRules(
+						o.Ctx,
+						nbi,
+						o.SourceConfig.RelationRules,
+						vlanStruct,
+						common.VlanAttributes(vlanStruct),
+					)
+					if err != nil {
+						return fmt.Errorf("match vlan to relation rules: %s", err)
+					}
 					_, err := nbi.AddVlan(o.Ctx, vlanStruct)
 					if err != nil {
 						return fmt.Errorf("adding vlan %s: %v", vlanStruct, err)
@@ -153,9 +166,10 @@ func (o *OVirtSource) syncClusters(nbi *inventory.NetboxInventory) error {
 			o.Logger.Warning(o.Ctx, "description for oVirt cluster ", clusterName, " is empty.")
 		}
 		var clusterGroup *objects.ClusterGroup
-		var clusterGroupName string
+		var clusterGroupName, datacenterName string
 		if _, ok := o.DataCenters[cluster.MustDataCenter().MustId()]; ok {
-			clusterGroupName = o.DataCenters[cluster.MustDataCenter().MustId()].MustName()
+			datacenterName = o.DataCenters[cluster.MustDataCenter().MustId()].MustName()
+			clusterGroupName = datacenterName
 		} else {
 			o.Logger.Warning(o.Ctx, "failed to get datacenter for oVirt cluster ", clusterName)
 		}
@@ -205,6 +219,13 @@ func (o *OVirtSource) syncClusters(nbi *inventory.NetboxInventory) error {
 			ScopeID:   clusterScopeID,
 			Tenant:    clusterTenant,
 		}
+		clusterAttributes := expr.Attributes{}
+		clusterAttributes.Set(parser.RelationAttributeName, clusterName)
+		clusterAttributes.Set(parser.RelationAttributeDatacenter, datacenterName)
+		err = common.ApplyRelationRules(o.Ctx, nbi, o.SourceConfig.RelationRules, nbCluster, clusterAttributes)
+		if err != nil {
+			return fmt.Errorf("match cluster to relation rules: %s", err)
+		}
 		_, err = nbi.AddCluster(o.Ctx, nbCluster)
 		if err != nil {
 			return fmt.Errorf(
@@ -227,9 +248,13 @@ func (o *OVirtSource) syncHosts(nbi *inventory.NetboxInventory) error {
 		}
 
 		nbHost, err := nbi.AddDevice(o.Ctx, hostStruct)
+		if errors.Is(err, inventory.ErrDropped) {
+			continue
+		}
 		if err != nil {
 			return fmt.Errorf("failed to add oVirt host %+v with error: %v", hostStruct, err)
 		}
+		o.NetboxHosts[hostID] = nbHost
 
 		// We also need to sync nics separately, because nic is a separate object in netbox
 		err = o.syncHostNics(nbi, host, nbHost)
@@ -407,7 +432,7 @@ func extractHostData(
 		}
 	}
 
-	return &objects.Device{
+	hostStruct := &objects.Device{
 		NetboxObject: objects.NetboxObject{
 			Description: hostDescription,
 			Tags:        o.GetSourceTags(),
@@ -428,7 +453,19 @@ func extractHostData(
 		Comments:     hostComment,
 		SerialNumber: hostSerialNumber,
 		DeviceType:   hostDeviceType,
-	}, nil
+	}
+	hostAttributes := expr.Attributes{}
+	hostAttributes.Set(parser.RelationAttributeName, hostName)
+	if ovirtCluster, ok := o.Clusters[host.MustCluster().MustId()]; ok {
+		hostAttributes.Set(parser.RelationAttributeCluster, ovirtCluster.MustName())
+		_, datacenterName := o.networkDataForClusterID(host.MustCluster().MustId())
+		hostAttributes.Set(parser.RelationAttributeDatacenter, datacenterName)
+	}
+	err = common.ApplyRelationRules(o.Ctx, nbi, o.SourceConfig.RelationRules, hostStruct, hostAttributes)
+	if err != nil {
+		return nil, fmt.Errorf("match host to relation rules: %s", err)
+	}
+	return hostStruct, nil
 }
 
 // networkDataForClusterID resolves the NetworkData and datacenter name for a given cluster ID.
@@ -951,6 +988,17 @@ func (o *OVirtSource) collectHostNicsData(
 				if err != nil {
 					return err
 				}
+				vlanGroup, err = common.MatchVlanGroupRules(
+					o.Ctx,
+					nbi,
+					o.SourceConfig.RelationRules,
+					vlanName,
+					int(vlanID),
+					vlanGroup,
+				)
+				if err != nil {
+					return fmt.Errorf("match vlan to relation rules: %s", err)
+				}
 				// Get vlan from inventory
 				nicVlan, _ = nbi.GetVlan(vlanGroup.ID, int(vlanID))
 			}
@@ -1034,6 +1082,8 @@ func (o *OVirtSource) syncVMs(nbi *inventory.NetboxInventory) error {
 	guard := make(chan struct{}, maxGoroutines)
 	errChan := make(chan error, len(o.Vms))
 	var wg sync.WaitGroup
+	// Networks of all VMs are added after the VMs with bulk requests
+	var vmNetworks common.VMNetworks
 
 	for vmID, ovirtVM := range o.Vms {
 		guard <- struct{}{} // Block if maxGoroutines are running
@@ -1043,7 +1093,7 @@ func (o *OVirtSource) syncVMs(nbi *inventory.NetboxInventory) error {
 			defer wg.Done()
 			defer func() { <-guard }() // Release one spot in the semaphore
 
-			if err := o.syncVM(nbi, vmID, ovirtVM); err != nil {
+			if err := o.syncVM(nbi, vmID, ovirtVM, &vmNetworks); err != nil {
 				errChan <- err
 			}
 		}(vmID, ovirtVM)
@@ -1059,14 +1109,19 @@ func (o *OVirtSource) syncVMs(nbi *inventory.NetboxInventory) error {
 		}
 	}
 
+	if err := vmNetworks.Sync(o.Ctx, nbi); err != nil {
+		return fmt.Errorf("failed to sync oVirt vms' interfaces: %v", err)
+	}
 	return nil
 }
 
 // syncVM synces a single ovirt vm into netbox inventory.
+// Interfaces of the vm are collected into vmNetworks.
 func (o *OVirtSource) syncVM(
 	nbi *inventory.NetboxInventory,
 	vmID string,
 	ovirtVM *ovirtsdk4.Vm,
+	vmNetworks *common.VMNetworks,
 ) error {
 	collectedVM, collectedVMDisks, err := o.extractVMData(nbi, vmID, ovirtVM)
 	if err != nil {
@@ -1074,6 +1129,9 @@ func (o *OVirtSource) syncVM(
 	}
 
 	nbVM, err := nbi.AddVM(o.Ctx, collectedVM)
+	if errors.Is(err, inventory.ErrDropped) {
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to sync oVirt vm %s: %v", collectedVM.Name, err)
 	}
@@ -1085,7 +1143,7 @@ func (o *OVirtSource) syncVM(
 		}
 	}
 
-	err = o.syncVMInterfaces(nbi, ovirtVM, nbVM)
+	err = o.collectVMInterfaces(nbi, ovirtVM, nbVM, vmNetworks)
 	if err != nil {
 		return fmt.Errorf("failed to sync oVirt vm %s's interfaces: %v", collectedVM.Name, err)
 	}
@@ -1172,7 +1230,9 @@ func (o *OVirtSource) extractVMData(
 						return nil, nil, fmt.Errorf("vm's host site: %s", err)
 					}
 				}
-				if vmSite != nil {
+				// Host can be renamed by a transform rule, so it is looked up by its ID first
+				vmHostDevice = o.NetboxHosts[host.MustId()]
+				if vmHostDevice == nil && vmSite != nil {
 					vmHostDevice, _ = nbi.GetDevice(oHostName, vmSite.ID)
 				}
 			}
@@ -1290,12 +1350,31 @@ func (o *OVirtSource) extractVMData(
 		)
 	}
 
-	return &objects.VM{
+	// Custom properties of the VM are mapped to custom fields
+	vmAttributes := map[string]interface{}{}
+	if customProperties, exists := vm.CustomProperties(); exists {
+		for _, customProperty := range customProperties.Slice() {
+			name, _ := customProperty.Name()
+			value, _ := customProperty.Value()
+			vmAttributes["customProperties."+name] = value
+		}
+	}
+	vmCustomFields, err := common.MapCustomFields(
+		o.Ctx,
+		nbi,
+		o.SourceConfig.CustomFieldMappings,
+		constants.ContentTypeVirtualizationVirtualMachine,
+		vmAttributes,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("map vm custom fields: %s", err)
+	}
+	vmCustomFields[constants.CustomFieldSourceName] = o.SourceConfig.Name
+
+	vmStruct := &objects.VM{
 		NetboxObject: objects.NetboxObject{
-			Tags: o.GetSourceTags(),
-			CustomFields: map[string]interface{}{
-				constants.CustomFieldSourceName: o.SourceConfig.Name,
-			},
+			Tags:         o.GetSourceTags(),
+			CustomFields: vmCustomFields,
 		},
 		Name:        vmName,
 		Cluster:     vmCluster,
@@ -1309,17 +1388,33 @@ func (o *OVirtSource) extractVMData(
 		Comments:    vmComments,
 		VCPUs:       vmVCPUs,
 		Memory:      int(vmMemorySizeBytes) / constants.MB, // MBs (default in netbox)
-	}, vmDisks, nil
+	}
+	vmRelationAttributes := expr.Attributes{}
+	vmRelationAttributes.Set(parser.RelationAttributeName, vmName)
+	if vmCluster != nil {
+		vmRelationAttributes.Set(parser.RelationAttributeCluster, vmCluster.Name)
+	}
+	if vmHostDevice != nil {
+		vmRelationAttributes.Set(parser.RelationAttributeHost, vmHostDevice.Name)
+	}
+	err = common.ApplyRelationRules(o.Ctx, nbi, o.SourceConfig.RelationRules, vmStruct, vmRelationAttributes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("match vm to relation rules: %s", err)
+	}
+	return vmStruct, vmDisks, nil
 }
 
 // syncVMInterfaces is a helper function for syncVMS. It syncs all interfaces from a VM to netbox.
 // Guest-agent reported devices are matched to oVirt NICs by MAC address so a single netbox
 // interface is created per NIC (named after the guest interface, carrying the NIC's VLAN data).
 // NICs not reported by the guest agent are created with their oVirt name.
-func (o *OVirtSource) syncVMInterfaces(
+// collectVMInterfaces collects interfaces of the vm with their IPs into vmNetworks,
+// which adds them to netbox together with interfaces of other vms.
+func (o *OVirtSource) collectVMInterfaces(
 	nbi *inventory.NetboxInventory,
 	ovirtVM *ovirtsdk4.Vm,
 	netboxVM *objects.VM,
+	vmNetworks *common.VMNetworks,
 ) error {
 	nicsData, err := o.collectVMNicData(nbi, ovirtVM)
 	if err != nil {
@@ -1333,80 +1428,62 @@ func (o *OVirtSource) syncVMInterfaces(
 	}
 	processedNics := make(map[*vmNicData]bool)
 
+	networks := make([]*common.VMNetwork, 0)
 	if reportedDevices, exist := ovirtVM.ReportedDevices(); exist {
 		for _, reportedDevice := range reportedDevices.Slice() {
 			if reportedDeviceType, exist := reportedDevice.Type(); exist {
 				if reportedDeviceType == "network" {
 					// We add interface to the list
-					var vmInterface *objects.VMInterface
-					var err error
 					vmInterfaceMac := ""
 					if macAddressObj, exists := reportedDevice.Mac(); exists {
 						if macAddress, exists := macAddressObj.Address(); exists {
 							vmInterfaceMac = strings.ToUpper(macAddress)
 						}
 					}
-					if reportedDeviceName, exists := reportedDevice.Name(); exists {
-						if utils.FilterInterfaceName(
+					reportedDeviceName, exists := reportedDevice.Name()
+					if !exists {
+						o.Logger.Warning(o.Ctx, "name for oVirt vm's reported device is empty. Skipping...")
+						continue
+					}
+					if utils.FilterInterfaceName(
+						reportedDeviceName,
+						o.SourceConfig.InterfaceFilter,
+					) {
+						o.Logger.Debugf(
+							o.Ctx,
+							"interface %s is filtered out with interfaceFilter %s",
 							reportedDeviceName,
 							o.SourceConfig.InterfaceFilter,
-						) {
-							o.Logger.Debugf(
-								o.Ctx,
-								"interface %s is filtered out with interfaceFilter %s",
-								reportedDeviceName,
-								o.SourceConfig.InterfaceFilter,
-							)
-							continue
-						}
-						vmInterfaceStruct := &objects.VMInterface{
-							NetboxObject: objects.NetboxObject{
-								Tags:        o.GetSourceTags(),
-								Description: reportedDevice.MustDescription(),
-							},
-							VM:      netboxVM,
-							Name:    reportedDeviceName,
-							Enabled: true, // TODO
-						}
-						if nicData, ok := mac2NicData[vmInterfaceMac]; ok {
-							processedNics[nicData] = true
-							if vmInterfaceStruct.Description == "" {
-								vmInterfaceStruct.Description = nicData.description
-							}
-							vmInterfaceStruct.Mode = nicData.mode
-							vmInterfaceStruct.TaggedVlans = nicData.vlans
-							vmInterfaceStruct.CustomFields = map[string]interface{}{
-								constants.CustomFieldSourceIDName: nicData.id,
-							}
-						}
-						vmInterface, err = nbi.AddVMInterface(o.Ctx, vmInterfaceStruct)
-						if err != nil {
-							return fmt.Errorf(
-								"failed to sync oVirt vm %s's interface %+v: %v",
-								netboxVM.Name,
-								vmInterfaceStruct,
-								err,
-							)
+						)
+						continue
+					}
+					vmInterfaceStruct := &objects.VMInterface{
+						NetboxObject: objects.NetboxObject{
+							Tags:        o.GetSourceTags(),
+							Description: reportedDevice.MustDescription(),
+						},
+						VM:      netboxVM,
+						Name:    reportedDeviceName,
+						Enabled: true, // TODO
+					}
+					if nicData, ok := mac2NicData[vmInterfaceMac]; ok {
+						processedNics[nicData] = true
+						if vmInterfaceStruct.Description == "" {
+							vmInterfaceStruct.Description = nicData.description
 						}
-						if vmInterfaceMac != "" {
-							nbMACAddress, err := common.CreateMACAddressForObjectType(
-								o.Ctx,
-								nbi,
-								vmInterfaceMac,
-								vmInterface,
-							)
-							if err != nil {
-								return fmt.Errorf("create mac address for object type: %s", err)
-							}
-							if err = common.SetPrimaryMACForInterface(o.Ctx, nbi, vmInterface, nbMACAddress); err != nil {
-								return fmt.Errorf("set primary mac for interface: %s", err)
-							}
+						vmInterfaceStruct.Mode = nicData.mode
+						vmInterfaceStruct.TaggedVlans = nicData.vlans
+						vmInterfaceStruct.CustomFields = map[string]interface{}{
+							constants.CustomFieldSourceIDName: nicData.id,
 						}
-					} else {
-						o.Logger.Warning(o.Ctx, "name for oVirt vm's reported device is empty. Skipping...")
-						continue
 					}
-					o.processVMInterfaceIPs(nbi, reportedDevice, netboxVM, vmInterface)
+					ipv4Addresses, ipv6Addresses := o.collectVMInterfaceIPs(nbi, reportedDevice, netboxVM)
+					networks = append(networks, &common.VMNetwork{
+						Interface:     vmInterfaceStruct,
+						MACAddress:    vmInterfaceMac,
+						IPv4Addresses: ipv4Addresses,
+						IPv6Addresses: ipv6Addresses,
+					})
 				}
 			}
 		}
@@ -1418,21 +1495,34 @@ func (o *OVirtSource) syncVMInterfaces(
 		if processedNics[nicData] {
 			continue
 		}
-		if err := o.addVMNicInterface(nbi, netboxVM, nicData); err != nil {
-			return err
-		}
+		networks = append(networks, o.vmNicNetwork(netboxVM, nicData))
 	}
+
+	// Primary ipv4 of the vm is the one its name resolves to
+	vmIP := utils.Lookup(netboxVM.Name)
+	vmNetworks.Add(netboxVM, networks, func(ipv4Addresses, _ []*objects.IPAddress) (
+		*objects.IPAddress, *objects.IPAddress,
+	) {
+		primaryIPv4 := netboxVM.PrimaryIPv4
+		for _, ipv4Address := range ipv4Addresses {
+			if primaryIPv4 == nil || vmIP != "" && strings.Split(ipv4Address.Address, "/")[0] == vmIP {
+				primaryIPv4 = ipv4Address
+			}
+		}
+		return primaryIPv4, netboxVM.PrimaryIPv6
+	})
 	return nil
 }
 
-// processVMInterfaceIPs is a helper function for syncVMInterfaces,
-// that processes IPs of VM interfaces.
-func (o *OVirtSource) processVMInterfaceIPs(
+// collectVMInterfaceIPs is a helper function for collectVMInterfaces,
+// that collects permitted ipv4 and ipv6 addresses of VM interfaces.
+func (o *OVirtSource) collectVMInterfaceIPs(
 	nbi *inventory.NetboxInventory,
 	reportedDevice *ovirtsdk4.ReportedDevice,
 	netboxVM *objects.VM,
-	vmInterface *objects.VMInterface,
-) {
+) ([]*objects.IPAddress, []*objects.IPAddress) {
+	ipv4Addresses := make([]*objects.IPAddress, 0)
+	ipv6Addresses := make([]*objects.IPAddress, 0)
 	if reportedDeviceIps, exist := reportedDevice.Ips(); exist {
 		for _, ip := range reportedDeviceIps.Slice() {
 			if ipAddress, exists := ip.Address(); exists {
@@ -1484,62 +1574,19 @@ func (o *OVirtSource) processVMInterfaceIPs(
 							Status:             &objects.IPAddressStatusActive,
 							DNSName:            hostname,
 							AssignedObjectType: constants.ContentTypeVirtualizationVMInterface,
-							AssignedObjectID:   vmInterface.ID,
 							VRF:                ipVRF,
 						}
-						newIPAddress, err := nbi.AddIPAddress(
-							o.Ctx,
-							ipAddressStruct,
-						)
-
-						if err != nil {
-							o.Logger.Warningf(
-								o.Ctx,
-								"add ip address %+v: %s",
-								err,
-								ipAddressStruct,
-							)
-							continue
-						}
-
-						// Check if ip is primary
 						if ipVersion == "v4" {
-							vmIP := utils.Lookup(netboxVM.Name)
-							if vmIP != "" && vmIP == ipAddress ||
-								netboxVM.PrimaryIPv4 == nil {
-								vmCopy := *netboxVM
-								vmCopy.PrimaryIPv4 = newIPAddress
-								_, err := nbi.AddVM(o.Ctx, &vmCopy)
-								if err != nil {
-									o.Logger.Warningf(
-										o.Ctx,
-										"adding vm's primary ipv4 address %+v: %s",
-										newIPAddress,
-										err,
-									)
-									continue
-								}
-							}
-						}
-						prefix, mask, err := utils.GetPrefixAndMaskFromIPAddress(
-							newIPAddress.Address,
-						)
-						if err != nil {
-							o.Logger.Debugf(o.Ctx, "extract prefix: %s", err)
-						} else if mask != constants.MaxIPv4MaskBits {
-							_, err = nbi.AddPrefix(o.Ctx, &objects.Prefix{
-								Prefix: prefix,
-								VRF:    ipVRF,
-							})
-							if err != nil {
-								o.Logger.Errorf(o.Ctx, "add prefix %+v: %s", prefix, err)
-							}
+							ipv4Addresses = append(ipv4Addresses, ipAddressStruct)
+						} else {
+							ipv6Addresses = append(ipv6Addresses, ipAddressStruct)
 						}
 					}
 				}
 			}
 		}
 	}
+	return ipv4Addresses, ipv6Addresses
 }
 
 // vmNicData holds data collected from an oVirt VM NIC. It is used to enrich
@@ -1660,6 +1707,17 @@ func (o *OVirtSource) collectVMNicData(
 									o.Logger.Warningf(o.Ctx, "match vlan to group: %s", err)
 									continue
 								}
+								vlanGroup, err = common.MatchVlanGroupRules(
+									o.Ctx,
+									nbi,
+									o.SourceConfig.RelationRules,
+									vlanName,
+									int(vlanID),
+									vlanGroup,
+								)
+								if err != nil {
+									return nil, fmt.Errorf("match vlan to relation rules: %s", err)
+								}
 								nicVlan, _ := nbi.GetVlan(vlanGroup.ID, int(vlanID))
 								nicVlans = []*objects.Vlan{nicVlan}
 								nicMode = &objects.VMInterfaceModeTagged
@@ -1681,50 +1739,23 @@ func (o *OVirtSource) collectVMNicData(
 	return nicsData, nil
 }
 
-// addVMNicInterface creates a netbox interface (with MAC address) from oVirt NIC data.
-func (o *OVirtSource) addVMNicInterface(
-	nbi *inventory.NetboxInventory,
-	netboxVM *objects.VM,
-	nicData *vmNicData,
-) error {
-	nbVMInterface, err := nbi.AddVMInterface(o.Ctx, &objects.VMInterface{
-		NetboxObject: objects.NetboxObject{
-			Tags:        o.GetSourceTags(),
-			Description: nicData.description,
-			CustomFields: map[string]interface{}{
-				constants.CustomFieldSourceIDName: nicData.id,
+// vmNicNetwork returns a netbox interface (with MAC address) from oVirt NIC data.
+func (o *OVirtSource) vmNicNetwork(netboxVM *objects.VM, nicData *vmNicData) *common.VMNetwork {
+	return &common.VMNetwork{
+		Interface: &objects.VMInterface{
+			NetboxObject: objects.NetboxObject{
+				Tags:        o.GetSourceTags(),
+				Description: nicData.description,
+				CustomFields: map[string]interface{}{
+					constants.CustomFieldSourceIDName: nicData.id,
+				},
 			},
+			VM:          netboxVM,
+			Name:        nicData.name,
+			Mode:        nicData.mode,
+			Enabled:     true,
+			TaggedVlans: nicData.vlans,
 		},
-		VM:          netboxVM,
-		Name:        nicData.name,
-		Mode:        nicData.mode,
-		Enabled:     true,
-		TaggedVlans: nicData.vlans,
-	})
-	if err != nil {
-		return fmt.Errorf("add vm interface: %s", err)
+		MACAddress: nicData.mac,
 	}
-	if nicData.mac != "" {
-		nbMACAddress, err := common.CreateMACAddressForObjectType(
-			o.Ctx,
-			nbi,
-			nicData.mac,
-			nbVMInterface,
-		)
-		if err != nil {
-			return fmt.Errorf(
-				"create mac address for vm interface %+v: %s",
-				nbVMInterface,
-				err,
-			)
-		}
-		if err = common.SetPrimaryMACForInterface(o.Ctx, nbi, nbVMInterface, nbMACAddress); err != nil {
-			return fmt.Errorf(
-				"set primary mac for vm interface %+v: %s",
-				nbVMInterface,
-				err,
-			)
-		}
-	}
-	return nil
 }
@@ -88,3 +88,59 @@ func TestCollectVMNicDataNoNics(t *testing.T) {
 		t.Fatalf("collectVMNicData() returned %d nics, want 0", len(nicsData))
 	}
 }
+
+func TestOVirtSourceDumpLoadData(t *testing.T) {
+	o := newTestOVirtSource(t, "")
+	dc := ovirtsdk4.NewDataCenterBuilder().Id("dc-1").Name("dc1").MustBuild()
+	o.DataCenters = map[string]*ovirtsdk4.DataCenter{"dc-1": dc}
+	o.Clusters = map[string]*ovirtsdk4.Cluster{
+		"cluster-1": ovirtsdk4.NewClusterBuilder().Id("cluster-1").Name("cluster1").DataCenter(dc).MustBuild(),
+	}
+	o.setNetworks([]*ovirtsdk4.Network{
+		ovirtsdk4.NewNetworkBuilder().
+			Id("network-1").
+			Name("vlan10").
+			DataCenter(dc).
+			Vlan(ovirtsdk4.NewVlanBuilder().Id(10).MustBuild()).
+			VnicProfilesOfAny(ovirtsdk4.NewVnicProfileBuilder().Id("profile-1").MustBuild()).
+			MustBuild(),
+	})
+	o.Hosts = map[string]*ovirtsdk4.Host{
+		"host-1": ovirtsdk4.NewHostBuilder().Id("host-1").Name("host1").MustBuild(),
+	}
+	o.Vms = map[string]*ovirtsdk4.Vm{
+		"vm-1": ovirtsdk4.NewVmBuilder().
+			Id("vm-1").
+			Name("vm1").
+			NicsOfAny(newTestNic("nic1", "nic-id-1", "56:6f:be:6a:03:21")).
+			MustBuild(),
+	}
+	o.Disks = map[string]*ovirtsdk4.Disk{}
+
+	data, err := o.DumpData()
+	if err != nil {
+		t.Fatalf("DumpData() error = %v", err)
+	}
+	loaded := newTestOVirtSource(t, "")
+	if err := loaded.LoadData(data); err != nil {
+		t.Fatalf("LoadData() error = %v", err)
+	}
+	if got := loaded.Clusters["cluster-1"].MustDataCenter().MustId(); got != "dc-1" {
+		t.Errorf("loaded data center of cluster-1 = %q, want %q", got, "dc-1")
+	}
+	if got := loaded.Hosts["host-1"].MustName(); got != "host1" {
+		t.Errorf("loaded name of host-1 = %q, want %q", got, "host1")
+	}
+	nics := loaded.Vms["vm-1"].MustNics().Slice()
+	if len(nics) != 1 || nics[0].MustMac().MustAddress() != "56:6f:be:6a:03:21" {
+		t.Errorf("loaded nics of vm-1 = %v, want nic1", nics)
+	}
+	networkData := loaded.Networks["dc-1"]
+	if networkData == nil || networkData.Vid2Name[10] != "vlan10" ||
+		networkData.VnicProfile2Network["profile-1"] != "network-1" {
+		t.Errorf("loaded networks of dc-1 = %+v, want network-1 with vlan 10 and profile-1", networkData)
+	}
+	if len(loaded.Disks) != 0 {
+		t.Errorf("loaded disks = %v, want none", loaded.Disks)
+	}
+}
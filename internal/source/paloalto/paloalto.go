@@ -1,6 +1,8 @@
 package paloalto
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -36,7 +38,8 @@ type PaloAltoSource struct {
 	NBFirewall *objects.Device
 }
 
-func (pas *PaloAltoSource) Init() error {
+func (pas *PaloAltoSource) Init(ctx context.Context) error {
+	pas.Ctx = ctx
 	var transport *http.Transport
 	var err error
 	if pas.CAFile != "" {
@@ -70,6 +73,9 @@ func (pas *PaloAltoSource) Init() error {
 	}
 	for _, initFunc := range initFunctions {
 		startTime := time.Now()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := initFunc(c); err != nil {
 			return fmt.Errorf("paloalto initialization failure: %v", err)
 		}
@@ -84,7 +90,8 @@ func (pas *PaloAltoSource) Init() error {
 	return nil
 }
 
-func (pas *PaloAltoSource) Sync(nbi *inventory.NetboxInventory) error {
+func (pas *PaloAltoSource) Sync(ctx context.Context, nbi *inventory.NetboxInventory) error {
+	pas.Ctx = ctx
 	syncFunctions := []func(*inventory.NetboxInventory) error{
 		pas.syncDevice,
 		pas.syncSecurityZones,
@@ -96,7 +103,15 @@ func (pas *PaloAltoSource) Sync(nbi *inventory.NetboxInventory) error {
 	for _, syncFunc := range syncFunctions {
 		startTime := time.Now()
 		funcName := utils.ExtractFunctionNameWithTrimPrefix(syncFunc, "sync")
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("sync %s: %w", funcName, err)
+		}
 		err := syncFunc(nbi)
+		if errors.Is(err, inventory.ErrDropped) {
+			// Other objects of the source belong to the dropped device
+			pas.Logger.Infof(pas.Ctx, "Device is dropped by a transform rule, skipping the rest of the sync")
+			return nil
+		}
 		if err != nil {
 			if pas.SourceConfig.ContinueOnError {
 				pas.Logger.Errorf(
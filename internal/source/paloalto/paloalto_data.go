@@ -0,0 +1,30 @@
+package paloalto
+
+import (
+	"encoding/json"
+
+	"github.com/bl4ko/netbox-ssot/internal/source/common"
+)
+
+// dataFields are fields of PaloAltoSource initialized in Init, which are dumped with --dump-source-data.
+var dataFields = []string{
+	"SystemInfo",
+	"VirtualSystems",
+	"SecurityZones",
+	"Iface2SecurityZone",
+	"Iface2VirtualRouter",
+	"Ifaces",
+	"Iface2SubIfaces",
+	"VirtualRouters",
+	"ArpData",
+}
+
+// DumpData implements common.Dumper.
+func (pas *PaloAltoSource) DumpData() (map[string]json.RawMessage, error) {
+	return common.MarshalFields(pas, dataFields, json.Marshal)
+}
+
+// LoadData implements common.Dumper.
+func (pas *PaloAltoSource) LoadData(data map[string]json.RawMessage) error {
+	return common.UnmarshalFields(pas, data, json.Unmarshal)
+}
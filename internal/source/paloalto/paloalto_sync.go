@@ -7,8 +7,10 @@ import (
 	"time"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/expr"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
 	"github.com/bl4ko/netbox-ssot/internal/source/common"
 	"github.com/bl4ko/netbox-ssot/internal/utils"
 )
@@ -95,9 +97,25 @@ func (pas *PaloAltoSource) syncDevice(nbi *inventory.NetboxInventory) error {
 	if err != nil {
 		return fmt.Errorf("add platform: %s", err)
 	}
+	// System info (e.g. sw-version, app-version) is mapped to custom fields
+	systemInfo := make(map[string]interface{}, len(pas.SystemInfo))
+	for key, value := range pas.SystemInfo {
+		systemInfo[key] = value
+	}
+	deviceCustomFields, err := common.MapCustomFields(
+		pas.Ctx,
+		nbi,
+		pas.SourceConfig.CustomFieldMappings,
+		constants.ContentTypeDcimDevice,
+		systemInfo,
+	)
+	if err != nil {
+		return fmt.Errorf("map device custom fields: %s", err)
+	}
 	deviceStruct := &objects.Device{
 		NetboxObject: objects.NetboxObject{
-			Tags: pas.GetSourceTags(),
+			Tags:         pas.GetSourceTags(),
+			CustomFields: deviceCustomFields,
 		},
 		Name:         deviceName,
 		Site:         deviceSite,
@@ -108,9 +126,15 @@ func (pas *PaloAltoSource) syncDevice(nbi *inventory.NetboxInventory) error {
 		Platform:     devicePlatform,
 		SerialNumber: deviceSerialNumber,
 	}
+	hostAttributes := expr.Attributes{}
+	hostAttributes.Set(parser.RelationAttributeName, deviceName)
+	err = common.ApplyRelationRules(pas.Ctx, nbi, pas.SourceConfig.RelationRules, deviceStruct, hostAttributes)
+	if err != nil {
+		return fmt.Errorf("match host to relation rules: %s", err)
+	}
 	NBDevice, err := nbi.AddDevice(pas.Ctx, deviceStruct)
 	if err != nil {
-		return fmt.Errorf("add device: %s", err)
+		return fmt.Errorf("add device: %w", err)
 	}
 
 	pas.NBFirewall = NBDevice
@@ -224,6 +248,16 @@ func (pas *PaloAltoSource) syncInterfaces(nbi *inventory.NetboxInventory) error
 					Tenant: vlanTenant,
 					Group:  vlanGroup,
 				}
+				err = common.ApplyRelationRules(
+					pas.Ctx,
+					nbi,
+					pas.SourceConfig.RelationRules,
+					vlanStruct,
+					common.VlanAttributes(vlanStruct),
+				)
+				if err != nil {
+					return fmt.Errorf("match vlan to relation rules: %s", err)
+				}
 				subIfaceVlan, err = nbi.AddVlan(pas.Ctx, vlanStruct)
 				if err != nil {
 					return fmt.Errorf("add vlan %+v: %s", vlanStruct, err)
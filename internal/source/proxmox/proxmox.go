@@ -22,6 +22,7 @@ type ProxmoxSource struct {
 	NodeIfaces      map[string][]*proxmox.NodeNetwork        // NodeName -> NodeNetworks (interfaces)
 	Vms             map[string][]*proxmox.VirtualMachine     // NodeName -> VirtualMachines
 	VMIfaces        map[string][]*proxmox.AgentNetworkIface  // VMName -> NetworkDevices
+	VMOsInfos       map[string]*proxmox.AgentOsInfo          // VMName -> OS info of running VMs from the guest agent
 	Containers      map[string][]*proxmox.Container          // NodeName -> Contatiners
 	ContainerIfaces map[string][]*proxmox.ContainerInterface // ContainerName -> ContainerInterfaces
 
@@ -31,7 +32,8 @@ type ProxmoxSource struct {
 }
 
 // Function that collects all data from Proxmox API and stores it in ProxmoxSource struct.
-func (ps *ProxmoxSource) Init() error {
+func (ps *ProxmoxSource) Init(ctx context.Context) error {
+	ps.Ctx = ctx
 	// Setup credentials for proxmox
 	credentials := proxmox.Credentials{
 		Username: ps.SourceConfig.Username,
@@ -51,9 +53,6 @@ func (ps *ProxmoxSource) Init() error {
 		proxmox.WithHTTPClient(HTTPClient),
 	)
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	initFuncs := []func(context.Context, *proxmox.Client) error{
 		ps.initCluster,
 		ps.initNodes,
@@ -61,6 +60,9 @@ func (ps *ProxmoxSource) Init() error {
 
 	for _, initFunc := range initFuncs {
 		startTime := time.Now()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := initFunc(ctx, client); err != nil {
 			return fmt.Errorf("proxmox initialization failure: %v", err)
 		}
@@ -77,7 +79,8 @@ func (ps *ProxmoxSource) Init() error {
 }
 
 // Function that syncs all collected data to Netbox inventory.
-func (ps *ProxmoxSource) Sync(nbi *inventory.NetboxInventory) error {
+func (ps *ProxmoxSource) Sync(ctx context.Context, nbi *inventory.NetboxInventory) error {
+	ps.Ctx = ctx
 	syncFunctions := []func(*inventory.NetboxInventory) error{
 		ps.syncCluster,
 		ps.syncNodes,
@@ -88,6 +91,9 @@ func (ps *ProxmoxSource) Sync(nbi *inventory.NetboxInventory) error {
 	for _, syncFunc := range syncFunctions {
 		startTime := time.Now()
 		funcName := utils.ExtractFunctionNameWithTrimPrefix(syncFunc, "sync")
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("sync %s: %w", funcName, err)
+		}
 		err := syncFunc(nbi)
 		if err != nil {
 			if ps.SourceConfig.ContinueOnError {
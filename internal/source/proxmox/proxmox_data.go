@@ -0,0 +1,55 @@
+package proxmox
+
+import (
+	"encoding/json"
+
+	"github.com/bl4ko/netbox-ssot/internal/source/common"
+	"github.com/luthermonson/go-proxmox"
+)
+
+// dataFields are fields of ProxmoxSource initialized in Init, which are dumped with --dump-source-data.
+var dataFields = []string{
+	"Nodes",
+	"NodeIfaces",
+	"Vms",
+	"VMIfaces",
+	"VMOsInfos",
+	"Containers",
+	"ContainerIfaces",
+}
+
+// cluster is proxmox.Cluster without its UnmarshalJSON,
+// which only accepts the cluster status of the Proxmox API.
+type cluster proxmox.Cluster
+
+// DumpData implements common.Dumper.
+func (ps *ProxmoxSource) DumpData() (map[string]json.RawMessage, error) {
+	data, err := common.MarshalFields(ps, dataFields, json.Marshal)
+	if err != nil {
+		return nil, err
+	}
+	if ps.Cluster != nil {
+		if data["Cluster"], err = json.Marshal((*cluster)(ps.Cluster)); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// LoadData implements common.Dumper.
+func (ps *ProxmoxSource) LoadData(data map[string]json.RawMessage) error {
+	if content, ok := data["Cluster"]; ok {
+		var loadedCluster cluster
+		if err := json.Unmarshal(content, &loadedCluster); err != nil {
+			return err
+		}
+		ps.Cluster = (*proxmox.Cluster)(&loadedCluster)
+	}
+	fields := make(map[string]json.RawMessage, len(data))
+	for field, content := range data {
+		if field != "Cluster" {
+			fields[field] = content
+		}
+	}
+	return common.UnmarshalFields(ps, fields, json.Unmarshal)
+}
@@ -27,6 +27,7 @@ func (ps *ProxmoxSource) initNodes(ctx context.Context, c *proxmox.Client) error
 	ps.NodeIfaces = make(map[string][]*proxmox.NodeNetwork, len(nodes))
 	ps.Vms = make(map[string][]*proxmox.VirtualMachine, len(nodes))
 	ps.VMIfaces = make(map[string][]*proxmox.AgentNetworkIface, 0)
+	ps.VMOsInfos = make(map[string]*proxmox.AgentOsInfo, 0)
 	ps.Containers = make(map[string][]*proxmox.Container, len(nodes))
 	ps.ContainerIfaces = make(map[string][]*proxmox.ContainerInterface, 0)
 
@@ -98,6 +99,13 @@ func (ps *ProxmoxSource) initNodeVMs(ctx context.Context, node *proxmox.Node) er
 		ifaces, _ := vm.AgentGetNetworkIFaces(ctx)
 		ps.VMIfaces[vm.Name] = make([]*proxmox.AgentNetworkIface, 0, len(ifaces))
 		ps.VMIfaces[vm.Name] = append(ps.VMIfaces[vm.Name], ifaces...)
+
+		// Load OS info from the guest agent
+		if vmconfig.Status == "running" {
+			if osInfo, _ := vmconfig.AgentOsInfo(ctx); osInfo != nil {
+				ps.VMOsInfos[vmconfig.Name] = osInfo
+			}
+		}
 	}
 	return nil
 }
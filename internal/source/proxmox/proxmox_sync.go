@@ -1,14 +1,17 @@
 package proxmox
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/expr"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
 	"github.com/bl4ko/netbox-ssot/internal/source/common"
 	"github.com/bl4ko/netbox-ssot/internal/utils"
 	"github.com/luthermonson/go-proxmox"
@@ -94,6 +97,12 @@ func (ps *ProxmoxSource) syncCluster(nbi *inventory.NetboxInventory) error {
 		ScopeID:   clusterScopeID,
 		Tenant:    clusterTenant,
 	}
+	clusterAttributes := expr.Attributes{}
+	clusterAttributes.Set(parser.RelationAttributeName, ps.Cluster.Name)
+	err = common.ApplyRelationRules(ps.Ctx, nbi, ps.SourceConfig.RelationRules, clusterStruct, clusterAttributes)
+	if err != nil {
+		return fmt.Errorf("match cluster to relation rules: %s", err)
+	}
 
 	nbCluster, err := nbi.AddCluster(ps.Ctx, clusterStruct)
 	if err != nil {
@@ -197,7 +206,7 @@ func (ps *ProxmoxSource) syncNodes(nbi *inventory.NetboxInventory) error {
 		deviceTags := ps.GetSourceTags()
 		deviceTags = append(deviceTags, nbi.IgnoreDeviceTypeTag)
 
-		nbHost, err := nbi.AddDevice(ps.Ctx, &objects.Device{
+		hostStruct := &objects.Device{
 			NetboxObject: objects.NetboxObject{
 				Tags: deviceTags,
 				CustomFields: map[string]interface{}{
@@ -214,7 +223,18 @@ func (ps *ProxmoxSource) syncNodes(nbi *inventory.NetboxInventory) error {
 			Tenant:     hostTenant,
 			Cluster:    ps.NetboxCluster,
 			DeviceType: hostDeviceType,
-		})
+		}
+		hostAttributes := expr.Attributes{}
+		hostAttributes.Set(parser.RelationAttributeName, node.Name)
+		hostAttributes.Set(parser.RelationAttributeCluster, ps.NetboxCluster.Name)
+		err = common.ApplyRelationRules(ps.Ctx, nbi, ps.SourceConfig.RelationRules, hostStruct, hostAttributes)
+		if err != nil {
+			return fmt.Errorf("match host to relation rules: %s", err)
+		}
+		nbHost, err := nbi.AddDevice(ps.Ctx, hostStruct)
+		if errors.Is(err, inventory.ErrDropped) {
+			continue
+		}
 		if err != nil {
 			return fmt.Errorf("add device: %s", err)
 		}
@@ -276,13 +296,19 @@ func (ps *ProxmoxSource) syncVMs(nbi *inventory.NetboxInventory) error {
 	errChan := make(chan error, len(ps.Vms))
 	// Use a WaitGroup to wait for all goroutines to complete
 	var wg sync.WaitGroup
+	// Networks of all VMs are added after the VMs with bulk requests
+	var vmNetworks common.VMNetworks
 
 	for nodeName, vms := range ps.Vms {
 		// Add domain name suffix if needed
 		if ps.SourceConfig.AssignDomainName != "" {
 			nodeName += ps.SourceConfig.AssignDomainName
 		}
-		nbHost := ps.NetboxNodes[nodeName]
+		nbHost, ok := ps.NetboxNodes[nodeName]
+		if !ok {
+			ps.Logger.Debugf(ps.Ctx, "Skipping VMs of node %s, which is not synced", nodeName)
+			continue
+		}
 
 		// Iterate over each VM and start a goroutine to sync it
 		for _, vm := range vms {
@@ -293,7 +319,7 @@ func (ps *ProxmoxSource) syncVMs(nbi *inventory.NetboxInventory) error {
 				defer wg.Done()
 				defer func() { <-guard }() // Release one spot in the semaphore
 
-				err := ps.syncVM(nbi, vm, nbHost)
+				err := ps.syncVM(nbi, vm, nbHost, &vmNetworks)
 				if err != nil {
 					errChan <- err
 				}
@@ -312,13 +338,18 @@ func (ps *ProxmoxSource) syncVMs(nbi *inventory.NetboxInventory) error {
 		}
 	}
 
+	if err := vmNetworks.Sync(ps.Ctx, nbi); err != nil {
+		return fmt.Errorf("failed to sync vms' networks: %s", err)
+	}
 	return nil
 }
 
+// syncVM syncs the VM to Netbox. Networks of the VM are collected into vmNetworks.
 func (ps *ProxmoxSource) syncVM( //nolint:gocyclo
 	nbi *inventory.NetboxInventory,
 	vm *proxmox.VirtualMachine,
 	nbHost *objects.Device,
+	vmNetworks *common.VMNetworks,
 ) error {
 	isTemplate := bool(vm.Template)
 
@@ -333,10 +364,7 @@ func (ps *ProxmoxSource) syncVM( //nolint:gocyclo
 	}
 
 	// Determine VM platform
-	var vmAgentOsInfo *proxmox.AgentOsInfo
-	if vm.Status == "running" {
-		vmAgentOsInfo, _ = vm.AgentOsInfo(ps.Ctx)
-	}
+	vmAgentOsInfo := ps.VMOsInfos[vm.Name]
 
 	platformName := "Unknown"
 	if vmAgentOsInfo != nil && vmAgentOsInfo.PrettyName != "" {
@@ -585,14 +613,34 @@ func (ps *ProxmoxSource) syncVM( //nolint:gocyclo
 		}
 	}
 
+	// Map VM attributes to custom fields
+	vmAttributes := map[string]interface{}{
+		"tags":        proxmoxTags(vm.Tags),
+		"template":    isTemplate,
+		"description": vm.VirtualMachineConfig.Description,
+		"onboot":      bool(vm.VirtualMachineConfig.OnBoot),
+	}
+	if vm.VirtualMachineConfig.OSType != nil {
+		vmAttributes["ostype"] = *vm.VirtualMachineConfig.OSType
+	}
+	vmCustomFields, err := common.MapCustomFields(
+		ps.Ctx,
+		nbi,
+		ps.SourceConfig.CustomFieldMappings,
+		constants.ContentTypeVirtualizationVirtualMachine,
+		vmAttributes,
+	)
+	if err != nil {
+		return fmt.Errorf("map vm custom fields: %s", err)
+	}
+	vmCustomFields[constants.CustomFieldSourceName] = ps.SourceConfig.Name
+	vmCustomFields[constants.CustomFieldSourceIDName] = fmt.Sprintf("%d", vm.VMID)
+
 	// Add VM to Netbox
 	vmStruct := &objects.VM{
 		NetboxObject: objects.NetboxObject{
-			Tags: newTags,
-			CustomFields: map[string]interface{}{
-				constants.CustomFieldSourceName:   ps.SourceConfig.Name,
-				constants.CustomFieldSourceIDName: fmt.Sprintf("%d", vm.VMID),
-			},
+			Tags:         newTags,
+			CustomFields: vmCustomFields,
 		},
 		Name:     vm.Name,
 		Cluster:  ps.NetboxCluster, // Default single proxmox cluster
@@ -606,17 +654,27 @@ func (ps *ProxmoxSource) syncVM( //nolint:gocyclo
 		Role:     vmRole,
 		// Disk:     vmTotalDiskSizeMiB,
 	}
+	err = common.ApplyRelationRules(
+		ps.Ctx,
+		nbi,
+		ps.SourceConfig.RelationRules,
+		vmStruct,
+		ps.vmRelationAttributes(vm.Name, nbHost),
+	)
+	if err != nil {
+		return fmt.Errorf("match vm to relation rules: %s", err)
+	}
 
 	nbVM, err := nbi.AddVM(ps.Ctx, vmStruct)
+	if errors.Is(err, inventory.ErrDropped) {
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to add vm: %s %s", vm.Name, err)
 	}
 
-	// Sync VM networks
-	err = ps.syncVMNetworks(nbi, nbVM)
-	if err != nil {
-		return fmt.Errorf("failed to sync vm's %+v networks: %s", nbVM, err)
-	}
+	// Collect VM networks
+	ps.collectVMNetworks(nbi, vm.Name, nbVM, vmNetworks)
 
 	// Sync VM disks
 	if !ps.SourceConfig.IgnoreVMDisks {
@@ -629,10 +687,26 @@ func (ps *ProxmoxSource) syncVM( //nolint:gocyclo
 	return nil
 }
 
-func (ps *ProxmoxSource) syncVMNetworks(nbi *inventory.NetboxInventory, nbVM *objects.VM) error {
-	vmIPv4Addresses := make([]*objects.IPAddress, 0)
-	vmIPv6Addresses := make([]*objects.IPAddress, 0)
-	for _, vmNetwork := range ps.VMIfaces[nbVM.Name] {
+// vmRelationAttributes returns the relation rule attributes of a VM or container
+// named vmName, which is running on host.
+func (ps *ProxmoxSource) vmRelationAttributes(vmName string, host *objects.Device) expr.Attributes {
+	attributes := expr.Attributes{}
+	attributes.Set(parser.RelationAttributeName, vmName)
+	attributes.Set(parser.RelationAttributeCluster, ps.NetboxCluster.Name)
+	attributes.Set(parser.RelationAttributeHost, host.Name)
+	return attributes
+}
+
+// collectVMNetworks collects networks of the VM, which are collected under vmName,
+// into vmNetworks.
+func (ps *ProxmoxSource) collectVMNetworks(
+	nbi *inventory.NetboxInventory,
+	vmName string,
+	nbVM *objects.VM,
+	vmNetworks *common.VMNetworks,
+) {
+	networks := make([]*common.VMNetwork, 0)
+	for _, vmNetwork := range ps.VMIfaces[vmName] {
 		if utils.FilterInterfaceName(vmNetwork.Name, ps.SourceConfig.InterfaceFilter) {
 			ps.Logger.Debugf(
 				ps.Ctx,
@@ -642,121 +716,78 @@ func (ps *ProxmoxSource) syncVMNetworks(nbi *inventory.NetboxInventory, nbVM *ob
 			)
 			continue
 		}
-		vmInterfaceStruct := &objects.VMInterface{
-			NetboxObject: objects.NetboxObject{
-				Tags: ps.GetSourceTags(),
+		network := &common.VMNetwork{
+			Interface: &objects.VMInterface{
+				NetboxObject: objects.NetboxObject{
+					Tags: ps.GetSourceTags(),
+				},
+				Name: vmNetwork.Name,
+				VM:   nbVM,
 			},
-			Name: vmNetwork.Name,
-			VM:   nbVM,
-		}
-		nbVMIface, err := nbi.AddVMInterface(ps.Ctx, vmInterfaceStruct)
-		if err != nil {
-			return fmt.Errorf("add vm interface %+v: %s", vmInterfaceStruct, err)
-		}
-		vmIfaceMAC := strings.ToUpper(vmNetwork.HardwareAddress)
-		if vmIfaceMAC != "" {
-			nbMACAddress, err := common.CreateMACAddressForObjectType(
-				ps.Ctx,
-				nbi,
-				vmIfaceMAC,
-				nbVMIface,
-			)
-			if err != nil {
-				return fmt.Errorf("create mac address for object type: %s", err)
-			}
-			if err = common.SetPrimaryMACForInterface(ps.Ctx, nbi, nbVMIface, nbMACAddress); err != nil {
-				return fmt.Errorf("set primary mac for interface %+v: %s", nbVMIface, err)
-			}
+			MACAddress: strings.ToUpper(vmNetwork.HardwareAddress),
 		}
 
 		for _, ipAddress := range vmNetwork.IPAddresses {
-			if utils.IsPermittedIPAddress(
+			if !utils.IsPermittedIPAddress(
 				ipAddress.IPAddress,
 				ps.SourceConfig.PermittedSubnets,
 				ps.SourceConfig.IgnoredSubnets,
 			) {
-				ipAddress.IPAddress = utils.RemoveZoneIndexFromIPAddress(ipAddress.IPAddress)
-				// VRF
-				ipVRF, err := common.MatchIPToVRF(ps.Ctx, nbi, ipAddress.IPAddress, ps.SourceConfig.IPVrfRelations)
-				if err != nil {
-					ps.Logger.Warningf(ps.Ctx, "match ip to vrf for %s: %s", ipAddress.IPAddress, err)
-				}
-				ipAddressStruct := &objects.IPAddress{
-					NetboxObject: objects.NetboxObject{
-						Tags: ps.GetSourceTags(),
-						CustomFields: map[string]interface{}{
-							constants.CustomFieldArpEntryName: false,
-						},
-					},
-					Address:            fmt.Sprintf("%s/%d", ipAddress.IPAddress, ipAddress.Prefix),
-					DNSName:            utils.ReverseLookup(ipAddress.IPAddress),
-					Tenant:             nbVM.Tenant,
-					AssignedObjectType: constants.ContentTypeVirtualizationVMInterface,
-					AssignedObjectID:   nbVMIface.ID,
-					Status:             &objects.IPAddressStatusActive, //TODO: this is hardcoded
-					VRF:                ipVRF,
-				}
-				nbIPAddress, err := nbi.AddIPAddress(ps.Ctx, ipAddressStruct)
-				if err != nil {
-					ps.Logger.Warningf(
-						ps.Ctx,
-						"failed adding ip address %s with: %s",
-						ipAddressStruct,
-						err,
-					)
-					continue
-				}
-				switch ipAddress.IPAddressType {
-				case "ipv4":
-					vmIPv4Addresses = append(vmIPv4Addresses, nbIPAddress)
-				case "ipv6":
-					vmIPv6Addresses = append(vmIPv6Addresses, nbIPAddress)
-				default:
-					ps.Logger.Warningf(
-						ps.Ctx,
-						"wrong IP type: %s for ip %s",
-						ipAddress.IPAddressType,
-						ipAddress.IPAddress,
-					)
-				}
-				prefix, mask, err := utils.GetPrefixAndMaskFromIPAddress(nbIPAddress.Address)
-				if err != nil {
-					ps.Logger.Warningf(
-						ps.Ctx,
-						"failed extracting prefix from ip address %s with: %s",
-						nbIPAddress.Address,
-						err,
-					)
-				} else if (ipAddress.IPAddressType == "ipv4" && mask != constants.MaxIPv4MaskBits) ||
-					(ipAddress.IPAddressType == "ipv6" && mask != constants.MaxIPv6MaskBits) {
-					_, err = nbi.AddPrefix(ps.Ctx, &objects.Prefix{
-						Prefix: prefix,
-						VRF:    ipVRF,
-					})
-					if err != nil {
-						ps.Logger.Errorf(ps.Ctx, "adding prefix: %s", err)
-					}
-				}
+				continue
+			}
+			ipAddress.IPAddress = utils.RemoveZoneIndexFromIPAddress(ipAddress.IPAddress)
+			ipAddressStruct := ps.vmIPAddress(
+				nbi,
+				fmt.Sprintf("%s/%d", ipAddress.IPAddress, ipAddress.Prefix),
+				ipAddress.IPAddress,
+				nbVM,
+			)
+			switch ipAddress.IPAddressType {
+			case "ipv4":
+				network.IPv4Addresses = append(network.IPv4Addresses, ipAddressStruct)
+			case "ipv6":
+				network.IPv6Addresses = append(network.IPv6Addresses, ipAddressStruct)
+			default:
+				ps.Logger.Warningf(
+					ps.Ctx,
+					"wrong IP type: %s for ip %s",
+					ipAddress.IPAddressType,
+					ipAddress.IPAddress,
+				)
 			}
 		}
+		networks = append(networks, network)
 	}
-	// From all IPv4 addresses and IPv6 addresses determine primary ips
-	if len(vmIPv4Addresses) > 0 || len(vmIPv6Addresses) > 0 {
-		nbVMCopy := *nbVM
-		if len(vmIPv4Addresses) > 0 {
-			// TODO: add criteria for primary IPv4
-			nbVMCopy.PrimaryIPv4 = vmIPv4Addresses[0]
-		}
-		if len(vmIPv6Addresses) > 0 {
-			// TODO add criteria for primary IPv6
-			nbVMCopy.PrimaryIPv6 = vmIPv6Addresses[0]
-		}
-		_, err := nbi.AddVM(ps.Ctx, &nbVMCopy)
-		if err != nil {
-			return fmt.Errorf("updating vm primary ip: %s", err)
-		}
+	vmNetworks.Add(nbVM, networks, nil)
+}
+
+// vmIPAddress returns IP address of a VM interface with the given address. The IP address
+// is assigned to the interface, when the interface is added to netbox.
+func (ps *ProxmoxSource) vmIPAddress(
+	nbi *inventory.NetboxInventory,
+	address string,
+	ipAddress string,
+	nbVM *objects.VM,
+) *objects.IPAddress {
+	// VRF
+	ipVRF, err := common.MatchIPToVRF(ps.Ctx, nbi, ipAddress, ps.SourceConfig.IPVrfRelations)
+	if err != nil {
+		ps.Logger.Warningf(ps.Ctx, "match ip to vrf for %s: %s", ipAddress, err)
+	}
+	return &objects.IPAddress{
+		NetboxObject: objects.NetboxObject{
+			Tags: ps.GetSourceTags(),
+			CustomFields: map[string]interface{}{
+				constants.CustomFieldArpEntryName: false,
+			},
+		},
+		Address:            address,
+		DNSName:            utils.ReverseLookup(ipAddress),
+		Tenant:             nbVM.Tenant,
+		AssignedObjectType: constants.ContentTypeVirtualizationVMInterface,
+		Status:             &objects.IPAddressStatusActive, //TODO: this is hardcoded
+		VRF:                ipVRF,
 	}
-	return nil
 }
 
 // syncVMDisks syncs VM's disks to Netbox.
@@ -783,13 +814,19 @@ func (ps *ProxmoxSource) syncContainers(nbi *inventory.NetboxInventory) error {
 		if err != nil {
 			return fmt.Errorf("create container role: %s", err)
 		}
+		// Networks of all containers are added after the containers with bulk requests
+		var containerNetworks common.VMNetworks
 		for nodeName, containers := range ps.Containers {
 			// Add domain name suffix if needed
 			if ps.SourceConfig.AssignDomainName != "" {
 				nodeName += ps.SourceConfig.AssignDomainName
 			}
 
-			nbHost := ps.NetboxNodes[nodeName]
+			nbHost, ok := ps.NetboxNodes[nodeName]
+			if !ok {
+				ps.Logger.Debugf(ps.Ctx, "Skipping containers of node %s, which is not synced", nodeName)
+				continue
+			}
 			for _, container := range containers {
 				// Determine Container status
 				containerStatus := &objects.VMStatusActive
@@ -828,12 +865,22 @@ func (ps *ProxmoxSource) syncContainers(nbi *inventory.NetboxInventory) error {
 					}
 				}
 
-				nbContainer, err := nbi.AddVM(ps.Ctx, &objects.VM{
+				containerCustomFields, err := common.MapCustomFields(
+					ps.Ctx,
+					nbi,
+					ps.SourceConfig.CustomFieldMappings,
+					constants.ContentTypeVirtualizationVirtualMachine,
+					map[string]interface{}{"tags": proxmoxTags(container.Tags)},
+				)
+				if err != nil {
+					return fmt.Errorf("map container custom fields: %s", err)
+				}
+				containerCustomFields[constants.CustomFieldSourceIDName] = fmt.Sprintf("%d", container.VMID)
+
+				containerStruct := &objects.VM{
 					NetboxObject: objects.NetboxObject{
-						Tags: newTags,
-						CustomFields: map[string]interface{}{
-							constants.CustomFieldSourceIDName: fmt.Sprintf("%d", container.VMID),
-						},
+						Tags:         newTags,
+						CustomFields: containerCustomFields,
 					},
 					Host:    nbHost,
 					Role:    containerRole,
@@ -845,28 +892,45 @@ func (ps *ProxmoxSource) syncContainers(nbi *inventory.NetboxInventory) error {
 					Site:    nbHost.Site,
 					Name:    container.Name,
 					Status:  containerStatus,
-				})
+				}
+				err = common.ApplyRelationRules(
+					ps.Ctx,
+					nbi,
+					ps.SourceConfig.RelationRules,
+					containerStruct,
+					ps.vmRelationAttributes(container.Name, nbHost),
+				)
 				if err != nil {
-					return fmt.Errorf("new vm: %s", err)
+					return fmt.Errorf("match container to relation rules: %s", err)
+				}
+				nbContainer, err := nbi.AddVM(ps.Ctx, containerStruct)
+				if errors.Is(err, inventory.ErrDropped) {
+					continue
 				}
-
-				err = ps.syncContainerNetworks(nbi, nbContainer)
 				if err != nil {
-					return fmt.Errorf("sync container networks: %s", err)
+					return fmt.Errorf("new vm: %s", err)
 				}
+
+				ps.collectContainerNetworks(nbi, container.Name, nbContainer, &containerNetworks)
 			}
 		}
+		if err := containerNetworks.Sync(ps.Ctx, nbi); err != nil {
+			return fmt.Errorf("sync container networks: %s", err)
+		}
 	}
 	return nil
 }
 
-func (ps *ProxmoxSource) syncContainerNetworks(
+// collectContainerNetworks collects networks of the container, which are collected
+// under containerName, into containerNetworks.
+func (ps *ProxmoxSource) collectContainerNetworks(
 	nbi *inventory.NetboxInventory,
+	containerName string,
 	nbContainer *objects.VM,
-) error {
-	vmIPv4Addresses := make([]*objects.IPAddress, 0)
-	vmIPv6Addresses := make([]*objects.IPAddress, 0)
-	for _, containerIface := range ps.ContainerIfaces[nbContainer.Name] {
+	containerNetworks *common.VMNetworks,
+) {
+	networks := make([]*common.VMNetwork, 0)
+	for _, containerIface := range ps.ContainerIfaces[containerName] {
 		if utils.FilterInterfaceName(containerIface.Name, ps.SourceConfig.InterfaceFilter) {
 			ps.Logger.Debugf(
 				ps.Ctx,
@@ -876,133 +940,43 @@ func (ps *ProxmoxSource) syncContainerNetworks(
 			)
 			continue
 		}
-		vmIfaceStruct := &objects.VMInterface{
-			NetboxObject: objects.NetboxObject{
-				Tags: ps.GetSourceTags(),
+		network := &common.VMNetwork{
+			Interface: &objects.VMInterface{
+				NetboxObject: objects.NetboxObject{
+					Tags: ps.GetSourceTags(),
+				},
+				Name: containerIface.Name,
+				VM:   nbContainer,
 			},
-			Name: containerIface.Name,
-			VM:   nbContainer,
-		}
-		nbVMIface, err := nbi.AddVMInterface(ps.Ctx, vmIfaceStruct)
-		if err != nil {
-			return fmt.Errorf("add vm interface: %s", err)
-		}
-		vmIfaceMAC := strings.ToUpper(containerIface.HWAddr)
-		if vmIfaceMAC != "" {
-			nbMACAddress, err := common.CreateMACAddressForObjectType(
-				ps.Ctx,
-				nbi,
-				vmIfaceMAC,
-				nbVMIface,
-			)
-			if err != nil {
-				return fmt.Errorf("create mac address for container iface: %s", err)
-			}
-			if err = common.SetPrimaryMACForInterface(ps.Ctx, nbi, nbVMIface, nbMACAddress); err != nil {
-				return fmt.Errorf("set primary mac for container iface %+v: %s", nbVMIface, err)
-			}
+			MACAddress: strings.ToUpper(containerIface.HWAddr),
 		}
 
 		// Check if IPv4 address is present
-		if utils.IsPermittedIPAddress(
+		if containerIface.Inet != "" && utils.IsPermittedIPAddress(
 			containerIface.Inet,
 			ps.SourceConfig.PermittedSubnets,
 			ps.SourceConfig.IgnoredSubnets,
 		) {
-			// Check if IPv4 address is present
-			if containerIface.Inet != "" {
-				// VRF
-				ipVRF, err := common.MatchIPToVRF(ps.Ctx, nbi, containerIface.Inet, ps.SourceConfig.IPVrfRelations)
-				if err != nil {
-					ps.Logger.Warningf(ps.Ctx, "match ip to vrf for %s: %s", containerIface.Inet, err)
-				}
-				nbIPAddress, err := nbi.AddIPAddress(ps.Ctx, &objects.IPAddress{
-					NetboxObject: objects.NetboxObject{
-						Tags: ps.GetSourceTags(),
-						CustomFields: map[string]interface{}{
-							constants.CustomFieldArpEntryName: false,
-						},
-					},
-					Address:            containerIface.Inet,
-					DNSName:            utils.ReverseLookup(containerIface.Inet),
-					Tenant:             nbContainer.Tenant,
-					AssignedObjectType: constants.ContentTypeVirtualizationVMInterface,
-					AssignedObjectID:   nbVMIface.ID,
-					Status:             &objects.IPAddressStatusActive, //TODO: this is hardcoded
-					VRF:                ipVRF,
-				})
-				if err != nil {
-					ps.Logger.Warningf(ps.Ctx, "add ip address: %s", err)
-				} else {
-					vmIPv4Addresses = append(vmIPv4Addresses, nbIPAddress)
-					prefix, mask, err := utils.GetPrefixAndMaskFromIPAddress(nbIPAddress.Address)
-					if err != nil {
-						ps.Logger.Warningf(ps.Ctx, "extract prefix from ip address: %s", err)
-					} else if mask != constants.MaxIPv4MaskBits {
-						_, err = nbi.AddPrefix(ps.Ctx, &objects.Prefix{
-							Prefix: prefix,
-							VRF:    ipVRF,
-						})
-						if err != nil {
-							ps.Logger.Errorf(ps.Ctx, "adding prefix: %s", err)
-						}
-					}
-				}
-			}
+			network.IPv4Addresses = append(
+				network.IPv4Addresses,
+				ps.vmIPAddress(nbi, containerIface.Inet, containerIface.Inet, nbContainer),
+			)
 		}
 		// Check if IPv6 address is present
-		if utils.IsPermittedIPAddress(
+		if containerIface.Inet6 != "" && utils.IsPermittedIPAddress(
 			containerIface.Inet6,
 			ps.SourceConfig.PermittedSubnets,
 			ps.SourceConfig.IgnoredSubnets,
 		) {
-			if containerIface.Inet6 != "" {
-				containerIface.Inet6 = utils.RemoveZoneIndexFromIPAddress(containerIface.Inet6)
-				// VRF
-				ipVRF, err := common.MatchIPToVRF(ps.Ctx, nbi, containerIface.Inet6, ps.SourceConfig.IPVrfRelations)
-				if err != nil {
-					ps.Logger.Warningf(ps.Ctx, "match ip to vrf for %s: %s", containerIface.Inet6, err)
-				}
-				nbIPAddress, err := nbi.AddIPAddress(ps.Ctx, &objects.IPAddress{
-					NetboxObject: objects.NetboxObject{
-						Tags: ps.GetSourceTags(),
-						CustomFields: map[string]interface{}{
-							constants.CustomFieldArpEntryName: false,
-						},
-					},
-					Address:            containerIface.Inet6,
-					DNSName:            utils.ReverseLookup(containerIface.Inet6),
-					Tenant:             nbContainer.Tenant,
-					AssignedObjectType: constants.ContentTypeVirtualizationVMInterface,
-					AssignedObjectID:   nbVMIface.ID,
-					Status:             &objects.IPAddressStatusActive, //TODO: this is hardcoded
-					VRF:                ipVRF,
-				})
-				if err != nil {
-					ps.Logger.Warningf(ps.Ctx, "add ipv6 address: %s", err)
-				} else {
-					vmIPv6Addresses = append(vmIPv6Addresses, nbIPAddress)
-				}
-			}
-		}
-	}
-	// From all IPv4 addresses and IPv6 addresses determine primary ips
-	if len(vmIPv4Addresses) > 0 || len(vmIPv6Addresses) > 0 {
-		nbContainerCopy := *nbContainer
-		if len(vmIPv4Addresses) > 0 {
-			// TODO: add criteria for primary IPv4
-			nbContainerCopy.PrimaryIPv4 = vmIPv4Addresses[0]
-		}
-		if len(vmIPv6Addresses) > 0 {
-			// TODO add criteria for primary IPv6
-			nbContainerCopy.PrimaryIPv6 = vmIPv6Addresses[0]
-		}
-		_, err := nbi.AddVM(ps.Ctx, &nbContainerCopy)
-		if err != nil {
-			return fmt.Errorf("updating vm primary ip: %s", err)
+			containerIface.Inet6 = utils.RemoveZoneIndexFromIPAddress(containerIface.Inet6)
+			network.IPv6Addresses = append(
+				network.IPv6Addresses,
+				ps.vmIPAddress(nbi, containerIface.Inet6, containerIface.Inet6, nbContainer),
+			)
 		}
+		networks = append(networks, network)
 	}
-	return nil
+	containerNetworks.Add(nbContainer, networks, nil)
 }
 
 // proxmoxOSTypeToPlatformName maps a Proxmox VM OSType identifier to a
@@ -1018,3 +992,10 @@ func proxmoxOSTypeToPlatformName(osType string) string {
 		return ""
 	}
 }
+
+// proxmoxTags splits tags of a Proxmox VM or container, which are separated by semicolons.
+func proxmoxTags(tags string) []string {
+	return strings.FieldsFunc(tags, func(r rune) bool {
+		return r == ';' || r == ' '
+	})
+}
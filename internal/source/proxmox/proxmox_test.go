@@ -1,6 +1,9 @@
 package proxmox
 
-import "testing"
+import (
+	"slices"
+	"testing"
+)
 
 func TestProxmoxOSTypeToPlatformName(t *testing.T) {
 	tests := []struct {
@@ -21,3 +24,22 @@ func TestProxmoxOSTypeToPlatformName(t *testing.T) {
 		})
 	}
 }
+
+func TestProxmoxTags(t *testing.T) {
+	tests := []struct {
+		name string
+		tags string
+		want []string
+	}{
+		{name: "several tags", tags: "web;prod", want: []string{"web", "prod"}},
+		{name: "blank tags", tags: " ", want: []string{}},
+		{name: "empty tags", tags: "", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := proxmoxTags(tt.tags); !slices.Equal(got, tt.want) {
+				t.Errorf("proxmoxTags(%q) = %q, want %q", tt.tags, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Package replay implements the replay source, which syncs source data
+// dumped with --dump-source-data, without connecting to the dumped source.
+package replay
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+	"github.com/bl4ko/netbox-ssot/internal/source/common"
+)
+
+// Source replays data of a source from a source data file. The data
+// is loaded into a source of the dumped type, which syncs it.
+type Source struct {
+	common.Config
+	// NewSource creates a source for the given config.
+	NewSource func(ctx context.Context, config *parser.SourceConfig) (common.Source, error)
+
+	// Replayed is the source of the dumped type with the loaded data. Initialized in Init.
+	Replayed common.Source
+}
+
+// Init loads the source data file.
+func (rs *Source) Init(ctx context.Context) error {
+	rs.Ctx = ctx
+	sourceData, err := common.ReadSourceData(rs.SourceConfig.DataFile)
+	if err != nil {
+		return fmt.Errorf("read source data: %s", err)
+	}
+	if sourceData.Type == constants.Replay {
+		return fmt.Errorf("%s: source data of type %s can't be replayed", rs.SourceConfig.DataFile, sourceData.Type)
+	}
+	// Source of the dumped type is configured by the replay source, so
+	// options (e.g. relations) can be changed between replays.
+	sourceConfig := *rs.SourceConfig
+	sourceConfig.Type = sourceData.Type
+	replayed, err := rs.NewSource(ctx, &sourceConfig)
+	if err != nil {
+		return fmt.Errorf("create %s source: %s", sourceData.Type, err)
+	}
+	dumper, ok := replayed.(common.Dumper)
+	if !ok {
+		return fmt.Errorf("source type %s doesn't support replay", sourceData.Type)
+	}
+	if err := dumper.LoadData(sourceData.Data); err != nil {
+		return fmt.Errorf("load data of %s source %s: %s", sourceData.Type, sourceData.Name, err)
+	}
+	rs.Logger.Infof(
+		ctx,
+		"Replaying data of %s source %s dumped at %s",
+		sourceData.Type,
+		sourceData.Name,
+		sourceData.DumpedAt.Format(time.RFC3339),
+	)
+	rs.Replayed = replayed
+	return nil
+}
+
+// Sync syncs the loaded data with Sync of the dumped source type.
+func (rs *Source) Sync(ctx context.Context, nbi *inventory.NetboxInventory) error {
+	rs.Ctx = ctx
+	return rs.Replayed.Sync(ctx, nbi)
+}
@@ -0,0 +1,128 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/logger"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+	"github.com/bl4ko/netbox-ssot/internal/source/common"
+)
+
+type testSource struct {
+	Names  []string
+	synced bool
+}
+
+func (ts *testSource) Init(_ context.Context) error {
+	return errors.New("init of replayed source must not be called")
+}
+
+func (ts *testSource) Sync(_ context.Context, _ *inventory.NetboxInventory) error {
+	ts.synced = true
+	return nil
+}
+
+func (ts *testSource) DumpData() (map[string]json.RawMessage, error) {
+	return common.MarshalFields(ts, []string{"Names"}, json.Marshal)
+}
+
+func (ts *testSource) LoadData(data map[string]json.RawMessage) error {
+	return common.UnmarshalFields(ts, data, json.Unmarshal)
+}
+
+// testSourceWithoutData is a source, which doesn't implement common.Dumper.
+type testSourceWithoutData struct{}
+
+func (ts *testSourceWithoutData) Init(_ context.Context) error {
+	return nil
+}
+
+func (ts *testSourceWithoutData) Sync(_ context.Context, _ *inventory.NetboxInventory) error {
+	return nil
+}
+
+func TestSource(t *testing.T) {
+	ctx := context.WithValue(context.Background(), constants.CtxSourceKey, "test")
+	testLogger, err := logger.New("", 1)
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+	dir := t.TempDir()
+	dataFile, err := common.WriteSourceData(dir, "vmware", constants.Vmware, &testSource{Names: []string{"vm1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayDataFile, err := common.WriteSourceData(dir, "replay", constants.Replay, &testSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		dataFile  string
+		newSource func() common.Source
+		wantErr   bool
+	}{
+		{
+			name:      "replay",
+			dataFile:  dataFile,
+			newSource: func() common.Source { return &testSource{} },
+		},
+		{
+			name:      "missing data file",
+			dataFile:  filepath.Join(dir, "missing.json"),
+			newSource: func() common.Source { return &testSource{} },
+			wantErr:   true,
+		},
+		{
+			name:      "replay of replay source",
+			dataFile:  replayDataFile,
+			newSource: func() common.Source { return &testSource{} },
+			wantErr:   true,
+		},
+		{
+			name:      "source without data",
+			dataFile:  dataFile,
+			newSource: func() common.Source { return &testSourceWithoutData{} },
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var createdType constants.SourceType
+			rs := &Source{
+				Config: common.Config{
+					Logger:       testLogger,
+					SourceConfig: &parser.SourceConfig{Name: "replay", Type: constants.Replay, DataFile: tt.dataFile},
+				},
+				NewSource: func(_ context.Context, config *parser.SourceConfig) (common.Source, error) {
+					createdType = config.Type
+					return tt.newSource(), nil
+				},
+			}
+			err := rs.Init(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Init() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if createdType != constants.Vmware {
+				t.Errorf("created source of type %s, want %s", createdType, constants.Vmware)
+			}
+			if err := rs.Sync(ctx, nil); err != nil {
+				t.Fatalf("Sync() error = %v", err)
+			}
+			replayed, ok := rs.Replayed.(*testSource)
+			if !ok || !replayed.synced || len(replayed.Names) != 1 || replayed.Names[0] != "vm1" {
+				t.Errorf("replayed source = %+v, want synced source with loaded names", rs.Replayed)
+			}
+		})
+	}
+}
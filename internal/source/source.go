@@ -21,6 +21,7 @@ import (
 	"github.com/bl4ko/netbox-ssot/internal/source/ovirt"
 	"github.com/bl4ko/netbox-ssot/internal/source/paloalto"
 	"github.com/bl4ko/netbox-ssot/internal/source/proxmox"
+	"github.com/bl4ko/netbox-ssot/internal/source/replay"
 	"github.com/bl4ko/netbox-ssot/internal/source/vmware"
 	"github.com/bl4ko/netbox-ssot/internal/utils"
 )
@@ -32,6 +33,21 @@ func NewSource(
 	logger *logger.Logger,
 	netboxInventory *inventory.NetboxInventory,
 ) (common.Source, error) {
+	// Replay source creates a source of the dumped type, once the data is read in Init
+	if config.Type == constants.Replay {
+		return &replay.Source{
+			Config: common.Config{
+				Logger:       logger,
+				SourceConfig: config,
+				Ctx:          ctx,
+				CAFile:       config.CAFile,
+			},
+			NewSource: func(ctx context.Context, config *parser.SourceConfig) (common.Source, error) {
+				return NewSource(ctx, config, logger, netboxInventory)
+			},
+		}, nil
+	}
+
 	// First we create default tags for the source
 	sourceNameTag, err := netboxInventory.AddTag(ctx, &objects.Tag{
 		Name:  config.Tag,
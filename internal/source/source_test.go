@@ -2,12 +2,14 @@ package source
 
 import (
 	"context"
+	"reflect"
 	"testing"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
 	"github.com/bl4ko/netbox-ssot/internal/netbox/service"
 	"github.com/bl4ko/netbox-ssot/internal/parser"
+	"github.com/bl4ko/netbox-ssot/internal/source/common"
 	"github.com/bl4ko/netbox-ssot/internal/source/dnac"
 	"github.com/bl4ko/netbox-ssot/internal/source/fmc"
 	"github.com/bl4ko/netbox-ssot/internal/source/fortigate"
@@ -15,6 +17,7 @@ import (
 	"github.com/bl4ko/netbox-ssot/internal/source/ovirt"
 	"github.com/bl4ko/netbox-ssot/internal/source/paloalto"
 	"github.com/bl4ko/netbox-ssot/internal/source/proxmox"
+	"github.com/bl4ko/netbox-ssot/internal/source/replay"
 	"github.com/bl4ko/netbox-ssot/internal/source/vmware"
 )
 
@@ -42,6 +45,7 @@ func TestNewSource_AllTypes(t *testing.T) {
 		{name: "fortigate", sourceType: constants.Fortigate},
 		{name: "fmc", sourceType: constants.FMC},
 		{name: "ios-xe", sourceType: constants.IOSXE},
+		{name: "replay", sourceType: constants.Replay},
 	}
 
 	for _, tt := range tests {
@@ -93,6 +97,10 @@ func TestNewSource_AllTypes(t *testing.T) {
 				if _, ok := src.(*iosxe.IOSXESource); !ok {
 					t.Errorf("expected *iosxe.IOSXESource, got %T", src)
 				}
+			case constants.Replay:
+				if _, ok := src.(*replay.Source); !ok {
+					t.Errorf("expected *replay.Source, got %T", src)
+				}
 			}
 		})
 	}
@@ -114,3 +122,65 @@ func TestNewSource_UnsupportedType(t *testing.T) {
 		t.Fatal("expected error for unsupported source type, got nil")
 	}
 }
+
+func TestNewSource_DumpAndReplay(t *testing.T) {
+	setupMockServer(t)
+	ctx := context.WithValue(context.Background(), constants.CtxSourceKey, "test")
+	nbi := inventory.MockInventory
+	dir := t.TempDir()
+
+	sourceTypes := []constants.SourceType{
+		constants.Ovirt,
+		constants.Vmware,
+		constants.Dnac,
+		constants.Proxmox,
+		constants.PaloAlto,
+		constants.Fortigate,
+		constants.FMC,
+		constants.IOSXE,
+		constants.F5,
+		constants.HetznerCloud,
+		constants.OpenStack,
+	}
+	for _, sourceType := range sourceTypes {
+		t.Run(string(sourceType), func(t *testing.T) {
+			config := &parser.SourceConfig{
+				Name:     "test-" + string(sourceType),
+				Type:     sourceType,
+				Tag:      "test-tag",
+				TagColor: "00add8",
+			}
+			src, err := NewSource(ctx, config, nbi.Logger, nbi)
+			if err != nil {
+				t.Fatalf("NewSource(%s) returned error: %v", sourceType, err)
+			}
+			dumper, ok := src.(common.Dumper)
+			if !ok {
+				t.Fatalf("%T doesn't implement common.Dumper", src)
+			}
+			dataFile, err := common.WriteSourceData(dir, config.Name, sourceType, dumper)
+			if err != nil {
+				t.Fatalf("WriteSourceData() error = %v", err)
+			}
+
+			replayConfig := &parser.SourceConfig{
+				Name:     "replay-" + string(sourceType),
+				Type:     constants.Replay,
+				Tag:      "test-tag",
+				TagColor: "00add8",
+				DataFile: dataFile,
+			}
+			replaySrc, err := NewSource(ctx, replayConfig, nbi.Logger, nbi)
+			if err != nil {
+				t.Fatalf("NewSource(%s) returned error: %v", constants.Replay, err)
+			}
+			if err := replaySrc.Init(ctx); err != nil {
+				t.Fatalf("Init() of replay source error = %v", err)
+			}
+			replayed := replaySrc.(*replay.Source).Replayed
+			if reflect.TypeOf(replayed) != reflect.TypeOf(src) {
+				t.Errorf("replayed source is %T, want %T", replayed, src)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
@@ -19,6 +20,7 @@ import (
 	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
 )
 
 // VmwareSource represents an vsphere source.
@@ -29,6 +31,7 @@ type VmwareSource struct {
 	// Vmware API data initialized in init functions
 	Disks       map[string]mo.Datastore
 	DataCenters map[string]mo.Datacenter
+	Folders     map[string]mo.Folder
 	Clusters    map[string]mo.ClusterComputeResource
 	Hosts       map[string]mo.HostSystem
 	Vms         map[string]mo.VirtualMachine
@@ -44,6 +47,14 @@ type VmwareSource struct {
 	// Object2Tags is a map of object ids to their tags
 	Object2Tags   map[string][]*tags.Tag
 	Object2NBTags map[string][]*objects.Tag // Created in sync function
+	// NetboxHosts is a map of host keys to their devices in Netbox. Hosts, which are
+	// dropped by a transform rule, map to nil. Created in sync function.
+	NetboxHosts map[string]*objects.Device
+
+	// changes are kinds of updates of hosts and VMs collected by Watch,
+	// which weren't synced yet. They are guarded by changesLock.
+	changes     map[types.ManagedObjectReference]types.ObjectUpdateKind
+	changesLock sync.Mutex
 }
 
 type NetworkData struct {
@@ -68,65 +79,31 @@ type DistributedPortgroupData struct {
 }
 
 type HostVirtualSwitchData struct {
-	mtu   int
-	pnics []string
+	MTU   int
+	Pnics []string
 }
 
 type HostProxySwitchData struct {
-	name  string
-	mtu   int
-	pnics []string
+	Name  string
+	MTU   int
+	Pnics []string
 }
 
 type HostPortgroupData struct {
-	vlanID  int
-	vswitch string
-	nics    []string
+	VlanID  int
+	VSwitch string
+	Nics    []string
 }
 
-func (vc *VmwareSource) Init() error {
+func (vc *VmwareSource) Init(ctx context.Context) error {
+	vc.Ctx = ctx
+	// Changes collected by Watch until now are synced with all other objects
+	vc.takeChanges()
 	// Initialize the connection
 	vc.Logger.Debug(vc.Ctx, "vmware source ", vc.SourceConfig.Name)
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// Correctly handle backslashes in username and password
-	escapedUsername := url.PathEscape(vc.SourceConfig.Username)
-	escapedPassword := url.PathEscape(vc.SourceConfig.Password)
-
-	vcURL := fmt.Sprintf(
-		"%s://%s:%s@%s:%d/sdk",
-		vc.SourceConfig.HTTPScheme,
-		escapedUsername,
-		escapedPassword,
-		vc.SourceConfig.Hostname,
-		vc.SourceConfig.Port,
-	)
-
-	url, err := url.Parse(vcURL)
-	if err != nil {
-		return fmt.Errorf("failed parsing url for %s with error %s", vc.SourceConfig.Hostname, err)
-	}
-
-	// How to set custom ca certificates for govmomi: https://github.com/vmware/govmomi/issues/1200#issuecomment-412950179
-	soapClient := soap.NewClient(url, !vc.SourceConfig.ValidateCert)
-	if vc.CAFile != "" {
-		err = soapClient.SetRootCAs(vc.CAFile)
-		if err != nil {
-			return fmt.Errorf("set root CAs: %s", err)
-		}
-	}
-	vim25Client, err := vim25.NewClient(ctx, soapClient)
+	vim25Client, sessionManager, user, err := vc.login(ctx)
 	if err != nil {
-		return fmt.Errorf("failed creating a govmomi client with an error: %s", err)
-	}
-
-	// Create a SessionManager and login to authenticate the session
-	sessionManager := session.NewManager(vim25Client)
-
-	// Perform login
-	if err = sessionManager.Login(ctx, url.User); err != nil {
-		return fmt.Errorf("login failed: %s", err)
+		return err
 	}
 
 	// View manager is used to create and manage views. Views are a mechanism in vSphere
@@ -136,7 +113,7 @@ func (vc *VmwareSource) Init() error {
 	// viewType specifies the types of objects to be included in our container view.
 	// Each string in this slice represents a different vSphere Managed Object type.
 	viewType := []string{
-		"Datastore", "Datacenter", "ClusterComputeResource", "HostSystem", "VirtualMachine", "Network",
+		"Datastore", "Datacenter", "Folder", "ClusterComputeResource", "HostSystem", "VirtualMachine", "Network",
 	}
 
 	// A container view is a subset of the vSphere inventory, focusing on the specified
@@ -173,7 +150,7 @@ func (vc *VmwareSource) Init() error {
 		return fmt.Errorf("create cluster datacenter relation failed: %s", err)
 	}
 
-	err = vc.CreateObjectTagsRelation(ctx, vim25Client, url.User)
+	err = vc.CreateObjectTagsRelation(ctx, vim25Client, user)
 	if err != nil {
 		return fmt.Errorf("create object tags relation failed: %s", err)
 	}
@@ -183,6 +160,7 @@ func (vc *VmwareSource) Init() error {
 		vc.initNetworks,
 		vc.initDisks,
 		vc.initDataCenters,
+		vc.initFolders,
 		vc.initClusters,
 		vc.initHosts,
 		vc.initVms,
@@ -190,6 +168,9 @@ func (vc *VmwareSource) Init() error {
 
 	for _, initFunc := range initFunctions {
 		startTime := time.Now()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := initFunc(ctx, containerView); err != nil {
 			return fmt.Errorf("vmware initialization failure: %v", err)
 		}
@@ -226,8 +207,52 @@ func (vc *VmwareSource) Init() error {
 	return nil
 }
 
+// login creates a client of the vsphere API, and logs in with credentials of the source.
+func (vc *VmwareSource) login(ctx context.Context) (*vim25.Client, *session.Manager, *url.Userinfo, error) {
+	// Correctly handle backslashes in username and password
+	escapedUsername := url.PathEscape(vc.SourceConfig.Username)
+	escapedPassword := url.PathEscape(vc.SourceConfig.Password)
+
+	vcURL := fmt.Sprintf(
+		"%s://%s:%s@%s:%d/sdk",
+		vc.SourceConfig.HTTPScheme,
+		escapedUsername,
+		escapedPassword,
+		vc.SourceConfig.Hostname,
+		vc.SourceConfig.Port,
+	)
+
+	url, err := url.Parse(vcURL)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed parsing url for %s with error %s", vc.SourceConfig.Hostname, err)
+	}
+
+	// How to set custom ca certificates for govmomi: https://github.com/vmware/govmomi/issues/1200#issuecomment-412950179
+	soapClient := soap.NewClient(url, !vc.SourceConfig.ValidateCert)
+	if vc.CAFile != "" {
+		err = soapClient.SetRootCAs(vc.CAFile)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("set root CAs: %s", err)
+		}
+	}
+	vim25Client, err := vim25.NewClient(ctx, soapClient)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed creating a govmomi client with an error: %s", err)
+	}
+
+	// Create a SessionManager and login to authenticate the session
+	sessionManager := session.NewManager(vim25Client)
+
+	// Perform login
+	if err = sessionManager.Login(ctx, url.User); err != nil {
+		return nil, nil, nil, fmt.Errorf("login failed: %s", err)
+	}
+	return vim25Client, sessionManager, url.User, nil
+}
+
 // Function that syncs all data from oVirt to Netbox.
-func (vc *VmwareSource) Sync(nbi *inventory.NetboxInventory) error {
+func (vc *VmwareSource) Sync(ctx context.Context, nbi *inventory.NetboxInventory) error {
+	vc.Ctx = ctx
 	syncFunctions := []func(*inventory.NetboxInventory) error{}
 	if !vc.SourceConfig.IgnoreTags {
 		syncFunctions = append(syncFunctions, vc.syncTags)
@@ -243,6 +268,9 @@ func (vc *VmwareSource) Sync(nbi *inventory.NetboxInventory) error {
 	for _, syncFunc := range syncFunctions {
 		startTime := time.Now()
 		funcName := utils.ExtractFunctionNameWithTrimPrefix(syncFunc, "sync")
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("sync %s: %w", funcName, err)
+		}
 		err := syncFunc(nbi)
 		if err != nil {
 			if vc.SourceConfig.ContinueOnError {
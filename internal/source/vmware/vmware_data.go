@@ -0,0 +1,56 @@
+package vmware
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/bl4ko/netbox-ssot/internal/source/common"
+	vimjson "github.com/vmware/govmomi/vim25/json"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// dataFields are fields of VmwareSource initialized in Init, which are dumped with --dump-source-data.
+var dataFields = []string{
+	"Disks",
+	"DataCenters",
+	"Folders",
+	"Clusters",
+	"Hosts",
+	"Vms",
+	"Networks",
+	"Cluster2Datacenter",
+	"Host2Cluster",
+	"VM2Host",
+	"CustomFieldID2Name",
+	"Object2Tags",
+}
+
+// DumpData implements common.Dumper.
+func (vc *VmwareSource) DumpData() (map[string]json.RawMessage, error) {
+	return common.MarshalFields(vc, dataFields, marshalVmomi)
+}
+
+// LoadData implements common.Dumper.
+func (vc *VmwareSource) LoadData(data map[string]json.RawMessage) error {
+	return common.UnmarshalFields(vc, data, unmarshalVmomi)
+}
+
+// marshalVmomi marshals v with type names of vSphere API objects, which is
+// needed for unmarshalling of their interface fields (e.g. BaseVirtualDevice).
+func marshalVmomi(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	// Unlike types.NewJSONEncoder, type names are encoded only for interface
+	// values, because decoder of maps would decode them as keys
+	encoder := vimjson.NewEncoder(&buf)
+	encoder.SetDiscriminator("_typeName", "_value", vimjson.DiscriminatorEncodeTypeNameIfRequired)
+	encoder.SetTypeToDiscriminatorFunc(types.VmomiTypeName)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(buf.Bytes()), nil
+}
+
+// unmarshalVmomi unmarshals data marshalled with marshalVmomi into v.
+func unmarshalVmomi(data []byte, v any) error {
+	return types.NewJSONDecoder(bytes.NewReader(data)).Decode(v)
+}
@@ -116,6 +116,20 @@ func (vc *VmwareSource) initDataCenters(
 	return nil
 }
 
+// initFolders initializes folders, which are used to determine folders of VMs.
+func (vc *VmwareSource) initFolders(ctx context.Context, containerView *view.ContainerView) error {
+	var folders []mo.Folder
+	err := containerView.Retrieve(ctx, []string{"Folder"}, []string{"name", "parent"}, &folders)
+	if err != nil {
+		return fmt.Errorf("failed retrieving folders: %s", err)
+	}
+	vc.Folders = make(map[string]mo.Folder, len(folders))
+	for _, folder := range folders {
+		vc.Folders[folder.Self.Value] = folder
+	}
+	return nil
+}
+
 func (vc *VmwareSource) initClusters(ctx context.Context, containerView *view.ContainerView) error {
 	var clusters []mo.ClusterComputeResource
 	err := containerView.Retrieve(
@@ -138,20 +152,36 @@ func (vc *VmwareSource) initClusters(ctx context.Context, containerView *view.Co
 	return nil
 }
 
+// hostProperties are properties of hosts used in sync functions.
+var hostProperties = []string{
+	"name",
+	"summary.host",
+	"summary.hardware",
+	"summary.runtime",
+	"summary.config",
+	"vm",
+	"config.network",
+	"summary.customValue",
+}
+
+// vmProperties are properties of VMs used in sync functions.
+var vmProperties = []string{
+	"summary",
+	"name",
+	"runtime",
+	"guest",
+	"config.hardware",
+	"config.template",
+	"config.guestFullName",
+	"parent",
+}
+
 func (vc *VmwareSource) initHosts(ctx context.Context, containerView *view.ContainerView) error {
 	var hosts []mo.HostSystem
 	err := containerView.Retrieve(
 		ctx,
 		[]string{"HostSystem"},
-		[]string{
-			"name",
-			"summary.host",
-			"summary.hardware",
-			"summary.runtime",
-			"summary.config",
-			"vm",
-			"config.network",
-		},
+		hostProperties,
 		&hosts,
 	)
 	if err != nil {
@@ -160,66 +190,71 @@ func (vc *VmwareSource) initHosts(ctx context.Context, containerView *view.Conta
 	vc.VM2Host = make(map[string]string)
 	vc.Hosts = make(map[string]mo.HostSystem, len(hosts))
 	for _, host := range hosts {
-		vc.Hosts[host.Self.Value] = host
-		for _, vm := range host.Vm {
-			vc.VM2Host[vm.Value] = host.Self.Value
-		}
+		vc.addHost(host)
+	}
+	return nil
+}
 
-		vc.Networks.HostPortgroups[host.Name] = make(map[string]*HostPortgroupData)
-		vc.Networks.HostVirtualSwitches[host.Name] = make(map[string]*HostVirtualSwitchData)
-		vc.Networks.HostProxySwitches[host.Name] = make(map[string]*HostProxySwitchData)
-
-		if host.Config != nil && host.Config.Network != nil {
-			// Add network data which is received from hosts
-			// Iterate over hosts virtual switches, needed to enrich data on physical interfaces
-			if host.Config.Network.Vswitch != nil {
-				for _, vswitch := range host.Config.Network.Vswitch {
-					if vswitch.Name != "" {
-						vc.Networks.HostVirtualSwitches[host.Name][vswitch.Name] = &HostVirtualSwitchData{
-							mtu:   int(vswitch.Mtu),
-							pnics: vswitch.Pnic,
-						}
+// addHost stores the host, with relations of its VMs and its network data.
+func (vc *VmwareSource) addHost(host mo.HostSystem) {
+	vc.Hosts[host.Self.Value] = host
+	for _, vm := range host.Vm {
+		vc.VM2Host[vm.Value] = host.Self.Value
+	}
+
+	vc.Networks.HostPortgroups[host.Name] = make(map[string]*HostPortgroupData)
+	vc.Networks.HostVirtualSwitches[host.Name] = make(map[string]*HostVirtualSwitchData)
+	vc.Networks.HostProxySwitches[host.Name] = make(map[string]*HostProxySwitchData)
+
+	if host.Config != nil && host.Config.Network != nil {
+		// Add network data which is received from hosts
+		// Iterate over hosts virtual switches, needed to enrich data on physical interfaces
+		if host.Config.Network.Vswitch != nil {
+			for _, vswitch := range host.Config.Network.Vswitch {
+				if vswitch.Name != "" {
+					vc.Networks.HostVirtualSwitches[host.Name][vswitch.Name] = &HostVirtualSwitchData{
+						MTU:   int(vswitch.Mtu),
+						Pnics: vswitch.Pnic,
 					}
 				}
 			}
-			// Iterate over hosts proxy switches, needed to enrich data on physical interfaces
-			// Also stores mtu data which is used for VM interfaces
-			if host.Config.Network.ProxySwitch != nil {
-				for _, pswitch := range host.Config.Network.ProxySwitch {
-					if pswitch.DvsUuid != "" {
-						vc.Networks.HostProxySwitches[host.Name][pswitch.DvsUuid] = &HostProxySwitchData{
-							mtu:   int(pswitch.Mtu),
-							pnics: pswitch.Pnic,
-							name:  pswitch.DvsName,
-						}
+		}
+		// Iterate over hosts proxy switches, needed to enrich data on physical interfaces
+		// Also stores mtu data which is used for VM interfaces
+		if host.Config.Network.ProxySwitch != nil {
+			for _, pswitch := range host.Config.Network.ProxySwitch {
+				if pswitch.DvsUuid != "" {
+					vc.Networks.HostProxySwitches[host.Name][pswitch.DvsUuid] = &HostProxySwitchData{
+						MTU:   int(pswitch.Mtu),
+						Pnics: pswitch.Pnic,
+						Name:  pswitch.DvsName,
 					}
 				}
 			}
-			// Iterate over hosts port groups, needed to enrich data on physical interfaces
-			if host.Config.Network.Portgroup != nil {
-				for _, pgroup := range host.Config.Network.Portgroup {
-					if pgroup.Spec.Name != "" {
-						nicOrder := pgroup.ComputedPolicy.NicTeaming.NicOrder
-						pgroupNics := []string{}
-						if nicOrder != nil {
-							if len(nicOrder.ActiveNic) > 0 {
-								pgroupNics = append(pgroupNics, nicOrder.ActiveNic...)
-							}
-							if len(nicOrder.StandbyNic) > 0 {
-								pgroupNics = append(pgroupNics, nicOrder.StandbyNic...)
-							}
+		}
+		// Iterate over hosts port groups, needed to enrich data on physical interfaces
+		if host.Config.Network.Portgroup != nil {
+			for _, pgroup := range host.Config.Network.Portgroup {
+				if pgroup.Spec.Name != "" {
+					nicOrder := pgroup.ComputedPolicy.NicTeaming.NicOrder
+					pgroupNics := []string{}
+					if nicOrder != nil {
+						if len(nicOrder.ActiveNic) > 0 {
+							pgroupNics = append(pgroupNics, nicOrder.ActiveNic...)
 						}
-						vc.Networks.HostPortgroups[host.Name][pgroup.Spec.Name] = &HostPortgroupData{
-							vlanID:  int(pgroup.Spec.VlanId),
-							vswitch: pgroup.Spec.VswitchName,
-							nics:    pgroupNics,
+						if len(nicOrder.StandbyNic) > 0 {
+							pgroupNics = append(pgroupNics, nicOrder.StandbyNic...)
 						}
 					}
+					vc.Networks.HostPortgroups[host.Name][pgroup.Spec.Name] = &HostPortgroupData{
+						VlanID:  int(pgroup.Spec.VlanId),
+						VSwitch: pgroup.Spec.VswitchName,
+						Nics:    pgroupNics,
+					}
 				}
 			}
 		}
 	}
-	return nil
 }
 
 func (vc *VmwareSource) initVms(ctx context.Context, containerView *view.ContainerView) error {
@@ -227,15 +262,7 @@ func (vc *VmwareSource) initVms(ctx context.Context, containerView *view.Contain
 	err := containerView.Retrieve(
 		ctx,
 		[]string{"VirtualMachine"},
-		[]string{
-			"summary",
-			"name",
-			"runtime",
-			"guest",
-			"config.hardware",
-			"config.template",
-			"config.guestFullName",
-		},
+		vmProperties,
 		&vms,
 	)
 	if err != nil {
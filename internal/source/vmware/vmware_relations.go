@@ -0,0 +1,115 @@
+package vmware
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bl4ko/netbox-ssot/internal/expr"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// hostAttributes returns attributes of the host, which are matched by relation rules.
+func (vc *VmwareSource) hostAttributes(hostID string, host mo.HostSystem) expr.Attributes {
+	attributes := expr.Attributes{}
+	clusterID := vc.Host2Cluster[hostID]
+	attributes.Set(parser.RelationAttributeName, host.Name)
+	attributes.Set(parser.RelationAttributeCluster, vc.Clusters[clusterID].Name)
+	attributes.Set(parser.RelationAttributeDatacenter, vc.DataCenters[vc.Cluster2Datacenter[clusterID]].Name)
+	attributes.Set(parser.RelationAttributeTags, vc.tagNames(hostID)...)
+	networks := make([]string, 0, len(vc.Networks.HostPortgroups[host.Name]))
+	for portgroupName := range vc.Networks.HostPortgroups[host.Name] {
+		networks = append(networks, portgroupName)
+	}
+	attributes.Set(parser.RelationAttributeNetworks, networks...)
+	var ips []string
+	if host.Config != nil && host.Config.Network != nil {
+		for _, vnic := range host.Config.Network.Vnic {
+			if vnic.Spec.Ip != nil && vnic.Spec.Ip.IpAddress != "" {
+				ips = append(ips, vnic.Spec.Ip.IpAddress)
+			}
+		}
+	}
+	attributes.Set(parser.RelationAttributeIPs, ips...)
+	vc.setCustomAttributes(attributes, host.Summary.CustomValue)
+	return attributes
+}
+
+// clusterAttributes returns attributes of the cluster, which are matched by relation rules.
+func (vc *VmwareSource) clusterAttributes(clusterID string, cluster mo.ClusterComputeResource) expr.Attributes {
+	attributes := expr.Attributes{}
+	attributes.Set(parser.RelationAttributeName, cluster.Name)
+	attributes.Set(parser.RelationAttributeDatacenter, vc.DataCenters[vc.Cluster2Datacenter[clusterID]].Name)
+	attributes.Set(parser.RelationAttributeTags, vc.tagNames(clusterID)...)
+	return attributes
+}
+
+// vmAttributes returns attributes of the VM, which are matched by relation rules.
+func (vc *VmwareSource) vmAttributes(vmKey string, vm mo.VirtualMachine) expr.Attributes {
+	attributes := expr.Attributes{}
+	hostID := vc.VM2Host[vmKey]
+	clusterID := vc.Host2Cluster[hostID]
+	attributes.Set(parser.RelationAttributeName, vm.Name)
+	attributes.Set(parser.RelationAttributeCluster, vc.Clusters[clusterID].Name)
+	attributes.Set(parser.RelationAttributeDatacenter, vc.DataCenters[vc.Cluster2Datacenter[clusterID]].Name)
+	attributes.Set(parser.RelationAttributeHost, vc.Hosts[hostID].Name)
+	attributes.Set(parser.RelationAttributeFolder, vc.folderPath(vm.Parent))
+	attributes.Set(parser.RelationAttributeTags, vc.tagNames(vmKey)...)
+	var networks, ips []string
+	if vm.Guest != nil {
+		for _, guestNic := range vm.Guest.Net {
+			if guestNic.Network != "" && !slices.Contains(networks, guestNic.Network) {
+				networks = append(networks, guestNic.Network)
+			}
+			if guestNic.IpConfig != nil {
+				for _, ip := range guestNic.IpConfig.IpAddress {
+					ips = append(ips, fmt.Sprintf("%s/%d", ip.IpAddress, ip.PrefixLength))
+				}
+			}
+		}
+	}
+	attributes.Set(parser.RelationAttributeNetworks, networks...)
+	attributes.Set(parser.RelationAttributeIPs, ips...)
+	vc.setCustomAttributes(attributes, vm.Summary.CustomValue)
+	return attributes
+}
+
+// tagNames returns names of vSphere tags of the object.
+func (vc *VmwareSource) tagNames(objectID string) []string {
+	names := make([]string, 0, len(vc.Object2Tags[objectID]))
+	for _, tag := range vc.Object2Tags[objectID] {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// setCustomAttributes sets values of custom attributes by their names.
+func (vc *VmwareSource) setCustomAttributes(attributes expr.Attributes, customValues []types.BaseCustomFieldValue) {
+	for _, customValue := range customValues {
+		if field, ok := customValue.(*types.CustomFieldStringValue); ok {
+			name := vc.CustomFieldID2Name[field.Key]
+			attributes.Set(expr.IndexedName(parser.RelationAttributeAttributes, name), field.Value)
+		}
+	}
+}
+
+// folderPath returns path of the folder in its datacenter, e.g. "Production/Web". Hidden
+// folder of VMs of the datacenter (vm) isn't included, so VMs in it have empty path.
+func (vc *VmwareSource) folderPath(parent *types.ManagedObjectReference) string {
+	var names []string
+	for parent != nil && parent.Type == "Folder" {
+		folder, ok := vc.Folders[parent.Value]
+		if !ok {
+			break
+		}
+		names = append(names, folder.Name)
+		parent = folder.Parent
+	}
+	if parent != nil && parent.Type == "Datacenter" && len(names) > 0 {
+		names = names[:len(names)-1]
+	}
+	slices.Reverse(names)
+	return strings.Join(names, "/")
+}
@@ -0,0 +1,99 @@
+package vmware
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bl4ko/netbox-ssot/internal/expr"
+	"github.com/vmware/govmomi/vapi/tags"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func folder(id string, name string, parent types.ManagedObjectReference) mo.Folder {
+	folder := mo.Folder{}
+	folder.Self = types.ManagedObjectReference{Type: "Folder", Value: id}
+	folder.Name = name
+	folder.Parent = &parent
+	return folder
+}
+
+func TestVmwareSourceVMAttributes(t *testing.T) {
+	datacenter := types.ManagedObjectReference{Type: "Datacenter", Value: "datacenter-1"}
+	vc := &VmwareSource{
+		DataCenters: map[string]mo.Datacenter{"datacenter-1": {ManagedEntity: mo.ManagedEntity{Name: "DC1"}}},
+		Clusters: map[string]mo.ClusterComputeResource{
+			"domain-c1": {ComputeResource: mo.ComputeResource{ManagedEntity: mo.ManagedEntity{Name: "Prod"}}},
+		},
+		Hosts:              map[string]mo.HostSystem{"host-1": {ManagedEntity: mo.ManagedEntity{Name: "esx01"}}},
+		Cluster2Datacenter: map[string]string{"domain-c1": "datacenter-1"},
+		Host2Cluster:       map[string]string{"host-1": "domain-c1"},
+		VM2Host:            map[string]string{"vm-1": "host-1"},
+		CustomFieldID2Name: map[int32]string{101: "Owner"},
+		Object2Tags:        map[string][]*tags.Tag{"vm-1": {{Name: "web"}, {Name: "backup"}}},
+		Folders: map[string]mo.Folder{
+			"group-v1": folder("group-v1", "vm", datacenter),
+			"group-v2": folder("group-v2", "Production", types.ManagedObjectReference{Type: "Folder", Value: "group-v1"}),
+			"group-v3": folder("group-v3", "Web", types.ManagedObjectReference{Type: "Folder", Value: "group-v2"}),
+		},
+	}
+
+	vm := mo.VirtualMachine{
+		ManagedEntity: mo.ManagedEntity{
+			Name:   "web01",
+			Parent: &types.ManagedObjectReference{Type: "Folder", Value: "group-v3"},
+		},
+		Summary: types.VirtualMachineSummary{CustomValue: []types.BaseCustomFieldValue{
+			&types.CustomFieldStringValue{CustomFieldValue: types.CustomFieldValue{Key: 101}, Value: "team-a"},
+		}},
+		Guest: &types.GuestInfo{Net: []types.GuestNicInfo{
+			{
+				Network: "VM Network",
+				IpConfig: &types.NetIpConfigInfo{IpAddress: []types.NetIpConfigInfoIpAddress{
+					{IpAddress: "10.1.2.3", PrefixLength: 24},
+				}},
+			},
+			{Network: "VM Network"},
+		}},
+	}
+
+	want := expr.Attributes{
+		"name":              {"web01"},
+		"cluster":           {"Prod"},
+		"datacenter":        {"DC1"},
+		"host":              {"esx01"},
+		"folder":            {"Production/Web"},
+		"tags":              {"web", "backup"},
+		"networks":          {"VM Network"},
+		"ips":               {"10.1.2.3/24"},
+		"attributes[Owner]": {"team-a"},
+	}
+	if got := vc.vmAttributes("vm-1", vm); !reflect.DeepEqual(got, want) {
+		t.Errorf("vmAttributes() = %v, want %v", got, want)
+	}
+}
+
+func TestVmwareSourceFolderPath(t *testing.T) {
+	datacenter := types.ManagedObjectReference{Type: "Datacenter", Value: "datacenter-1"}
+	vc := &VmwareSource{Folders: map[string]mo.Folder{
+		"group-v1": folder("group-v1", "vm", datacenter),
+		"group-v2": folder("group-v2", "Production", types.ManagedObjectReference{Type: "Folder", Value: "group-v1"}),
+	}}
+	tests := []struct {
+		name   string
+		parent *types.ManagedObjectReference
+		want   string
+	}{
+		{"Nested folder", &types.ManagedObjectReference{Type: "Folder", Value: "group-v2"}, "Production"},
+		{"Folder of VMs of the datacenter", &types.ManagedObjectReference{Type: "Folder", Value: "group-v1"}, ""},
+		{"Unknown folder", &types.ManagedObjectReference{Type: "Folder", Value: "group-v9"}, ""},
+		{"No parent", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := vc.folderPath(tt.parent); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("folderPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
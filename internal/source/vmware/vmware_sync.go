@@ -1,7 +1,9 @@
 package vmware
 
 import (
+	"errors"
 	"fmt"
+	"maps"
 	"slices"
 	"strconv"
 	"strings"
@@ -98,6 +100,16 @@ func (vc *VmwareSource) syncNetworks(nbi *inventory.NetboxInventory) error {
 				Status: &objects.VlanStatusActive,
 				Tenant: vlanTenant,
 			}
+			err = common.ApplyRelationRules(
+				vc.Ctx,
+				nbi,
+				vc.SourceConfig.RelationRules,
+				vlanStruct,
+				common.VlanAttributes(vlanStruct),
+			)
+			if err != nil {
+				return fmt.Errorf("match vlan to relation rules: %s", err)
+			}
 			_, err := nbi.AddVlan(vc.Ctx, vlanStruct)
 			if err != nil {
 				return fmt.Errorf("add vlan %+v: %s", vlanStruct, err)
@@ -201,6 +213,16 @@ func (vc *VmwareSource) syncClusters(nbi *inventory.NetboxInventory) error {
 			ScopeID:   clusterScopeID,
 			Tenant:    clusterTenant,
 		}
+		err = common.ApplyRelationRules(
+			vc.Ctx,
+			nbi,
+			vc.SourceConfig.RelationRules,
+			clusterStruct,
+			vc.clusterAttributes(clusterID, cluster),
+		)
+		if err != nil {
+			return fmt.Errorf("match cluster to relation rules: %s", err)
+		}
 		_, err = nbi.AddCluster(vc.Ctx, clusterStruct)
 		if err != nil {
 			return fmt.Errorf(
@@ -216,7 +238,15 @@ func (vc *VmwareSource) syncClusters(nbi *inventory.NetboxInventory) error {
 // Host in vmware is a represented as device in netbox with a
 // custom role Server.
 func (vc *VmwareSource) syncHosts(nbi *inventory.NetboxInventory) error {
-	for hostID, host := range vc.Hosts {
+	return vc.syncHostsOf(nbi, vc.Hosts)
+}
+
+// syncHostsOf syncs hosts by their keys from the source to Netbox.
+func (vc *VmwareSource) syncHostsOf(nbi *inventory.NetboxInventory, hosts map[string]mo.HostSystem) error {
+	if vc.NetboxHosts == nil {
+		vc.NetboxHosts = make(map[string]*objects.Device, len(hosts))
+	}
+	for hostID, host := range hosts {
 		var err error
 		hostName := host.Name
 
@@ -240,6 +270,25 @@ func (vc *VmwareSource) syncHosts(nbi *inventory.NetboxInventory) error {
 			return fmt.Errorf("hostTenant: %s", err)
 		}
 
+		// Relation rules take precedence over relations
+		relations, err := common.MatchRelationRules(
+			vc.Ctx,
+			nbi,
+			vc.SourceConfig.RelationRules,
+			constants.ContentTypeDcimDevice,
+			vc.hostAttributes(hostID, host),
+			hostSite,
+		)
+		if err != nil {
+			return fmt.Errorf("match host to relation rules: %s", err)
+		}
+		if relations.Site != nil {
+			hostSite = relations.Site
+		}
+		if relations.Tenant != nil {
+			hostTenant = relations.Tenant
+		}
+
 		hostCluster, _ := nbi.GetCluster(vc.Clusters[vc.Host2Cluster[hostID]].Name)
 		if hostCluster == nil {
 			// Create a hypothetical cluster https://github.com/bl4ko/netbox-ssot/issues/141
@@ -249,7 +298,7 @@ func (vc *VmwareSource) syncHosts(nbi *inventory.NetboxInventory) error {
 			}
 		}
 
-		hostTags := vc.Object2NBTags[hostID]
+		hostTags := append(slices.Clip(vc.Object2NBTags[hostID]), relations.Tags...)
 
 		// Extract host hardware info
 		var hostUUID, hostModel, hostManufacturerName string
@@ -356,8 +405,8 @@ func (vc *VmwareSource) syncHosts(nbi *inventory.NetboxInventory) error {
 
 		// Match host to a role. First test if user provided relations, if not
 		// use default server role.
-		var hostRole *objects.DeviceRole
-		if len(vc.SourceConfig.HostRoleRelations) > 0 {
+		hostRole := relations.Role
+		if hostRole == nil && len(vc.SourceConfig.HostRoleRelations) > 0 {
 			hostRole, err = common.MatchHostToRole(
 				vc.Ctx,
 				nbi,
@@ -392,6 +441,7 @@ func (vc *VmwareSource) syncHosts(nbi *inventory.NetboxInventory) error {
 			Platform:     hostPlatform,
 			DeviceRole:   hostRole,
 			Site:         hostSite,
+			Location:     relations.Location,
 			Tenant:       hostTenant,
 			Cluster:      hostCluster,
 			SerialNumber: hostSerialNumber,
@@ -399,9 +449,14 @@ func (vc *VmwareSource) syncHosts(nbi *inventory.NetboxInventory) error {
 			DeviceType:   hostDeviceType,
 		}
 		nbHost, err := nbi.AddDevice(vc.Ctx, hostStruct)
+		if errors.Is(err, inventory.ErrDropped) {
+			vc.NetboxHosts[hostID] = nil
+			continue
+		}
 		if err != nil {
 			return fmt.Errorf("failed to add vmware host %+v with error: %v", hostStruct, err)
 		}
+		vc.NetboxHosts[hostID] = nbHost
 
 		// We also need to sync nics separately, because nic is a separate object in netbox
 		err = vc.syncHostNics(nbi, host, nbHost, deviceData)
@@ -454,7 +509,7 @@ func (vc *VmwareSource) syncHostPhysicalNics(
 	if vcHost.Config != nil && vcHost.Config.Network != nil && vcHost.Config.Network.Pnic != nil {
 		for _, pnic := range vcHost.Config.Network.Pnic {
 			// Fetch host pnic data
-			hostPnic, macAddress, err := vc.collectHostPhysicalNicData(nbi, nbHost, pnic, deviceData)
+			hostPnic, macAddress, err := vc.collectHostPhysicalNicData(nbi, vcHost, nbHost, pnic, deviceData)
 			if err != nil {
 				return err
 			}
@@ -500,6 +555,7 @@ func (vc *VmwareSource) syncHostPhysicalNics(
 //nolint:gocyclo
 func (vc *VmwareSource) collectHostPhysicalNicData(
 	nbi *inventory.NetboxInventory,
+	vcHost mo.HostSystem,
 	nbHost *objects.Device,
 	pnic types.PhysicalNic,
 	_ *devices.DeviceData,
@@ -529,32 +585,32 @@ func (vc *VmwareSource) collectHostPhysicalNicData(
 	var pnicMtu int
 	var pnicMode *objects.InterfaceMode
 	// Check virtual switches for data
-	for vswitch, vswitchData := range vc.Networks.HostVirtualSwitches[nbHost.Name] {
-		if slices.Contains(vswitchData.pnics, pnic.Key) {
+	for vswitch, vswitchData := range vc.Networks.HostVirtualSwitches[vcHost.Name] {
+		if slices.Contains(vswitchData.Pnics, pnic.Key) {
 			pnicDescription = fmt.Sprintf("%s (%s)", pnicDescription, vswitch)
-			pnicMtu = vswitchData.mtu
+			pnicMtu = vswitchData.MTU
 		}
 	}
 
 	// Check proxy switches for data
-	for _, pswitchData := range vc.Networks.HostProxySwitches[nbHost.Name] {
-		if slices.Contains(pswitchData.pnics, pnic.Key) {
-			pnicDescription = fmt.Sprintf("%s (%s)", pnicDescription, pswitchData.name)
-			pnicMtu = pswitchData.mtu
+	for _, pswitchData := range vc.Networks.HostProxySwitches[vcHost.Name] {
+		if slices.Contains(pswitchData.Pnics, pnic.Key) {
+			pnicDescription = fmt.Sprintf("%s (%s)", pnicDescription, pswitchData.Name)
+			pnicMtu = pswitchData.MTU
 			pnicMode = &objects.InterfaceModeTaggedAll
 		}
 	}
 
 	// Check vlans on this pnic
 	vlanIDMap := map[int]*objects.Vlan{} // set of vlans
-	for portgroupName, portgroupData := range vc.Networks.HostPortgroups[nbHost.Name] {
-		if slices.Contains(portgroupData.nics, pnicName) {
-			if portgroupData.vlanID == 0 || portgroupData.vlanID > 4094 {
-				vlanIDMap[portgroupData.vlanID] = &objects.Vlan{Vid: portgroupData.vlanID}
+	for portgroupName, portgroupData := range vc.Networks.HostPortgroups[vcHost.Name] {
+		if slices.Contains(portgroupData.Nics, pnicName) {
+			if portgroupData.VlanID == 0 || portgroupData.VlanID > 4094 {
+				vlanIDMap[portgroupData.VlanID] = &objects.Vlan{Vid: portgroupData.VlanID}
 				continue
 			}
 			// Check if vlan with this vid already exists, else create it
-			if vlanName, ok := vc.Networks.Vid2Name[portgroupData.vlanID]; ok {
+			if vlanName, ok := vc.Networks.Vid2Name[portgroupData.VlanID]; ok {
 				vlanSite, err := common.MatchVlanToSite(
 					vc.Ctx,
 					nbi,
@@ -576,14 +632,25 @@ func (vc *VmwareSource) collectHostPhysicalNicData(
 				if err != nil {
 					return nil, "", fmt.Errorf("match vlan to group: %s", err)
 				}
-				vlan, vlanExists := nbi.GetVlan(vlanGroup.ID, portgroupData.vlanID)
+				vlanGroup, err = common.MatchVlanGroupRules(
+					vc.Ctx,
+					nbi,
+					vc.SourceConfig.RelationRules,
+					vlanName,
+					portgroupData.VlanID,
+					vlanGroup,
+				)
+				if err != nil {
+					return nil, "", fmt.Errorf("match vlan to relation rules: %s", err)
+				}
+				vlan, vlanExists := nbi.GetVlan(vlanGroup.ID, portgroupData.VlanID)
 				if vlanExists {
-					vlanIDMap[portgroupData.vlanID] = vlan
+					vlanIDMap[portgroupData.VlanID] = vlan
 				}
 			} else {
 				vlanName := portgroupName
 				if !strings.HasPrefix(vlanName, vc.SourceConfig.VlanPrefix) {
-					vlanName = fmt.Sprintf("%s%04d_%s", vc.SourceConfig.VlanPrefix, portgroupData.vlanID, vlanName)
+					vlanName = fmt.Sprintf("%s%04d_%s", vc.SourceConfig.VlanPrefix, portgroupData.VlanID, vlanName)
 				}
 				vlanSite, err := common.MatchVlanToSite(vc.Ctx, nbi, vlanName, vc.SourceConfig.VlanSiteRelations)
 				if err != nil {
@@ -605,7 +672,18 @@ func (vc *VmwareSource) collectHostPhysicalNicData(
 				if err != nil {
 					return nil, "", fmt.Errorf("match vlan to tenant: %s", err)
 				}
-				newVlan, newVlanExists := nbi.GetVlan(vlanGroup.ID, portgroupData.vlanID)
+				vlanGroup, err = common.MatchVlanGroupRules(
+					vc.Ctx,
+					nbi,
+					vc.SourceConfig.RelationRules,
+					vlanName,
+					portgroupData.VlanID,
+					vlanGroup,
+				)
+				if err != nil {
+					return nil, "", fmt.Errorf("match vlan to relation rules: %s", err)
+				}
+				newVlan, newVlanExists := nbi.GetVlan(vlanGroup.ID, portgroupData.VlanID)
 				if !newVlanExists {
 					vlanStruct := &objects.Vlan{
 						NetboxObject: objects.NetboxObject{
@@ -614,16 +692,26 @@ func (vc *VmwareSource) collectHostPhysicalNicData(
 						Status: &objects.VlanStatusActive,
 						Name:   vlanName,
 						Site:   vlanSite,
-						Vid:    portgroupData.vlanID,
+						Vid:    portgroupData.VlanID,
 						Tenant: vlanTenant,
 						Group:  vlanGroup,
 					}
+					err = common.ApplyRelationRules(
+						vc.Ctx,
+						nbi,
+						vc.SourceConfig.RelationRules,
+						vlanStruct,
+						common.VlanAttributes(vlanStruct),
+					)
+					if err != nil {
+						return nil, "", fmt.Errorf("match vlan to relation rules: %s", err)
+					}
 					newVlan, err = nbi.AddVlan(vc.Ctx, vlanStruct)
 					if err != nil {
 						return nil, "", fmt.Errorf("add vlan %+v: %s", vlanStruct, err)
 					}
 				}
-				vlanIDMap[portgroupData.vlanID] = newVlan
+				vlanIDMap[portgroupData.VlanID] = newVlan
 			}
 		}
 	}
@@ -865,8 +953,8 @@ func (vc *VmwareSource) collectHostVirtualNicData(
 
 	// Get data from local portgroup, or distributed portgroup
 	if vnicPortgroupDataOk {
-		vnicPortgroupVlanID = vnicPortgroupData.vlanID
-		vnicSwitch := vnicPortgroupData.vswitch
+		vnicPortgroupVlanID = vnicPortgroupData.VlanID
+		vnicSwitch := vnicPortgroupData.VSwitch
 		vnicDescription = fmt.Sprintf(
 			"%s (%s, vlan ID: %d)",
 			vnic.Portgroup,
@@ -922,6 +1010,17 @@ func (vc *VmwareSource) collectHostVirtualNicData(
 		if err != nil {
 			return nil, "", fmt.Errorf("vlan group: %s", err)
 		}
+		vnicUntaggedVlanGroup, err = common.MatchVlanGroupRules(
+			vc.Ctx,
+			nbi,
+			vc.SourceConfig.RelationRules,
+			vc.Networks.Vid2Name[vnicPortgroupVlanID],
+			vnicPortgroupVlanID,
+			vnicUntaggedVlanGroup,
+		)
+		if err != nil {
+			return nil, "", fmt.Errorf("match vlan to relation rules: %s", err)
+		}
 		vnicUntaggedVlan, _ = nbi.GetVlan(vnicUntaggedVlanGroup.ID, vnicPortgroupVlanID)
 		vnicMode = &objects.InterfaceModeAccess
 		// vnicUntaggedVlan = &objects.Vlan{
@@ -958,6 +1057,17 @@ func (vc *VmwareSource) collectHostVirtualNicData(
 			if err != nil {
 				return nil, "", fmt.Errorf("match vlan to vlan group: %s", err)
 			}
+			vnicTaggedVlanGroup, err = common.MatchVlanGroupRules(
+				vc.Ctx,
+				nbi,
+				vc.SourceConfig.RelationRules,
+				vc.Networks.Vid2Name[vnicDvPortgroupDataVlanID],
+				vnicDvPortgroupDataVlanID,
+				vnicTaggedVlanGroup,
+			)
+			if err != nil {
+				return nil, "", fmt.Errorf("match vlan to relation rules: %s", err)
+			}
 			taggedVlan, taggedVlanExists := nbi.GetVlan(vnicTaggedVlanGroup.ID, vnicDvPortgroupDataVlanID)
 			if taggedVlanExists {
 				vnicTaggedVlans = append(vnicTaggedVlans, taggedVlan)
@@ -987,16 +1097,23 @@ func (vc *VmwareSource) collectHostVirtualNicData(
 
 // syncVMs syncs VMs from the source to Netbox.
 func (vc *VmwareSource) syncVMs(nbi *inventory.NetboxInventory) error {
+	return vc.syncVMsOf(nbi, vc.Vms)
+}
+
+// syncVMsOf syncs VMs by their keys from the source to Netbox.
+func (vc *VmwareSource) syncVMsOf(nbi *inventory.NetboxInventory, vms map[string]mo.VirtualMachine) error {
 	const maxGoroutines = 50 // Maximum number of goroutines to run concurrently
 	// Use a guard channel as semaphore to limit the number of goroutines
 	guard := make(chan struct{}, maxGoroutines)
 	// Use errChan to collect errors from goroutines
-	errChan := make(chan error, len(vc.Vms))
+	errChan := make(chan error, len(vms))
 	// Use a WaitGroup to wait for all goroutines to complete
 	var wg sync.WaitGroup
+	// Networks of all VMs are added after the VMs with bulk requests
+	var vmNetworks common.VMNetworks
 
 	// Iterate over each VM and start a goroutine to sync it
-	for vmKey, vm := range vc.Vms {
+	for vmKey, vm := range vms {
 		guard <- struct{}{} // Block if max goroutines are running
 		wg.Add(1)
 
@@ -1004,7 +1121,7 @@ func (vc *VmwareSource) syncVMs(nbi *inventory.NetboxInventory) error {
 			defer wg.Done()
 			defer func() { <-guard }() // Release one spot in the semaphore
 
-			err := vc.syncVM(nbi, vmKey, vm)
+			err := vc.syncVM(nbi, vmKey, vm, &vmNetworks)
 			if err != nil {
 				errChan <- err
 			}
@@ -1022,16 +1139,21 @@ func (vc *VmwareSource) syncVMs(nbi *inventory.NetboxInventory) error {
 		}
 	}
 
+	if err := vmNetworks.Sync(vc.Ctx, nbi); err != nil {
+		return fmt.Errorf("failed to sync vmware vms' interfaces: %s", err)
+	}
 	return nil
 }
 
-// syncVM synces VM from the source to Netbox.
+// syncVM synces VM from the source to Netbox. Interfaces of the VM
+// are collected into vmNetworks.
 //
 //nolint:gocyclo
 func (vc *VmwareSource) syncVM(
 	nbi *inventory.NetboxInventory,
 	vmKey string,
 	vm mo.VirtualMachine,
+	vmNetworks *common.VMNetworks,
 ) error {
 	isTemplate := vm.Config != nil && vm.Config.Template
 
@@ -1047,10 +1169,22 @@ func (vc *VmwareSource) syncVM(
 	}
 	vmHostName := vc.Hosts[hostKey].Name
 
+	// Relation rules take precedence over relations
+	relations, err := common.MatchRelationRules(
+		vc.Ctx,
+		nbi,
+		vc.SourceConfig.RelationRules,
+		constants.ContentTypeVirtualizationVirtualMachine,
+		vc.vmAttributes(vmKey, vm),
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("match vm to relation rules: %s", err)
+	}
+
 	// Map to a vm role
-	var vmRole *objects.DeviceRole
-	var err error
-	if len(vc.SourceConfig.VMRoleRelations) > 0 {
+	vmRole := relations.Role
+	if vmRole == nil && len(vc.SourceConfig.VMRoleRelations) > 0 {
 		vmRole, err = common.MatchVMToRole(vc.Ctx, nbi, vmHostName, vc.SourceConfig.VMRoleRelations)
 		if err != nil {
 			return fmt.Errorf("match vm to role: %s", err)
@@ -1075,6 +1209,9 @@ func (vc *VmwareSource) syncVM(
 	if err != nil {
 		return fmt.Errorf("vm's Tenant: %s", err)
 	}
+	if relations.Tenant != nil {
+		vmTenant = relations.Tenant
+	}
 
 	// Site is the same as the Host
 	vmSite, err := common.MatchHostToSite(
@@ -1086,13 +1223,24 @@ func (vc *VmwareSource) syncVM(
 	if err != nil {
 		return fmt.Errorf("vm's Site: %s", err)
 	}
-	vmHost, _ := nbi.GetDevice(vmHostName, vmSite.ID)
+	vmHost, synced := vc.NetboxHosts[hostKey]
+	if synced && vmHost == nil {
+		vc.Logger.Debugf(vc.Ctx, "Skipping VM %q, because its host %q is dropped", vmName, vmHostName)
+		return nil
+	}
+	if vmHost == nil {
+		// Host wasn't synced in this run, e.g. when only the VM changed between runs
+		vmHost, _ = nbi.GetDevice(vmHostName, vmSite.ID)
+	}
 	if vmHost == nil {
 		return fmt.Errorf("host device %q not found in site %d, skipping VM", vmHostName, vmSite.ID)
 	}
 
 	// Cluster of the vm is same as the host
 	vmCluster := vmHost.Cluster
+	if relations.Site != nil {
+		vmSite = relations.Site
+	}
 
 	// VM status
 	vmStatus := &objects.VMStatusOffline
@@ -1155,6 +1303,8 @@ func (vc *VmwareSource) syncVM(
 	var vmOwnerEmails []string
 	var vmDescription string
 	vmCustomFields := map[string]interface{}{}
+	// Custom attributes mapped to custom fields with other names
+	mappedAttributes := map[string]interface{}{}
 	if len(vm.Summary.CustomValue) > 0 {
 		for _, field := range vm.Summary.CustomValue {
 			if field, ok := field.(*types.CustomFieldStringValue); ok {
@@ -1168,6 +1318,8 @@ func (vc *VmwareSource) syncVM(
 						vmOwnerEmails = utils.SerializeEmails(strings.Split(field.Value, ","))
 					case "description":
 						vmDescription = strings.TrimSpace(field.Value)
+					default:
+						mappedAttributes[fieldName] = field.Value
 					}
 				} else {
 					fieldName = utils.Alphanumeric(fieldName)
@@ -1189,6 +1341,17 @@ func (vc *VmwareSource) syncVM(
 			}
 		}
 	}
+	mappedCustomFields, err := common.MapCustomFields(
+		vc.Ctx,
+		nbi,
+		vc.SourceConfig.CustomFieldMappings,
+		constants.ContentTypeVirtualizationVirtualMachine,
+		mappedAttributes,
+	)
+	if err != nil {
+		return fmt.Errorf("map vm's custom fields: %s", err)
+	}
+	maps.Copy(vmCustomFields, mappedCustomFields)
 	vmCustomFields[constants.CustomFieldSourceName] = vc.SourceConfig.Name
 
 	// netbox description has constraint <= len(200 characters)
@@ -1201,7 +1364,7 @@ func (vc *VmwareSource) syncVM(
 
 	vmStruct := &objects.VM{
 		NetboxObject: objects.NetboxObject{
-			Tags:         append(vc.GetSourceTags(), vc.Object2NBTags[vmKey]...),
+			Tags:         append(append(vc.GetSourceTags(), vc.Object2NBTags[vmKey]...), relations.Tags...),
 			Description:  vmDescription,
 			CustomFields: vmCustomFields,
 		},
@@ -1219,6 +1382,9 @@ func (vc *VmwareSource) syncVM(
 		Role:     vmRole,
 	}
 	newVM, err := nbi.AddVM(vc.Ctx, vmStruct)
+	if errors.Is(err, inventory.ErrDropped) {
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to sync vmware VM %s: %v", vmName, err)
 	}
@@ -1230,8 +1396,8 @@ func (vc *VmwareSource) syncVM(
 			return fmt.Errorf("adding %s's contact: %s", newVM, err)
 		}
 
-		// Sync vm interfaces
-		err = vc.syncVMInterfaces(nbi, vm, newVM)
+		// Collect vm interfaces
+		err = vc.collectVMInterfaces(nbi, vm, newVM, vmNetworks)
 		if err != nil {
 			return fmt.Errorf("failed to sync vmware %s's interfaces: %v", newVM, err)
 		}
@@ -1263,17 +1429,17 @@ func (vc *VmwareSource) syncVMDisks(
 	return nil
 }
 
-// Syncs VM's interfaces to Netbox.
-func (vc *VmwareSource) syncVMInterfaces(
+// collectVMInterfaces collects VM's interfaces with their IPs into vmNetworks,
+// which adds them to Netbox together with interfaces of other VMs.
+func (vc *VmwareSource) collectVMInterfaces(
 	nbi *inventory.NetboxInventory,
 	vmwareVM mo.VirtualMachine,
 	netboxVM *objects.VM,
+	vmNetworks *common.VMNetworks,
 ) error {
 	// Data to determine the primary IP address of the vm
 	var vmDefaultGatewayIpv4 string
 	var vmDefaultGatewayIpv6 string
-	vmIPv4Addresses := make([]*objects.IPAddress, 0)
-	vmIPv6Addresses := make([]*objects.IPAddress, 0)
 
 	// From vm's routing determine the default interface
 	if len(vmwareVM.Guest.IpStack) > 0 {
@@ -1300,6 +1466,7 @@ func (vc *VmwareSource) syncVMInterfaces(
 		}
 	}
 
+	networks := make([]*common.VMNetwork, 0)
 	for _, vmDevice := range vmwareVM.Config.Hardware.Device {
 		// TODO: Refactor this to avoid hardcoded typecasting. Ensure all types
 		// that compose VirtualEthernetCard are properly handled.
@@ -1346,45 +1513,26 @@ func (vc *VmwareSource) syncVMInterfaces(
 				)
 				continue
 			}
-
-			nbVMInterface, err := nbi.AddVMInterface(vc.Ctx, collectedVMIface)
-			if err != nil {
-				return fmt.Errorf("adding VmInterface %+v: %s", collectedVMIface, err)
-			}
-			if macAddress != "" {
-				nbMACAddress, err := common.CreateMACAddressForObjectType(
-					vc.Ctx,
-					nbi,
-					macAddress,
-					nbVMInterface,
-				)
-				if err != nil {
-					return fmt.Errorf("creating MAC address for %+v: %s", collectedVMIface, err)
-				}
-				if err = common.SetPrimaryMACForInterface(vc.Ctx, nbi, nbVMInterface, nbMACAddress); err != nil {
-					return fmt.Errorf("setting primary MAC for %+v: %s", collectedVMIface, err)
-				}
-			}
-
-			vmIPv4Addresses, vmIPv6Addresses = vc.addVMInterfaceIPs(
+			ipv4Addresses, ipv6Addresses := vc.collectVMInterfaceIPs(
 				nbi,
 				netboxVM,
-				nbVMInterface,
 				nicIPv4Addresses,
 				nicIPv6Addresses,
-				vmIPv4Addresses,
-				vmIPv6Addresses,
 			)
+			networks = append(networks, &common.VMNetwork{
+				Interface:     collectedVMIface,
+				MACAddress:    macAddress,
+				IPv4Addresses: ipv4Addresses,
+				IPv6Addresses: ipv6Addresses,
+			})
 		}
 	}
-	vc.setVMPrimaryIPAddress(
-		nbi,
-		netboxVM,
-		vmDefaultGatewayIpv4,
-		vmDefaultGatewayIpv6,
-		vmIPv4Addresses,
-		vmIPv6Addresses,
-	)
+
+	vmNetworks.Add(netboxVM, networks, func(ipv4Addresses, ipv6Addresses []*objects.IPAddress) (
+		*objects.IPAddress, *objects.IPAddress,
+	) {
+		return selectVMPrimaryIPAddresses(vmDefaultGatewayIpv4, vmDefaultGatewayIpv6, ipv4Addresses, ipv6Addresses)
+	})
 	return nil
 }
 
@@ -1406,19 +1554,24 @@ func (vc *VmwareSource) collectVMInterfaceData(
 	var intMode *objects.VMInterfaceMode
 	intNetworkVlanIDs := []int{}
 	intNetworkVlanIDRanges := []string{}
+	// Networks of hosts are indexed by host names in vSphere, which can be transformed in Netbox
+	var vcHostName string
+	if vmwareVM.Runtime.Host != nil {
+		vcHostName = vc.Hosts[vmwareVM.Runtime.Host.Value].Name
+	}
 
 	// Get info from local vSwitches if possible, else from DistributedPortGroup
 	if backingInfo, ok := intDeviceBackingInfo.(*types.VirtualEthernetCardNetworkBackingInfo); ok {
 		intNetworkName = backingInfo.DeviceName
-		intHostPgroup := vc.Networks.HostPortgroups[netboxVM.Host.Name][intNetworkName]
+		intHostPgroup := vc.Networks.HostPortgroups[vcHostName][intNetworkName]
 
 		if intHostPgroup != nil {
-			intNetworkVlanIDs = []int{intHostPgroup.vlanID}
-			intNetworkVlanIDRanges = []string{strconv.Itoa(intHostPgroup.vlanID)}
-			intVswitchName := intHostPgroup.vswitch
-			intVswitchData := vc.Networks.HostVirtualSwitches[netboxVM.Host.Name][intVswitchName]
+			intNetworkVlanIDs = []int{intHostPgroup.VlanID}
+			intNetworkVlanIDRanges = []string{strconv.Itoa(intHostPgroup.VlanID)}
+			intVswitchName := intHostPgroup.VSwitch
+			intVswitchData := vc.Networks.HostVirtualSwitches[vcHostName][intVswitchName]
 			if intVswitchData != nil {
-				intMtu = intVswitchData.mtu
+				intMtu = intVswitchData.MTU
 			}
 		}
 	} else if backingInfo, ok := intDeviceBackingInfo.(*types.VirtualEthernetCardDistributedVirtualPortBackingInfo); ok {
@@ -1436,10 +1589,10 @@ func (vc *VmwareSource) collectVMInterfaceData(
 		}
 
 		intDvswitchUUID := backingInfo.Port.SwitchUuid
-		intDvswitchData := vc.Networks.HostProxySwitches[netboxVM.Host.Name][intDvswitchUUID]
+		intDvswitchData := vc.Networks.HostProxySwitches[vcHostName][intDvswitchUUID]
 
 		if intDvswitchData != nil {
-			intMtu = intDvswitchData.mtu
+			intMtu = intDvswitchData.MTU
 		}
 	}
 
@@ -1527,6 +1680,20 @@ func (vc *VmwareSource) collectVMInterfaceData(
 					err,
 				)
 			}
+			nicUntaggedVlanGroup, err = common.MatchVlanGroupRules(
+				vc.Ctx,
+				nbi,
+				vc.SourceConfig.RelationRules,
+				vc.Networks.Vid2Name[vidID],
+				vidID,
+				nicUntaggedVlanGroup,
+			)
+			if err != nil {
+				return nicIPv4Addresses, nicIPv6Addresses, nil, "", fmt.Errorf(
+					"match vlan to relation rules: %s",
+					err,
+				)
+			}
 			intUntaggedVlan, _ = nbi.GetVlan(nicUntaggedVlanGroup.ID, vidID)
 		} else {
 			intTaggedVlanList = []*objects.Vlan{}
@@ -1553,154 +1720,78 @@ func (vc *VmwareSource) collectVMInterfaceData(
 	}, strings.ToUpper(intMac), nil
 }
 
-// Function that adds all collected IPs for the vm's interface to netbox.
-func (vc *VmwareSource) addVMInterfaceIPs(
+// collectVMInterfaceIPs returns permitted ipv4 and ipv6 addresses of the vm's
+// interface, which are assigned to the interface, when it is added to netbox.
+func (vc *VmwareSource) collectVMInterfaceIPs(
 	nbi *inventory.NetboxInventory,
 	netboxVM *objects.VM,
-	nbVMInterface *objects.VMInterface,
 	nicIPv4Addresses []string,
 	nicIPv6Addresses []string,
-	vmIPv4Addresses []*objects.IPAddress,
-	vmIPv6Addresses []*objects.IPAddress,
 ) ([]*objects.IPAddress, []*objects.IPAddress) {
-	// Add all collected ipv4 addresses for the interface to netbox
-	for _, ipv4Address := range nicIPv4Addresses {
-		if utils.IsPermittedIPAddress(
-			ipv4Address,
-			vc.SourceConfig.PermittedSubnets,
-			vc.SourceConfig.IgnoredSubnets,
-		) {
-			// VRF
-			ipVRF, err := common.MatchIPToVRF(vc.Ctx, nbi, ipv4Address, vc.SourceConfig.IPVrfRelations)
-			if err != nil {
-				vc.Logger.Warningf(vc.Ctx, "match ip to vrf for %s: %s", ipv4Address, err)
-			}
-
-			ipAddressStruct := &objects.IPAddress{
-				NetboxObject: objects.NetboxObject{
-					Tags: vc.GetSourceTags(),
-					CustomFields: map[string]interface{}{
-						constants.CustomFieldArpEntryName: false,
-					},
-				},
-				Address:            ipv4Address,
-				DNSName:            utils.ReverseLookup(ipv4Address),
-				AssignedObjectType: constants.ContentTypeVirtualizationVMInterface,
-				AssignedObjectID:   nbVMInterface.ID,
-				Tenant:             netboxVM.Tenant,
-				VRF:                ipVRF,
-			}
-			nbIPv4Address, err := nbi.AddIPAddress(vc.Ctx, ipAddressStruct)
-			if err != nil {
-				vc.Logger.Warningf(vc.Ctx, "adding ipv4 address %s: %s", ipAddressStruct, err)
+	collect := func(addresses []string, tenant *objects.Tenant) []*objects.IPAddress {
+		ipAddresses := make([]*objects.IPAddress, 0, len(addresses))
+		for _, ipAddress := range addresses {
+			if !utils.IsPermittedIPAddress(
+				ipAddress,
+				vc.SourceConfig.PermittedSubnets,
+				vc.SourceConfig.IgnoredSubnets,
+			) {
 				continue
 			}
-			vmIPv4Addresses = append(vmIPv4Addresses, nbIPv4Address)
-			prefix, mask, err := utils.GetPrefixAndMaskFromIPAddress(nbIPv4Address.Address)
-			if err != nil {
-				vc.Logger.Warningf(vc.Ctx, "extract prefix from ip address: %s", err)
-			} else if mask != constants.MaxIPv4MaskBits {
-				prefixStruct := &objects.Prefix{
-					Prefix: prefix,
-					VRF:    ipVRF,
-				}
-				_, err = nbi.AddPrefix(vc.Ctx, prefixStruct)
-				if err != nil {
-					vc.Logger.Errorf(vc.Ctx, "add prefix %+v: %s", prefixStruct, err)
-				}
-			}
-		}
-	}
-
-	// Add all collected ipv6 addresses for the interface to netbox
-	for _, ipv6Address := range nicIPv6Addresses {
-		if utils.IsPermittedIPAddress(
-			ipv6Address,
-			vc.SourceConfig.PermittedSubnets,
-			vc.SourceConfig.IgnoredSubnets,
-		) {
 			// VRF
-			ipVRF, err := common.MatchIPToVRF(vc.Ctx, nbi, ipv6Address, vc.SourceConfig.IPVrfRelations)
+			ipVRF, err := common.MatchIPToVRF(vc.Ctx, nbi, ipAddress, vc.SourceConfig.IPVrfRelations)
 			if err != nil {
-				vc.Logger.Warningf(vc.Ctx, "match ip to vrf for %s: %s", ipv6Address, err)
+				vc.Logger.Warningf(vc.Ctx, "match ip to vrf for %s: %s", ipAddress, err)
 			}
-
-			nbIPv6Address, err := nbi.AddIPAddress(vc.Ctx, &objects.IPAddress{
+			ipAddresses = append(ipAddresses, &objects.IPAddress{
 				NetboxObject: objects.NetboxObject{
 					Tags: vc.GetSourceTags(),
 					CustomFields: map[string]interface{}{
 						constants.CustomFieldArpEntryName: false,
 					},
 				},
-				Address:            ipv6Address,
-				DNSName:            utils.ReverseLookup(ipv6Address),
+				Address:            ipAddress,
+				DNSName:            utils.ReverseLookup(ipAddress),
 				AssignedObjectType: constants.ContentTypeVirtualizationVMInterface,
-				AssignedObjectID:   nbVMInterface.ID,
+				Tenant:             tenant,
 				VRF:                ipVRF,
 			})
-			if err != nil {
-				vc.Logger.Warningf(vc.Ctx, "adding ipv6 address: %s", err)
-				continue
-			}
-			vmIPv6Addresses = append(vmIPv6Addresses, nbIPv6Address)
-			prefix, mask, err := utils.GetPrefixAndMaskFromIPAddress(nbIPv6Address.Address)
-			if err != nil {
-				vc.Logger.Warningf(vc.Ctx, "extract prefix from ip address: %s", err)
-			} else if mask != constants.MaxIPv6MaskBits {
-				prefixStruct := &objects.Prefix{
-					Prefix: prefix,
-					VRF:    ipVRF,
-				}
-				_, err = nbi.AddPrefix(vc.Ctx, prefixStruct)
-				if err != nil {
-					vc.Logger.Errorf(vc.Ctx, "add prefix: %s", err)
-				}
-			}
 		}
+		return ipAddresses
 	}
-	return vmIPv4Addresses, vmIPv6Addresses
+	return collect(nicIPv4Addresses, netboxVM.Tenant), collect(nicIPv6Addresses, nil)
 }
 
-// setVMPrimaryIPAddress updates the vm's primary IP in the following way:
-// we loop through all of the collected IPv4 and IPv6 addresses for the vm.
+// selectVMPrimaryIPAddresses selects the vm's primary IPs in the following way:
+// we loop through all of the added IPv4 and IPv6 addresses of the vm.
 // If any of the ips is in the same subnet as the default gateway, we choose it.
 // If there is no ip in the subnet of the default gateway, we choose the first one.
-func (vc *VmwareSource) setVMPrimaryIPAddress(
-	nbi *inventory.NetboxInventory,
-	netboxVM *objects.VM,
+func selectVMPrimaryIPAddresses(
 	vmDefaultGatewayIpv4 string,
 	vmDefaultGatewayIpv6 string,
 	vmIPv4Addresses []*objects.IPAddress,
 	vmIPv6Addresses []*objects.IPAddress,
-) {
-	if len(vmIPv4Addresses) > 0 || len(vmIPv6Addresses) > 0 {
-		var vmIPv4PrimaryAddress *objects.IPAddress
-		for _, addr := range vmIPv4Addresses {
-			if vmIPv4PrimaryAddress == nil ||
-				utils.SubnetContainsIPAddress(vmDefaultGatewayIpv4, addr.Address) {
-				vmIPv4PrimaryAddress = addr
-			}
-		}
-		var vmIPv6PrimaryAddress *objects.IPAddress
-		for _, addr := range vmIPv6Addresses {
-			if vmIPv6PrimaryAddress == nil ||
-				utils.SubnetContainsIPAddress(vmDefaultGatewayIpv6, addr.Address) {
-				vmIPv6PrimaryAddress = addr
-			}
+) (*objects.IPAddress, *objects.IPAddress) {
+	var vmIPv4PrimaryAddress *objects.IPAddress
+	for _, addr := range vmIPv4Addresses {
+		if vmIPv4PrimaryAddress == nil ||
+			utils.SubnetContainsIPAddress(vmDefaultGatewayIpv4, addr.Address) {
+			vmIPv4PrimaryAddress = addr
 		}
-		// Don't set link-local IPv6 as primary when a routable IPv4 exists
-		if vmIPv6PrimaryAddress != nil && vmIPv4PrimaryAddress != nil &&
-			strings.HasPrefix(vmIPv6PrimaryAddress.Address, "fe80:") {
-			vmIPv6PrimaryAddress = nil
-		}
-		newNetboxVM := *netboxVM
-		newNetboxVM.PrimaryIPv4 = vmIPv4PrimaryAddress
-		newNetboxVM.PrimaryIPv6 = vmIPv6PrimaryAddress
-		_, err := nbi.AddVM(vc.Ctx, &newNetboxVM)
-		if err != nil {
-			vc.Logger.Warningf(vc.Ctx, "updating vm's primary ip: %s", err)
+	}
+	var vmIPv6PrimaryAddress *objects.IPAddress
+	for _, addr := range vmIPv6Addresses {
+		if vmIPv6PrimaryAddress == nil ||
+			utils.SubnetContainsIPAddress(vmDefaultGatewayIpv6, addr.Address) {
+			vmIPv6PrimaryAddress = addr
 		}
 	}
+	// Don't set link-local IPv6 as primary when a routable IPv4 exists
+	if vmIPv6PrimaryAddress != nil && vmIPv4PrimaryAddress != nil &&
+		strings.HasPrefix(vmIPv6PrimaryAddress.Address, "fe80:") {
+		vmIPv6PrimaryAddress = nil
+	}
+	return vmIPv4PrimaryAddress, vmIPv6PrimaryAddress
 }
 
 func (vc *VmwareSource) addVMContact(
@@ -1,9 +1,12 @@
 package vmware
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/bl4ko/netbox-ssot/internal/netbox/objects"
+	"github.com/vmware/govmomi/vapi/tags"
+	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 )
 
@@ -27,3 +30,78 @@ func TestVmwareConnectionStateToDeviceStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestVmwareSourceDumpLoadData(t *testing.T) {
+	source := &VmwareSource{
+		Hosts: map[string]mo.HostSystem{
+			"host-1": {
+				ManagedEntity: mo.ManagedEntity{Name: "host1"},
+				Summary: types.HostListSummary{
+					Hardware: &types.HostHardwareSummary{Vendor: "Dell", Model: "R740"},
+				},
+			},
+		},
+		Vms: map[string]mo.VirtualMachine{
+			"vm-1": {
+				ManagedEntity: mo.ManagedEntity{Name: "vm1"},
+				Config: &types.VirtualMachineConfigInfo{
+					Hardware: types.VirtualHardware{
+						NumCPU: 2,
+						Device: []types.BaseVirtualDevice{
+							&types.VirtualVmxnet3{
+								VirtualVmxnet: types.VirtualVmxnet{
+									VirtualEthernetCard: types.VirtualEthernetCard{
+										VirtualDevice: types.VirtualDevice{
+											Key: 4000,
+											Backing: &types.VirtualEthernetCardNetworkBackingInfo{
+												Network: &types.ManagedObjectReference{Type: "Network", Value: "network-1"},
+											},
+										},
+										MacAddress: "00:50:56:00:00:01",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Networks: NetworkData{
+			HostVirtualSwitches: map[string]map[string]*HostVirtualSwitchData{
+				"host1": {"vSwitch0": {MTU: 1500, Pnics: []string{"vmnic0"}}},
+			},
+			HostPortgroups: map[string]map[string]*HostPortgroupData{
+				"host1": {"VM Network": {VlanID: 10, VSwitch: "vSwitch0", Nics: []string{"vmnic0"}}},
+			},
+		},
+		VM2Host:            map[string]string{"vm-1": "host-1"},
+		CustomFieldID2Name: map[int32]string{1: "owner"},
+		Object2Tags:        map[string][]*tags.Tag{"vm-1": {{ID: "tag-1", Name: "prod"}}},
+	}
+	data, err := source.DumpData()
+	if err != nil {
+		t.Fatalf("DumpData() error = %v", err)
+	}
+	loaded := &VmwareSource{}
+	if err := loaded.LoadData(data); err != nil {
+		t.Fatalf("LoadData() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Hosts, source.Hosts) {
+		t.Errorf("loaded Hosts = %+v, want %+v", loaded.Hosts, source.Hosts)
+	}
+	if !reflect.DeepEqual(loaded.Vms, source.Vms) {
+		t.Errorf("loaded Vms = %+v, want %+v", loaded.Vms, source.Vms)
+	}
+	if !reflect.DeepEqual(loaded.Networks, source.Networks) {
+		t.Errorf("loaded Networks = %+v, want %+v", loaded.Networks, source.Networks)
+	}
+	if !reflect.DeepEqual(loaded.VM2Host, source.VM2Host) {
+		t.Errorf("loaded VM2Host = %v, want %v", loaded.VM2Host, source.VM2Host)
+	}
+	if !reflect.DeepEqual(loaded.CustomFieldID2Name, source.CustomFieldID2Name) {
+		t.Errorf("loaded CustomFieldID2Name = %v, want %v", loaded.CustomFieldID2Name, source.CustomFieldID2Name)
+	}
+	if !reflect.DeepEqual(loaded.Object2Tags, source.Object2Tags) {
+		t.Errorf("loaded Object2Tags = %v, want %v", loaded.Object2Tags, source.Object2Tags)
+	}
+}
@@ -0,0 +1,277 @@
+package vmware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/netbox/inventory"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// watchedHostProperties are properties of hosts, whose changes are synced by SyncChanges.
+// Frequently changing properties (e.g. quick stats) are not watched.
+var watchedHostProperties = []string{
+	"name",
+	"vm",
+	"config.network",
+	"summary.config.product",
+	"summary.runtime.connectionState",
+	"summary.runtime.powerState",
+	"summary.runtime.inMaintenanceMode",
+	"summary.customValue",
+}
+
+// watchedVMProperties are properties of VMs, whose changes are synced by SyncChanges.
+var watchedVMProperties = []string{
+	"name",
+	"config.hardware",
+	"config.template",
+	"config.guestFullName",
+	"runtime.host",
+	"runtime.powerState",
+	"guest.hostName",
+	"guest.net",
+	"summary.config.annotation",
+	"summary.customValue",
+	"parent",
+}
+
+// watchedHostObjectTypes are object types, which are synced for changed hosts.
+var watchedHostObjectTypes = []constants.APIPath{
+	constants.DevicesAPIPath,
+	constants.InterfacesAPIPath,
+	constants.IPAddressesAPIPath,
+	constants.MACAddressesAPIPath,
+}
+
+// watchedVMObjectTypes are object types, which are synced for changed VMs.
+var watchedVMObjectTypes = []constants.APIPath{
+	constants.VirtualMachinesAPIPath,
+	constants.VMInterfacesAPIPath,
+	constants.VirtualDisksAPIPath,
+	constants.IPAddressesAPIPath,
+	constants.MACAddressesAPIPath,
+}
+
+// Watch implements common.Watcher. vCenter pushes changes of watched properties of hosts
+// and VMs through WaitForUpdatesEx of the PropertyCollector, so objects don't have to be
+// retrieved again. Changes, which happen before Watch is started, are synced by the next Init.
+func (vc *VmwareSource) Watch(ctx context.Context, changed func(objectTypes ...constants.APIPath)) error {
+	client, sessionManager, _, err := vc.login(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := sessionManager.Logout(context.Background()); err != nil {
+			vc.Logger.Errorf(ctx, "failed ending vmware connection: %s", err)
+		}
+	}()
+
+	containerView, err := view.NewManager(client).CreateContainerView(
+		ctx,
+		client.ServiceContent.RootFolder,
+		[]string{"HostSystem", "VirtualMachine"},
+		true,
+	)
+	if err != nil {
+		return fmt.Errorf("failed creating containerView: %s", err)
+	}
+	defer func() {
+		if err := containerView.Destroy(context.Background()); err != nil {
+			vc.Logger.Errorf(ctx, "failed destroying containerView: %s", err)
+		}
+	}()
+
+	// Dedicated collector is used, because the default one can wait for a single caller only
+	collector, err := property.DefaultCollector(client).Create(ctx)
+	if err != nil {
+		return fmt.Errorf("failed creating property collector: %s", err)
+	}
+	defer func() {
+		if err := collector.Destroy(context.Background()); err != nil {
+			vc.Logger.Errorf(ctx, "failed destroying property collector: %s", err)
+		}
+	}()
+
+	filter := new(property.WaitFilter)
+	filter.Spec.ObjectSet = []types.ObjectSpec{{
+		Obj:  containerView.Reference(),
+		Skip: types.NewBool(true),
+		SelectSet: []types.BaseSelectionSpec{
+			&types.TraversalSpec{Type: containerView.Reference().Type, Path: "view"},
+		},
+	}}
+	filter.Spec.PropSet = []types.PropertySpec{
+		{Type: "HostSystem", PathSet: watchedHostProperties},
+		{Type: "VirtualMachine", PathSet: watchedVMProperties},
+	}
+
+	vc.Logger.Infof(ctx, "Watching hosts and VMs of %s for changes", vc.SourceConfig.Hostname)
+	initial := true
+	err = property.WaitForUpdatesEx(ctx, collector, filter, func(updates []types.ObjectUpdate) bool {
+		// The first updates contain current state of all objects, which may be truncated
+		if initial {
+			initial = filter.Truncated
+			return false
+		}
+		if len(updates) > 0 {
+			vc.addChanges(updates)
+			changed(changedObjectTypes(updates)...)
+		}
+		return false
+	})
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("wait for updates: %s", err)
+	}
+	return nil
+}
+
+// SyncChanges implements common.Watcher. Changed hosts and VMs are retrieved again,
+// and synced with the same functions as in Sync. Removed hosts and VMs are only
+// removed from the source, orphans are removed by the next scheduled run.
+func (vc *VmwareSource) SyncChanges(ctx context.Context, nbi *inventory.NetboxInventory) error {
+	vc.Ctx = ctx
+	if vc.Hosts == nil || vc.Vms == nil {
+		return errors.New("changes can't be synced before the source is initialized")
+	}
+	changes := vc.takeChanges()
+	if len(changes) == 0 {
+		return nil
+	}
+	hosts, vms, err := vc.applyChanges(ctx, changes)
+	if err == nil {
+		err = vc.syncHostsOf(nbi, hosts)
+	}
+	if err == nil {
+		err = vc.syncVMsOf(nbi, vms)
+	}
+	if err != nil {
+		// Changes are synced again, with the next changes
+		vc.restoreChanges(changes)
+		return fmt.Errorf("sync changes: %s", err)
+	}
+	vc.Logger.Infof(vc.Ctx, "Successfully synced %d changed hosts and %d changed VMs", len(hosts), len(vms))
+	return nil
+}
+
+// applyChanges retrieves changed hosts and VMs, and updates them in the source.
+// It returns the changed hosts and VMs, which still exist, by their keys.
+func (vc *VmwareSource) applyChanges(
+	ctx context.Context,
+	changes map[types.ManagedObjectReference]types.ObjectUpdateKind,
+) (map[string]mo.HostSystem, map[string]mo.VirtualMachine, error) {
+	var hostRefs, vmRefs []types.ManagedObjectReference
+	for ref, kind := range changes {
+		switch {
+		case kind == types.ObjectUpdateKindLeave:
+			delete(vc.Hosts, ref.Value)
+			delete(vc.Vms, ref.Value)
+			delete(vc.VM2Host, ref.Value)
+		case ref.Type == "HostSystem":
+			hostRefs = append(hostRefs, ref)
+		case ref.Type == "VirtualMachine":
+			vmRefs = append(vmRefs, ref)
+		}
+	}
+	changedHosts := make(map[string]mo.HostSystem, len(hostRefs))
+	changedVMs := make(map[string]mo.VirtualMachine, len(vmRefs))
+	if len(hostRefs) == 0 && len(vmRefs) == 0 {
+		return changedHosts, changedVMs, nil
+	}
+
+	client, sessionManager, _, err := vc.login(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err := sessionManager.Logout(context.Background()); err != nil {
+			vc.Logger.Errorf(vc.Ctx, "failed ending vmware connection: %s", err)
+		}
+	}()
+	collector := property.DefaultCollector(client)
+
+	var hosts []mo.HostSystem
+	if len(hostRefs) > 0 {
+		if err := collector.Retrieve(ctx, hostRefs, hostProperties, &hosts); err != nil {
+			return nil, nil, fmt.Errorf("failed retrieving changed hosts: %s", err)
+		}
+	}
+	var vms []mo.VirtualMachine
+	if len(vmRefs) > 0 {
+		if err := collector.Retrieve(ctx, vmRefs, vmProperties, &vms); err != nil {
+			return nil, nil, fmt.Errorf("failed retrieving changed vms: %s", err)
+		}
+	}
+	for _, host := range hosts {
+		vc.addHost(host)
+		changedHosts[host.Self.Value] = host
+	}
+	for _, vm := range vms {
+		vc.Vms[vm.Self.Value] = vm
+		if vm.Runtime.Host != nil {
+			vc.VM2Host[vm.Self.Value] = vm.Runtime.Host.Value
+		}
+		changedVMs[vm.Self.Value] = vm
+	}
+	return changedHosts, changedVMs, nil
+}
+
+// changedObjectTypes returns object types, which are synced for the updates.
+func changedObjectTypes(updates []types.ObjectUpdate) []constants.APIPath {
+	var objectTypes []constants.APIPath
+	for _, update := range updates {
+		switch update.Obj.Type {
+		case "HostSystem":
+			objectTypes = append(objectTypes, watchedHostObjectTypes...)
+		case "VirtualMachine":
+			objectTypes = append(objectTypes, watchedVMObjectTypes...)
+		}
+	}
+	slices.Sort(objectTypes)
+	return slices.Compact(objectTypes)
+}
+
+// addChanges adds updates collected by Watch to the changes.
+func (vc *VmwareSource) addChanges(updates []types.ObjectUpdate) {
+	vc.changesLock.Lock()
+	defer vc.changesLock.Unlock()
+	if vc.changes == nil {
+		vc.changes = make(map[types.ManagedObjectReference]types.ObjectUpdateKind)
+	}
+	for _, update := range updates {
+		vc.changes[update.Obj] = update.Kind
+	}
+}
+
+// takeChanges returns all collected changes, and removes them from the source.
+func (vc *VmwareSource) takeChanges() map[types.ManagedObjectReference]types.ObjectUpdateKind {
+	vc.changesLock.Lock()
+	defer vc.changesLock.Unlock()
+	changes := vc.changes
+	vc.changes = nil
+	return changes
+}
+
+// restoreChanges adds back changes, which failed to sync. Changes collected
+// in the meantime are newer, so they are kept.
+func (vc *VmwareSource) restoreChanges(changes map[types.ManagedObjectReference]types.ObjectUpdateKind) {
+	vc.changesLock.Lock()
+	defer vc.changesLock.Unlock()
+	if vc.changes == nil {
+		vc.changes = make(map[types.ManagedObjectReference]types.ObjectUpdateKind, len(changes))
+	}
+	for ref, kind := range changes {
+		if _, ok := vc.changes[ref]; !ok {
+			vc.changes[ref] = kind
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package vmware
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bl4ko/netbox-ssot/internal/constants"
+	"github.com/bl4ko/netbox-ssot/internal/logger"
+	"github.com/bl4ko/netbox-ssot/internal/parser"
+	"github.com/bl4ko/netbox-ssot/internal/source/common"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	_ "github.com/vmware/govmomi/vapi/simulator" // vapi endpoints used for tags
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// newSimulatorSource returns a source of the vcsim simulator, and a client to change the simulator.
+func newSimulatorSource(ctx context.Context, t *testing.T) (*VmwareSource, *govmomi.Client) {
+	t.Helper()
+	model := simulator.VPX()
+	if err := model.Create(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(model.Remove)
+	model.Service.RegisterEndpoints = true
+	server := model.Service.NewServer()
+	t.Cleanup(server.Close)
+
+	client, err := govmomi.NewClient(ctx, server.URL, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testLogger, err := logger.New("", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(server.URL.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	password, _ := server.URL.User.Password()
+	vc := &VmwareSource{
+		Config: common.Config{
+			Logger: testLogger,
+			SourceConfig: &parser.SourceConfig{
+				Name:       "test",
+				Type:       constants.Vmware,
+				HTTPScheme: parser.HTTP,
+				Hostname:   server.URL.Hostname(),
+				Port:       port,
+				Username:   server.URL.User.Username(),
+				Password:   password,
+				Watch:      true,
+			},
+		},
+	}
+	return vc, client
+}
+
+// waitForChanges calls change until Watch collects changes, for which want returns true.
+// Watch ignores changes, which happen before it receives the current state, so change
+// is repeated while no changes are collected.
+func waitForChanges(
+	ctx context.Context,
+	t *testing.T,
+	vc *VmwareSource,
+	changed <-chan struct{},
+	change func(attempt int) error,
+	want func(changes map[types.ManagedObjectReference]types.ObjectUpdateKind) bool,
+) map[types.ManagedObjectReference]types.ObjectUpdateKind {
+	t.Helper()
+	collected := make(map[types.ManagedObjectReference]types.ObjectUpdateKind)
+	for attempt := 0; ; attempt++ {
+		if err := change(attempt); err != nil {
+			t.Fatal(err)
+		}
+		for collecting := true; collecting; {
+			select {
+			case <-changed:
+				maps.Copy(collected, vc.takeChanges())
+				if want(collected) {
+					return collected
+				}
+			case <-time.After(200 * time.Millisecond):
+				collecting = false
+			case <-ctx.Done():
+				t.Fatalf("changes %v were collected by Watch, want more", collected)
+			}
+		}
+	}
+}
+
+func TestVmwareSourceWatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	vc, client := newSimulatorSource(ctx, t)
+	if err := vc.SyncChanges(ctx, nil); err == nil {
+		t.Error("SyncChanges() of uninitialized source returned nil error")
+	}
+	if err := vc.Init(ctx); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	changed := make(chan struct{}, 1)
+	watchDone := make(chan struct{})
+	var watchErr error
+	go func() {
+		defer close(watchDone)
+		watchErr = vc.Watch(watchCtx, func(...constants.APIPath) {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		})
+	}()
+	// Watch is stopped before the simulator, which waits for open requests
+	t.Cleanup(func() {
+		stopWatch()
+		<-watchDone
+	})
+
+	finder := find.NewFinder(client.Client, true)
+	datacenter, err := finder.DefaultDatacenter(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finder.SetDatacenter(datacenter)
+	vm, err := finder.VirtualMachine(ctx, "DC0_H0_VM0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Renamed VM is retrieved again
+	var newName string
+	changes := waitForChanges(ctx, t, vc, changed, func(attempt int) error {
+		newName = "renamed-vm-" + strconv.Itoa(attempt)
+		task, err := vm.Rename(ctx, newName)
+		if err != nil {
+			return err
+		}
+		return task.Wait(ctx)
+	}, func(changes map[types.ManagedObjectReference]types.ObjectUpdateKind) bool {
+		return changes[vm.Reference()] == types.ObjectUpdateKindModify
+	})
+	_, vms, err := vc.applyChanges(ctx, changes)
+	if err != nil {
+		t.Fatalf("applyChanges() error = %v", err)
+	}
+	if got := vms[vm.Reference().Value].Name; got != newName {
+		t.Errorf("changed VM name = %q, want %q", got, newName)
+	}
+	if got := vc.Vms[vm.Reference().Value].Name; got != newName {
+		t.Errorf("VM name in source = %q, want %q", got, newName)
+	}
+
+	// Created VM is added to the source
+	folders, err := datacenter.Folders(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, err := finder.HostSystem(ctx, "DC0_H0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, err := host.ResourcePool(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes = waitForChanges(ctx, t, vc, changed, func(attempt int) error {
+		task, err := folders.VmFolder.CreateVM(ctx, types.VirtualMachineConfigSpec{
+			Name:  "new-vm-" + strconv.Itoa(attempt),
+			Files: &types.VirtualMachineFileInfo{VmPathName: "[LocalDS_0]"},
+		}, pool, host)
+		if err != nil {
+			return err
+		}
+		return task.Wait(ctx)
+	}, func(changes map[types.ManagedObjectReference]types.ObjectUpdateKind) bool {
+		return slices.Contains(slices.Collect(maps.Values(changes)), types.ObjectUpdateKindEnter)
+	})
+	_, vms, err = vc.applyChanges(ctx, changes)
+	if err != nil {
+		t.Fatalf("applyChanges() error = %v", err)
+	}
+	var newVMKey string
+	for key, changedVM := range vms {
+		if strings.HasPrefix(changedVM.Name, "new-vm-") && vc.VM2Host[key] == host.Reference().Value {
+			newVMKey = key
+		}
+	}
+	if _, ok := vc.Vms[newVMKey]; newVMKey == "" || !ok {
+		t.Errorf("created VM wasn't added to the source, changed VMs: %v", vms)
+	}
+
+	// Destroyed VM is removed from the source
+	destroyedVM := object.NewVirtualMachine(client.Client, types.ManagedObjectReference{
+		Type:  "VirtualMachine",
+		Value: newVMKey,
+	})
+	changes = waitForChanges(ctx, t, vc, changed, func(attempt int) error {
+		if attempt > 0 {
+			return nil
+		}
+		task, err := destroyedVM.Destroy(ctx)
+		if err != nil {
+			return err
+		}
+		return task.Wait(ctx)
+	}, func(changes map[types.ManagedObjectReference]types.ObjectUpdateKind) bool {
+		return changes[destroyedVM.Reference()] == types.ObjectUpdateKindLeave
+	})
+	if _, _, err := vc.applyChanges(ctx, changes); err != nil {
+		t.Fatalf("applyChanges() error = %v", err)
+	}
+	if _, ok := vc.Vms[newVMKey]; ok {
+		t.Error("destroyed VM wasn't removed from the source")
+	}
+
+	stopWatch()
+	<-watchDone
+	if watchErr != nil {
+		t.Errorf("Watch() error = %v", watchErr)
+	}
+}
+
+func TestVmwareSourceRestoreChanges(t *testing.T) {
+	vm := types.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-1"}
+	host := types.ManagedObjectReference{Type: "HostSystem", Value: "host-1"}
+	vc := &VmwareSource{}
+	vc.addChanges([]types.ObjectUpdate{
+		{Obj: vm, Kind: types.ObjectUpdateKindModify},
+		{Obj: host, Kind: types.ObjectUpdateKindModify},
+	})
+	failed := vc.takeChanges()
+	vc.addChanges([]types.ObjectUpdate{{Obj: vm, Kind: types.ObjectUpdateKindLeave}})
+	vc.restoreChanges(failed)
+	changes := vc.takeChanges()
+	if changes[vm] != types.ObjectUpdateKindLeave || changes[host] != types.ObjectUpdateKindModify {
+		t.Errorf("restored changes = %v, want newer change of vm and failed change of host", changes)
+	}
+	if changes := vc.takeChanges(); len(changes) != 0 {
+		t.Errorf("takeChanges() = %v, want no changes", changes)
+	}
+}
+
+func TestChangedObjectTypes(t *testing.T) {
+	vm := types.ObjectUpdate{Obj: types.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-1"}}
+	host := types.ObjectUpdate{Obj: types.ManagedObjectReference{Type: "HostSystem", Value: "host-1"}}
+	tests := []struct {
+		name    string
+		updates []types.ObjectUpdate
+		want    []constants.APIPath
+	}{
+		{
+			name:    "Changed VMs",
+			updates: []types.ObjectUpdate{vm, vm},
+			want: []constants.APIPath{
+				constants.MACAddressesAPIPath,
+				constants.IPAddressesAPIPath,
+				constants.VMInterfacesAPIPath,
+				constants.VirtualDisksAPIPath,
+				constants.VirtualMachinesAPIPath,
+			},
+		},
+		{
+			name:    "Changed hosts and VMs",
+			updates: []types.ObjectUpdate{host, vm},
+			want: []constants.APIPath{
+				constants.DevicesAPIPath,
+				constants.InterfacesAPIPath,
+				constants.MACAddressesAPIPath,
+				constants.IPAddressesAPIPath,
+				constants.VMInterfacesAPIPath,
+				constants.VirtualDisksAPIPath,
+				constants.VirtualMachinesAPIPath,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := changedObjectTypes(tt.updates); !slices.Equal(got, tt.want) {
+				t.Errorf("changedObjectTypes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -79,6 +79,11 @@ func hasPriorityOver(newObj, existingObj reflect.Value, source2priority map[stri
 	return true
 }
 
+// FieldPriority decides priority of the new object over the existing object
+// for the field, represented by its JSON tag name. ruled is true if priority
+// is decided by a rule of the field instead of priority of the whole object.
+type FieldPriority func(field string) (hasPriority bool, ruled bool)
+
 // JSONDiffMapExceptID compares two objects and returns a map of fields
 // (represented by their JSON tag names) that are different with their
 // values from newObj.
@@ -91,14 +96,48 @@ func JSONDiffMapExceptID(
 	resetFields bool,
 	source2priority map[string]int,
 ) (map[string]interface{}, error) {
-	diff := make(map[string]interface{})
+	newObject, existingObject, err := structValues(newObj, existingObj)
+	if err != nil {
+		return nil, err
+	}
+	hasPriority := hasPriorityOver(newObject, existingObject, source2priority)
+	return jsonDiffMap(newObject, existingObject, resetFields, func(string) (bool, bool) { return hasPriority, false })
+}
 
+// JSONDiffMapWithFieldPriority is like JSONDiffMapExceptID, but priority
+// is decided for each field separately by fieldPriority. It is called only
+// for fields, which are set in newObj.
+func JSONDiffMapWithFieldPriority(
+	newObj, existingObj interface{},
+	resetFields bool,
+	fieldPriority FieldPriority,
+) (map[string]interface{}, error) {
+	newObject, existingObject, err := structValues(newObj, existingObj)
+	if err != nil {
+		return nil, err
+	}
+	return jsonDiffMap(newObject, existingObject, resetFields, fieldPriority)
+}
+
+// HasPriorityOver returns true if newObj has priority over existingObj
+// based on source2priority. See hasPriorityOver for details.
+func HasPriorityOver(newObj, existingObj interface{}, source2priority map[string]int) bool {
+	newObject, existingObject, err := structValues(newObj, existingObj)
+	if err != nil {
+		return true
+	}
+	return hasPriorityOver(newObject, existingObject, source2priority)
+}
+
+// structValues returns structs of newObj and existingObj,
+// which have to be structs or pointers to structs.
+func structValues(newObj, existingObj interface{}) (reflect.Value, reflect.Value, error) {
 	newObject := reflect.ValueOf(newObj)
 	existingObject := reflect.ValueOf(existingObj)
 
 	// Ensure that both objects are of the same kind (e.g. struct)
 	if newObject.Kind() != existingObject.Kind() {
-		return nil, fmt.Errorf("arguments are not of the same type")
+		return reflect.Value{}, reflect.Value{}, fmt.Errorf("arguments are not of the same type")
 	}
 
 	// Check if the values are pointers and get the element they point to
@@ -109,11 +148,17 @@ func JSONDiffMapExceptID(
 
 	// Ensure that we are dealing with structs
 	if newObject.Kind() != reflect.Struct {
-		return nil, fmt.Errorf("arguments are not structs")
+		return reflect.Value{}, reflect.Value{}, fmt.Errorf("arguments are not structs")
 	}
+	return newObject, existingObject, nil
+}
 
-	// Check for priority
-	hasPriority := hasPriorityOver(newObject, existingObject, source2priority)
+func jsonDiffMap(
+	newObject, existingObject reflect.Value,
+	resetFields bool,
+	fieldPriority FieldPriority,
+) (map[string]interface{}, error) {
+	diff := make(map[string]interface{})
 
 	for i := 0; i < newObject.NumField(); i++ {
 		fieldName := newObject.Type().Field(i).Name
@@ -125,12 +170,14 @@ func JSONDiffMapExceptID(
 
 		// Custom logic for all objects that inherit from NetboxObject
 		if fieldName == "NetboxObject" {
-			netboxObjectDiffMap, err := JSONDiffMapExceptID(
+			newNetboxObject, existingNetboxObject, err := structValues(
 				newObject.Field(i).Interface(),
 				existingObject.Field(i).Interface(),
-				resetFields,
-				source2priority,
 			)
+			var netboxObjectDiffMap map[string]interface{}
+			if err == nil {
+				netboxObjectDiffMap, err = jsonDiffMap(newNetboxObject, existingNetboxObject, resetFields, fieldPriority)
+			}
 			if err != nil {
 				return nil, fmt.Errorf(
 					"error processing JsonDiffMapExceptID when processing NetboxObject %s",
@@ -169,6 +216,7 @@ func JSONDiffMapExceptID(
 			continue
 		}
 
+		hasPriority, ruled := fieldPriority(jsonTag)
 		switch newObjectField.Kind() {
 		// Reset the field (when it is set to nil),
 		// this only happens if flag resetFields is set to true.
@@ -187,7 +235,9 @@ func JSONDiffMapExceptID(
 			}
 
 		case reflect.Struct:
-			err := addStructDiff(newObjectField, existingObjectField, jsonTag, hasPriority, diff)
+			// Referenced objects are always updated, unless a rule of the field decides otherwise
+			referencePriority := hasPriority || !ruled
+			err := addStructDiff(newObjectField, existingObjectField, jsonTag, hasPriority, referencePriority, diff)
 			if err != nil {
 				return nil, fmt.Errorf(
 					"error processing JsonDiffMapExceptID when processing struct %s",
@@ -419,6 +469,7 @@ func addStructDiff(
 	existingObj reflect.Value,
 	jsonTag string,
 	hasPriority bool,
+	referencePriority bool,
 	diffMap map[string]interface{},
 ) error {
 	// If first struct is nil, that means that we reset the attribute to nil
@@ -454,7 +505,7 @@ func addStructDiff(
 				return fmt.Errorf("id field is not an int")
 			}
 			diffMap[jsonTag] = IDObject{ID: idValue}
-		} else if newObj.FieldByName("ID").Interface() != existingObj.FieldByName("ID").Interface() {
+		} else if referencePriority && newObj.FieldByName("ID").Interface() != existingObj.FieldByName("ID").Interface() {
 			// Objects have ID field, compare their ids
 			idValue, ok := idField.Interface().(int)
 			if !ok {
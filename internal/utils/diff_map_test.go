@@ -2,6 +2,7 @@ package utils
 
 import (
 	"reflect"
+	"slices"
 	"testing"
 
 	"github.com/bl4ko/netbox-ssot/internal/constants"
@@ -568,6 +569,95 @@ func TestPriorityMergeDiff(t *testing.T) {
 	}
 }
 
+func TestJSONDiffMapWithFieldPriority(t *testing.T) {
+	newDevice := &objects.Device{
+		NetboxObject: objects.NetboxObject{
+			Description: "New description",
+			CustomFields: map[string]interface{}{
+				constants.CustomFieldSourceName: "vcenter",
+			},
+		},
+		Name:         "server1",
+		SerialNumber: "VC123",
+		Platform:     &objects.Platform{NetboxObject: objects.NetboxObject{ID: 1}},
+	}
+	existingDevice := &objects.Device{
+		NetboxObject: objects.NetboxObject{
+			ID: 1,
+			CustomFields: map[string]interface{}{
+				constants.CustomFieldSourceName: "dnac",
+			},
+		},
+		Name:         "server1",
+		SerialNumber: "DNAC123",
+		Platform:     &objects.Platform{NetboxObject: objects.NetboxObject{ID: 2}},
+	}
+
+	tests := []struct {
+		name         string
+		newFields    []string
+		ruledFields  []string
+		expectedDiff map[string]interface{}
+	}{
+		{
+			name: "New object has priority for all fields",
+			newFields: []string{
+				"description", "custom_fields", "name", "serial", "platform",
+			},
+			expectedDiff: map[string]interface{}{
+				"description": "New description",
+				"custom_fields": map[string]interface{}{
+					constants.CustomFieldSourceName: "vcenter",
+				},
+				"serial":   "VC123",
+				"platform": IDObject{ID: 1},
+			},
+		},
+		{
+			name:      "New object has priority for some fields",
+			newFields: []string{"description", "platform"},
+			expectedDiff: map[string]interface{}{
+				"description": "New description",
+				"platform":    IDObject{ID: 1},
+			},
+		},
+		{
+			// Empty fields of the existing object are always set
+			name:        "New object has priority for no fields",
+			ruledFields: []string{"platform"},
+			expectedDiff: map[string]interface{}{
+				"description": "New description",
+			},
+		},
+		{
+			// Only rules of fields can keep referenced objects
+			name: "Referenced objects of fields without rules are updated",
+			expectedDiff: map[string]interface{}{
+				"description": "New description",
+				"platform":    IDObject{ID: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDiff, err := JSONDiffMapWithFieldPriority(
+				newDevice,
+				existingDevice,
+				false,
+				func(field string) (bool, bool) {
+					return slices.Contains(tt.newFields, field), slices.Contains(tt.ruledFields, field)
+				},
+			)
+			if err != nil {
+				t.Errorf("JSONDiffMapWithFieldPriority() error = %v", err)
+			}
+			if !reflect.DeepEqual(outputDiff, tt.expectedDiff) {
+				t.Errorf("JSONDiffMapWithFieldPriority() = %v, want %v", outputDiff, tt.expectedDiff)
+			}
+		})
+	}
+}
+
 func Test_hasPriorityOver(t *testing.T) {
 	type args struct {
 		newObj          reflect.Value
@@ -1015,11 +1105,12 @@ func Test_addSliceDiff(t *testing.T) {
 
 func Test_addStructDiff(t *testing.T) {
 	type args struct {
-		newObj      reflect.Value
-		existingObj reflect.Value
-		jsonTag     string
-		hasPriority bool
-		diffMap     map[string]interface{}
+		newObj            reflect.Value
+		existingObj       reflect.Value
+		jsonTag           string
+		hasPriority       bool
+		referencePriority bool
+		diffMap           map[string]interface{}
 	}
 	tests := []struct {
 		name    string
@@ -1109,6 +1200,7 @@ func Test_addStructDiff(t *testing.T) {
 				tt.args.existingObj,
 				tt.args.jsonTag,
 				tt.args.hasPriority,
+				tt.args.referencePriority,
 				tt.args.diffMap); (err != nil) != tt.wantErr {
 				t.Errorf("addStructDiff() error = %v, wantErr %v", err, tt.wantErr)
 			}